@@ -0,0 +1,267 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"rag-cli/internal/chat"
+	"rag-cli/internal/embeddings"
+	"rag-cli/internal/llm"
+	"rag-cli/internal/vector"
+	"rag-cli/pkg/config"
+)
+
+var (
+	askCollection  string
+	askTopK        int
+	askShowContext bool
+	askOutput      string
+	askContextOnly bool
+)
+
+var askCmd = &cobra.Command{
+	Use:   "ask <question>",
+	Short: "Answer a question from indexed documents without running commands",
+	Long: `Answer a question using Retrieval-Augmented Generation over your indexed documents.
+
+Unlike the default chat mode, ask uses a question-answering prompt and never executes
+shell commands, so it is safe to point at any corpus or run from scripts. If the model
+mentions a command in its answer it is printed as text only.
+
+EXAMPLES:
+  # Ask a question about indexed documents
+  rag-cli ask "what port does the staging database use?"
+
+  # Show the retrieved context alongside the answer
+  rag-cli ask --show-context --top-k 8 "how are releases tagged?"
+
+  # Answer from a one-off corpus indexed with 'rag-cli index --collection project-x'
+  rag-cli ask --collection project-x "what does the API rate limit default to?"
+
+  # Print the context that would be sent to the model, without calling it
+  rag-cli ask --context-only --top-k 8 "how are releases tagged?"
+
+  # Machine-readable output
+  rag-cli ask --output json "who owns the billing service?"`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if askTopK <= 0 {
+			return fmt.Errorf("--top-k must be greater than 0")
+		}
+		if askOutput != "text" && askOutput != "json" {
+			return fmt.Errorf("invalid --output %q (expected text or json)", askOutput)
+		}
+
+		cfg, err := config.Load()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		// --context-only never generates, so it works without a reachable LLM
+		var generator answerGenerator
+		if !askContextOnly {
+			llmClient, err := llm.NewClient(cfg.LLM, cfg.Timeouts)
+			if err != nil {
+				return fmt.Errorf("failed to initialize LLM client: %w", err)
+			}
+			if err := checkModelOverride(llmClient, cfg.LLM.Model); err != nil {
+				return err
+			}
+			generator = llmClient
+		}
+
+		embeddingClient, err := embeddings.NewClient(cfg.Embeddings, cfg.Timeouts)
+		if err != nil {
+			return fmt.Errorf("failed to initialize embedding client: %w", err)
+		}
+
+		vectorStore, err := vector.NewChromaClient(cfg.Vector, cfg.Timeouts)
+		if err != nil {
+			return fmt.Errorf("failed to initialize vector store: %w", err)
+		}
+
+		return runAsk(os.Stdout, generator, embeddingClient, vectorStore, args[0], askOptions{
+			collection:  askCollection,
+			topK:        askTopK,
+			showContext: askShowContext,
+			contextOnly: askContextOnly,
+			output:      askOutput,
+			budget:      contextBudget(cfg.Chat, answerPromptChars),
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(askCmd)
+
+	askCmd.Flags().StringVarP(&askCollection, "collection", "c", "documents", "Collection to retrieve context from: documents, commands, auto, or an existing collection name")
+	askCmd.Flags().IntVarP(&askTopK, "top-k", "k", 5, "Number of context chunks to retrieve")
+	askCmd.Flags().BoolVar(&askShowContext, "show-context", false, "Print the retrieved context before the answer")
+	askCmd.Flags().BoolVar(&askContextOnly, "context-only", false, "Print the retrieved context with sources and distances, then exit without calling the LLM")
+	askCmd.Flags().StringVarP(&askOutput, "output", "o", "text", "Output format: text or json")
+}
+
+// answerGenerator produces answers in question-answering mode. It deliberately
+// has no command-generation method so ask cannot end up executing anything.
+type answerGenerator interface {
+	GenerateAnswer(query string, context []string) (string, error)
+}
+
+type askOptions struct {
+	collection  string
+	topK        int
+	showContext bool
+	contextOnly bool
+	output      string
+	budget      chat.ContextBudget // Trims the context given to the model
+}
+
+// askOutputJSON is the JSON representation of an ask result
+type askOutputJSON struct {
+	Question string                `json:"question"`
+	Answer   string                `json:"answer"`
+	Context  []vector.SearchResult `json:"context"`
+}
+
+func runAsk(out io.Writer, generator answerGenerator, embedder embeddings.Embedder, store vector.VectorStore, question string, opts askOptions) error {
+	collectionName, err := resolveCollection(store, opts.collection)
+	if err != nil {
+		return err
+	}
+
+	if opts.contextOnly {
+		return runContextOnly(out, embedder, store, question, []contextSource{{collection: collectionName, topK: opts.topK}}, opts.budget, opts.output == "json")
+	}
+
+	queryEmbedding, err := embedder.GenerateEmbedding(question)
+	if err != nil {
+		return fmt.Errorf("failed to generate query embedding: %w", err)
+	}
+
+	results, err := store.SearchWithScores(collectionName, queryEmbedding, opts.topK)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve context: %w", err)
+	}
+
+	items := make([]chat.ContextItem, 0, len(results))
+	for _, result := range results {
+		items = append(items, contextSource{collection: collectionName}.item(result))
+	}
+	items = opts.budget.Fit(question, items)
+	results = results[:len(items)]
+	context := chat.ContextTexts(items)
+
+	answer, err := generator.GenerateAnswer(question, context)
+	if err != nil {
+		return fmt.Errorf("error generating answer: %w", err)
+	}
+
+	if opts.output == "json" {
+		if results == nil {
+			results = []vector.SearchResult{}
+		}
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(askOutputJSON{Question: question, Answer: answer, Context: results})
+	}
+
+	if opts.showContext {
+		if len(results) == 0 {
+			fmt.Fprintf(out, "No context found in %s\n\n", collectionName)
+		} else {
+			fmt.Fprintf(out, "Context from %s:\n", collectionName)
+			for i, result := range results {
+				fmt.Fprintf(out, "%d. [distance %.4f] %s\n", i+1, result.Distance, formatSource(result))
+				fmt.Fprintf(out, "   %s\n", snippet(result.Document, searchSnippetLength))
+			}
+			fmt.Fprintln(out)
+		}
+	}
+
+	fmt.Fprintln(out, answer)
+	return nil
+}
+
+// contextSource is a collection to retrieve context from and how many chunks to take
+type contextSource struct {
+	collection string
+	topK       int
+	labelled   bool // Documents are given to the model labelled with their source, as in chat
+}
+
+// item returns the context item result is given to the model as
+func (s contextSource) item(result vector.SearchResult) chat.ContextItem {
+	if s.labelled {
+		return chat.SearchResultItem(result, s.collection)
+	}
+	return chat.ContextItem{Text: result.Document, Distance: result.Distance, Collection: s.collection}
+}
+
+// contextBudget returns the chat settings that bound the context given to the
+// model, measuring the prompt without context with promptChars
+func contextBudget(cfg config.ChatConfig, promptChars func(prompt string) int) chat.ContextBudget {
+	return chat.ContextBudget{MaxChars: cfg.MaxContextChars, ItemChars: cfg.ContextItemChars, PromptChars: promptChars}
+}
+
+// answerPromptChars returns the characters of the question-answering prompt
+// for question without context
+func answerPromptChars(question string) int {
+	return llm.AnswerPromptSize(question, nil)
+}
+
+// runContextOnly retrieves context from each source in turn and prints the
+// blocks in the order they would be given to the model, trimmed to budget,
+// without generating
+func runContextOnly(out io.Writer, embedder embeddings.Embedder, store vector.VectorStore, query string, sources []contextSource, budget chat.ContextBudget, asJSON bool) error {
+	queryEmbedding, err := embedder.GenerateEmbedding(query)
+	if err != nil {
+		return fmt.Errorf("failed to generate query embedding: %w", err)
+	}
+
+	var retrieved []vector.SearchResult
+	var items []chat.ContextItem
+	for _, source := range sources {
+		results, err := store.SearchWithScores(source.collection, queryEmbedding, source.topK)
+		if err != nil {
+			return fmt.Errorf("failed to retrieve context from %s: %w", source.collection, err)
+		}
+		for _, result := range results {
+			retrieved = append(retrieved, result)
+			items = append(items, source.item(result))
+		}
+	}
+
+	// Items are only ever cut or dropped from the end, so each kept one
+	// still lines up with the result it came from
+	blocks := []searchOutput{}
+	for i, item := range budget.Fit(query, items) {
+		result := retrieved[i]
+		label := strings.TrimSuffix(items[i].Text, result.Document)
+		blocks = append(blocks, searchOutput{
+			Rank:       i + 1,
+			ID:         result.ID,
+			Distance:   result.Distance,
+			Collection: item.Collection,
+			Document:   strings.TrimPrefix(item.Text, label),
+			Metadata:   result.Metadata,
+		})
+	}
+
+	if asJSON {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(blocks)
+	}
+
+	fmt.Fprintf(out, "Context for %q: %d block(s), LLM not called\n", query, len(blocks))
+	for _, block := range blocks {
+		source := formatSource(vector.SearchResult{ID: block.ID, Metadata: block.Metadata})
+		fmt.Fprintf(out, "\n--- [%d] %s, distance %.4f, %s\n", block.Rank, block.Collection, block.Distance, source)
+		fmt.Fprintln(out, block.Document)
+	}
+	return nil
+}
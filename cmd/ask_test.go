@@ -0,0 +1,228 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"rag-cli/internal/chat"
+	"rag-cli/pkg/config"
+)
+
+func TestRunAsk_UsesAnswerMode(t *testing.T) {
+	generator := &fakeLLM{response: "Releases are tagged with semver."}
+	var out bytes.Buffer
+
+	err := runAsk(&out, generator, &fakeEmbedder{}, newSearchFixture(), "how are releases tagged?", askOptions{
+		collection: "documents",
+		topK:       2,
+		output:     "text",
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if generator.answerCalls != 1 {
+		t.Errorf("Expected one answer-mode call, got %d", generator.answerCalls)
+	}
+	if generator.responseCalls != 0 {
+		t.Errorf("Expected no command-generation calls, got %d", generator.responseCalls)
+	}
+	if len(generator.lastContext) != 2 || !strings.HasPrefix(generator.lastContext[0], "Chunk size controls") {
+		t.Errorf("Expected the top 2 documents as context, got: %v", generator.lastContext)
+	}
+	if strings.TrimSpace(out.String()) != "Releases are tagged with semver." {
+		t.Errorf("Expected only the answer in output, got: %q", out.String())
+	}
+}
+
+func TestRunAsk_NeverExecutesCommands(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "should-not-exist")
+	generator := &fakeLLM{response: "touch " + marker}
+	var out bytes.Buffer
+
+	if err := runAsk(&out, generator, &fakeEmbedder{}, newSearchFixture(), "create a file", askOptions{
+		collection: "documents",
+		topK:       5,
+		output:     "text",
+	}); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if _, err := os.Stat(marker); !os.IsNotExist(err) {
+		t.Errorf("Expected command in answer not to be executed, but %s exists", marker)
+	}
+	if !strings.Contains(out.String(), "touch "+marker) {
+		t.Errorf("Expected command to be printed as text, got: %s", out.String())
+	}
+}
+
+func TestRunAsk_ShowContext(t *testing.T) {
+	var out bytes.Buffer
+	err := runAsk(&out, &fakeLLM{response: "answer"}, &fakeEmbedder{}, newSearchFixture(), "q", askOptions{
+		collection:  "commands",
+		topK:        5,
+		showContext: true,
+		output:      "text",
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	output := out.String()
+	if !strings.Contains(output, "Context from command_history:") || !strings.Contains(output, "$ git status") {
+		t.Errorf("Expected retrieved context in output, got:\n%s", output)
+	}
+	if strings.Index(output, "$ git status") > strings.Index(output, "answer") {
+		t.Errorf("Expected context to be printed before the answer, got:\n%s", output)
+	}
+}
+
+func TestRunAsk_JSONOutput(t *testing.T) {
+	var out bytes.Buffer
+	err := runAsk(&out, &fakeLLM{response: "42"}, &fakeEmbedder{}, newSearchFixture(), "meaning?", askOptions{
+		collection: "documents",
+		topK:       1,
+		output:     "json",
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	var result askOutputJSON
+	if err := json.Unmarshal(out.Bytes(), &result); err != nil {
+		t.Fatalf("Expected valid JSON, got error %v for:\n%s", err, out.String())
+	}
+	if result.Question != "meaning?" || result.Answer != "42" {
+		t.Errorf("Unexpected JSON result: %+v", result)
+	}
+	if len(result.Context) != 1 || result.Context[0].ID != "doc-1" {
+		t.Errorf("Expected one context item with id doc-1, got: %+v", result.Context)
+	}
+}
+
+func TestRunAsk_ContextOnly(t *testing.T) {
+	t.Run("text", func(t *testing.T) {
+		generator := &fakeLLM{response: "should not be generated"}
+		var out bytes.Buffer
+
+		err := runAsk(&out, generator, &fakeEmbedder{}, newSearchFixture(), "chunk size?", askOptions{
+			collection:  "documents",
+			topK:        2,
+			contextOnly: true,
+			output:      "text",
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+
+		if generator.answerCalls+generator.responseCalls != 0 {
+			t.Errorf("Expected no LLM calls, got %d", generator.answerCalls+generator.responseCalls)
+		}
+		output := out.String()
+		expected := []string{
+			`Context for "chunk size?": 2 block(s), LLM not called`,
+			"--- [1] documents, distance 0.1200, docs/config.md (id: doc-1)",
+			"Chunk size controls\nhow large each piece is.",
+			"--- [2] documents, distance 0.4500, id: doc-2",
+		}
+		for _, want := range expected {
+			if !strings.Contains(output, want) {
+				t.Errorf("Expected output to contain %q, got:\n%s", want, output)
+			}
+		}
+		if strings.Contains(output, "should not be generated") {
+			t.Errorf("Expected no answer in output, got:\n%s", output)
+		}
+	})
+
+	t.Run("json without a generator", func(t *testing.T) {
+		var out bytes.Buffer
+		err := runAsk(&out, nil, &fakeEmbedder{}, newSearchFixture(), "q", askOptions{
+			collection:  "commands",
+			topK:        5,
+			contextOnly: true,
+			output:      "json",
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+
+		var blocks []searchOutput
+		if err := json.Unmarshal(out.Bytes(), &blocks); err != nil {
+			t.Fatalf("Expected valid JSON, got error %v for:\n%s", err, out.String())
+		}
+		if len(blocks) != 1 || blocks[0].Collection != "command_history" || blocks[0].Document != "$ git status" {
+			t.Errorf("Unexpected context blocks: %+v", blocks)
+		}
+	})
+}
+
+func TestRunContextOnly_Budget(t *testing.T) {
+	store := newSearchFixture()
+	sources := chatContextSources(store, config.ChatConfig{TopKDocuments: 3, TopKHistory: 1}, false)
+	// Room for the three documents, the second cut to 100 characters, but
+	// not the past session ranked below them
+	budget := chat.ContextBudget{MaxChars: 230, ItemChars: 100}
+	var out bytes.Buffer
+
+	if err := runContextOnly(&out, &fakeEmbedder{}, store, "deploy", sources, budget, true); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	var blocks []searchOutput
+	if err := json.Unmarshal(out.Bytes(), &blocks); err != nil {
+		t.Fatalf("Expected valid JSON, got error %v", err)
+	}
+	if len(blocks) != 3 || blocks[2].ID != "doc-3" {
+		t.Fatalf("Expected the past session to be dropped, got %+v", blocks)
+	}
+	if blocks[0].Document != "Chunk size controls\nhow large each piece is." {
+		t.Errorf("Expected the document without its source label, got %q", blocks[0].Document)
+	}
+	if len(blocks[1].Document) > 100 || !strings.HasSuffix(blocks[1].Document, "[truncated]") {
+		t.Errorf("Expected the long document cut to 100 characters, got %q", blocks[1].Document)
+	}
+}
+
+func TestRunContextOnly_ChatSources(t *testing.T) {
+	tests := []struct {
+		name        string
+		noHistory   bool
+		wantBlocks  int
+		wantHistory bool
+	}{
+		{name: "documents and history", wantBlocks: 3, wantHistory: true},
+		{name: "no history", noHistory: true, wantBlocks: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newSearchFixture()
+			sources := chatContextSources(store, config.ChatConfig{TopKDocuments: 2, TopKHistory: 3}, tt.noHistory)
+			var out bytes.Buffer
+
+			if err := runContextOnly(&out, &fakeEmbedder{}, store, "deploy", sources, chat.ContextBudget{}, true); err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+
+			var blocks []searchOutput
+			if err := json.Unmarshal(out.Bytes(), &blocks); err != nil {
+				t.Fatalf("Expected valid JSON, got error %v", err)
+			}
+			if len(blocks) != tt.wantBlocks {
+				t.Fatalf("Expected %d blocks, got %d: %+v", tt.wantBlocks, len(blocks), blocks)
+			}
+			last := blocks[len(blocks)-1]
+			if (last.Collection == "command_history") != tt.wantHistory {
+				t.Errorf("Expected history block present = %v, got last block from %s", tt.wantHistory, last.Collection)
+			}
+			if last.Rank != len(blocks) {
+				t.Errorf("Expected ranks to run across sources, got last rank %d", last.Rank)
+			}
+		})
+	}
+}
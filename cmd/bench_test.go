@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"rag-cli/internal/chunker"
+	"rag-cli/internal/vector"
+	"rag-cli/pkg/config"
+)
+
+// noopEmbedder returns a fixed embedding without recording anything, so the
+// benchmark measures the pipeline rather than the fake
+type noopEmbedder struct{}
+
+func (noopEmbedder) GenerateEmbedding(text string) ([]float32, error) {
+	return benchEmbedding, nil
+}
+
+var benchEmbedding = make([]float32, 768)
+
+// noopStore discards documents
+type noopStore struct {
+	vector.VectorStore
+}
+
+func (noopStore) AddDocument(collectionName, id, content string, embedding []float32) error {
+	return nil
+}
+
+func (noopStore) AddDocumentWithMetadata(collectionName, id, content string, embedding []float32, metadata map[string]interface{}) error {
+	return nil
+}
+
+func (noopStore) DocumentsCollection() string { return "documents" }
+
+// BenchmarkIndexPipeline runs the index pipeline end to end over a tree of
+// text and HTML files, with clients that do no work
+func BenchmarkIndexPipeline(b *testing.B) {
+	dir := b.TempDir()
+	paragraph := strings.Repeat("The quick brown fox jumps over the lazy dog. ", 20) + "\n\n"
+	text := strings.Repeat(paragraph, 50) // About 45KB
+	var files []string
+	var total int64
+	for i := 0; i < 40; i++ {
+		name := filepath.Join(dir, fmt.Sprintf("doc%02d.txt", i))
+		content := text
+		if i%4 == 0 {
+			name = filepath.Join(dir, fmt.Sprintf("page%02d.html", i))
+			content = "<html><head><title>Page</title></head><body><p>" + text + "</p></body></html>"
+		}
+		if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+			b.Fatalf("Failed to write %s: %v", name, err)
+		}
+		files = append(files, name)
+		total += int64(len(content))
+	}
+
+	chunkerClient := chunker.New(config.ChunkerConfig{ChunkSize: 1000, ChunkOverlap: 200})
+	process := func(file string) (int, error) {
+		return processFile(file, chunkerClient, noopEmbedder{}, noopStore{})
+	}
+
+	for _, workers := range []int{1, 4} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			b.SetBytes(total)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				result := indexFiles(context.Background(), io.Discard, files, workers, process)
+				if len(result.failures) > 0 {
+					b.Fatalf("Expected no failures, got %v", result.failures[0].err)
+				}
+			}
+		})
+	}
+}
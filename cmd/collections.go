@@ -0,0 +1,330 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"rag-cli/internal/vector"
+	"rag-cli/pkg/config"
+)
+
+var (
+	collectionsJSON bool
+	collectionsYes  bool
+)
+
+// collectionSampleSize is the number of document IDs shown by collections stats
+const collectionSampleSize = 5
+
+var collectionsCmd = &cobra.Command{
+	Use:   "collections",
+	Short: "List, inspect, clear, and drop vector store collections",
+	Long: `List the collections in ChromaDB with their IDs, document counts, and embedding
+dimensions where the server reports them, or manage one of them.
+
+The configured collections (documents, command history, and auto-indexed files) are
+listed first and labelled with their role, followed by any other collections found
+on the server, such as namespaced or per-project ones. A collection is named by its
+role (documents, commands, auto) or by its name on the server.
+
+EXAMPLES:
+  # Show a table of collections
+  rag-cli collections list
+
+  # JSON output for scripting
+  rag-cli collections --json
+
+  # Document count and a few IDs from the command history
+  rag-cli collections stats commands
+
+  # Forget every stored command session, e.g. when bad ones keep being retrieved
+  rag-cli collections clear commands
+
+  # Remove a collection left behind by an old project
+  rag-cli collections drop project-x --yes`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		vectorStore, err := newCollectionsStore()
+		if err != nil {
+			return err
+		}
+		return runCollections(os.Stdout, vectorStore, collectionsJSON)
+	},
+}
+
+var collectionsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List collections and their document counts",
+	Args:  cobra.NoArgs,
+	RunE:  collectionsCmd.RunE,
+}
+
+var collectionsStatsCmd = &cobra.Command{
+	Use:   "stats <name>",
+	Short: "Show the document count and a few document IDs of a collection",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		vectorStore, err := newCollectionsStore()
+		if err != nil {
+			return err
+		}
+		return runCollectionStats(os.Stdout, vectorStore, args[0], collectionsJSON)
+	},
+}
+
+var collectionsClearCmd = &cobra.Command{
+	Use:   "clear <name>",
+	Short: "Delete every document in a collection, keeping the collection",
+	Long: `Delete every document in a collection. The collection itself is kept, empty.
+
+You are asked to confirm first unless --yes is passed. To delete only some
+documents, see 'rag-cli purge' and 'rag-cli history prune'.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		vectorStore, err := newCollectionsStore()
+		if err != nil {
+			return err
+		}
+		return runCollectionClear(os.Stdin, os.Stdout, vectorStore, args[0], collectionsYes)
+	},
+}
+
+var collectionsDropCmd = &cobra.Command{
+	Use:   "drop <name>",
+	Short: "Remove a collection and everything in it",
+	Long: `Remove a collection and everything in it from ChromaDB. A configured collection
+is created again, empty, the next time rag-cli uses it.
+
+You are asked to confirm first unless --yes is passed.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		vectorStore, err := newCollectionsStore()
+		if err != nil {
+			return err
+		}
+		return runCollectionDrop(os.Stdin, os.Stdout, vectorStore, args[0], collectionsYes)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(collectionsCmd)
+	collectionsCmd.AddCommand(collectionsListCmd)
+	collectionsCmd.AddCommand(collectionsStatsCmd)
+	collectionsCmd.AddCommand(collectionsClearCmd)
+	collectionsCmd.AddCommand(collectionsDropCmd)
+
+	collectionsCmd.PersistentFlags().BoolVar(&collectionsJSON, "json", false, "Output collection information in JSON format")
+	for _, cmd := range []*cobra.Command{collectionsClearCmd, collectionsDropCmd} {
+		cmd.Flags().BoolVarP(&collectionsYes, "yes", "y", false, "Skip the confirmation prompt")
+	}
+}
+
+// newCollectionsStore connects to the vector store from the loaded config
+func newCollectionsStore() (*vector.ChromaClient, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	vectorStore, err := vector.NewChromaClient(cfg.Vector, cfg.Timeouts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize vector store: %w", err)
+	}
+	return vectorStore, nil
+}
+
+// collectionSummary is a collection together with its document count and role
+type collectionSummary struct {
+	Name      string `json:"name"`
+	ID        string `json:"id"`
+	Role      string `json:"role,omitempty"`
+	Documents int    `json:"documents"`
+	Dimension int    `json:"dimension,omitempty"`
+}
+
+func runCollections(out io.Writer, store vector.VectorStore, asJSON bool) error {
+	summaries, err := summarizeCollections(store)
+	if err != nil {
+		return err
+	}
+
+	if asJSON {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(summaries)
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tROLE\tDOCUMENTS\tDIMENSION\tID")
+	for _, summary := range summaries {
+		role := summary.Role
+		if role == "" {
+			role = "-"
+		}
+		dimension := "-"
+		if summary.Dimension > 0 {
+			dimension = fmt.Sprintf("%d", summary.Dimension)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\n", summary.Name, role, summary.Documents, dimension, summary.ID)
+	}
+	return w.Flush()
+}
+
+// summarizeCollections gathers counts for every collection, configured ones first
+func summarizeCollections(store vector.VectorStore) ([]collectionSummary, error) {
+	collections, err := store.ListCollections()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list collections: %w", err)
+	}
+
+	order := map[string]int{"documents": 0, "commands": 1, "auto": 2}
+
+	summaries := make([]collectionSummary, 0, len(collections))
+	for _, collection := range collections {
+		count, err := store.Count(collection.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count documents in %s: %w", collection.Name, err)
+		}
+		summaries = append(summaries, collectionSummary{
+			Name:      collection.Name,
+			ID:        collection.ID,
+			Role:      collectionRole(store, collection.Name),
+			Documents: count,
+			Dimension: collection.Dimension,
+		})
+	}
+
+	sort.SliceStable(summaries, func(i, j int) bool {
+		ri, iConfigured := order[summaries[i].Role]
+		rj, jConfigured := order[summaries[j].Role]
+		if iConfigured != jConfigured {
+			return iConfigured
+		}
+		if iConfigured {
+			return ri < rj
+		}
+		return summaries[i].Name < summaries[j].Name
+	})
+
+	return summaries, nil
+}
+
+// collectionRole returns the role of a configured collection, documents,
+// commands or auto, or "" for any other collection
+func collectionRole(store vector.VectorStore, name string) string {
+	switch name {
+	case store.DocumentsCollection():
+		return "documents"
+	case store.CommandsCollection():
+		return "commands"
+	case store.AutoIndexCollection():
+		return "auto"
+	}
+	return ""
+}
+
+// collectionStats is a collection's document count with a few of its IDs
+type collectionStats struct {
+	Name      string   `json:"name"`
+	Role      string   `json:"role,omitempty"`
+	Documents int      `json:"documents"`
+	SampleIDs []string `json:"sample_ids"`
+}
+
+func runCollectionStats(out io.Writer, store vector.VectorStore, collection string, asJSON bool) error {
+	name, err := resolveCollection(store, collection)
+	if err != nil {
+		return err
+	}
+	count, err := store.Count(name)
+	if err != nil {
+		return fmt.Errorf("failed to count documents in %s: %w", name, err)
+	}
+	docs, err := store.GetDocuments(name, collectionSampleSize)
+	if err != nil {
+		return fmt.Errorf("failed to read collection %s: %w", name, err)
+	}
+
+	stats := collectionStats{Name: name, Role: collectionRole(store, name), Documents: count, SampleIDs: []string{}}
+	for _, doc := range docs {
+		stats.SampleIDs = append(stats.SampleIDs, doc.ID)
+	}
+
+	if asJSON {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(stats)
+	}
+
+	fmt.Fprintf(out, "Collection: %s\n", name)
+	if stats.Role != "" {
+		fmt.Fprintf(out, "Role:       %s\n", stats.Role)
+	}
+	fmt.Fprintf(out, "Documents:  %d\n", count)
+	if len(stats.SampleIDs) > 0 {
+		fmt.Fprintf(out, "Sample IDs: %s\n", strings.Join(stats.SampleIDs, ", "))
+	}
+	return nil
+}
+
+func runCollectionClear(in io.Reader, out io.Writer, store vector.VectorStore, collection string, yes bool) error {
+	name, err := resolveCollection(store, collection)
+	if err != nil {
+		return err
+	}
+	count, err := store.Count(name)
+	if err != nil {
+		return fmt.Errorf("failed to count documents in %s: %w", name, err)
+	}
+	if count == 0 {
+		fmt.Fprintf(out, "%s is already empty\n", name)
+		return nil
+	}
+
+	if !yes {
+		fmt.Fprintf(out, "Delete all %d document(s) in %s? [y/N]: ", count, name)
+		if !confirm(in) {
+			fmt.Fprintln(out, "Clear cancelled")
+			return nil
+		}
+	}
+	if err := store.ResetCollection(name); err != nil {
+		return fmt.Errorf("failed to clear %s: %w", name, err)
+	}
+	fmt.Fprintf(out, "Cleared %d document(s) from %s\n", count, name)
+	return nil
+}
+
+func runCollectionDrop(in io.Reader, out io.Writer, store vector.VectorStore, collection string, yes bool) error {
+	name, err := resolveCollection(store, collection)
+	if err != nil {
+		return err
+	}
+	count, err := store.Count(name)
+	if err != nil && !errors.Is(err, vector.ErrCollectionNotFound) {
+		return fmt.Errorf("failed to count documents in %s: %w", name, err)
+	}
+
+	if !yes {
+		fmt.Fprintf(out, "Drop collection %s and its %d document(s)? [y/N]: ", name, count)
+		if !confirm(in) {
+			fmt.Fprintln(out, "Drop cancelled")
+			return nil
+		}
+	}
+	if err := store.DeleteCollection(name); err != nil {
+		return fmt.Errorf("failed to drop %s: %w", name, err)
+	}
+	fmt.Fprintf(out, "Dropped collection %s (%d document(s))\n", name, count)
+	if role := collectionRole(store, name); role != "" {
+		fmt.Fprintf(out, "It is the %s collection, so it will be created again, empty, when next used\n", role)
+	}
+	return nil
+}
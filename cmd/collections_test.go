@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"rag-cli/internal/vector"
+)
+
+func newCollectionsFixture() *fakeStore {
+	store := newFakeStore()
+	store.collections = []vector.CollectionInfo{
+		{Name: "project-x", ID: "id-px"},
+		{Name: "auto_indexed", ID: "id-auto"},
+		{Name: "documents", ID: "id-docs", Dimension: 384},
+		{Name: "command_history", ID: "id-cmds"},
+		{Name: "archive", ID: "id-arch", Dimension: 768},
+	}
+	store.counts = map[string]int{
+		"documents":       120,
+		"command_history": 14,
+		"auto_indexed":    3,
+		"project-x":       42,
+		"archive":         0,
+	}
+	return store
+}
+
+func TestRunCollections_Table(t *testing.T) {
+	var out bytes.Buffer
+	if err := runCollections(&out, newCollectionsFixture(), false); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 6 {
+		t.Fatalf("Expected header and 5 rows, got %d lines:\n%s", len(lines), out.String())
+	}
+
+	expectedOrder := []string{"NAME", "documents", "command_history", "auto_indexed", "archive", "project-x"}
+	for i, name := range expectedOrder {
+		if fields := strings.Fields(lines[i]); fields[0] != name {
+			t.Errorf("Expected row %d to be %s, got: %s", i, name, lines[i])
+		}
+	}
+
+	if fields := strings.Fields(lines[1]); strings.Join(fields, " ") != "documents documents 120 384 id-docs" {
+		t.Errorf("Unexpected documents row: %s", lines[1])
+	}
+	if fields := strings.Fields(lines[5]); strings.Join(fields, " ") != "project-x - 42 - id-px" {
+		t.Errorf("Unexpected namespaced row: %s", lines[5])
+	}
+}
+
+func TestRunCollections_JSON(t *testing.T) {
+	var out bytes.Buffer
+	if err := runCollections(&out, newCollectionsFixture(), true); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	var summaries []collectionSummary
+	if err := json.Unmarshal(out.Bytes(), &summaries); err != nil {
+		t.Fatalf("Expected valid JSON, got error %v for:\n%s", err, out.String())
+	}
+	if len(summaries) != 5 {
+		t.Fatalf("Expected 5 collections, got %d", len(summaries))
+	}
+	if summaries[1].Name != "command_history" || summaries[1].Role != "commands" || summaries[1].Documents != 14 {
+		t.Errorf("Unexpected command history summary: %+v", summaries[1])
+	}
+	if summaries[4].Role != "" {
+		t.Errorf("Expected namespaced collection to have no role, got %q", summaries[4].Role)
+	}
+}
+
+func TestRunCollectionStats(t *testing.T) {
+	store := newCollectionsFixture()
+	for i := 1; i <= 7; i++ {
+		store.documents["command_history"] = append(store.documents["command_history"], vector.StoredDocument{ID: fmt.Sprintf("cmd_session_%d", i)})
+	}
+
+	var out bytes.Buffer
+	if err := runCollectionStats(&out, store, "commands", false); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	for _, want := range []string{"Collection: command_history", "Role:       commands", "Documents:  14", "Sample IDs: cmd_session_1, cmd_session_2, cmd_session_3, cmd_session_4, cmd_session_5\n"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, out.String())
+		}
+	}
+
+	out.Reset()
+	if err := runCollectionStats(&out, store, "archive", true); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	var stats collectionStats
+	if err := json.Unmarshal(out.Bytes(), &stats); err != nil {
+		t.Fatalf("Expected valid JSON, got error %v for:\n%s", err, out.String())
+	}
+	if stats.Name != "archive" || stats.Role != "" || stats.Documents != 0 || len(stats.SampleIDs) != 0 {
+		t.Errorf("Unexpected stats for an empty collection: %+v", stats)
+	}
+
+	if err := runCollectionStats(&out, store, "missing", false); !errors.Is(err, vector.ErrCollectionNotFound) {
+		t.Errorf("Expected ErrCollectionNotFound for an unknown collection, got: %v", err)
+	}
+}
+
+func TestRunCollectionClear(t *testing.T) {
+	t.Run("declined", func(t *testing.T) {
+		store := newCollectionsFixture()
+		var out bytes.Buffer
+		if err := runCollectionClear(strings.NewReader("n\n"), &out, store, "commands", false); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if !strings.Contains(out.String(), "Delete all 14 document(s) in command_history? [y/N]") || !strings.Contains(out.String(), "Clear cancelled") {
+			t.Errorf("Expected a prompt and cancellation, got:\n%s", out.String())
+		}
+		if len(store.reset) != 0 {
+			t.Errorf("Expected nothing cleared, got %v", store.reset)
+		}
+	})
+
+	t.Run("confirmed", func(t *testing.T) {
+		store := newCollectionsFixture()
+		var out bytes.Buffer
+		if err := runCollectionClear(strings.NewReader("y\n"), &out, store, "commands", false); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if len(store.reset) != 1 || store.reset[0] != "command_history" {
+			t.Errorf("Expected command_history to be cleared, got %v", store.reset)
+		}
+		if !strings.Contains(out.String(), "Cleared 14 document(s) from command_history") {
+			t.Errorf("Expected a summary, got:\n%s", out.String())
+		}
+	})
+
+	t.Run("already empty", func(t *testing.T) {
+		store := newCollectionsFixture()
+		var out bytes.Buffer
+		if err := runCollectionClear(strings.NewReader(""), &out, store, "archive", true); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if len(store.reset) != 0 || !strings.Contains(out.String(), "archive is already empty") {
+			t.Errorf("Expected nothing to do, got %v:\n%s", store.reset, out.String())
+		}
+	})
+}
+
+func TestRunCollectionDrop(t *testing.T) {
+	store := newCollectionsFixture()
+	var out bytes.Buffer
+	if err := runCollectionDrop(strings.NewReader(""), &out, store, "project-x", false); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(store.dropped) != 0 || !strings.Contains(out.String(), "Drop cancelled") {
+		t.Errorf("Expected the drop to need confirmation, got %v:\n%s", store.dropped, out.String())
+	}
+
+	out.Reset()
+	if err := runCollectionDrop(strings.NewReader(""), &out, store, "project-x", true); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(store.dropped) != 1 || store.dropped[0] != "project-x" {
+		t.Errorf("Expected project-x to be dropped, got %v", store.dropped)
+	}
+	if !strings.Contains(out.String(), "Dropped collection project-x (42 document(s))") || strings.Contains(out.String(), "created again") {
+		t.Errorf("Unexpected output:\n%s", out.String())
+	}
+
+	out.Reset()
+	if err := runCollectionDrop(strings.NewReader("yes\n"), &out, store, "auto", false); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !strings.Contains(out.String(), "It is the auto collection, so it will be created again, empty, when next used") {
+		t.Errorf("Expected a note that the configured collection comes back, got:\n%s", out.String())
+	}
+}
@@ -0,0 +1,478 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"rag-cli/pkg/config"
+)
+
+var (
+	configShowJSON bool
+	configProject  bool
+
+	configInitForce       bool
+	configInitInteractive bool
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect and manage rag-cli configuration",
+	Long: `Inspect and manage rag-cli configuration.
+
+Settings are resolved from several layers, highest precedence first:
+command-line flags, environment variables, the project config file, the user
+config file, and built-in defaults.
+
+The user config file is config.yaml in the rag-cli config directory:
+$XDG_CONFIG_HOME/rag-cli, ~/.config/rag-cli on Linux, ~/Library/Application
+Support/rag-cli on macOS, or %AppData%\rag-cli on Windows. A ~/.rag-cli.yaml from
+earlier versions is still used when that file does not exist.
+
+The project config file is the first .rag-cli.yaml or .rag-cli/config.yaml found
+in the current directory or one of its parents, stopping below your home
+directory. Check it into a repository to share settings such as the collection
+or auto-index extensions with everyone working on it.
+
+--config <file> loads that file instead of both the user and project config files.`,
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Show effective configuration values and where they came from",
+	Long: `Print every effective configuration setting together with its source:
+default, file, env, or flag. Values from a config file name the file, so project
+settings can be told apart from your own. Secrets such as API keys are redacted.
+
+This command only reads configuration, so it works even when Ollama or
+ChromaDB are not running.
+
+EXAMPLES:
+  # Table of settings and their sources
+  rag-cli config show
+
+  # JSON output for scripting
+  rag-cli config show --json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := loadSettings(os.Stderr); err != nil {
+			return err
+		}
+		userFile, projectFile := config.ConfigFiles()
+		return runConfigShow(os.Stdout, userFile, projectFile, config.EffectiveSettings(), configShowJSON)
+	},
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: "Print the effective value of a configuration key",
+	Long: `Print the effective value of a single configuration key, after applying
+defaults, the config file, environment variables, and flags. Secrets are redacted.
+
+EXAMPLES:
+  rag-cli config get llm.model
+  rag-cli config get auto_index.extensions`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := loadSettings(os.Stderr); err != nil {
+			return err
+		}
+		return runConfigGet(os.Stdout, config.EffectiveSettings(), args[0])
+	},
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Set a configuration key in the config file",
+	Long: `Set a configuration key in the user config file, or in the project config file when
+--project is passed (./.rag-cli.yaml is created if no project file is found).
+A project file may only hold project settings such as the collections and
+auto_index, and safety settings that make rag-cli stricter.
+The key must be a known setting and the value must match its type. List settings
+take comma-separated values. Other keys and comments in the file are kept.
+
+EXAMPLES:
+  # Switch the LLM model
+  rag-cli config set llm.model llama3
+
+  # Enable auto-indexing for this project only
+  rag-cli config set auto_index.enabled true --project
+
+  # Set a list value
+  rag-cli config set auto_index.extensions .go,.md,.txt`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := configFilePath(configProject)
+		if err != nil {
+			return err
+		}
+		return runConfigSet(os.Stdout, path, args[0], args[1], configProject)
+	},
+}
+
+var configUnsetCmd = &cobra.Command{
+	Use:   "unset <key>",
+	Short: "Remove a configuration key from the config file",
+	Long: `Remove a configuration key from the user config file, or from the project config file
+when --project is passed, so that a lower layer or the default applies again.
+
+EXAMPLES:
+  rag-cli config unset llm.model
+  rag-cli config unset auto_index.enabled --project`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := configFilePath(configProject)
+		if err != nil {
+			return err
+		}
+		return runConfigUnset(os.Stdout, path, args[0])
+	},
+}
+
+var configInitCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Create a commented user config file with the default settings",
+	Long: `Write a commented user config file populated with the default settings, to
+config.yaml in the rag-cli config directory (see 'rag-cli config --help'). An existing file is never replaced unless --force is passed.
+
+With --interactive you are asked for the most commonly changed settings
+(LLM model, ChromaDB host, and whether to enable auto-indexing) first.
+
+EXAMPLES:
+  # Write the defaults
+  rag-cli config init
+
+  # Answer a few questions first
+  rag-cli config init --interactive
+
+  # Replace an existing config file
+  rag-cli config init --force`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := config.UserConfigPath()
+		if err != nil {
+			return err
+		}
+		return runConfigInit(os.Stdin, os.Stdout, path, configInitForce, configInitInteractive)
+	},
+}
+
+var configDefaultsCmd = &cobra.Command{
+	Use:   "defaults",
+	Short: "Print the built-in default configuration as commented YAML",
+	Long: `Print the built-in default configuration as a commented YAML config file.
+Config files, environment variables and flags are ignored, so the output only
+changes when rag-cli's defaults do. Diff it against your config file to see
+what you have changed.
+
+EXAMPLES:
+  # Show the defaults
+  rag-cli config defaults
+
+  # Compare them with your config file
+  diff <(rag-cli config defaults) ~/.config/rag-cli/config.yaml`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runConfigDefaults(os.Stdout)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configInitCmd)
+	configCmd.AddCommand(configDefaultsCmd)
+	configCmd.AddCommand(configShowCmd)
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configSetCmd)
+	configCmd.AddCommand(configUnsetCmd)
+
+	configInitCmd.Flags().BoolVar(&configInitForce, "force", false, "Overwrite an existing config file")
+	configInitCmd.Flags().BoolVarP(&configInitInteractive, "interactive", "i", false, "Prompt for common settings before writing")
+	configShowCmd.Flags().BoolVar(&configShowJSON, "json", false, "Output settings in JSON format")
+	configSetCmd.Flags().BoolVar(&configProject, "project", false, "Write to the project config file found above the current directory, or create one here")
+	configUnsetCmd.Flags().BoolVar(&configProject, "project", false, "Edit the project config file found above the current directory")
+}
+
+func runConfigDefaults(out io.Writer) error {
+	defaults, err := config.DefaultConfig()
+	if err != nil {
+		return err
+	}
+	data, err := config.RenderConfig(defaults)
+	if err != nil {
+		return err
+	}
+	_, err = out.Write(data)
+	return err
+}
+
+// configFilePath returns the config file edited by set and unset
+func configFilePath(project bool) (string, error) {
+	if project {
+		return config.ProjectConfigPath()
+	}
+	return config.UserConfigPath()
+}
+
+// configShowOutput is the JSON representation of config show
+type configShowOutput struct {
+	ConfigFile    string           `json:"config_file"`
+	ProjectFile   string           `json:"project_file,omitempty"`
+	CustomPrompts []string         `json:"custom_prompts,omitempty"`
+	Safety        string           `json:"safety,omitempty"`
+	Settings      []config.Setting `json:"settings"`
+}
+
+func runConfigShow(out io.Writer, configFile, projectFile string, settings []config.Setting, asJSON bool) error {
+	if asJSON {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(configShowOutput{ConfigFile: configFile, ProjectFile: projectFile, CustomPrompts: customPrompts(settings), Safety: safetySummary(settings), Settings: settings})
+	}
+
+	if configFile == "" {
+		configFile = "(none, using defaults)"
+	}
+	fmt.Fprintf(out, "Config file: %s\n", configFile)
+	if projectFile != "" {
+		fmt.Fprintf(out, "Project config file: %s\n", projectFile)
+	}
+	if prompts := customPrompts(settings); len(prompts) > 0 {
+		fmt.Fprintf(out, "Custom prompts: %s\n", strings.Join(prompts, ", "))
+	}
+	if summary := safetySummary(settings); summary != "" {
+		fmt.Fprintf(out, "Safety rules: %s\n", summary)
+	}
+	fmt.Fprintln(out)
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KEY\tVALUE\tSOURCE")
+	for _, setting := range settings {
+		source := string(setting.Source)
+		if setting.File != "" {
+			source = fmt.Sprintf("%s (%s)", setting.Source, setting.File)
+		}
+		fmt.Fprintf(w, "%s\t%v\t%s\n", setting.Key, setting.Value, source)
+	}
+	return w.Flush()
+}
+
+// customPrompts lists the prompts replaced by template files, naming the
+// model for per-model overrides
+func customPrompts(settings []config.Setting) []string {
+	var prompts []string
+	for _, setting := range settings {
+		if setting.Key == "prompts.models" {
+			overrides, _ := setting.Value.([]interface{})
+			for _, override := range overrides {
+				fields, _ := override.(map[string]interface{})
+				for _, name := range config.PromptNames() {
+					if path, _ := fields[name].(string); path != "" {
+						prompts = append(prompts, fmt.Sprintf("%s (%v)", name, fields["model"]))
+					}
+				}
+			}
+			continue
+		}
+		if name, ok := strings.CutPrefix(setting.Key, "prompts."); ok {
+			if path, _ := setting.Value.(string); path != "" {
+				prompts = append(prompts, name)
+			}
+		}
+	}
+	return prompts
+}
+
+// safetySummary describes the safety policy and chat.commands added to the
+// built-in rules, or returns "" when there are none
+func safetySummary(settings []config.Setting) string {
+	var blocked, warned, dangerous, allowed int
+	var modes []string
+	for _, setting := range settings {
+		switch setting.Key {
+		case "safety.blocklist":
+			rules, _ := setting.Value.([]interface{})
+			for _, rule := range rules {
+				fields, _ := rule.(map[string]interface{})
+				if fields["severity"] == config.SeverityWarn {
+					warned++
+				} else {
+					blocked++
+				}
+			}
+		case "safety.dangerous":
+			rules, _ := setting.Value.([]interface{})
+			dangerous = len(rules)
+		case "safety.allowlist":
+			allowed = listLength(setting.Value)
+		case "chat.commands.allowlist":
+			if n := listLength(setting.Value); n > 0 {
+				modes = append(modes, fmt.Sprintf("%d permitted program(s)", n))
+			}
+		case "chat.commands.denylist":
+			if n := listLength(setting.Value); n > 0 {
+				modes = append(modes, fmt.Sprintf("%d denied program(s)", n))
+			}
+		case "safety.read_only":
+			if enabled, _ := setting.Value.(bool); enabled {
+				modes = append(modes, "read-only")
+			}
+		case "safety.require_typed_confirmation":
+			if enabled, _ := setting.Value.(bool); enabled {
+				modes = append(modes, "typed confirmation")
+			}
+		}
+	}
+
+	var parts []string
+	for _, count := range []struct {
+		n    int
+		what string
+	}{{blocked, "blocked"}, {warned, "warned"}, {dangerous, "dangerous"}, {allowed, "allowed"}} {
+		if count.n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", count.n, count.what))
+		}
+	}
+	summary := ""
+	if len(parts) > 0 {
+		summary = strings.Join(parts, ", ") + " pattern(s)"
+	}
+	if len(modes) > 0 {
+		if summary != "" {
+			summary += "; "
+		}
+		summary += strings.Join(modes, ", ")
+	}
+	return summary
+}
+
+// listLength returns the number of entries in a list setting
+func listLength(value interface{}) int {
+	switch list := value.(type) {
+	case []string:
+		return len(list)
+	case []interface{}:
+		return len(list)
+	}
+	return 0
+}
+
+func runConfigGet(out io.Writer, settings []config.Setting, key string) error {
+	for _, setting := range settings {
+		if setting.Key == key {
+			fmt.Fprintln(out, formatSettingValue(setting.Value))
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown config key %q (run 'rag-cli config show' to list keys)", key)
+}
+
+func runConfigSet(out io.Writer, path, key, raw string, project bool) error {
+	if project && !config.ProjectScoped(key) {
+		return fmt.Errorf("%s cannot be set in a project config file; set it in your user config instead", key)
+	}
+	value, err := config.ParseValue(key, raw)
+	if err != nil {
+		return err
+	}
+	if err := config.SetValue(path, key, value); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Set %s = %s in %s\n", key, formatSettingValue(value), path)
+	return nil
+}
+
+func runConfigUnset(out io.Writer, path, key string) error {
+	removed, err := config.UnsetValue(path, key)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		fmt.Fprintf(out, "%s is not set in %s\n", key, path)
+		return nil
+	}
+	fmt.Fprintf(out, "Removed %s from %s\n", key, path)
+	return nil
+}
+
+// formatSettingValue renders lists as comma-separated values, the same form
+// config set accepts
+func formatSettingValue(value interface{}) string {
+	switch v := value.(type) {
+	case []string:
+		return strings.Join(v, ",")
+	case []interface{}:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = fmt.Sprint(item)
+		}
+		return strings.Join(items, ",")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func runConfigInit(in io.Reader, out io.Writer, path string, force, interactive bool) error {
+	if _, err := os.Stat(path); err == nil && !force {
+		return fmt.Errorf("%s already exists (use --force to overwrite)", path)
+	}
+
+	cfg, err := config.DefaultConfig()
+	if err != nil {
+		return err
+	}
+
+	if interactive {
+		reader := bufio.NewReader(in)
+		cfg.LLM.Model = promptValue(reader, out, "LLM model", cfg.LLM.Model)
+		cfg.Vector.Host = promptValue(reader, out, "ChromaDB host", cfg.Vector.Host)
+		answer := promptValue(reader, out, "Enable auto-indexing of changed files? (y/n)", "n")
+		cfg.AutoIndex.Enabled = strings.HasPrefix(strings.ToLower(answer), "y")
+	}
+
+	data, err := config.RenderConfig(cfg)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+
+	fmt.Fprintf(out, "Wrote %s\n", path)
+	return nil
+}
+
+// promptValue asks for a value, returning def when the answer is empty
+func promptValue(reader *bufio.Reader, out io.Writer, label, def string) string {
+	fmt.Fprintf(out, "%s [%s]: ", label, def)
+	answer, _ := reader.ReadString('\n')
+	if answer = strings.TrimSpace(answer); answer != "" {
+		return answer
+	}
+	return def
+}
+
+// loadSettings loads configuration for the read-only config commands. Invalid
+// settings are reported as a warning so they can still be inspected.
+func loadSettings(warn io.Writer) error {
+	if _, err := config.Load(); err != nil {
+		var validationErr *config.ValidationError
+		if !errors.As(err, &validationErr) {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		fmt.Fprintf(warn, "Warning: %v\n", err)
+	}
+	return nil
+}
@@ -0,0 +1,227 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"rag-cli/pkg/config"
+)
+
+// readGeneratedConfig parses a config file written by config init
+func readGeneratedConfig(t *testing.T, path string) config.Config {
+	t.Helper()
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		t.Fatalf("Generated config does not parse: %v", err)
+	}
+	var cfg config.Config
+	if err := v.Unmarshal(&cfg); err != nil {
+		t.Fatalf("Failed to unmarshal generated config: %v", err)
+	}
+	return cfg
+}
+
+func TestRunConfigInit(t *testing.T) {
+	t.Run("writes defaults", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), ".rag-cli.yaml")
+		var out bytes.Buffer
+		if err := runConfigInit(strings.NewReader(""), &out, path, false, false); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		defaults, _ := config.DefaultConfig()
+		cfg := readGeneratedConfig(t, path)
+		if cfg.LLM.Model != defaults.LLM.Model || cfg.Vector.Port != defaults.Vector.Port {
+			t.Errorf("Expected default values, got %+v", cfg)
+		}
+		if !strings.Contains(out.String(), "Wrote "+path) {
+			t.Errorf("Expected confirmation message, got %q", out.String())
+		}
+	})
+
+	t.Run("refuses to overwrite without force", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), ".rag-cli.yaml")
+		if err := os.WriteFile(path, []byte("llm:\n  model: mine\n"), 0644); err != nil {
+			t.Fatalf("Failed to write config file: %v", err)
+		}
+
+		err := runConfigInit(strings.NewReader(""), &bytes.Buffer{}, path, false, false)
+		if err == nil || !strings.Contains(err.Error(), "--force") {
+			t.Errorf("Expected an error mentioning --force, got %v", err)
+		}
+		if cfg := readGeneratedConfig(t, path); cfg.LLM.Model != "mine" {
+			t.Errorf("Expected existing file to be untouched, got model %q", cfg.LLM.Model)
+		}
+
+		if err := runConfigInit(strings.NewReader(""), &bytes.Buffer{}, path, true, false); err != nil {
+			t.Fatalf("Unexpected error with force: %v", err)
+		}
+		if cfg := readGeneratedConfig(t, path); cfg.LLM.Model == "mine" {
+			t.Error("Expected --force to overwrite the existing file")
+		}
+	})
+
+	t.Run("interactive answers", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), ".rag-cli.yaml")
+		in := strings.NewReader("llama3\n\ny\n")
+		if err := runConfigInit(in, &bytes.Buffer{}, path, false, true); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		cfg := readGeneratedConfig(t, path)
+		if cfg.LLM.Model != "llama3" {
+			t.Errorf("Expected model llama3, got %q", cfg.LLM.Model)
+		}
+		if cfg.Vector.Host != "localhost" {
+			t.Errorf("Expected empty answer to keep default host, got %q", cfg.Vector.Host)
+		}
+		if !cfg.AutoIndex.Enabled {
+			t.Error("Expected auto-indexing to be enabled")
+		}
+	})
+}
+
+func TestRunConfigSetAndGet(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".rag-cli.yaml")
+
+	if err := runConfigSet(&bytes.Buffer{}, path, "auto_index.extensions", ".go,.md", true); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := runConfigSet(&bytes.Buffer{}, path, "vector.port", "not-a-port", false); err == nil {
+		t.Error("Expected an error for a non-integer port")
+	}
+	if err := runConfigSet(&bytes.Buffer{}, path, "chat.always_allow", "rm", true); err == nil || !strings.Contains(err.Error(), "project config file") {
+		t.Errorf("Expected chat.always_allow to be refused in a project file, got: %v", err)
+	}
+
+	settings := []config.Setting{{Key: "auto_index.extensions", Value: readGeneratedConfig(t, path).AutoIndex.Extensions}}
+	var out bytes.Buffer
+	if err := runConfigGet(&out, settings, "auto_index.extensions"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := strings.TrimSpace(out.String()); got != ".go,.md" {
+		t.Errorf("Expected .go,.md, got %q", got)
+	}
+
+	if err := runConfigGet(&bytes.Buffer{}, settings, "nope"); err == nil {
+		t.Error("Expected an error for an unknown key")
+	}
+}
+
+func TestRunConfigDefaults(t *testing.T) {
+	var out bytes.Buffer
+	if err := runConfigDefaults(&out); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "defaults.yaml")
+	if err := os.WriteFile(path, out.Bytes(), 0644); err != nil {
+		t.Fatalf("Failed to write defaults: %v", err)
+	}
+	cfg := readGeneratedConfig(t, path)
+
+	// The same values config_test.go asserts for the defaults
+	if cfg.Vector.Host != "localhost" {
+		t.Errorf("Expected default vector host to be 'localhost', got '%s'", cfg.Vector.Host)
+	}
+	if cfg.Vector.Port != 8000 {
+		t.Errorf("Expected default vector port to be 8000, got %d", cfg.Vector.Port)
+	}
+	if cfg.Chunker.ChunkSize != 1000 {
+		t.Errorf("Expected default chunk size to be 1000, got %d", cfg.Chunker.ChunkSize)
+	}
+	if cfg.Chunker.ChunkOverlap != 200 {
+		t.Errorf("Expected default chunk overlap to be 200, got %d", cfg.Chunker.ChunkOverlap)
+	}
+	expectedChat := config.ChatConfig{MaxAttempts: 3, MaxOutputLines: 50, TruncateOutput: true, TopKDocuments: 5, TopKHistory: 3, MemoryChars: 4000, MaxContextChars: 12000, ContextItemChars: 2000, CommandTimeout: time.Minute, ShowSources: true, TranscriptMaxOutput: 10000, AlwaysAllowMatch: config.ApprovalMatchProgram}
+	gotChat := cfg.Chat
+	gotChat.Commands = config.CommandPolicyConfig{}
+	gotChat.AlwaysAllow = nil
+	if !reflect.DeepEqual(gotChat, expectedChat) || len(cfg.Chat.Commands.Allowlist) != 0 || len(cfg.Chat.Commands.Denylist) != 0 || len(cfg.Chat.AlwaysAllow) != 0 {
+		t.Errorf("Expected chat defaults %+v, got %+v", expectedChat, cfg.Chat)
+	}
+	expectedTimeouts := config.TimeoutsConfig{LLM: 5 * time.Minute, Embeddings: 30 * time.Second, Vector: 30 * time.Second, Dial: 10 * time.Second, TLSHandshake: 10 * time.Second}
+	if cfg.Timeouts != expectedTimeouts {
+		t.Errorf("Expected timeout defaults %+v, got %+v", expectedTimeouts, cfg.Timeouts)
+	}
+}
+
+func TestCustomPrompts(t *testing.T) {
+	settings := []config.Setting{
+		{Key: "prompts.command_generation", Value: ""},
+		{Key: "prompts.goal_check", Value: "/prompts/goal.tmpl"},
+		{Key: "prompts.models", Value: []interface{}{
+			map[string]interface{}{"model": "llama3", "final_answer": "/prompts/answer.tmpl"},
+		}},
+		{Key: "vector.host", Value: "localhost"},
+	}
+
+	expected := []string{"goal_check", "final_answer (llama3)"}
+	if got := customPrompts(settings); strings.Join(got, ", ") != strings.Join(expected, ", ") {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+
+	var out bytes.Buffer
+	if err := runConfigShow(&out, "", "", settings, false); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), "Custom prompts: goal_check, final_answer (llama3)") {
+		t.Errorf("Expected custom prompts to be listed, got:\n%s", out.String())
+	}
+}
+
+func TestSafetySummary(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings []config.Setting
+		expected string
+	}{
+		{
+			name: "defaults",
+			settings: []config.Setting{
+				{Key: "safety.blocklist", Value: []interface{}{}},
+				{Key: "safety.allowlist", Value: []string{}},
+				{Key: "safety.read_only", Value: false},
+				{Key: "safety.require_typed_confirmation", Value: false},
+			},
+			expected: "",
+		},
+		{
+			name: "rules and modes",
+			settings: []config.Setting{
+				{Key: "safety.blocklist", Value: []interface{}{
+					map[string]interface{}{"pattern": "git push --force*"},
+					map[string]interface{}{"pattern": "kubectl *", "severity": "warn"},
+				}},
+				{Key: "safety.allowlist", Value: []interface{}{"make *"}},
+				{Key: "safety.read_only", Value: true},
+				{Key: "safety.require_typed_confirmation", Value: true},
+			},
+			expected: "1 blocked, 1 warned, 1 allowed pattern(s); read-only, typed confirmation",
+		},
+		{
+			name: "command policy",
+			settings: []config.Setting{
+				{Key: "chat.commands.allowlist", Value: []interface{}{"ls", "git"}},
+				{Key: "chat.commands.denylist", Value: []string{}},
+				{Key: "safety.allowlist", Value: []string{}},
+			},
+			expected: "2 permitted program(s)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := safetySummary(tt.settings); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,236 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/cobra/doc"
+	"rag-cli/pkg/version"
+)
+
+var (
+	docsFormat        string
+	docsOutputDir     string
+	docsExcludeHidden bool
+	docsFrontMatter   []string
+)
+
+// docsCmd generates documentation for all commands
+var docsCmd = &cobra.Command{
+	Use:   "docs [command...]",
+	Short: "Generate documentation for all commands",
+	Long: `Generate documentation for all commands and subcommands.
+
+Name one or more top-level commands to document only those commands and their
+subcommands. Hidden commands are left out unless --exclude-hidden=false is passed.
+
+Formats:
+  md     Markdown, one file per command (default)
+  man    Man pages in section 1, dated from the build date, for packaging
+  rest   reStructuredText, one file per command
+
+Markdown files can start with YAML front matter for static site generators such
+as Hugo or Jekyll. Each --front-matter key=value pair is added after a title
+field set to the command path.
+
+EXAMPLES:
+  # Regenerate the Markdown docs in ./docs
+  rag-cli docs
+
+  # Build man pages for a package
+  rag-cli docs --format man --output-dir build/man/man1
+
+  # Document only the search and index commands
+  rag-cli docs search index --output-dir build/docs
+
+  # Markdown pages for a Hugo site
+  rag-cli docs --output-dir site/content/cli --front-matter layout=cli --front-matter weight=20`,
+	Hidden:       true, // Hidden from help output
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runDocs(os.Stdout, rootCmd, docsOptions{
+			format:        docsFormat,
+			dir:           docsOutputDir,
+			excludeHidden: docsExcludeHidden,
+			commands:      args,
+			frontMatter:   docsFrontMatter,
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(docsCmd)
+
+	docsCmd.Flags().StringVar(&docsFormat, "format", "md", "Documentation format: md, man, or rest")
+	docsCmd.Flags().StringVar(&docsOutputDir, "output-dir", "./docs", "Directory to write the documentation to, created if needed")
+	docsCmd.Flags().BoolVar(&docsExcludeHidden, "exclude-hidden", true, "Leave hidden commands out of the documentation")
+	docsCmd.Flags().StringArrayVar(&docsFrontMatter, "front-matter", nil, "Add a key=value field to YAML front matter at the top of each Markdown file (repeatable)")
+}
+
+type docsOptions struct {
+	format        string
+	dir           string
+	excludeHidden bool
+	commands      []string // Top-level commands to document; empty documents the whole tree
+	frontMatter   []string // key=value pairs for Markdown front matter
+}
+
+func runDocs(out io.Writer, root *cobra.Command, opts docsOptions) error {
+	var (
+		extension string
+		generate  func(cmd *cobra.Command, w io.Writer) error
+	)
+	switch opts.format {
+	case "md", "markdown":
+		extension = ".md"
+		generate = doc.GenMarkdown
+	case "man":
+		header := manHeader(version.GetBuildInfo())
+		extension = "." + header.Section
+		generate = func(cmd *cobra.Command, w io.Writer) error {
+			headerCopy := *header
+			return doc.GenMan(cmd, &headerCopy, w)
+		}
+	case "rest", "rst":
+		extension = ".rst"
+		generate = doc.GenReST
+	default:
+		return fmt.Errorf("unknown format %q (expected md, man, or rest)", opts.format)
+	}
+
+	frontMatter, err := parseFrontMatter(opts.frontMatter)
+	if err != nil {
+		return err
+	}
+	if len(frontMatter) > 0 && extension != ".md" {
+		return fmt.Errorf("--front-matter is only supported for Markdown")
+	}
+
+	commands, err := docCommands(root, opts.commands, opts.excludeHidden)
+	if err != nil {
+		return err
+	}
+
+	// Create docs directory if it doesn't exist
+	if err := os.MkdirAll(opts.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create docs directory: %w", err)
+	}
+
+	for _, cmd := range commands {
+		separator := "_"
+		if opts.format == "man" {
+			separator = "-"
+		}
+		name := strings.ReplaceAll(cmd.CommandPath(), " ", separator) + extension
+
+		if err := writeDoc(filepath.Join(opts.dir, name), cmd, frontMatter, generate); err != nil {
+			return fmt.Errorf("failed to generate documentation for %s: %w", cmd.CommandPath(), err)
+		}
+	}
+
+	fmt.Fprintf(out, "Documentation for %d command(s) generated in %s/\n", len(commands), opts.dir)
+	return nil
+}
+
+// writeDoc writes one command's documentation, preceded by front matter when
+// any fields are given
+func writeDoc(path string, cmd *cobra.Command, frontMatter [][2]string, generate func(*cobra.Command, io.Writer) error) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if len(frontMatter) > 0 {
+		fmt.Fprintln(file, "---")
+		fmt.Fprintf(file, "title: %q\n", cmd.CommandPath())
+		for _, field := range frontMatter {
+			fmt.Fprintf(file, "%s: %q\n", field[0], field[1])
+		}
+		fmt.Fprintln(file, "---")
+		fmt.Fprintln(file)
+	}
+
+	if err := generate(cmd, file); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// parseFrontMatter splits key=value pairs, keeping their order
+func parseFrontMatter(pairs []string) ([][2]string, error) {
+	fields := make([][2]string, 0, len(pairs))
+	for _, pair := range pairs {
+		key, value, ok := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid --front-matter %q (expected key=value)", pair)
+		}
+		if key == "title" {
+			return nil, fmt.Errorf("--front-matter cannot set title; it is the command path")
+		}
+		fields = append(fields, [2]string{key, value})
+	}
+	return fields, nil
+}
+
+// docCommands lists the commands to document: the whole tree from root, or
+// the named top-level commands and their subcommands
+func docCommands(root *cobra.Command, names []string, excludeHidden bool) ([]*cobra.Command, error) {
+	if len(names) == 0 {
+		return appendDocCommands(nil, root, excludeHidden), nil
+	}
+
+	var commands []*cobra.Command
+	for _, name := range names {
+		var found *cobra.Command
+		for _, cmd := range root.Commands() {
+			if cmd.Name() == name || cmd.HasAlias(name) {
+				found = cmd
+				break
+			}
+		}
+		if found == nil {
+			return nil, fmt.Errorf("unknown command %q", name)
+		}
+		if excludeHidden && found.Hidden {
+			return nil, fmt.Errorf("command %q is hidden; pass --exclude-hidden=false to document it", name)
+		}
+		commands = appendDocCommands(commands, found, excludeHidden)
+	}
+	return commands, nil
+}
+
+// appendDocCommands adds cmd and its documented subcommands, skipping help
+// and deprecated commands the way cobra's tree generators do
+func appendDocCommands(commands []*cobra.Command, cmd *cobra.Command, excludeHidden bool) []*cobra.Command {
+	commands = append(commands, cmd)
+	for _, child := range cmd.Commands() {
+		documented := child.IsAvailableCommand() || (child.Hidden && !excludeHidden && child.Deprecated == "")
+		if !documented || child.IsAdditionalHelpTopicCommand() {
+			continue
+		}
+		commands = appendDocCommands(commands, child, excludeHidden)
+	}
+	return commands
+}
+
+// manHeader describes the man pages for a build. The date is the build date
+// when it is known; otherwise cobra uses SOURCE_DATE_EPOCH or the current time.
+func manHeader(info version.BuildInfo) *doc.GenManHeader {
+	header := &doc.GenManHeader{
+		Title:   "RAG-CLI",
+		Section: "1",
+		Source:  "rag-cli " + info.Version,
+		Manual:  "rag-cli Manual",
+	}
+	if built, err := time.Parse(time.RFC3339, info.BuildDate); err == nil {
+		header.Date = &built
+	}
+	return header
+}
@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"rag-cli/pkg/version"
+)
+
+func TestRunDocs(t *testing.T) {
+	tests := []struct {
+		format string
+		file   string
+	}{
+		{format: "md", file: "rag-cli_index.md"},
+		{format: "man", file: "rag-cli-index.1"},
+		{format: "rest", file: "rag-cli_index.rst"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "docs")
+			var out bytes.Buffer
+
+			if err := runDocs(&out, rootCmd, docsOptions{format: tt.format, dir: dir, excludeHidden: true}); err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+
+			content, err := os.ReadFile(filepath.Join(dir, tt.file))
+			if err != nil {
+				t.Fatalf("Expected %s to be generated: %v", tt.file, err)
+			}
+			if !strings.Contains(string(content), "Index documents") {
+				t.Errorf("Expected %s to document the index command, got:\n%s", tt.file, content)
+			}
+		})
+	}
+
+	t.Run("unknown format", func(t *testing.T) {
+		dir := filepath.Join(t.TempDir(), "docs")
+		err := runDocs(&bytes.Buffer{}, rootCmd, docsOptions{format: "pdf", dir: dir})
+		if err == nil || !strings.Contains(err.Error(), "unknown format") {
+			t.Errorf("Expected unknown format error, got: %v", err)
+		}
+		if _, statErr := os.Stat(dir); !os.IsNotExist(statErr) {
+			t.Errorf("Expected no output directory for an unknown format")
+		}
+	})
+}
+
+// newDocsTestTree builds a small command tree with a hidden command
+func newDocsTestTree() *cobra.Command {
+	root := &cobra.Command{Use: "tool", Short: "A tool", Run: func(*cobra.Command, []string) {}}
+	visible := &cobra.Command{Use: "visible", Short: "A visible command", Run: func(*cobra.Command, []string) {}}
+	visible.AddCommand(&cobra.Command{Use: "child", Short: "A subcommand", Run: func(*cobra.Command, []string) {}})
+	other := &cobra.Command{Use: "other", Short: "Another command", Run: func(*cobra.Command, []string) {}}
+	secret := &cobra.Command{Use: "secret", Short: "A hidden command", Hidden: true, Run: func(*cobra.Command, []string) {}}
+	root.AddCommand(visible, other, secret)
+	return root
+}
+
+// generatedFiles returns the sorted file names in dir
+func generatedFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", dir, err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestRunDocs_Selection(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     docsOptions
+		expected []string
+	}{
+		{
+			name:     "whole tree without hidden commands",
+			opts:     docsOptions{format: "md", excludeHidden: true},
+			expected: []string{"tool.md", "tool_other.md", "tool_visible.md", "tool_visible_child.md"},
+		},
+		{
+			name:     "hidden commands included",
+			opts:     docsOptions{format: "md"},
+			expected: []string{"tool.md", "tool_other.md", "tool_secret.md", "tool_visible.md", "tool_visible_child.md"},
+		},
+		{
+			name:     "selected command and its subcommands",
+			opts:     docsOptions{format: "md", excludeHidden: true, commands: []string{"visible"}},
+			expected: []string{"tool_visible.md", "tool_visible_child.md"},
+		},
+		{
+			name:     "man pages for selected commands",
+			opts:     docsOptions{format: "man", excludeHidden: true, commands: []string{"other", "visible"}},
+			expected: []string{"tool-other.1", "tool-visible-child.1", "tool-visible.1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.dir = t.TempDir()
+			if err := runDocs(&bytes.Buffer{}, newDocsTestTree(), tt.opts); err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if files := generatedFiles(t, tt.opts.dir); !reflect.DeepEqual(files, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, files)
+			}
+		})
+	}
+
+	t.Run("unknown or hidden selection", func(t *testing.T) {
+		err := runDocs(&bytes.Buffer{}, newDocsTestTree(), docsOptions{format: "md", dir: t.TempDir(), commands: []string{"missing"}})
+		if err == nil || !strings.Contains(err.Error(), "unknown command") {
+			t.Errorf("Expected unknown command error, got: %v", err)
+		}
+
+		err = runDocs(&bytes.Buffer{}, newDocsTestTree(), docsOptions{format: "md", dir: t.TempDir(), excludeHidden: true, commands: []string{"secret"}})
+		if err == nil || !strings.Contains(err.Error(), "hidden") {
+			t.Errorf("Expected hidden command error, got: %v", err)
+		}
+	})
+}
+
+func TestRunDocs_FrontMatter(t *testing.T) {
+	dir := t.TempDir()
+	opts := docsOptions{format: "md", dir: dir, excludeHidden: true, commands: []string{"other"}, frontMatter: []string{"layout=cli", "weight=20"}}
+	if err := runDocs(&bytes.Buffer{}, newDocsTestTree(), opts); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(dir, "tool_other.md"))
+	if err != nil {
+		t.Fatalf("Expected tool_other.md to be generated: %v", err)
+	}
+	expected := "---\ntitle: \"tool other\"\nlayout: \"cli\"\nweight: \"20\"\n---\n\n## tool other"
+	if !strings.HasPrefix(string(content), expected) {
+		t.Errorf("Expected front matter before the page, got:\n%s", content)
+	}
+
+	tests := []struct {
+		name string
+		opts docsOptions
+		want string
+	}{
+		{name: "missing value separator", opts: docsOptions{format: "md", frontMatter: []string{"layout"}}, want: "expected key=value"},
+		{name: "title is reserved", opts: docsOptions{format: "md", frontMatter: []string{"title=Other"}}, want: "cannot set title"},
+		{name: "markdown only", opts: docsOptions{format: "man", frontMatter: []string{"layout=cli"}}, want: "only supported for Markdown"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.dir = t.TempDir()
+			err := runDocs(&bytes.Buffer{}, newDocsTestTree(), tt.opts)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got: %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestManHeader(t *testing.T) {
+	header := manHeader(version.BuildInfo{Version: "v1.2.3", BuildDate: "2025-03-04T05:06:07Z"})
+	if header.Section != "1" || header.Source != "rag-cli v1.2.3" {
+		t.Errorf("Unexpected header: %+v", header)
+	}
+	if header.Date == nil || header.Date.Format("2006-01-02") != "2025-03-04" {
+		t.Errorf("Expected the build date, got: %v", header.Date)
+	}
+
+	if header := manHeader(version.BuildInfo{BuildDate: "unknown"}); header.Date != nil {
+		t.Errorf("Expected no date for an unknown build date, got: %v", header.Date)
+	}
+}
@@ -0,0 +1,375 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"rag-cli/internal/embeddings"
+	"rag-cli/internal/httpclient"
+	"rag-cli/internal/indexing"
+	"rag-cli/internal/llm"
+	"rag-cli/internal/system"
+	"rag-cli/internal/vector"
+	"rag-cli/pkg/config"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check that rag-cli and its services are set up correctly",
+	Long: `Run health checks against your configuration and the services rag-cli depends on:
+
+- Configuration file parses and contains no unknown keys
+- Ollama is reachable and the configured LLM model is pulled
+- The embeddings model returns vectors
+- ChromaDB is reachable, speaks the API version rag-cli uses, and lists its collections
+- The data directory (~/.local/share/rag-cli on Linux) is writable
+- A shell is available for command execution
+
+Each check prints a green PASS, yellow WARN, or red FAIL with a hint for fixing problems. The command
+exits with a non-zero status when any check fails.
+
+The environment detected for command prompts (OS, shell, GNU or BSD command syntax,
+and tool versions) is listed after the checks. It is cached between runs; use
+--refresh-sysinfo to detect it again.
+
+EXAMPLES:
+  rag-cli doctor
+  rag-cli doctor --refresh-sysinfo`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, cfgErr := config.Load()
+		if cfgErr != nil {
+			// Probe services with the defaults so the other checks still run
+			defaults, err := config.DefaultConfig()
+			if err != nil {
+				return err
+			}
+			cfg = defaults
+		}
+
+		llmClient, err := llm.NewClient(cfg.LLM, cfg.Timeouts)
+		if err != nil {
+			return fmt.Errorf("failed to initialize LLM client: %w", err)
+		}
+		embeddingsClient, err := embeddings.NewClient(cfg.Embeddings, cfg.Timeouts)
+		if err != nil {
+			return fmt.Errorf("failed to initialize embeddings client: %w", err)
+		}
+
+		dataDir := ""
+		if statePath, err := indexing.DefaultStatePath(); err == nil {
+			dataDir = filepath.Dir(statePath)
+		}
+
+		return runDoctor(os.Stdout, doctorDeps{
+			cfg:         cfg,
+			cfgErr:      cfgErr,
+			unknownKeys: config.UnknownKeys(),
+			models:      llmClient,
+			embedder:    embeddingsClient,
+			server:      vector.NewChromaServer(cfg.Vector, cfg.Timeouts),
+			collections: vector.NewLazyChromaClient(cfg.Vector, cfg.Timeouts),
+			dataDir:     dataDir,
+			lookPath:    exec.LookPath,
+			systemInfo:  systemInfoCache(cfg).Info(),
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+}
+
+// checkStatus is the outcome of a single doctor check
+type checkStatus string
+
+const (
+	checkPass checkStatus = "PASS"
+	checkWarn checkStatus = "WARN"
+	checkFail checkStatus = "FAIL"
+)
+
+type checkResult struct {
+	Name   string
+	Status checkStatus
+	Detail string
+	Hint   string
+}
+
+// modelLister lists the models available on the LLM server
+type modelLister interface {
+	ListModels() ([]string, error)
+}
+
+// chromaServer reports on the ChromaDB server itself
+type chromaServer interface {
+	Heartbeat() error
+	Version() (string, error)
+}
+
+// collectionLister lists the collections stored in ChromaDB
+type collectionLister interface {
+	ListCollections() ([]vector.CollectionInfo, error)
+}
+
+// doctorDeps holds everything the doctor checks inspect, so tests can
+// substitute fakes for each service
+type doctorDeps struct {
+	cfg         *config.Config
+	cfgErr      error
+	unknownKeys []string
+	models      modelLister
+	embedder    embeddings.Embedder
+	server      chromaServer
+	collections collectionLister
+	dataDir     string
+	lookPath    func(file string) (string, error)
+	systemInfo  *system.SystemInfo // Environment detected for command prompts; nil skips the listing
+}
+
+func runDoctor(out io.Writer, deps doctorDeps) error {
+	results := []checkResult{
+		checkConfig(deps),
+		checkLLM(deps),
+		checkEmbeddings(deps),
+		checkVectorStore(deps),
+		checkDataDir(deps),
+		checkShell(deps),
+	}
+
+	failed, warned := 0, 0
+	for _, result := range results {
+		fmt.Fprintf(out, "%s %s: %s\n", statusLabel(result.Status), result.Name, result.Detail)
+		if result.Hint != "" {
+			fmt.Fprintf(out, "       → %s\n", result.Hint)
+		}
+		switch result.Status {
+		case checkFail:
+			failed++
+		case checkWarn:
+			warned++
+		}
+	}
+
+	if deps.systemInfo != nil {
+		fmt.Fprintln(out)
+		fmt.Fprintln(out, "Detected environment:")
+		for _, line := range deps.systemInfo.Describe() {
+			fmt.Fprintf(out, "  %s\n", line)
+		}
+	}
+
+	fmt.Fprintln(out)
+	if failed > 0 {
+		fmt.Fprintf(out, "%d check(s) failed, %d warning(s)\n", failed, warned)
+		return fmt.Errorf("%d doctor check(s) failed", failed)
+	}
+	fmt.Fprintf(out, "All checks passed (%d warning(s))\n", warned)
+	return nil
+}
+
+func checkConfig(deps doctorDeps) checkResult {
+	result := checkResult{Name: "config"}
+	err := deps.cfgErr
+	if err == nil {
+		err = deps.cfg.Validate()
+	}
+	if err != nil {
+		result.Status = checkFail
+		result.Detail = err.Error()
+		var validationErr *config.ValidationError
+		if errors.As(err, &validationErr) {
+			result.Hint = fmt.Sprintf("Fix each setting with 'rag-cli config set %s <value>'", validationErr.Problems[0].Key)
+		} else {
+			result.Hint = "Fix the config file, or regenerate it with 'rag-cli config init --force'"
+		}
+		return result
+	}
+	if len(deps.unknownKeys) > 0 {
+		result.Status = checkWarn
+		result.Detail = fmt.Sprintf("unknown keys in config file: %s", strings.Join(deps.unknownKeys, ", "))
+		result.Hint = "Check for typos; 'rag-cli config show' lists every valid key"
+		return result
+	}
+	result.Status = checkPass
+	result.Detail = "configuration loaded"
+	return result
+}
+
+func checkLLM(deps doctorDeps) checkResult {
+	result := checkResult{Name: "llm"}
+	models, err := deps.models.ListModels()
+	if err != nil {
+		result.Status = checkFail
+		result.Detail = fmt.Sprintf("cannot reach Ollama at %s: %v", deps.cfg.LLM.URL(), err)
+		result.Hint = "Start Ollama with 'ollama serve', or check llm.base_url"
+		if errors.Is(err, httpclient.ErrTimeout) {
+			result.Hint = "Ollama did not answer in time; check that it is not overloaded, or raise timeouts.llm"
+		}
+		return result
+	}
+	if !hasModel(models, deps.cfg.LLM.Model) {
+		result.Status = checkFail
+		result.Detail = fmt.Sprintf("model %s is not available (found %d model(s))", deps.cfg.LLM.Model, len(models))
+		result.Hint = fmt.Sprintf("ollama pull %s", deps.cfg.LLM.Model)
+		return result
+	}
+	result.Status = checkPass
+	result.Detail = fmt.Sprintf("model %s available at %s", deps.cfg.LLM.Model, deps.cfg.LLM.URL())
+	return result
+}
+
+func checkEmbeddings(deps doctorDeps) checkResult {
+	result := checkResult{Name: "embeddings"}
+	embedding, err := deps.embedder.GenerateEmbedding("rag-cli doctor")
+	if err != nil {
+		result.Status = checkFail
+		result.Detail = fmt.Sprintf("model %s failed to embed text: %v", deps.cfg.Embeddings.Model, err)
+		switch {
+		case errors.Is(err, embeddings.ErrModelNotFound):
+			result.Hint = fmt.Sprintf("ollama pull %s", deps.cfg.Embeddings.Model)
+		case errors.Is(err, httpclient.ErrTimeout):
+			result.Hint = "Ollama did not answer in time; the model may still be loading, or raise timeouts.embeddings"
+		default:
+			result.Hint = fmt.Sprintf("ollama pull %s, or check embeddings.base_url", deps.cfg.Embeddings.Model)
+		}
+		return result
+	}
+	result.Status = checkPass
+	result.Detail = fmt.Sprintf("model %s returns %d-dimensional vectors", deps.cfg.Embeddings.Model, len(embedding))
+	return result
+}
+
+func checkVectorStore(deps doctorDeps) checkResult {
+	result := checkResult{Name: "vector store"}
+	address := deps.cfg.Vector.URL()
+
+	if err := deps.server.Heartbeat(); err != nil {
+		result.Status = checkFail
+		if errors.Is(err, vector.ErrUnsupportedAPI) {
+			version, _ := deps.server.Version()
+			result.Detail = fmt.Sprintf("ChromaDB %s at %s does not serve the %s API", version, address, wantedAPI(deps.cfg.Vector.APIVersion))
+			result.Hint = "Set vector.api_version to auto, or use ChromaDB 0.5 or later, e.g. docker run -p 8000:8000 chromadb/chroma:0.5.23"
+			return result
+		}
+		result.Detail = err.Error()
+		result.Hint = "Start ChromaDB, e.g. docker run -p 8000:8000 chromadb/chroma:0.5.23, or check vector.host and vector.port (or vector.base_url)"
+		if errors.Is(err, httpclient.ErrTimeout) {
+			result.Hint = "ChromaDB did not answer in time; check vector.host and vector.port (or vector.base_url), or raise timeouts.vector"
+		}
+		return result
+	}
+
+	version, err := deps.server.Version()
+	if err != nil {
+		result.Status = checkWarn
+		result.Detail = fmt.Sprintf("reachable at %s, but the version could not be read: %v", address, err)
+		return result
+	}
+
+	collections, err := deps.collections.ListCollections()
+	if err != nil {
+		result.Status = checkWarn
+		result.Detail = fmt.Sprintf("ChromaDB %s reachable at %s, but its collections could not be listed: %v", version, address, err)
+		return result
+	}
+	result.Status = checkPass
+	result.Detail = fmt.Sprintf("ChromaDB %s reachable at %s, %s", version, address, describeCollections(collections))
+	return result
+}
+
+// describeCollections names the collections ChromaDB holds
+func describeCollections(collections []vector.CollectionInfo) string {
+	if len(collections) == 0 {
+		return "no collections yet"
+	}
+	names := make([]string, 0, len(collections))
+	for _, collection := range collections {
+		names = append(names, collection.Name)
+	}
+	sort.Strings(names)
+	return fmt.Sprintf("%d collection(s): %s", len(names), strings.Join(names, ", "))
+}
+
+// statusLabel renders a check status in brackets, colored when writing to a
+// terminal
+func statusLabel(status checkStatus) string {
+	label := "[" + string(status) + "]"
+	switch status {
+	case checkPass:
+		return color.New(color.FgGreen, color.Bold).Sprint(label)
+	case checkWarn:
+		return color.New(color.FgYellow, color.Bold).Sprint(label)
+	default:
+		return color.New(color.FgRed, color.Bold).Sprint(label)
+	}
+}
+
+// wantedAPI describes the ChromaDB API versions vector.api_version accepts
+func wantedAPI(version string) string {
+	if version == config.ChromaAPIV1 || version == config.ChromaAPIV2 {
+		return version
+	}
+	return "v1 or v2"
+}
+
+func checkDataDir(deps doctorDeps) checkResult {
+	result := checkResult{Name: "data directory"}
+	if deps.dataDir == "" {
+		result.Status = checkFail
+		result.Detail = "could not determine the home directory"
+		result.Hint = "Set the HOME environment variable"
+		return result
+	}
+
+	err := os.MkdirAll(deps.dataDir, 0755)
+	if err == nil {
+		var probe *os.File
+		if probe, err = os.CreateTemp(deps.dataDir, ".doctor-*"); err == nil {
+			probe.Close()
+			os.Remove(probe.Name())
+		}
+	}
+	if err != nil {
+		result.Status = checkFail
+		result.Detail = fmt.Sprintf("%s is not writable: %v", deps.dataDir, err)
+		result.Hint = fmt.Sprintf("Check the ownership and permissions of %s", deps.dataDir)
+		return result
+	}
+	result.Status = checkPass
+	result.Detail = fmt.Sprintf("%s is writable", deps.dataDir)
+	return result
+}
+
+func checkShell(deps doctorDeps) checkResult {
+	result := checkResult{Name: "shell"}
+	path, err := deps.lookPath("sh")
+	if err != nil {
+		result.Status = checkFail
+		result.Detail = "sh not found on PATH"
+		result.Hint = "Commands suggested by the model are run with sh; install a POSIX shell or fix PATH"
+		return result
+	}
+	result.Status = checkPass
+	result.Detail = fmt.Sprintf("commands run with %s", path)
+	return result
+}
+
+// hasModel reports whether model is in the list, treating a missing tag as :latest
+func hasModel(models []string, model string) bool {
+	for _, name := range models {
+		if name == model || name == model+":latest" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,241 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"rag-cli/internal/embeddings"
+	"rag-cli/internal/httpclient"
+	"rag-cli/internal/system"
+	"rag-cli/internal/vector"
+	"rag-cli/pkg/config"
+)
+
+type fakeModelLister struct {
+	models []string
+	err    error
+}
+
+func (f *fakeModelLister) ListModels() ([]string, error) {
+	return f.models, f.err
+}
+
+type fakeChromaServer struct {
+	heartbeatErr error
+	version      string
+	versionErr   error
+}
+
+func (f *fakeChromaServer) Heartbeat() error {
+	return f.heartbeatErr
+}
+
+func (f *fakeChromaServer) Version() (string, error) {
+	return f.version, f.versionErr
+}
+
+type fakeCollectionLister struct {
+	collections []vector.CollectionInfo
+	err         error
+}
+
+func (f *fakeCollectionLister) ListCollections() ([]vector.CollectionInfo, error) {
+	return f.collections, f.err
+}
+
+// newDoctorFixture returns dependencies for which every check passes
+func newDoctorFixture(t *testing.T) doctorDeps {
+	cfg, err := config.DefaultConfig()
+	if err != nil {
+		t.Fatalf("Failed to build default config: %v", err)
+	}
+	return doctorDeps{
+		cfg:      cfg,
+		models:   &fakeModelLister{models: []string{cfg.LLM.Model, cfg.Embeddings.Model + ":latest"}},
+		embedder: &fakeEmbedder{},
+		server:   &fakeChromaServer{version: "0.5.23"},
+		collections: &fakeCollectionLister{collections: []vector.CollectionInfo{
+			{Name: "documents"}, {Name: "command_history"},
+		}},
+		dataDir:  filepath.Join(t.TempDir(), ".rag-cli"),
+		lookPath: func(file string) (string, error) { return "/bin/" + file, nil },
+		systemInfo: &system.SystemInfo{
+			OS:           "linux",
+			Architecture: "amd64",
+			Shell:        "/bin/bash",
+			Capabilities: map[string]string{"stat": "GNU", "git": "git version 2.43.0"},
+		},
+	}
+}
+
+func TestRunDoctor_AllPass(t *testing.T) {
+	var out bytes.Buffer
+	if err := runDoctor(&out, newDoctorFixture(t)); err != nil {
+		t.Fatalf("Expected all checks to pass, got %v\n%s", err, out.String())
+	}
+	if strings.Contains(out.String(), "[FAIL]") || strings.Contains(out.String(), "[WARN]") {
+		t.Errorf("Expected only passing checks, got:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), "ChromaDB 0.5.23") || !strings.Contains(out.String(), "2 collection(s): command_history, documents") {
+		t.Errorf("Expected ChromaDB version in output, got:\n%s", out.String())
+	}
+	for _, want := range []string{"Detected environment:", "OS: linux/amd64", "Shell: /bin/bash", "Command syntax: stat GNU", "git: git version 2.43.0"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected %q in output, got:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestRunDoctor_FailureModes(t *testing.T) {
+	tests := []struct {
+		name     string
+		modify   func(deps *doctorDeps)
+		status   checkStatus
+		expected string
+	}{
+		{
+			name:     "config parse error",
+			modify:   func(deps *doctorDeps) { deps.cfgErr = errors.New("failed to read config file: yaml: line 3") },
+			status:   checkFail,
+			expected: "config init --force",
+		},
+		{
+			name:     "chunk overlap too large",
+			modify:   func(deps *doctorDeps) { deps.cfg.Chunker.ChunkOverlap = deps.cfg.Chunker.ChunkSize },
+			status:   checkFail,
+			expected: "chunker.chunk_overlap",
+		},
+		{
+			name: "invalid config values",
+			modify: func(deps *doctorDeps) {
+				deps.cfgErr = &config.ValidationError{Problems: []config.Problem{{Key: "llm.model", Message: "must not be empty"}}}
+			},
+			status:   checkFail,
+			expected: "config set llm.model",
+		},
+		{
+			name:     "unknown config keys",
+			modify:   func(deps *doctorDeps) { deps.unknownKeys = []string{"llm.modle"} },
+			status:   checkWarn,
+			expected: "llm.modle",
+		},
+		{
+			name:     "ollama down",
+			modify:   func(deps *doctorDeps) { deps.models = &fakeModelLister{err: errors.New("connection refused")} },
+			status:   checkFail,
+			expected: "ollama serve",
+		},
+		{
+			name:     "model missing",
+			modify:   func(deps *doctorDeps) { deps.models = &fakeModelLister{models: []string{"other:7b"}} },
+			status:   checkFail,
+			expected: "ollama pull granite-code:3b",
+		},
+		{
+			name: "ollama timing out",
+			modify: func(deps *doctorDeps) {
+				deps.models = &fakeModelLister{err: fmt.Errorf("failed to list models: %w", httpclient.ErrTimeout)}
+			},
+			status:   checkFail,
+			expected: "timeouts.llm",
+		},
+		{
+			name:     "embeddings failing",
+			modify:   func(deps *doctorDeps) { deps.embedder = &fakeEmbedder{err: errors.New("connection refused")} },
+			status:   checkFail,
+			expected: "ollama pull all-minilm, or check embeddings.base_url",
+		},
+		{
+			name: "embeddings model missing",
+			modify: func(deps *doctorDeps) {
+				deps.embedder = &fakeEmbedder{err: fmt.Errorf("%w: all-minilm", embeddings.ErrModelNotFound)}
+			},
+			status:   checkFail,
+			expected: "→ ollama pull all-minilm\n",
+		},
+		{
+			name:     "embeddings timing out",
+			modify:   func(deps *doctorDeps) { deps.embedder = &fakeEmbedder{err: httpclient.ErrTimeout} },
+			status:   checkFail,
+			expected: "timeouts.embeddings",
+		},
+		{
+			name: "chroma down",
+			modify: func(deps *doctorDeps) {
+				deps.server = &fakeChromaServer{heartbeatErr: errors.New("connection refused")}
+			},
+			status:   checkFail,
+			expected: "Start ChromaDB",
+		},
+		{
+			name: "chroma version mismatch",
+			modify: func(deps *doctorDeps) {
+				deps.server = &fakeChromaServer{heartbeatErr: vector.ErrUnsupportedAPI, version: "0.3.29"}
+			},
+			status:   checkFail,
+			expected: "ChromaDB 0.3.29",
+		},
+		{
+			name:     "chroma version unreadable",
+			modify:   func(deps *doctorDeps) { deps.server = &fakeChromaServer{versionErr: errors.New("bad response")} },
+			status:   checkWarn,
+			expected: "version could not be read",
+		},
+		{
+			name: "chroma collections unreadable",
+			modify: func(deps *doctorDeps) {
+				deps.collections = &fakeCollectionLister{err: errors.New("unexpected status code: 500")}
+			},
+			status:   checkWarn,
+			expected: "collections could not be listed",
+		},
+		{
+			name: "data dir not writable",
+			modify: func(deps *doctorDeps) {
+				file := filepath.Join(filepath.Dir(deps.dataDir), "blocker")
+				if err := os.WriteFile(file, nil, 0644); err != nil {
+					t.Fatalf("Failed to create blocker file: %v", err)
+				}
+				deps.dataDir = filepath.Join(file, ".rag-cli")
+			},
+			status:   checkFail,
+			expected: "is not writable",
+		},
+		{
+			name: "no shell",
+			modify: func(deps *doctorDeps) {
+				deps.lookPath = func(file string) (string, error) { return "", fmt.Errorf("%s: not found", file) }
+			},
+			status:   checkFail,
+			expected: "sh not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deps := newDoctorFixture(t)
+			tt.modify(&deps)
+
+			var out bytes.Buffer
+			err := runDoctor(&out, deps)
+
+			if tt.status == checkFail && err == nil {
+				t.Errorf("Expected doctor to return an error, output:\n%s", out.String())
+			}
+			if tt.status == checkWarn && err != nil {
+				t.Errorf("Expected warnings not to fail doctor, got %v", err)
+			}
+			if !strings.Contains(out.String(), "["+string(tt.status)+"]") {
+				t.Errorf("Expected a %s line, got:\n%s", tt.status, out.String())
+			}
+			if !strings.Contains(out.String(), tt.expected) {
+				t.Errorf("Expected output to contain %q, got:\n%s", tt.expected, out.String())
+			}
+		})
+	}
+}
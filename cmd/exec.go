@@ -0,0 +1,221 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"rag-cli/internal/chat"
+	"rag-cli/internal/embeddings"
+	"rag-cli/internal/vector"
+	"rag-cli/pkg/config"
+)
+
+var (
+	execYes    bool
+	execRecord bool
+)
+
+var execCmd = &cobra.Command{
+	Use:   "exec [flags] <command> [args...]",
+	Short: "Run a shell command with the same safety checks as chat",
+	Long: `Run a shell command through the same executor chat uses, with its safety checks and
+approval prompt, and optionally record it in the command history.
+
+Commands that could destroy the system, such as recursive deletes of / or the home
+directory, formatting disks, or piping a download into a shell, are always refused.
+The safety section of the config adds blocked, warned and allowed patterns, a
+read-only mode, and typed confirmation. Other commands are shown and run after you
+confirm, or immediately with --yes. Dangerous commands, such as anything run with
+sudo or recursive deletes outside the working directory, are shown with the reason
+in red and always need confirming, even with --yes.
+
+The command's output is printed as it would be in chat and rag-cli exits with the
+command's exit code, so exec can be used in scripts.
+
+With --record, the command and its output are stored in the commands collection
+like a chat execution session, so future sessions can learn from commands you ran
+by hand.
+
+Everything after the first argument is passed to the command; quote it or use --
+to keep rag-cli from parsing its flags.
+
+EXAMPLES:
+  # Run a command after confirming it
+  rag-cli exec ls -la
+
+  # Run without a prompt and remember it for future sessions
+  rag-cli exec --yes --record -- kubectl rollout restart deploy/api
+
+  # Pipes are run step by step, as in chat
+  rag-cli exec --yes "du -sh * | sort -h | tail -5"`,
+	Args:          cobra.MinimumNArgs(1),
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		safety, err := safetyPolicy(cfg)
+		if err != nil {
+			return err
+		}
+
+		var embedder embeddings.Embedder
+		var store vector.VectorStore
+		if execRecord {
+			embeddingClient, err := embeddings.NewClient(cfg.Embeddings, cfg.Timeouts)
+			if err != nil {
+				return fmt.Errorf("failed to initialize embedding client: %w", err)
+			}
+
+			vectorStore, err := vector.NewChromaClient(cfg.Vector, cfg.Timeouts)
+			if err != nil {
+				return fmt.Errorf("failed to initialize vector store: %w", err)
+			}
+			embedder, store = embeddingClient, vectorStore
+		}
+
+		return runExec(os.Stdin, os.Stdout, chat.NewCommandExecutor(safety), embedder, store, strings.Join(args, " "), execOptions{
+			yes:    execYes,
+			record: execRecord,
+			safety: safety,
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(execCmd)
+
+	// Flags after the command belong to the command
+	execCmd.Flags().SetInterspersed(false)
+	execCmd.Flags().BoolVarP(&execYes, "yes", "y", false, "Run without asking for confirmation. Blocked commands are still refused and dangerous ones still asked about")
+	execCmd.Flags().BoolVar(&execRecord, "record", false, "Store the command and its output in the commands collection for future sessions")
+}
+
+// safetyPolicy returns the checker for the safety section and chat.commands,
+// which decide the commands chat, exec and serve may run
+func safetyPolicy(cfg *config.Config) (*chat.SafetyChecker, error) {
+	safety, err := chat.NewSafetyPolicy(cfg.Safety)
+	if err != nil {
+		return nil, err
+	}
+	if err := safety.UseCommandPolicy(cfg.Chat.Commands); err != nil {
+		return nil, err
+	}
+	return safety, nil
+}
+
+// approvalPolicy returns the chat.always_allow rules, saving those added by
+// answering "always" to the user config file when chat.save_always_allow
+// is set
+func approvalPolicy(cfg *config.Config) *chat.ApprovalPolicy {
+	var save func(rules []string) error
+	if cfg.Chat.SaveAlwaysAllow {
+		save = func(rules []string) error {
+			path, err := config.UserConfigPath()
+			if err != nil {
+				return err
+			}
+			return config.AppendValues(path, "chat.always_allow", rules)
+		}
+	}
+	return chat.NewApprovalPolicy(cfg.Chat.AlwaysAllow, cfg.Chat.AlwaysAllowMatch, save)
+}
+
+// ExitCodeError reports that a command run on the user's behalf exited with a
+// non-zero status, which rag-cli passes through as its own exit code. The
+// command's output has already been shown, so there is nothing more to print.
+type ExitCodeError struct {
+	Code int
+}
+
+func (e *ExitCodeError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+// commandRunner runs a shell command and reports how it finished
+type commandRunner interface {
+	Run(command string) chat.ExecutionResult
+}
+
+type execOptions struct {
+	yes    bool
+	record bool
+	safety *chat.SafetyChecker // nil uses the built-in rules
+}
+
+func runExec(in io.Reader, out io.Writer, runner commandRunner, embedder embeddings.Embedder, store vector.VectorStore, command string, opts execOptions) error {
+	safety := opts.safety
+	if safety == nil {
+		safety = chat.NewSafetyChecker()
+	}
+	verdict := safety.Classify(command)
+	switch verdict.Action {
+	case chat.SafetyBlock:
+		return &chat.BlockedCommandError{Command: command, Reason: verdict.Reason}
+	case chat.SafetyWarn:
+		fmt.Fprintf(out, "Warning: %q %s\n", command, verdict.Reason)
+	}
+
+	// --yes does not cover dangerous commands, which are always confirmed
+	if !opts.yes || verdict.Action == chat.SafetyConfirm {
+		fmt.Fprintf(out, "$ %s\n", command)
+		if notice := verdict.RiskNotice(); notice != "" {
+			color.New(color.FgRed, color.Bold).Fprintln(out, notice)
+		}
+		fmt.Fprint(out, safety.ConfirmationPrompt())
+		answer, _ := bufio.NewReader(in).ReadString('\n')
+		if !safety.Approves(answer) {
+			fmt.Fprintln(out, "Command not run")
+			return nil
+		}
+	}
+
+	result := runner.Run(command)
+	for _, output := range []string{result.Stdout, result.Stderr} {
+		fmt.Fprint(out, output)
+		if output != "" && !strings.HasSuffix(output, "\n") {
+			fmt.Fprintln(out)
+		}
+	}
+
+	if opts.record {
+		if err := recordExecResult(embedder, store, result); err != nil {
+			slog.Warn("failed to record command", "component", "exec", "collection", store.CommandsCollection(), "error", err)
+		}
+	}
+
+	if result.Succeeded() {
+		return nil
+	}
+	if result.ExitCode > 0 {
+		return &ExitCodeError{Code: result.ExitCode}
+	}
+	return fmt.Errorf("failed to run command: %w", result.Err)
+}
+
+// recordExecResult stores a manually run command as a command session, using
+// the same log format as chat so history and retrieval treat it alike
+func recordExecResult(embedder embeddings.Embedder, store vector.VectorStore, result chat.ExecutionResult) error {
+	var executionLog strings.Builder
+	fmt.Fprintf(&executionLog, "$ %s\n%s\n", result.Command, chat.LoggedOutput(result))
+	if result.Err != nil {
+		fmt.Fprintf(&executionLog, "Error: %v\n", result.Err)
+	}
+
+	return chat.RecordExecution(embedder, store, executionLog.String(), map[string]interface{}{
+		"request":   result.Command,
+		"source":    "exec",
+		"success":   result.Succeeded(),
+		"exit_code": result.ExitCode,
+		"timestamp": time.Now().UTC().Format(time.RFC3339),
+	})
+}
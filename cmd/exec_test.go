@@ -0,0 +1,188 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"rag-cli/internal/chat"
+	"rag-cli/pkg/config"
+)
+
+// fakeRunner records the commands it was asked to run and returns a canned result
+type fakeRunner struct {
+	result chat.ExecutionResult
+	ran    []string
+}
+
+func (f *fakeRunner) Run(command string) chat.ExecutionResult {
+	f.ran = append(f.ran, command)
+	result := f.result
+	result.Command = command
+	return result
+}
+
+func TestRunExec(t *testing.T) {
+	t.Run("refuses blocked commands without running them", func(t *testing.T) {
+		runner := &fakeRunner{}
+		var out bytes.Buffer
+
+		err := runExec(strings.NewReader(""), &out, runner, nil, nil, "rm -rf /", execOptions{yes: true})
+
+		var blocked *chat.BlockedCommandError
+		if !errors.As(err, &blocked) {
+			t.Fatalf("Expected a BlockedCommandError, got: %v", err)
+		}
+		if len(runner.ran) != 0 {
+			t.Errorf("Expected blocked command not to run, got %v", runner.ran)
+		}
+	})
+
+	t.Run("passes through the exit code", func(t *testing.T) {
+		var out bytes.Buffer
+
+		err := runExec(strings.NewReader(""), &out, chat.NewCommandExecutor(nil), nil, nil, "echo failing; exit 3", execOptions{yes: true})
+
+		var exitErr *ExitCodeError
+		if !errors.As(err, &exitErr) {
+			t.Fatalf("Expected an ExitCodeError, got: %v", err)
+		}
+		if exitErr.Code != 3 {
+			t.Errorf("Expected exit code 3, got %d", exitErr.Code)
+		}
+		if !strings.Contains(out.String(), "failing") {
+			t.Errorf("Expected command output to be printed, got: %q", out.String())
+		}
+	})
+
+	t.Run("succeeds when the command does", func(t *testing.T) {
+		var out bytes.Buffer
+
+		err := runExec(strings.NewReader(""), &out, chat.NewCommandExecutor(nil), nil, nil, "echo hello", execOptions{yes: true})
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if out.String() != "hello\n" {
+			t.Errorf("Expected only the command output, got: %q", out.String())
+		}
+	})
+
+	t.Run("declined approval does not run the command", func(t *testing.T) {
+		runner := &fakeRunner{}
+		var out bytes.Buffer
+
+		err := runExec(strings.NewReader("n\n"), &out, runner, nil, nil, "make deploy", execOptions{})
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if len(runner.ran) != 0 {
+			t.Errorf("Expected declined command not to run, got %v", runner.ran)
+		}
+		if !strings.Contains(out.String(), "Command not run") {
+			t.Errorf("Expected cancellation message, got: %q", out.String())
+		}
+	})
+
+	t.Run("dangerous commands are confirmed even with --yes", func(t *testing.T) {
+		runner := &fakeRunner{}
+		var out bytes.Buffer
+
+		if err := runExec(strings.NewReader("n\n"), &out, runner, nil, nil, "sudo make install", execOptions{yes: true}); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if len(runner.ran) != 0 {
+			t.Errorf("Expected declined command not to run, got %v", runner.ran)
+		}
+		if !strings.Contains(out.String(), "Risk: dangerous - runs as root") {
+			t.Errorf("Expected the risk and its reason, got: %q", out.String())
+		}
+	})
+
+	t.Run("approval defaults to yes", func(t *testing.T) {
+		runner := &fakeRunner{}
+		var out bytes.Buffer
+
+		if err := runExec(strings.NewReader("\n"), &out, runner, nil, nil, "make test", execOptions{}); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if len(runner.ran) != 1 {
+			t.Errorf("Expected command to run once, got %v", runner.ran)
+		}
+	})
+
+	t.Run("typed confirmation ignores Enter", func(t *testing.T) {
+		safety, err := chat.NewSafetyPolicy(config.SafetyConfig{RequireTypedConfirmation: true})
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+
+		for _, tt := range []struct {
+			answer string
+			runs   int
+		}{{"\n", 0}, {"y\n", 0}, {"yes\n", 1}} {
+			runner := &fakeRunner{}
+			var out bytes.Buffer
+			if err := runExec(strings.NewReader(tt.answer), &out, runner, nil, nil, "make test", execOptions{safety: safety}); err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if len(runner.ran) != tt.runs {
+				t.Errorf("Expected answer %q to run the command %d time(s), got %v", tt.answer, tt.runs, runner.ran)
+			}
+			if !strings.Contains(out.String(), "Type 'yes'") {
+				t.Errorf("Expected a typed confirmation prompt, got: %q", out.String())
+			}
+		}
+	})
+
+	t.Run("policy rules", func(t *testing.T) {
+		safety, err := chat.NewSafetyPolicy(config.SafetyConfig{Blocklist: []config.SafetyRule{
+			{Pattern: "make deploy*", Reason: "deploys to production"},
+			{Pattern: "make release*", Severity: config.SeverityWarn, Reason: "publishes a release"},
+		}})
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+
+		runner := &fakeRunner{}
+		var out bytes.Buffer
+		err = runExec(strings.NewReader(""), &out, runner, nil, nil, "make deploy", execOptions{yes: true, safety: safety})
+		var blocked *chat.BlockedCommandError
+		if !errors.As(err, &blocked) || blocked.Reason != "deploys to production" {
+			t.Fatalf("Expected the blocklist to refuse the command, got: %v", err)
+		}
+
+		if err := runExec(strings.NewReader(""), &out, runner, nil, nil, "make release", execOptions{yes: true, safety: safety}); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if len(runner.ran) != 1 || !strings.Contains(out.String(), `Warning: "make release" publishes a release`) {
+			t.Errorf("Expected the warned command to run after a warning, got runs %v and output %q", runner.ran, out.String())
+		}
+	})
+
+	t.Run("records the run in the commands collection", func(t *testing.T) {
+		runner := &fakeRunner{result: chat.ExecutionResult{Stderr: "permission denied\n", ExitCode: 1, Err: errors.New("exit status 1")}}
+		store := newFakeStore()
+		var out bytes.Buffer
+
+		err := runExec(strings.NewReader(""), &out, runner, &fakeEmbedder{}, store, "cat /root/secret", execOptions{yes: true, record: true})
+
+		var exitErr *ExitCodeError
+		if !errors.As(err, &exitErr) || exitErr.Code != 1 {
+			t.Fatalf("Expected exit code 1, got: %v", err)
+		}
+
+		added := store.added[store.CommandsCollection()]
+		if len(added) != 1 {
+			t.Fatalf("Expected 1 recorded session, got %d", len(added))
+		}
+		if !strings.Contains(added[0], "$ cat /root/secret\n[stderr]\npermission denied") {
+			t.Errorf("Expected command and output in recorded log, got: %q", added[0])
+		}
+
+		metadata := store.addedMetadata[store.CommandsCollection()][0]
+		if metadata["success"] != false || metadata["source"] != "exec" || metadata["exit_code"] != 1 {
+			t.Errorf("Unexpected recorded metadata: %v", metadata)
+		}
+	})
+}
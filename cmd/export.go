@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"rag-cli/internal/vector"
+	"rag-cli/pkg/config"
+)
+
+var (
+	exportCollection string
+	exportOutput     string
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Back up a collection to a JSON Lines file",
+	Long: `Write every document in a collection to a JSON Lines file, one document per line
+with its ID, text, metadata, and embedding. The file can be loaded again with
+'rag-cli import', into the same or another ChromaDB instance, without re-embedding.
+
+The export is written to standard output unless --output is given. Progress and the
+final count are printed to standard error.
+
+EXAMPLES:
+  # Back up indexed documents
+  rag-cli export --collection documents -o docs.jsonl
+
+  # Back up command history and compress it
+  rag-cli export --collection commands | gzip > history.jsonl.gz`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		vectorStore, err := vector.NewChromaClient(cfg.Vector, cfg.Timeouts)
+		if err != nil {
+			return fmt.Errorf("failed to initialize vector store: %w", err)
+		}
+
+		var out io.Writer = os.Stdout
+		if exportOutput != "" && exportOutput != "-" {
+			file, err := os.Create(exportOutput)
+			if err != nil {
+				return fmt.Errorf("failed to create export file: %w", err)
+			}
+			defer file.Close()
+			out = file
+		}
+
+		writer := bufio.NewWriter(out)
+		if err := runExport(writer, os.Stderr, vectorStore, exportCollection); err != nil {
+			return err
+		}
+		if err := writer.Flush(); err != nil {
+			return fmt.Errorf("failed to write export: %w", err)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+
+	exportCmd.Flags().StringVarP(&exportCollection, "collection", "c", "documents", "Collection to export: documents, commands, auto, or an existing collection name")
+	exportCmd.Flags().StringVarP(&exportOutput, "output", "o", "", "File to write the export to (default: standard output)")
+}
+
+// runExport writes a collection to out, reporting progress and the final
+// count on status
+func runExport(out, status io.Writer, store vector.VectorStore, collection string) error {
+	collectionName, err := resolveCollection(store, collection)
+	if err != nil {
+		return err
+	}
+
+	written, err := vector.Export(store, collectionName, out, func(written int) {
+		fmt.Fprintf(status, "\rExported %d document(s)...", written)
+	})
+	if written > 0 {
+		fmt.Fprintln(status)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(status, "Exported %d document(s) from %s\n", written, collectionName)
+	return nil
+}
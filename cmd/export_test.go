@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"rag-cli/internal/vector"
+)
+
+func TestExportImport_RoundTrip(t *testing.T) {
+	source := newFakeStore()
+	source.documents["documents"] = []vector.StoredDocument{
+		{ID: "a", Document: "alpha", Metadata: map[string]interface{}{"source_path": "a.md"}, Embedding: []float32{0.1, 0.2}},
+		{ID: "b", Document: "beta", Embedding: []float32{0.3, 0.4}},
+	}
+
+	var exported, status bytes.Buffer
+	if err := runExport(&exported, &status, source, "documents"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if lines := strings.Count(exported.String(), "\n"); lines != 2 {
+		t.Errorf("Expected 2 JSONL records, got %d:\n%s", lines, exported.String())
+	}
+	if !strings.Contains(status.String(), "Exported 2 document(s) from documents") {
+		t.Errorf("Expected export summary, got: %q", status.String())
+	}
+
+	target := newFakeStore()
+	status.Reset()
+	if err := runImport(&exported, &status, target, "project-x", importOptions{}); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if !reflect.DeepEqual(target.addedDocuments["project-x"], source.documents["documents"]) {
+		t.Errorf("Expected imported documents to match the export, got: %+v", target.addedDocuments["project-x"])
+	}
+	if !strings.Contains(status.String(), "Imported 2 document(s) into project-x") {
+		t.Errorf("Expected import summary, got: %q", status.String())
+	}
+}
+
+func TestExport_Pages(t *testing.T) {
+	store := newFakeStore()
+	for i := 0; i < 1200; i++ {
+		store.documents["documents"] = append(store.documents["documents"], vector.StoredDocument{ID: fmt.Sprintf("doc-%d", i), Embedding: []float32{1}})
+	}
+
+	var exported bytes.Buffer
+	if err := runExport(&exported, &bytes.Buffer{}, store, "documents"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if lines := strings.Count(exported.String(), "\n"); lines != 1200 {
+		t.Errorf("Expected all 1200 documents across pages, got %d", lines)
+	}
+}
+
+func TestRunImport(t *testing.T) {
+	record := func(id string, embedding string) string {
+		return `{"id":"` + id + `","document":"text ` + id + `","embedding":` + embedding + "}\n"
+	}
+
+	t.Run("refuses a non-empty collection without merge", func(t *testing.T) {
+		store := newFakeStore()
+		store.collections = []vector.CollectionInfo{{Name: "documents"}}
+		store.counts["documents"] = 3
+
+		err := runImport(strings.NewReader(record("a", "[1,2]")), &bytes.Buffer{}, store, "documents", importOptions{})
+		if err == nil || !strings.Contains(err.Error(), "--merge") {
+			t.Fatalf("Expected refusal mentioning --merge, got: %v", err)
+		}
+		if len(store.added["documents"]) != 0 {
+			t.Errorf("Expected nothing to be imported, got %v", store.added["documents"])
+		}
+	})
+
+	t.Run("merge checks the existing dimension and skips existing IDs", func(t *testing.T) {
+		store := newFakeStore()
+		store.collections = []vector.CollectionInfo{{Name: "documents"}}
+		store.counts["documents"] = 1
+		store.documents["documents"] = []vector.StoredDocument{{ID: "a", Document: "mine", Embedding: []float32{1, 2}}}
+		var status bytes.Buffer
+
+		err := runImport(strings.NewReader(record("a", "[3,4]")+record("b", "[5,6]")), &status, store, "documents", importOptions{merge: true, skipExisting: true})
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if !reflect.DeepEqual(store.added["documents"], []string{"text b"}) {
+			t.Errorf("Expected only b to be imported, got %v", store.added["documents"])
+		}
+		if !strings.Contains(status.String(), "Imported 1 document(s) into documents, skipped 1 existing") {
+			t.Errorf("Expected summary with skipped count, got: %q", status.String())
+		}
+
+		err = runImport(strings.NewReader(record("c", "[1,2,3]")), &bytes.Buffer{}, store, "documents", importOptions{merge: true})
+		if err == nil || !strings.Contains(err.Error(), "expected 2") {
+			t.Errorf("Expected a dimension mismatch against the collection, got: %v", err)
+		}
+	})
+
+	t.Run("skip-existing into a collection that does not exist yet", func(t *testing.T) {
+		store := newFakeStore()
+		var status bytes.Buffer
+
+		err := runImport(strings.NewReader(record("a", "[1,2]")+record("b", "[3,4]")+record("a", "[1,2]")), &status, store, "fresh", importOptions{skipExisting: true})
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if !reflect.DeepEqual(store.added["fresh"], []string{"text a", "text b"}) {
+			t.Errorf("Expected a and b to be imported once, got %v", store.added["fresh"])
+		}
+		if !strings.Contains(status.String(), "Imported 2 document(s) into fresh, skipped 1 existing") {
+			t.Errorf("Expected summary with skipped count, got: %q", status.String())
+		}
+	})
+
+	t.Run("mismatched dimensions in the file stop the import", func(t *testing.T) {
+		store := newFakeStore()
+
+		err := runImport(strings.NewReader(record("a", "[1,2]")+record("b", "[1,2,3]")), &bytes.Buffer{}, store, "documents", importOptions{})
+		if err == nil || !strings.Contains(err.Error(), "line 2") {
+			t.Fatalf("Expected a dimension error on line 2, got: %v", err)
+		}
+		if !strings.Contains(err.Error(), "after 1 document(s)") {
+			t.Errorf("Expected the error to report progress, got: %v", err)
+		}
+	})
+
+	t.Run("records without embeddings are rejected", func(t *testing.T) {
+		err := runImport(strings.NewReader(`{"id":"a","document":"no vector"}`+"\n"), &bytes.Buffer{}, newFakeStore(), "documents", importOptions{})
+		if err == nil || !strings.Contains(err.Error(), "has no embedding") {
+			t.Errorf("Expected missing embedding error, got: %v", err)
+		}
+	})
+}
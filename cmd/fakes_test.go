@@ -0,0 +1,236 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+
+	"rag-cli/internal/vector"
+)
+
+// fakeEmbedder returns a fixed embedding and records the texts it was asked to embed
+type fakeEmbedder struct {
+	embedding []float32
+	err       error
+	texts     []string
+}
+
+func (f *fakeEmbedder) GenerateEmbedding(text string) ([]float32, error) {
+	f.texts = append(f.texts, text)
+	if f.err != nil {
+		return nil, f.err
+	}
+	if f.embedding == nil {
+		return []float32{0.1, 0.2, 0.3}, nil
+	}
+	return f.embedding, nil
+}
+
+// fakeStore is an in-memory vector.VectorStore that returns canned search results
+type fakeStore struct {
+	results        map[string][]vector.SearchResult
+	added          map[string][]string
+	addedMetadata  map[string][]map[string]interface{}
+	addedDocuments map[string][]vector.StoredDocument
+	collections    []vector.CollectionInfo
+	counts         map[string]int
+	documents      map[string][]vector.StoredDocument
+	deleted        map[string][]string
+	reset          []string
+	dropped        []string
+
+	// documentsCollection overrides the documents collection name, as --collection does
+	documentsCollection string
+
+	searchedCollection string
+	searchedTopK       int
+
+	mu sync.Mutex
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{
+		results:        make(map[string][]vector.SearchResult),
+		added:          make(map[string][]string),
+		addedMetadata:  make(map[string][]map[string]interface{}),
+		addedDocuments: make(map[string][]vector.StoredDocument),
+		counts:         make(map[string]int),
+		documents:      make(map[string][]vector.StoredDocument),
+		deleted:        make(map[string][]string),
+	}
+}
+
+func (f *fakeStore) AddDocument(collectionName, id, content string, embedding []float32) error {
+	return f.AddDocumentWithMetadata(collectionName, id, content, embedding, nil)
+}
+
+func (f *fakeStore) AddDocumentWithMetadata(collectionName, id, content string, embedding []float32, metadata map[string]interface{}) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.added[collectionName] = append(f.added[collectionName], content)
+	f.addedMetadata[collectionName] = append(f.addedMetadata[collectionName], metadata)
+	f.addedDocuments[collectionName] = append(f.addedDocuments[collectionName], vector.StoredDocument{ID: id, Document: content, Metadata: metadata, Embedding: embedding})
+	return nil
+}
+
+func (f *fakeStore) SearchWithEmbedding(collectionName string, queryEmbedding []float32, numResults int) ([]string, error) {
+	results, err := f.SearchWithScores(collectionName, queryEmbedding, numResults)
+	if err != nil {
+		return nil, err
+	}
+	documents := make([]string, 0, len(results))
+	for _, result := range results {
+		documents = append(documents, result.Document)
+	}
+	return documents, nil
+}
+
+func (f *fakeStore) SearchWithScores(collectionName string, queryEmbedding []float32, numResults int) ([]vector.SearchResult, error) {
+	f.searchedCollection = collectionName
+	f.searchedTopK = numResults
+	results, ok := f.results[collectionName]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", vector.ErrCollectionNotFound, collectionName)
+	}
+	if len(results) > numResults {
+		results = results[:numResults]
+	}
+	return results, nil
+}
+
+func (f *fakeStore) ListCollections() ([]vector.CollectionInfo, error) {
+	return f.collections, nil
+}
+
+func (f *fakeStore) Count(collectionName string) (int, error) {
+	count, ok := f.counts[collectionName]
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", vector.ErrCollectionNotFound, collectionName)
+	}
+	return count, nil
+}
+
+func (f *fakeStore) GetDocuments(collectionName string, limit int) ([]vector.StoredDocument, error) {
+	docs := f.documents[collectionName]
+	if limit > 0 && len(docs) > limit {
+		docs = docs[:limit]
+	}
+	return docs, nil
+}
+
+func (f *fakeStore) GetDocument(collectionName, id string) (*vector.StoredDocument, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, doc := range f.documents[collectionName] {
+		if doc.ID == id {
+			return &doc, nil
+		}
+	}
+	for _, doc := range f.addedDocuments[collectionName] {
+		if doc.ID == id && !slices.Contains(f.deleted[collectionName], id) {
+			return &doc, nil
+		}
+	}
+	if !f.hasCollection(collectionName) {
+		return nil, fmt.Errorf("%w: %s", vector.ErrCollectionNotFound, collectionName)
+	}
+	return nil, nil
+}
+
+// hasCollection reports whether a collection is listed or holds documents,
+// as ChromaDB only knows collections that were created
+func (f *fakeStore) hasCollection(name string) bool {
+	if len(f.documents[name]) > 0 || len(f.addedDocuments[name]) > 0 {
+		return true
+	}
+	return slices.ContainsFunc(f.collections, func(info vector.CollectionInfo) bool { return info.Name == name })
+}
+
+func (f *fakeStore) ExportDocuments(collectionName string, offset, limit int) ([]vector.StoredDocument, error) {
+	docs := f.documents[collectionName]
+	if offset >= len(docs) {
+		return nil, nil
+	}
+	docs = docs[offset:]
+	if limit > 0 && len(docs) > limit {
+		docs = docs[:limit]
+	}
+	return docs, nil
+}
+
+func (f *fakeStore) DeleteDocuments(collectionName string, ids []string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.deleted[collectionName] = append(f.deleted[collectionName], ids...)
+	remove := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		remove[id] = true
+	}
+	var kept []vector.StoredDocument
+	for _, doc := range f.documents[collectionName] {
+		if !remove[doc.ID] {
+			kept = append(kept, doc)
+		}
+	}
+	f.documents[collectionName] = kept
+	return nil
+}
+
+func (f *fakeStore) ResetCollection(name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.reset = append(f.reset, name)
+	delete(f.documents, name)
+	f.counts[name] = 0
+	return nil
+}
+
+func (f *fakeStore) DeleteCollection(name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.dropped = append(f.dropped, name)
+	delete(f.documents, name)
+	delete(f.counts, name)
+	var kept []vector.CollectionInfo
+	for _, info := range f.collections {
+		if info.Name != name {
+			kept = append(kept, info)
+		}
+	}
+	f.collections = kept
+	return nil
+}
+
+func (f *fakeStore) DocumentsCollection() string {
+	if f.documentsCollection != "" {
+		return f.documentsCollection
+	}
+	return "documents"
+}
+func (f *fakeStore) CommandsCollection() string  { return "command_history" }
+func (f *fakeStore) AutoIndexCollection() string { return "auto_indexed" }
+
+// fakeLLM records which generation mode was requested and returns a canned response
+type fakeLLM struct {
+	response string
+	err      error
+
+	answerCalls   int
+	responseCalls int
+	lastQuery     string
+	lastContext   []string
+}
+
+func (f *fakeLLM) GenerateAnswer(query string, context []string) (string, error) {
+	f.answerCalls++
+	f.lastQuery = query
+	f.lastContext = context
+	return f.response, f.err
+}
+
+func (f *fakeLLM) GenerateResponse(query string, context []string) (string, error) {
+	f.responseCalls++
+	f.lastQuery = query
+	f.lastContext = context
+	return f.response, f.err
+}
@@ -0,0 +1,407 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"rag-cli/internal/chat"
+	"rag-cli/internal/embeddings"
+	"rag-cli/internal/history"
+	"rag-cli/internal/transcript"
+	"rag-cli/internal/vector"
+	"rag-cli/pkg/config"
+)
+
+var (
+	historyJSON  bool
+	historyLimit int
+	historyTopK  int
+
+	historyPruneOlderThan  string
+	historyPruneKeepLast   int
+	historyPruneFailedOnly bool
+	historyPruneYes        bool
+)
+
+// historySummaryLength is the number of characters of each session summary shown by history list
+const historySummaryLength = 80
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Browse stored command execution sessions",
+	Long: `Browse the command execution sessions that rag-cli stores in the command history
+collection. These sessions are what the assistant draws on as historical context.
+
+EXAMPLES:
+  # Most recent sessions
+  rag-cli history list
+
+  # Full log of one session
+  rag-cli history show cmd_session_1722513600
+
+  # The latest saved chat transcript (see chat.save_transcripts), or a given one
+  rag-cli history show
+  rag-cli history show 2026-10-01T09-30-00.jsonl
+
+  # Sessions related to a topic
+  rag-cli history search "docker cleanup"
+
+  # Forget sessions older than two months
+  rag-cli history prune --older-than 60d`,
+}
+
+var historyListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the most recent command sessions",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if historyLimit <= 0 {
+			return fmt.Errorf("--limit must be greater than 0")
+		}
+
+		vectorStore, err := newHistoryStore()
+		if err != nil {
+			return err
+		}
+		return runHistoryList(os.Stdout, vectorStore, historyLimit, historyJSON)
+	},
+}
+
+var historyShowCmd = &cobra.Command{
+	Use:   "show [id|file]",
+	Short: "Show the full log of a command session or a chat transcript",
+	Long: `Show the full log of a stored command session, given its ID, or a chat
+transcript saved with chat.save_transcripts, given its file name or path.
+With no argument, the latest transcript is shown.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 || isTranscriptRef(args[0]) {
+			dir, err := transcript.DefaultDir()
+			if err != nil {
+				return err
+			}
+			ref := ""
+			if len(args) > 0 {
+				ref = args[0]
+			}
+			path, err := resolveTranscript(dir, ref)
+			if err != nil {
+				return err
+			}
+			return runTranscriptShow(os.Stdout, path, historyJSON)
+		}
+
+		vectorStore, err := newHistoryStore()
+		if err != nil {
+			return err
+		}
+		return runHistoryShow(os.Stdout, vectorStore, args[0], historyJSON)
+	},
+}
+
+var historySearchCmd = &cobra.Command{
+	Use:   "search <query>",
+	Short: "Semantic search over stored command sessions",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if historyTopK <= 0 {
+			return fmt.Errorf("--top-k must be greater than 0")
+		}
+
+		cfg, err := config.Load()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		embeddingClient, err := embeddings.NewClient(cfg.Embeddings, cfg.Timeouts)
+		if err != nil {
+			return fmt.Errorf("failed to initialize embedding client: %w", err)
+		}
+
+		vectorStore, err := vector.NewChromaClient(cfg.Vector, cfg.Timeouts)
+		if err != nil {
+			return fmt.Errorf("failed to initialize vector store: %w", err)
+		}
+
+		return runHistorySearch(os.Stdout, embeddingClient, vectorStore, args[0], historyTopK, historyJSON)
+	},
+}
+
+var historyPruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove old or failed command sessions",
+	Long: `Remove command sessions from the history collection. At least one selector is required:
+
+  --older-than AGE   Remove sessions older than AGE, e.g. 60d, 2w, or 12h
+  --keep-last N      Keep only the N most recent sessions
+  --failed-only      Only remove failed sessions; on its own, removes every failed session
+
+--older-than and --keep-last can be combined; a session matching either is removed.
+Automatic retention can be configured with history.retention_days and
+history.max_sessions, which are applied whenever a chat session starts.
+
+EXAMPLES:
+  # Forget sessions older than 60 days
+  rag-cli history prune --older-than 60d
+
+  # Keep the newest 500 sessions
+  rag-cli history prune --keep-last 500 --yes
+
+  # Drop failed attempts older than a week
+  rag-cli history prune --failed-only --older-than 1w`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opts := history.PruneOptions{
+			KeepLast:   historyPruneKeepLast,
+			FailedOnly: historyPruneFailedOnly,
+		}
+		if historyPruneOlderThan != "" {
+			age, err := parseAge(historyPruneOlderThan)
+			if err != nil {
+				return err
+			}
+			opts.OlderThan = age
+		}
+		if historyPruneKeepLast < 0 {
+			return fmt.Errorf("--keep-last must not be negative")
+		}
+		if opts.IsZero() {
+			return fmt.Errorf("nothing selected: use --older-than, --keep-last, or --failed-only")
+		}
+
+		vectorStore, err := newHistoryStore()
+		if err != nil {
+			return err
+		}
+		return runHistoryPrune(os.Stdin, os.Stdout, vectorStore, opts, historyPruneYes, time.Now())
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+	historyCmd.AddCommand(historyListCmd)
+	historyCmd.AddCommand(historyShowCmd)
+	historyCmd.AddCommand(historySearchCmd)
+	historyCmd.AddCommand(historyPruneCmd)
+
+	historyCmd.PersistentFlags().BoolVar(&historyJSON, "json", false, "Output in JSON format")
+	historyListCmd.Flags().IntVarP(&historyLimit, "limit", "n", 20, "Number of sessions to list")
+	historySearchCmd.Flags().IntVarP(&historyTopK, "top-k", "k", 5, "Number of results to return")
+	historyPruneCmd.Flags().StringVar(&historyPruneOlderThan, "older-than", "", "Remove sessions older than this age (e.g. 60d, 2w, 12h)")
+	historyPruneCmd.Flags().IntVar(&historyPruneKeepLast, "keep-last", 0, "Keep only this many of the most recent sessions")
+	historyPruneCmd.Flags().BoolVar(&historyPruneFailedOnly, "failed-only", false, "Only remove failed sessions")
+	historyPruneCmd.Flags().BoolVarP(&historyPruneYes, "yes", "y", false, "Skip the confirmation prompt")
+}
+
+func newHistoryStore() (*vector.ChromaClient, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	vectorStore, err := vector.NewChromaClient(cfg.Vector, cfg.Timeouts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize vector store: %w", err)
+	}
+	return vectorStore, nil
+}
+
+// historyEntry is a stored command session as presented by the history command
+type historyEntry struct {
+	ID        string     `json:"id"`
+	Timestamp *time.Time `json:"timestamp,omitempty"`
+	Success   bool       `json:"success"`
+	Summary   string     `json:"summary"`
+	Log       string     `json:"log,omitempty"`
+}
+
+func newHistoryEntry(doc vector.StoredDocument) historyEntry {
+	entry := historyEntry{
+		ID:      doc.ID,
+		Success: history.Succeeded(doc),
+		Summary: sessionSummary(doc),
+		Log:     doc.Document,
+	}
+	if created, ok := history.Timestamp(doc); ok {
+		entry.Timestamp = &created
+	}
+	return entry
+}
+
+func runHistoryList(out io.Writer, store vector.VectorStore, limit int, asJSON bool) error {
+	docs, err := store.GetDocuments(store.CommandsCollection(), 0)
+	if err != nil {
+		return fmt.Errorf("failed to read command sessions: %w", err)
+	}
+
+	history.SortNewestFirst(docs)
+	entries := make([]historyEntry, 0, len(docs))
+	for _, doc := range docs {
+		entry := newHistoryEntry(doc)
+		entry.Log = ""
+		entries = append(entries, entry)
+	}
+	if len(entries) > limit {
+		entries = entries[:limit]
+	}
+
+	if asJSON {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(entries)
+	}
+
+	if len(entries) == 0 {
+		fmt.Fprintln(out, "No command sessions stored yet")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tTIME\tSTATUS\tSUMMARY")
+	for _, entry := range entries {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", entry.ID, formatEntryTime(entry), formatEntryStatus(entry), entry.Summary)
+	}
+	return w.Flush()
+}
+
+func runHistoryShow(out io.Writer, store vector.VectorStore, id string, asJSON bool) error {
+	doc, err := store.GetDocument(store.CommandsCollection(), id)
+	if err != nil {
+		return fmt.Errorf("failed to read command session: %w", err)
+	}
+	if doc == nil {
+		return fmt.Errorf("no command session with ID %s (see 'rag-cli history list')", id)
+	}
+
+	entry := newHistoryEntry(*doc)
+	if asJSON {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(entry)
+	}
+
+	fmt.Fprintf(out, "Session: %s\n", entry.ID)
+	fmt.Fprintf(out, "Time:    %s\n", formatEntryTime(entry))
+	fmt.Fprintf(out, "Status:  %s\n\n", formatEntryStatus(entry))
+	fmt.Fprintln(out, strings.TrimRight(entry.Log, "\n"))
+	return nil
+}
+
+// isTranscriptRef reports whether a history show argument names a transcript
+// file rather than a stored session ID
+func isTranscriptRef(arg string) bool {
+	return strings.HasSuffix(arg, ".jsonl") || strings.ContainsRune(arg, os.PathSeparator)
+}
+
+// resolveTranscript finds the transcript ref names: a path as given, or a file
+// in dir. An empty ref is the latest transcript in dir.
+func resolveTranscript(dir, ref string) (string, error) {
+	if ref == "" {
+		files, err := transcript.List(dir)
+		if err != nil {
+			return "", err
+		}
+		if len(files) == 0 {
+			return "", fmt.Errorf("no chat transcripts in %s (enable them with chat.save_transcripts)", dir)
+		}
+		return files[len(files)-1], nil
+	}
+	if _, err := os.Stat(ref); err == nil {
+		return ref, nil
+	}
+	if !strings.ContainsRune(ref, os.PathSeparator) {
+		path := filepath.Join(dir, ref)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("no chat transcript %s", ref)
+}
+
+func runTranscriptShow(out io.Writer, path string, asJSON bool) error {
+	events, err := transcript.Load(path)
+	if err != nil {
+		return err
+	}
+
+	if asJSON {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(events)
+	}
+
+	fmt.Fprintf(out, "Transcript: %s\n\n", path)
+	chat.PrintTranscript(out, events)
+	return nil
+}
+
+func runHistorySearch(out io.Writer, embedder embeddings.Embedder, store vector.VectorStore, query string, topK int, asJSON bool) error {
+	return runSearch(out, embedder, store, query, "commands", topK, asJSON)
+}
+
+func runHistoryPrune(in io.Reader, out io.Writer, store vector.VectorStore, opts history.PruneOptions, yes bool, now time.Time) error {
+	sessions, err := store.GetDocuments(store.CommandsCollection(), 0)
+	if err != nil {
+		return fmt.Errorf("failed to read command sessions: %w", err)
+	}
+
+	ids := history.Select(sessions, opts, now)
+	if len(ids) == 0 {
+		fmt.Fprintln(out, "No matching command sessions, nothing to prune")
+		return nil
+	}
+
+	if !yes {
+		fmt.Fprintf(out, "Remove %d of %d command session(s)? [y/N]: ", len(ids), len(sessions))
+		if !confirm(in) {
+			fmt.Fprintln(out, "Prune cancelled")
+			return nil
+		}
+	}
+
+	if err := store.DeleteDocuments(store.CommandsCollection(), ids); err != nil {
+		return fmt.Errorf("failed to delete command sessions: %w", err)
+	}
+	fmt.Fprintf(out, "Removed %d command session(s), %d remaining\n", len(ids), len(sessions)-len(ids))
+	return nil
+}
+
+// sessionSummary describes what a session did: the original request when it
+// was recorded, otherwise the commands that were run
+func sessionSummary(doc vector.StoredDocument) string {
+	if request, ok := doc.Metadata["request"].(string); ok && request != "" {
+		return snippet(request, historySummaryLength)
+	}
+
+	var commands []string
+	for _, line := range strings.Split(doc.Document, "\n") {
+		if strings.HasPrefix(line, "$ ") {
+			commands = append(commands, strings.TrimPrefix(line, "$ "))
+		}
+	}
+	if len(commands) > 0 {
+		return snippet(strings.Join(commands, "; "), historySummaryLength)
+	}
+	return snippet(strings.TrimPrefix(doc.Document, "Command execution session:"), historySummaryLength)
+}
+
+func formatEntryTime(entry historyEntry) string {
+	if entry.Timestamp == nil {
+		return "-"
+	}
+	return entry.Timestamp.Local().Format("2006-01-02 15:04")
+}
+
+func formatEntryStatus(entry historyEntry) string {
+	if entry.Success {
+		return "ok"
+	}
+	return "failed"
+}
@@ -0,0 +1,265 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"rag-cli/internal/history"
+	"rag-cli/internal/transcript"
+	"rag-cli/internal/vector"
+)
+
+func newHistoryFixture() *fakeStore {
+	store := newFakeStore()
+	store.documents["command_history"] = []vector.StoredDocument{
+		{ID: "cmd_session_1700000000", Document: "Command execution session:\n$ ls -la\ntotal 0\n\n$ pwd\n/tmp\n\n"},
+		{ID: "cmd_session_1750000000", Document: "Command execution session:\n$ cat missing.txt\nError: exit status 1\n\n"},
+		{ID: "legacy", Document: "Command execution session:\nno commands recorded"},
+		{ID: "cmd_session_1720000000", Document: "Command execution session:\n$ date\n", Metadata: map[string]interface{}{"request": "what time is it", "success": true}},
+	}
+	store.results["command_history"] = []vector.SearchResult{
+		{ID: "cmd_session_1700000000", Document: "$ ls -la", Distance: 0.2},
+	}
+	return store
+}
+
+func TestRunHistoryList(t *testing.T) {
+	t.Run("newest first with summaries", func(t *testing.T) {
+		var out bytes.Buffer
+		if err := runHistoryList(&out, newHistoryFixture(), 20, false); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		if len(lines) != 5 {
+			t.Fatalf("Expected header and 4 sessions, got:\n%s", out.String())
+		}
+		order := []string{"cmd_session_1750000000", "cmd_session_1720000000", "cmd_session_1700000000", "legacy"}
+		for i, id := range order {
+			if !strings.HasPrefix(lines[i+1], id) {
+				t.Errorf("Expected line %d to be session %s, got %q", i+1, id, lines[i+1])
+			}
+		}
+
+		expected := []string{"ls -la; pwd", "what time is it", "failed", "no commands recorded"}
+		for _, want := range expected {
+			if !strings.Contains(out.String(), want) {
+				t.Errorf("Expected output to contain %q, got:\n%s", want, out.String())
+			}
+		}
+	})
+
+	t.Run("limit and json", func(t *testing.T) {
+		var out bytes.Buffer
+		if err := runHistoryList(&out, newHistoryFixture(), 2, true); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		var entries []historyEntry
+		if err := json.Unmarshal(out.Bytes(), &entries); err != nil {
+			t.Fatalf("Expected valid JSON, got %v:\n%s", err, out.String())
+		}
+		if len(entries) != 2 {
+			t.Fatalf("Expected 2 entries, got %d", len(entries))
+		}
+		if entries[0].Success || !entries[1].Success {
+			t.Errorf("Expected success flags [false true], got [%t %t]", entries[0].Success, entries[1].Success)
+		}
+		if entries[0].Log != "" {
+			t.Error("Expected list output to omit session logs")
+		}
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		var out bytes.Buffer
+		if err := runHistoryList(&out, newFakeStore(), 20, false); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !strings.Contains(out.String(), "No command sessions stored yet") {
+			t.Errorf("Expected empty message, got %q", out.String())
+		}
+	})
+}
+
+func TestRunHistoryShow(t *testing.T) {
+	t.Run("full log", func(t *testing.T) {
+		var out bytes.Buffer
+		if err := runHistoryShow(&out, newHistoryFixture(), "cmd_session_1700000000", false); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		for _, want := range []string{"Session: cmd_session_1700000000", "Status:  ok", "$ pwd\n/tmp"} {
+			if !strings.Contains(out.String(), want) {
+				t.Errorf("Expected output to contain %q, got:\n%s", want, out.String())
+			}
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var out bytes.Buffer
+		if err := runHistoryShow(&out, newHistoryFixture(), "cmd_session_1750000000", true); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		var entry historyEntry
+		if err := json.Unmarshal(out.Bytes(), &entry); err != nil {
+			t.Fatalf("Expected valid JSON, got %v", err)
+		}
+		if entry.Success || !strings.Contains(entry.Log, "missing.txt") || entry.Timestamp == nil {
+			t.Errorf("Unexpected entry: %+v", entry)
+		}
+	})
+
+	t.Run("unknown id", func(t *testing.T) {
+		err := runHistoryShow(&bytes.Buffer{}, newHistoryFixture(), "nope", false)
+		if err == nil || !strings.Contains(err.Error(), "no command session") {
+			t.Errorf("Expected not found error, got %v", err)
+		}
+	})
+}
+
+func TestRunTranscriptShow(t *testing.T) {
+	dir := t.TempDir()
+	older := filepath.Join(dir, "2026-10-01T09-30-00.jsonl")
+	latest := filepath.Join(dir, "2026-10-02T08-00-00.jsonl")
+	lines := []string{
+		`{"timestamp":"2026-10-02T08:00:00Z","type":"user","content":"where am I"}`,
+		`{"timestamp":"2026-10-02T08:00:01Z","type":"ai","content":"pwd"}`,
+		`{"timestamp":"2026-10-02T08:00:02Z","type":"command","content":"pwd"}`,
+		`{"timestamp":"2026-10-02T08:00:02Z","type":"output","content":"/tmp\n"}`,
+	}
+	if err := os.WriteFile(older, []byte(lines[0]+"\n"), 0600); err != nil {
+		t.Fatalf("Failed to write transcript: %v", err)
+	}
+	if err := os.WriteFile(latest, []byte(strings.Join(lines, "\n")+"\n"), 0600); err != nil {
+		t.Fatalf("Failed to write transcript: %v", err)
+	}
+
+	t.Run("latest by default", func(t *testing.T) {
+		path, err := resolveTranscript(dir, "")
+		if err != nil || path != latest {
+			t.Fatalf("Expected the latest transcript, got %q, %v", path, err)
+		}
+
+		var out bytes.Buffer
+		if err := runTranscriptShow(&out, path, false); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		for _, want := range []string{"> where am I", "AI: pwd", "$ pwd", "/tmp"} {
+			if !strings.Contains(out.String(), want) {
+				t.Errorf("Expected output to contain %q, got:\n%s", want, out.String())
+			}
+		}
+	})
+
+	t.Run("by name and json", func(t *testing.T) {
+		path, err := resolveTranscript(dir, "2026-10-01T09-30-00.jsonl")
+		if err != nil || path != older {
+			t.Fatalf("Expected the named transcript, got %q, %v", path, err)
+		}
+
+		var out bytes.Buffer
+		if err := runTranscriptShow(&out, path, true); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		var events []transcript.Event
+		if err := json.Unmarshal(out.Bytes(), &events); err != nil {
+			t.Fatalf("Expected valid JSON, got %v", err)
+		}
+		if len(events) != 1 || events[0].Type != transcript.User {
+			t.Errorf("Unexpected events: %+v", events)
+		}
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		if _, err := resolveTranscript(dir, "nope.jsonl"); err == nil {
+			t.Error("Expected an error for an unknown transcript")
+		}
+		if _, err := resolveTranscript(t.TempDir(), ""); err == nil || !strings.Contains(err.Error(), "chat.save_transcripts") {
+			t.Errorf("Expected a hint to enable transcripts, got %v", err)
+		}
+	})
+
+	if !isTranscriptRef("session.jsonl") || isTranscriptRef("cmd_session_1700000000") {
+		t.Error("Expected file names, not session IDs, to be treated as transcripts")
+	}
+}
+
+func TestRunHistorySearch(t *testing.T) {
+	store := newHistoryFixture()
+	var out bytes.Buffer
+	if err := runHistorySearch(&out, &fakeEmbedder{}, store, "list files", 3, false); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if store.searchedCollection != "command_history" || store.searchedTopK != 3 {
+		t.Errorf("Expected search of command_history with top-k 3, got %s/%d", store.searchedCollection, store.searchedTopK)
+	}
+	if !strings.Contains(out.String(), "cmd_session_1700000000") {
+		t.Errorf("Expected matching session in output, got:\n%s", out.String())
+	}
+}
+
+func newPruneFixture() *fakeStore {
+	store := newFakeStore()
+	ts := func(days int) string {
+		return purgeNow.Add(-time.Duration(days) * 24 * time.Hour).Format(time.RFC3339)
+	}
+	store.documents["command_history"] = []vector.StoredDocument{
+		{ID: "s1", Document: "$ ls", Metadata: map[string]interface{}{"timestamp": ts(1)}},
+		{ID: "s2", Document: "$ cat x\nError: exit status 1", Metadata: map[string]interface{}{"timestamp": ts(10)}},
+		{ID: "s3", Document: "$ pwd", Metadata: map[string]interface{}{"timestamp": ts(90)}},
+		{ID: "s4", Document: "$ make\nError: exit status 2", Metadata: map[string]interface{}{"timestamp": ts(120)}},
+	}
+	return store
+}
+
+func TestRunHistoryPrune(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     history.PruneOptions
+		expected []string
+	}{
+		{"older than", history.PruneOptions{OlderThan: 60 * 24 * time.Hour}, []string{"s3", "s4"}},
+		{"keep last", history.PruneOptions{KeepLast: 1}, []string{"s2", "s3", "s4"}},
+		{"failed only", history.PruneOptions{FailedOnly: true}, []string{"s2", "s4"}},
+		{"failed and older than", history.PruneOptions{FailedOnly: true, OlderThan: 60 * 24 * time.Hour}, []string{"s4"}},
+		{"older than or beyond keep last", history.PruneOptions{OlderThan: 100 * 24 * time.Hour, KeepLast: 2}, []string{"s3", "s4"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newPruneFixture()
+			var out bytes.Buffer
+			if err := runHistoryPrune(strings.NewReader(""), &out, store, tt.opts, true, purgeNow); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(store.deleted["command_history"], tt.expected) {
+				t.Errorf("Expected %v to be removed, got %v", tt.expected, store.deleted["command_history"])
+			}
+			want := fmt.Sprintf("Removed %d command session(s), %d remaining", len(tt.expected), 4-len(tt.expected))
+			if !strings.Contains(out.String(), want) {
+				t.Errorf("Expected output to contain %q, got %q", want, out.String())
+			}
+		})
+	}
+
+	t.Run("declined confirmation", func(t *testing.T) {
+		store := newPruneFixture()
+		var out bytes.Buffer
+		if err := runHistoryPrune(strings.NewReader("n\n"), &out, store, history.PruneOptions{KeepLast: 1}, false, purgeNow); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(store.deleted["command_history"]) != 0 {
+			t.Errorf("Expected nothing to be removed, got %v", store.deleted["command_history"])
+		}
+		if !strings.Contains(out.String(), "Prune cancelled") {
+			t.Errorf("Expected cancellation message, got %q", out.String())
+		}
+	})
+}
@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"rag-cli/internal/vector"
+	"rag-cli/pkg/config"
+)
+
+var (
+	importCollection   string
+	importSkipExisting bool
+	importMerge        bool
+)
+
+var importCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Restore a collection from a JSON Lines export",
+	Long: `Load documents written by 'rag-cli export' into a collection, keeping their IDs,
+metadata, and embeddings. Use - to read from standard input.
+
+Importing into a collection that already has documents is refused unless --merge is
+passed, so a backup is not mixed into existing data by accident. With --skip-existing,
+documents whose ID is already in the collection are left as they are.
+
+Every embedding in the file must have the same dimension, which must also match the
+documents already in the collection when merging. Embeddings from a different
+embedding model cannot be searched together, so a mismatch stops the import.
+
+EXAMPLES:
+  # Restore a backup into an empty documents collection
+  rag-cli import docs.jsonl --collection documents
+
+  # Add a colleague's corpus to your own, keeping your copies of shared documents
+  rag-cli import team-docs.jsonl --merge --skip-existing
+
+  # Restore compressed command history
+  gunzip -c history.jsonl.gz | rag-cli import - --collection commands`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		vectorStore, err := vector.NewChromaClient(cfg.Vector, cfg.Timeouts)
+		if err != nil {
+			return fmt.Errorf("failed to initialize vector store: %w", err)
+		}
+
+		var in io.Reader = os.Stdin
+		if args[0] != "-" {
+			file, err := os.Open(args[0])
+			if err != nil {
+				return fmt.Errorf("failed to open import file: %w", err)
+			}
+			defer file.Close()
+			in = file
+		}
+
+		return runImport(in, os.Stderr, vectorStore, importCollection, importOptions{
+			skipExisting: importSkipExisting,
+			merge:        importMerge,
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(importCmd)
+
+	importCmd.Flags().StringVarP(&importCollection, "collection", "c", "documents", "Collection to import into: documents, commands, auto, or any collection name, created if needed")
+	importCmd.Flags().BoolVar(&importSkipExisting, "skip-existing", false, "Leave documents whose ID is already in the collection untouched")
+	importCmd.Flags().BoolVar(&importMerge, "merge", false, "Allow importing into a collection that already has documents")
+}
+
+type importOptions struct {
+	skipExisting bool
+	merge        bool
+}
+
+// runImport loads an export into a collection, reporting progress and the
+// final counts on status
+func runImport(in io.Reader, status io.Writer, store vector.VectorStore, collection string, opts importOptions) error {
+	collectionName, exists, err := importTarget(store, collection)
+	if err != nil {
+		return err
+	}
+
+	dimension := 0
+	if exists {
+		count, err := store.Count(collectionName)
+		if err != nil {
+			return fmt.Errorf("failed to count documents in %s: %w", collectionName, err)
+		}
+		if count > 0 {
+			if !opts.merge {
+				return fmt.Errorf("collection %s already has %d document(s); pass --merge to import into it anyway", collectionName, count)
+			}
+			sample, err := store.ExportDocuments(collectionName, 0, 1)
+			if err != nil {
+				return fmt.Errorf("failed to read documents from %s: %w", collectionName, err)
+			}
+			if len(sample) > 0 {
+				dimension = len(sample[0].Embedding)
+			}
+		}
+	}
+
+	result, err := vector.Import(store, collectionName, in, vector.ImportOptions{
+		SkipExisting: opts.skipExisting,
+		Dimension:    dimension,
+		Progress: func(read int) {
+			fmt.Fprintf(status, "\rImported %d document(s)...", read)
+		},
+	})
+	if result.Imported+result.Skipped > 0 {
+		fmt.Fprintln(status)
+	}
+	if err != nil {
+		return fmt.Errorf("import stopped after %d document(s): %w", result.Imported, err)
+	}
+
+	fmt.Fprintf(status, "Imported %d document(s) into %s", result.Imported, collectionName)
+	if result.Skipped > 0 {
+		fmt.Fprintf(status, ", skipped %d existing", result.Skipped)
+	}
+	fmt.Fprintln(status)
+	return nil
+}
+
+// importTarget resolves the collection to import into and whether it exists.
+// Unlike other commands, import accepts the name of a collection that does
+// not exist yet; it is created when the first document is added.
+func importTarget(store vector.VectorStore, collection string) (string, bool, error) {
+	name, ok := collectionAlias(store, collection)
+	if !ok {
+		name = collection
+	}
+
+	collections, err := store.ListCollections()
+	if err != nil {
+		return "", false, fmt.Errorf("failed to list collections: %w", err)
+	}
+	for _, info := range collections {
+		if info.Name == name {
+			return name, true, nil
+		}
+	}
+	return name, false, nil
+}
@@ -1,14 +1,27 @@
 package cmd
 
 import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
 	"os"
 	"path/filepath"
+	"runtime"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/spf13/cobra"
 	"rag-cli/internal/chunker"
 	"rag-cli/internal/embeddings"
+	"rag-cli/internal/extract"
+	"rag-cli/internal/indexing"
 	"rag-cli/internal/vector"
 	"rag-cli/pkg/config"
 )
@@ -16,22 +29,90 @@ import (
 var (
 	indexRecursive bool
 	indexFormats   []string
+	indexExcludes  []string
+	indexWorkers   int
+
+	indexURLs         []string
+	indexURLsFile     string
+	indexFetchTimeout time.Duration
+
+	indexCollection string
+	indexForce      bool
+	indexWatch      bool
+
+	indexStdin bool
+	indexName  string
 )
 
+// errUnchanged is returned for a file whose content has not changed since it
+// was last indexed into the collection
+var errUnchanged = errors.New("unchanged since it was last indexed")
+
+// errBinary is returned for content that is not text, which is skipped with a
+// warning rather than indexed as noise
+var errBinary = errors.New("binary content")
+
+// binarySniffBytes is how much of a file is checked for NUL bytes, as git
+// does, to tell binary content from text
+const binarySniffBytes = 8000
+
+// defaultIndexFormats are the file extensions indexed when --formats is not given
+var defaultIndexFormats = []string{"txt", "md", "go", "py", "js", "ts", "json", "yaml", "yml", "html", "htm", "docx"}
+
 var indexCmd = &cobra.Command{
-	Use:   "index [path]",
+	Use:   "index [path | --stdin --name NAME]",
 	Short: "Index documents for RAG",
 	Long: `Index documents by chunking them, generating embeddings, and storing them in the vector database.
 This enables the AI to use these documents as context for responses and improves the quality
 of AI-generated answers by providing relevant background information.
 
 The indexing process:
-1. Scans files in the specified directory (or current directory if none specified)
+1. Scans files in the specified directory (or current directory if none specified), or
+   reads the one file or the standard input given
 2. Chunks large documents into manageable pieces (default: 1000 chars with 200 char overlap)
 3. Generates embeddings for each chunk using the configured embedding model
 4. Stores chunks and embeddings in ChromaDB for fast semantic search
 
-Supported file formats: txt, md, go, py, js, ts, json, yaml, yml (configurable)
+Supported file formats: txt, md, go, py, js, ts, json, yaml, yml, html, htm, docx (configurable)
+
+A path naming a file indexes just that file, whatever its extension: --formats and
+--exclude only filter the files found in a directory. --stdin indexes what is piped in
+instead, stored with --name as its source. Content that is not text, such as an image
+or an executable, is skipped with a warning.
+
+HTML files are reduced to their title and main readable text, and .docx documents to their
+paragraph text. Files whose structure cannot be read are skipped with a warning rather than
+indexed as raw markup.
+
+Files are processed by a pool of workers (--workers or --concurrency, default index.workers
+or 4, fewer on machines with fewer cores). Each worker embeds one file at a time, so N workers keep up to N embedding
+requests in flight. A single local Ollama instance typically saturates at 2-4 concurrent
+requests; raising --workers beyond that adds load without speeding up indexing.
+Progress lines show the files processed so far and the throughput; errors for files that
+could not be indexed are listed at the end. Ctrl+C stops starting new files, lets the
+ones in progress finish, and saves what was indexed.
+
+Web pages can be indexed with --url (repeatable) or --urls-file, a file with one URL per
+line. Each page's title and main content are extracted from the HTML and stored with the
+URL as its source. Non-HTML responses are refused. When only URLs are given, no local
+files are indexed.
+
+Chunks are stored in the configured documents collection (vector.collection). Use
+--collection to index into a different collection instead, which is created if it
+does not exist yet; chat, ask, and search accept the same flag to use it.
+
+Paths can be skipped with gitignore-style --exclude patterns, which are combined with
+index.exclude_patterns from the config file. Exclusions take precedence over --formats.
+
+Each indexed file is recorded in an index manifest with its content hash and the IDs of
+the documents stored for it. Running index again skips files whose content has not
+changed, and replaces the documents of files that have, so nothing is stored twice.
+--force indexes every file again regardless, still replacing what was stored before.
+
+--watch keeps running after startup and indexes files as they are created or edited,
+exactly like the watch command: see rag-cli watch --help. It always watches the whole
+tree and indexes into the auto-index collection with the auto_index settings, so it
+cannot be combined with --collection, --exclude, --formats, --force or --workers.
 
 EXAMPLES:
   # Index current directory (non-recursive)
@@ -40,18 +121,89 @@ EXAMPLES:
   # Index specific directory recursively
   rag-cli index -r /path/to/docs
 
+  # Index one file
+  rag-cli index notes/meeting.md
+
+  # Index the clipboard
+  pbpaste | rag-cli index --stdin --name clipboard-note
+
   # Index with specific file formats
   rag-cli index -f txt,md,go /path/to/project
 
   # Index documentation recursively with multiple formats
-  rag-cli index -r -f md,txt,rst ~/projects/my-docs`,
+  rag-cli index -r -f md,txt,rst ~/projects/my-docs
+
+  # Index with four parallel workers
+  rag-cli index -r --workers 4 ~/projects/my-docs
+
+  # Skip vendored dependencies and generated files
+  rag-cli index -r --exclude vendor/ --exclude '*.pb.go' .
+
+  # Index every file again, even those that have not changed
+  rag-cli index -r --force ~/projects/my-docs
+
+  # Keep indexing a notes directory as files change, until Ctrl+C
+  rag-cli index --watch ~/notes
+
+  # Index a one-off corpus into its own collection
+  rag-cli index -r --collection project-x ~/projects/x/docs
+
+  # Index web pages
+  rag-cli index --url https://example.com/docs/page --url https://example.com/docs/faq
+  rag-cli index --urls-file bookmarks.txt`,
 	Args: cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if indexWorkers < 0 {
+			return fmt.Errorf("--workers must not be negative")
+		}
+
+		urls, err := collectURLs(indexURLs, indexURLsFile)
+		if err != nil {
+			return err
+		}
+
+		if indexStdin {
+			if indexName == "" {
+				return fmt.Errorf("--stdin requires --name, the source to store the document under")
+			}
+			if len(args) > 0 || len(urls) > 0 || indexWatch {
+				return fmt.Errorf("--stdin cannot be combined with a path, --url, --urls-file or --watch")
+			}
+			content, err := io.ReadAll(cmd.InOrStdin())
+			if err != nil {
+				return fmt.Errorf("failed to read stdin: %w", err)
+			}
+			if len(bytes.TrimSpace(content)) == 0 {
+				return fmt.Errorf("nothing to index: stdin is empty")
+			}
+			return runIndex(cmd.Context(), "", nil, &stdinDocument{name: indexName, content: content})
+		}
+		if indexName != "" {
+			return fmt.Errorf("--name is only used with --stdin")
+		}
+
+		if indexWatch {
+			if len(urls) > 0 {
+				return fmt.Errorf("--watch watches local files and cannot be combined with --url or --urls-file")
+			}
+			if err := watchConflict(cmd.Flags().Changed); err != nil {
+				return err
+			}
+			path := "."
+			if len(args) > 0 {
+				path = args[0]
+			}
+			return runWatch(cmd.Context(), path)
+		}
+
+		// Index the current directory unless only URLs were given
 		path := "."
 		if len(args) > 0 {
 			path = args[0]
+		} else if len(urls) > 0 {
+			path = ""
 		}
-		return runIndex(path)
+		return runIndex(cmd.Context(), path, urls, nil)
 	},
 }
 
@@ -59,60 +211,383 @@ func init() {
 	rootCmd.AddCommand(indexCmd)
 	
 	indexCmd.Flags().BoolVarP(&indexRecursive, "recursive", "r", false, "Index directories recursively, including all subdirectories")
-	indexCmd.Flags().StringSliceVarP(&indexFormats, "formats", "f", []string{"txt", "md", "go", "py", "js", "ts", "json", "yaml", "yml"}, "Comma-separated list of file extensions to index (without dots)")
+	indexCmd.Flags().StringSliceVarP(&indexFormats, "formats", "f", defaultIndexFormats, "Comma-separated list of file extensions to index (without dots)")
+	indexCmd.Flags().IntVarP(&indexWorkers, "workers", "w", 0, "Number of files to index in parallel (default: index.workers or 4)")
+	indexCmd.Flags().IntVar(&indexWorkers, "concurrency", 0, "Same as --workers")
+	indexCmd.Flags().StringArrayVarP(&indexExcludes, "exclude", "x", nil, "gitignore-style pattern of paths to skip (repeatable), e.g. vendor/ or '*.min.js'")
+	indexCmd.Flags().StringArrayVar(&indexURLs, "url", nil, "URL of a web page to index (repeatable)")
+	indexCmd.Flags().StringVar(&indexURLsFile, "urls-file", "", "File listing URLs to index, one per line")
+	indexCmd.Flags().StringVarP(&indexCollection, "collection", "c", "", "Collection to index into instead of vector.collection, created if needed")
+	indexCmd.Flags().BoolVar(&indexWatch, "watch", false, "Keep running and index files as they change, like the watch command")
+	indexCmd.Flags().BoolVar(&indexForce, "force", false, "Index every file again, even if the index manifest shows it has not changed")
+	indexCmd.Flags().BoolVar(&indexStdin, "stdin", false, "Index the text piped to standard input instead of files")
+	indexCmd.Flags().StringVar(&indexName, "name", "", "Source to store the --stdin document under, such as clipboard-note")
+	indexCmd.Flags().DurationVar(&indexFetchTimeout, "fetch-timeout", extract.DefaultFetchTimeout, "Timeout for fetching each URL")
+}
+
+// watchUnsupportedFlags are index flags the watcher has no use for, since it
+// indexes with the auto_index settings
+var watchUnsupportedFlags = []string{"collection", "exclude", "formats", "force", "workers", "concurrency"}
+
+// watchConflict returns an error naming the first flag given, as reported by
+// changed, that --watch would otherwise ignore
+func watchConflict(changed func(name string) bool) error {
+	for _, name := range watchUnsupportedFlags {
+		if changed(name) {
+			return fmt.Errorf("--watch indexes into the auto-index collection with the auto_index settings and cannot be combined with --%s", name)
+		}
+	}
+	return nil
 }
 
-func runIndex(path string) error {
+// stdinDocument is text read from standard input to index under name
+type stdinDocument struct {
+	name    string
+	content []byte
+}
+
+// runIndex indexes the files at path, which may be a directory or a single
+// file, the web pages at urls and, when not nil, a document read from stdin
+func runIndex(ctx context.Context, path string, urls []string, stdin *stdinDocument) error {
 	cfg, err := config.Load()
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
+	overrideDocumentsCollection(cfg, indexCollection)
 
 	// Initialize components
-	embeddingClient, err := embeddings.NewClient(cfg.Embeddings)
+	embeddingClient, err := embeddings.NewClient(cfg.Embeddings, cfg.Timeouts)
 	if err != nil {
 		return fmt.Errorf("failed to initialize embedding client: %w", err)
 	}
 
-	vectorStore, err := vector.NewChromaClient(cfg.Vector)
+	vectorStore, err := vector.NewChromaClient(cfg.Vector, cfg.Timeouts)
 	if err != nil {
 		return fmt.Errorf("failed to initialize vector store: %w", err)
 	}
 
 	chunkerClient := chunker.New(cfg.Chunker)
 
-	// Get files to index
-	files, err := getFilesToIndex(path, indexFormats, indexRecursive)
+	excludes, err := indexing.NewExcludeMatcher(append(cfg.Index.ExcludePatterns, indexExcludes...))
 	if err != nil {
-		return fmt.Errorf("failed to get files to index: %w", err)
+		return err
 	}
 
-	fmt.Printf("Found %d files to index\n", len(files))
+	// Get files to index
+	var files []string
+	skipped := 0
+	if path != "" {
+		files, skipped, err = getFilesToIndex(path, indexFormats, indexRecursive, excludes)
+		if err != nil {
+			return fmt.Errorf("failed to get files to index: %w", err)
+		}
+
+		fmt.Printf("Found %d files to index\n", len(files))
+		if skipped > 0 {
+			fmt.Printf("Skipped %d excluded path(s)\n", skipped)
+		}
+	}
+	if len(urls) > 0 {
+		fmt.Printf("Found %d URL(s) to index\n", len(urls))
+	}
+	sources := append(files, urls...)
+	if stdin != nil {
+		fmt.Printf("Indexing stdin as %s\n", stdin.name)
+		sources = append(sources, stdin.name)
+	}
 
-	// Process each file
-	for i, file := range files {
-		fmt.Printf("Processing file %d/%d: %s\n", i+1, len(files), file)
-		
-		if err := processFile(file, chunkerClient, embeddingClient, vectorStore); err != nil {
-			fmt.Printf("Error processing file %s: %v\n", file, err)
-			continue
+	workers := resolveWorkers(indexWorkers, cfg.Index.Workers)
+	fmt.Printf("Indexing with %d worker(s)\n", workers)
+
+	isURL := make(map[string]bool, len(urls))
+	for _, u := range urls {
+		isURL[u] = true
+	}
+	fetcher := extract.NewFetcher(indexFetchTimeout, extract.DefaultMaxPageBytes)
+
+	manifestPath, err := indexing.DefaultManifestPath()
+	if err != nil {
+		return err
+	}
+	manifest, err := indexing.LoadManifest(manifestPath)
+	if err != nil {
+		return fmt.Errorf("%w (delete it to index everything again)", err)
+	}
+	indexer := &manifestIndexer{
+		manifest: manifest,
+		store:    vectorStore,
+		force:    indexForce,
+		index: func(file string) ([]string, error) {
+			return storeFile(file, chunkerClient, embeddingClient, vectorStore)
+		},
+	}
+
+	result := indexFiles(ctx, os.Stdout, sources, workers, func(source string) (int, error) {
+		switch {
+		case stdin != nil && source == stdin.name:
+			return processText(stdin.name, stdin.content, chunkerClient, embeddingClient, vectorStore)
+		case isURL[source]:
+			return processURL(source, fetcher, chunkerClient, embeddingClient, vectorStore)
 		}
+		return indexer.process(source)
+	})
+	indexedFiles, totalChunks := result.files, result.chunks
+	if err := manifest.Save(manifestPath); err != nil {
+		slog.Warn("failed to save index manifest", "component", "index", "path", manifestPath, "error", err)
 	}
 
 	fmt.Println("Indexing complete!")
+	fmt.Printf("Indexed %d source(s) into %d chunk(s), skipped %d excluded path(s)\n", indexedFiles, totalChunks, skipped)
+	fmt.Printf("Added %d, updated %d, skipped %d unchanged file(s)\n", indexedFiles-indexer.updated, indexer.updated, result.unchanged)
+	if result.unsupported > 0 {
+		fmt.Printf("Skipped %d file(s) whose structure could not be read\n", result.unsupported)
+	}
+	if result.binary > 0 {
+		fmt.Printf("Skipped %d source(s) with binary content\n", result.binary)
+	}
+	if len(result.failures) > 0 {
+		fmt.Printf("Failed to index %d source(s):\n", len(result.failures))
+		for _, failure := range result.failures {
+			fmt.Printf("  %s: %v\n", failure.file, failure.err)
+		}
+	}
+	var description string
+	if stdin != nil {
+		description = "stdin as " + stdin.name
+	} else {
+		description = describeIndexSource(path, urls)
+	}
+	recordIndexRun(description, vectorStore.DocumentsCollection(), indexedFiles, totalChunks)
+	if result.notStarted > 0 {
+		fmt.Printf("Interrupted: %d source(s) were not indexed; run the same command again to finish\n", result.notStarted)
+		return interruptedExit(ctx.Err())
+	}
 	return nil
 }
 
-func getFilesToIndex(path string, formats []string, recursive bool) ([]string, error) {
+// overrideDocumentsCollection points the documents collection at name for this
+// invocation. The vector store creates the collection if it does not exist.
+func overrideDocumentsCollection(cfg *config.Config, name string) {
+	if name != "" {
+		cfg.Vector.Collection = name
+	}
+}
+
+// describeIndexSource summarizes what an index run covered for the index state
+func describeIndexSource(path string, urls []string) string {
+	if path != "" {
+		if absPath, err := filepath.Abs(path); err == nil {
+			path = absPath
+		}
+		if len(urls) > 0 {
+			return fmt.Sprintf("%s and %d URL(s)", path, len(urls))
+		}
+		return path
+	}
+	if len(urls) == 1 {
+		return urls[0]
+	}
+	return fmt.Sprintf("%s and %d more URL(s)", urls[0], len(urls)-1)
+}
+
+// collectURLs combines --url flags with the entries of a --urls-file, which
+// lists one URL per line and may contain blank lines and # comments
+func collectURLs(flagURLs []string, urlsFile string) ([]string, error) {
+	candidates := append([]string{}, flagURLs...)
+	if urlsFile != "" {
+		data, err := os.ReadFile(urlsFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read URLs file: %w", err)
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			line = strings.TrimSpace(line)
+			if line != "" && !strings.HasPrefix(line, "#") {
+				candidates = append(candidates, line)
+			}
+		}
+	}
+
+	seen := make(map[string]bool, len(candidates))
+	var urls []string
+	for _, candidate := range candidates {
+		parsed, err := url.Parse(candidate)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return nil, fmt.Errorf("invalid URL %q: only http and https URLs can be indexed", candidate)
+		}
+		if !seen[candidate] {
+			seen[candidate] = true
+			urls = append(urls, candidate)
+		}
+	}
+	return urls, nil
+}
+
+// defaultIndexWorkers is how many files are indexed at once by default,
+// about where a single local Ollama instance stops getting faster
+const defaultIndexWorkers = 4
+
+// resolveWorkers picks the worker count: the flag, then config, then
+// defaultIndexWorkers or the number of CPU cores when there are fewer
+func resolveWorkers(flagValue, configValue int) int {
+	if flagValue > 0 {
+		return flagValue
+	}
+	if configValue > 0 {
+		return configValue
+	}
+	return max(1, min(defaultIndexWorkers, runtime.NumCPU()))
+}
+
+// indexFailure records a file that could not be indexed
+type indexFailure struct {
+	file string
+	err  error
+}
+
+// indexResult aggregates the outcome of indexing a set of files
+type indexResult struct {
+	files       int
+	chunks      int
+	unchanged   int
+	unsupported int
+	binary      int
+	notStarted  int // Files left when the run was cancelled
+	failures    []indexFailure
+}
+
+// throughput describes how many files were processed per second
+func throughput(files int, elapsed time.Duration) string {
+	if elapsed <= 0 {
+		return fmt.Sprintf("%d files/s", files)
+	}
+	return fmt.Sprintf("%.1f files/s", float64(files)/elapsed.Seconds())
+}
+
+// indexFiles runs process over files using a pool of workers, printing a
+// progress line with the throughput so far as each file finishes. Failures are
+// collected and their errors left for the summary rather than stopping the
+// run, files with an unsupported structure or binary content are skipped
+// with a warning, and files process reports as errUnchanged are counted as
+// unchanged. Once ctx is
+// cancelled no more files are started; those already started finish.
+func indexFiles(ctx context.Context, out io.Writer, files []string, workers int, process func(file string) (int, error)) indexResult {
+	if workers < 1 {
+		workers = 1
+	}
+
+	jobs := make(chan string)
+	start := time.Now()
+	var (
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		result indexResult
+		done   int
+	)
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for file := range jobs {
+				chunks, err := process(file)
+
+				mu.Lock()
+				done++
+				var status string
+				switch {
+				case errors.Is(err, errUnchanged):
+					result.unchanged++
+					status = fmt.Sprintf("Skipped %s (unchanged)", file)
+				case errors.Is(err, errBinary):
+					result.binary++
+					status = fmt.Sprintf("Warning: skipping %s: %v", file, err)
+				case errors.Is(err, extract.ErrUnsupported):
+					result.unsupported++
+					status = fmt.Sprintf("Warning: skipping %s: %v", file, err)
+				case err != nil:
+					result.failures = append(result.failures, indexFailure{file: file, err: err})
+					status = fmt.Sprintf("Failed %s", file)
+				default:
+					result.files++
+					result.chunks += chunks
+					status = fmt.Sprintf("Indexed %s (%d chunks)", file, chunks)
+				}
+				fmt.Fprintf(out, "[%d/%d] %s, %s\n", done, len(files), status, throughput(done, time.Since(start)))
+				mu.Unlock()
+			}
+		}()
+	}
+
+dispatch:
+	for i, file := range files {
+		if ctx.Err() != nil {
+			result.notStarted = len(files) - i
+			break
+		}
+		select {
+		case jobs <- file:
+		case <-ctx.Done():
+			result.notStarted = len(files) - i
+			break dispatch
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	sort.Slice(result.failures, func(i, j int) bool {
+		return result.failures[i].file < result.failures[j].file
+	})
+	return result
+}
+
+// recordIndexRun persists a summary of this run for `rag-cli stats`, writing
+// any warning to out
+func recordIndexRun(path, collection string, files, chunks int) {
+	statePath, err := indexing.DefaultStatePath()
+	if err == nil {
+		err = indexing.SaveIndexState(statePath, &indexing.IndexState{
+			LastRun:    time.Now(),
+			Path:       path,
+			Collection: collection,
+			Files:      files,
+			Chunks:     chunks,
+		})
+	}
+	if err != nil {
+		slog.Warn("failed to record index run", "component", "index", "path", path, "collection", collection, "error", err)
+	}
+}
+
+// getFilesToIndex returns the files under path with an allowed format, along
+// with the number of files and directories skipped by exclude patterns. A
+// path naming a file is returned as it is, since it was asked for by name.
+func getFilesToIndex(path string, formats []string, recursive bool, excludes *indexing.ExcludeMatcher) ([]string, int, error) {
+	if info, err := os.Stat(path); err == nil && !info.IsDir() {
+		return []string{path}, 0, nil
+	}
+
 	var files []string
+	skipped := 0
 	
 	err := filepath.Walk(path, func(filePath string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
 
+		relPath, relErr := filepath.Rel(path, filePath)
+		if relErr != nil {
+			relPath = filePath
+		}
+
 		if info.IsDir() {
-			if !recursive && filePath != path {
+			if filePath == path {
+				return nil
+			}
+			if !recursive {
+				return filepath.SkipDir
+			}
+			if excludes.Excluded(relPath, true) {
+				skipped++
 				return filepath.SkipDir
 			}
 			return nil
@@ -122,7 +597,11 @@ func getFilesToIndex(path string, formats []string, recursive bool) ([]string, e
 		ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(filePath), "."))
 		for _, format := range formats {
 			if ext == format {
-				files = append(files, filePath)
+				if excludes.Excluded(relPath, false) {
+					skipped++
+				} else {
+					files = append(files, filePath)
+				}
 				break
 			}
 		}
@@ -130,34 +609,201 @@ func getFilesToIndex(path string, formats []string, recursive bool) ([]string, e
 		return nil
 	})
 
-	return files, err
+	return files, skipped, err
+}
+
+// processURL fetches a web page and chunks, embeds, and stores its readable
+// text with the URL as source metadata, returning the number of chunks stored
+func processURL(pageURL string, fetcher *extract.Fetcher, chunkerClient *chunker.Client, embeddingClient embeddings.Embedder, vectorStore vector.VectorStore) (int, error) {
+	page, err := fetcher.Fetch(pageURL)
+	if err != nil {
+		return 0, err
+	}
+
+	metadata := map[string]interface{}{
+		"source": pageURL,
+		"title":  page.Title,
+	}
+	return storeChunks(page.Content(), metadata, chunkerClient, embeddingClient, vectorStore)
+}
+
+// processText chunks, embeds, and stores text that did not come from a file,
+// such as standard input, with name as its source, returning the number of
+// chunks stored. Binary content returns errBinary.
+func processText(name string, content []byte, chunkerClient *chunker.Client, embeddingClient embeddings.Embedder, vectorStore vector.VectorStore) (int, error) {
+	if isBinary(content) {
+		return 0, errBinary
+	}
+	return storeChunks(string(content), map[string]interface{}{"source": name}, chunkerClient, embeddingClient, vectorStore)
+}
+
+// isBinary reports whether content looks like something other than text,
+// having a NUL byte near its start
+func isBinary(content []byte) bool {
+	return bytes.IndexByte(content[:min(len(content), binarySniffBytes)], 0) >= 0
+}
+
+// processFile chunks, embeds, and stores a single file, returning the number of chunks stored.
+// Formats with a registered extractor, such as HTML and docx, are converted to readable text
+// first; files whose structure cannot be read return an error wrapping extract.ErrUnsupported,
+// and other files that are not text return errBinary.
+func processFile(filePath string, chunkerClient *chunker.Client, embeddingClient embeddings.Embedder, vectorStore vector.VectorStore) (int, error) {
+	ids, err := storeFile(filePath, chunkerClient, embeddingClient, vectorStore)
+	return len(ids), err
 }
 
-func processFile(filePath string, chunkerClient *chunker.Client, embeddingClient *embeddings.Client, vectorStore *vector.ChromaClient) error {
+// storeFile is processFile returning the IDs of the documents stored
+func storeFile(filePath string, chunkerClient *chunker.Client, embeddingClient embeddings.Embedder, vectorStore vector.VectorStore) ([]string, error) {
 	// Read file content
 	content, err := os.ReadFile(filePath)
 	if err != nil {
-		return fmt.Errorf("failed to read file: %w", err)
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	ext := filepath.Ext(filePath)
+	extractor, ok := extract.ForExtension(ext)
+	if !ok {
+		if isBinary(content) {
+			return nil, errBinary
+		}
+		return storeChunkIDs(string(content), map[string]interface{}{"source_path": filePath}, chunkerClient, embeddingClient, vectorStore)
+	}
+
+	doc, err := extractor(content)
+	if err != nil {
+		return nil, err
 	}
+	metadata := map[string]interface{}{
+		"source_path": filePath,
+		"title":       doc.Title,
+		"format":      strings.TrimPrefix(strings.ToLower(ext), "."),
+	}
+	return storeChunkIDs(doc.Content(), metadata, chunkerClient, embeddingClient, vectorStore)
+}
+
+// storeChunks chunks text and stores each chunk with its embedding, returning
+// the number of chunks stored. Each chunk is stored with a copy of metadata
+// that also records its chunk_index and when it was indexed, so search
+// results can be traced to their source.
+func storeChunks(text string, metadata map[string]interface{}, chunkerClient *chunker.Client, embeddingClient embeddings.Embedder, vectorStore vector.VectorStore) (int, error) {
+	ids, err := storeChunkIDs(text, metadata, chunkerClient, embeddingClient, vectorStore)
+	return len(ids), err
+}
 
+// storeChunkIDs is storeChunks returning the IDs of the chunks stored, which
+// share a prefix unique to this call followed by the chunk index
+func storeChunkIDs(text string, metadata map[string]interface{}, chunkerClient *chunker.Client, embeddingClient embeddings.Embedder, vectorStore vector.VectorStore) ([]string, error) {
 	// Chunk the content
-	chunks, err := chunkerClient.ChunkText(string(content))
+	chunks, err := chunkerClient.ChunkText(text)
 	if err != nil {
-		return fmt.Errorf("failed to chunk text: %w", err)
+		return nil, fmt.Errorf("failed to chunk text: %w", err)
 	}
 
+	prefix := documentIDPrefix()
+	indexedAt := time.Now().UTC().Format(time.RFC3339)
+
 	// Generate embeddings for each chunk
+	ids := make([]string, 0, len(chunks))
 	for i, chunk := range chunks {
 		embedding, err := embeddingClient.GenerateEmbedding(chunk)
 		if err != nil {
-			return fmt.Errorf("failed to generate embedding for chunk %d: %w", i, err)
+			return ids, fmt.Errorf("failed to generate embedding for chunk %d: %w", i, err)
 		}
 
-		// Store in vector database with empty ID to auto-generate UUID
-		if err := vectorStore.AddDocument(vectorStore.DocumentsCollection(), "", chunk, embedding); err != nil {
-			return fmt.Errorf("failed to store document in vector database: %w", err)
+		chunkMetadata := make(map[string]interface{}, len(metadata)+2)
+		for key, value := range metadata {
+			chunkMetadata[key] = value
+		}
+		chunkMetadata["chunk_index"] = i
+		chunkMetadata["indexed_at"] = indexedAt
+		id := fmt.Sprintf("%s_%d", prefix, i)
+		err = vectorStore.AddDocumentWithMetadata(vectorStore.DocumentsCollection(), id, chunk, embedding, chunkMetadata)
+		if err != nil {
+			return ids, fmt.Errorf("failed to store document in vector database: %w", err)
 		}
+		ids = append(ids, id)
 	}
 
-	return nil
+	return ids, nil
+}
+
+// documentIDPrefix returns a random prefix for the IDs of one source's chunks
+func documentIDPrefix() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("index_%d", time.Now().UnixNano())
+	}
+	return fmt.Sprintf("index_%x", b)
+}
+
+// manifestIndexer indexes files through the index manifest. Files whose
+// content is unchanged since they were indexed into the documents collection,
+// and whose documents are still stored, are skipped with errUnchanged; files
+// that changed have their previous documents replaced.
+type manifestIndexer struct {
+	manifest *indexing.Manifest
+	store    vector.VectorStore
+	force    bool // Index every file, even unchanged ones
+	index    func(file string) ([]string, error)
+
+	mu      sync.Mutex
+	updated int // Files whose previous documents were replaced
+}
+
+// process indexes one file, returning the number of chunks stored
+func (m *manifestIndexer) process(file string) (int, error) {
+	stat, err := os.Stat(file)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read file: %w", err)
+	}
+	hash, err := indexing.HashFile(file)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read file: %w", err)
+	}
+	path, err := filepath.Abs(file)
+	if err != nil {
+		path = file
+	}
+
+	collection := m.store.DocumentsCollection()
+	previous, found := m.manifest.Lookup(collection, path)
+	if found && !m.force && previous.Hash == hash && m.stored(collection, previous.DocIDs) {
+		return 0, errUnchanged
+	}
+
+	ids, err := m.index(file)
+	if err != nil {
+		// Keep the previous version rather than half of this one
+		if deleteErr := m.store.DeleteDocuments(collection, ids); deleteErr != nil {
+			slog.Warn("failed to remove partly indexed file", "component", "index", "path", path, "error", deleteErr)
+		}
+		return 0, err
+	}
+
+	if found {
+		if err := m.store.DeleteDocuments(collection, previous.DocIDs); err != nil {
+			slog.Warn("failed to remove previous version of file", "component", "index", "path", path, "error", err)
+		}
+		m.mu.Lock()
+		m.updated++
+		m.mu.Unlock()
+	}
+	m.manifest.Record(collection, path, indexing.ManifestEntry{
+		Hash:      hash,
+		Size:      stat.Size(),
+		DocIDs:    ids,
+		IndexedAt: time.Now(),
+	})
+	return len(ids), nil
+}
+
+// stored reports whether the documents recorded for a file are still in the
+// collection, checking the first one, so files removed by purge or reindex
+// are indexed again
+func (m *manifestIndexer) stored(collection string, ids []string) bool {
+	if len(ids) == 0 {
+		return true
+	}
+	doc, err := m.store.GetDocument(collection, ids[0])
+	return err == nil && doc != nil
 }
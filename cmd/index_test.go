@@ -0,0 +1,584 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"rag-cli/internal/chunker"
+	"rag-cli/internal/extract"
+	"rag-cli/internal/indexing"
+	"rag-cli/pkg/config"
+)
+
+// newIndexFixture creates a small project tree and returns its root
+func newIndexFixture(t *testing.T) string {
+	root := t.TempDir()
+	files := []string{
+		"README.md",
+		"main.go",
+		"notes.txt",
+		"docs/guide.md",
+		"docs/old/legacy.md",
+		"vendor/lib/lib.go",
+		"pkg/vendor/dep.go",
+		"pkg/util.go",
+		"pkg/util.pb.go",
+	}
+	for _, file := range files {
+		path := filepath.Join(root, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte("content"), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", file, err)
+		}
+	}
+	return root
+}
+
+func TestGetFilesToIndex_Exclude(t *testing.T) {
+	tests := []struct {
+		name      string
+		formats   []string
+		recursive bool
+		excludes  []string
+		expected  []string
+		skipped   int
+	}{
+		{
+			name:      "no excludes",
+			formats:   []string{"md", "go"},
+			recursive: true,
+			expected:  []string{"README.md", "docs/guide.md", "docs/old/legacy.md", "main.go", "pkg/util.go", "pkg/util.pb.go", "pkg/vendor/dep.go", "vendor/lib/lib.go"},
+		},
+		{
+			name:      "directory glob",
+			formats:   []string{"md", "go"},
+			recursive: true,
+			excludes:  []string{"vendor/"},
+			expected:  []string{"README.md", "docs/guide.md", "docs/old/legacy.md", "main.go", "pkg/util.go", "pkg/util.pb.go"},
+			skipped:   2,
+		},
+		{
+			name:      "anchored directory and file glob",
+			formats:   []string{"md", "go"},
+			recursive: true,
+			excludes:  []string{"docs/old", "*.pb.go"},
+			expected:  []string{"README.md", "docs/guide.md", "main.go", "pkg/util.go", "pkg/vendor/dep.go", "vendor/lib/lib.go"},
+			skipped:   2,
+		},
+		{
+			name:      "excludes take precedence over formats",
+			formats:   []string{"md", "txt"},
+			recursive: true,
+			excludes:  []string{"*.md", "!README.md"},
+			expected:  []string{"README.md", "notes.txt"},
+			skipped:   2,
+		},
+		{
+			name:      "files outside formats are not counted as skipped",
+			formats:   []string{"txt"},
+			recursive: true,
+			excludes:  []string{"*.go"},
+			expected:  []string{"notes.txt"},
+		},
+		{
+			name:     "non-recursive ignores directory excludes",
+			formats:  []string{"md", "go"},
+			excludes: []string{"vendor/", "main.go"},
+			expected: []string{"README.md"},
+			skipped:  1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := newIndexFixture(t)
+			matcher, err := indexing.NewExcludeMatcher(tt.excludes)
+			if err != nil {
+				t.Fatalf("Failed to compile excludes: %v", err)
+			}
+
+			files, skipped, err := getFilesToIndex(root, tt.formats, tt.recursive, matcher)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			relFiles := make([]string, len(files))
+			for i, file := range files {
+				rel, _ := filepath.Rel(root, file)
+				relFiles[i] = filepath.ToSlash(rel)
+			}
+			sort.Strings(relFiles)
+
+			if !reflect.DeepEqual(relFiles, tt.expected) {
+				t.Errorf("Expected files %v, got %v", tt.expected, relFiles)
+			}
+			if skipped != tt.skipped {
+				t.Errorf("Expected %d skipped, got %d", tt.skipped, skipped)
+			}
+		})
+	}
+}
+
+func TestGetFilesToIndex_SingleFile(t *testing.T) {
+	root := newIndexFixture(t)
+	matcher, err := indexing.NewExcludeMatcher([]string{"*.go"})
+	if err != nil {
+		t.Fatalf("Failed to compile excludes: %v", err)
+	}
+
+	// A file asked for by name is indexed whatever the formats and excludes
+	file := filepath.Join(root, "pkg", "util.go")
+	files, skipped, err := getFilesToIndex(file, []string{"md"}, false, matcher)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(files, []string{file}) || skipped != 0 {
+		t.Errorf("Expected just %s, got %v (%d skipped)", file, files, skipped)
+	}
+}
+
+// slowEmbedder blocks each call for a while and records the peak number of
+// concurrent calls
+type slowEmbedder struct {
+	delay    time.Duration
+	inFlight int32
+	peak     int32
+}
+
+func (s *slowEmbedder) GenerateEmbedding(text string) ([]float32, error) {
+	current := atomic.AddInt32(&s.inFlight, 1)
+	defer atomic.AddInt32(&s.inFlight, -1)
+	for {
+		peak := atomic.LoadInt32(&s.peak)
+		if current <= peak || atomic.CompareAndSwapInt32(&s.peak, peak, current) {
+			break
+		}
+	}
+	time.Sleep(s.delay)
+	return []float32{0.1, 0.2}, nil
+}
+
+func TestIndexFiles(t *testing.T) {
+	root := t.TempDir()
+	var files []string
+	for i := 0; i < 8; i++ {
+		path := filepath.Join(root, fmt.Sprintf("file%d.txt", i))
+		if err := os.WriteFile(path, []byte("hello world"), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+		files = append(files, path)
+	}
+	chunkerClient := chunker.New(config.ChunkerConfig{ChunkSize: 1000, ChunkOverlap: 200})
+
+	for _, workers := range []int{1, 4} {
+		t.Run(fmt.Sprintf("%d workers", workers), func(t *testing.T) {
+			embedder := &slowEmbedder{delay: 50 * time.Millisecond}
+			store := newFakeStore()
+			var out bytes.Buffer
+
+			result := indexFiles(context.Background(), &out, files, workers, func(file string) (int, error) {
+				return processFile(file, chunkerClient, embedder, store)
+			})
+
+			if peak := atomic.LoadInt32(&embedder.peak); int(peak) != workers {
+				t.Errorf("Expected %d concurrent embedding calls, got %d", workers, peak)
+			}
+			if result.files != len(files) || result.chunks != len(files) {
+				t.Errorf("Expected %d files and chunks, got %d files, %d chunks", len(files), result.files, result.chunks)
+			}
+			if len(store.added["documents"]) != len(files) {
+				t.Errorf("Expected %d stored chunks, got %d", len(files), len(store.added["documents"]))
+			}
+			if lines := strings.Count(out.String(), "\n"); lines != len(files) {
+				t.Errorf("Expected one progress line per file, got:\n%s", out.String())
+			}
+			if !strings.Contains(out.String(), fmt.Sprintf("[%d/%d]", len(files), len(files))) {
+				t.Errorf("Expected final progress count, got:\n%s", out.String())
+			}
+		})
+	}
+
+	t.Run("failures are aggregated", func(t *testing.T) {
+		var out bytes.Buffer
+		result := indexFiles(context.Background(), &out, []string{"b", "ok", "a"}, 2, func(file string) (int, error) {
+			if file == "ok" {
+				return 3, nil
+			}
+			return 0, errors.New("embedding failed")
+		})
+
+		if result.files != 1 || result.chunks != 3 {
+			t.Errorf("Expected 1 file and 3 chunks, got %d files, %d chunks", result.files, result.chunks)
+		}
+		if len(result.failures) != 2 || result.failures[0].file != "a" || result.failures[1].file != "b" {
+			t.Errorf("Expected sorted failures for a and b, got %+v", result.failures)
+		}
+		if strings.Contains(out.String(), "embedding failed") || !strings.Contains(out.String(), "Failed a") {
+			t.Errorf("Expected errors to be left for the summary, got:\n%s", out.String())
+		}
+		if !strings.Contains(out.String(), "files/s") {
+			t.Errorf("Expected progress lines to show the throughput, got:\n%s", out.String())
+		}
+	})
+
+	t.Run("cancellation stops dispatching", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		var out bytes.Buffer
+		var processed int32
+		result := indexFiles(ctx, &out, []string{"a", "b", "c", "d"}, 1, func(file string) (int, error) {
+			atomic.AddInt32(&processed, 1)
+			cancel()
+			return 1, nil
+		})
+
+		if got := atomic.LoadInt32(&processed); got > 2 {
+			t.Errorf("Expected no more files to start after cancelling, %d were processed", got)
+		}
+		if result.notStarted+int(atomic.LoadInt32(&processed)) != 4 {
+			t.Errorf("Expected the remaining files to be counted as not started, got %+v", result)
+		}
+	})
+}
+
+func TestWatchConflict(t *testing.T) {
+	if err := watchConflict(func(name string) bool { return name == "recursive" }); err != nil {
+		t.Errorf("Expected -r to be accepted, the watcher covering the whole tree, got: %v", err)
+	}
+	for _, name := range []string{"collection", "exclude", "formats", "force", "workers", "concurrency"} {
+		err := watchConflict(func(changed string) bool { return changed == name })
+		if err == nil || !strings.Contains(err.Error(), "--"+name) {
+			t.Errorf("Expected --%s to be refused with --watch, got: %v", name, err)
+		}
+	}
+}
+
+func TestResolveWorkers(t *testing.T) {
+	if got := resolveWorkers(3, 5); got != 3 {
+		t.Errorf("Expected flag to win, got %d", got)
+	}
+	if got := resolveWorkers(0, 5); got != 5 {
+		t.Errorf("Expected config value, got %d", got)
+	}
+	if got, want := resolveWorkers(0, 0), min(defaultIndexWorkers, runtime.NumCPU()); got != want {
+		t.Errorf("Expected the default of %d workers, got %d", want, got)
+	}
+}
+
+func TestProcessURL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><head><title>FAQ</title></head><body><article><p>Answers here.</p></article></body></html>"))
+	}))
+	defer server.Close()
+
+	store := newFakeStore()
+	chunkerClient := chunker.New(config.ChunkerConfig{ChunkSize: 1000, ChunkOverlap: 200})
+	fetcher := extract.NewFetcher(time.Second, extract.DefaultMaxPageBytes)
+
+	chunks, err := processURL(server.URL+"/faq", fetcher, chunkerClient, &fakeEmbedder{}, store)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if chunks != 1 {
+		t.Fatalf("Expected 1 chunk, got %d", chunks)
+	}
+	if got := store.added["documents"][0]; got != "FAQ\n\nAnswers here." {
+		t.Errorf("Expected title and text to be stored, got %q", got)
+	}
+	metadata := store.addedMetadata["documents"][0]
+	if metadata["source"] != server.URL+"/faq" || metadata["title"] != "FAQ" {
+		t.Errorf("Expected URL and title metadata, got %v", metadata)
+	}
+}
+
+func TestCollectURLs(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "urls.txt")
+	contents := "# docs\nhttps://example.com/a\n\nhttps://example.com/b\nhttps://example.com/a\n"
+	if err := os.WriteFile(file, []byte(contents), 0644); err != nil {
+		t.Fatalf("Failed to write URLs file: %v", err)
+	}
+
+	urls, err := collectURLs([]string{"http://example.com/flag"}, file)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := []string{"http://example.com/flag", "https://example.com/a", "https://example.com/b"}
+	if !reflect.DeepEqual(urls, expected) {
+		t.Errorf("Expected %v, got %v", expected, urls)
+	}
+
+	for _, invalid := range []string{"ftp://example.com/file", "example.com/page", "https://"} {
+		if _, err := collectURLs([]string{invalid}, ""); err == nil {
+			t.Errorf("Expected %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestProcessFile_Extractors(t *testing.T) {
+	root := t.TempDir()
+	htmlPath := filepath.Join(root, "export.html")
+	if err := os.WriteFile(htmlPath, []byte("<html><head><title>Export</title></head><body><nav>Menu</nav><p>Body text.</p></body></html>"), 0644); err != nil {
+		t.Fatalf("Failed to write HTML file: %v", err)
+	}
+	brokenPath := filepath.Join(root, "broken.docx")
+	if err := os.WriteFile(brokenPath, []byte("not a zip"), 0644); err != nil {
+		t.Fatalf("Failed to write docx file: %v", err)
+	}
+	chunkerClient := chunker.New(config.ChunkerConfig{ChunkSize: 1000, ChunkOverlap: 200})
+
+	t.Run("html is stored as readable text", func(t *testing.T) {
+		store := newFakeStore()
+		if _, err := processFile(htmlPath, chunkerClient, &fakeEmbedder{}, store); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if got := store.added["documents"][0]; got != "Export\n\nBody text." {
+			t.Errorf("Expected extracted text, got %q", got)
+		}
+		metadata := store.addedMetadata["documents"][0]
+		if metadata["source_path"] != htmlPath || metadata["format"] != "html" || metadata["title"] != "Export" {
+			t.Errorf("Unexpected metadata: %v", metadata)
+		}
+	})
+
+	t.Run("unreadable structure is skipped with a warning", func(t *testing.T) {
+		store := newFakeStore()
+		var out bytes.Buffer
+		result := indexFiles(context.Background(), &out, []string{brokenPath}, 1, func(file string) (int, error) {
+			return processFile(file, chunkerClient, &fakeEmbedder{}, store)
+		})
+
+		if result.unsupported != 1 || len(result.failures) != 0 {
+			t.Errorf("Expected one unsupported file and no failures, got %+v", result)
+		}
+		if len(store.added["documents"]) != 0 {
+			t.Errorf("Expected raw markup not to be indexed, got %v", store.added["documents"])
+		}
+		if !strings.Contains(out.String(), "Warning: skipping") {
+			t.Errorf("Expected a warning, got %q", out.String())
+		}
+	})
+}
+
+func TestProcessText(t *testing.T) {
+	chunkerClient := chunker.New(config.ChunkerConfig{ChunkSize: 1000, ChunkOverlap: 200})
+
+	t.Run("stored with its name as source", func(t *testing.T) {
+		store := newFakeStore()
+		chunks, err := processText("clipboard-note", []byte("pasted text"), chunkerClient, &fakeEmbedder{}, store)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if chunks != 1 || store.added["documents"][0] != "pasted text" {
+			t.Fatalf("Expected the text as one chunk, got %d: %v", chunks, store.added["documents"])
+		}
+		if metadata := store.addedMetadata["documents"][0]; metadata["source"] != "clipboard-note" {
+			t.Errorf("Expected the name as source, got %v", metadata)
+		}
+	})
+
+	t.Run("binary content is refused", func(t *testing.T) {
+		store := newFakeStore()
+		if _, err := processText("image", []byte("\x89PNG\r\n\x1a\n\x00\x00"), chunkerClient, &fakeEmbedder{}, store); !errors.Is(err, errBinary) {
+			t.Errorf("Expected errBinary, got: %v", err)
+		}
+		if len(store.added["documents"]) != 0 {
+			t.Errorf("Expected nothing to be stored, got %v", store.added["documents"])
+		}
+	})
+}
+
+func TestProcessFile_Binary(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app")
+	if err := os.WriteFile(path, []byte("\x7fELF\x02\x01\x01\x00\x00\x00"), 0755); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	store := newFakeStore()
+	chunkerClient := chunker.New(config.ChunkerConfig{ChunkSize: 1000, ChunkOverlap: 200})
+
+	var out bytes.Buffer
+	result := indexFiles(context.Background(), &out, []string{path}, 1, func(file string) (int, error) {
+		return processFile(file, chunkerClient, &fakeEmbedder{}, store)
+	})
+	if result.binary != 1 || result.files != 0 || len(result.failures) != 0 {
+		t.Errorf("Expected one binary file skipped, got %+v", result)
+	}
+	if !strings.Contains(out.String(), "Warning: skipping "+path+": binary content") {
+		t.Errorf("Expected a warning, got %q", out.String())
+	}
+}
+
+func TestCollectionOverride(t *testing.T) {
+	cfg, err := config.DefaultConfig()
+	if err != nil {
+		t.Fatalf("Failed to build default config: %v", err)
+	}
+	overrideDocumentsCollection(cfg, "")
+	if cfg.Vector.Collection != "documents" {
+		t.Errorf("Expected no override without a flag, got %s", cfg.Vector.Collection)
+	}
+	overrideDocumentsCollection(cfg, "project-x")
+	if cfg.Vector.Collection != "project-x" {
+		t.Errorf("Expected collection project-x, got %s", cfg.Vector.Collection)
+	}
+
+	// The store reports the overridden name, so every stage of indexing writes there
+	store := newFakeStore()
+	store.documentsCollection = cfg.Vector.Collection
+	root := t.TempDir()
+	notes := filepath.Join(root, "notes.md")
+	if err := os.WriteFile(notes, []byte("project x notes"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	chunkerClient := chunker.New(config.ChunkerConfig{ChunkSize: 1000, ChunkOverlap: 200})
+	if _, err := processFile(notes, chunkerClient, &fakeEmbedder{}, store); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := storeChunks("more notes", map[string]interface{}{"source": "x"}, chunkerClient, &fakeEmbedder{}, store); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(store.added["project-x"]) != 2 || len(store.added["documents"]) != 0 {
+		t.Errorf("Expected both chunks in project-x, got %v", store.added)
+	}
+}
+
+func TestProcessFile_Provenance(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	if err := os.WriteFile(path, []byte("plain notes"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	store := newFakeStore()
+	chunkerClient := chunker.New(config.ChunkerConfig{ChunkSize: 1000, ChunkOverlap: 200})
+
+	if _, err := processFile(path, chunkerClient, &fakeEmbedder{}, store); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	metadata := store.addedMetadata["documents"][0]
+	if metadata["source_path"] != path || metadata["chunk_index"] != 0 {
+		t.Errorf("Expected the file and chunk index to be stored, got %v", metadata)
+	}
+	if indexedAt, ok := metadata["indexed_at"].(string); !ok || indexedAt == "" {
+		t.Errorf("Expected the indexing time to be stored, got %v", metadata)
+	}
+}
+
+func TestManifestIndexer(t *testing.T) {
+	root := t.TempDir()
+	unchanged := filepath.Join(root, "unchanged.md")
+	modified := filepath.Join(root, "modified.md")
+	for _, path := range []string{unchanged, modified} {
+		if err := os.WriteFile(path, []byte("first version"), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+	}
+
+	store := newFakeStore()
+	manifest, err := indexing.LoadManifest(filepath.Join(root, "index-manifest.json"))
+	if err != nil {
+		t.Fatalf("Failed to load manifest: %v", err)
+	}
+	chunkerClient := chunker.New(config.ChunkerConfig{ChunkSize: 1000, ChunkOverlap: 200})
+	newIndexer := func(force bool) *manifestIndexer {
+		return &manifestIndexer{
+			manifest: manifest,
+			store:    store,
+			force:    force,
+			index: func(file string) ([]string, error) {
+				return storeFile(file, chunkerClient, &fakeEmbedder{}, store)
+			},
+		}
+	}
+	run := func(indexer *manifestIndexer) indexResult {
+		var out bytes.Buffer
+		return indexFiles(context.Background(), &out, []string{unchanged, modified}, 1, indexer.process)
+	}
+
+	if result := run(newIndexer(false)); result.files != 2 || result.unchanged != 0 {
+		t.Fatalf("Expected both files to be added, got %+v", result)
+	}
+	entry, _ := manifest.Lookup("documents", modified)
+	firstIDs := entry.DocIDs
+	if len(firstIDs) != 1 || entry.Size != int64(len("first version")) {
+		t.Fatalf("Expected the file to be recorded with its document, got %+v", entry)
+	}
+
+	// Rewriting the same content with a new modification time is not a change,
+	// while new content of the same size is
+	later := time.Now().Add(time.Hour)
+	if err := os.WriteFile(unchanged, []byte("first version"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := os.Chtimes(unchanged, later, later); err != nil {
+		t.Fatalf("Failed to touch file: %v", err)
+	}
+	if err := os.WriteFile(modified, []byte("other version"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	indexer := newIndexer(false)
+	result := run(indexer)
+	if result.files != 1 || result.unchanged != 1 || indexer.updated != 1 {
+		t.Errorf("Expected one updated and one unchanged file, got %+v with %d updated", result, indexer.updated)
+	}
+	if !reflect.DeepEqual(store.deleted["documents"], firstIDs) {
+		t.Errorf("Expected the previous documents %v to be deleted, got %v", firstIDs, store.deleted["documents"])
+	}
+	if entry, _ := manifest.Lookup("documents", modified); reflect.DeepEqual(entry.DocIDs, firstIDs) {
+		t.Errorf("Expected the new documents to be recorded, got %v", entry.DocIDs)
+	}
+	if added := store.added["documents"]; len(added) != 3 || added[2] != "other version" {
+		t.Errorf("Expected only the modified file to be indexed again, got %v", added)
+	}
+
+	// --force indexes unchanged files again, replacing their documents
+	indexer = newIndexer(true)
+	if result := run(indexer); result.files != 2 || result.unchanged != 0 || indexer.updated != 2 {
+		t.Errorf("Expected both files to be indexed again, got %+v with %d updated", result, indexer.updated)
+	}
+	if len(store.deleted["documents"]) != 3 {
+		t.Errorf("Expected each file's previous documents to be deleted, got %v", store.deleted["documents"])
+	}
+}
+
+func TestManifestIndexer_MissingDocuments(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.md")
+	if err := os.WriteFile(path, []byte("notes"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	hash, err := indexing.HashFile(path)
+	if err != nil {
+		t.Fatalf("Failed to hash file: %v", err)
+	}
+
+	// The manifest remembers the file, but its documents were purged since
+	manifest := &indexing.Manifest{Collections: map[string]map[string]indexing.ManifestEntry{}}
+	manifest.Record("documents", path, indexing.ManifestEntry{Hash: hash, DocIDs: []string{"index_gone_0"}})
+	store := newFakeStore()
+	indexer := &manifestIndexer{
+		manifest: manifest,
+		store:    store,
+		index: func(file string) ([]string, error) {
+			return []string{"index_new_0"}, nil
+		},
+	}
+
+	if chunks, err := indexer.process(path); err != nil || chunks != 1 {
+		t.Errorf("Expected the file to be indexed again, got %d chunks and %v", chunks, err)
+	}
+}
@@ -0,0 +1,189 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/spf13/cobra"
+	"rag-cli/internal/chunker"
+	"rag-cli/internal/embeddings"
+	"rag-cli/internal/indexing"
+	"rag-cli/internal/mcp"
+	"rag-cli/internal/vector"
+	"rag-cli/pkg/config"
+	"rag-cli/pkg/version"
+)
+
+var mcpCmd = &cobra.Command{
+	Use:   "mcp",
+	Short: "Run a Model Context Protocol server over stdio",
+	Long: `Run rag-cli as a Model Context Protocol (MCP) server on stdin/stdout, so MCP
+clients such as Claude Desktop and editor agents can query your local corpus.
+
+Tools:
+  rag_search   Semantic search over the documents, commands, or auto collection
+  rag_ask      Answer a question from retrieved context (never runs commands)
+  index_path   Index the files under a path into the documents collection
+
+Stdout carries only protocol messages; logs and warnings go to stderr. The client
+starts and stops the server, so you normally do not run this command yourself.
+
+EXAMPLES:
+  # Claude Desktop (claude_desktop_config.json)
+  {
+    "mcpServers": {
+      "rag-cli": {"command": "rag-cli", "args": ["mcp"]}
+    }
+  }
+
+  # Smoke test the protocol by hand
+  echo '{"jsonrpc":"2.0","id":1,"method":"tools/list"}' | rag-cli mcp`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		llmClient, err := newLLMClient(cfg)
+		if err != nil {
+			return err
+		}
+		if err := checkModelOverride(llmClient, cfg.LLM.Model); err != nil {
+			return err
+		}
+
+		embeddingClient, err := embeddings.NewClient(cfg.Embeddings, cfg.Timeouts)
+		if err != nil {
+			return fmt.Errorf("failed to initialize embedding client: %w", err)
+		}
+
+		vectorStore, err := vector.NewChromaClient(cfg.Vector, cfg.Timeouts)
+		if err != nil {
+			return fmt.Errorf("failed to initialize vector store: %w", err)
+		}
+
+		excludes, err := indexing.NewExcludeMatcher(cfg.Index.ExcludePatterns)
+		if err != nil {
+			return err
+		}
+
+		// Command execution stays disabled: there is no way to approve commands over MCP
+		backend := &apiServer{
+			generator: llmClient,
+			embedder:  embeddingClient,
+			store:     vectorStore,
+			chunker:   chunker.New(cfg.Chunker),
+			excludes:  excludes,
+			workers:   resolveWorkers(0, cfg.Index.Workers),
+			logger:    log.New(os.Stderr, "", log.LstdFlags),
+		}
+		return mcp.NewServer("rag-cli", version.Version, mcpTools(backend)).Serve(os.Stdin, os.Stdout)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(mcpCmd)
+}
+
+// mcpTools returns the tools exposed over MCP, backed by the same handlers as 'rag-cli serve'
+func mcpTools(backend *apiServer) []mcp.Tool {
+	return []mcp.Tool{
+		{
+			Name:        "rag_search",
+			Description: "Semantic search over the user's locally indexed documents. Returns the closest chunks as JSON with their distance (lower is closer) and source metadata.",
+			InputSchema: objectSchema(map[string]interface{}{
+				"query":      schemaProperty("string", "What to search for"),
+				"collection": schemaProperty("string", "Collection to search: documents (default), commands, or auto"),
+				"top_k":      schemaProperty("integer", "Number of results to return (default 5)"),
+			}, "query"),
+			Handler: func(arguments json.RawMessage) (string, error) {
+				var req searchRequest
+				if err := decodeToolArguments(arguments, &req); err != nil {
+					return "", err
+				}
+				results, err := backend.search(req)
+				if err != nil {
+					return "", err
+				}
+				return marshalToolResult(results)
+			},
+		},
+		{
+			Name:        "rag_ask",
+			Description: "Answer a question using the local language model with context retrieved from the user's indexed documents. Never runs commands.",
+			InputSchema: objectSchema(map[string]interface{}{
+				"question":        schemaProperty("string", "The question to answer"),
+				"top_k":           schemaProperty("integer", "Number of context chunks to retrieve (default 5)"),
+				"include_history": schemaProperty("boolean", "Also retrieve similar past command sessions as context"),
+			}, "question"),
+			Handler: func(arguments json.RawMessage) (string, error) {
+				var req askRequest
+				if err := decodeToolArguments(arguments, &req); err != nil {
+					return "", err
+				}
+				resp, err := backend.ask(context.Background(), askRequest{Question: req.Question, TopK: req.TopK, IncludeHistory: req.IncludeHistory})
+				if err != nil {
+					return "", err
+				}
+				return resp.Answer, nil
+			},
+		},
+		{
+			Name:        "index_path",
+			Description: "Index the files under a local path into the documents collection so later searches can find them.",
+			InputSchema: objectSchema(map[string]interface{}{
+				"path":      schemaProperty("string", "File or directory to index"),
+				"recursive": schemaProperty("boolean", "Include subdirectories"),
+			}, "path"),
+			Handler: func(arguments json.RawMessage) (string, error) {
+				var req indexRequest
+				if err := decodeToolArguments(arguments, &req); err != nil {
+					return "", err
+				}
+				if req.Path == "" {
+					return "", fmt.Errorf("path is required")
+				}
+				resp, err := backend.index(indexRequest{Path: req.Path, Recursive: req.Recursive})
+				if err != nil {
+					return "", err
+				}
+				return marshalToolResult(resp)
+			},
+		},
+	}
+}
+
+// objectSchema builds a JSON Schema for an object with the given properties
+func objectSchema(properties map[string]interface{}, required ...string) map[string]interface{} {
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+		"required":   required,
+	}
+}
+
+// schemaProperty builds a JSON Schema property of the given type
+func schemaProperty(typ, description string) map[string]interface{} {
+	return map[string]interface{}{"type": typ, "description": description}
+}
+
+// decodeToolArguments decodes the arguments object of a tool call
+func decodeToolArguments(arguments json.RawMessage, v interface{}) error {
+	if err := json.Unmarshal(arguments, v); err != nil {
+		return fmt.Errorf("invalid arguments: %w", err)
+	}
+	return nil
+}
+
+// marshalToolResult renders a structured result as indented JSON text
+func marshalToolResult(v interface{}) (string, error) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
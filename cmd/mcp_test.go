@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"rag-cli/internal/mcp"
+)
+
+// mcpToolResponse is a decoded tools/call response
+type mcpToolResponse struct {
+	ID     int `json:"id"`
+	Result struct {
+		Content []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content"`
+		IsError bool `json:"isError"`
+	} `json:"result"`
+}
+
+// runMCP sends canned JSON-RPC messages to the MCP server and returns the raw output
+func runMCP(t *testing.T, backend *apiServer, lines ...string) string {
+	t.Helper()
+	var out bytes.Buffer
+	server := mcp.NewServer("rag-cli", "test", mcpTools(backend))
+	if err := server.Serve(strings.NewReader(strings.Join(lines, "\n")+"\n"), &out); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	return out.String()
+}
+
+// callMCPTool invokes a single tool and returns the decoded response
+func callMCPTool(t *testing.T, backend *apiServer, name string, arguments interface{}) mcpToolResponse {
+	t.Helper()
+	request, _ := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  "tools/call",
+		"params":  map[string]interface{}{"name": name, "arguments": arguments},
+	})
+	output := runMCP(t, backend, string(request))
+
+	var resp mcpToolResponse
+	if err := json.Unmarshal([]byte(output), &resp); err != nil {
+		t.Fatalf("Failed to decode response %q: %v", output, err)
+	}
+	if len(resp.Result.Content) != 1 {
+		t.Fatalf("Expected one content item, got: %s", output)
+	}
+	return resp
+}
+
+func TestMCP_ListTools(t *testing.T) {
+	backend, _, _ := newServeFixture(t, &fakeLLM{})
+
+	output := runMCP(t, backend,
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":{},"clientInfo":{"name":"test","version":"0"}}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`,
+	)
+
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 responses, got %d: %s", len(lines), output)
+	}
+	var list struct {
+		Result struct {
+			Tools []struct {
+				Name        string `json:"name"`
+				InputSchema struct {
+					Required []string `json:"required"`
+				} `json:"inputSchema"`
+			} `json:"tools"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &list); err != nil {
+		t.Fatalf("Failed to decode tools/list: %v", err)
+	}
+
+	required := map[string]string{}
+	for _, tool := range list.Result.Tools {
+		if len(tool.InputSchema.Required) == 1 {
+			required[tool.Name] = tool.InputSchema.Required[0]
+		}
+	}
+	expected := map[string]string{"rag_search": "query", "rag_ask": "question", "index_path": "path"}
+	for name, arg := range expected {
+		if required[name] != arg {
+			t.Errorf("Expected tool %s to require %q, got tools: %+v", name, arg, list.Result.Tools)
+		}
+	}
+}
+
+func TestMCP_RagSearch(t *testing.T) {
+	backend, _, _ := newServeFixture(t, &fakeLLM{})
+
+	resp := callMCPTool(t, backend, "rag_search", map[string]interface{}{"query": "chunk size", "top_k": 1})
+	if resp.Result.IsError {
+		t.Fatalf("Expected success, got error: %s", resp.Result.Content[0].Text)
+	}
+	var results []searchOutput
+	if err := json.Unmarshal([]byte(resp.Result.Content[0].Text), &results); err != nil {
+		t.Fatalf("Expected JSON search results, got %q: %v", resp.Result.Content[0].Text, err)
+	}
+	if len(results) != 1 || results[0].ID != "doc-1" {
+		t.Errorf("Expected the closest document, got: %+v", results)
+	}
+}
+
+func TestMCP_RagAsk(t *testing.T) {
+	t.Run("answers without running commands", func(t *testing.T) {
+		generator := &fakeLLM{response: "rm -rf build"}
+		backend, _, _ := newServeFixture(t, generator)
+		executor := &fakeExecutor{}
+		backend.newExecutor = executorFactory(executor)
+
+		resp := callMCPTool(t, backend, "rag_ask", map[string]interface{}{"question": "clean up", "execute": true})
+		if resp.Result.IsError {
+			t.Fatalf("Expected success, got error: %s", resp.Result.Content[0].Text)
+		}
+		if resp.Result.Content[0].Text != "rm -rf build" {
+			t.Errorf("Expected the answer text, got %q", resp.Result.Content[0].Text)
+		}
+		if generator.answerCalls != 1 || len(executor.commands) != 0 {
+			t.Errorf("Expected answer mode only, got %d answer call(s) and commands %v", generator.answerCalls, executor.commands)
+		}
+	})
+
+	t.Run("missing question is a tool error", func(t *testing.T) {
+		backend, _, _ := newServeFixture(t, &fakeLLM{})
+
+		resp := callMCPTool(t, backend, "rag_ask", map[string]interface{}{})
+		if !resp.Result.IsError || resp.Result.Content[0].Text != "question is required" {
+			t.Errorf("Expected a tool error, got: %+v", resp.Result)
+		}
+	})
+}
+
+func TestMCP_IndexPath(t *testing.T) {
+	backend, store, _ := newServeFixture(t, &fakeLLM{})
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "notes.md"), []byte("deploys run on Fridays"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	resp := callMCPTool(t, backend, "index_path", map[string]interface{}{"path": root})
+	if resp.Result.IsError {
+		t.Fatalf("Expected success, got error: %s", resp.Result.Content[0].Text)
+	}
+	if !strings.Contains(resp.Result.Content[0].Text, `"sources": 1`) {
+		t.Errorf("Expected an index summary, got: %s", resp.Result.Content[0].Text)
+	}
+	if len(store.added["documents"]) != 1 {
+		t.Errorf("Expected 1 stored chunk, got %d", len(store.added["documents"]))
+	}
+
+	resp = callMCPTool(t, backend, "index_path", map[string]interface{}{"text": "raw text is not accepted"})
+	if !resp.Result.IsError {
+		t.Errorf("Expected index_path without a path to fail, got: %+v", resp.Result)
+	}
+}
@@ -0,0 +1,228 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"rag-cli/internal/history"
+	"rag-cli/internal/vector"
+	"rag-cli/pkg/config"
+)
+
+var (
+	purgeCollection string
+	purgeAll        bool
+	purgeSource     string
+	purgeOlderThan  string
+	purgeYes        bool
+)
+
+var purgeCmd = &cobra.Command{
+	Use:   "purge",
+	Short: "Delete indexed data from the vector store",
+	Long: `Delete documents from the vector store. At least one selector is required:
+
+  --collection NAME   Delete every document in one collection (documents, commands, auto)
+  --all               Delete every document in all configured collections
+  --source PREFIX     Delete chunks whose source path starts with PREFIX
+                      (searches documents and auto-indexed files unless --collection is set)
+  --older-than AGE    Delete command sessions older than AGE, e.g. 30d, 2w, or 12h
+                      (searches command history unless --collection is set)
+
+You are asked to confirm before anything is deleted unless --yes is passed.
+
+EXAMPLES:
+  # Wipe the documents collection
+  rag-cli purge --collection documents
+
+  # Remove everything indexed from a directory
+  rag-cli purge --source docs/old/ --yes
+
+  # Forget command sessions older than a month
+  rag-cli purge --older-than 30d`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opts := purgeOptions{
+			collection: purgeCollection,
+			all:        purgeAll,
+			source:     purgeSource,
+			yes:        purgeYes,
+		}
+		if purgeOlderThan != "" {
+			age, err := parseAge(purgeOlderThan)
+			if err != nil {
+				return err
+			}
+			opts.olderThan = age
+		}
+		if err := opts.validate(); err != nil {
+			return err
+		}
+
+		cfg, err := config.Load()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		vectorStore, err := vector.NewChromaClient(cfg.Vector, cfg.Timeouts)
+		if err != nil {
+			return fmt.Errorf("failed to initialize vector store: %w", err)
+		}
+
+		return runPurge(os.Stdin, os.Stdout, vectorStore, opts, time.Now())
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(purgeCmd)
+
+	purgeCmd.Flags().StringVarP(&purgeCollection, "collection", "c", "", "Collection to purge: documents, commands, or auto")
+	purgeCmd.Flags().BoolVar(&purgeAll, "all", false, "Delete all documents in every configured collection")
+	purgeCmd.Flags().StringVar(&purgeSource, "source", "", "Delete chunks whose source path starts with this prefix")
+	purgeCmd.Flags().StringVar(&purgeOlderThan, "older-than", "", "Delete command sessions older than this age (e.g. 30d, 2w, 12h)")
+	purgeCmd.Flags().BoolVarP(&purgeYes, "yes", "y", false, "Skip the confirmation prompt")
+}
+
+type purgeOptions struct {
+	collection string
+	all        bool
+	source     string
+	olderThan  time.Duration
+	yes        bool
+}
+
+func (o purgeOptions) validate() error {
+	if !o.all && o.collection == "" && o.source == "" && o.olderThan == 0 {
+		return fmt.Errorf("nothing selected: use --collection, --all, --source, or --older-than")
+	}
+	if o.all && (o.collection != "" || o.source != "" || o.olderThan != 0) {
+		return fmt.Errorf("--all cannot be combined with other selectors")
+	}
+	return nil
+}
+
+// targetCollections returns the collections a purge applies to
+func (o purgeOptions) targetCollections(store vector.VectorStore) ([]string, error) {
+	switch {
+	case o.collection != "":
+		name, err := resolveCollection(store, o.collection)
+		if err != nil {
+			return nil, err
+		}
+		return []string{name}, nil
+	case o.all:
+		return []string{store.DocumentsCollection(), store.CommandsCollection(), store.AutoIndexCollection()}, nil
+	case o.olderThan != 0:
+		return []string{store.CommandsCollection()}, nil
+	default:
+		return []string{store.DocumentsCollection(), store.AutoIndexCollection()}, nil
+	}
+}
+
+// matches reports whether a stored document is selected for deletion
+func (o purgeOptions) matches(doc vector.StoredDocument, now time.Time) bool {
+	if o.source != "" && !strings.HasPrefix(documentSource(doc), strings.TrimPrefix(o.source, "./")) {
+		return false
+	}
+	if o.olderThan != 0 {
+		created, ok := history.Timestamp(doc)
+		if !ok || now.Sub(created) <= o.olderThan {
+			return false
+		}
+	}
+	return true
+}
+
+func runPurge(in io.Reader, out io.Writer, store vector.VectorStore, opts purgeOptions, now time.Time) error {
+	collections, err := opts.targetCollections(store)
+	if err != nil {
+		return err
+	}
+
+	selected := make(map[string][]string)
+	total := 0
+	for _, collection := range collections {
+		docs, err := store.GetDocuments(collection, 0)
+		if err != nil {
+			return fmt.Errorf("failed to read collection %s: %w", collection, err)
+		}
+		for _, doc := range docs {
+			if opts.matches(doc, now) {
+				selected[collection] = append(selected[collection], doc.ID)
+			}
+		}
+		total += len(selected[collection])
+	}
+
+	if total == 0 {
+		fmt.Fprintln(out, "No matching documents found, nothing to purge")
+		return nil
+	}
+
+	if !opts.yes {
+		fmt.Fprintf(out, "Delete %d document(s) from %s? [y/N]: ", total, strings.Join(collections, ", "))
+		if !confirm(in) {
+			fmt.Fprintln(out, "Purge cancelled")
+			return nil
+		}
+	}
+
+	removed := 0
+	for _, collection := range collections {
+		ids := selected[collection]
+		if len(ids) == 0 {
+			continue
+		}
+		if err := store.DeleteDocuments(collection, ids); err != nil {
+			return fmt.Errorf("failed to delete documents from %s (%d removed so far): %w", collection, removed, err)
+		}
+		removed += len(ids)
+		fmt.Fprintf(out, "Removed %d document(s) from %s\n", len(ids), collection)
+	}
+
+	fmt.Fprintf(out, "Purge complete: %d document(s) removed\n", removed)
+	return nil
+}
+
+// confirm reads a yes/no answer, defaulting to no
+func confirm(in io.Reader) bool {
+	answer, _ := bufio.NewReader(in).ReadString('\n')
+	answer = strings.TrimSpace(strings.ToLower(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// parseAge parses durations like "30d", "2w", or anything time.ParseDuration accepts
+func parseAge(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if strings.HasSuffix(value, suffix) {
+			n, err := strconv.Atoi(strings.TrimSuffix(value, suffix))
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid age %q", value)
+			}
+			return time.Duration(n) * unit, nil
+		}
+	}
+
+	age, err := time.ParseDuration(value)
+	if err != nil || age <= 0 {
+		return 0, fmt.Errorf("invalid age %q (use e.g. 30d, 2w, or 12h)", value)
+	}
+	return age, nil
+}
+
+// documentSource returns the source path recorded in a document's metadata
+func documentSource(doc vector.StoredDocument) string {
+	for _, key := range []string{"source_path", "source", "path"} {
+		if value, ok := doc.Metadata[key].(string); ok {
+			return strings.TrimPrefix(value, "./")
+		}
+	}
+	return ""
+}
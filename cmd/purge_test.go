@@ -0,0 +1,190 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"rag-cli/internal/vector"
+)
+
+var (
+	purgeNow       = time.Date(2025, 8, 1, 12, 0, 0, 0, time.UTC)
+	oldSessionID   = fmt.Sprintf("cmd_session_%d", purgeNow.Add(-40*24*time.Hour).Unix())
+	recentSession  = fmt.Sprintf("cmd_session_%d", purgeNow.Add(-2*24*time.Hour).Unix())
+	purgeFixtureTS = purgeNow.Add(-90 * 24 * time.Hour).Format(time.RFC3339)
+)
+
+func newPurgeFixture() *fakeStore {
+	store := newFakeStore()
+	store.documents["documents"] = []vector.StoredDocument{
+		{ID: "d1", Metadata: map[string]interface{}{"source_path": "docs/old/a.md"}},
+		{ID: "d2", Metadata: map[string]interface{}{"source_path": "docs/current/b.md"}},
+		{ID: "d3"},
+	}
+	store.documents["auto_indexed"] = []vector.StoredDocument{
+		{ID: "a1", Metadata: map[string]interface{}{"source_path": "docs/old/c.md"}},
+	}
+	store.documents["command_history"] = []vector.StoredDocument{
+		{ID: oldSessionID},
+		{ID: recentSession},
+		{ID: "s3", Metadata: map[string]interface{}{"timestamp": purgeFixtureTS}},
+		{ID: "s4"},
+	}
+	return store
+}
+
+func TestRunPurge_Selectors(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     purgeOptions
+		expected map[string][]string
+	}{
+		{
+			name:     "single collection",
+			opts:     purgeOptions{collection: "documents", yes: true},
+			expected: map[string][]string{"documents": {"d1", "d2", "d3"}},
+		},
+		{
+			name: "all collections",
+			opts: purgeOptions{all: true, yes: true},
+			expected: map[string][]string{
+				"documents":       {"d1", "d2", "d3"},
+				"command_history": {oldSessionID, recentSession, "s3", "s4"},
+				"auto_indexed":    {"a1"},
+			},
+		},
+		{
+			name: "source prefix",
+			opts: purgeOptions{source: "./docs/old/", yes: true},
+			expected: map[string][]string{
+				"documents":    {"d1"},
+				"auto_indexed": {"a1"},
+			},
+		},
+		{
+			name:     "source prefix restricted to collection",
+			opts:     purgeOptions{source: "docs/", collection: "documents", yes: true},
+			expected: map[string][]string{"documents": {"d1", "d2"}},
+		},
+		{
+			name:     "older than",
+			opts:     purgeOptions{olderThan: 30 * 24 * time.Hour, yes: true},
+			expected: map[string][]string{"command_history": {oldSessionID, "s3"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newPurgeFixture()
+			var out bytes.Buffer
+			if err := runPurge(strings.NewReader(""), &out, store, tt.opts, purgeNow); err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+
+			if !reflect.DeepEqual(store.deleted, tt.expected) {
+				t.Errorf("Expected deletions %v, got %v", tt.expected, store.deleted)
+			}
+
+			total := 0
+			for _, ids := range tt.expected {
+				total += len(ids)
+			}
+			if want := fmt.Sprintf("Purge complete: %d document(s) removed", total); !strings.Contains(out.String(), want) {
+				t.Errorf("Expected %q in output, got:\n%s", want, out.String())
+			}
+		})
+	}
+}
+
+func TestRunPurge_Confirmation(t *testing.T) {
+	t.Run("declined", func(t *testing.T) {
+		store := newPurgeFixture()
+		var out bytes.Buffer
+		if err := runPurge(strings.NewReader("n\n"), &out, store, purgeOptions{collection: "auto"}, purgeNow); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if len(store.deleted) != 0 {
+			t.Errorf("Expected nothing deleted, got %v", store.deleted)
+		}
+		if !strings.Contains(out.String(), "Delete 1 document(s) from auto_indexed? [y/N]") || !strings.Contains(out.String(), "Purge cancelled") {
+			t.Errorf("Expected confirmation prompt and cancellation, got:\n%s", out.String())
+		}
+	})
+
+	t.Run("empty answer defaults to no", func(t *testing.T) {
+		store := newPurgeFixture()
+		var out bytes.Buffer
+		if err := runPurge(strings.NewReader("\n"), &out, store, purgeOptions{collection: "auto"}, purgeNow); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if len(store.deleted) != 0 {
+			t.Errorf("Expected nothing deleted, got %v", store.deleted)
+		}
+	})
+
+	t.Run("accepted", func(t *testing.T) {
+		store := newPurgeFixture()
+		var out bytes.Buffer
+		if err := runPurge(strings.NewReader("yes\n"), &out, store, purgeOptions{collection: "auto"}, purgeNow); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if !reflect.DeepEqual(store.deleted["auto_indexed"], []string{"a1"}) {
+			t.Errorf("Expected a1 deleted, got %v", store.deleted)
+		}
+	})
+
+	t.Run("nothing matched", func(t *testing.T) {
+		store := newPurgeFixture()
+		var out bytes.Buffer
+		if err := runPurge(strings.NewReader(""), &out, store, purgeOptions{source: "nowhere/"}, purgeNow); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if !strings.Contains(out.String(), "nothing to purge") {
+			t.Errorf("Expected nothing-to-purge message, got:\n%s", out.String())
+		}
+	})
+}
+
+func TestPurgeOptions_Validate(t *testing.T) {
+	if err := (purgeOptions{}).validate(); err == nil {
+		t.Error("Expected error when no selector is given")
+	}
+	if err := (purgeOptions{all: true, source: "x"}).validate(); err == nil {
+		t.Error("Expected error when --all is combined with --source")
+	}
+	if err := (purgeOptions{source: "x", olderThan: time.Hour}).validate(); err != nil {
+		t.Errorf("Expected combined selectors to be valid, got: %v", err)
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected time.Duration
+		wantErr  bool
+	}{
+		{"30d", 30 * 24 * time.Hour, false},
+		{"2w", 14 * 24 * time.Hour, false},
+		{"12h", 12 * time.Hour, false},
+		{"90m", 90 * time.Minute, false},
+		{"0d", 0, true},
+		{"xd", 0, true},
+		{"soon", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := parseAge(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseAge(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.expected {
+				t.Errorf("parseAge(%q) = %v, expected %v", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
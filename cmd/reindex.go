@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"rag-cli/internal/vector"
+	"rag-cli/pkg/config"
+)
+
+var (
+	reindexCollection string
+	reindexRecursive  bool
+	reindexYes        bool
+)
+
+var reindexCmd = &cobra.Command{
+	Use:   "reindex [path]",
+	Short: "Wipe the documents collection and index again from scratch",
+	Long: `Delete everything in the documents collection and index the given path (or the
+current directory) again with the current settings.
+
+Use this after changing chunk sizes, the embedding model, or file formats, when the
+stored chunks no longer match what indexing would produce. Document counts are shown
+before and after the rebuild.
+
+Unlike index, reindex walks directories recursively by default. You are asked to
+confirm before the collection is wiped unless --yes is passed.
+
+EXAMPLES:
+  # Rebuild the documents collection from the current directory
+  rag-cli reindex
+
+  # Rebuild a separate collection without a prompt
+  rag-cli reindex --collection project-x --yes ~/projects/x/docs`,
+	Args:         cobra.MaximumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path := "."
+		if len(args) > 0 {
+			path = args[0]
+		}
+
+		cfg, err := config.Load()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		overrideDocumentsCollection(cfg, reindexCollection)
+
+		vectorStore, err := vector.NewChromaClient(cfg.Vector, cfg.Timeouts)
+		if err != nil {
+			return fmt.Errorf("failed to initialize vector store: %w", err)
+		}
+
+		// Rebuild with the same pipeline and defaults as index
+		indexCollection = reindexCollection
+		indexRecursive = reindexRecursive
+		indexForce = true
+		return runReindex(os.Stdin, os.Stdout, vectorStore, vectorStore.DocumentsCollection(), reindexYes, func() error {
+			return runIndex(cmd.Context(), path, nil, nil)
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(reindexCmd)
+
+	reindexCmd.Flags().StringVarP(&reindexCollection, "collection", "c", "", "Collection to rebuild instead of vector.collection")
+	reindexCmd.Flags().BoolVarP(&reindexRecursive, "recursive", "r", true, "Index directories recursively")
+	reindexCmd.Flags().BoolVarP(&reindexYes, "yes", "y", false, "Skip the confirmation prompt")
+}
+
+// runReindex resets a collection and then rebuilds it with index, reporting
+// document counts before and after
+func runReindex(in io.Reader, out io.Writer, store vector.VectorStore, collection string, yes bool, index func() error) error {
+	before, err := store.Count(collection)
+	if err != nil {
+		return fmt.Errorf("failed to count documents in %s: %w", collection, err)
+	}
+
+	if !yes {
+		fmt.Fprintf(out, "Delete all %d document(s) in %s and index again? [y/N]: ", before, collection)
+		if !confirm(in) {
+			fmt.Fprintln(out, "Reindex cancelled")
+			return nil
+		}
+	}
+
+	if err := store.ResetCollection(collection); err != nil {
+		return fmt.Errorf("failed to reset collection %s: %w", collection, err)
+	}
+	fmt.Fprintf(out, "Removed %d document(s) from %s\n", before, collection)
+
+	if err := index(); err != nil {
+		return err
+	}
+
+	after, err := store.Count(collection)
+	if err != nil {
+		return fmt.Errorf("failed to count documents in %s: %w", collection, err)
+	}
+	fmt.Fprintf(out, "Reindex complete: %s went from %d to %d document(s)\n", collection, before, after)
+	return nil
+}
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRunReindex(t *testing.T) {
+	t.Run("resets before indexing and reports counts", func(t *testing.T) {
+		store := newFakeStore()
+		store.counts["documents"] = 40
+		var out bytes.Buffer
+
+		indexed := false
+		err := runReindex(strings.NewReader(""), &out, store, "documents", true, func() error {
+			if len(store.reset) != 1 || store.reset[0] != "documents" {
+				t.Errorf("Expected documents to be reset before indexing, got %v", store.reset)
+			}
+			indexed = true
+			store.counts["documents"] = 25
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if !indexed {
+			t.Fatal("Expected index to run")
+		}
+		if !strings.Contains(out.String(), "went from 40 to 25 document(s)") {
+			t.Errorf("Expected before/after counts, got: %q", out.String())
+		}
+	})
+
+	t.Run("declined confirmation leaves the collection alone", func(t *testing.T) {
+		store := newFakeStore()
+		store.counts["documents"] = 3
+		var out bytes.Buffer
+
+		err := runReindex(strings.NewReader("n\n"), &out, store, "documents", false, func() error {
+			t.Error("Expected index not to run")
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if len(store.reset) != 0 {
+			t.Errorf("Expected no reset, got %v", store.reset)
+		}
+		if !strings.Contains(out.String(), "Reindex cancelled") {
+			t.Errorf("Expected cancellation message, got: %q", out.String())
+		}
+	})
+
+	t.Run("index failure is returned", func(t *testing.T) {
+		store := newFakeStore()
+		store.counts["project-x"] = 1
+		var out bytes.Buffer
+
+		err := runReindex(strings.NewReader("y\n"), &out, store, "project-x", false, func() error {
+			return errors.New("embedding service unavailable")
+		})
+		if err == nil || !strings.Contains(err.Error(), "embedding service unavailable") {
+			t.Errorf("Expected index error, got: %v", err)
+		}
+		if len(store.reset) != 1 || store.reset[0] != "project-x" {
+			t.Errorf("Expected project-x to be reset, got %v", store.reset)
+		}
+	})
+}
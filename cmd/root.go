@@ -1,16 +1,31 @@
 package cmd
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
-	"github.com/spf13/cobra/doc"
-	"github.com/spf13/viper"
 	"rag-cli/internal/chat"
+	"rag-cli/internal/chunker"
 	"rag-cli/internal/embeddings"
+	"rag-cli/internal/history"
+	"rag-cli/internal/httpclient"
 	"rag-cli/internal/indexing"
 	"rag-cli/internal/llm"
+	"rag-cli/internal/logging"
+	"rag-cli/internal/metrics"
+	"rag-cli/internal/system"
+	"rag-cli/internal/transcript"
+	"rag-cli/internal/update"
 	"rag-cli/internal/vector"
 	"rag-cli/pkg/config"
 	"rag-cli/pkg/version"
@@ -18,6 +33,9 @@ import (
 
 var cfgFile string
 
+// refreshSysInfo forces system detection instead of reading the cache
+var refreshSysInfo bool
+
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "rag-cli",
@@ -36,23 +54,48 @@ EXAMPLES:
   # Start interactive chat (default behavior)
   rag-cli
 
+  # Single prompt; proposed commands are printed, not run
+  rag-cli --prompt "how do I find files larger than 1GB?"
+
   # Single prompt with command execution
-  rag-cli --prompt "create a backup of my config files"
+  rag-cli --allow-commands --prompt "create a backup of my config files"
+
+  # Interactive chat that only suggests commands
+  rag-cli --no-exec
+
+  # See what a task would run, with each command's risk, without running it
+  rag-cli --dry-run --prompt "free up disk space in my home directory"
+
+  # Use a different model for one invocation
+  rag-cli --model llama3.1:8b --prompt "summarize the open TODOs in this repo"
+
+  # Chat against a corpus indexed with 'rag-cli index --collection project-x'
+  rag-cli --collection project-x
+
+  # Show the context a prompt would retrieve, without calling the LLM
+  rag-cli --context-only --prompt "how do I rotate the API keys?"
 
   # Auto-approve commands (use with caution)
-  rag-cli --auto-approve --prompt "show me the largest files"
+  rag-cli --allow-commands --auto-approve --prompt "show me the largest files"
+
+  # Give up after five minutes in CI; exits with status 124 on timeout
+  rag-cli --allow-commands --auto-approve --timeout 5m --prompt "run the test suite and summarize failures"
 
   # Non-interactive mode without command execution
   rag-cli --prompt "explain how to set up a Go project" --no-history
 
+  # Script a task and read the answer and each command's output as JSON
+  rag-cli --allow-commands --auto-approve --output json --prompt "count the Go files" | jq .answer
+
 PREREQUISITES:
   - Ollama running locally (brew install ollama)
   - ChromaDB running in Docker or locally
   - Models: llama3.1:8b (8B+ recommended), all-minilm
 
 CONFIGURATION:
-  Create ~/.rag-cli.yaml to customize LLM models, hosts, and other settings.
-  See config-example.yaml for reference.`,
+  Run 'rag-cli config init' to create a config file (~/.config/rag-cli/config.yaml
+  on Linux) to customize LLM models, hosts, and other settings. See
+  config-example.yaml for reference.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if versionFlag, _ := cmd.Flags().GetBool("version"); versionFlag {
 			fmt.Println(version.GetBuildInfo().String())
@@ -64,56 +107,65 @@ CONFIGURATION:
 	},
 }
 
-// docsCmd generates documentation for all commands
-var docsCmd = &cobra.Command{
-	Use:    "docs",
-	Short:  "Generate documentation for all commands",
-	Long:   "Generate Markdown documentation for all commands and subcommands.",
-	Hidden: true, // Hidden from help output
-	RunE: func(cmd *cobra.Command, args []string) error {
-		docsDir := "./docs"
-		
-		// Create docs directory if it doesn't exist
-		if err := os.MkdirAll(docsDir, 0755); err != nil {
-			return fmt.Errorf("failed to create docs directory: %w", err)
-		}
-		
-		// Generate markdown documentation
-		if err := doc.GenMarkdownTree(rootCmd, docsDir); err != nil {
-			return fmt.Errorf("failed to generate documentation: %w", err)
-		}
-		
-		fmt.Printf("Documentation generated in %s/\n", docsDir)
-		return nil
-	},
-}
-
 // Execute adds all child commands to the root command and sets flags appropriately.
+// Commands run under a context that is cancelled by the first Ctrl+C or
+// SIGTERM, so they can stop their work and save their state; a second Ctrl+C
+// exits immediately. Ctrl+C while a chat is running a command only stops
+// that command.
 func Execute() error {
-	return rootCmd.Execute()
+	ctx, stop := interruptContext(os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	cmd, err := rootCmd.ExecuteContextC(ctx)
+	if err == nil && cmd != versionCmd {
+		if cfg, cfgErr := config.Load(); cfgErr == nil {
+			cachePath, _ := update.DefaultCachePath()
+			notifyUpdate(os.Stderr, update.NewChecker(cachePath), version.Version, cfg.Updates)
+		}
+	}
+	return err
 }
 
 func init() {
 	cobra.OnInitialize(initConfig)
 
-	// Add subcommands
-	rootCmd.AddCommand(docsCmd)
-
 	// Global flags
-	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.rag-cli.yaml)")
-	rootCmd.PersistentFlags().Bool("debug", false, "Enable debug mode with detailed logging")
+	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file to use instead of the user and project config files")
+	rootCmd.PersistentFlags().Bool("debug", false, "Write detailed evaluation logs to debug.log_file (default debug.log in the rag-cli state directory, e.g. ~/.local/state/rag-cli)")
+	rootCmd.PersistentFlags().String("log-level", "", "Least severe messages to log: error, warn, info, or debug, overriding log.level")
+	rootCmd.PersistentFlags().String("model", "", "LLM model to use for this invocation, overriding llm.model (also RAG_CLI_LLM_MODEL)")
+	rootCmd.PersistentFlags().BoolVar(&refreshSysInfo, "refresh-sysinfo", false, "Detect the OS and installed tools again instead of using the cached results, e.g. after installing new tools")
 	rootCmd.Flags().BoolP("version", "v", false, "Print version information and build details")
 	
 	// Chat flags (now at root level)
 	rootCmd.Flags().StringP("prompt", "p", "", "Single prompt for non-interactive mode. Execute one task and exit.")
 	rootCmd.Flags().Bool("auto-approve", false, "Automatically approve command execution without user confirmation. USE WITH CAUTION - commands execute immediately.")
+	rootCmd.Flags().Bool("no-exec", false, "Print the commands the model proposes instead of running them")
+	rootCmd.Flags().Bool("dry-run", false, "Show the commands the model would run, with what each does and its risk level, without running them, asking for approval or writing to the vector store")
+	rootCmd.Flags().Bool("allow-commands", false, "Run the commands the model proposes, overriding chat.allow_commands (with --prompt, commands are only printed unless this or chat.allow_commands is set)")
 	rootCmd.Flags().Bool("auto-index", false, "Automatically index file changes after command execution for learning")
+	rootCmd.Flags().Int("top-k", 0, "Number of document chunks to retrieve as context, overriding chat.top_k_documents (1-50)")
+	rootCmd.Flags().String("collection", "", "Documents collection to use as chat context instead of vector.collection")
+	rootCmd.Flags().Bool("context-only", false, "With --prompt, print the context that would be retrieved (documents and history) and exit without calling the LLM")
+	rootCmd.Flags().Duration("timeout", 0, "With --prompt, stop the whole run after this long (e.g. 90s, 5m), cancelling in-flight LLM requests and commands, and exit with status 124")
 	rootCmd.Flags().Bool("no-history", false, "Disable historical context lookup. Useful for testing or when you want fresh responses without past context.")
+	rootCmd.Flags().Bool("no-sources", false, "Hide the documents an answer drew on, overriding chat.show_sources")
+	rootCmd.Flags().StringP("output", "o", "text", "With --prompt, output format: text, or json for one JSON object with the answer and the commands run, with all other output on stderr")
+	rootCmd.Flags().Bool("show-prompt", false, "Show the full prompt sent to the LLM (context, system hints and request) after each response, with secrets redacted; with --prompt it is written to stderr")
 	
 	// Bind flags to viper
-	if err := viper.BindPFlag("debug", rootCmd.PersistentFlags().Lookup("debug")); err != nil {
+	if err := config.BindFlag("debug.enabled", rootCmd.PersistentFlags().Lookup("debug")); err != nil {
 		fmt.Fprintf(os.Stderr, "Error binding debug flag: %v\n", err)
 	}
+	if err := config.BindFlag("log.level", rootCmd.PersistentFlags().Lookup("log-level")); err != nil {
+		fmt.Fprintf(os.Stderr, "Error binding log-level flag: %v\n", err)
+	}
+	if err := config.BindFlag("llm.model", rootCmd.PersistentFlags().Lookup("model")); err != nil {
+		fmt.Fprintf(os.Stderr, "Error binding model flag: %v\n", err)
+	}
+	if err := config.BindFlag("chat.top_k_documents", rootCmd.Flags().Lookup("top-k")); err != nil {
+		fmt.Fprintf(os.Stderr, "Error binding top-k flag: %v\n", err)
+	}
 }
 
 func runChat(cmd *cobra.Command) error {
@@ -121,39 +173,126 @@ func runChat(cmd *cobra.Command) error {
 	if err != nil {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
+	collection, _ := cmd.Flags().GetString("collection")
+	overrideDocumentsCollection(cfg, collection)
+	prompt, _ := cmd.Flags().GetString("prompt")
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+	if timeout < 0 {
+		return fmt.Errorf("--timeout must not be negative")
+	}
+	if timeout > 0 && prompt == "" {
+		return fmt.Errorf("--timeout requires --prompt")
+	}
+	output, _ := cmd.Flags().GetString("output")
+	if output != "text" && output != "json" {
+		return fmt.Errorf("invalid --output %q (expected text or json)", output)
+	}
+	jsonOutput := output == "json"
+	if jsonOutput && prompt == "" {
+		return fmt.Errorf("--output json requires --prompt")
+	}
+	// With --output json, stdout carries nothing but the result
+	progress := io.Writer(os.Stdout)
+	if jsonOutput {
+		progress = os.Stderr
+	}
 
-	// Initialize LLM client
-	llmClient, err := llm.NewClient(cfg.LLM)
+	noExec, _ := cmd.Flags().GetBool("no-exec")
+	allow, _ := cmd.Flags().GetBool("allow-commands")
+	allowCommands, err := resolveAllowCommands(noExec, allow, cfg.Chat.AllowCommands, config.SourceOf("chat.allow_commands"), prompt != "")
 	if err != nil {
-		return fmt.Errorf("failed to initialize LLM client: %w", err)
+		return err
+	}
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	if dryRun {
+		for _, name := range []string{"allow-commands", "auto-approve", "auto-index"} {
+			if set, _ := cmd.Flags().GetBool(name); set {
+				return fmt.Errorf("--dry-run and --%s cannot be used together", name)
+			}
+		}
 	}
 
-	// Initialize embeddings client
-	embeddingsClient, err := embeddings.NewClient(cfg.Embeddings)
+	if contextOnly, _ := cmd.Flags().GetBool("context-only"); contextOnly {
+		return runChatContextOnly(cmd, cfg, jsonOutput)
+	}
+	if err := configureDebugLog(os.Stderr, cfg.Debug); err != nil {
+		return err
+	}
+
+	// Initialize LLM client
+	llmClient, err := newLLMClient(cfg)
 	if err != nil {
-		return fmt.Errorf("failed to initialize embeddings client: %w", err)
+		return err
+	}
+	if err := requireLLM(llmClient, cfg.LLM.URL()); err != nil {
+		return err
+	}
+	if err := checkModelOverride(llmClient, cfg.LLM.Model); err != nil {
+		return err
 	}
 
-	// Initialize vector store
-	vectorStore, err := vector.NewChromaClient(cfg.Vector)
+	// Initialize embeddings client
+	embeddingsClient, err := embeddings.NewClient(cfg.Embeddings, cfg.Timeouts)
 	if err != nil {
-		return fmt.Errorf("failed to initialize vector store: %w", err)
+		return fmt.Errorf("failed to initialize embeddings client: %w", err)
 	}
 
 	// Get flags
-	prompt, _ := cmd.Flags().GetString("prompt")
 	autoApprove, _ := cmd.Flags().GetBool("auto-approve")
 	autoIndex, _ := cmd.Flags().GetBool("auto-index")
 	noHistory, _ := cmd.Flags().GetBool("no-history")
+	showPrompt, _ := cmd.Flags().GetBool("show-prompt")
+	noSources, _ := cmd.Flags().GetBool("no-sources")
+
+	// Initialize vector store, going ahead without it if it is down
+	vectorStore, ragUnavailable, err := connectVectorStore(os.Stderr, cfg)
+	if err != nil {
+		return err
+	}
+	if ragUnavailable && autoIndex {
+		fmt.Fprintln(os.Stderr, "Warning: auto-indexing is off for this session because ChromaDB is not reachable")
+		autoIndex = false
+	}
+
+	// Apply the configured command history retention; a dry run changes nothing
+	if !ragUnavailable && !dryRun {
+		if removed, err := history.Prune(vectorStore, history.RetentionPolicy(cfg.History), time.Now()); err != nil {
+			slog.Warn("failed to apply history retention", "component", "history", "collection", vectorStore.CommandsCollection(), "error", err)
+		} else if removed > 0 {
+			fmt.Fprintf(progress, "Pruned %d old command session(s) from history\n", removed)
+		}
+	}
 
 	// Create session config
 	sessionConfig := &chat.SessionConfig{
-		AutoApprove:     autoApprove,
-		AutoIndex:       autoIndex,
-		NoHistory:       noHistory,
-		MaxAttempts:     cfg.Chat.MaxAttempts,
-		MaxOutputLines:  cfg.Chat.MaxOutputLines,
-		TruncateOutput:  cfg.Chat.TruncateOutput,
+		AutoApprove:      autoApprove,
+		AutoIndex:        autoIndex,
+		NoHistory:        noHistory,
+		MaxAttempts:      cfg.Chat.MaxAttempts,
+		MaxOutputLines:   cfg.Chat.MaxOutputLines,
+		TruncateOutput:   cfg.Chat.TruncateOutput,
+		MaxInputChars:    cfg.Chat.MaxInputChars,
+		TopKDocuments:    cfg.Chat.TopKDocuments,
+		TopKHistory:      cfg.Chat.TopKHistory,
+		NoExec:           !allowCommands,
+		DryRun:           dryRun,
+		RAGUnavailable:   ragUnavailable,
+		ShowPrompt:       showPrompt,
+		MemoryChars:      cfg.Chat.MemoryChars,
+		MaxContextChars:  cfg.Chat.MaxContextChars,
+		ContextItemChars: cfg.Chat.ContextItemChars,
+		SummarizeMemory:  cfg.Chat.SummarizeMemory,
+		CommandTimeout:   cfg.Chat.CommandTimeout,
+		NoSources:        noSources || !cfg.Chat.ShowSources,
+	}
+	if sessionConfig.Safety, err = safetyPolicy(cfg); err != nil {
+		return err
+	}
+	sessionConfig.Approvals = approvalPolicy(cfg)
+	if cfg.Telemetry.Local {
+		if path, err := metrics.DefaultPath(); err == nil {
+			sessionConfig.Usage = metrics.NewRecorder(path)
+		}
 	}
 
 	// Initialize auto-indexer if enabled
@@ -169,46 +308,334 @@ func runChat(cmd *cobra.Command) error {
 		autoIndexConfig.Enabled = true
 		
 		autoIndexer = indexing.NewAutoIndexer(&autoIndexConfig, embeddingsClient, vectorStore, cwd)
+		autoIndexer.UseChunker(chunker.New(cfg.Chunker))
+		useAutoIndexManifest(autoIndexer)
 		// Take initial snapshot
 		if err := autoIndexer.TakeSnapshot(); err != nil {
-			fmt.Printf("Warning: Failed to take initial file snapshot: %v\n", err)
+			slog.Warn("failed to take initial file snapshot", "component", "auto_index", "path", cwd, "error", err)
 		}
 	}
 
 	// Check if we're in non-interactive mode
 	if prompt != "" {
 		session := chat.NewSession(sessionConfig, llmClient, embeddingsClient, vectorStore, autoIndexer)
-		return session.HandlePrompt(prompt)
+		if jsonOutput {
+			// Keeps stdout for the JSON while warnings, refusals and
+			// progress still reach the user
+			session.UseDisplay(os.Stderr)
+		}
+		return runPromptWithTimeout(cmd.Context(), os.Stderr, timeout, func(ctx context.Context) error {
+			if !jsonOutput {
+				return session.HandlePrompt(ctx, prompt)
+			}
+			result, err := session.RunPrompt(ctx, prompt)
+			if encodeErr := writePromptJSON(os.Stdout, result); encodeErr != nil {
+				return encodeErr
+			}
+			return err
+		})
+	}
+
+	// Keep a transcript of interactive chats when asked to
+	if cfg.Chat.SaveTranscripts {
+		if dir, err := transcript.DefaultDir(); err == nil {
+			sessionConfig.Transcript = transcript.NewWriter(dir, cfg.Chat.TranscriptMaxOutput)
+		} else {
+			slog.Warn("transcripts disabled", "component", "transcript", "error", err)
+		}
 	}
 
 	// Run interactive session with simple implementation
 	simpleSession := chat.NewSimpleSession(sessionConfig, llmClient, embeddingsClient, vectorStore, autoIndexer)
-	return simpleSession.Run()
+	return interruptedExit(simpleSession.Run(cmd.Context()))
 }
 
-// initConfig reads in config file and ENV variables if set.
-func initConfig() {
-	if cfgFile != "" {
-		// Use config file from the flag.
-		viper.SetConfigFile(cfgFile)
-	} else {
-		// Find home directory.
-		home, err := os.UserHomeDir()
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error getting home directory: %v\n", err)
-			os.Exit(1)
+// resolveAllowCommands decides whether proposed commands are run. --no-exec
+// and --allow-commands win, then chat.allow_commands when it is set in a
+// config file or the environment. Otherwise interactive chat runs commands
+// and a single --prompt only prints them.
+func resolveAllowCommands(noExec, allow, configured bool, source config.Source, singlePrompt bool) (bool, error) {
+	switch {
+	case noExec && allow:
+		return false, fmt.Errorf("--no-exec and --allow-commands cannot be used together")
+	case noExec:
+		return false, nil
+	case allow:
+		return true, nil
+	case source != config.SourceDefault:
+		return configured, nil
+	default:
+		return !singlePrompt, nil
+	}
+}
+
+// configureDebugLog turns on chat debug logging when --debug or
+// debug.enabled is set and reports where the log is written
+func configureDebugLog(out io.Writer, debug config.DebugConfig) error {
+	if !debug.Enabled {
+		return nil
+	}
+	path, err := debug.DebugLogPath()
+	if err != nil {
+		return err
+	}
+	logger, err := chat.NewDebugLogger(path, debug.MaxSize)
+	if err != nil {
+		return err
+	}
+	chat.SetDebugLogger(logger)
+	fmt.Fprintf(out, "Debug log: %s\n", path)
+	return nil
+}
+
+// interruptContext returns a context cancelled by the first of signals, after
+// which the signals get their default behavior back. An interrupt that stops
+// a command run by a chat session does not cancel it.
+func interruptContext(signals ...os.Signal) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	received := make(chan os.Signal, 1)
+	signal.Notify(received, signals...)
+	stop := func() {
+		signal.Stop(received)
+		cancel()
+	}
+
+	go func() {
+		for {
+			select {
+			case sig := <-received:
+				if sig == os.Interrupt && chat.InterruptCommand() {
+					continue
+				}
+				stop()
+				return
+			case <-ctx.Done():
+				return
+			}
 		}
+	}()
+	return ctx, stop
+}
+
+// timeoutExitCode is the exit status when --timeout expires, matching timeout(1)
+const timeoutExitCode = 124
+
+// interruptExitCode is the exit status after Ctrl+C, as shells report SIGINT
+const interruptExitCode = 130
+
+// goalNotAchievedExitCode is the exit status of a --prompt run whose commands
+// failed or ran out of attempts
+const goalNotAchievedExitCode = 2
+
+// commandDeniedExitCode is the exit status of a --prompt run stopped because a
+// command was not approved
+const commandDeniedExitCode = 3
+
+// generationFailedExitCode is the exit status of a --prompt run the model
+// could not answer, as when Ollama is down or the model is missing
+const generationFailedExitCode = 4
+
+// promptExit turns the error of a --prompt run into an exit with the status
+// for its kind of failure, after printing it to out, and returns other errors
+// unchanged
+func promptExit(out io.Writer, err error) error {
+	var code int
+	switch {
+	case errors.Is(err, chat.ErrGenerationFailed):
+		code = generationFailedExitCode
+	case errors.Is(err, chat.ErrCommandDenied):
+		code = commandDeniedExitCode
+	case errors.Is(err, chat.ErrGoalNotAchieved):
+		code = goalNotAchievedExitCode
+	default:
+		return err
+	}
+	fmt.Fprintf(out, "Error: %v\n", err)
+	return &ExitCodeError{Code: code}
+}
+
+// interruptedExit turns the error of a run stopped by Ctrl+C into an exit with
+// interruptExitCode and returns other errors unchanged
+func interruptedExit(err error) error {
+	if errors.Is(err, context.Canceled) {
+		return &ExitCodeError{Code: interruptExitCode}
+	}
+	return err
+}
+
+// runPromptWithTimeout runs a single prompt under parent, with a deadline of
+// timeout, or without one when timeout is 0. When the deadline passes or
+// parent is cancelled, handle is expected to stop its work and return the
+// context's error, which becomes an exit with timeoutExitCode or
+// interruptExitCode. Failed tasks exit as promptExit describes.
+func runPromptWithTimeout(parent context.Context, out io.Writer, timeout time.Duration, handle func(ctx context.Context) error) error {
+	ctx := parent
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	err := handle(ctx)
+	if errors.Is(err, context.DeadlineExceeded) {
+		fmt.Fprintf(out, "Timed out after %s\n", timeout)
+		return &ExitCodeError{Code: timeoutExitCode}
+	}
+	if errors.Is(err, context.Canceled) {
+		fmt.Fprintln(out, "Interrupted")
+		return interruptedExit(err)
+	}
+	return promptExit(out, err)
+}
+
+// writePromptJSON prints the result of a --prompt run as a single JSON object
+func writePromptJSON(out io.Writer, result *chat.PromptResult) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(result)
+}
+
+// runChatContextOnly prints the context a --prompt would be given, using the
+// same retrieval depth and history setting as a chat session
+func runChatContextOnly(cmd *cobra.Command, cfg *config.Config, jsonOutput bool) error {
+	prompt, _ := cmd.Flags().GetString("prompt")
+	if prompt == "" {
+		return fmt.Errorf("--context-only requires --prompt")
+	}
+	noHistory, _ := cmd.Flags().GetBool("no-history")
+
+	embeddingsClient, err := embeddings.NewClient(cfg.Embeddings, cfg.Timeouts)
+	if err != nil {
+		return fmt.Errorf("failed to initialize embeddings client: %w", err)
+	}
+
+	vectorStore, err := vector.NewChromaClient(cfg.Vector, cfg.Timeouts)
+	if err != nil {
+		return fmt.Errorf("failed to initialize vector store: %w", err)
+	}
+
+	// Only measures the prompt the session would send; the LLM is not called
+	llmClient, err := newLLMClient(cfg)
+	if err != nil {
+		return err
+	}
+	budget := contextBudget(cfg.Chat, func(prompt string) int {
+		return llmClient.PromptSize(prompt, nil, nil)
+	})
+
+	return runContextOnly(os.Stdout, embeddingsClient, vectorStore, prompt, chatContextSources(vectorStore, cfg.Chat, noHistory), budget, jsonOutput)
+}
+
+// chatContextSources lists where a chat session retrieves context from, in order
+func chatContextSources(store vector.VectorStore, chat config.ChatConfig, noHistory bool) []contextSource {
+	sources := []contextSource{{collection: store.DocumentsCollection(), topK: chat.TopKDocuments, labelled: true}}
+	if !noHistory {
+		sources = append(sources, contextSource{collection: store.CommandsCollection(), topK: chat.TopKHistory})
+	}
+	return sources
+}
+
+// newLLMClient creates the LLM client with the prompt templates configured
+// for the model
+func newLLMClient(cfg *config.Config) (*llm.Client, error) {
+	llmClient, err := llm.NewClient(cfg.LLM, cfg.Timeouts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize LLM client: %w", err)
+	}
+	prompts, err := cfg.Prompts.Templates(cfg.LLM.Model)
+	if err != nil {
+		return nil, err
+	}
+	llmClient.UsePrompts(prompts)
+	llmClient.UseSystemInfoCache(systemInfoCache(cfg))
+	return llmClient, nil
+}
+
+// requireLLM refuses to start a chat when the LLM server cannot be reached,
+// since every prompt needs it. Other errors listing models are left for the
+// first prompt to report.
+func requireLLM(lister modelLister, baseURL string) error {
+	if _, err := lister.ListModels(); httpclient.Unavailable(err) {
+		return fmt.Errorf("chat needs the LLM, which is not reachable at %s: %w (is 'ollama serve' running? index, search, and doctor still work without it)", baseURL, err)
+	}
+	return nil
+}
+
+// connectVectorStore connects to ChromaDB. When the server cannot be reached
+// it writes a single warning to out and returns a client that connects on
+// first use, with unavailable set, so chat goes ahead without retrieval and
+// picks it up again once ChromaDB is back.
+func connectVectorStore(out io.Writer, cfg *config.Config) (store *vector.ChromaClient, unavailable bool, err error) {
+	if err := vector.NewChromaServer(cfg.Vector, cfg.Timeouts).Heartbeat(); httpclient.Unavailable(err) {
+		fmt.Fprintf(out, "Warning: %v\nContinuing without documents or command history until ChromaDB is back.\n", err)
+		return vector.NewLazyChromaClient(cfg.Vector, cfg.Timeouts), true, nil
+	}
+	store, err = vector.NewChromaClient(cfg.Vector, cfg.Timeouts)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to initialize vector store: %w", err)
+	}
+	return store, false, nil
+}
+
+// systemInfoCache returns the cache of detected system information, which
+// stores nothing when system_info.cache is off. With --refresh-sysinfo the
+// cached information is detected again and rewritten.
+func systemInfoCache(cfg *config.Config) *system.Cache {
+	path := ""
+	if cfg.SystemInfo.Cache {
+		path, _ = system.DefaultCachePath()
+	}
+	cache := system.NewCache(path, cfg.SystemInfo.CacheTTL)
+	cache.Resources = cfg.SystemInfo.Resources
+	if refreshSysInfo && path != "" {
+		cache.Refresh()
+	}
+	return cache
+}
+
+// checkModelOverride fails fast when the model chosen with --model or
+// RAG_CLI_LLM_MODEL is not available locally. Models from the config file are
+// not checked here; 'rag-cli doctor' reports on those.
+func checkModelOverride(lister modelLister, model string) error {
+	if source := config.SourceOf("llm.model"); source != config.SourceFlag && source != config.SourceEnv {
+		return nil
+	}
+	return validateModel(lister, model)
+}
 
-		// Search config in home directory with name ".rag-cli" (without extension).
-		viper.AddConfigPath(home)
-		viper.SetConfigType("yaml")
-		viper.SetConfigName(".rag-cli")
+// validateModel returns an error listing the available models when model is
+// not one of them. If the models cannot be listed, the model is not checked.
+func validateModel(lister modelLister, model string) error {
+	models, err := lister.ListModels()
+	if err != nil || hasModel(models, model) {
+		return nil
 	}
+	if len(models) == 0 {
+		return fmt.Errorf("model %q is not available locally and no models are installed (run 'ollama pull %s')", model, model)
+	}
+	return fmt.Errorf("model %q is not available locally; available models: %s", model, strings.Join(models, ", "))
+}
 
-	viper.AutomaticEnv() // read in environment variables that match
+// initConfig points config loading at the file given with --config, if any
+func initConfig() {
+	config.SetConfigFile(cfgFile)
+	configureLogging(os.Stderr)
+}
 
-	// If a config file is found, read it in.
-	if err := viper.ReadInConfig(); err == nil {
-		fmt.Fprintln(os.Stderr, "Using config file:", viper.ConfigFileUsed())
+// configureLogging sets up slog from the log settings. When the config cannot
+// be loaded, warnings go to stderr and the command reports the problem itself.
+func configureLogging(stderr io.Writer) {
+	logConfig := config.LogConfig{Level: "warn"}
+	if cfg, err := config.Load(); err == nil {
+		logConfig = cfg.Log
+	}
+	if err := logging.Configure(logConfig, stderr); err != nil {
+		fmt.Fprintf(stderr, "Warning: %v; logging to stderr\n", err)
+		logging.Configure(config.LogConfig{Level: logConfig.Level}, stderr)
+	}
+	if ignored := config.IgnoredProjectKeys(); len(ignored) > 0 {
+		_, project := config.ConfigFiles()
+		slog.Warn("ignoring settings a project config file may not change; set them in your user config instead",
+			"component", "config", "file", project, "keys", strings.Join(ignored, ", "))
 	}
 }
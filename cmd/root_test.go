@@ -0,0 +1,265 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"rag-cli/internal/chat"
+	"rag-cli/internal/fakeserver"
+	"rag-cli/internal/httpclient"
+	"rag-cli/internal/llm"
+	"rag-cli/pkg/config"
+)
+
+func TestValidateModel(t *testing.T) {
+	tests := []struct {
+		name      string
+		lister    *fakeModelLister
+		model     string
+		wantError string
+	}{
+		{name: "available", lister: &fakeModelLister{models: []string{"llama3.1:8b", "mistral:latest"}}, model: "llama3.1:8b"},
+		{name: "implicit latest tag", lister: &fakeModelLister{models: []string{"mistral:latest"}}, model: "mistral"},
+		{name: "typo lists available models", lister: &fakeModelLister{models: []string{"llama3.1:8b", "mistral:latest"}}, model: "lama3.1:8b", wantError: "available models: llama3.1:8b, mistral:latest"},
+		{name: "no models installed", lister: &fakeModelLister{}, model: "mistral", wantError: "ollama pull mistral"},
+		{name: "Ollama unreachable", lister: &fakeModelLister{err: errors.New("connection refused")}, model: "mistral"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateModel(tt.lister, tt.model)
+			if tt.wantError == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantError) {
+				t.Errorf("Expected error containing %q, got: %v", tt.wantError, err)
+			}
+		})
+	}
+}
+
+func TestRunPromptWithTimeout(t *testing.T) {
+	// slowPrompt stands in for a session whose LLM and commands take far longer
+	// than the deadline but stop when the context is cancelled
+	slowPrompt := func(ctx context.Context) error {
+		select {
+		case <-time.After(5 * time.Second):
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	t.Run("deadline stops the run with the timeout exit code", func(t *testing.T) {
+		var out bytes.Buffer
+		start := time.Now()
+
+		err := runPromptWithTimeout(context.Background(), &out, 50*time.Millisecond, slowPrompt)
+
+		var exitErr *ExitCodeError
+		if !errors.As(err, &exitErr) || exitErr.Code != timeoutExitCode {
+			t.Fatalf("Expected exit code %d, got: %v", timeoutExitCode, err)
+		}
+		if elapsed := time.Since(start); elapsed > 2*time.Second {
+			t.Errorf("Expected the run to stop at the deadline, took %s", elapsed)
+		}
+		if !strings.Contains(out.String(), "Timed out after 50ms") {
+			t.Errorf("Expected timeout message, got: %q", out.String())
+		}
+	})
+
+	t.Run("interrupt stops the run with the interrupt exit code", func(t *testing.T) {
+		var out bytes.Buffer
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(50*time.Millisecond, cancel)
+		start := time.Now()
+
+		err := runPromptWithTimeout(ctx, &out, time.Minute, slowPrompt)
+
+		var exitErr *ExitCodeError
+		if !errors.As(err, &exitErr) || exitErr.Code != interruptExitCode {
+			t.Fatalf("Expected exit code %d, got: %v", interruptExitCode, err)
+		}
+		if elapsed := time.Since(start); elapsed > 2*time.Second {
+			t.Errorf("Expected the run to stop when interrupted, took %s", elapsed)
+		}
+		if !strings.Contains(out.String(), "Interrupted") {
+			t.Errorf("Expected interrupt message, got: %q", out.String())
+		}
+	})
+
+	t.Run("no timeout runs without a deadline", func(t *testing.T) {
+		err := runPromptWithTimeout(context.Background(), &bytes.Buffer{}, 0, func(ctx context.Context) error {
+			if _, ok := ctx.Deadline(); ok {
+				t.Error("Expected no deadline")
+			}
+			return nil
+		})
+		if err != nil {
+			t.Errorf("Expected no error, got: %v", err)
+		}
+	})
+
+	t.Run("other errors pass through", func(t *testing.T) {
+		want := errors.New("error generating response: connection refused")
+		err := runPromptWithTimeout(context.Background(), &bytes.Buffer{}, time.Minute, func(ctx context.Context) error { return want })
+		if err != want {
+			t.Errorf("Expected %v, got: %v", want, err)
+		}
+	})
+}
+
+func TestPromptExit(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		wantCode int
+	}{
+		{"goal not achieved", fmt.Errorf("%w: max attempts (3) reached", chat.ErrGoalNotAchieved), goalNotAchievedExitCode},
+		{"command failed", fmt.Errorf("%w: %w", chat.ErrGoalNotAchieved, chat.ErrCommandFailed), goalNotAchievedExitCode},
+		{"command denied", fmt.Errorf("%w: rm -rf build", chat.ErrCommandDenied), commandDeniedExitCode},
+		{"model missing", fmt.Errorf("%w: %w", chat.ErrGenerationFailed, llm.ErrModelNotFound), generationFailedExitCode},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := runPromptWithTimeout(context.Background(), &out, time.Minute, func(ctx context.Context) error { return tt.err })
+
+			var exitErr *ExitCodeError
+			if !errors.As(err, &exitErr) || exitErr.Code != tt.wantCode {
+				t.Fatalf("Expected exit code %d, got: %v", tt.wantCode, err)
+			}
+			if !strings.Contains(out.String(), "Error: "+tt.err.Error()) {
+				t.Errorf("Expected the error to be printed, got: %q", out.String())
+			}
+		})
+	}
+
+	if err := promptExit(&bytes.Buffer{}, nil); err != nil {
+		t.Errorf("Expected no error for an achieved goal, got: %v", err)
+	}
+}
+
+func TestWritePromptJSON(t *testing.T) {
+	result := &chat.PromptResult{
+		Prompt:   "count the Go files",
+		Answer:   "There are 3 Go files",
+		Commands: []chat.CommandResult{{Command: "ls *.go | wc -l", Stdout: "3\n"}},
+		Attempts: 1,
+		Achieved: true,
+	}
+
+	var out bytes.Buffer
+	if err := writePromptJSON(&out, result); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatalf("Expected a single JSON object, got %q: %v", out.String(), err)
+	}
+	for _, key := range []string{"prompt", "answer", "commands", "attempts", "achieved"} {
+		if _, ok := decoded[key]; !ok {
+			t.Errorf("Expected key %q in %s", key, out.String())
+		}
+	}
+	command := decoded["commands"].([]interface{})[0].(map[string]interface{})
+	if command["exit_code"] != float64(0) || command["stdout"] != "3\n" || command["stderr"] != "" {
+		t.Errorf("Expected the command's exit code and streams, got %v", command)
+	}
+}
+
+func TestResolveAllowCommands(t *testing.T) {
+	tests := []struct {
+		name         string
+		noExec       bool
+		allow        bool
+		configured   bool
+		source       config.Source
+		singlePrompt bool
+		expected     bool
+	}{
+		{name: "interactive runs commands by default", configured: true, source: config.SourceDefault, expected: true},
+		{name: "prompt only prints commands by default", configured: true, source: config.SourceDefault, singlePrompt: true, expected: false},
+		{name: "configured true applies to prompt", configured: true, source: config.SourceFile, singlePrompt: true, expected: true},
+		{name: "configured false applies to interactive", configured: false, source: config.SourceEnv, expected: false},
+		{name: "allow-commands overrides config", allow: true, configured: false, source: config.SourceFile, singlePrompt: true, expected: true},
+		{name: "no-exec overrides config", noExec: true, configured: true, source: config.SourceFile, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allowed, err := resolveAllowCommands(tt.noExec, tt.allow, tt.configured, tt.source, tt.singlePrompt)
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if allowed != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, allowed)
+			}
+		})
+	}
+
+	t.Run("conflicting flags", func(t *testing.T) {
+		if _, err := resolveAllowCommands(true, true, true, config.SourceDefault, false); err == nil {
+			t.Error("Expected an error for --no-exec with --allow-commands")
+		}
+	})
+}
+
+func TestRequireLLM(t *testing.T) {
+	if err := requireLLM(&fakeModelLister{models: []string{"llama3.1:8b"}}, "http://localhost:11434"); err != nil {
+		t.Errorf("Expected no error with the LLM up, got: %v", err)
+	}
+	if err := requireLLM(&fakeModelLister{err: errors.New("unexpected status code: 500")}, "http://localhost:11434"); err != nil {
+		t.Errorf("Expected other failures to be left for the first prompt, got: %v", err)
+	}
+
+	err := requireLLM(&fakeModelLister{err: fmt.Errorf("failed to make request: %w", httpclient.ErrUnreachable)}, "http://localhost:11434")
+	if err == nil || !strings.Contains(err.Error(), "not reachable at http://localhost:11434") || !strings.Contains(err.Error(), "index, search, and doctor still work") {
+		t.Errorf("Expected chat to be refused with a hint, got: %v", err)
+	}
+}
+
+func TestConnectVectorStore(t *testing.T) {
+	vectorConfig := func(url string) *config.Config {
+		return &config.Config{Vector: config.VectorConfig{BaseURL: url, Collection: "documents", CommandCollection: "command_history", AutoIndexCollection: "auto_indexed"}}
+	}
+
+	t.Run("reachable", func(t *testing.T) {
+		server := fakeserver.NewChroma(t)
+		var out bytes.Buffer
+		store, unavailable, err := connectVectorStore(&out, vectorConfig(server.URL))
+		if err != nil || store == nil || unavailable {
+			t.Fatalf("Expected a connected store, got %v, %v, %v", store, unavailable, err)
+		}
+		if out.Len() != 0 {
+			t.Errorf("Expected no warning, got: %s", out.String())
+		}
+		if len(server.Collections()) != 3 {
+			t.Errorf("Expected the collections to be created at startup, got %v", server.Collections())
+		}
+	})
+
+	t.Run("down", func(t *testing.T) {
+		server := fakeserver.NewChroma(t)
+		server.Close()
+		var out bytes.Buffer
+		store, unavailable, err := connectVectorStore(&out, vectorConfig(server.URL))
+		if err != nil || store == nil || !unavailable {
+			t.Fatalf("Expected a store to retry later, got %v, %v, %v", store, unavailable, err)
+		}
+		if strings.Count(out.String(), "Warning:") != 1 || !strings.Contains(out.String(), "Continuing without documents or command history") {
+			t.Errorf("Expected a single warning, got: %s", out.String())
+		}
+	})
+}
@@ -0,0 +1,210 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"rag-cli/internal/embeddings"
+	"rag-cli/internal/vector"
+	"rag-cli/pkg/config"
+)
+
+var (
+	searchCollection string
+	searchTopK       int
+	searchJSON       bool
+)
+
+// searchSnippetLength is the number of characters of each document shown in plain output
+const searchSnippetLength = 200
+
+var searchCmd = &cobra.Command{
+	Use:   "search <query>",
+	Short: "Query the vector store directly without the LLM",
+	Long: `Embed a query and run a raw semantic search against the vector store, printing
+the ranked matches with their distances, source metadata, and a snippet of each document.
+
+This bypasses the language model entirely, which makes it useful for checking what
+indexing produced and why a document does or does not show up as chat context.
+Lower distances mean closer matches. When vector.max_distance is set, matches
+farther than it are left out, as they are from chat context.
+
+Collections:
+  documents  - Documents added with 'rag-cli index' (default)
+  commands   - Stored command execution sessions
+  auto       - Files picked up by auto-indexing
+  <name>     - Any other existing collection, such as one created with 'rag-cli index --collection'
+
+EXAMPLES:
+  # Search indexed documents
+  rag-cli search "how do I configure the chunk size"
+
+  # Show the ten closest command sessions
+  rag-cli search --collection commands --top-k 10 "git rebase"
+
+  # Search a one-off corpus indexed into its own collection
+  rag-cli search --collection project-x "release process"
+
+  # Emit full documents and metadata as JSON
+  rag-cli search --json "deployment checklist" | jq '.[0].document'`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if searchTopK <= 0 {
+			return fmt.Errorf("--top-k must be greater than 0")
+		}
+
+		cfg, err := config.Load()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		embeddingClient, err := embeddings.NewClient(cfg.Embeddings, cfg.Timeouts)
+		if err != nil {
+			return fmt.Errorf("failed to initialize embedding client: %w", err)
+		}
+
+		vectorStore, err := vector.NewChromaClient(cfg.Vector, cfg.Timeouts)
+		if err != nil {
+			return fmt.Errorf("failed to initialize vector store: %w", err)
+		}
+
+		return runSearch(os.Stdout, embeddingClient, vectorStore, args[0], searchCollection, searchTopK, searchJSON)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(searchCmd)
+
+	searchCmd.Flags().StringVarP(&searchCollection, "collection", "c", "documents", "Collection to search: documents, commands, auto, or an existing collection name")
+	searchCmd.Flags().IntVarP(&searchTopK, "top-k", "k", 5, "Number of results to return")
+	searchCmd.Flags().BoolVar(&searchJSON, "json", false, "Output full results as JSON for scripting")
+}
+
+// searchOutput is the JSON representation of a single search hit
+type searchOutput struct {
+	Rank       int                    `json:"rank"`
+	ID         string                 `json:"id"`
+	Distance   float32                `json:"distance"`
+	Collection string                 `json:"collection"`
+	Document   string                 `json:"document"`
+	Metadata   map[string]interface{} `json:"metadata,omitempty"`
+}
+
+func runSearch(out io.Writer, embedder embeddings.Embedder, store vector.VectorStore, query, collection string, topK int, asJSON bool) error {
+	collectionName, err := resolveCollection(store, collection)
+	if err != nil {
+		return err
+	}
+
+	queryEmbedding, err := embedder.GenerateEmbedding(query)
+	if err != nil {
+		return fmt.Errorf("failed to generate query embedding: %w", err)
+	}
+
+	results, err := store.SearchWithScores(collectionName, queryEmbedding, topK)
+	if err != nil {
+		return fmt.Errorf("failed to search collection %s: %w", collectionName, err)
+	}
+
+	if asJSON {
+		output := make([]searchOutput, 0, len(results))
+		for i, result := range results {
+			output = append(output, searchOutput{
+				Rank:       i + 1,
+				ID:         result.ID,
+				Distance:   result.Distance,
+				Collection: collectionName,
+				Document:   result.Document,
+				Metadata:   result.Metadata,
+			})
+		}
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(output)
+	}
+
+	if len(results) == 0 {
+		fmt.Fprintf(out, "No results found in collection %s\n", collectionName)
+		return nil
+	}
+
+	fmt.Fprintf(out, "Top %d result(s) from %s for %q:\n\n", len(results), collectionName, query)
+	for i, result := range results {
+		fmt.Fprintf(out, "%d. [distance %.4f] %s\n", i+1, result.Distance, formatSource(result))
+		fmt.Fprintf(out, "   %s\n\n", snippet(result.Document, searchSnippetLength))
+	}
+	return nil
+}
+
+// resolveCollection maps a --collection value to a collection name. The
+// documents, commands, and auto aliases map to the configured collections;
+// any other value must name an existing collection.
+func resolveCollection(store vector.VectorStore, collection string) (string, error) {
+	if name, ok := collectionAlias(store, collection); ok {
+		return name, nil
+	}
+
+	collections, err := store.ListCollections()
+	if err != nil {
+		return "", fmt.Errorf("failed to list collections: %w", err)
+	}
+	for _, info := range collections {
+		if info.Name == collection {
+			return collection, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %q (expected documents, commands, auto, or the name of an existing collection)", vector.ErrCollectionNotFound, collection)
+}
+
+// collectionAlias maps the documents, commands, and auto aliases, or the
+// configured collection names themselves, to the configured collections
+func collectionAlias(store vector.VectorStore, collection string) (string, bool) {
+	switch collection {
+	case "documents", "docs", store.DocumentsCollection():
+		return store.DocumentsCollection(), true
+	case "commands", store.CommandsCollection():
+		return store.CommandsCollection(), true
+	case "auto", store.AutoIndexCollection():
+		return store.AutoIndexCollection(), true
+	}
+	return "", false
+}
+
+// formatSource describes where a result came from, preferring the indexed file path
+func formatSource(result vector.SearchResult) string {
+	for _, key := range []string{"source_path", "source", "path"} {
+		if value, ok := result.Metadata[key]; ok {
+			return fmt.Sprintf("%v (id: %s)", value, result.ID)
+		}
+	}
+
+	if len(result.Metadata) == 0 {
+		return fmt.Sprintf("id: %s", result.ID)
+	}
+
+	keys := make([]string, 0, len(result.Metadata))
+	for key := range result.Metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, fmt.Sprintf("%s=%v", key, result.Metadata[key]))
+	}
+	return fmt.Sprintf("id: %s, %s", result.ID, strings.Join(pairs, ", "))
+}
+
+// snippet collapses whitespace and truncates text to at most maxLen runes
+func snippet(text string, maxLen int) string {
+	collapsed := strings.Join(strings.Fields(text), " ")
+	runes := []rune(collapsed)
+	if len(runes) <= maxLen {
+		return collapsed
+	}
+	return string(runes[:maxLen]) + "..."
+}
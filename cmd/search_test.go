@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"rag-cli/internal/vector"
+)
+
+func newSearchFixture() *fakeStore {
+	store := newFakeStore()
+	store.results["documents"] = []vector.SearchResult{
+		{ID: "doc-1", Document: "Chunk size controls\nhow large each piece is.", Distance: 0.12, Metadata: map[string]interface{}{"source_path": "docs/config.md", "chunk_index": 0}},
+		{ID: "doc-2", Document: strings.Repeat("overlap ", 60), Distance: 0.45},
+		{ID: "doc-3", Document: "unrelated", Distance: 1.7, Metadata: map[string]interface{}{"kind": "note"}},
+	}
+	store.results["command_history"] = []vector.SearchResult{
+		{ID: "cmd_session_1", Document: "$ git status", Distance: 0.3},
+	}
+	store.results["auto_indexed"] = nil
+	return store
+}
+
+func TestRunSearch_PlainOutput(t *testing.T) {
+	store := newSearchFixture()
+	embedder := &fakeEmbedder{}
+	var out bytes.Buffer
+
+	if err := runSearch(&out, embedder, store, "chunk size", "documents", 5, false); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	output := out.String()
+	expected := []string{
+		`Top 3 result(s) from documents for "chunk size"`,
+		"1. [distance 0.1200] docs/config.md (id: doc-1)",
+		"Chunk size controls how large each piece is.",
+		"2. [distance 0.4500] id: doc-2",
+		"3. [distance 1.7000] id: doc-3, kind=note",
+	}
+	for _, want := range expected {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, output)
+		}
+	}
+	if !strings.Contains(output, "...") {
+		t.Errorf("Expected long document to be truncated, got:\n%s", output)
+	}
+	if len(embedder.texts) != 1 || embedder.texts[0] != "chunk size" {
+		t.Errorf("Expected the query to be embedded once, got: %v", embedder.texts)
+	}
+}
+
+func TestRunSearch_Flags(t *testing.T) {
+	tests := []struct {
+		name               string
+		collection         string
+		topK               int
+		expectedCollection string
+	}{
+		{"documents alias", "documents", 2, "documents"},
+		{"commands alias", "commands", 5, "command_history"},
+		{"auto alias", "auto", 1, "auto_indexed"},
+		{"configured name", "command_history", 7, "command_history"},
+		{"existing collection", "project-x", 3, "project-x"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newSearchFixture()
+			store.collections = []vector.CollectionInfo{{Name: "project-x", ID: "px"}}
+			store.results["project-x"] = nil
+			var out bytes.Buffer
+			if err := runSearch(&out, &fakeEmbedder{}, store, "q", tt.collection, tt.topK, false); err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if store.searchedCollection != tt.expectedCollection {
+				t.Errorf("Expected search in %s, got %s", tt.expectedCollection, store.searchedCollection)
+			}
+			if store.searchedTopK != tt.topK {
+				t.Errorf("Expected top-k %d, got %d", tt.topK, store.searchedTopK)
+			}
+		})
+	}
+
+	t.Run("unknown collection", func(t *testing.T) {
+		var out bytes.Buffer
+		err := runSearch(&out, &fakeEmbedder{}, newSearchFixture(), "q", "bogus", 5, false)
+		if !errors.Is(err, vector.ErrCollectionNotFound) {
+			t.Errorf("Expected unknown collection error, got: %v", err)
+		}
+	})
+}
+
+func TestRunSearch_JSONOutput(t *testing.T) {
+	var out bytes.Buffer
+	if err := runSearch(&out, &fakeEmbedder{}, newSearchFixture(), "q", "documents", 2, true); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	var results []searchOutput
+	if err := json.Unmarshal(out.Bytes(), &results); err != nil {
+		t.Fatalf("Expected valid JSON, got error %v for:\n%s", err, out.String())
+	}
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results, got %d", len(results))
+	}
+	if results[0].Rank != 1 || results[0].ID != "doc-1" || results[0].Metadata["source_path"] != "docs/config.md" {
+		t.Errorf("Unexpected first result: %+v", results[0])
+	}
+	if results[1].Document != strings.Repeat("overlap ", 60) {
+		t.Errorf("Expected full untruncated document in JSON output")
+	}
+}
+
+func TestRunSearch_EmptyAndErrors(t *testing.T) {
+	var out bytes.Buffer
+	if err := runSearch(&out, &fakeEmbedder{}, newSearchFixture(), "q", "auto", 5, false); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !strings.Contains(out.String(), "No results found in collection auto_indexed") {
+		t.Errorf("Expected empty result message, got: %s", out.String())
+	}
+
+	out.Reset()
+	if err := runSearch(&out, &fakeEmbedder{}, newSearchFixture(), "q", "auto", 5, true); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if strings.TrimSpace(out.String()) != "[]" {
+		t.Errorf("Expected empty JSON array, got: %s", out.String())
+	}
+
+	err := runSearch(&out, &fakeEmbedder{err: errors.New("ollama down")}, newSearchFixture(), "q", "documents", 5, false)
+	if err == nil || !strings.Contains(err.Error(), "ollama down") {
+		t.Errorf("Expected embedding error to be returned, got: %v", err)
+	}
+}
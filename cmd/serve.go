@@ -0,0 +1,549 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"rag-cli/internal/chat"
+	"rag-cli/internal/chunker"
+	"rag-cli/internal/embeddings"
+	"rag-cli/internal/indexing"
+	"rag-cli/internal/llm"
+	"rag-cli/internal/vector"
+	"rag-cli/pkg/config"
+	"rag-cli/pkg/version"
+)
+
+var (
+	serveHost          string
+	servePort          int
+	serveAllowCommands bool
+)
+
+const (
+	// serveMaxBodyBytes caps the size of a request body, which bounds raw text sent to /index
+	serveMaxBodyBytes = 10 << 20
+
+	// serveShutdownTimeout is how long in-flight requests get to finish after a shutdown signal
+	serveShutdownTimeout = 10 * time.Second
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve a JSON HTTP API for editors and scripts",
+	Long: `Start an HTTP server exposing rag-cli's retrieval and indexing over a small JSON API,
+so editors and scripts can use it without shelling out.
+
+Endpoints:
+  POST /ask      {"question": "...", "top_k": 5, "include_history": false}
+                 Answers a question from retrieved context
+  POST /search   {"query": "...", "collection": "documents", "top_k": 5}
+                 Raw semantic search, same results as 'rag-cli search --json'
+  POST /index    {"path": "./docs", "recursive": true} or {"text": "...", "source": "notes"}
+                 Indexes files under a path on the server, or raw text
+  GET  /healthz  Reports that the server is up and its version
+
+Errors are returned as {"error": "..."} with a 4xx or 5xx status. Each request is
+logged to stderr. SIGINT or SIGTERM stops the server after in-flight requests finish.
+
+Command execution is disabled by default, so /ask only ever answers. With
+--allow-commands, an /ask request may set "execute": true to have the model propose
+shell commands that are then run on the server. There is nobody to confirm them, so
+they are auto-approved exactly as with 'rag-cli --auto-approve': every proposed
+command runs immediately as the user running the server, except dangerous ones
+such as sudo or recursive deletes outside the working directory, which need a
+confirmation the API cannot give and are refused. A cd or export carries over
+between the commands of one request but never into another request. Only enable
+this on a loopback address you trust.
+
+EXAMPLES:
+  # Serve on the default address (127.0.0.1:8765)
+  rag-cli serve
+
+  # Ask a question
+  curl -s localhost:8765/ask -d '{"question": "how do I configure chunking?"}'
+
+  # Index a directory and a note
+  curl -s localhost:8765/index -d '{"path": "./docs", "recursive": true}'
+  curl -s localhost:8765/index -d '{"text": "deploys run on Fridays", "source": "team notes"}'
+
+  # Allow /ask to run commands (auto-approved, use with caution)
+  rag-cli serve --allow-commands`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		llmClient, err := newLLMClient(cfg)
+		if err != nil {
+			return err
+		}
+		if err := checkModelOverride(llmClient, cfg.LLM.Model); err != nil {
+			return err
+		}
+
+		embeddingClient, err := embeddings.NewClient(cfg.Embeddings, cfg.Timeouts)
+		if err != nil {
+			return fmt.Errorf("failed to initialize embedding client: %w", err)
+		}
+
+		vectorStore, err := vector.NewChromaClient(cfg.Vector, cfg.Timeouts)
+		if err != nil {
+			return fmt.Errorf("failed to initialize vector store: %w", err)
+		}
+
+		excludes, err := indexing.NewExcludeMatcher(cfg.Index.ExcludePatterns)
+		if err != nil {
+			return err
+		}
+
+		server := &apiServer{
+			generator: llmClient,
+			embedder:  embeddingClient,
+			store:     vectorStore,
+			chunker:   chunker.New(cfg.Chunker),
+			excludes:  excludes,
+			workers:   resolveWorkers(0, cfg.Index.Workers),
+			logger:    log.New(os.Stderr, "", log.LstdFlags),
+
+			contextBudget: contextBudget(cfg.Chat, nil),
+		}
+		if serveAllowCommands {
+			// Checked once here so that a bad policy stops the server starting
+			if _, err := safetyPolicy(cfg); err != nil {
+				return err
+			}
+			server.newExecutor = newRequestExecutor(cfg)
+		}
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		return runServe(ctx, net.JoinHostPort(serveHost, strconv.Itoa(servePort)), server)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+
+	serveCmd.Flags().StringVar(&serveHost, "host", "127.0.0.1", "Address to listen on")
+	serveCmd.Flags().IntVarP(&servePort, "port", "P", 8765, "Port to listen on")
+	serveCmd.Flags().BoolVar(&serveAllowCommands, "allow-commands", false, "Let /ask requests with \"execute\": true run the commands the model proposes. Commands are auto-approved - USE WITH CAUTION")
+}
+
+// newRequestExecutor returns an apiServer.newExecutor that starts a command
+// executor for each request under the configured safety policy and timeout
+func newRequestExecutor(cfg *config.Config) func() (commandExecutor, *chat.SafetyChecker, error) {
+	return func() (commandExecutor, *chat.SafetyChecker, error) {
+		safety, err := safetyPolicy(cfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		executor := chat.NewCommandExecutor(safety)
+		executor.UseTimeout(cfg.Chat.CommandTimeout)
+		return executor, safety, nil
+	}
+}
+
+// runServe listens on addr until ctx is cancelled, then shuts down gracefully
+func runServe(ctx context.Context, addr string, server *apiServer) error {
+	httpServer := &http.Server{
+		Addr:              addr,
+		Handler:           server.handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- httpServer.ListenAndServe()
+	}()
+
+	server.logger.Printf("Listening on http://%s", addr)
+	if server.newExecutor != nil {
+		server.logger.Printf("Command execution is enabled: /ask requests with \"execute\": true run commands without confirmation")
+	}
+
+	select {
+	case err := <-errCh:
+		return fmt.Errorf("server failed: %w", err)
+	case <-ctx.Done():
+	}
+
+	server.logger.Printf("Shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), serveShutdownTimeout)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to shut down server: %w", err)
+	}
+	return nil
+}
+
+// serveGenerator is the LLM used by the server. GenerateResponse is only
+// called when command execution is enabled.
+type serveGenerator interface {
+	answerGenerator
+	GenerateResponse(query string, context []string) (string, error)
+}
+
+// promptSizer measures the prompt GenerateResponse would send, so retrieved
+// context can be trimmed to fit
+type promptSizer interface {
+	PromptSize(query string, contextDocs []string, history []llm.Turn) int
+}
+
+// commandExecutor runs a shell command and returns what it wrote
+type commandExecutor interface {
+	Execute(command string) (chat.ExecutionResult, error)
+}
+
+// apiServer holds the dependencies of the HTTP API handlers
+type apiServer struct {
+	generator serveGenerator
+	embedder  embeddings.Embedder
+	store     vector.VectorStore
+	chunker   *chunker.Client
+	excludes  *indexing.ExcludeMatcher
+	workers   int
+	logger    *log.Logger
+
+	// contextBudget trims the context of each /ask request, as in chat
+	contextBudget chat.ContextBudget
+
+	// newExecutor starts the executor that runs the commands proposed for
+	// one /ask request, with the checker that finds dangerous ones to refuse
+	// (nil for the built-in rules). Each request gets its own, so that a cd
+	// or export in one never carries into another. nil disables execution.
+	newExecutor func() (commandExecutor, *chat.SafetyChecker, error)
+}
+
+// handler returns the API routes wrapped in request logging
+func (s *apiServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /ask", s.handleAsk)
+	mux.HandleFunc("POST /search", s.handleSearch)
+	mux.HandleFunc("POST /index", s.handleIndex)
+	mux.HandleFunc("GET /healthz", s.handleHealth)
+	return s.logRequests(mux)
+}
+
+// statusRecorder captures the status code written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// logRequests logs the method, path, status, and duration of every request
+func (s *apiServer) logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+		s.logger.Printf("%s %s %d %s", r.Method, r.URL.Path, recorder.status, time.Since(start).Round(time.Millisecond))
+	})
+}
+
+// requestError is an error caused by the request itself rather than by a
+// backend service, reported to clients as a 4xx status
+type requestError struct {
+	status  int
+	message string
+}
+
+func (e *requestError) Error() string { return e.message }
+
+func badRequest(format string, args ...interface{}) error {
+	return &requestError{status: http.StatusBadRequest, message: fmt.Sprintf(format, args...)}
+}
+
+// askRequest is the body of POST /ask
+type askRequest struct {
+	Question       string `json:"question"`
+	TopK           int    `json:"top_k"`
+	IncludeHistory bool   `json:"include_history"`
+	Execute        bool   `json:"execute"`
+}
+
+// commandResult is the outcome of a command run for an /ask request
+type commandResult struct {
+	Command string `json:"command"`
+	Output  string `json:"output"` // What the command wrote to stdout
+	Stderr  string `json:"stderr,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// askResponse is the body returned by POST /ask
+type askResponse struct {
+	Question string          `json:"question"`
+	Answer   string          `json:"answer"`
+	Context  []string        `json:"context"`
+	Commands []commandResult `json:"commands,omitempty"`
+}
+
+// ask answers a question from retrieved context, running the commands the
+// model proposes when execution is requested and enabled
+func (s *apiServer) ask(ctx context.Context, req askRequest) (*askResponse, error) {
+	if req.Question == "" {
+		return nil, badRequest("question is required")
+	}
+	if req.Execute && s.newExecutor == nil {
+		return nil, &requestError{status: http.StatusForbidden, message: "command execution is disabled; start the server with --allow-commands"}
+	}
+	if req.TopK <= 0 {
+		req.TopK = 5
+	}
+
+	budget := s.contextBudget
+	budget.PromptChars = answerPromptChars
+	if sizer, ok := s.generator.(promptSizer); ok && req.Execute {
+		budget.PromptChars = func(prompt string) int {
+			return sizer.PromptSize(prompt, nil, nil)
+		}
+	}
+	contextManager := chat.NewContextManager(s.embedder, s.store)
+	contextManager.UseBudget(budget)
+	items, err := contextManager.GetCombinedContext(ctx, req.Question, req.IncludeHistory, req.TopK, req.TopK)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve context: %w", err)
+	}
+	context := chat.ContextTexts(items)
+
+	resp := &askResponse{Question: req.Question, Context: context}
+	if !req.Execute {
+		resp.Answer, err = s.generator.GenerateAnswer(req.Question, context)
+		if err != nil {
+			return nil, fmt.Errorf("error generating answer: %w", err)
+		}
+		return resp, nil
+	}
+
+	resp.Answer, err = s.generator.GenerateResponse(req.Question, context)
+	if err != nil {
+		return nil, fmt.Errorf("error generating response: %w", err)
+	}
+	executor, safety, err := s.newExecutor()
+	if err != nil {
+		return nil, err
+	}
+	if safety == nil {
+		safety = chat.NewSafetyChecker()
+	}
+	// Commands run in order and stop at the first failure, as in a chat session
+	for _, command := range chat.NewCommandValidator().ParseCommands(resp.Answer) {
+		var output chat.ExecutionResult
+		if verdict := safety.Classify(command); verdict.Action == chat.SafetyConfirm {
+			// Nobody is there to confirm a dangerous command, so it is refused
+			err = &chat.BlockedCommandError{Command: command, Reason: verdict.Reason + ", and needs confirming"}
+		} else {
+			s.logger.Printf("Auto-approving command: %s", command)
+			output, err = executor.Execute(command)
+		}
+		result := commandResult{Command: command, Output: output.Stdout, Stderr: output.Stderr}
+		if err != nil {
+			result.Error = err.Error()
+		}
+		resp.Commands = append(resp.Commands, result)
+		if err != nil {
+			break
+		}
+	}
+	return resp, nil
+}
+
+// searchRequest is the body of POST /search
+type searchRequest struct {
+	Query      string `json:"query"`
+	Collection string `json:"collection"`
+	TopK       int    `json:"top_k"`
+}
+
+// search runs a raw semantic search against a collection
+func (s *apiServer) search(req searchRequest) ([]searchOutput, error) {
+	if req.Query == "" {
+		return nil, badRequest("query is required")
+	}
+	if req.Collection == "" {
+		req.Collection = "documents"
+	}
+	if req.TopK <= 0 {
+		req.TopK = 5
+	}
+
+	collectionName, err := resolveCollection(s.store, req.Collection)
+	if err != nil {
+		return nil, badRequest("%v", err)
+	}
+
+	queryEmbedding, err := s.embedder.GenerateEmbedding(req.Query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate query embedding: %w", err)
+	}
+
+	results, err := s.store.SearchWithScores(collectionName, queryEmbedding, req.TopK)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search collection %s: %w", collectionName, err)
+	}
+
+	output := make([]searchOutput, 0, len(results))
+	for i, result := range results {
+		output = append(output, searchOutput{
+			Rank:       i + 1,
+			ID:         result.ID,
+			Distance:   result.Distance,
+			Collection: collectionName,
+			Document:   result.Document,
+			Metadata:   result.Metadata,
+		})
+	}
+	return output, nil
+}
+
+// indexRequest is the body of POST /index. Exactly one of Path and Text is set.
+type indexRequest struct {
+	Path      string `json:"path"`
+	Recursive bool   `json:"recursive"`
+	Text      string `json:"text"`
+	Source    string `json:"source"`
+}
+
+// indexFailureOutput reports a source that could not be indexed
+type indexFailureOutput struct {
+	Source string `json:"source"`
+	Error  string `json:"error"`
+}
+
+// indexResponse is the body returned by POST /index
+type indexResponse struct {
+	Sources  int                  `json:"sources"`
+	Chunks   int                  `json:"chunks"`
+	Skipped  int                  `json:"skipped"`
+	Failures []indexFailureOutput `json:"failures,omitempty"`
+}
+
+// index stores raw text, or the files under a path, in the documents collection
+func (s *apiServer) index(req indexRequest) (*indexResponse, error) {
+	if (req.Path == "") == (req.Text == "") {
+		return nil, badRequest("exactly one of path or text is required")
+	}
+
+	if req.Text != "" {
+		var metadata map[string]interface{}
+		if req.Source != "" {
+			metadata = map[string]interface{}{"source": req.Source}
+		}
+		chunks, err := storeChunks(req.Text, metadata, s.chunker, s.embedder, s.store)
+		if err != nil {
+			return nil, err
+		}
+		return &indexResponse{Sources: 1, Chunks: chunks}, nil
+	}
+
+	files, skipped, err := getFilesToIndex(req.Path, defaultIndexFormats, req.Recursive, s.excludes)
+	if err != nil {
+		return nil, badRequest("failed to get files to index: %v", err)
+	}
+
+	result := indexFiles(context.Background(), io.Discard, files, s.workers, func(file string) (int, error) {
+		return processFile(file, s.chunker, s.embedder, s.store)
+	})
+	resp := &indexResponse{Sources: result.files, Chunks: result.chunks, Skipped: skipped + result.unsupported + result.binary}
+	for _, failure := range result.failures {
+		resp.Failures = append(resp.Failures, indexFailureOutput{Source: failure.file, Error: failure.err.Error()})
+	}
+	if resp.Sources > 0 {
+		recordIndexRun(describeIndexSource(req.Path, nil), s.store.DocumentsCollection(), resp.Sources, resp.Chunks)
+	}
+	return resp, nil
+}
+
+func (s *apiServer) handleAsk(w http.ResponseWriter, r *http.Request) {
+	var req askRequest
+	if decodeRequest(w, r, &req) {
+		resp, err := s.ask(r.Context(), req)
+		writeResult(w, resp, err)
+	}
+}
+
+func (s *apiServer) handleSearch(w http.ResponseWriter, r *http.Request) {
+	var req searchRequest
+	if decodeRequest(w, r, &req) {
+		resp, err := s.search(req)
+		writeResult(w, resp, err)
+	}
+}
+
+func (s *apiServer) handleIndex(w http.ResponseWriter, r *http.Request) {
+	var req indexRequest
+	if decodeRequest(w, r, &req) {
+		resp, err := s.index(req)
+		writeResult(w, resp, err)
+	}
+}
+
+func (s *apiServer) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{
+		"status":  "ok",
+		"version": version.Version,
+	})
+}
+
+// decodeRequest decodes a JSON request body into v, writing a 400 response
+// and returning false when the body is not valid
+func decodeRequest(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, serveMaxBodyBytes))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, "request body too large")
+		} else {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+		}
+		return false
+	}
+	return true
+}
+
+// writeJSON writes v as a JSON response with the given status
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeResult writes a successful result, or maps err to an error response:
+// request errors keep their status and anything else is a backend failure
+func writeResult(w http.ResponseWriter, result interface{}, err error) {
+	if err == nil {
+		writeJSON(w, http.StatusOK, result)
+		return
+	}
+	var reqErr *requestError
+	if errors.As(err, &reqErr) {
+		writeError(w, reqErr.status, reqErr.message)
+		return
+	}
+	writeError(w, http.StatusBadGateway, err.Error())
+}
+
+// writeError writes a JSON error response
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,342 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"rag-cli/internal/chat"
+	"rag-cli/internal/chunker"
+	"rag-cli/pkg/config"
+)
+
+// fakeExecutor records the commands it is asked to run
+type fakeExecutor struct {
+	output   string
+	failOn   string
+	commands []string
+}
+
+func (f *fakeExecutor) Execute(command string) (chat.ExecutionResult, error) {
+	f.commands = append(f.commands, command)
+	if command == f.failOn {
+		return chat.ExecutionResult{Command: command, Stdout: "boom"}, errors.New("command failed: exit status 1")
+	}
+	return chat.ExecutionResult{Command: command, Stdout: f.output}, nil
+}
+
+// executorFactory returns an apiServer.newExecutor that always hands out
+// executor, with the built-in safety rules
+func executorFactory(executor commandExecutor) func() (commandExecutor, *chat.SafetyChecker, error) {
+	return func() (commandExecutor, *chat.SafetyChecker, error) {
+		return executor, nil, nil
+	}
+}
+
+// newServeFixture returns a server backed by fakes and a buffer holding its request log
+func newServeFixture(t *testing.T, generator *fakeLLM) (*apiServer, *fakeStore, *bytes.Buffer) {
+	t.Setenv("HOME", t.TempDir())
+	store := newSearchFixture()
+	var logs bytes.Buffer
+	return &apiServer{
+		generator: generator,
+		embedder:  &fakeEmbedder{},
+		store:     store,
+		chunker:   chunker.New(config.ChunkerConfig{ChunkSize: 1000, ChunkOverlap: 100}),
+		workers:   2,
+		logger:    log.New(&logs, "", 0),
+	}, store, &logs
+}
+
+// serveRequest sends a request through the server's handler and returns the recorded response
+func serveRequest(server *apiServer, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	server.handler().ServeHTTP(rec, req)
+	return rec
+}
+
+func decodeResponse(t *testing.T, rec *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+		t.Fatalf("Expected JSON response, got %q: %v", rec.Body.String(), err)
+	}
+}
+
+func TestServe_Health(t *testing.T) {
+	server, _, logs := newServeFixture(t, &fakeLLM{})
+
+	rec := serveRequest(server, http.MethodGet, "/healthz", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+	var body map[string]string
+	decodeResponse(t, rec, &body)
+	if body["status"] != "ok" {
+		t.Errorf("Expected status ok, got: %v", body)
+	}
+	if !strings.Contains(logs.String(), "GET /healthz 200") {
+		t.Errorf("Expected request to be logged, got: %q", logs.String())
+	}
+}
+
+func TestServe_Ask(t *testing.T) {
+	t.Run("answers from retrieved context", func(t *testing.T) {
+		generator := &fakeLLM{response: "Set chunker.chunk_size."}
+		server, _, _ := newServeFixture(t, generator)
+
+		rec := serveRequest(server, http.MethodPost, "/ask", `{"question": "how do I change chunking?", "top_k": 2}`)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var resp askResponse
+		decodeResponse(t, rec, &resp)
+		if resp.Answer != "Set chunker.chunk_size." {
+			t.Errorf("Expected the generated answer, got %q", resp.Answer)
+		}
+		if len(resp.Context) != 2 {
+			t.Errorf("Expected 2 context documents, got %d", len(resp.Context))
+		}
+		if generator.answerCalls != 1 || generator.responseCalls != 0 {
+			t.Errorf("Expected one answer-mode call, got %d answer and %d response call(s)", generator.answerCalls, generator.responseCalls)
+		}
+	})
+
+	t.Run("trims context to the chat budget", func(t *testing.T) {
+		generator := &fakeLLM{response: "Overlap is shared between chunks."}
+		server, _, _ := newServeFixture(t, generator)
+		server.contextBudget = chat.ContextBudget{ItemChars: 100}
+
+		rec := serveRequest(server, http.MethodPost, "/ask", `{"question": "what is overlap?", "top_k": 2}`)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var resp askResponse
+		decodeResponse(t, rec, &resp)
+		if len(resp.Context) != 2 || len(resp.Context[1]) > 100 || !strings.HasSuffix(resp.Context[1], "[truncated]") {
+			t.Errorf("Expected the long document cut to 100 characters, got %q", resp.Context)
+		}
+		if !slices.Equal(generator.lastContext, resp.Context) {
+			t.Errorf("Expected the model to be given the context returned, got %q", generator.lastContext)
+		}
+	})
+
+	t.Run("rejects execute when commands are disabled", func(t *testing.T) {
+		generator := &fakeLLM{response: "touch /tmp/x"}
+		server, _, _ := newServeFixture(t, generator)
+
+		rec := serveRequest(server, http.MethodPost, "/ask", `{"question": "make a file", "execute": true}`)
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("Expected status 403, got %d", rec.Code)
+		}
+		if generator.answerCalls+generator.responseCalls != 0 {
+			t.Errorf("Expected the model not to be called, got %d call(s)", generator.answerCalls+generator.responseCalls)
+		}
+	})
+
+	t.Run("runs proposed commands when allowed", func(t *testing.T) {
+		generator := &fakeLLM{response: "mkdir build\nfalse\necho never"}
+		server, _, logs := newServeFixture(t, generator)
+		executor := &fakeExecutor{output: "ok", failOn: "false"}
+		server.newExecutor = executorFactory(executor)
+
+		rec := serveRequest(server, http.MethodPost, "/ask", `{"question": "build it", "execute": true}`)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var resp askResponse
+		decodeResponse(t, rec, &resp)
+		if len(executor.commands) != 2 {
+			t.Errorf("Expected execution to stop at the failing command, ran: %v", executor.commands)
+		}
+		if len(resp.Commands) != 2 || resp.Commands[1].Error == "" || resp.Commands[0].Output != "ok" {
+			t.Errorf("Expected command results with the failure reported, got: %+v", resp.Commands)
+		}
+		if !strings.Contains(logs.String(), "Auto-approving command: mkdir build") {
+			t.Errorf("Expected auto-approved commands to be logged, got: %q", logs.String())
+		}
+	})
+
+	t.Run("does not carry a cd into later requests", func(t *testing.T) {
+		dir := t.TempDir()
+		if err := os.Mkdir(filepath.Join(dir, "sub"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		t.Chdir(dir)
+		generator := &fakeLLM{response: "cd sub"}
+		server, _, _ := newServeFixture(t, generator)
+		server.newExecutor = newRequestExecutor(&config.Config{})
+
+		serveRequest(server, http.MethodPost, "/ask", `{"question": "go to sub", "execute": true}`)
+		generator.response = "pwd"
+		rec := serveRequest(server, http.MethodPost, "/ask", `{"question": "where am I", "execute": true}`)
+		var resp askResponse
+		decodeResponse(t, rec, &resp)
+		if len(resp.Commands) != 1 || strings.TrimSpace(resp.Commands[0].Output) != dir {
+			t.Errorf("Expected pwd to run in the server's directory, got: %+v", resp.Commands)
+		}
+	})
+
+	t.Run("refuses dangerous commands", func(t *testing.T) {
+		generator := &fakeLLM{response: "sudo make install\necho never"}
+		server, _, _ := newServeFixture(t, generator)
+		executor := &fakeExecutor{output: "ok"}
+		server.newExecutor = executorFactory(executor)
+
+		rec := serveRequest(server, http.MethodPost, "/ask", `{"question": "install it", "execute": true}`)
+		var resp askResponse
+		decodeResponse(t, rec, &resp)
+		if len(executor.commands) != 0 {
+			t.Errorf("Expected no commands to run, ran: %v", executor.commands)
+		}
+		if len(resp.Commands) != 1 || !strings.Contains(resp.Commands[0].Error, "runs as root") {
+			t.Errorf("Expected the dangerous command to be refused with its reason, got: %+v", resp.Commands)
+		}
+	})
+
+	t.Run("requires a question", func(t *testing.T) {
+		server, _, _ := newServeFixture(t, &fakeLLM{})
+
+		rec := serveRequest(server, http.MethodPost, "/ask", `{}`)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400, got %d", rec.Code)
+		}
+	})
+
+	t.Run("reports generation errors", func(t *testing.T) {
+		server, _, _ := newServeFixture(t, &fakeLLM{err: errors.New("model not found")})
+
+		rec := serveRequest(server, http.MethodPost, "/ask", `{"question": "hi"}`)
+		if rec.Code != http.StatusBadGateway {
+			t.Errorf("Expected status 502, got %d", rec.Code)
+		}
+		var body map[string]string
+		decodeResponse(t, rec, &body)
+		if !strings.Contains(body["error"], "model not found") {
+			t.Errorf("Expected error message in body, got: %v", body)
+		}
+	})
+}
+
+func TestServe_Search(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantCount  int
+	}{
+		{name: "documents by default", body: `{"query": "chunk size", "top_k": 2}`, wantStatus: http.StatusOK, wantCount: 2},
+		{name: "named collection", body: `{"query": "git", "collection": "commands"}`, wantStatus: http.StatusOK, wantCount: 1},
+		{name: "unknown collection", body: `{"query": "git", "collection": "nope"}`, wantStatus: http.StatusBadRequest},
+		{name: "missing query", body: `{"top_k": 2}`, wantStatus: http.StatusBadRequest},
+		{name: "unknown field", body: `{"q": "git"}`, wantStatus: http.StatusBadRequest},
+		{name: "malformed JSON", body: `{"query":`, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _, _ := newServeFixture(t, &fakeLLM{})
+
+			rec := serveRequest(server, http.MethodPost, "/search", tt.body)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var results []searchOutput
+			decodeResponse(t, rec, &results)
+			if len(results) != tt.wantCount {
+				t.Errorf("Expected %d results, got %d", tt.wantCount, len(results))
+			}
+			if results[0].Rank != 1 {
+				t.Errorf("Expected results to be ranked from 1, got %d", results[0].Rank)
+			}
+		})
+	}
+}
+
+func TestServe_Index(t *testing.T) {
+	t.Run("raw text", func(t *testing.T) {
+		server, store, _ := newServeFixture(t, &fakeLLM{})
+
+		rec := serveRequest(server, http.MethodPost, "/index", `{"text": "Deploys run on Fridays.", "source": "team notes"}`)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var resp indexResponse
+		decodeResponse(t, rec, &resp)
+		if resp.Sources != 1 || resp.Chunks != 1 {
+			t.Errorf("Expected 1 source and 1 chunk, got %+v", resp)
+		}
+		metadata := store.addedMetadata["documents"]
+		if len(metadata) != 1 || metadata[0]["source"] != "team notes" {
+			t.Errorf("Expected the source to be stored as metadata, got: %v", metadata)
+		}
+	})
+
+	t.Run("path", func(t *testing.T) {
+		server, store, _ := newServeFixture(t, &fakeLLM{})
+		root := t.TempDir()
+		for _, file := range []string{"a.md", "b.txt", "image.png"} {
+			if err := os.WriteFile(filepath.Join(root, file), []byte("content of "+file), 0644); err != nil {
+				t.Fatalf("Failed to write %s: %v", file, err)
+			}
+		}
+
+		body, _ := json.Marshal(indexRequest{Path: root})
+		rec := serveRequest(server, http.MethodPost, "/index", string(body))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var resp indexResponse
+		decodeResponse(t, rec, &resp)
+		if resp.Sources != 2 || resp.Chunks != 2 {
+			t.Errorf("Expected 2 sources and 2 chunks, got %+v", resp)
+		}
+		if len(store.added["documents"]) != 2 {
+			t.Errorf("Expected 2 stored chunks, got %d", len(store.added["documents"]))
+		}
+	})
+
+	t.Run("requires exactly one of path or text", func(t *testing.T) {
+		server, _, _ := newServeFixture(t, &fakeLLM{})
+
+		for _, body := range []string{`{}`, `{"path": ".", "text": "x"}`} {
+			rec := serveRequest(server, http.MethodPost, "/index", body)
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("Expected status 400 for %s, got %d", body, rec.Code)
+			}
+		}
+	})
+
+	t.Run("missing path", func(t *testing.T) {
+		server, _, _ := newServeFixture(t, &fakeLLM{})
+
+		rec := serveRequest(server, http.MethodPost, "/index", `{"path": "/does/not/exist"}`)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400, got %d", rec.Code)
+		}
+	})
+}
+
+func TestServe_MethodNotAllowed(t *testing.T) {
+	server, _, _ := newServeFixture(t, &fakeLLM{})
+
+	rec := serveRequest(server, http.MethodGet, "/ask", "")
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405, got %d", rec.Code)
+	}
+	if body, _ := io.ReadAll(rec.Body); len(body) == 0 {
+		t.Errorf("Expected an error body")
+	}
+}
@@ -0,0 +1,307 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"rag-cli/internal/history"
+	"rag-cli/internal/indexing"
+	"rag-cli/internal/metrics"
+	"rag-cli/internal/vector"
+	"rag-cli/pkg/config"
+)
+
+var (
+	statsJSON  bool
+	statsUsage bool
+)
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Summarize indexed data, stored sessions, and configured models",
+	Long: `Summarize the state of your RAG setup in one place:
+
+- Configured LLM and embedding models
+- Document counts for every collection in the vector store
+- When 'rag-cli index' last ran, on which path, and how much it stored
+- How many command sessions are stored and how many of them succeeded
+- How much disk the vector store uses, when vector.data_dir points at a
+  local ChromaDB's data directory (ChromaDB does not report it over its API)
+
+With --usage, summarize the local usage metrics instead: how often tasks
+succeed, and on the first attempt, which commands fail most, and how long
+the model takes to answer. They are recorded only when telemetry.local is
+on, and never leave your machine.
+
+EXAMPLES:
+  # Human-readable summary
+  rag-cli stats
+
+  # JSON output for scripting
+  rag-cli stats --json
+
+  # Start recording usage metrics, then summarize them later
+  rag-cli config set telemetry.local true
+  rag-cli stats --usage`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		if statsUsage {
+			path, err := metrics.DefaultPath()
+			if err != nil {
+				return err
+			}
+			events, err := metrics.Load(path)
+			if err != nil {
+				return err
+			}
+			return runUsageStats(os.Stdout, events, cfg.Telemetry.Local, statsJSON)
+		}
+
+		vectorStore, err := vector.NewChromaClient(cfg.Vector, cfg.Timeouts)
+		if err != nil {
+			return fmt.Errorf("failed to initialize vector store: %w", err)
+		}
+
+		var state *indexing.IndexState
+		if statePath, err := indexing.DefaultStatePath(); err == nil {
+			if state, err = indexing.LoadIndexState(statePath); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			}
+		}
+
+		return runStats(os.Stdout, cfg, vectorStore, state, statsJSON)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(statsCmd)
+
+	statsCmd.Flags().BoolVar(&statsJSON, "json", false, "Output statistics in JSON format")
+	statsCmd.Flags().BoolVar(&statsUsage, "usage", false, "Summarize local usage metrics (see telemetry.local)")
+}
+
+// systemStats is the aggregated view printed by the stats command
+type systemStats struct {
+	Models      modelStats           `json:"models"`
+	Collections []collectionSummary  `json:"collections"`
+	LastIndex   *indexing.IndexState `json:"last_index"`
+	Sessions    sessionStats         `json:"command_sessions"`
+	DiskUsage   *diskUsage           `json:"disk_usage"` // nil when vector.data_dir is not set
+}
+
+// diskUsage is the size of a local ChromaDB's data directory
+type diskUsage struct {
+	Path  string `json:"path"`
+	Bytes int64  `json:"bytes"`
+}
+
+type modelStats struct {
+	LLM        string `json:"llm"`
+	Embeddings string `json:"embeddings"`
+}
+
+// sessionStats aggregates stored command execution sessions
+type sessionStats struct {
+	Total       int     `json:"total"`
+	Succeeded   int     `json:"succeeded"`
+	Failed      int     `json:"failed"`
+	SuccessRate float64 `json:"success_rate"`
+}
+
+func runStats(out io.Writer, cfg *config.Config, store vector.VectorStore, state *indexing.IndexState, asJSON bool) error {
+	collections, err := summarizeCollections(store)
+	if err != nil {
+		return err
+	}
+
+	sessions, err := store.GetDocuments(store.CommandsCollection(), 0)
+	if err != nil {
+		return fmt.Errorf("failed to read command sessions: %w", err)
+	}
+
+	stats := systemStats{
+		Models: modelStats{
+			LLM:        cfg.LLM.Model,
+			Embeddings: cfg.Embeddings.Model,
+		},
+		Collections: collections,
+		LastIndex:   state,
+		Sessions:    summarizeSessions(sessions),
+	}
+	if path := cfg.Vector.DataPath(); path != "" {
+		size, err := directorySize(path)
+		if err != nil {
+			return fmt.Errorf("failed to measure vector.data_dir: %w", err)
+		}
+		stats.DiskUsage = &diskUsage{Path: path, Bytes: size}
+	}
+
+	if asJSON {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(stats)
+	}
+
+	fmt.Fprintln(out, "Models:")
+	fmt.Fprintf(out, "  LLM:        %s\n", stats.Models.LLM)
+	fmt.Fprintf(out, "  Embeddings: %s\n", stats.Models.Embeddings)
+
+	fmt.Fprintln(out, "\nCollections:")
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	for _, collection := range stats.Collections {
+		name := collection.Name
+		if collection.Role != "" {
+			name = fmt.Sprintf("%s (%s)", collection.Name, collection.Role)
+		}
+		fmt.Fprintf(w, "  %s\t%d documents\n", name, collection.Documents)
+	}
+	w.Flush()
+
+	fmt.Fprintln(out, "\nLast index run:")
+	if state == nil {
+		fmt.Fprintln(out, "  never (run 'rag-cli index' to add documents)")
+	} else {
+		fmt.Fprintf(out, "  %s (%s ago)\n", state.LastRun.Format(time.RFC1123), time.Since(state.LastRun).Round(time.Minute))
+		fmt.Fprintf(out, "  %s: %d file(s), %d chunk(s) into %s\n", state.Path, state.Files, state.Chunks, state.Collection)
+	}
+
+	fmt.Fprintln(out, "\nCommand sessions:")
+	if stats.Sessions.Total == 0 {
+		fmt.Fprintln(out, "  none stored yet")
+	} else {
+		fmt.Fprintf(out, "  %d stored, %d succeeded, %d failed (%.0f%% success rate)\n",
+			stats.Sessions.Total, stats.Sessions.Succeeded, stats.Sessions.Failed, stats.Sessions.SuccessRate*100)
+	}
+
+	fmt.Fprintln(out, "\nDisk usage:")
+	if stats.DiskUsage == nil {
+		fmt.Fprintln(out, "  not available: ChromaDB does not report it over its API")
+		fmt.Fprintln(out, "  (set vector.data_dir to a local ChromaDB's data directory to show it)")
+	} else {
+		fmt.Fprintf(out, "  %s in %s\n", formatSize(stats.DiskUsage.Bytes), stats.DiskUsage.Path)
+	}
+	return nil
+}
+
+// directorySize adds up the sizes of the regular files under root
+func directorySize(root string) (int64, error) {
+	var total int64
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		total += info.Size()
+		return nil
+	})
+	return total, err
+}
+
+// formatSize renders a size in binary units with one decimal place
+func formatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit && exp < 4; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTP"[exp])
+}
+
+// summarizeSessions counts successful and failed command sessions. The
+// "success" metadata flag is used when present; older sessions without
+// metadata are judged by whether their log records an error.
+func summarizeSessions(sessions []vector.StoredDocument) sessionStats {
+	stats := sessionStats{Total: len(sessions)}
+	for _, session := range sessions {
+		if history.Succeeded(session) {
+			stats.Succeeded++
+		} else {
+			stats.Failed++
+		}
+	}
+	if stats.Total > 0 {
+		stats.SuccessRate = float64(stats.Succeeded) / float64(stats.Total)
+	}
+	return stats
+}
+
+// runUsageStats prints the aggregated usage events. enabled reports whether
+// telemetry.local is on, to explain an empty summary.
+func runUsageStats(out io.Writer, events []metrics.Event, enabled, asJSON bool) error {
+	summary := metrics.Summarize(events)
+	if asJSON {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(summary)
+	}
+
+	if len(events) == 0 {
+		fmt.Fprintln(out, "No usage recorded yet")
+		if !enabled {
+			fmt.Fprintln(out, "Turn on local usage metrics with 'rag-cli config set telemetry.local true'")
+		}
+		return nil
+	}
+
+	percent := func(rate float64) string { return fmt.Sprintf("%.0f%%", rate*100) }
+	millis := func(ms int64) time.Duration {
+		return (time.Duration(ms) * time.Millisecond).Round(100 * time.Millisecond)
+	}
+
+	fmt.Fprintf(out, "Usage since %s:\n", summary.Since.Format("2006-01-02"))
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "  Tasks:\t%d\n", summary.Tasks)
+	if summary.Tasks > 0 {
+		fmt.Fprintf(w, "  Succeeded:\t%d (%s)\n", summary.Succeeded, percent(summary.SuccessRate))
+		fmt.Fprintf(w, "  First attempt:\t%d (%s)\n", summary.FirstAttempt, percent(summary.FirstAttemptRate))
+		fmt.Fprintf(w, "  Average task time:\t%s\n", millis(summary.AverageTaskMS))
+	}
+	fmt.Fprintf(w, "  Commands run:\t%d (%d failed)\n", summary.Commands, summary.CommandsFailed)
+	fmt.Fprintf(w, "  Model responses:\t%d", summary.LLMRequests)
+	if summary.LLMRequests > 0 {
+		fmt.Fprintf(w, ", %s on average", millis(summary.AverageLLMMS))
+	}
+	fmt.Fprintln(w)
+	w.Flush()
+
+	if len(summary.Models) > 1 {
+		fmt.Fprintln(out, "\nModels:")
+		w = tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		for _, model := range summary.Models {
+			fmt.Fprintf(w, "  %s\t%d response(s), %s on average\n", model.Model, model.Requests, millis(model.AverageMS))
+		}
+		w.Flush()
+	}
+
+	if len(summary.FailingCommands) > 0 {
+		fmt.Fprintln(out, "\nCommands that fail most:")
+		w = tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		for _, command := range summary.FailingCommands {
+			fmt.Fprintf(w, "  %s\t%d of %d run(s) failed\n", command.Command, command.Failures, command.Runs)
+		}
+		w.Flush()
+	}
+	return nil
+}
@@ -0,0 +1,205 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"rag-cli/internal/indexing"
+	"rag-cli/internal/metrics"
+	"rag-cli/internal/vector"
+	"rag-cli/pkg/config"
+)
+
+func newStatsFixture() *fakeStore {
+	store := newCollectionsFixture()
+	store.documents["command_history"] = []vector.StoredDocument{
+		{ID: "s1", Document: "Command execution session:\n$ ls\nfile.txt\n"},
+		{ID: "s2", Document: "Command execution session:\n$ bogus\nError: command failed\n"},
+		{ID: "s3", Document: "Error: looks like a failure", Metadata: map[string]interface{}{"success": true}},
+		{ID: "s4", Document: "$ echo ok", Metadata: map[string]interface{}{"success": false}},
+		{ID: "s5", Document: "$ make\n\nMax attempts (3) reached. Remaining commands not executed.\n"},
+	}
+	return store
+}
+
+func TestSummarizeSessions(t *testing.T) {
+	stats := summarizeSessions(newStatsFixture().documents["command_history"])
+
+	if stats.Total != 5 || stats.Succeeded != 2 || stats.Failed != 3 {
+		t.Errorf("Expected 5 total, 2 succeeded, 3 failed, got %+v", stats)
+	}
+	if stats.SuccessRate != 0.4 {
+		t.Errorf("Expected success rate 0.4, got %v", stats.SuccessRate)
+	}
+
+	if empty := summarizeSessions(nil); empty.Total != 0 || empty.SuccessRate != 0 {
+		t.Errorf("Expected empty stats for no sessions, got %+v", empty)
+	}
+}
+
+func TestRunStats(t *testing.T) {
+	cfg := &config.Config{
+		LLM:        config.LLMConfig{Model: "llama3.1:8b"},
+		Embeddings: config.EmbeddingsConfig{Model: "all-minilm"},
+	}
+	state := &indexing.IndexState{
+		LastRun:    time.Now().Add(-2 * time.Hour),
+		Path:       "/home/me/docs",
+		Collection: "documents",
+		Files:      12,
+		Chunks:     87,
+	}
+
+	t.Run("text", func(t *testing.T) {
+		var out bytes.Buffer
+		if err := runStats(&out, cfg, newStatsFixture(), state, false); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+
+		expected := []string{
+			"LLM:        llama3.1:8b",
+			"Embeddings: all-minilm",
+			"documents (documents)",
+			"120 documents",
+			"project-x",
+			"/home/me/docs: 12 file(s), 87 chunk(s) into documents",
+			"5 stored, 2 succeeded, 3 failed (40% success rate)",
+		}
+		for _, want := range expected {
+			if !strings.Contains(out.String(), want) {
+				t.Errorf("Expected output to contain %q, got:\n%s", want, out.String())
+			}
+		}
+	})
+
+	t.Run("never indexed", func(t *testing.T) {
+		var out bytes.Buffer
+		if err := runStats(&out, cfg, newCollectionsFixture(), nil, false); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if !strings.Contains(out.String(), "never") || !strings.Contains(out.String(), "none stored yet") {
+			t.Errorf("Expected empty state messages, got:\n%s", out.String())
+		}
+		if !strings.Contains(out.String(), "not available") || !strings.Contains(out.String(), "vector.data_dir") {
+			t.Errorf("Expected disk usage to explain how to enable it, got:\n%s", out.String())
+		}
+	})
+
+	t.Run("disk usage", func(t *testing.T) {
+		dir := t.TempDir()
+		if err := os.MkdirAll(filepath.Join(dir, "segments"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "chroma.sqlite3"), make([]byte, 2048), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "segments", "data_level0.bin"), make([]byte, 1024), 0644); err != nil {
+			t.Fatal(err)
+		}
+		withDataDir := *cfg
+		withDataDir.Vector.DataDir = dir
+
+		var out bytes.Buffer
+		if err := runStats(&out, &withDataDir, newStatsFixture(), state, false); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if want := "3.0 KiB in " + dir; !strings.Contains(out.String(), want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, out.String())
+		}
+
+		out.Reset()
+		if err := runStats(&out, &withDataDir, newStatsFixture(), state, true); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		var stats systemStats
+		if err := json.Unmarshal(out.Bytes(), &stats); err != nil {
+			t.Fatalf("Expected valid JSON, got error %v for:\n%s", err, out.String())
+		}
+		if stats.DiskUsage == nil || stats.DiskUsage.Bytes != 3072 {
+			t.Errorf("Expected 3072 bytes of disk usage, got %+v", stats.DiskUsage)
+		}
+
+		withDataDir.Vector.DataDir = filepath.Join(dir, "missing")
+		if err := runStats(&out, &withDataDir, newStatsFixture(), state, false); err == nil {
+			t.Error("Expected an error for a data_dir that does not exist")
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var out bytes.Buffer
+		if err := runStats(&out, cfg, newStatsFixture(), state, true); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+
+		var stats systemStats
+		if err := json.Unmarshal(out.Bytes(), &stats); err != nil {
+			t.Fatalf("Expected valid JSON, got error %v for:\n%s", err, out.String())
+		}
+		if len(stats.Collections) != 5 || stats.Sessions.Total != 5 || stats.LastIndex.Chunks != 87 {
+			t.Errorf("Unexpected aggregated stats: %+v", stats)
+		}
+	})
+}
+
+func TestRunUsageStats(t *testing.T) {
+	day := time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)
+	events := []metrics.Event{
+		{Time: day, Type: metrics.LLMRequest, Model: "llama3.1:8b", Success: true, DurationMS: 2400},
+		{Time: day, Type: metrics.CommandRun, Command: "grep", Success: false},
+		{Time: day, Type: metrics.CommandRun, Command: "grep", Success: true},
+		{Time: day, Type: metrics.TaskFinished, Attempts: 2, Failures: 1, Success: true, DurationMS: 6000},
+		{Time: day, Type: metrics.TaskFinished, Attempts: 0, Success: true, DurationMS: 2000},
+	}
+
+	t.Run("text", func(t *testing.T) {
+		var out bytes.Buffer
+		if err := runUsageStats(&out, events, true, false); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+
+		expected := []string{
+			"Usage since 2026-10-01:",
+			"Succeeded:          2 (100%)",
+			"First attempt:      1 (50%)",
+			"Average task time:  4s",
+			"Commands run:       2 (1 failed)",
+			"Model responses:    1, 2.4s on average",
+			"grep  1 of 2 run(s) failed",
+		}
+		for _, want := range expected {
+			if !strings.Contains(out.String(), want) {
+				t.Errorf("Expected output to contain %q, got:\n%s", want, out.String())
+			}
+		}
+	})
+
+	t.Run("nothing recorded", func(t *testing.T) {
+		var out bytes.Buffer
+		if err := runUsageStats(&out, nil, false, false); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if !strings.Contains(out.String(), "No usage recorded yet") || !strings.Contains(out.String(), "telemetry.local true") {
+			t.Errorf("Expected a hint to turn metrics on, got:\n%s", out.String())
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var out bytes.Buffer
+		if err := runUsageStats(&out, events, true, true); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+
+		var summary metrics.Summary
+		if err := json.Unmarshal(out.Bytes(), &summary); err != nil {
+			t.Fatalf("Expected valid JSON, got error %v for:\n%s", err, out.String())
+		}
+		if summary.Tasks != 2 || summary.FirstAttempt != 1 || len(summary.FailingCommands) != 1 {
+			t.Errorf("Unexpected usage summary: %+v", summary)
+		}
+	})
+}
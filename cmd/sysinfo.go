@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"rag-cli/internal/system"
+	"rag-cli/pkg/config"
+)
+
+var (
+	sysinfoJSON    bool
+	sysinfoRefresh bool
+)
+
+var sysinfoCmd = &cobra.Command{
+	Use:   "sysinfo",
+	Short: "Show the environment rag-cli detected for command prompts",
+	Long: `Show what rag-cli believes about this machine when it asks the model for commands:
+
+- OS, architecture, and shell
+- Whether ls, stat, du, and find use GNU or BSD syntax
+- Installed tools and their versions
+- Package managers, preferred first
+- Whether rag-cli runs in a container or on a CI runner
+- CPU count, memory, and free disk space (unless system_info.resources is off)
+
+The information is cached for system_info.cache_ttl. Use --refresh to detect it
+again, e.g. after installing tools. The output is handy to include in bug reports.
+
+EXAMPLES:
+  rag-cli sysinfo
+
+  # Detect again and print JSON
+  rag-cli sysinfo --refresh --json`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		cache := systemInfoCache(cfg)
+		var info *system.SystemInfo
+		if sysinfoRefresh {
+			info = cache.Refresh()
+		} else {
+			info = cache.Info()
+		}
+		return runSysinfo(os.Stdout, info, sysinfoJSON)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(sysinfoCmd)
+
+	sysinfoCmd.Flags().BoolVar(&sysinfoJSON, "json", false, "Output the detected environment in JSON format")
+	sysinfoCmd.Flags().BoolVar(&sysinfoRefresh, "refresh", false, "Detect the environment again instead of using the cached results")
+}
+
+// sysinfoReport is the JSON form of the detected environment
+type sysinfoReport struct {
+	OS                      string            `json:"os"`
+	Architecture            string            `json:"architecture"`
+	Shell                   string            `json:"shell"`
+	CommandSyntax           map[string]string `json:"command_syntax"`
+	Tools                   map[string]string `json:"tools"`
+	PackageManagers         []string          `json:"package_managers"`
+	PreferredPackageManager string            `json:"preferred_package_manager"`
+	WSL                     bool              `json:"wsl"`
+	InContainer             bool              `json:"in_container"`
+	Container               string            `json:"container,omitempty"`
+	InCI                    bool              `json:"in_ci"`
+	CI                      string            `json:"ci,omitempty"`
+	Resources               *resourcesReport  `json:"resources,omitempty"`
+}
+
+type resourcesReport struct {
+	CPUs          int    `json:"cpus"`
+	MemoryBytes   uint64 `json:"memory_bytes"`
+	DiskFreeBytes uint64 `json:"disk_free_bytes"`
+	DiskPath      string `json:"disk_path"`
+}
+
+func newSysinfoReport(info *system.SystemInfo) sysinfoReport {
+	report := sysinfoReport{
+		OS:                      info.OS,
+		Architecture:            info.Architecture,
+		Shell:                   info.Shell,
+		CommandSyntax:           info.CommandSyntax(),
+		Tools:                   info.Tools(),
+		PackageManagers:         info.PackageManagers,
+		PreferredPackageManager: info.PreferredPackageManager(),
+		WSL:                     info.HasWSL,
+		InContainer:             info.InContainer,
+		Container:               info.Container,
+		InCI:                    info.InCI,
+		CI:                      info.CI,
+	}
+	if report.PackageManagers == nil {
+		report.PackageManagers = []string{}
+	}
+	if r := info.Resources; r != nil {
+		report.Resources = &resourcesReport{
+			CPUs:          r.CPUs,
+			MemoryBytes:   r.MemoryBytes,
+			DiskFreeBytes: r.DiskFreeBytes,
+			DiskPath:      r.DiskPath,
+		}
+	}
+	return report
+}
+
+func runSysinfo(out io.Writer, info *system.SystemInfo, asJSON bool) error {
+	if asJSON {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(newSysinfoReport(info))
+	}
+
+	for _, line := range info.Describe() {
+		fmt.Fprintln(out, line)
+	}
+	return nil
+}
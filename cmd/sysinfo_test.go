@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"rag-cli/internal/system"
+)
+
+// sysinfoFixture is a detected macOS environment
+func sysinfoFixture() *system.SystemInfo {
+	return &system.SystemInfo{
+		OS:           "darwin",
+		Architecture: "arm64",
+		Shell:        "/bin/zsh",
+		HasBSD:       true,
+		Capabilities: map[string]string{
+			"stat":   "BSD",
+			"du":     "BSD",
+			"find":   "BSD",
+			"git":    "git version 2.39.3",
+			"docker": "available",
+		},
+		PackageManagers: []string{"brew"},
+		InCI:            true,
+		CI:              "GitHub Actions",
+		Resources:       &system.Resources{CPUs: 10, MemoryBytes: 17179869184, DiskFreeBytes: 1073741824, DiskPath: "/Users/me/project"},
+	}
+}
+
+func TestRunSysinfo_JSON(t *testing.T) {
+	var out bytes.Buffer
+	if err := runSysinfo(&out, sysinfoFixture(), true); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	var report map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatalf("Expected valid JSON, got %v:\n%s", err, out.String())
+	}
+
+	expected := map[string]interface{}{
+		"os":                        "darwin",
+		"architecture":              "arm64",
+		"shell":                     "/bin/zsh",
+		"command_syntax":            map[string]interface{}{"stat": "BSD", "du": "BSD", "find": "BSD"},
+		"tools":                     map[string]interface{}{"git": "git version 2.39.3", "docker": "available"},
+		"package_managers":          []interface{}{"brew"},
+		"preferred_package_manager": "brew",
+		"wsl":                       false,
+		"in_container":              false,
+		"in_ci":                     true,
+		"ci":                        "GitHub Actions",
+		"resources": map[string]interface{}{
+			"cpus":            float64(10),
+			"memory_bytes":    float64(17179869184),
+			"disk_free_bytes": float64(1073741824),
+			"disk_path":       "/Users/me/project",
+		},
+	}
+	for key, want := range expected {
+		got, _ := json.Marshal(report[key])
+		wantJSON, _ := json.Marshal(want)
+		if string(got) != string(wantJSON) {
+			t.Errorf("Expected %s to be %s, got %s", key, wantJSON, got)
+		}
+	}
+	if _, ok := report["container"]; ok {
+		t.Errorf("Expected no container field outside a container, got %v", report["container"])
+	}
+	if len(report) != len(expected) {
+		t.Errorf("Expected %d fields, got %d: %v", len(expected), len(report), report)
+	}
+}
+
+func TestRunSysinfo_Text(t *testing.T) {
+	var out bytes.Buffer
+	if err := runSysinfo(&out, sysinfoFixture(), false); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	for _, want := range []string{
+		"OS: darwin/arm64",
+		"Shell: /bin/zsh",
+		"CI: GitHub Actions",
+		"Resources: 10 CPUs, 16.0 GiB memory, 1.0 GiB free disk on /Users/me/project",
+		"Command syntax: stat BSD, du BSD, find BSD",
+		"Package managers: brew",
+		"git: git version 2.39.3",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected %q in output, got:\n%s", want, out.String())
+		}
+	}
+}
@@ -3,11 +3,19 @@ package cmd
 import (
 	"encoding/json"
 	"fmt"
+	"io"
+	"os"
+	"time"
 
 	"github.com/spf13/cobra"
+	"rag-cli/internal/update"
+	"rag-cli/pkg/config"
 	"rag-cli/pkg/version"
 )
 
+// updateNoticeInterval is how often the passive update notice checks for a release
+const updateNoticeInterval = 24 * time.Hour
+
 // versionCmd represents the version command
 var versionCmd = &cobra.Command{
 	Use:   "version",
@@ -20,12 +28,20 @@ var versionCmd = &cobra.Command{
 
 Useful for debugging, support requests, and verifying your installation.
 
+With --check, the latest release is looked up on GitHub and compared with this
+build. Set updates.notify: true in the config to be told about new releases, at
+most once a day, after any command. Set updates.check: false to never contact
+GitHub.
+
 EXAMPLES:
   # Show version in human-readable format
   rag-cli version
 
   # Show version in JSON format for scripts
-  rag-cli version --json`,
+  rag-cli version --json
+
+  # Check whether a newer release is available
+  rag-cli version --check`,
 	Run: func(cmd *cobra.Command, args []string) {
 		outputJSON, _ := cmd.Flags().GetBool("json")
 		
@@ -41,6 +57,16 @@ EXAMPLES:
 		} else {
 			fmt.Println(buildInfo.String())
 		}
+
+		if check, _ := cmd.Flags().GetBool("check"); check {
+			cfg, err := config.Load()
+			if err != nil {
+				fmt.Printf("Error loading config: %v\n", err)
+				return
+			}
+			cachePath, _ := update.DefaultCachePath()
+			runVersionCheck(os.Stdout, update.NewChecker(cachePath), buildInfo.Version, cfg.Updates)
+		}
 	},
 }
 
@@ -49,4 +75,48 @@ func init() {
 	
 	// Add JSON output flag
 	versionCmd.Flags().BoolP("json", "j", false, "Output version information in JSON format for scripting and automation")
+	versionCmd.Flags().Bool("check", false, "Check GitHub for a newer release")
+}
+
+// runVersionCheck reports whether a newer release than current is available.
+// It fails soft: problems reaching GitHub are reported in one line and never
+// make the command fail.
+func runVersionCheck(out io.Writer, checker *update.Checker, current string, updates config.UpdatesConfig) {
+	if !updates.Check {
+		fmt.Fprintln(out, "Update checks are disabled (updates.check is false)")
+		return
+	}
+
+	release, err := checker.Latest()
+	if err != nil {
+		fmt.Fprintf(out, "Could not check for updates: %v\n", err)
+		return
+	}
+
+	newer, err := update.Newer(release.Version, current)
+	switch {
+	case err != nil:
+		fmt.Fprintf(out, "Latest release is %s; this build (%s) cannot be compared with it\n  %s\n", release.Version, current, release.URL)
+	case newer:
+		fmt.Fprintf(out, "A newer version is available: %s (you have %s)\n  %s\n", release.Version, current, release.URL)
+	default:
+		fmt.Fprintf(out, "rag-cli %s is up to date\n", current)
+	}
+}
+
+// notifyUpdate prints a one-line notice when updates.notify is enabled and a
+// newer release has come out. It checks at most once per
+// updateNoticeInterval and stays silent on any error.
+func notifyUpdate(out io.Writer, checker *update.Checker, current string, updates config.UpdatesConfig) {
+	if !updates.Check || !updates.Notify || !checker.Due(updateNoticeInterval) {
+		return
+	}
+
+	release, err := checker.Latest()
+	if err != nil {
+		return
+	}
+	if newer, err := update.Newer(release.Version, current); err == nil && newer {
+		fmt.Fprintf(out, "A newer version of rag-cli is available: %s (you have %s)\n  %s\n", release.Version, current, release.URL)
+	}
 }
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"rag-cli/internal/update"
+	"rag-cli/pkg/config"
+)
+
+func newTestChecker(t *testing.T, handler http.HandlerFunc) (*update.Checker, *int) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	checker := update.NewChecker(filepath.Join(t.TempDir(), "update-check.json"))
+	checker.ReleasesURL = server.URL
+	return checker, &requests
+}
+
+func latestRelease(tag string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"tag_name": "` + tag + `", "html_url": "https://example.com/releases/` + tag + `"}`))
+	}
+}
+
+func TestRunVersionCheck(t *testing.T) {
+	enabled := config.UpdatesConfig{Check: true}
+
+	tests := []struct {
+		name     string
+		handler  http.HandlerFunc
+		updates  config.UpdatesConfig
+		expected string
+	}{
+		{name: "update available", handler: latestRelease("v0.2.0"), updates: enabled, expected: "A newer version is available: v0.2.0 (you have v0.1.0)\n  https://example.com/releases/v0.2.0"},
+		{name: "up to date", handler: latestRelease("v0.1.0"), updates: enabled, expected: "rag-cli v0.1.0 is up to date"},
+		{name: "network error fails soft", handler: func(w http.ResponseWriter, r *http.Request) { http.Error(w, "down", http.StatusBadGateway) }, updates: enabled, expected: "Could not check for updates"},
+		{name: "disabled in config", handler: latestRelease("v0.2.0"), updates: config.UpdatesConfig{Check: false}, expected: "Update checks are disabled"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker, requests := newTestChecker(t, tt.handler)
+			var out bytes.Buffer
+
+			runVersionCheck(&out, checker, "v0.1.0", tt.updates)
+
+			if !strings.Contains(out.String(), tt.expected) {
+				t.Errorf("Expected output to contain %q, got: %q", tt.expected, out.String())
+			}
+			if !tt.updates.Check && *requests != 0 {
+				t.Errorf("Expected no requests when disabled, got %d", *requests)
+			}
+		})
+	}
+}
+
+func TestNotifyUpdate(t *testing.T) {
+	t.Run("notifies once per day", func(t *testing.T) {
+		checker, requests := newTestChecker(t, latestRelease("v0.2.0"))
+		updates := config.UpdatesConfig{Check: true, Notify: true}
+
+		var first, second bytes.Buffer
+		notifyUpdate(&first, checker, "v0.1.0", updates)
+		notifyUpdate(&second, checker, "v0.1.0", updates)
+
+		if !strings.Contains(first.String(), "v0.2.0") {
+			t.Errorf("Expected a notice, got: %q", first.String())
+		}
+		if second.Len() != 0 || *requests != 1 {
+			t.Errorf("Expected the second run to use the cache silently, got %d request(s) and %q", *requests, second.String())
+		}
+	})
+
+	t.Run("opt-in", func(t *testing.T) {
+		checker, requests := newTestChecker(t, latestRelease("v0.2.0"))
+		var out bytes.Buffer
+
+		notifyUpdate(&out, checker, "v0.1.0", config.UpdatesConfig{Check: true})
+
+		if out.Len() != 0 || *requests != 0 {
+			t.Errorf("Expected no check without updates.notify, got %d request(s) and %q", *requests, out.String())
+		}
+	})
+
+	t.Run("silent on errors", func(t *testing.T) {
+		checker, _ := newTestChecker(t, func(w http.ResponseWriter, r *http.Request) { http.Error(w, "down", http.StatusBadGateway) })
+		var out bytes.Buffer
+
+		notifyUpdate(&out, checker, "v0.1.0", config.UpdatesConfig{Check: true, Notify: true})
+
+		if out.Len() != 0 {
+			t.Errorf("Expected no output on errors, got: %q", out.String())
+		}
+	})
+}
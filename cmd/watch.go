@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/cobra"
+	"rag-cli/internal/chunker"
+	"rag-cli/internal/embeddings"
+	"rag-cli/internal/indexing"
+	"rag-cli/internal/vector"
+	"rag-cli/pkg/config"
+)
+
+var (
+	watchDryRun   bool
+	watchDebounce time.Duration
+)
+
+// defaultWatchDebounce is used when auto_index.batch_delay is zero
+const defaultWatchDebounce = 2 * time.Second
+
+var watchCmd = &cobra.Command{
+	Use:   "watch [path]",
+	Short: "Keep the auto-index collection in sync as files change",
+	Long: `Watch a directory tree and index files into the auto-index collection as they are
+created or edited, printing a line for each indexed file. Runs until interrupted.
+
+Files are chosen with the auto_index settings: only the configured extensions are
+indexed, files larger than max_file_size are skipped, and paths matching
+exclude_patterns are ignored. Patterns in the watched directory's .gitignore are
+honored as well. Bursts of changes are batched until no event has arrived for
+auto_index.batch_delay (or --debounce).
+
+On Ctrl+C, changes still waiting for the debounce delay are indexed before exiting.
+A file indexed again replaces its earlier chunks, and the chunks of a file are
+removed when it is deleted or renamed. Which chunks belong to which file is kept
+in auto-index-manifest.json in the rag-cli data directory, so this also holds for
+files indexed by an earlier watch or chat.
+
+EXAMPLES:
+  # Watch the current directory
+  rag-cli watch
+
+  # Watch a notes directory, indexing half a second after edits stop
+  rag-cli watch ~/notes --debounce 500ms
+
+  # Show what would be indexed without touching the vector store
+  rag-cli watch --dry-run`,
+	Args:         cobra.MaximumNArgs(1),
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		root := "."
+		if len(args) > 0 {
+			root = args[0]
+		}
+		return runWatch(cmd.Context(), root)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(watchCmd)
+
+	watchCmd.Flags().BoolVar(&watchDryRun, "dry-run", false, "Print the files that would be indexed without indexing them")
+	watchCmd.Flags().DurationVar(&watchDebounce, "debounce", 0, "Quiet period before indexing a batch of changes (default: auto_index.batch_delay)")
+}
+
+// runWatch watches the directory tree at root, indexing changed files until
+// ctx is cancelled or the process is interrupted. It serves both watch and
+// index --watch.
+func runWatch(ctx context.Context, root string) error {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return fmt.Errorf("failed to resolve path: %w", err)
+	}
+	if info, err := os.Stat(root); err != nil || !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", root)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	embeddingClient, err := embeddings.NewClient(cfg.Embeddings, cfg.Timeouts)
+	if err != nil {
+		return fmt.Errorf("failed to initialize embedding client: %w", err)
+	}
+
+	vectorStore, err := vector.NewChromaClient(cfg.Vector, cfg.Timeouts)
+	if err != nil {
+		return fmt.Errorf("failed to initialize vector store: %w", err)
+	}
+
+	patterns, err := indexing.ReadIgnoreFile(filepath.Join(root, ".gitignore"))
+	if err != nil {
+		return err
+	}
+	ignore, err := indexing.NewExcludeMatcher(patterns)
+	if err != nil {
+		return err
+	}
+
+	// Watching is an explicit request to auto-index, whatever auto_index.enabled says
+	autoIndexConfig := cfg.AutoIndex
+	autoIndexConfig.Enabled = true
+	indexer := indexing.NewAutoIndexer(&autoIndexConfig, embeddingClient, vectorStore, root)
+	indexer.UseChunker(chunker.New(cfg.Chunker))
+	useAutoIndexManifest(indexer)
+
+	delay := resolveDebounce(watchDebounce, cfg.AutoIndex.BatchDelay)
+	watcher := indexing.NewWatcher(indexer, ignore, delay, watchDryRun, os.Stdout)
+
+	fsWatcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to start file watcher: %w", err)
+	}
+	defer fsWatcher.Close()
+
+	if err := watcher.WatchTree(fsWatcher.Add); err != nil {
+		return err
+	}
+
+	mode := ""
+	if watchDryRun {
+		mode = " (dry run)"
+	}
+	fmt.Printf("Watching %s%s, press Ctrl+C to stop\n", root, mode)
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := watcher.Run(ctx, fsWatcher.Events, fsWatcher.Errors); err != nil {
+		return err
+	}
+	fmt.Println("Stopped watching")
+	return nil
+}
+
+// useAutoIndexManifest has indexer keep the documents it stores for each
+// file in the auto-index manifest, so that a later run can still remove
+// them. Without the manifest they are tracked for this run only.
+func useAutoIndexManifest(indexer *indexing.AutoIndexer) {
+	path, err := indexing.DefaultAutoIndexManifestPath()
+	if err != nil {
+		slog.Warn("failed to locate auto-index manifest", "component", "auto_index", "error", err)
+		return
+	}
+	manifest, err := indexing.LoadManifest(path)
+	if err != nil {
+		slog.Warn("failed to load auto-index manifest", "component", "auto_index", "error", err)
+		return
+	}
+	indexer.UseManifest(manifest, path)
+}
+
+// resolveDebounce picks the debounce delay: the flag, then the configured batch delay
+func resolveDebounce(flagValue, batchDelay time.Duration) time.Duration {
+	if flagValue > 0 {
+		return flagValue
+	}
+	if batchDelay > 0 {
+		return batchDelay
+	}
+	return defaultWatchDebounce
+}
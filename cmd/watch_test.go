@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+)
+
+func TestResolveDebounce(t *testing.T) {
+	tests := []struct {
+		name       string
+		flag       time.Duration
+		batchDelay time.Duration
+		expected   time.Duration
+	}{
+		{name: "flag wins", flag: 500 * time.Millisecond, batchDelay: 5 * time.Second, expected: 500 * time.Millisecond},
+		{name: "config batch delay", batchDelay: 5 * time.Second, expected: 5 * time.Second},
+		{name: "zero batch delay", expected: defaultWatchDebounce},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := resolveDebounce(tt.flag, tt.batchDelay); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
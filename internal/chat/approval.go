@@ -0,0 +1,220 @@
+package chat
+
+import (
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"rag-cli/pkg/config"
+)
+
+// ApprovalPolicy holds the always-allow rules that let commands run without
+// asking, from chat.always_allow and from answering "always" to an approval
+// prompt. A rule of one word allows that program with any arguments, so
+// "git" allows "git status" and "git log | head -5" when head is also
+// allowed; any other rule allows exactly that command. Program rules do not
+// cover commands that redirect output to a file, run further commands
+// through sudo, sh -c, find -exec and the like, or run a program named by a
+// variable or command substitution. Dangerous commands are asked about
+// every time whatever the rules say.
+type ApprovalPolicy struct {
+	match string                     // What "always" remembers, config.ApprovalMatchProgram or config.ApprovalMatchExact
+	save  func(rules []string) error // Persists rules added with "always"; nil keeps them for the session
+	mu    sync.Mutex
+	rules []string
+}
+
+// NewApprovalPolicy returns a policy starting from rules. match is what
+// answering "always" remembers, the programs when it is not
+// config.ApprovalMatchExact, and save, when not nil, is called with the new
+// rules each time answering "always" adds some.
+func NewApprovalPolicy(rules []string, match string, save func(rules []string) error) *ApprovalPolicy {
+	return &ApprovalPolicy{match: match, save: save, rules: slices.Clone(rules)}
+}
+
+// Allows returns the rule that lets command run without asking, or "" when
+// none does. When several program rules are needed, as for a pipe, they are
+// joined with commas.
+func (p *ApprovalPolicy) Allows(command string) string {
+	if p == nil {
+		return ""
+	}
+	command = strings.TrimSpace(command)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if slices.Contains(p.rules, command) {
+		return command
+	}
+	programs := rulePrograms(command)
+	if len(programs) == 0 {
+		return ""
+	}
+	for _, program := range programs {
+		if !slices.Contains(p.rules, program) {
+			return ""
+		}
+	}
+	return strings.Join(programs, ", ")
+}
+
+// RulesFor returns the rules answering "always" would add for command: its
+// programs, or the command itself when matching exactly or when its programs
+// cannot stand for it
+func (p *ApprovalPolicy) RulesFor(command string) []string {
+	command = strings.TrimSpace(command)
+	if p == nil || command == "" {
+		return nil
+	}
+	if p.match != config.ApprovalMatchExact {
+		if programs := rulePrograms(command); len(programs) > 0 {
+			return programs
+		}
+	}
+	return []string{command}
+}
+
+// Remember adds the rules RulesFor returns for command and returns them,
+// saving them when the policy persists rules. The rules apply for the rest
+// of the session even when saving fails.
+func (p *ApprovalPolicy) Remember(command string) ([]string, error) {
+	rules := p.RulesFor(command)
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	var added []string
+	p.mu.Lock()
+	for _, rule := range rules {
+		if !slices.Contains(p.rules, rule) {
+			p.rules = append(p.rules, rule)
+			added = append(added, rule)
+		}
+	}
+	p.mu.Unlock()
+
+	if p.save == nil || len(added) == 0 {
+		return rules, nil
+	}
+	return rules, p.save(added)
+}
+
+// Persistent reports whether rules added with "always" are saved for later
+// sessions
+func (p *ApprovalPolicy) Persistent() bool {
+	return p != nil && p.save != nil
+}
+
+// rulePrograms returns the programs each command in command runs, each
+// once, or nil when a program rule could not stand for the command: it
+// redirects output to a file, starts a loop or other compound command, runs
+// further commands through a wrapper such as sudo, a shell or find -exec, or
+// runs a program named by a variable or command substitution
+func rulePrograms(command string) []string {
+	if writeRedirect.MatchString(command) || runsSubstitutedProgram(command) {
+		return nil
+	}
+	var programs []string
+	for _, part := range commandSeparator.Split(shellScript(command), -1) {
+		words := shellWords(strings.Trim(part, " ()"))
+		// Skip variable assignments such as LC_ALL=C before the program
+		for len(words) > 0 && strings.Contains(words[0], "=") && !strings.HasPrefix(words[0], "=") {
+			words = words[1:]
+		}
+		if len(words) == 0 {
+			continue
+		}
+		program := filepath.Base(words[0])
+		if _, wrapper := commandWrappers[program]; wrapper || shellKeywords[program] || openers[program] || isShell(program) {
+			return nil
+		}
+		for _, arg := range words[1:] {
+			if arg == "-delete" || strings.HasPrefix(arg, "-exec") || strings.HasPrefix(arg, "-ok") {
+				return nil // find running or deleting whatever it finds
+			}
+		}
+		if !slices.Contains(programs, program) {
+			programs = append(programs, program)
+		}
+	}
+	return programs
+}
+
+// runsSubstitutedProgram reports whether a command in command is named by a
+// variable or a command substitution, as in $(echo rm) x or $CMD x, so that
+// what it runs cannot be told from the text
+func runsSubstitutedProgram(command string) bool {
+	for _, part := range policyCommandSeparator.Split(shellScript(command), -1) {
+		words := shellWords(strings.Trim(part, " ()"))
+		for len(words) > 0 && strings.Contains(words[0], "=") && !strings.HasPrefix(words[0], "=") {
+			words = words[1:]
+		}
+		if len(words) > 0 && (strings.HasPrefix(words[0], "$") || strings.HasPrefix(words[0], "`")) {
+			return true
+		}
+	}
+	return false
+}
+
+// isShell reports whether program is a shell that can run a script given
+// as an argument
+func isShell(program string) bool {
+	switch program {
+	case "sh", "bash", "zsh", "dash", "ksh", "eval", "source", ".":
+		return true
+	}
+	return false
+}
+
+// ruleApproving returns the always-allow rule that lets command run without
+// asking, or "" when it is asked about as usual. Rules are not needed with
+// auto-approve and never cover commands the safety rules confirm or block.
+func (s *Session) ruleApproving(command string) string {
+	if s.config.AutoApprove {
+		return ""
+	}
+	switch s.safety().Classify(command).Action {
+	case SafetyConfirm, SafetyBlock:
+		return ""
+	}
+	return s.approvals.Allows(command)
+}
+
+// alwaysOption returns what answering "always" would allow for command, such
+// as "git", or "" when the answer is not offered because the command is
+// dangerous
+func (s *Session) alwaysOption(command string) string {
+	if s.safety().Classify(command).Action == SafetyConfirm {
+		return ""
+	}
+	return strings.Join(s.approvals.RulesFor(command), ", ")
+}
+
+// answerApproval reports whether answer, given when asked about command,
+// approves it. Answering "always" also adds rules that let commands like it
+// run without asking, described by the message returned.
+func (s *Session) answerApproval(command, answer string) (bool, string) {
+	if !s.safety().AlwaysApproves(answer) {
+		return s.safety().Approves(answer), ""
+	}
+	if s.alwaysOption(command) == "" {
+		return true, ""
+	}
+	return true, s.rememberApproval(command)
+}
+
+// rememberApproval adds rules that let commands like command run without
+// asking and returns a message saying what is now allowed
+func (s *Session) rememberApproval(command string) string {
+	rules, err := s.approvals.Remember(command)
+	allowed := strings.Join(rules, ", ")
+	switch {
+	case err != nil:
+		slog.Warn("failed to save always-allow rule", "component", "chat", "rules", rules, "error", err)
+		return fmt.Sprintf("Always allowing %s for this session (could not save it: %v)", allowed, err)
+	case s.approvals.Persistent():
+		return fmt.Sprintf("Always allowing %s; saved to chat.always_allow", allowed)
+	}
+	return fmt.Sprintf("Always allowing %s for this session", allowed)
+}
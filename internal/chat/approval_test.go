@@ -0,0 +1,149 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"rag-cli/pkg/config"
+)
+
+func TestApprovalPolicy_Allows(t *testing.T) {
+	policy := NewApprovalPolicy([]string{"git", "ls", "head", "cat", "echo", "make test"}, config.ApprovalMatchProgram, nil)
+
+	tests := []struct {
+		command string
+		want    string
+	}{
+		{"git status", "git"},
+		{"  ls -la  ", "ls"},
+		{"git log | head -5", "git, head"},
+		{"LC_ALL=C ls", "ls"},
+		{"make test", "make test"},
+		{"make install", ""},
+		{"git log | wc -l", ""},
+		{"ls > files.txt", ""},
+		{"sudo ls", ""},
+		{"sh -c 'git status'", ""},
+		{"git status && rm notes.txt", ""},
+		{"ls $(rm notes.txt)", ""},
+		{"ls $(cat list)", "ls, cat"},
+		{"$(echo rm) notes.txt", ""},
+		{"$(cat cmd.txt)", ""},
+		{"echo ls | $(echo sh)", ""},
+		{"`echo rm` notes.txt", ""},
+		{"CMD=rm; $CMD notes.txt", ""},
+	}
+	for _, tt := range tests {
+		if got := policy.Allows(tt.command); got != tt.want {
+			t.Errorf("Allows(%q) = %q, want %q", tt.command, got, tt.want)
+		}
+	}
+
+	var none *ApprovalPolicy
+	if got := none.Allows("ls"); got != "" {
+		t.Errorf("Expected a nil policy to allow nothing, got %q", got)
+	}
+}
+
+func TestApprovalPolicy_Remember(t *testing.T) {
+	t.Run("programs", func(t *testing.T) {
+		var saved [][]string
+		policy := NewApprovalPolicy(nil, config.ApprovalMatchProgram, func(rules []string) error {
+			saved = append(saved, rules)
+			return nil
+		})
+
+		rules, err := policy.Remember("git log | head -5")
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if want := []string{"git", "head"}; !reflect.DeepEqual(rules, want) {
+			t.Errorf("Expected rules %v, got %v", want, rules)
+		}
+		if policy.Allows("git diff") != "git" {
+			t.Errorf("Expected later git commands to be allowed")
+		}
+
+		policy.Remember("git status")
+		policy.Remember("find . -name '*.go' -exec rm {} +")
+		if want := [][]string{{"git", "head"}, {"find . -name '*.go' -exec rm {} +"}}; !reflect.DeepEqual(saved, want) {
+			t.Errorf("Expected only new rules to be saved, %v, got %v", want, saved)
+		}
+		if policy.Allows("find . -delete") != "" {
+			t.Errorf("Expected find -exec to be remembered exactly, not as find")
+		}
+	})
+
+	t.Run("exact", func(t *testing.T) {
+		policy := NewApprovalPolicy(nil, config.ApprovalMatchExact, nil)
+		policy.Remember("git status --short")
+		if policy.Allows("git status --short") == "" {
+			t.Errorf("Expected the command to be allowed")
+		}
+		if got := policy.Allows("git push"); got != "" {
+			t.Errorf("Expected other git commands to be asked about, got rule %q", got)
+		}
+	})
+
+	t.Run("save fails", func(t *testing.T) {
+		policy := NewApprovalPolicy(nil, config.ApprovalMatchProgram, func([]string) error {
+			return errors.New("read-only file system")
+		})
+		if _, err := policy.Remember("ls"); err == nil {
+			t.Errorf("Expected the save error to be returned")
+		}
+		if policy.Allows("ls -la") != "ls" {
+			t.Errorf("Expected the rule to apply for the session anyway")
+		}
+	})
+}
+
+func TestSession_AlwaysAllow(t *testing.T) {
+	t.Run("answering always skips later approvals", func(t *testing.T) {
+		executor := &fakeCommander{failing: make(map[string]bool)}
+		session := NewSessionWithDeps(&SessionConfig{NoHistory: true}, nil, nil, SessionDeps{
+			Executor:  executor,
+			Validator: NewCommandValidator(),
+			Evaluator: &fakeEvaluator{evaluations: []evaluation{{proceed: false}}},
+		})
+
+		var err error
+		output := withMockedInput("a\n", func() {
+			_, err = session.executeCommandsIteratively(context.Background(), []string{"git status", "git log -1"}, "what changed")
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if strings.Join(executor.executed, ",") != "git status,git log -1" {
+			t.Errorf("Expected both commands to run, got %v", executor.executed)
+		}
+		for _, want := range []string{"a = always allow git", "Always allowing git for this session", "git log -1 (auto-approved by rule: git)"} {
+			if !strings.Contains(output, want) {
+				t.Errorf("Expected output to contain %q, got:\n%s", want, output)
+			}
+		}
+		if strings.Count(output, "Do you want to allow this?") != 1 {
+			t.Errorf("Expected to be asked once, got:\n%s", output)
+		}
+	})
+
+	t.Run("dangerous commands are still asked about", func(t *testing.T) {
+		session := NewSessionWithDeps(&SessionConfig{
+			NoHistory: true,
+			Approvals: NewApprovalPolicy([]string{"rm"}, config.ApprovalMatchProgram, nil),
+		}, nil, nil, SessionDeps{Executor: &fakeCommander{failing: make(map[string]bool)}})
+
+		if session.needsApproval("rm notes.txt") {
+			t.Errorf("Expected rm of a file to be allowed by the rule")
+		}
+		if !session.needsApproval("rm -rf /tmp/build") {
+			t.Errorf("Expected a dangerous rm to need approval despite the rule")
+		}
+		if option := session.alwaysOption("rm -rf /tmp/build"); option != "" {
+			t.Errorf("Expected no always option for a dangerous command, got %q", option)
+		}
+	})
+}
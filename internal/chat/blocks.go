@@ -0,0 +1,242 @@
+package chat
+
+import "strings"
+
+// commandBlocks splits text into the commands it holds, one per line except
+// where a command carries on over several: a heredoc runs to its end marker,
+// a line ending in a backslash continues on the next, and an open quote,
+// brace group or compound command such as a for loop runs until it is
+// closed. Blank lines between commands are dropped. A block still open at the
+// end of the text, such as prose with an apostrophe, is split into lines
+// again.
+func commandBlocks(text string) []string {
+	var blocks []string
+	var block []string
+	var scan blockScanner
+	for _, line := range strings.Split(text, "\n") {
+		if len(block) == 0 && strings.TrimSpace(line) == "" {
+			continue
+		}
+		block = append(block, line)
+		if scan.scanLine(line) {
+			continue
+		}
+		blocks = append(blocks, strings.TrimSpace(strings.Join(block, "\n")))
+		block = nil
+		scan = blockScanner{}
+	}
+	if len(block) == 0 {
+		return blocks
+	}
+
+	// Never closed: the first line stands alone and the rest is read again
+	blocks = append(blocks, strings.TrimSpace(block[0]))
+	return append(blocks, commandBlocks(strings.Join(block[1:], "\n"))...)
+}
+
+// blockScanner follows the shell syntax of a command line by line, far
+// enough to tell whether the command is complete
+type blockScanner struct {
+	quote     rune     // Quote left open by an earlier line
+	depth     int      // Brace groups and compound commands left open
+	heredocs  []string // End markers of heredocs whose bodies are still to come
+	continued bool     // The last line ended in a backslash
+	argument  bool     // The next word is an argument rather than a command
+}
+
+// openers start a compound command or group, closers end one, and
+// commandPrefixes are followed by another command, all only when they are
+// the command word
+var (
+	openers         = map[string]bool{"if": true, "case": true, "for": true, "while": true, "until": true, "select": true, "{": true}
+	closers         = map[string]bool{"fi": true, "esac": true, "done": true, "}": true}
+	commandPrefixes = map[string]bool{"if": true, "then": true, "else": true, "elif": true, "do": true, "while": true, "until": true, "!": true, "{": true, "time": true}
+)
+
+// scanLine reads the next line of the command and reports whether the
+// command is still open after it
+func (s *blockScanner) scanLine(line string) bool {
+	if len(s.heredocs) > 0 {
+		// Markers are matched leniently, as models often indent them
+		if strings.TrimSpace(line) == s.heredocs[0] {
+			s.heredocs = s.heredocs[1:]
+		}
+		return s.open()
+	}
+
+	if !s.continued && s.quote == 0 {
+		s.argument = false
+	}
+	s.continued = false
+
+	runes := []rune(line)
+	var word strings.Builder
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		if s.quote != 0 {
+			switch {
+			case r == '\\' && s.quote == '"':
+				i++
+			case r == s.quote:
+				s.quote = 0
+			}
+			continue
+		}
+
+		switch {
+		case r == '\\':
+			if i == len(runes)-1 {
+				s.continued = true
+			}
+			word.WriteRune(r)
+			i++
+		case r == '\'' || r == '"':
+			s.quote = r
+			word.WriteRune(r)
+		case r == '#' && word.Len() == 0:
+			i = len(runes) // A comment runs to the end of the line
+		case r == '<' && i+1 < len(runes) && runes[i+1] == '<':
+			s.endWord(&word)
+			i = s.heredoc(runes, i+2) - 1
+			s.argument = true
+		case r == ' ' || r == '\t':
+			s.endWord(&word)
+		case strings.ContainsRune(";&|()", r):
+			s.endWord(&word)
+			s.argument = false
+		default:
+			word.WriteRune(r)
+		}
+	}
+	s.endWord(&word)
+	return s.open()
+}
+
+// endWord counts a finished word that opens or closes a compound command
+func (s *blockScanner) endWord(word *strings.Builder) {
+	if word.Len() == 0 {
+		return
+	}
+	w := word.String()
+	word.Reset()
+	if s.argument {
+		return
+	}
+	switch {
+	case openers[w]:
+		s.depth++
+	case closers[w]:
+		s.depth--
+	}
+	s.argument = !commandPrefixes[w]
+}
+
+// heredoc records the end marker of a heredoc whose operator ends just
+// before runes[i] and returns the index after the marker. A here-string
+// (<<<) has no body.
+func (s *blockScanner) heredoc(runes []rune, i int) int {
+	if i < len(runes) && runes[i] == '<' {
+		return i + 1
+	}
+	if i < len(runes) && runes[i] == '-' {
+		i++
+	}
+	for i < len(runes) && (runes[i] == ' ' || runes[i] == '\t') {
+		i++
+	}
+	var marker strings.Builder
+	for ; i < len(runes) && !strings.ContainsRune(" \t;&|()<>", runes[i]); i++ {
+		if runes[i] != '\'' && runes[i] != '"' && runes[i] != '\\' {
+			marker.WriteRune(runes[i])
+		}
+	}
+	if marker.Len() > 0 {
+		s.heredocs = append(s.heredocs, marker.String())
+	}
+	return i
+}
+
+func (s *blockScanner) open() bool {
+	return s.quote != 0 || s.depth > 0 || len(s.heredocs) > 0 || s.continued
+}
+
+// shellScript returns cmd as the shell reads its commands: heredoc bodies,
+// which are data rather than commands, are left out and lines continued with
+// a backslash are joined, so each remaining line is one or more commands
+func shellScript(cmd string) string {
+	if !strings.Contains(cmd, "\n") {
+		return cmd
+	}
+	var lines []string
+	var scan blockScanner
+	for _, line := range strings.Split(cmd, "\n") {
+		body := len(scan.heredocs) > 0
+		scan.scanLine(line)
+		if body {
+			continue
+		}
+		if n := len(lines); n > 0 && strings.HasSuffix(lines[n-1], "\\") {
+			lines[n-1] = strings.TrimSuffix(lines[n-1], "\\") + " " + line
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// shellHeredocs returns the bodies of the heredocs in cmd that a shell runs
+// as its script, as in bash <<EOF or cat <<EOF | sh, including those nested
+// in such a body. shellScript leaves every heredoc body out, so these must
+// be checked on their own.
+func shellHeredocs(cmd string) []string {
+	if !strings.Contains(cmd, "<<") {
+		return nil
+	}
+	var scripts []string
+	var pending []int // Index in scripts of each heredoc still to come, -1 for data
+	var scan blockScanner
+	for _, line := range strings.Split(cmd, "\n") {
+		open := len(scan.heredocs)
+		scan.scanLine(line)
+		switch {
+		case open == 0:
+			runs := runsScriptFromInput(line)
+			for range scan.heredocs {
+				if !runs {
+					pending = append(pending, -1)
+					continue
+				}
+				pending = append(pending, len(scripts))
+				scripts = append(scripts, "")
+			}
+		case len(scan.heredocs) < open:
+			pending = pending[1:] // The end marker
+		case pending[0] >= 0:
+			scripts[pending[0]] += line + "\n"
+		}
+	}
+	for _, script := range scripts {
+		scripts = append(scripts, shellHeredocs(script)...)
+	}
+	return scripts
+}
+
+// runsScriptFromInput reports whether a line runs a shell that reads its
+// script from input, or eval or source, which run whatever they are given
+func runsScriptFromInput(line string) bool {
+	for _, part := range commandSeparator.Split(line, -1) {
+		program, args, _ := wrappedCommand(shellWords(part))
+		for program == "sudo" || program == "doas" {
+			program, args, _ = wrappedCommand(wrappedArgs(program, args, commandWrappers[program]))
+		}
+		switch program {
+		case "eval", "source", ".":
+			return true
+		case "sh", "bash", "zsh", "dash", "ksh":
+			if _, ok := scriptArgument(program, args); !ok {
+				return true
+			}
+		}
+	}
+	return false
+}
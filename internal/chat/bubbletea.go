@@ -1,6 +1,7 @@
 package chat
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"time"
@@ -31,11 +32,28 @@ const (
 type aiResponseMsg struct {
 	response string
 	err      error
+	notices  []string // Left by retrieval, such as ChromaDB coming back
+	prompt   string   // The prompt sent to the model, when --show-prompt is set
+	sources  string   // The documents the response drew on, or ""
+}
+
+// aiTokenMsg carries a piece of a response as the model streams it; stream
+// delivers the next message for the same response
+type aiTokenMsg struct {
+	token  string
+	stream <-chan tea.Msg
+}
+
+// waitForStream returns a command that delivers the next message from stream
+func waitForStream(stream <-chan tea.Msg) tea.Cmd {
+	return func() tea.Msg {
+		return <-stream
+	}
 }
 
 type commandExecutedMsg struct {
 	command string
-	output  string
+	result  ExecutionResult
 	err     error
 }
 
@@ -55,9 +73,27 @@ type finalAnswerMsg struct {
 	err    error
 }
 
+// autoIndexMsg reports a background auto-index run
+type autoIndexMsg struct {
+	result autoIndexResult
+}
+
+// autoIndexCmd indexes changed files in the background and reports the
+// result as an autoIndexMsg, or is nil when auto-indexing is off
+func (s *Session) autoIndexCmd(ctx context.Context) tea.Cmd {
+	if s.autoIndexer == nil {
+		return nil
+	}
+	return safeCmd("auto-indexing", func() tea.Msg {
+		return autoIndexMsg{result: s.autoIndexChanges(ctx)}
+	})
+}
+
 type Model struct {
 	// Core session components
 	session *Session
+	ctx     context.Context // Cancels model requests, commands, and auto-indexing; set by Run
+	fatal   error           // A panic in Update that closed the program; returned by Run
 	
 	// UI state
 	state        state
@@ -71,10 +107,13 @@ type Model struct {
 	
 	// Chat history
 	messages     []ChatMessage
+	streaming    bool // The last message is a response still arriving
 	
 	// Current command awaiting approval
 	pendingCommand string
 	pendingExplanation string
+	pendingRisk string // Risk notice for the pending command, shown in red
+	pendingAlways string // What answering "always" would allow, or "" when it is not offered
 	
 	// Iterative execution state
 	commandQueue    []string
@@ -192,7 +231,7 @@ func NewBubbleTeaSession(config *SessionConfig, llmClient *llm.Client, embedding
 	ti := textarea.New()
 	ti.Placeholder = "Type your message here... (Ctrl+C to quit, Tab to send)"
 	ti.Focus()
-	ti.CharLimit = 2000
+	ti.CharLimit = config.MaxInputChars
 	ti.SetWidth(80)
 	ti.SetHeight(3)
 	ti.ShowLineNumbers = false
@@ -209,6 +248,7 @@ func NewBubbleTeaSession(config *SessionConfig, llmClient *llm.Client, embedding
 	
 	m := &Model{
 		session:   session,
+		ctx:       context.Background(),
 		state:     stateInput,
 		textarea:  ti,
 		viewport:  vp,
@@ -237,7 +277,19 @@ func (m *Model) Init() tea.Cmd {
 	)
 }
 
-func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+// Update handles msg. A panic closes the program cleanly, so the terminal
+// is restored, and Run returns it as an error.
+func (m *Model) Update(msg tea.Msg) (model tea.Model, cmd tea.Cmd) {
+	defer func() {
+		if r := recover(); r != nil {
+			m.fatal = recordPanic("updating the chat", r)
+			model, cmd = m, tea.Quit
+		}
+	}()
+	return m.update(msg)
+}
+
+func (m *Model) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmds []tea.Cmd
 	
 	switch msg := msg.(type) {
@@ -264,6 +316,10 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case tea.KeyMsg:
 		switch msg.String() {
 		case "ctrl+c":
+			if m.state == stateProcessing && InterruptCommand() {
+				m.addSystemMessage("Stopping the running command")
+				return m, nil
+			}
 			return m, tea.Quit
 		case "tab":
 			if m.state == stateInput && strings.TrimSpace(m.textarea.Value()) != "" {
@@ -282,26 +338,56 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if m.state == stateWaitingApproval {
 				return m.denyCommand()
 			}
+		case "a", "A":
+			if m.state == stateWaitingApproval && m.pendingAlways != "" {
+				m.addSystemMessage(m.session.rememberApproval(m.pendingCommand))
+				return m.approveCommand()
+			}
 		case "esc":
 			if m.state == stateWaitingApproval {
 				return m.denyCommand()
 			}
 		}
 	
+	case aiTokenMsg:
+		if !m.streaming {
+			m.addAIMessage("")
+			m.streaming = true
+		}
+		m.messages[len(m.messages)-1].Content += msg.token
+		m.updateViewport()
+		return m, waitForStream(msg.stream)
+	
 	case aiResponseMsg:
+		if m.streaming {
+			// Replace the streamed text with the response, after any notices
+			m.messages = m.messages[:len(m.messages)-1]
+			m.streaming = false
+		}
+		for _, notice := range msg.notices {
+			m.addSystemMessage(notice)
+		}
+		if msg.prompt != "" {
+			m.addSystemMessage(msg.prompt)
+		}
 		if msg.err != nil {
 			m.addErrorMessage(fmt.Sprintf("Error: %v", msg.err))
 			m.state = stateInput
 		} else {
 			m.addAIMessage(msg.response)
+			if msg.sources != "" {
+				m.addSystemMessage(msg.sources)
+			}
 			// Check if the response contains commands that need approval
 			validCommands := m.session.validator.ParseCommands(msg.response)
-			if len(validCommands) > 0 && !m.session.config.AutoApprove {
+			if len(validCommands) > 0 && !m.session.config.AutoApprove && m.session.ruleApproving(validCommands[0]) == "" {
 				// Show first command for approval
 				command := validCommands[0]
 				explanation := m.session.generateCommandExplanation(command)
 				m.pendingCommand = command
 				m.pendingExplanation = explanation
+				m.pendingRisk = m.session.safety().Classify(command).RiskNotice()
+				m.pendingAlways = m.session.alwaysOption(command)
 				m.state = stateWaitingApproval
 				return m, nil
 			} else if len(validCommands) > 0 {
@@ -314,37 +400,45 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.updateViewport()
 	
 	case commandExecutedMsg:
+		m.session.stats.RecordCommand(msg.command, msg.err != nil)
+		var indexCmd tea.Cmd
 		if msg.err != nil {
 			m.addErrorMessage(fmt.Sprintf("Command failed: %v", msg.err))
 			// Log the failed command
-			if msg.output != "" {
-				m.executionLog.WriteString(fmt.Sprintf("$ %s\n%s\nError: %v\n\n", msg.command, msg.output, msg.err))
-			} else {
-				m.executionLog.WriteString(fmt.Sprintf("$ %s\nError: %v\n\n", msg.command, msg.err))
-			}
+			m.executionLog.WriteString(m.session.logEntry(msg.command, msg.result, msg.err))
 		} else {
 			m.addCommandMessage(msg.command)
-			if msg.output != "" {
-				m.addOutputMessage(msg.output)
+			if msg.result.Stdout != "" {
+				m.addOutputMessage(msg.result.Stdout)
+			}
+			if msg.result.Stderr != "" {
+				m.addSystemMessage(m.session.truncateOutputForDisplay(msg.result.Stderr))
 			}
 			m.addSystemMessage("✅ Command completed successfully")
 			// Log the successful command
-			m.executionLog.WriteString(fmt.Sprintf("$ %s\n%s\n\n", msg.command, msg.output))
+			m.executionLog.WriteString(m.session.logEntry(msg.command, msg.result, nil))
 			
-			// Auto-index if enabled
-			if m.session.autoIndexer != nil {
-				go func() {
-					if changedFiles, err := m.session.autoIndexer.DetectChanges(); err == nil && len(changedFiles) > 0 {
-						if err := m.session.autoIndexer.IndexChangedFiles(changedFiles); err != nil {
-							m.addSystemMessage(fmt.Sprintf("[Auto-index error: %v]", err))
-						}
-					}
-				}()
-			}
+			// Auto-index in the background; the result comes back as an autoIndexMsg
+			indexCmd = m.session.autoIndexCmd(m.ctx)
 		}
 		m.updateViewport()
 		// Continue with next command or evaluation
-		return m.executeNextCommand()
+		model, next := m.executeNextCommand()
+		return model, tea.Batch(next, indexCmd)
+
+	case autoIndexMsg:
+		if message := msg.result.message(); message != "" {
+			m.addSystemMessage(message)
+			m.updateViewport()
+		}
+		return m, nil
+
+	case panicMsg:
+		m.addErrorMessage(msg.err.Error())
+		m.commandQueue = nil
+		m.state = stateInput
+		m.updateViewport()
+		return m, nil
 	
 	case nextCommandsMsg:
 		if msg.err != nil {
@@ -353,8 +447,8 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		} else if !msg.shouldContinue {
 			// Task completed, generate final answer
 			m.state = stateProcessing
-			return m, tea.Cmd(func() tea.Msg {
-				finalAnswer, err := m.session.evaluator.GenerateFinalAnswer(m.executionLog.String(), m.originalRequest)
+			return m, safeCmd("generating the final answer", func() tea.Msg {
+				finalAnswer, err := m.session.generateFinalAnswer(m.ctx, m.executionLog.String(), m.originalRequest)
 				return finalAnswerMsg{answer: finalAnswer, err: err}
 			})
 		} else {
@@ -391,9 +485,17 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	// Update components based on current state
 	switch m.state {
 	case stateInput:
+		truncated := false
+		if keyMsg, ok := msg.(tea.KeyMsg); ok {
+			truncated = exceedsInputLimit(m.textarea.Value(), keyMsg, m.textarea.CharLimit)
+		}
 		var cmd tea.Cmd
 		m.textarea, cmd = m.textarea.Update(msg)
 		cmds = append(cmds, cmd)
+		if truncated {
+			m.addSystemMessage(inputTruncatedWarning(m.textarea.CharLimit))
+			m.updateViewport()
+		}
 	case stateProcessing:
 		var cmd tea.Cmd
 		m.spinner, cmd = m.spinner.Update(msg)
@@ -431,7 +533,7 @@ func (m *Model) View() string {
 	case stateProcessing:
 		status = fmt.Sprintf("%s Processing your request...", m.spinner.View())
 	case stateWaitingApproval:
-		status = "⚠️  Command approval required - Press Enter/Y to approve, N to deny"
+		status = "⚠️  Command approval required - " + m.approvalKeys()
 	case stateError:
 		status = "❌ Error occurred"
 	}
@@ -444,7 +546,10 @@ func (m *Model) View() string {
 		if m.pendingExplanation != "" {
 			approvalContent = fmt.Sprintf("%s\n\n%s", m.pendingExplanation, approvalContent)
 		}
-		approvalContent += "\n\nPress Enter/Y to approve, N to deny"
+		if m.pendingRisk != "" {
+			approvalContent += "\n\n" + m.styles.ErrorStyle.Render(m.pendingRisk)
+		}
+		approvalContent += "\n\n" + m.approvalKeys()
 		approval := m.styles.Approval.Width(m.width-4).Render(approvalContent)
 		sections = append(sections, approval)
 	} else {
@@ -471,33 +576,55 @@ func (m *Model) sendMessage() (tea.Model, tea.Cmd) {
 		return m, nil
 	case "clear":
 		m.messages = []ChatMessage{}
+		m.session.conversation.Reset() // Later requests start afresh
 		m.addSystemMessage("🤖 RAG CLI Chat - Chat cleared")
 		m.textarea.Reset()
 		m.updateViewport()
 		return m, nil
 	case "exit", "quit":
 		return m, tea.Quit
+	case "/prompt last":
+		m.addSystemMessage(m.session.lastPromptReport())
+		m.textarea.Reset()
+		m.updateViewport()
+		return m, nil
 	}
 	
 	// Add user message
 	m.addUserMessage(input)
 	m.originalRequest = input // Store for iterative execution
+	m.session.stats.RecordTask()
 	m.textarea.Reset()
 	m.state = stateProcessing
 	m.updateViewport()
 	
-	// Process with AI
-	return m, tea.Cmd(func() tea.Msg {
+	// Process with AI, showing the response as it streams in
+	ctx := m.ctx
+	stream := make(chan tea.Msg)
+	send := func(msg tea.Msg) {
+		select {
+		case stream <- msg:
+		case <-ctx.Done():
+		}
+	}
+	generate := safeCmd("generating a response", func() tea.Msg {
 		// Get context
-		context, err := m.session.contextManager.GetCombinedContext(input, !m.session.config.NoHistory, 5, 3)
+		context, err := m.session.retrieveContext(ctx, input)
 		if err != nil {
-			context = []string{}
+			context = []ContextItem{}
 		}
+		notices := m.session.notices.take()
 		
 		// Generate response
-		response, err := m.session.llmClient.GenerateResponse(input, context)
-		return aiResponseMsg{response: response, err: err}
+		response, err := m.session.generateResponseStream(ctx, input, ContextTexts(context), func(token string) {
+			send(aiTokenMsg{token: token, stream: stream})
+		})
+		return aiResponseMsg{response: response, err: err, notices: notices, prompt: m.session.shownPrompt(), sources: m.session.sourcesLine(context)}
 	})
+	go func() {
+		send(generate())
+	}()
+	return m, waitForStream(stream)
 }
 
 func (m *Model) approveCommand() (tea.Model, tea.Cmd) {
@@ -509,18 +636,30 @@ func (m *Model) approveCommand() (tea.Model, tea.Cmd) {
 	command := m.pendingCommand
 	m.pendingCommand = ""
 	m.pendingExplanation = ""
+	m.pendingRisk = ""
+	m.pendingAlways = ""
 	m.state = stateProcessing
 	
-	return m, tea.Cmd(func() tea.Msg {
-		output, err := m.session.executor.Execute(command)
-		return commandExecutedMsg{command: command, output: output, err: err}
+	return m, safeCmd("running a command", func() tea.Msg {
+		result, err := m.session.executeCommand(m.ctx, command)
+		return commandExecutedMsg{command: command, result: result, err: err}
 	})
 }
 
+// approvalKeys describes the answers to the pending command's approval
+func (m *Model) approvalKeys() string {
+	if m.pendingAlways == "" {
+		return "Press Enter/Y to approve, N to deny"
+	}
+	return fmt.Sprintf("Press Enter/Y to approve, N to deny, A to always allow %s", m.pendingAlways)
+}
+
 func (m *Model) denyCommand() (tea.Model, tea.Cmd) {
 	m.addSystemMessage("❌ Command execution cancelled by user")
 	m.pendingCommand = ""
 	m.pendingExplanation = ""
+	m.pendingRisk = ""
+	m.pendingAlways = ""
 	m.state = stateInput
 	m.updateViewport()
 	return m, nil
@@ -550,29 +689,35 @@ func (m *Model) executeNextCommand() (tea.Model, tea.Cmd) {
 	command := m.commandQueue[0]
 	m.commandQueue = m.commandQueue[1:]
 	
-	if !m.session.config.AutoApprove {
+	if m.session.needsApproval(command) {
+		if m.session.config.AutoApprove {
+			m.addSystemMessage(fmt.Sprintf("Not auto-approving a dangerous command: %s", command))
+		}
 		// Need approval for this command
 		explanation := m.session.generateCommandExplanation(command)
 		m.pendingCommand = command
 		m.pendingExplanation = explanation
+		m.pendingRisk = m.session.safety().Classify(command).RiskNotice()
+		m.pendingAlways = m.session.alwaysOption(command)
 		m.state = stateWaitingApproval
 		return m, nil
 	} else {
 		// Auto-approve, execute immediately
-		m.addSystemMessage(fmt.Sprintf("⚡ Auto-approving command: %s", command))
+		if rule := m.session.ruleApproving(command); rule != "" {
+			m.addSystemMessage(fmt.Sprintf("⚡ %s (auto-approved by rule: %s)", command, rule))
+		} else {
+			m.addSystemMessage(fmt.Sprintf("⚡ Auto-approving command: %s", command))
+		}
 		m.state = stateProcessing
-		return m, tea.Cmd(func() tea.Msg {
-			output, err := m.session.executor.Execute(command)
-			return commandExecutedMsg{command: command, output: output, err: err}
+		return m, safeCmd("running a command", func() tea.Msg {
+			result, err := m.session.executeCommand(m.ctx, command)
+			return commandExecutedMsg{command: command, result: result, err: err}
 		})
 	}
 }
 
 func (m *Model) evaluateExecution() (tea.Model, tea.Cmd) {
-	maxAttempts := m.session.config.MaxAttempts
-	if maxAttempts <= 0 {
-		maxAttempts = 3
-	}
+	maxAttempts := m.session.config.maxAttempts()
 	
 	if m.currentAttempt >= maxAttempts {
 		m.addSystemMessage(fmt.Sprintf("❌ Max attempts (%d) reached", maxAttempts))
@@ -581,9 +726,10 @@ func (m *Model) evaluateExecution() (tea.Model, tea.Cmd) {
 	}
 	
 	m.state = stateProcessing
-	return m, tea.Cmd(func() tea.Msg {
+	return m, safeCmd("evaluating the results", func() tea.Msg {
 		// Evaluate results and get next commands
 		nextCommands, shouldContinue, err := m.session.evaluator.EvaluateAndGetNextCommands(
+			m.ctx,
 			m.executionLog.String(),
 			m.originalRequest,
 			m.commandQueue,
@@ -648,7 +794,8 @@ func (m *Model) showHelp() {
 
 Available commands:
   help, ?     - Show this help message
-  clear       - Clear the chat history
+  clear       - Clear the chat history and forget earlier turns
+  /prompt last - Show the prompt last sent to the model
   exit, quit  - Exit the chat
 
 Keyboard shortcuts:
@@ -696,9 +843,31 @@ func (m *Model) updateViewport() {
 	m.viewport.GotoBottom()
 }
 
-// Run starts the Bubble Tea interface
-func (m *Model) Run() error {
-	p := tea.NewProgram(m)
+// exceedsInputLimit reports whether inserting the runes of msg into current
+// would go past limit, meaning the input component will silently drop the rest
+func exceedsInputLimit(current string, msg tea.KeyMsg, limit int) bool {
+	if limit <= 0 || msg.Type != tea.KeyRunes {
+		return false
+	}
+	return len([]rune(current))+len(msg.Runes) > limit
+}
+
+// inputTruncatedWarning returns the message shown when pasted input was cut off
+func inputTruncatedWarning(limit int) string {
+	return fmt.Sprintf("⚠️  Input truncated to %d characters (raise chat.max_input_chars or set it to 0 for unlimited)", limit)
+}
+
+// Run starts the Bubble Tea interface. Cancelling ctx stops in-flight work
+// and closes the program.
+func (m *Model) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel() // Stops a response still streaming when the user quits
+	m.ctx = ctx
+	p := tea.NewProgram(m, tea.WithContext(ctx))
 	_, err := p.Run()
+	fmt.Println(m.styles.SystemMessage.Render(m.session.finish()))
+	if m.fatal != nil {
+		return m.fatal
+	}
 	return err
 }
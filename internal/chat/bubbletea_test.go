@@ -0,0 +1,209 @@
+package chat
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"rag-cli/internal/fakeserver"
+	"rag-cli/internal/llm"
+	"rag-cli/internal/system"
+	"rag-cli/pkg/config"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestBubbleTeaSession_InputLimit(t *testing.T) {
+	t.Run("configured limit is applied", func(t *testing.T) {
+		m := NewBubbleTeaSession(&SessionConfig{MaxInputChars: 10}, nil, nil, nil, nil)
+		if m.textarea.CharLimit != 10 {
+			t.Errorf("Expected textarea limit 10, got %d", m.textarea.CharLimit)
+		}
+	})
+
+	t.Run("zero means unlimited", func(t *testing.T) {
+		m := NewBubbleTeaSession(&SessionConfig{}, nil, nil, nil, nil)
+		if m.textarea.CharLimit != 0 {
+			t.Errorf("Expected unlimited textarea, got limit %d", m.textarea.CharLimit)
+		}
+
+		long := strings.Repeat("x", 5000)
+		m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(long)})
+		if got := len(m.textarea.Value()); got != 5000 {
+			t.Errorf("Expected 5000 characters to be accepted, got %d", got)
+		}
+		for _, msg := range m.messages {
+			if strings.Contains(msg.Content, "Input truncated") {
+				t.Errorf("Did not expect a truncation warning, got: %s", msg.Content)
+			}
+		}
+	})
+
+	t.Run("paste over the limit warns", func(t *testing.T) {
+		m := NewBubbleTeaSession(&SessionConfig{MaxInputChars: 10}, nil, nil, nil, nil)
+		m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("a long stack trace")})
+
+		if got := len(m.textarea.Value()); got != 10 {
+			t.Errorf("Expected input to be cut to 10 characters, got %d", got)
+		}
+		last := m.messages[len(m.messages)-1]
+		if last.Type != "system" || !strings.Contains(last.Content, "Input truncated to 10 characters") {
+			t.Errorf("Expected truncation warning, got %q", last.Content)
+		}
+	})
+}
+
+func TestInlineSession_InputLimit(t *testing.T) {
+	m := NewInlineSession(&SessionConfig{MaxInputChars: 25}, nil, nil, nil, nil)
+	if m.textInput.CharLimit != 25 {
+		t.Errorf("Expected text input limit 25, got %d", m.textInput.CharLimit)
+	}
+
+	output := withMockedInput("", func() {
+		m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(strings.Repeat("y", 40))})
+	})
+
+	if got := len(m.textInput.Value()); got != 25 {
+		t.Errorf("Expected input to be cut to 25 characters, got %d", got)
+	}
+	if !strings.Contains(output, "Input truncated to 25 characters") {
+		t.Errorf("Expected truncation warning in output, got: %q", output)
+	}
+}
+
+func TestExceedsInputLimit(t *testing.T) {
+	tests := []struct {
+		name     string
+		current  string
+		msg      tea.KeyMsg
+		limit    int
+		expected bool
+	}{
+		{"unlimited", "abc", tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("defgh")}, 0, false},
+		{"fits exactly", "abc", tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("de")}, 5, false},
+		{"overflows", "abc", tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("def")}, 5, true},
+		{"multibyte runes counted once", "äö", tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("ü")}, 3, false},
+		{"non-rune keys ignored", "abcde", tea.KeyMsg{Type: tea.KeyEnter}, 5, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exceedsInputLimit(tt.current, tt.msg, tt.limit); got != tt.expected {
+				t.Errorf("exceedsInputLimit() = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+// newStreamingClient returns an LLM client for a fake server that streams
+// response word by word. The tests use comments, which are not run as commands.
+func newStreamingClient(t *testing.T, response string) *llm.Client {
+	t.Helper()
+	server := fakeserver.NewOllama(t)
+	server.SetResponse(response)
+	client, err := llm.NewClient(config.LLMConfig{BaseURL: server.URL, Model: "granite-code:3b"}, config.TimeoutsConfig{})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	client.UseSystemInfoCache(system.NewCache("", time.Hour))
+	return client
+}
+
+func TestBubbleTeaSession_StreamsResponse(t *testing.T) {
+	m := NewBubbleTeaSession(&SessionConfig{NoHistory: true}, newStreamingClient(t, "# nothing to run"), nil, nil, nil)
+	m.session.contextManager = NewContextManager(contextEmbedder{}, &contextStore{requested: make(map[string]int)})
+	m.textarea.SetValue("what is this?")
+
+	_, cmd := m.sendMessage()
+	var partial []string
+	for cmd != nil {
+		msg := cmd()
+		_, cmd = m.Update(msg)
+		if _, ok := msg.(aiTokenMsg); ok {
+			partial = append(partial, m.messages[len(m.messages)-1].Content)
+		} else {
+			break
+		}
+	}
+
+	expected := []string{"# ", "# nothing ", "# nothing to ", "# nothing to run"}
+	if strings.Join(partial, "|") != strings.Join(expected, "|") {
+		t.Errorf("Expected the response to grow token by token %q, got %q", expected, partial)
+	}
+	last := m.messages[len(m.messages)-1]
+	if last.Type != "ai" || last.Content != "# nothing to run" || m.streaming {
+		t.Errorf("Expected the streamed response as one AI message, got %+v", last)
+	}
+	aiMessages := 0
+	for _, msg := range m.messages {
+		if msg.Type == "ai" {
+			aiMessages++
+		}
+	}
+	if aiMessages != 1 {
+		t.Errorf("Expected one AI message, got %d", aiMessages)
+	}
+	if m.state != stateInput {
+		t.Errorf("Expected the chat to take the next prompt, got state %v", m.state)
+	}
+}
+
+func TestSimpleSession_StreamsResponse(t *testing.T) {
+	session := NewSimpleSession(&SessionConfig{NoHistory: true}, newStreamingClient(t, "# nothing to run"), nil, nil, nil)
+	session.session.contextManager = NewContextManager(contextEmbedder{}, &contextStore{requested: make(map[string]int)})
+
+	output := withMockedInput("what is this?\n", func() {
+		session.Run(context.Background())
+	})
+
+	if strings.Count(output, "# nothing to run") != 1 {
+		t.Errorf("Expected the response to be printed once, got:\n%s", output)
+	}
+}
+
+// sourcesRetriever returns one labelled document, as a documents collection
+// with metadata would
+type sourcesRetriever struct{}
+
+func (sourcesRetriever) GetCombinedContext(ctx context.Context, prompt string, includeHistory bool, maxDocuments, maxHistory int) ([]ContextItem, error) {
+	return []ContextItem{{Text: "[docs/setup.md, chunk 0] Run make setup.", Source: "docs/setup.md", Distance: 0.19, Collection: "documents"}}, nil
+}
+
+func TestSimpleSession_ShowsSources(t *testing.T) {
+	for _, noSources := range []bool{false, true} {
+		session := NewSimpleSession(&SessionConfig{NoHistory: true, NoSources: noSources}, newStreamingClient(t, "# run make setup"), nil, nil, nil)
+		session.session.contextManager = sourcesRetriever{}
+
+		output := withMockedInput("how do I set up?\n", func() {
+			session.Run(context.Background())
+		})
+
+		if shown := strings.Contains(output, "Sources: docs/setup.md (0.19)"); shown == noSources {
+			t.Errorf("Expected sources shown to be %v with NoSources %v, got:\n%s", !noSources, noSources, output)
+		}
+	}
+}
+
+func TestBubbleTeaSession_ShowsSources(t *testing.T) {
+	m := NewBubbleTeaSession(&SessionConfig{NoHistory: true}, newStreamingClient(t, "# run make setup"), nil, nil, nil)
+	m.session.contextManager = sourcesRetriever{}
+	m.textarea.SetValue("how do I set up?")
+
+	_, cmd := m.sendMessage()
+	for cmd != nil {
+		msg := cmd()
+		_, cmd = m.Update(msg)
+		if _, ok := msg.(aiTokenMsg); !ok {
+			break
+		}
+	}
+
+	last := m.messages[len(m.messages)-1]
+	if last.Type != "system" || last.Content != "Sources: docs/setup.md (0.19)" {
+		t.Errorf("Expected the sources under the answer, got %+v", last)
+	}
+	if previous := m.messages[len(m.messages)-2]; previous.Type != "ai" {
+		t.Errorf("Expected the answer before the sources, got %+v", previous)
+	}
+}
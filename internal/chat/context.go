@@ -1,78 +1,299 @@
 package chat
 
 import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"unicode/utf8"
+
 	"rag-cli/internal/embeddings"
 	"rag-cli/internal/vector"
 )
 
 // ContextManager handles retrieval of contextual information for chat sessions
 type ContextManager struct {
-	embeddingsClient *embeddings.Client
-	vectorStore      *vector.ChromaClient
+	embeddingsClient embeddings.Embedder
+	vectorStore      vector.VectorStore
+	budget           ContextBudget
 }
 
 // NewContextManager creates a new context manager
-func NewContextManager(embeddingsClient *embeddings.Client, vectorStore *vector.ChromaClient) *ContextManager {
+func NewContextManager(embeddingsClient embeddings.Embedder, vectorStore vector.VectorStore) *ContextManager {
 	return &ContextManager{
 		embeddingsClient: embeddingsClient,
 		vectorStore:      vectorStore,
 	}
 }
 
-// GetDocumentContext retrieves relevant context from the document store
-func (c *ContextManager) GetDocumentContext(prompt string, maxResults int) ([]string, error) {
+// UseBudget trims the context GetCombinedContext returns to budget, so that
+// every caller gives the model, and shows, the same context
+func (c *ContextManager) UseBudget(budget ContextBudget) {
+	c.budget = budget
+}
+
+// ContextItem is a piece of context retrieved for a prompt, with where it
+// came from
+type ContextItem struct {
+	Text       string  // What the model is given; documents are labelled with their source
+	Source     string  // File a document was indexed from, or its ID; empty for history
+	Distance   float32 // From the prompt, lower being closer; 0 when the store does not say
+	Collection string
+}
+
+// GetDocumentContext retrieves relevant context from the document store.
+// When the store returns metadata, each document is labelled with the file
+// it came from.
+func (c *ContextManager) GetDocumentContext(ctx context.Context, prompt string, maxResults int) ([]ContextItem, error) {
 	// Generate embedding for the query
-	queryEmbedding, err := c.embeddingsClient.GenerateEmbedding(prompt)
+	queryEmbedding, err := embeddings.Generate(ctx, c.embeddingsClient, prompt)
 	if err != nil {
 		return nil, err
 	}
 
+	collection := c.vectorStore.DocumentsCollection()
+	if searcher, ok := c.vectorStore.(vector.FilterSearcher); ok {
+		results, err := searcher.SearchWithFilterContext(ctx, collection, queryEmbedding, maxResults, nil)
+		if err != nil {
+			return nil, err
+		}
+		items := make([]ContextItem, 0, len(results))
+		for _, result := range results {
+			items = append(items, SearchResultItem(result, collection))
+		}
+		return items, nil
+	}
+
 	// Retrieve relevant context from vector store
-	context, err := c.vectorStore.SearchWithEmbedding(c.vectorStore.DocumentsCollection(), queryEmbedding, maxResults)
+	documents, err := vector.Search(ctx, c.vectorStore, collection, queryEmbedding, maxResults)
 	if err != nil {
 		return nil, err
 	}
 
-	return context, nil
+	return textItems(documents, collection), nil
 }
 
 // GetHistoricalContext retrieves similar command execution sessions from ChromaDB
-func (c *ContextManager) GetHistoricalContext(query string, maxResults int) ([]string, error) {
+func (c *ContextManager) GetHistoricalContext(ctx context.Context, query string, maxResults int) ([]ContextItem, error) {
 	// Generate embedding for the query
-	queryEmbedding, err := c.embeddingsClient.GenerateEmbedding(query)
+	queryEmbedding, err := embeddings.Generate(ctx, c.embeddingsClient, query)
 	if err != nil {
 		return nil, err
 	}
 
 	// Search for similar historical command sessions
-	historicalContext, err := c.vectorStore.SearchWithEmbedding(c.vectorStore.CommandsCollection(), queryEmbedding, maxResults)
+	collection := c.vectorStore.CommandsCollection()
+	historicalContext, err := vector.Search(ctx, c.vectorStore, collection, queryEmbedding, maxResults)
 	if err != nil {
 		return nil, err
 	}
 
-	return historicalContext, nil
+	return textItems(historicalContext, collection), nil
 }
 
-// GetCombinedContext retrieves both document and historical context
-func (c *ContextManager) GetCombinedContext(prompt string, includeHistory bool, maxDocuments, maxHistory int) ([]string, error) {
+// GetCombinedContext retrieves both document and historical context. Both
+// lookups stop when ctx is cancelled.
+func (c *ContextManager) GetCombinedContext(ctx context.Context, prompt string, includeHistory bool, maxDocuments, maxHistory int) ([]ContextItem, error) {
 	// Get document context
-	documentContext, err := c.GetDocumentContext(prompt, maxDocuments)
+	documentContext, err := c.GetDocumentContext(ctx, prompt, maxDocuments)
 	if err != nil {
 		return nil, err
 	}
 
-	var allContext []string
+	var allContext []ContextItem
 	allContext = append(allContext, documentContext...)
 
 	// Get historical context if enabled
 	if includeHistory {
-		historicalContext, err := c.GetHistoricalContext(prompt, maxHistory)
+		historicalContext, err := c.GetHistoricalContext(ctx, prompt, maxHistory)
 		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
 			// Don't fail completely if historical context fails
-			return documentContext, nil
+			return c.budget.Fit(prompt, documentContext), nil
 		}
 		allContext = append(allContext, historicalContext...)
 	}
 
-	return allContext, nil
+	return c.budget.Fit(prompt, allContext), nil
+}
+
+// ContextBudget bounds the retrieved context given to the model with a
+// prompt. The zero value is unlimited.
+type ContextBudget struct {
+	MaxChars  int // Characters of the whole prompt; context is trimmed to fit (0 means unlimited)
+	ItemChars int // Characters kept of each item (0 means unlimited)
+
+	// PromptChars returns the characters of the prompt the model would be
+	// sent for prompt without any context; nil counts the prompt alone
+	PromptChars func(prompt string) int
+}
+
+// Fit trims items so the prompt built from them stays within MaxChars,
+// leaving room for the instructions and prompt itself, and logs a warning
+// when anything had to go
+func (b ContextBudget) Fit(prompt string, items []ContextItem) []ContextItem {
+	budget := 0
+	if b.MaxChars > 0 {
+		budget = b.MaxChars - b.promptChars(prompt)
+		if budget <= 0 {
+			budget = -1 // Not even the prompt fits; send it without context
+		}
+	}
+	fitted, trimmed := fitContext(items, budget, b.ItemChars)
+	if trimmed {
+		slog.Warn("trimmed retrieved context to fit the prompt budget", "component", "chat",
+			"retrieved", len(items), "kept", len(fitted), "max_context_chars", b.MaxChars)
+	}
+	return fitted
+}
+
+// promptChars returns the characters of the prompt without any context
+func (b ContextBudget) promptChars(prompt string) int {
+	if b.PromptChars != nil {
+		return b.PromptChars(prompt)
+	}
+	return len(prompt)
+}
+
+// SearchResultItem is the context item a document search result is given to
+// the model as, labelled with the file it came from when its metadata says
+func SearchResultItem(result vector.SearchResult, collection string) ContextItem {
+	return ContextItem{
+		Text:       withSource(result),
+		Source:     documentSource(result),
+		Distance:   result.Distance,
+		Collection: collection,
+	}
+}
+
+// contextItemOverhead is the characters a prompt adds around each context
+// item, such as its "12. " number and newline, and contextHeaderChars those
+// of the heading above them
+const (
+	contextItemOverhead = 5
+	contextHeaderChars  = len("Context information:\n\n")
+)
+
+// truncatedMarker ends an item cut to fit the budget
+const truncatedMarker = "\n... [truncated]"
+
+// minContextItem is the fewest characters of an item worth keeping when the
+// rest of it would not fit
+const minContextItem = 200
+
+// fitContext cuts each item to itemChars and keeps items, in rank order,
+// while their text fits in budget characters. The item that crosses the
+// budget is cut to the space left, or dropped with everything ranked below
+// it when that is too little to be useful. Documents are ranked above past
+// sessions, each closest first, as GetCombinedContext returns them. A budget
+// or itemChars of 0 is unlimited and a negative budget keeps nothing. It
+// reports whether anything was cut or dropped.
+func fitContext(items []ContextItem, budget, itemChars int) ([]ContextItem, bool) {
+	if budget < 0 {
+		return []ContextItem{}, len(items) > 0
+	}
+	fitted := make([]ContextItem, 0, len(items))
+	trimmed := false
+	remaining := budget - contextHeaderChars
+	for _, item := range items {
+		if itemChars > 0 && len(item.Text) > itemChars {
+			item.Text = truncateContext(item.Text, itemChars)
+			trimmed = true
+		}
+		if budget == 0 {
+			fitted = append(fitted, item)
+			continue
+		}
+		space := remaining - contextItemOverhead
+		if len(item.Text) > space {
+			if space >= minContextItem {
+				item.Text = truncateContext(item.Text, space)
+				fitted = append(fitted, item)
+			}
+			return fitted, true
+		}
+		fitted = append(fitted, item)
+		remaining -= len(item.Text) + contextItemOverhead
+	}
+	return fitted, trimmed
+}
+
+// truncateContext cuts text to at most max bytes, marker included, on a
+// UTF-8 boundary
+func truncateContext(text string, max int) string {
+	cut := max - len(truncatedMarker)
+	if cut <= 0 {
+		return text[:0]
+	}
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	return text[:cut] + truncatedMarker
+}
+
+// textItems wraps search results that are only text
+func textItems(texts []string, collection string) []ContextItem {
+	items := make([]ContextItem, 0, len(texts))
+	for _, text := range texts {
+		items = append(items, ContextItem{Text: text, Collection: collection})
+	}
+	return items
+}
+
+// ContextTexts returns the text of each item, as given to the model
+func ContextTexts(items []ContextItem) []string {
+	texts := make([]string, 0, len(items))
+	for _, item := range items {
+		texts = append(texts, item.Text)
+	}
+	return texts
+}
+
+// sourcesLine lists the documents among items, each once and closest first,
+// as "Sources: docs/setup.md (0.19), main.go (0.26)" with their distances
+// from the prompt, or returns "" when no labelled documents were retrieved
+func sourcesLine(items []ContextItem) string {
+	var sources []string
+	seen := make(map[string]bool)
+	for _, item := range items {
+		if item.Source == "" || seen[item.Source] {
+			continue
+		}
+		seen[item.Source] = true
+		sources = append(sources, fmt.Sprintf("%s (%.2f)", item.Source, item.Distance))
+	}
+	if len(sources) == 0 {
+		return ""
+	}
+	return "Sources: " + strings.Join(sources, ", ")
+}
+
+// documentSource names the file a document was indexed from, falling back to
+// its ID
+func documentSource(result vector.SearchResult) string {
+	for _, key := range []string{"source_path", "source"} {
+		if source, ok := result.Metadata[key]; ok {
+			return fmt.Sprint(source)
+		}
+	}
+	return result.ID
+}
+
+// withSource prefixes a document with where it was indexed from, such as
+// "[docs/setup.md, chunk 2]", when its metadata records that
+func withSource(result vector.SearchResult) string {
+	source, ok := result.Metadata["source_path"]
+	if !ok {
+		source, ok = result.Metadata["source"]
+	}
+	if !ok {
+		return result.Document
+	}
+	label := fmt.Sprintf("[%v", source)
+	if index, ok := result.Metadata["chunk_index"]; ok {
+		label += fmt.Sprintf(", chunk %v", index)
+	}
+	return label + "] " + result.Document
 }
@@ -0,0 +1,338 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"rag-cli/internal/fakeserver"
+	"rag-cli/internal/llm"
+	"rag-cli/internal/system"
+	"rag-cli/internal/vector"
+	"rag-cli/pkg/config"
+)
+
+type contextEmbedder struct{}
+
+func (contextEmbedder) GenerateEmbedding(text string) ([]float32, error) {
+	return []float32{0.1, 0.2}, nil
+}
+
+// contextStore records the number of results requested from each collection;
+// other VectorStore methods are not used
+type contextStore struct {
+	vector.VectorStore
+	requested map[string]int
+}
+
+func (s *contextStore) SearchWithEmbedding(collectionName string, queryEmbedding []float32, numResults int) ([]string, error) {
+	s.requested[collectionName] = numResults
+	return []string{"result from " + collectionName}, nil
+}
+
+func (s *contextStore) DocumentsCollection() string { return "documents" }
+func (s *contextStore) CommandsCollection() string  { return "command_history" }
+
+func TestSession_RetrieveContext(t *testing.T) {
+	tests := []struct {
+		name          string
+		config        SessionConfig
+		wantDocuments int
+		wantHistory   int
+	}{
+		{name: "configured depth", config: SessionConfig{TopKDocuments: 12, TopKHistory: 7}, wantDocuments: 12, wantHistory: 7},
+		{name: "defaults when unset", config: SessionConfig{}, wantDocuments: DefaultTopKDocuments, wantHistory: DefaultTopKHistory},
+		{name: "history disabled", config: SessionConfig{TopKDocuments: 2, TopKHistory: 4, NoHistory: true}, wantDocuments: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &contextStore{requested: make(map[string]int)}
+			session := &Session{
+				config:         &tt.config,
+				contextManager: NewContextManager(contextEmbedder{}, store),
+			}
+
+			retrieved, err := session.retrieveContext(context.Background(), "how do I deploy?")
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+
+			if store.requested["documents"] != tt.wantDocuments {
+				t.Errorf("Expected %d documents requested, got %d", tt.wantDocuments, store.requested["documents"])
+			}
+			if got, searched := store.requested["command_history"]; tt.wantHistory == 0 && searched {
+				t.Errorf("Expected no history search, got %d requested", got)
+			} else if tt.wantHistory != 0 && got != tt.wantHistory {
+				t.Errorf("Expected %d history items requested, got %d", tt.wantHistory, got)
+			}
+			if len(retrieved) == 0 {
+				t.Errorf("Expected retrieved context to be returned")
+			}
+		})
+	}
+}
+
+func TestFitContext(t *testing.T) {
+	items := []ContextItem{
+		{Text: strings.Repeat("a", 600), Source: "first.md"},
+		{Text: strings.Repeat("b", 600), Source: "second.md"},
+		{Text: strings.Repeat("c", 600), Source: "third.md"},
+	}
+	size := func(items []ContextItem) int {
+		total := contextHeaderChars
+		for _, item := range items {
+			total += len(item.Text) + contextItemOverhead
+		}
+		return total
+	}
+
+	tests := []struct {
+		name        string
+		budget      int
+		itemChars   int
+		wantSources []string
+		wantTrimmed bool
+	}{
+		{name: "unlimited", wantSources: []string{"first.md", "second.md", "third.md"}},
+		{name: "everything fits", budget: 2000, wantSources: []string{"first.md", "second.md", "third.md"}},
+		{name: "lowest ranked dropped", budget: 1300, wantSources: []string{"first.md", "second.md"}, wantTrimmed: true},
+		{name: "crossing item cut to fit", budget: 1000, wantSources: []string{"first.md", "second.md"}, wantTrimmed: true},
+		{name: "item cap", itemChars: 300, wantSources: []string{"first.md", "second.md", "third.md"}, wantTrimmed: true},
+		{name: "item cap leaves room", budget: 1000, itemChars: 300, wantSources: []string{"first.md", "second.md", "third.md"}, wantTrimmed: true},
+		{name: "no room", budget: -1, wantTrimmed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fitted, trimmed := fitContext(items, tt.budget, tt.itemChars)
+			var sources []string
+			for _, item := range fitted {
+				sources = append(sources, item.Source)
+				if tt.itemChars > 0 && len(item.Text) > tt.itemChars {
+					t.Errorf("Expected %s cut to %d characters, got %d", item.Source, tt.itemChars, len(item.Text))
+				}
+			}
+			if strings.Join(sources, ",") != strings.Join(tt.wantSources, ",") {
+				t.Errorf("Expected %v kept, got %v", tt.wantSources, sources)
+			}
+			if trimmed != tt.wantTrimmed {
+				t.Errorf("Expected trimmed %v, got %v", tt.wantTrimmed, trimmed)
+			}
+			if tt.budget > 0 && size(fitted) > tt.budget {
+				t.Errorf("Expected at most %d characters, got %d", tt.budget, size(fitted))
+			}
+			if len(fitted) > 0 && !strings.HasPrefix(fitted[0].Text, "aaa") {
+				t.Errorf("Expected the top-ranked item first, got %q", fitted[0].Text[:10])
+			}
+		})
+	}
+	if len(items[0].Text) != 600 {
+		t.Errorf("Expected the retrieved items to be left as they were")
+	}
+}
+
+// oversizedRetriever returns more context than fits in a small model's
+// window: long documents, ranked by name, then a long past session
+type oversizedRetriever struct{}
+
+func (oversizedRetriever) GetCombinedContext(ctx context.Context, prompt string, includeHistory bool, maxDocuments, maxHistory int) ([]ContextItem, error) {
+	var items []ContextItem
+	for i := 1; i <= 5; i++ {
+		source := fmt.Sprintf("doc%d.md", i)
+		items = append(items, ContextItem{Text: "[" + source + "] " + strings.Repeat("deploy ", 400), Source: source, Collection: "documents"})
+	}
+	return append(items, ContextItem{Text: "$ make deploy\n" + strings.Repeat("output\n", 1000), Collection: "command_history"}), nil
+}
+
+func TestSession_RetrieveContext_Budget(t *testing.T) {
+	server := fakeserver.NewOllama(t)
+	server.SetResponse("make deploy")
+	llmClient, err := llm.NewClient(config.LLMConfig{BaseURL: server.URL, Model: "granite-code:3b"}, config.TimeoutsConfig{})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	llmClient.UseSystemInfoCache(system.NewCache("", time.Hour))
+
+	const budget = 6000
+	session := NewSessionWithDeps(&SessionConfig{MaxContextChars: budget, ContextItemChars: 2000}, llmClient, nil, SessionDeps{
+		Context: oversizedRetriever{},
+	})
+
+	retrieved, err := session.retrieveContext(context.Background(), "how do I deploy?")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(retrieved) == 0 || retrieved[0].Source != "doc1.md" {
+		t.Fatalf("Expected the top-ranked document to be kept, got %+v", retrieved)
+	}
+	for _, item := range retrieved {
+		if item.Collection == "command_history" {
+			t.Errorf("Expected the lowest-ranked session to be dropped before documents")
+		}
+	}
+
+	if _, err := llmClient.GenerateResponse("how do I deploy?", ContextTexts(retrieved)); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	req, _ := server.LastRequest("/api/generate")
+	var body llm.GenerateRequest
+	if err := req.Decode(&body); err != nil {
+		t.Fatalf("Expected a JSON request body, got: %v", err)
+	}
+	if len(body.Prompt) > budget {
+		t.Errorf("Expected a prompt of at most %d characters, got %d", budget, len(body.Prompt))
+	}
+	if !strings.Contains(body.Prompt, "[doc1.md] deploy") || !strings.Contains(body.Prompt, "how do I deploy?") {
+		t.Errorf("Expected the prompt to keep the top-ranked document and the request")
+	}
+}
+
+func TestSession_RetrieveContext_MaxDistance(t *testing.T) {
+	server := fakeserver.NewOllama(t)
+	server.SetResponse("ls")
+	llmClient, err := llm.NewClient(config.LLMConfig{BaseURL: server.URL, Model: "test"}, config.TimeoutsConfig{})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	llmClient.UseSystemInfoCache(system.NewCache("", time.Hour))
+
+	chroma := fakeserver.NewChroma(t)
+	store, err := vector.NewChromaClient(config.VectorConfig{BaseURL: chroma.URL, Collection: "documents", CommandCollection: "command_history", AutoIndexCollection: "auto_indexed", MaxDistance: 1}, config.TimeoutsConfig{})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	session := NewSessionWithDeps(&SessionConfig{}, llmClient, nil, SessionDeps{
+		Context: NewContextManager(contextEmbedder{}, store),
+	})
+
+	prompt := func() string {
+		t.Helper()
+		retrieved, err := session.retrieveContext(context.Background(), "how do I set up?")
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if _, err := llmClient.GenerateResponse("how do I set up?", ContextTexts(retrieved)); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		return llmClient.LastPrompt()
+	}
+
+	// Both are about 30 from the query embedding {0.1, 0.2}, squared
+	store.AddDocument("documents", "recipes", "Knead the dough for ten minutes.", []float32{5, 3})
+	store.AddDocument("command_history", "old", "$ make clean", []float32{4, 4})
+	if got := prompt(); strings.Contains(got, "Context information") || strings.Contains(got, "dough") {
+		t.Errorf("Expected no context section when nothing is close enough, got:\n%s", got)
+	}
+
+	store.AddDocument("documents", "setup", "Run make setup first.", []float32{0.2, 0.1})
+	got := prompt()
+	if !strings.Contains(got, "Context information:\n1. Run make setup first.\n\n") {
+		t.Errorf("Expected only the close document as context, got:\n%s", got)
+	}
+}
+
+// blockingEmbedder waits for its context to be cancelled, like an embeddings
+// server that has stopped responding
+type blockingEmbedder struct{}
+
+func (blockingEmbedder) GenerateEmbedding(text string) ([]float32, error) {
+	return blockingEmbedder{}.GenerateEmbeddingContext(context.Background(), text)
+}
+
+func (blockingEmbedder) GenerateEmbeddingContext(ctx context.Context, text string) ([]float32, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestContextManager_Cancel(t *testing.T) {
+	store := &contextStore{requested: make(map[string]int)}
+	manager := NewContextManager(blockingEmbedder{}, store)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	retrieved, err := manager.GetCombinedContext(ctx, "how do I deploy?", true, 5, 3)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected a cancellation error, got %v with %v", err, retrieved)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected retrieval to stop when cancelled, took %s", elapsed)
+	}
+	if len(store.requested) != 0 {
+		t.Errorf("Expected no searches after cancellation, got %v", store.requested)
+	}
+}
+
+func TestContextManager_DocumentSources(t *testing.T) {
+	chroma := fakeserver.NewChroma(t)
+	store, err := vector.NewChromaClient(config.VectorConfig{BaseURL: chroma.URL, Collection: "documents", CommandCollection: "command_history", AutoIndexCollection: "auto_indexed"}, config.TimeoutsConfig{})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	store.AddDocumentWithMetadata("documents", "setup", "Run make setup first.", []float32{0.1, 0.2}, map[string]interface{}{"source_path": "docs/setup.md", "chunk_index": 2})
+	store.AddDocumentWithMetadata("documents", "notes", "Deploys run on Fridays.", []float32{0.9, 0.9}, map[string]interface{}{"source": "team notes"})
+	store.AddDocument("documents", "bare", "Unlabelled text.", []float32{5, 5})
+
+	documents, err := NewContextManager(contextEmbedder{}, store).GetDocumentContext(context.Background(), "how do I set up?", 5)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	expected := []string{
+		"[docs/setup.md, chunk 2] Run make setup first.",
+		"[team notes] Deploys run on Fridays.",
+		"Unlabelled text.",
+	}
+	if texts := ContextTexts(documents); strings.Join(texts, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected documents labelled with their source %q, got %q", expected, texts)
+	}
+	if documents[0].Source != "docs/setup.md" || documents[1].Source != "team notes" || documents[2].Source != "bare" {
+		t.Errorf("Expected sources from metadata, falling back to the ID, got %+v", documents)
+	}
+	if documents[0].Collection != "documents" || documents[0].Distance >= documents[1].Distance {
+		t.Errorf("Expected the collection and distances to be kept, got %+v", documents)
+	}
+}
+
+func TestSourcesLine(t *testing.T) {
+	tests := []struct {
+		name     string
+		items    []ContextItem
+		expected string
+	}{
+		{name: "no context", expected: ""},
+		{
+			name:     "history only",
+			items:    []ContextItem{{Text: "$ make deploy", Collection: "command_history"}},
+			expected: "",
+		},
+		{
+			name: "documents",
+			items: []ContextItem{
+				{Text: "[docs/setup.md, chunk 2] Run make setup first.", Source: "docs/setup.md", Distance: 0.19},
+				{Text: "[main.go, chunk 0] package main", Source: "main.go", Distance: 0.264},
+				{Text: "[docs/setup.md, chunk 3] Then make test.", Source: "docs/setup.md", Distance: 0.3},
+				{Text: "$ make setup", Collection: "command_history"},
+			},
+			expected: "Sources: docs/setup.md (0.19), main.go (0.26)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sourcesLine(tt.items); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+
+	session := &Session{config: &SessionConfig{NoSources: true}}
+	if got := session.sourcesLine([]ContextItem{{Source: "main.go"}}); got != "" {
+		t.Errorf("Expected sources to be hidden, got %q", got)
+	}
+}
@@ -0,0 +1,108 @@
+package chat
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"rag-cli/internal/llm"
+)
+
+// summarizeFunc condenses turns, and summary of the turns before them, into a
+// new summary
+type summarizeFunc func(ctx context.Context, summary string, turns []llm.Turn) (string, error)
+
+// ConversationHistory remembers the recent requests and responses of a chat,
+// so a follow-up such as "now do the same for the other directory" reaches
+// the model with the turns it refers to. It keeps at most maxChars of text:
+// when a new turn would go over, the oldest turns are dropped, or folded into
+// a running summary when a summarizer is set. A zero maxChars remembers
+// nothing.
+type ConversationHistory struct {
+	mutex     sync.Mutex
+	turns     []llm.Turn // Oldest first
+	chars     int        // Total length of turns' content
+	summary   string     // Gist of turns dropped so far, when summarizing
+	maxChars  int
+	summarize summarizeFunc // nil drops old turns
+}
+
+// NewConversationHistory creates a history holding at most maxChars of turns
+func NewConversationHistory(maxChars int) *ConversationHistory {
+	return &ConversationHistory{maxChars: maxChars}
+}
+
+// Record adds a request and the model's response to it. Turns that no longer
+// fit are summarized with ctx, or dropped if that fails.
+func (h *ConversationHistory) Record(ctx context.Context, request, response string) {
+	if h == nil || h.maxChars <= 0 {
+		return
+	}
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.turns = append(h.turns,
+		llm.Turn{Role: llm.RoleUser, Content: request},
+		llm.Turn{Role: llm.RoleAssistant, Content: response},
+	)
+	h.chars += len(request) + len(response)
+
+	var dropped []llm.Turn
+	for h.chars+len(h.summary) > h.maxChars && len(h.turns) > 0 {
+		dropped = append(dropped, h.dropOldest()...)
+	}
+	if len(dropped) == 0 || h.summarize == nil {
+		return
+	}
+	summary, err := h.summarize(ctx, h.summary, dropped)
+	if err != nil {
+		slog.Debug("failed to summarize conversation", "component", "chat", "error", err)
+		return
+	}
+	if len(summary) > h.maxChars/2 {
+		summary = strings.ToValidUTF8(summary[:h.maxChars/2], "")
+	}
+	h.summary = summary
+	// The summary takes room from the turns still kept
+	for h.chars+len(h.summary) > h.maxChars && len(h.turns) > 0 {
+		h.dropOldest()
+	}
+}
+
+// dropOldest removes and returns the oldest exchange, a request and its
+// response, so no response is kept without the request it answered
+func (h *ConversationHistory) dropOldest() []llm.Turn {
+	n := min(2, len(h.turns))
+	dropped := h.turns[:n:n]
+	for _, turn := range dropped {
+		h.chars -= len(turn.Content)
+	}
+	h.turns = h.turns[n:]
+	return dropped
+}
+
+// Turns returns the remembered turns, oldest first, led by the summary of
+// dropped ones when there is one
+func (h *ConversationHistory) Turns() []llm.Turn {
+	if h == nil {
+		return nil
+	}
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	var turns []llm.Turn
+	if h.summary != "" {
+		turns = append(turns, llm.Turn{Role: llm.RoleSummary, Content: h.summary})
+	}
+	return append(turns, h.turns...)
+}
+
+// Reset forgets every turn and the summary
+func (h *ConversationHistory) Reset() {
+	if h == nil {
+		return
+	}
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.turns, h.chars, h.summary = nil, 0, ""
+}
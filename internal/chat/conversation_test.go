@@ -0,0 +1,158 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"rag-cli/internal/fakeserver"
+	"rag-cli/internal/llm"
+	"rag-cli/internal/system"
+	"rag-cli/pkg/config"
+)
+
+func TestConversationHistory_Record(t *testing.T) {
+	history := NewConversationHistory(50)
+
+	history.Record(context.Background(), "list go files", "ls *.go")
+	history.Record(context.Background(), "count them", "ls *.go | wc -l")
+
+	turns := history.Turns()
+	if len(turns) != 4 || turns[0].Role != llm.RoleUser || turns[0].Content != "list go files" || turns[3].Content != "ls *.go | wc -l" {
+		t.Fatalf("Expected both exchanges oldest first, got %+v", turns)
+	}
+
+	history.Record(context.Background(), "now the tests", "ls *_test.go")
+	turns = history.Turns()
+	if turns[0].Content != "count them" || len(turns) != 4 {
+		t.Errorf("Expected the oldest exchange to be dropped, got %+v", turns)
+	}
+
+	history.Reset()
+	if turns := history.Turns(); len(turns) != 0 {
+		t.Errorf("Expected no turns after Reset, got %+v", turns)
+	}
+}
+
+func TestConversationHistory_Disabled(t *testing.T) {
+	history := NewConversationHistory(0)
+	history.Record(context.Background(), "list files", "ls")
+	if turns := history.Turns(); len(turns) != 0 {
+		t.Errorf("Expected nothing to be remembered, got %+v", turns)
+	}
+
+	var none *ConversationHistory
+	none.Record(context.Background(), "list files", "ls") // Must not panic
+	none.Reset()
+	if turns := none.Turns(); turns != nil {
+		t.Errorf("Expected no turns, got %+v", turns)
+	}
+}
+
+func TestConversationHistory_Summarize(t *testing.T) {
+	history := NewConversationHistory(60)
+	var summarized [][]llm.Turn
+	history.summarize = func(ctx context.Context, summary string, turns []llm.Turn) (string, error) {
+		summarized = append(summarized, turns)
+		return "User listed go files", nil
+	}
+
+	history.Record(context.Background(), "list go files", "ls *.go")
+	history.Record(context.Background(), "count lines in them", "wc -l *.go")
+	history.Record(context.Background(), "now the tests", "wc -l *_test.go")
+
+	if len(summarized) == 0 || summarized[0][0].Content != "list go files" {
+		t.Fatalf("Expected the oldest turns to be summarized, got %+v", summarized)
+	}
+	turns := history.Turns()
+	if turns[0].Role != llm.RoleSummary || turns[0].Content != "User listed go files" {
+		t.Errorf("Expected the summary to lead the turns, got %+v", turns)
+	}
+	if last := turns[len(turns)-1]; last.Content != "wc -l *_test.go" {
+		t.Errorf("Expected the latest turn to be kept, got %+v", last)
+	}
+
+	t.Run("failure drops the turns", func(t *testing.T) {
+		history := NewConversationHistory(30)
+		history.summarize = func(ctx context.Context, summary string, turns []llm.Turn) (string, error) {
+			return "", errors.New("model unavailable")
+		}
+		history.Record(context.Background(), "list go files", "ls *.go")
+		history.Record(context.Background(), "now the tests", "ls *_test.go")
+		for _, turn := range history.Turns() {
+			if turn.Role == llm.RoleSummary || turn.Content == "list go files" {
+				t.Errorf("Expected the old turns to be dropped, got %+v", history.Turns())
+			}
+		}
+	})
+}
+
+func TestSimpleSession_RemembersTurns(t *testing.T) {
+	server := fakeserver.NewOllama(t)
+	server.SetResponse("# nothing to run")
+	client, err := llm.NewClient(config.LLMConfig{BaseURL: server.URL, Model: "granite-code:3b"}, config.TimeoutsConfig{})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	client.UseSystemInfoCache(system.NewCache("", time.Hour))
+	session := NewSimpleSession(&SessionConfig{NoHistory: true, MemoryChars: 1000}, client, nil, nil, nil)
+	session.session.contextManager = NewContextManager(contextEmbedder{}, &contextStore{requested: make(map[string]int)})
+
+	withMockedInput("list the go files in cmd\nnow do the same for internal\nclear\nlist the tests\n", func() {
+		session.Run(context.Background())
+	})
+
+	requests := server.Requests("/api/generate")
+	if len(requests) != 3 {
+		t.Fatalf("Expected 3 requests to the model, got %d", len(requests))
+	}
+	var prompts []string
+	for _, req := range requests {
+		var body llm.GenerateRequest
+		if err := req.Decode(&body); err != nil {
+			t.Fatalf("Expected a JSON request body, got: %v", err)
+		}
+		prompts = append(prompts, body.Prompt)
+	}
+	if strings.Contains(prompts[0], "Conversation so far") {
+		t.Errorf("Expected no earlier turns in the first prompt, got:\n%s", prompts[0])
+	}
+	if !strings.Contains(prompts[1], "User: list the go files in cmd\nAssistant: # nothing to run\n") || !strings.HasSuffix(prompts[1], "User request: now do the same for internal") {
+		t.Errorf("Expected the earlier turn before the follow-up, got:\n%s", prompts[1])
+	}
+	if strings.Contains(prompts[2], "Conversation so far") {
+		t.Errorf("Expected clear to forget earlier turns, got:\n%s", prompts[2])
+	}
+}
+
+func TestBubbleTeaSession_RemembersTurns(t *testing.T) {
+	client := newStreamingClient(t, "# nothing to run")
+	m := NewBubbleTeaSession(&SessionConfig{NoHistory: true, MemoryChars: 1000}, client, nil, nil, nil)
+	m.session.contextManager = NewContextManager(contextEmbedder{}, &contextStore{requested: make(map[string]int)})
+
+	send := func(input string) {
+		m.textarea.SetValue(input)
+		_, cmd := m.sendMessage()
+		for cmd != nil {
+			msg := cmd()
+			_, cmd = m.Update(msg)
+			if _, ok := msg.(aiTokenMsg); !ok {
+				break
+			}
+		}
+	}
+
+	send("show disk usage of /var")
+	send("now the same for /home")
+	if prompt := client.LastPrompt(); !strings.Contains(prompt, "User: show disk usage of /var\n") {
+		t.Errorf("Expected the earlier turn in the follow-up prompt, got:\n%s", prompt)
+	}
+
+	m.textarea.SetValue("clear")
+	m.sendMessage()
+	if turns := m.session.conversation.Turns(); len(turns) != 0 {
+		t.Errorf("Expected clear to forget earlier turns, got %+v", turns)
+	}
+}
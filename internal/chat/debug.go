@@ -0,0 +1,117 @@
+package chat
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// DefaultDebugLogMaxSize caps the debug log when no other size is given
+const DefaultDebugLogMaxSize = 10 << 20
+
+// DebugLogger appends timestamped entries to a file. When the file would grow
+// past its size cap it is moved aside to <path>.1, replacing any earlier one,
+// and a new file is started, so at most twice the cap is kept on disk. A nil
+// *DebugLogger writes nothing.
+type DebugLogger struct {
+	path    string
+	maxSize int64 // 0 disables rotation
+	mutex   sync.Mutex
+}
+
+// NewDebugLogger creates a logger that writes to path, creating its directory
+// if needed
+func NewDebugLogger(path string, maxSize int64) (*DebugLogger, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create debug log directory: %w", err)
+	}
+	return &DebugLogger{path: path, maxSize: maxSize}, nil
+}
+
+// Path returns the file the logger writes to
+func (l *DebugLogger) Path() string {
+	if l == nil {
+		return ""
+	}
+	return l.path
+}
+
+// Write appends a timestamped entry. Errors are ignored so debugging never
+// interrupts a session.
+func (l *DebugLogger) Write(content string) {
+	if l == nil {
+		return
+	}
+	entry := fmt.Sprintf("[%s] %s\n", time.Now().Format("2006-01-02 15:04:05"), content)
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.rotate(int64(len(entry)))
+
+	file, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	file.WriteString(entry)
+}
+
+// rotate moves the log aside when adding n bytes would take it past the cap
+func (l *DebugLogger) rotate(n int64) {
+	if l.maxSize <= 0 {
+		return
+	}
+	stat, err := os.Stat(l.path)
+	if err != nil || stat.Size() == 0 || stat.Size()+n <= l.maxSize {
+		return
+	}
+	os.Rename(l.path, l.path+".1")
+}
+
+var (
+	debugLogMu sync.Mutex
+	debugLog   *DebugLogger // nil while debug logging is disabled
+)
+
+// SetDebugLogger sends the chat's debug logging to logger. A nil logger
+// disables debug logging.
+func SetDebugLogger(logger *DebugLogger) {
+	debugLogMu.Lock()
+	defer debugLogMu.Unlock()
+	debugLog = logger
+}
+
+// EnableDebugLog sends debug logging to the file at path, capped at
+// DefaultDebugLogMaxSize. An empty path disables debug logging again.
+func EnableDebugLog(path string) error {
+	if path == "" {
+		SetDebugLogger(nil)
+		return nil
+	}
+	logger, err := NewDebugLogger(path, DefaultDebugLogMaxSize)
+	if err != nil {
+		return err
+	}
+	SetDebugLogger(logger)
+	return nil
+}
+
+// currentDebugLogger returns the logger set with SetDebugLogger, or nil
+func currentDebugLogger() *DebugLogger {
+	debugLogMu.Lock()
+	defer debugLogMu.Unlock()
+	return debugLog
+}
+
+// debugLogEnabled reports whether WriteDebugLog writes anywhere
+func debugLogEnabled() bool {
+	return currentDebugLogger() != nil
+}
+
+// WriteDebugLog appends a timestamped entry to the debug log. It does nothing
+// unless debug logging was enabled with SetDebugLogger or EnableDebugLog.
+func WriteDebugLog(content string) {
+	currentDebugLogger().Write(content)
+}
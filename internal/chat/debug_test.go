@@ -0,0 +1,157 @@
+package chat
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"rag-cli/internal/embeddings"
+	"rag-cli/internal/fakeserver"
+	"rag-cli/internal/llm"
+	"rag-cli/internal/system"
+	"rag-cli/internal/vector"
+	"rag-cli/pkg/config"
+)
+
+func TestWriteDebugLog(t *testing.T) {
+	t.Run("disabled writes nothing", func(t *testing.T) {
+		dir := t.TempDir()
+		cwd, err := os.Getwd()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chdir(dir); err != nil {
+			t.Fatal(err)
+		}
+		defer os.Chdir(cwd)
+
+		WriteDebugLog("EVALUATION START")
+
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 0 {
+			t.Errorf("Expected no files to be created, got %d", len(entries))
+		}
+	})
+
+	t.Run("enabled appends to the configured file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "state", "debug.log")
+		if err := EnableDebugLog(path); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		defer EnableDebugLog("")
+
+		WriteDebugLog("first entry")
+		WriteDebugLog("second entry")
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Expected debug log to be written: %v", err)
+		}
+		if !strings.Contains(string(data), "first entry") || !strings.Contains(string(data), "second entry") {
+			t.Errorf("Expected both entries in the log, got:\n%s", data)
+		}
+	})
+}
+
+func TestDebugLogger_Rotates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "debug.log")
+	logger, err := NewDebugLogger(path, 100)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	for i := 0; i < 10; i++ {
+		logger.Write(strings.Repeat("x", 30))
+	}
+
+	for _, file := range []string{path, path + ".1"} {
+		stat, err := os.Stat(file)
+		if err != nil {
+			t.Fatalf("Expected %s to exist: %v", file, err)
+		}
+		if stat.Size() > 100 {
+			t.Errorf("Expected %s to stay under the 100 byte cap, got %d bytes", file, stat.Size())
+		}
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 2 {
+		t.Errorf("Expected only the log and one rotated file, got %d files", len(entries))
+	}
+}
+
+func TestDebugLogger_Nil(t *testing.T) {
+	var logger *DebugLogger
+	logger.Write("entry") // Must not panic
+}
+
+func TestSession_DebugLogStaysOutOfWorkingDirectory(t *testing.T) {
+	for _, debug := range []bool{false, true} {
+		name := "debug off"
+		if debug {
+			name = "debug on"
+		}
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			cwd, err := os.Getwd()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Chdir(dir); err != nil {
+				t.Fatal(err)
+			}
+			defer os.Chdir(cwd)
+
+			logPath := filepath.Join(t.TempDir(), "state", "debug.log")
+			if debug {
+				if err := EnableDebugLog(logPath); err != nil {
+					t.Fatalf("Expected no error, got: %v", err)
+				}
+				defer EnableDebugLog("")
+			}
+
+			server := fakeserver.NewOllama(t)
+			server.SetResponse("ls -la")
+			llmClient, err := llm.NewClient(config.LLMConfig{BaseURL: server.URL, Model: "test"}, config.TimeoutsConfig{})
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			llmClient.UseSystemInfoCache(system.NewCache("", time.Hour))
+			embeddingsClient, err := embeddings.NewClient(config.EmbeddingsConfig{BaseURL: server.URL, Model: "embed"}, config.TimeoutsConfig{})
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			chroma := fakeserver.NewChroma(t)
+			vectorStore, err := vector.NewChromaClient(config.VectorConfig{BaseURL: chroma.URL, Collection: "documents", CommandCollection: "command_history", AutoIndexCollection: "auto_indexed"}, config.TimeoutsConfig{})
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+
+			session := NewSessionWithDeps(&SessionConfig{NoHistory: true, AutoApprove: true, MaxAttempts: 2}, llmClient, nil, SessionDeps{
+				Executor:  &fakeCommander{},
+				Validator: NewCommandValidator(),
+				Evaluator: NewAIEvaluator(llmClient, embeddingsClient, vectorStore),
+				Context:   NewContextManager(contextEmbedder{}, &contextStore{requested: make(map[string]int)}),
+			})
+
+			withMockedInput("", func() {
+				session.HandlePrompt(context.Background(), "list files")
+			})
+
+			if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+				t.Errorf("Expected no files in the working directory, got %s", entries[0].Name())
+			}
+			data, err := os.ReadFile(logPath)
+			if debug && !strings.Contains(string(data), "EVALUATION START") {
+				t.Errorf("Expected the evaluation in the debug log, got %q (%v)", data, err)
+			}
+			if !debug && err == nil {
+				t.Error("Expected no debug log while debugging is off")
+			}
+		})
+	}
+}
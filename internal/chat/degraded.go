@@ -0,0 +1,80 @@
+package chat
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// ragRetryInterval is how long a session waits after an outage before trying
+// retrieval or session storage again, so a server that is down costs at most
+// one failed request per interval rather than one per prompt
+const ragRetryInterval = 30 * time.Second
+
+// serviceStatus tracks whether a service the session can do without, such as
+// ChromaDB, is working. An outage is logged once rather than on every prompt,
+// and the session notices when the service comes back. The zero value is a
+// working service.
+type serviceStatus struct {
+	name      string
+	now       func() time.Time // nil uses time.Now
+	mutex     sync.Mutex
+	down      bool
+	lastTried time.Time
+}
+
+func (s *serviceStatus) clock() time.Time {
+	if s.now == nil {
+		return time.Now()
+	}
+	return s.now()
+}
+
+// markDown records that the service could not be reached, as when it was
+// probed at startup, without logging anything
+func (s *serviceStatus) markDown() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.down = true
+	s.lastTried = s.clock()
+}
+
+// ready reports whether the service should be used now: it is up, or it has
+// been down for ragRetryInterval and should be tried again
+func (s *serviceStatus) ready() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.down && s.clock().Sub(s.lastTried) < ragRetryInterval {
+		return false
+	}
+	s.lastTried = s.clock()
+	return true
+}
+
+// failed records that using the service failed with err. The first failure
+// of an outage is logged as a warning, later ones only at debug level.
+func (s *serviceStatus) failed(message string, err error) {
+	s.mutex.Lock()
+	wasDown := s.down
+	s.down = true
+	s.lastTried = s.clock()
+	s.mutex.Unlock()
+
+	if wasDown {
+		slog.Debug(message, "component", "chat", "error", err)
+		return
+	}
+	slog.Warn(message, "component", "chat", "error", err)
+}
+
+// succeeded records that using the service worked and returns a message to
+// show when it had been down, or "" otherwise
+func (s *serviceStatus) succeeded() string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if !s.down {
+		return ""
+	}
+	s.down = false
+	return s.name + " is working again"
+}
@@ -0,0 +1,97 @@
+package chat
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"rag-cli/internal/httpclient"
+)
+
+// flakyRetriever fails as an unreachable server would while down is set
+type flakyRetriever struct {
+	down  bool
+	calls int
+}
+
+func (f *flakyRetriever) GetCombinedContext(ctx context.Context, prompt string, includeHistory bool, maxDocuments, maxHistory int) ([]ContextItem, error) {
+	f.calls++
+	if f.down {
+		return nil, fmt.Errorf("failed to query: %w", httpclient.ErrUnreachable)
+	}
+	return []ContextItem{{Text: "doc"}}, nil
+}
+
+func TestSession_RAGOutage(t *testing.T) {
+	var logs bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelWarn})))
+
+	now := time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)
+	retriever := &flakyRetriever{down: true}
+	evaluator := &fakeEvaluator{}
+	session := NewSessionWithDeps(&SessionConfig{RAGUnavailable: true}, nil, nil, SessionDeps{
+		Context:   retriever,
+		Evaluator: evaluator,
+	})
+	session.rag.now = func() time.Time { return now }
+	session.rag.lastTried = now
+
+	retrieve := func() []ContextItem {
+		t.Helper()
+		docs, err := session.retrieveContext(context.Background(), "list files")
+		if err != nil {
+			t.Fatalf("Expected the prompt to go ahead, got: %v", err)
+		}
+		return docs
+	}
+
+	// Down at startup: nothing is tried until the retry interval has passed
+	if docs := retrieve(); len(docs) != 0 || retriever.calls != 0 {
+		t.Errorf("Expected no retrieval right after startup, got %v after %d calls", docs, retriever.calls)
+	}
+	session.storeExecutionSession("log")
+	if len(evaluator.stored) != 0 {
+		t.Error("Expected the session not to be stored while ChromaDB is down")
+	}
+
+	now = now.Add(ragRetryInterval)
+	if docs := retrieve(); len(docs) != 0 || retriever.calls != 1 {
+		t.Errorf("Expected one retry with no context, got %v after %d calls", docs, retriever.calls)
+	}
+	now = now.Add(ragRetryInterval / 2)
+	retrieve()
+	if retriever.calls != 1 {
+		t.Errorf("Expected no retry within the interval, got %d calls", retriever.calls)
+	}
+	if logs.Len() != 0 {
+		t.Errorf("Expected the outage reported at startup not to be logged again, got: %s", logs.String())
+	}
+
+	// ChromaDB comes back
+	retriever.down = false
+	now = now.Add(ragRetryInterval)
+	if docs := retrieve(); len(docs) != 1 {
+		t.Errorf("Expected context once ChromaDB is back, got %v", docs)
+	}
+	if notices := session.notices.take(); len(notices) != 1 || !strings.Contains(notices[0], "Context retrieval is working again") {
+		t.Errorf("Expected a notice that retrieval is back, got %v", notices)
+	}
+	session.storeExecutionSession("log")
+	if len(evaluator.stored) != 1 {
+		t.Error("Expected the session to be stored once ChromaDB is back")
+	}
+
+	// A new outage is logged once
+	retriever.down = true
+	retrieve()
+	now = now.Add(ragRetryInterval)
+	retrieve()
+	if count := strings.Count(logs.String(), "level=WARN"); count != 1 || !strings.Contains(logs.String(), "failed to retrieve context") {
+		t.Errorf("Expected a single warning for the outage, got: %s", logs.String())
+	}
+}
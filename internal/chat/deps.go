@@ -0,0 +1,46 @@
+package chat
+
+import "context"
+
+// Commander runs the commands a session has approved
+type Commander interface {
+	Execute(cmdStr string) (ExecutionResult, error)
+	ExecuteContext(ctx context.Context, cmdStr string) (ExecutionResult, error)
+	Safety() *SafetyChecker
+}
+
+// WorkingDirCommander is a Commander that keeps the working directory a
+// command moves to with cd for the commands after it, as CommandExecutor does
+type WorkingDirCommander interface {
+	Commander
+	WorkingDir() string
+}
+
+// CommandParser extracts runnable commands from a model response
+type CommandParser interface {
+	ParseCommands(response string) []string
+}
+
+// Evaluator judges command results, plans what to run next, and records
+// finished sessions
+type Evaluator interface {
+	EvaluateAndGetNextCommands(ctx context.Context, executionLog string, originalRequest string, remainingCommands []string, hadError bool) ([]string, bool, error)
+	GenerateFinalAnswer(ctx context.Context, executionLog, originalRequest string) (string, error)
+	StoreExecutionSession(executionLog string) error
+}
+
+// ContextRetriever finds documents and past sessions relevant to a prompt
+type ContextRetriever interface {
+	GetCombinedContext(ctx context.Context, prompt string, includeHistory bool, maxDocuments, maxHistory int) ([]ContextItem, error)
+}
+
+// SessionDeps are the collaborators a Session delegates to. NewSession wires
+// up the real ones; tests substitute fakes with NewSessionWithDeps.
+type SessionDeps struct {
+	Executor  Commander
+	Validator CommandParser
+	Evaluator Evaluator
+	Context   ContextRetriever
+	// Approve asks the user whether a command may run; nil prompts on stdin
+	Approve func(command string) bool
+}
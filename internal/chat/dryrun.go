@@ -0,0 +1,59 @@
+package chat
+
+import (
+	"fmt"
+	"strings"
+)
+
+// proposedStatus marks the commands of a dry run in a PromptResult, which
+// were shown but not run
+const proposedStatus = "proposed"
+
+// dryRunPlan describes the commands in a response without running them:
+// what each does, when that is known, and how the safety rules grade it,
+// ending with how many were proposed
+func (s *Session) dryRunPlan(commands []string) string {
+	var b strings.Builder
+	b.WriteString("Dry run: the model proposes the following command(s):\n")
+	for i, command := range commands {
+		fmt.Fprintf(&b, "\n%d. $ %s\n", i+1, command)
+		if explanation := s.generateCommandExplanation(command); explanation != "" {
+			fmt.Fprintf(&b, "   %s\n", explanation)
+		}
+		fmt.Fprintf(&b, "   Risk: %s\n", s.riskLabel(command))
+	}
+	fmt.Fprintf(&b, "\n%d command(s) proposed, none executed", len(commands))
+	return b.String()
+}
+
+// riskLabel describes how the safety rules grade command, such as "safe",
+// "caution - deletes files" or "blocked - formats a disk"
+func (s *Session) riskLabel(command string) string {
+	verdict := s.safety().Classify(command)
+	level := verdict.Risk.String()
+	if verdict.Action == SafetyBlock {
+		level = "blocked"
+	}
+	if verdict.Reason == "" {
+		return level
+	}
+	return level + " - " + verdict.Reason
+}
+
+// recordProposed adds the commands of a dry run to the result of the prompt
+// being handled, if one is being collected
+func (s *Session) recordProposed(commands []string) {
+	if s.result == nil {
+		return
+	}
+	s.result.Proposed = commands
+	for _, command := range commands {
+		s.result.Commands = append(s.result.Commands, CommandResult{
+			Command:     command,
+			ExitCode:    -1,
+			Status:      proposedStatus,
+			Risk:        s.riskLabel(command),
+			Explanation: s.generateCommandExplanation(command),
+		})
+	}
+}
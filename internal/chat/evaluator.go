@@ -1,6 +1,7 @@
 package chat
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"time"
@@ -8,6 +9,7 @@ import (
 	"rag-cli/internal/embeddings"
 	"rag-cli/internal/llm"
 	"rag-cli/internal/vector"
+	"rag-cli/pkg/config"
 )
 
 // AIEvaluator handles AI decision making for command execution
@@ -27,33 +29,33 @@ func NewAIEvaluator(llmClient *llm.Client, embeddingsClient *embeddings.Client,
 }
 
 // EvaluateAndGetNextCommands asks AI to evaluate command results using structured decision-making
-func (e *AIEvaluator) EvaluateAndGetNextCommands(executionLog string, originalRequest string, remainingCommands []string, hadError bool) ([]string, bool, error) {
+func (e *AIEvaluator) EvaluateAndGetNextCommands(ctx context.Context, executionLog string, originalRequest string, remainingCommands []string, hadError bool) ([]string, bool, error) {
 	// Debug log the evaluation start
-	WriteDebugLog("evaluation_debug.log", fmt.Sprintf("EVALUATION START:\nOriginal Request: %s\nHad Error: %t\nRemaining Commands: %v\nExecution Log: %s\n\n", originalRequest, hadError, remainingCommands, executionLog))
+	WriteDebugLog(fmt.Sprintf("EVALUATION START:\nOriginal Request: %s\nHad Error: %t\nRemaining Commands: %v\nExecution Log: %s\n\n", originalRequest, hadError, remainingCommands, executionLog))
 
 	// Step 1: Check if the original goal has been achieved
-	goalAchieved, err := e.checkGoalAchievement(executionLog, originalRequest)
+	goalAchieved, err := e.checkGoalAchievement(ctx, executionLog, originalRequest)
 	if err != nil {
-		WriteDebugLog("evaluation_debug.log", fmt.Sprintf("Goal achievement check failed: %v\n", err))
+		WriteDebugLog(fmt.Sprintf("Goal achievement check failed: %v\n", err))
 		return nil, false, fmt.Errorf("failed to check goal achievement: %w", err)
 	}
 
 	if goalAchieved {
-		WriteDebugLog("evaluation_debug.log", "Goal achieved! Stopping execution.\n\n")
+		WriteDebugLog("Goal achieved! Stopping execution.\n\n")
 		return nil, false, nil
 	}
 
 	// Step 2: If goal not achieved, determine next steps based on current state
 	if len(remainingCommands) == 0 {
 		// Step 3: No commands queued - determine what to do next
-		nextCommands, err := e.determineNextCommands(executionLog, originalRequest, hadError)
+		nextCommands, err := e.determineNextCommands(ctx, executionLog, originalRequest, hadError)
 		if err != nil {
 			return nil, false, fmt.Errorf("failed to determine next commands: %w", err)
 		}
 		return nextCommands, len(nextCommands) > 0, nil
 	} else {
 		// Step 4: Commands queued - decide whether to proceed or modify
-		queueDecision, newCommands, err := e.evaluateCommandQueue(executionLog, originalRequest, remainingCommands, hadError)
+		queueDecision, newCommands, err := e.evaluateCommandQueue(ctx, executionLog, originalRequest, remainingCommands, hadError)
 		if err != nil {
 			return nil, false, fmt.Errorf("failed to evaluate command queue: %w", err)
 		}
@@ -71,8 +73,19 @@ func (e *AIEvaluator) EvaluateAndGetNextCommands(executionLog string, originalRe
 	}
 }
 
-// checkGoalAchievement determines if the original user request has been satisfied
-func (e *AIEvaluator) checkGoalAchievement(executionLog, originalRequest string) (bool, error) {
+// promptText returns the configured template for a prompt rendered with
+// data, or builtIn when none is configured
+func (e *AIEvaluator) promptText(name string, data config.PromptData, builtIn string) (string, error) {
+	tmpl := e.llmClient.Prompt(name)
+	if tmpl == nil {
+		return builtIn, nil
+	}
+	return config.RenderPrompt(tmpl, data)
+}
+
+// goalCheckPrompt is the built-in goal_check prompt, which must be answered
+// with YES or NO
+func goalCheckPrompt(executionLog, originalRequest string) string {
 	var prompt strings.Builder
 	prompt.WriteString("Analyze whether the user's original request has been successfully completed.\n\n")
 	prompt.WriteString("Original request: ")
@@ -94,39 +107,11 @@ func (e *AIEvaluator) checkGoalAchievement(executionLog, originalRequest string)
 	prompt.WriteString("\nDo NOT explain your reasoning. Do NOT repeat the command. Just answer YES or NO.\n")
 	prompt.WriteString("\nHas the original request been successfully completed? Answer: ")
 
-	// Debug log the goal achievement evaluation
-	WriteDebugLog("evaluation_debug.log", fmt.Sprintf("GOAL ACHIEVEMENT CHECK:\nPrompt: %s\n", prompt.String()))
-
-	response, err := e.llmClient.GenerateResponse(prompt.String(), nil)
-	if err != nil {
-		WriteDebugLog("evaluation_debug.log", fmt.Sprintf("Goal achievement error: %v\n", err))
-		return false, err
-	}
-
-	// Clean and parse the response more robustly
-	cleanResponse := strings.TrimSpace(strings.ToUpper(response))
-	
-	// Check for various ways the AI might say yes
-	result := cleanResponse == "YES" || 
-	         cleanResponse == "Y" ||
-	         strings.Contains(cleanResponse, "YES") ||
-	         strings.Contains(cleanResponse, "ACHIEVED") ||
-	         strings.Contains(cleanResponse, "COMPLETED") ||
-	         strings.Contains(cleanResponse, "SUCCESS")
-	         
-	// Special case: if it's a time question and we have date output, assume success
-	if strings.Contains(strings.ToLower(originalRequest), "time") && strings.Contains(executionLog, "$ date") && !strings.Contains(executionLog, "Error:") {
-		result = true
-		WriteDebugLog("evaluation_debug.log", fmt.Sprintf("Goal achievement response: '%s' -> Overriding to true for time question with successful date command\n\n", response))
-	} else {
-		WriteDebugLog("evaluation_debug.log", fmt.Sprintf("Goal achievement response: '%s' -> Result: %t\n\n", response, result))
-	}
-
-	return result, nil
+	return prompt.String()
 }
 
-// determineNextCommands decides what commands to execute next when none are queued
-func (e *AIEvaluator) determineNextCommands(executionLog, originalRequest string, hadError bool) ([]string, error) {
+// nextCommandsPrompt is the built-in next_commands prompt
+func nextCommandsPrompt(executionLog, originalRequest string, hadError bool) string {
 	var prompt strings.Builder
 	prompt.WriteString("You need to determine the next steps to achieve the user's goal.\n\n")
 	prompt.WriteString("Original user request: ")
@@ -143,22 +128,12 @@ func (e *AIEvaluator) determineNextCommands(executionLog, originalRequest string
 	prompt.WriteString("\nProvide the next commands to execute, one per line. ")
 	prompt.WriteString("If no more commands are needed, respond with 'NONE'.")
 
-	response, err := e.llmClient.GenerateResponse(prompt.String(), nil)
-	if err != nil {
-		return nil, err
-	}
-
-	response = strings.TrimSpace(response)
-	if response == "NONE" || response == "" {
-		return nil, nil
-	}
-
-	validator := NewCommandValidator()
-	return validator.ParseCommands(response), nil
+	return prompt.String()
 }
 
-// evaluateCommandQueue decides whether to proceed with planned commands or modify the plan
-func (e *AIEvaluator) evaluateCommandQueue(executionLog string, originalRequest string, remainingCommands []string, hadError bool) (string, []string, error) {
+// queueDecisionPrompt is the built-in queue_decision prompt, which must be
+// answered with PROCEED, MODIFY, or STOP
+func queueDecisionPrompt(executionLog, originalRequest string, remainingCommands []string, hadError bool) string {
 	var prompt strings.Builder
 	prompt.WriteString("You need to decide whether to proceed with the planned commands or modify the plan.\n\n")
 	prompt.WriteString("Original user request: ")
@@ -186,7 +161,93 @@ func (e *AIEvaluator) evaluateCommandQueue(executionLog string, originalRequest
 	prompt.WriteString("- 'MODIFY' followed by new commands (one per line) to replace the plan\n")
 	prompt.WriteString("- 'STOP' if no more commands are needed\n")
 
-	response, err := e.llmClient.GenerateResponse(prompt.String(), nil)
+	return prompt.String()
+}
+
+// finalAnswerPrompt is the built-in final_answer prompt
+func finalAnswerPrompt(executionLog, originalRequest string) string {
+	var prompt strings.Builder
+	prompt.WriteString("You are answering a user's question based on command output. DO NOT repeat commands or technical output.\n\n")
+	prompt.WriteString("User asked: ")
+	prompt.WriteString(originalRequest)
+	prompt.WriteString("\n\nCommand output:\n")
+	prompt.WriteString(executionLog)
+	prompt.WriteString("\n\nIMPORTANT: You must provide a conversational answer in plain English. Do NOT just repeat the command name.\n")
+	prompt.WriteString("Examples of good answers:\n")
+	prompt.WriteString("- For 'what time is it?' with date output → 'The current time is 12:08 AM.'\n")
+	prompt.WriteString("- For 'what files are here?' with ls output → 'There are 5 files: file1.txt, file2.py, etc.'\n")
+	prompt.WriteString("\nYour answer (complete sentence, no commands): ")
+
+	return prompt.String()
+}
+
+// checkGoalAchievement determines if the original user request has been satisfied
+func (e *AIEvaluator) checkGoalAchievement(ctx context.Context, executionLog, originalRequest string) (bool, error) {
+	text, err := e.promptText(config.PromptGoalCheck, config.PromptData{Request: originalRequest, ExecutionLog: executionLog}, goalCheckPrompt(executionLog, originalRequest))
+	if err != nil {
+		return false, err
+	}
+
+	// Debug log the goal achievement evaluation
+	WriteDebugLog(fmt.Sprintf("GOAL ACHIEVEMENT CHECK:\nPrompt: %s\n", text))
+
+	response, err := e.llmClient.GenerateResponseContext(ctx, text, nil)
+	if err != nil {
+		WriteDebugLog(fmt.Sprintf("Goal achievement error: %v\n", err))
+		return false, err
+	}
+
+	// Clean and parse the response more robustly
+	cleanResponse := strings.TrimSpace(strings.ToUpper(response))
+	
+	// Check for various ways the AI might say yes
+	result := cleanResponse == "YES" || 
+	         cleanResponse == "Y" ||
+	         strings.Contains(cleanResponse, "YES") ||
+	         strings.Contains(cleanResponse, "ACHIEVED") ||
+	         strings.Contains(cleanResponse, "COMPLETED") ||
+	         strings.Contains(cleanResponse, "SUCCESS")
+	         
+	// Special case: if it's a time question and we have date output, assume success
+	if strings.Contains(strings.ToLower(originalRequest), "time") && strings.Contains(executionLog, "$ date") && !strings.Contains(executionLog, "Error:") {
+		result = true
+		WriteDebugLog(fmt.Sprintf("Goal achievement response: '%s' -> Overriding to true for time question with successful date command\n\n", response))
+	} else {
+		WriteDebugLog(fmt.Sprintf("Goal achievement response: '%s' -> Result: %t\n\n", response, result))
+	}
+
+	return result, nil
+}
+
+// determineNextCommands decides what commands to execute next when none are queued
+func (e *AIEvaluator) determineNextCommands(ctx context.Context, executionLog, originalRequest string, hadError bool) ([]string, error) {
+	text, err := e.promptText(config.PromptNextCommands, config.PromptData{Request: originalRequest, ExecutionLog: executionLog, HadError: hadError}, nextCommandsPrompt(executionLog, originalRequest, hadError))
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := e.llmClient.GenerateResponseContext(ctx, text, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	response = strings.TrimSpace(response)
+	if response == "NONE" || response == "" {
+		return nil, nil
+	}
+
+	validator := NewCommandValidator()
+	return validator.ParseCommands(response), nil
+}
+
+// evaluateCommandQueue decides whether to proceed with planned commands or modify the plan
+func (e *AIEvaluator) evaluateCommandQueue(ctx context.Context, executionLog string, originalRequest string, remainingCommands []string, hadError bool) (string, []string, error) {
+	text, err := e.promptText(config.PromptQueueDecision, config.PromptData{Request: originalRequest, ExecutionLog: executionLog, RemainingCommands: remainingCommands, HadError: hadError}, queueDecisionPrompt(executionLog, originalRequest, remainingCommands, hadError))
+	if err != nil {
+		return "", nil, err
+	}
+
+	response, err := e.llmClient.GenerateResponseContext(ctx, text, nil)
 	if err != nil {
 		return "", nil, err
 	}
@@ -203,8 +264,7 @@ func (e *AIEvaluator) evaluateCommandQueue(executionLog string, originalRequest
 	case "MODIFY":
 		validator := NewCommandValidator()
 		var newCommands []string
-		for i := 1; i < len(lines); i++ {
-			cmd := strings.TrimSpace(lines[i])
+		for _, cmd := range commandBlocks(strings.Join(lines[1:], "\n")) {
 			if cmd != "" && !strings.HasPrefix(cmd, "#") && validator.IsValid(cmd) {
 				newCommands = append(newCommands, cmd)
 			}
@@ -217,18 +277,30 @@ func (e *AIEvaluator) evaluateCommandQueue(executionLog string, originalRequest
 
 // StoreExecutionSession stores the command execution session in ChromaDB for future learning
 func (e *AIEvaluator) StoreExecutionSession(executionLog string) error {
+	return RecordExecution(e.embeddingsClient, e.vectorStore, executionLog, nil)
+}
+
+// RecordExecution stores an execution log in the commands collection so later
+// sessions can retrieve it as history. Metadata such as "success" is stored
+// alongside when given.
+func RecordExecution(embedder embeddings.Embedder, store vector.VectorStore, executionLog string, metadata map[string]interface{}) error {
 	// Create a summary of the execution session
 	summary := fmt.Sprintf("Command execution session:\n%s", executionLog)
 
 	// Generate embedding for the execution session
-	embedding, err := e.embeddingsClient.GenerateEmbedding(summary)
+	embedding, err := embedder.GenerateEmbedding(summary)
 	if err != nil {
 		return fmt.Errorf("failed to generate embedding for execution session: %w", err)
 	}
 
 	// Store in ChromaDB with a unique ID
 	sessionID := fmt.Sprintf("cmd_session_%d", time.Now().Unix())
-	if err := e.vectorStore.AddDocument(e.vectorStore.CommandsCollection(), sessionID, summary, embedding); err != nil {
+	if metadata == nil {
+		err = store.AddDocument(store.CommandsCollection(), sessionID, summary, embedding)
+	} else {
+		err = store.AddDocumentWithMetadata(store.CommandsCollection(), sessionID, summary, embedding, metadata)
+	}
+	if err != nil {
 		return fmt.Errorf("failed to store execution session: %w", err)
 	}
 
@@ -236,7 +308,7 @@ func (e *AIEvaluator) StoreExecutionSession(executionLog string) error {
 }
 
 // GenerateFinalAnswer creates a human-readable final answer based on the conversation
-func (e *AIEvaluator) GenerateFinalAnswer(executionLog, originalRequest string) (string, error) {
+func (e *AIEvaluator) GenerateFinalAnswer(ctx context.Context, executionLog, originalRequest string) (string, error) {
 	// Special handling for time questions with simple pattern matching
 	if strings.Contains(strings.ToLower(originalRequest), "time") && strings.Contains(executionLog, "$ date") {
 		// Extract the date output from the execution log
@@ -298,29 +370,22 @@ func (e *AIEvaluator) GenerateFinalAnswer(executionLog, originalRequest string)
 		}
 	}
 
-	var prompt strings.Builder
-	prompt.WriteString("You are answering a user's question based on command output. DO NOT repeat commands or technical output.\n\n")
-	prompt.WriteString("User asked: ")
-	prompt.WriteString(originalRequest)
-	prompt.WriteString("\n\nCommand output:\n")
-	prompt.WriteString(executionLog)
-	prompt.WriteString("\n\nIMPORTANT: You must provide a conversational answer in plain English. Do NOT just repeat the command name.\n")
-	prompt.WriteString("Examples of good answers:\n")
-	prompt.WriteString("- For 'what time is it?' with date output → 'The current time is 12:08 AM.'\n")
-	prompt.WriteString("- For 'what files are here?' with ls output → 'There are 5 files: file1.txt, file2.py, etc.'\n")
-	prompt.WriteString("\nYour answer (complete sentence, no commands): ")
+	text, err := e.promptText(config.PromptFinalAnswer, config.PromptData{Request: originalRequest, ExecutionLog: executionLog}, finalAnswerPrompt(executionLog, originalRequest))
+	if err != nil {
+		return "", err
+	}
 
 	// Debug log the final answer generation
-	WriteDebugLog("evaluation_debug.log", fmt.Sprintf("FINAL ANSWER GENERATION:\nPrompt: %s\n", prompt.String()))
+	WriteDebugLog(fmt.Sprintf("FINAL ANSWER GENERATION:\nPrompt: %s\n", text))
 
-	response, err := e.llmClient.GenerateResponse(prompt.String(), nil)
+	response, err := e.llmClient.GenerateResponseContext(ctx, text, nil)
 	if err != nil {
-		WriteDebugLog("evaluation_debug.log", fmt.Sprintf("Final answer generation error: %v\n", err))
+		WriteDebugLog(fmt.Sprintf("Final answer generation error: %v\n", err))
 		return "", err
 	}
 
 	finalAnswer := strings.TrimSpace(response)
-	WriteDebugLog("evaluation_debug.log", fmt.Sprintf("Final answer response: '%s'\n\n", finalAnswer))
+	WriteDebugLog(fmt.Sprintf("Final answer response: '%s'\n\n", finalAnswer))
 
 	return finalAnswer, nil
 }
@@ -0,0 +1,31 @@
+package chat
+
+import (
+	"path/filepath"
+	"testing"
+
+	"rag-cli/internal/golden"
+)
+
+// executionLog is a fixed execution log rendered into the evaluator prompts
+const executionLog = "$ ls -la\ntotal 8\n-rw-r--r--  1 user  staff  12 Jan  2 10:00 notes.txt\n\n$ wc -l notes.txt\n       1 notes.txt\n"
+
+func TestEvaluatorPrompts_Golden(t *testing.T) {
+	tests := []struct {
+		name   string
+		prompt string
+	}{
+		{name: "goal_check", prompt: goalCheckPrompt(executionLog, "how many lines are in notes.txt?")},
+		{name: "next_commands_after_success", prompt: nextCommandsPrompt(executionLog, "count the lines in every text file", false)},
+		{name: "next_commands_after_error", prompt: nextCommandsPrompt(executionLog+"Error: exit status 1\n", "count the lines in every text file", true)},
+		{name: "queue_decision_after_success", prompt: queueDecisionPrompt(executionLog, "count the lines in every text file", []string{"wc -l *.md", "du -sh ."}, false)},
+		{name: "queue_decision_after_error", prompt: queueDecisionPrompt(executionLog+"Error: exit status 1\n", "count the lines in every text file", []string{"wc -l *.md"}, true)},
+		{name: "final_answer", prompt: finalAnswerPrompt(executionLog, "how many lines are in notes.txt?")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			golden.Assert(t, filepath.Join("testdata", "prompts", tt.name+".golden"), tt.prompt)
+		})
+	}
+}
@@ -2,53 +2,242 @@ package chat
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"os/exec"
 	"strings"
+	"sync"
+	"time"
 )
 
+// ErrCommandFailed is returned when a command exits with a non-zero status or
+// cannot be started
+var ErrCommandFailed = errors.New("command failed")
+
+// ErrCommandTimeout is returned, wrapped in ErrCommandFailed, when a command
+// or a step of a pipe runs past the executor's timeout and is killed
+var ErrCommandTimeout = errors.New("command timed out")
+
 // CommandExecutor handles the execution of shell commands with proper pipe handling
-type CommandExecutor struct{}
+type CommandExecutor struct {
+	safety  *SafetyChecker
+	timeout time.Duration // Longest a command or pipe step may run, 0 for no limit
+	stateMu sync.Mutex
+	state   shellState // Working directory and environment left by earlier commands
+}
+
+// NewCommandExecutor creates a command executor that refuses commands
+// blocked by safety, or by the built-in rules when safety is nil. safety
+// then grades commands as running wherever cd has left this executor.
+func NewCommandExecutor(safety *SafetyChecker) *CommandExecutor {
+	if safety == nil {
+		safety = NewSafetyChecker()
+	}
+	e := &CommandExecutor{safety: safety}
+	safety.UseWorkingDir(e.WorkingDir)
+	return e
+}
+
+// UseTimeout limits how long a command, or each step of a pipe, may run
+// before it is killed. 0, the default, lets commands run until they finish.
+func (e *CommandExecutor) UseTimeout(timeout time.Duration) {
+	e.timeout = timeout
+}
+
+// Safety returns the checker that decides which commands may run. A
+// session built without an executor gets the built-in rules.
+func (e *CommandExecutor) Safety() *SafetyChecker {
+	if e == nil || e.safety == nil {
+		return NewSafetyChecker()
+	}
+	return e.safety
+}
+
+// ExecutionResult describes a finished command. Stdout is exactly what the
+// command wrote there, so it can be read by whatever comes next; what it
+// wrote to stderr is kept apart for the session to show and log as it sees
+// fit.
+type ExecutionResult struct {
+	Command  string
+	Stdout   string       // For a pipe run step by step, the last step's
+	Stderr   string       // For a pipe, every step's in turn
+	ExitCode int          // -1 when the command could not be started or was stopped
+	Duration time.Duration
+	Steps    []StepResult // The steps of a pipe run step by step, up to the one that failed; nil for other commands
+	Err      error        // The error Execute returns with the result
+}
 
-// NewCommandExecutor creates a new command executor
-func NewCommandExecutor() *CommandExecutor {
-	return &CommandExecutor{}
+// StepResult is one step of a pipe
+type StepResult struct {
+	Command  string
+	Stdout   string // Passed to the next step
+	Stderr   string
+	ExitCode int
 }
 
-// Execute runs a shell command and returns its output
+// Succeeded reports whether the command exited with status 0
+func (r ExecutionResult) Succeeded() bool {
+	return r.Err == nil
+}
+
+// Run executes a command like Execute, for callers that read the error from
+// the result
+func (e *CommandExecutor) Run(cmdStr string) ExecutionResult {
+	result, _ := e.Execute(cmdStr)
+	return result
+}
+
+// Execute runs a shell command and returns what it wrote and how it exited.
 // If the command contains pipes, it splits and executes each part separately
 // to provide better visibility into intermediate outputs
-func (e *CommandExecutor) Execute(cmdStr string) (string, error) {
-	// Check if command contains pipes
-	if strings.Contains(cmdStr, " | ") {
-		return e.executePipedCommand(cmdStr)
+func (e *CommandExecutor) Execute(cmdStr string) (ExecutionResult, error) {
+	return e.ExecuteContext(context.Background(), cmdStr)
+}
+
+// ExecuteContext is Execute with a context that kills the running command when
+// it is cancelled
+func (e *CommandExecutor) ExecuteContext(ctx context.Context, cmdStr string) (ExecutionResult, error) {
+	start := time.Now()
+	var result ExecutionResult
+	err := e.Safety().Check(cmdStr)
+	if err == nil {
+		// Check if command is a pipeline. Anything more, such as a list
+		// joined with && or a heredoc, goes to the shell whole.
+		if len(pipeStages(cmdStr)) > 1 {
+			result, err = e.executePipedCommand(ctx, cmdStr)
+		} else {
+			result, err = e.runWhole(ctx, cmdStr)
+		}
 	}
-	
-	// Simple command execution
-	cmd := exec.Command("sh", "-c", cmdStr)
-	output, err := cmd.CombinedOutput()
+	result.Command = cmdStr
+	result.ExitCode = exitCode(err)
+	result.Duration = time.Since(start)
+	result.Err = err
+	return result, err
+}
+
+// exitCode returns the status a command that failed with err exited with, 0
+// when err is nil, or -1 when it did not exit by itself
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return -1
+}
+
+// runWhole runs cmdStr under the executor's timeout and returns its stdout
+// and stderr. The working directory and variables it leaves are kept for
+// later commands.
+func (e *CommandExecutor) runWhole(ctx context.Context, cmdStr string) (ExecutionResult, error) {
+	stepCtx, cancel := e.stepContext(ctx)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	script, keepState := e.captureState(cmdStr)
+	cmd := e.shellCommand(stepCtx, script)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	keepState()
+
+	result := ExecutionResult{Stdout: stdout.String(), Stderr: stderr.String()}
 	if err != nil {
-		return string(output), fmt.Errorf("command failed: %w", err)
+		return result, fmt.Errorf("%w: %w", ErrCommandFailed, e.stepError(ctx, stepCtx, err))
+	}
+	return result, nil
+}
+
+// stepContext returns the context for running one command or pipe step,
+// which expires after the executor's timeout
+func (e *CommandExecutor) stepContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if e.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, e.timeout)
+}
+
+// stepError reports a step killed by its own timeout as ErrCommandTimeout,
+// so it can be told apart from a command that failed, and returns other
+// errors, including cancellation of ctx, unchanged
+func (e *CommandExecutor) stepError(ctx, stepCtx context.Context, err error) error {
+	if ctx.Err() == nil && errors.Is(stepCtx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w after %s", ErrCommandTimeout, e.timeout)
+	}
+	return err
+}
+
+// pipeStages splits cmd at the pipes between its stages. A | inside quotes,
+// escaped with a backslash, or within $(...), (...), {...} or backquotes
+// does not end a stage, nor does the clobbering redirection >|. A command
+// that is more than a pipeline, because it also uses ;, &&, ||, & or |& or
+// spans lines, is returned as a single stage so the shell runs it whole.
+func pipeStages(cmd string) []string {
+	var stages []string
+	var quote rune
+	depth := 0
+	backquote := false
+	start := 0
+	runes := []rune(cmd)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		next := rune(0)
+		if i+1 < len(runes) {
+			next = runes[i+1]
+		}
+		switch {
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			}
+		case r == '\\':
+			i++
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+		case r == '`':
+			backquote = !backquote
+		case backquote:
+		case r == '(' || r == '{':
+			depth++
+		case r == ')' || r == '}':
+			depth--
+		case depth > 0:
+		case r == '&' && (next == '>' || i > 0 && (runes[i-1] == '>' || runes[i-1] == '<')):
+			// 2>&1, &> and the like are redirections
+		case r == ';' || r == '\n' || r == '&', r == '|' && (next == '|' || next == '&'):
+			return []string{cmd}
+		case r == '|' && i > 0 && runes[i-1] == '>':
+			// >| overwrites a file
+		case r == '|':
+			stages = append(stages, string(runes[start:i]))
+			start = i + 1
+		}
 	}
-	return string(output), nil
+	return append(stages, string(runes[start:]))
 }
 
-// executePipedCommand handles commands with pipes by executing each part separately
-func (e *CommandExecutor) executePipedCommand(cmdStr string) (string, error) {
+// executePipedCommand handles commands with pipes by executing each part
+// separately. Each step's stdout is passed to the next untouched; what the
+// steps write to stderr is gathered apart.
+func (e *CommandExecutor) executePipedCommand(ctx context.Context, cmdStr string) (ExecutionResult, error) {
 	// Split command on pipes
-	parts := strings.Split(cmdStr, " | ")
+	parts := pipeStages(cmdStr)
 	if len(parts) < 2 {
 		// Fallback to normal execution if split didn't work as expected
-		cmd := exec.Command("sh", "-c", cmdStr)
-		output, err := cmd.CombinedOutput()
-		if err != nil {
-			return string(output), fmt.Errorf("command failed: %w", err)
-		}
-		return string(output), nil
+		return e.runWhole(ctx, cmdStr)
 	}
 	
 	var currentInput []byte
-	var executionDetails strings.Builder
+	var result ExecutionResult
+	var allStderr strings.Builder // What every step wrote to stderr
 	
 	for i, part := range parts {
 		part = strings.TrimSpace(part)
@@ -56,8 +245,9 @@ func (e *CommandExecutor) executePipedCommand(cmdStr string) (string, error) {
 			continue
 		}
 		
-		// Create command
-		cmd := exec.Command("sh", "-c", part)
+		// Create command, with a timeout of its own
+		stepCtx, cancel := e.stepContext(ctx)
+		cmd := e.shellCommand(stepCtx, part)
 		
 		// If this is not the first command, pipe the previous output as input
 		if i > 0 && len(currentInput) > 0 {
@@ -69,52 +259,27 @@ func (e *CommandExecutor) executePipedCommand(cmdStr string) (string, error) {
 		cmd.Stdout = &stdout
 		cmd.Stderr = &stderr
 		err := cmd.Run()
+		if err != nil {
+			err = e.stepError(ctx, stepCtx, err)
+		}
+		cancel()
 		
 		output := stdout.Bytes()
-		stderrOutput := stderr.String()
+		allStderr.WriteString(stderr.String())
+		result.Steps = append(result.Steps, StepResult{Command: part, Stdout: string(output), Stderr: stderr.String(), ExitCode: exitCode(err)})
+		result.Stdout = string(output)
+		result.Stderr = allStderr.String()
 		
 		if err != nil {
-			// Log details of what succeeded before the failure
 			if i > 0 {
-				executionDetails.WriteString(fmt.Sprintf("Steps 1-%d succeeded. ", i))
-				executionDetails.WriteString(fmt.Sprintf("Step %d failed: %s", i+1, part))
-				if stderrOutput != "" {
-					executionDetails.WriteString(fmt.Sprintf(" (stderr: %s)", stderrOutput))
-				}
-				// Include the intermediate output that was successful
-				if len(currentInput) > 0 {
-					executionDetails.WriteString(fmt.Sprintf("\nIntermediate output from previous steps:\n%s", string(currentInput)))
-				}
-				return executionDetails.String(), fmt.Errorf("pipe step %d failed: %w", i+1, err)
-			} else {
-				// For first step failures, include stderr in the error output
-				errorOutput := string(output)
-				if stderrOutput != "" {
-					errorOutput += "\nstderr: " + stderrOutput
-				}
-				return errorOutput, fmt.Errorf("command failed: %w", err)
+				return result, fmt.Errorf("%w: pipe step %d failed: %w", ErrCommandFailed, i+1, err)
 			}
-		}
-		
-		// For successful commands, combine stdout and stderr (if stderr has content)
-		combinedOutput := output
-		if stderrOutput != "" {
-			// Include stderr output for successful commands as it may contain useful info
-			combinedOutput = append(output, []byte("\nstderr: "+stderrOutput)...)
+			return result, fmt.Errorf("%w: %w", ErrCommandFailed, err)
 		}
 		
 		// Store output for next command in the pipe (only stdout goes to next command)
 		currentInput = output
-		
-		// Log successful step (but don't include in final output unless it's the last step)
-		if i < len(parts)-1 {
-			executionDetails.WriteString(fmt.Sprintf("Step %d (%s): %d bytes of output\n", i+1, part, len(output)))
-		} else {
-			// For the last step, return combined output including stderr
-			return string(combinedOutput), nil
-		}
 	}
 	
-	// This shouldn't be reached, but return currentInput as fallback
-	return string(currentInput), nil
+	return result, nil
 }
@@ -1,20 +1,25 @@
 package chat
 
 import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestCommandExecutor_Execute(t *testing.T) {
-	executor := NewCommandExecutor()
+	executor := NewCommandExecutor(nil)
 	
 	t.Run("simple successful command", func(t *testing.T) {
 		output, err := executor.Execute("echo hello")
 		if err != nil {
 			t.Fatalf("Expected no error, got: %v", err)
 		}
-		if strings.TrimSpace(output) != "hello" {
-			t.Errorf("Expected 'hello', got: %q", strings.TrimSpace(output))
+		if strings.TrimSpace(output.Stdout) != "hello" {
+			t.Errorf("Expected 'hello', got: %q", strings.TrimSpace(output.Stdout))
 		}
 	})
 	
@@ -26,9 +31,12 @@ func TestCommandExecutor_Execute(t *testing.T) {
 		if !strings.Contains(err.Error(), "command failed") {
 			t.Errorf("Expected 'command failed' in error, got: %v", err)
 		}
-		// Output should contain stderr information
-		if !strings.Contains(output, "not found") && !strings.Contains(output, "command not found") {
-			t.Errorf("Expected stderr info in output, got: %q", output)
+		// The shell's complaint goes to stderr, leaving stdout empty
+		if !strings.Contains(output.Stderr, "not found") || output.Stdout != "" {
+			t.Errorf("Expected stderr info apart from stdout, got: %+v", output)
+		}
+		if output.ExitCode != 127 {
+			t.Errorf("Expected exit status 127, got %d", output.ExitCode)
 		}
 	})
 	
@@ -38,8 +46,8 @@ func TestCommandExecutor_Execute(t *testing.T) {
 			t.Fatalf("Expected no error, got: %v", err)
 		}
 		// wc -w should return "1" for one word
-		if !strings.Contains(strings.TrimSpace(output), "1") {
-			t.Errorf("Expected output to contain '1', got: %q", strings.TrimSpace(output))
+		if !strings.Contains(strings.TrimSpace(output.Stdout), "1") {
+			t.Errorf("Expected output to contain '1', got: %q", strings.TrimSpace(output.Stdout))
 		}
 	})
 	
@@ -70,40 +78,158 @@ func TestCommandExecutor_Execute(t *testing.T) {
 			t.Fatalf("Expected no error, got: %v", err)
 		}
 		// Should output "2" (two lines)
-		if !strings.Contains(strings.TrimSpace(output), "2") {
-			t.Errorf("Expected output to contain '2', got: %q", strings.TrimSpace(output))
+		if !strings.Contains(strings.TrimSpace(output.Stdout), "2") {
+			t.Errorf("Expected output to contain '2', got: %q", strings.TrimSpace(output.Stdout))
+		}
+	})
+
+	t.Run("multi-line command runs whole", func(t *testing.T) {
+		output, err := executor.Execute("cat <<EOF | wc -l\none | two\nthree\nEOF")
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if strings.TrimSpace(output.Stdout) != "2" {
+			t.Errorf("Expected the heredoc to be piped as written, got: %q", output.Stdout)
+		}
+	})
+}
+
+func TestCommandExecutor_SessionState(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to resolve temp dir: %v", err)
+	}
+
+	t.Run("cd carries over to the next command", func(t *testing.T) {
+		executor := NewCommandExecutor(nil)
+		if _, err := executor.Execute("cd " + shellQuote(dir)); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		output, err := executor.Execute("pwd")
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if strings.TrimSpace(output.Stdout) != dir || executor.WorkingDir() != dir {
+			t.Errorf("Expected commands to run in %s, got %q (working dir %q)", dir, output.Stdout, executor.WorkingDir())
+		}
+
+		// A failed cd leaves the directory as it was
+		executor.Execute("cd " + filepath.Join(dir, "missing"))
+		if executor.WorkingDir() != dir {
+			t.Errorf("Expected a failed cd to keep %s, got %q", dir, executor.WorkingDir())
 		}
 	})
+
+	t.Run("exported variables are visible later", func(t *testing.T) {
+		executor := NewCommandExecutor(nil)
+		if _, err := executor.Execute(`export FOO="bar baz" && echo set`); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		output, err := executor.Execute("echo $FOO")
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if strings.TrimSpace(output.Stdout) != "bar baz" {
+			t.Errorf("Expected the exported value, got %q", output.Stdout)
+		}
+
+		executor.Execute("unset FOO")
+		output, _ = executor.Execute(`echo "${FOO-unset}"`)
+		if strings.TrimSpace(output.Stdout) != "unset" {
+			t.Errorf("Expected unset to remove the variable, got %q", output.Stdout)
+		}
+	})
+
+	t.Run("exit status is kept", func(t *testing.T) {
+		result := NewCommandExecutor(nil).Run("export FOO=1; exit 3")
+		if result.ExitCode != 3 {
+			t.Errorf("Expected exit status 3, got %d", result.ExitCode)
+		}
+	})
+}
+
+func TestPipeStages(t *testing.T) {
+	tests := []struct {
+		command  string
+		expected []string
+	}{
+		{`ls -la`, []string{`ls -la`}},
+		{`ls | wc -l`, []string{`ls `, ` wc -l`}},
+		{`grep "a | b" file | wc -l`, []string{`grep "a | b" file `, ` wc -l`}},
+		{`awk '{print $1 " | " $2}' data.txt`, []string{`awk '{print $1 " | " $2}' data.txt`}},
+		{`echo a\|b | cat`, []string{`echo a\|b `, ` cat`}},
+		{`echo $(ls | head -1) | wc -c`, []string{`echo $(ls | head -1) `, ` wc -c`}},
+		{"echo `ls | head -1`", []string{"echo `ls | head -1`"}},
+		{`make test || echo failed`, []string{`make test || echo failed`}},
+		{`cd src && ls | wc -l`, []string{`cd src && ls | wc -l`}},
+		{`ls 2>&1 | wc -l`, []string{`ls 2>&1 `, ` wc -l`}},
+		{`ls >| out.txt`, []string{`ls >| out.txt`}},
+		{`ls |& wc -l`, []string{`ls |& wc -l`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			if got := pipeStages(tt.command); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("pipeStages(%q) = %q, expected %q", tt.command, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCommandExecutor_QuotedPipes(t *testing.T) {
+	executor := NewCommandExecutor(nil)
+	tests := []struct {
+		name     string
+		command  string
+		expected string
+	}{
+		{"pipe in double quotes", `echo "a | b" | wc -w`, "3"},
+		{"pipe in single quotes", `echo 'x|y' | tr '|' ' '`, "x y"},
+		{"logical or", `false || echo fallback`, "fallback"},
+		{"three stages with quoted arguments", `printf 'x|1\ny|2\nz|3\n' | grep -v "y|2" | wc -l`, "2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := executor.Execute(tt.command)
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if strings.TrimSpace(output.Stdout) != tt.expected {
+				t.Errorf("Expected %q, got: %q", tt.expected, strings.TrimSpace(output.Stdout))
+			}
+		})
+	}
 }
 
 func TestCommandExecutor_ExecutePipedCommand(t *testing.T) {
-	executor := NewCommandExecutor()
+	executor := NewCommandExecutor(nil)
 	
 	t.Run("command without pipes falls back to normal execution", func(t *testing.T) {
-		output, err := executor.executePipedCommand("echo hello")
+		output, err := executor.executePipedCommand(context.Background(), "echo hello")
 		if err != nil {
 			t.Fatalf("Expected no error, got: %v", err)
 		}
-		if strings.TrimSpace(output) != "hello" {
-			t.Errorf("Expected 'hello', got: %q", strings.TrimSpace(output))
+		if strings.TrimSpace(output.Stdout) != "hello" {
+			t.Errorf("Expected 'hello', got: %q", strings.TrimSpace(output.Stdout))
 		}
 	})
 	
 	t.Run("empty pipe parts are skipped", func(t *testing.T) {
 		// This has empty parts but should still work
-		output, err := executor.executePipedCommand("echo hello |  | wc -w")
+		output, err := executor.executePipedCommand(context.Background(), "echo hello |  | wc -w")
 		if err != nil {
 			t.Fatalf("Expected no error, got: %v", err)
 		}
-		if !strings.Contains(strings.TrimSpace(output), "1") {
-			t.Errorf("Expected output to contain '1', got: %q", strings.TrimSpace(output))
+		if !strings.Contains(strings.TrimSpace(output.Stdout), "1") {
+			t.Errorf("Expected output to contain '1', got: %q", strings.TrimSpace(output.Stdout))
 		}
 	})
 }
 
 // Test helper to verify error messages contain expected information
 func TestErrorMessageFormat(t *testing.T) {
-	executor := NewCommandExecutor()
+	executor := NewCommandExecutor(nil)
 	
 	t.Run("first step error includes stderr", func(t *testing.T) {
 		output, err := executor.Execute("ls /nonexistenttestdir123456")
@@ -111,11 +237,11 @@ func TestErrorMessageFormat(t *testing.T) {
 			t.Fatal("Expected error for nonexistent directory")
 		}
 		
-		// Check that output contains helpful error message
-		if !strings.Contains(output, "No such file or directory") &&
-		   !strings.Contains(output, "cannot access") &&
-		   !strings.Contains(output, "not found") {
-			t.Errorf("Expected helpful error message in output, got: %q", output)
+		// Check that stderr contains helpful error message
+		if !strings.Contains(output.Stderr, "No such file or directory") &&
+		   !strings.Contains(output.Stderr, "cannot access") &&
+		   !strings.Contains(output.Stderr, "not found") {
+			t.Errorf("Expected helpful error message in stderr, got: %q", output.Stderr)
 		}
 	})
 	
@@ -130,3 +256,144 @@ func TestErrorMessageFormat(t *testing.T) {
 		}
 	})
 }
+
+func TestCommandExecutor_Streams(t *testing.T) {
+	executor := NewCommandExecutor(nil)
+
+	tests := []struct {
+		name    string
+		command string
+		stdout  string
+		stderr  string
+		failed  bool
+	}{
+		{name: "both streams", command: "echo out; echo err >&2", stdout: "out\n", stderr: "err\n"},
+		{name: "pipe", command: "echo a | cat; echo last >&2", stdout: "a\n", stderr: "last\n"},
+		{name: "failure", command: "echo partial; echo broken >&2; exit 3", stdout: "partial\n", stderr: "broken\n", failed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := executor.ExecuteContext(context.Background(), tt.command)
+			if (err != nil) != tt.failed {
+				t.Fatalf("Expected failure %v, got: %v", tt.failed, err)
+			}
+			if output.Stdout != tt.stdout || output.Stderr != tt.stderr {
+				t.Errorf("Expected stdout %q and stderr %q, got %q and %q", tt.stdout, tt.stderr, output.Stdout, output.Stderr)
+			}
+		})
+	}
+}
+
+func TestCommandExecutor_PipeStderr(t *testing.T) {
+	executor := NewCommandExecutor(nil)
+
+	t.Run("stderr of an early step stays out of stdout", func(t *testing.T) {
+		output, err := executor.Execute(`sh -c 'echo progress >&2; echo "{\"count\": 2}"' | cat`)
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if output.Stdout != "{\"count\": 2}\n" {
+			t.Errorf("Expected only the JSON on stdout, got %q", output.Stdout)
+		}
+		if output.Stderr != "progress\n" {
+			t.Errorf("Expected the progress message on stderr, got %q", output.Stderr)
+		}
+		if len(output.Steps) != 2 || output.Steps[0].Stderr != "progress\n" || output.Steps[1].Stdout != output.Stdout {
+			t.Errorf("Expected each step to keep its own streams, got %+v", output.Steps)
+		}
+	})
+
+	t.Run("failed step is recorded", func(t *testing.T) {
+		output, err := executor.Execute("echo hello | nonexistentcommand12345 | wc -l")
+		if err == nil {
+			t.Fatal("Expected error for failed pipe")
+		}
+		if len(output.Steps) != 2 {
+			t.Fatalf("Expected the pipe to stop at step 2, got %+v", output.Steps)
+		}
+		if failed := output.Steps[1]; failed.ExitCode != 127 || !strings.Contains(failed.Stderr, "not found") {
+			t.Errorf("Expected step 2 to fail with exit status 127, got %+v", failed)
+		}
+		if output.ExitCode != 127 {
+			t.Errorf("Expected the pipe to exit with status 127, got %d", output.ExitCode)
+		}
+	})
+}
+
+func TestCommandExecutor_ExecuteContext(t *testing.T) {
+	executor := NewCommandExecutor(nil)
+
+	for _, command := range []string{"sleep 5", "echo start | sleep 5"} {
+		t.Run("cancels "+command, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+
+			start := time.Now()
+			_, err := executor.ExecuteContext(ctx, command)
+			if err == nil {
+				t.Fatal("Expected an error for a cancelled command")
+			}
+			if elapsed := time.Since(start); elapsed > 2*time.Second {
+				t.Errorf("Expected the command to be killed at the deadline, took %s", elapsed)
+			}
+			if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				t.Errorf("Expected the deadline to have passed, got: %v", ctx.Err())
+			}
+		})
+	}
+}
+
+func TestCommandExecutor_Timeout(t *testing.T) {
+	executor := NewCommandExecutor(nil)
+	executor.UseTimeout(100 * time.Millisecond)
+
+	tests := []struct {
+		command  string
+		expected string
+	}{
+		{"sleep 5", "command failed: command timed out after 100ms"},
+		{"echo start | sleep 5", "command failed: pipe step 2 failed: command timed out after 100ms"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			start := time.Now()
+			_, err := executor.Execute(tt.command)
+			if !errors.Is(err, ErrCommandTimeout) || !errors.Is(err, ErrCommandFailed) {
+				t.Fatalf("Expected a timed out command failure, got: %v", err)
+			}
+			if err.Error() != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, err.Error())
+			}
+			if elapsed := time.Since(start); elapsed > 2*time.Second {
+				t.Errorf("Expected the command to be killed at the timeout, took %s", elapsed)
+			}
+		})
+	}
+
+	t.Run("each pipe step gets the full timeout", func(t *testing.T) {
+		executor := NewCommandExecutor(nil)
+		executor.UseTimeout(300 * time.Millisecond)
+		output, err := executor.Execute("sleep 0.2 | sleep 0.2 | echo done")
+		if err != nil || strings.TrimSpace(output.Stdout) != "done" {
+			t.Errorf("Expected the pipe to finish, got %q and %v", output.Stdout, err)
+		}
+	})
+
+	t.Run("failures are not timeouts", func(t *testing.T) {
+		_, err := executor.Execute("exit 3")
+		if err == nil || errors.Is(err, ErrCommandTimeout) {
+			t.Errorf("Expected a plain failure, got: %v", err)
+		}
+	})
+
+	t.Run("cancellation is not a timeout", func(t *testing.T) {
+		executor := NewCommandExecutor(nil)
+		executor.UseTimeout(time.Minute)
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		if _, err := executor.ExecuteContext(ctx, "sleep 5"); err == nil || errors.Is(err, ErrCommandTimeout) {
+			t.Errorf("Expected the cancellation not to be reported as a timeout, got: %v", err)
+		}
+	})
+}
@@ -1,6 +1,7 @@
 package chat
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
@@ -18,16 +19,20 @@ import (
 // Simple inline model without viewport
 type InlineModel struct {
 	session         *Session
+	ctx             context.Context // Cancels model requests, commands, and auto-indexing; set by Run
 	textInput       textinput.Model
 	spinner         spinner.Model
 	state           string
 	pendingCommand  string
 	pendingExplanation string
+	pendingRisk string // Risk notice for the pending command, shown in red
+	pendingAlways string // What answering "always" would allow, or "" when it is not offered
 	originalRequest string
 	commandQueue    []string
 	executionLog    strings.Builder
 	currentAttempt  int
 	quitting        bool
+	fatal           error // A panic in Update that closed the program; returned by Run
 	
 	// Styles
 	userStyle     lipgloss.Style
@@ -43,7 +48,7 @@ func NewInlineSession(config *SessionConfig, llmClient *llm.Client, embeddingsCl
 	ti := textinput.New()
 	ti.Placeholder = "Type your message and press Enter..."
 	ti.Focus()
-	ti.CharLimit = 500
+	ti.CharLimit = config.MaxInputChars
 	ti.Width = 80
 	
 	s := spinner.New()
@@ -52,6 +57,7 @@ func NewInlineSession(config *SessionConfig, llmClient *llm.Client, embeddingsCl
 	
 	return &InlineModel{
 		session:   session,
+		ctx:       context.Background(),
 		textInput: ti,
 		spinner:   s,
 		state:     "input",
@@ -77,7 +83,20 @@ func (m *InlineModel) Init() tea.Cmd {
 	return tea.Batch(textinput.Blink, m.spinner.Tick)
 }
 
-func (m *InlineModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+// Update handles msg. A panic closes the program cleanly, so the terminal
+// is restored, and Run returns it as an error.
+func (m *InlineModel) Update(msg tea.Msg) (model tea.Model, cmd tea.Cmd) {
+	defer func() {
+		if r := recover(); r != nil {
+			m.fatal = recordPanic("updating the chat", r)
+			m.quitting = true
+			model, cmd = m, tea.Quit
+		}
+	}()
+	return m.update(msg)
+}
+
+func (m *InlineModel) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
 		switch m.state {
@@ -102,16 +121,39 @@ func (m *InlineModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				return m.approveCommand()
 			case "n", "N", "esc":
 				return m.denyCommand()
+			case "a", "A":
+				if m.pendingAlways != "" {
+					fmt.Println(m.systemStyle.Render(m.session.rememberApproval(m.pendingCommand)))
+					return m.approveCommand()
+				}
+			}
+		case "processing":
+			if msg.String() == "ctrl+c" {
+				if InterruptCommand() {
+					fmt.Println(m.systemStyle.Render("Stopping the running command"))
+					return m, nil
+				}
+				m.quitting = true
+				return m, tea.Quit
 			}
 		}
 		
 	case aiResponseMsg:
+		for _, notice := range msg.notices {
+			fmt.Println(m.systemStyle.Render(notice))
+		}
+		if msg.prompt != "" {
+			fmt.Println(m.systemStyle.Render(msg.prompt))
+		}
 		fmt.Print(m.aiStyle.Render("AI: ") + msg.response + "\n\n")
 		if msg.err != nil {
 			fmt.Println(m.errorStyle.Render(fmt.Sprintf("Error: %v", msg.err)))
 			m.state = "input"
 			return m, nil
 		}
+		if msg.sources != "" {
+			fmt.Println(m.systemStyle.Render(msg.sources))
+		}
 		
 		// Check for commands
 		validCommands := m.session.validator.ParseCommands(msg.response)
@@ -123,35 +165,42 @@ func (m *InlineModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, nil
 		
 	case commandExecutedMsg:
+		m.session.stats.RecordCommand(msg.command, msg.err != nil)
+		var indexCmd tea.Cmd
 		if msg.err != nil {
 			fmt.Println(m.errorStyle.Render(fmt.Sprintf("❌ Command failed: %v", msg.err)))
-			m.executionLog.WriteString(fmt.Sprintf("$ %s\nError: %v\n\n", msg.command, msg.err))
+			m.executionLog.WriteString(m.session.logEntry(msg.command, msg.result, msg.err))
 		} else {
 			fmt.Println(m.commandStyle.Render(fmt.Sprintf("$ %s", msg.command)))
-			if msg.output != "" {
-				// Truncate output for display
-				displayOutput := m.session.truncateOutputForDisplay(msg.output)
-				fmt.Print(displayOutput)
-				if !strings.HasSuffix(displayOutput, "\n") {
-					fmt.Print("\n")
-				}
+			// Truncate output for display, with stderr dimmed below stdout
+			stdout, stderr := m.session.shownOutput(msg.result)
+			fmt.Print(stdout)
+			if stderr != "" {
+				fmt.Println(m.systemStyle.Render(strings.TrimSuffix(stderr, "\n")))
+			} else if stdout != "" && !strings.HasSuffix(stdout, "\n") {
+				fmt.Print("\n")
 			}
 			fmt.Println(m.systemStyle.Render("✅ Command completed successfully"))
-			m.executionLog.WriteString(fmt.Sprintf("$ %s\n%s\n\n", msg.command, msg.output))
+			m.executionLog.WriteString(m.session.logEntry(msg.command, msg.result, nil))
 			
-			// Auto-index if enabled
-			if m.session.autoIndexer != nil {
-				go func() {
-					if changedFiles, err := m.session.autoIndexer.DetectChanges(); err == nil && len(changedFiles) > 0 {
-						if err := m.session.autoIndexer.IndexChangedFiles(changedFiles); err != nil {
-							fmt.Println(m.systemStyle.Render(fmt.Sprintf("[Auto-index error: %v]", err)))
-						}
-					}
-				}()
-			}
+			// Auto-index in the background; the result comes back as an autoIndexMsg
+			indexCmd = m.session.autoIndexCmd(m.ctx)
 		}
 		fmt.Print("\n")
-		return m.executeNextCommand()
+		model, next := m.executeNextCommand()
+		return model, tea.Batch(next, indexCmd)
+
+	case autoIndexMsg:
+		if message := msg.result.message(); message != "" {
+			fmt.Println(m.systemStyle.Render(message))
+		}
+		return m, nil
+
+	case panicMsg:
+		fmt.Println(m.errorStyle.Render(msg.err.Error()))
+		m.commandQueue = nil
+		m.state = "input"
+		return m, nil
 		
 	case nextCommandsMsg:
 		if msg.err != nil {
@@ -162,8 +211,8 @@ func (m *InlineModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		
 		if !msg.shouldContinue {
 			// Generate final answer
-			return m, tea.Cmd(func() tea.Msg {
-				finalAnswer, err := m.session.evaluator.GenerateFinalAnswer(m.executionLog.String(), m.originalRequest)
+			return m, safeCmd("generating the final answer", func() tea.Msg {
+				finalAnswer, err := m.session.generateFinalAnswer(m.ctx, m.executionLog.String(), m.originalRequest)
 				return finalAnswerMsg{answer: finalAnswer, err: err}
 			})
 		}
@@ -197,8 +246,15 @@ func (m *InlineModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	
 	// Update text input when in input state
 	if m.state == "input" {
+		truncated := false
+		if keyMsg, ok := msg.(tea.KeyMsg); ok {
+			truncated = exceedsInputLimit(m.textInput.Value(), keyMsg, m.textInput.CharLimit)
+		}
 		var cmd tea.Cmd
 		m.textInput, cmd = m.textInput.Update(msg)
+		if truncated {
+			fmt.Println(m.systemStyle.Render(inputTruncatedWarning(m.textInput.CharLimit)))
+		}
 		return m, cmd
 	}
 	
@@ -221,7 +277,14 @@ func (m *InlineModel) View() string {
 			content += fmt.Sprintf("%s\n", m.pendingExplanation)
 		}
 		content += fmt.Sprintf("%s %s\n", m.commandStyle.Render("$"), m.pendingCommand)
-		content += "Press Enter/Y to approve, N to deny: "
+		if m.pendingRisk != "" {
+			content += m.errorStyle.Render(m.pendingRisk) + "\n"
+		}
+		if m.pendingAlways != "" {
+			content += fmt.Sprintf("Press Enter/Y to approve, N to deny, A to always allow %s: ", m.pendingAlways)
+		} else {
+			content += "Press Enter/Y to approve, N to deny: "
+		}
 		return content
 	}
 	
@@ -239,6 +302,7 @@ func (m *InlineModel) handleInput(input string) (tea.Model, tea.Cmd) {
 		return m, nil
 	case "clear":
 		fmt.Print("\033[H\033[2J") // Clear screen
+		m.session.conversation.Reset() // Later requests start afresh
 		fmt.Println(m.systemStyle.Render("🤖 RAG CLI Chat - Chat cleared"))
 		fmt.Print("\n")
 		m.textInput.Reset()
@@ -246,20 +310,26 @@ func (m *InlineModel) handleInput(input string) (tea.Model, tea.Cmd) {
 	case "exit", "quit":
 		m.quitting = true
 		return m, tea.Quit
+	case "/prompt last":
+		fmt.Println(m.systemStyle.Render(m.session.lastPromptReport()))
+		m.textInput.Reset()
+		return m, nil
 	}
 	
 	m.originalRequest = input
+	m.session.stats.RecordTask()
 	m.textInput.Reset()
 	m.state = "processing"
 	
-	return m, tea.Cmd(func() tea.Msg {
-		context, err := m.session.contextManager.GetCombinedContext(input, !m.session.config.NoHistory, 5, 3)
+	return m, safeCmd("generating a response", func() tea.Msg {
+		context, err := m.session.retrieveContext(m.ctx, input)
 		if err != nil {
-			context = []string{}
+			context = []ContextItem{}
 		}
+		notices := m.session.notices.take()
 		
-		response, err := m.session.llmClient.GenerateResponse(input, context)
-		return aiResponseMsg{response: response, err: err}
+		response, err := m.session.generateResponse(m.ctx, input, ContextTexts(context))
+		return aiResponseMsg{response: response, err: err, notices: notices, prompt: m.session.shownPrompt(), sources: m.session.sourcesLine(context)}
 	})
 }
 
@@ -272,10 +342,7 @@ func (m *InlineModel) handleCommands(commands []string) (tea.Model, tea.Cmd) {
 func (m *InlineModel) executeNextCommand() (tea.Model, tea.Cmd) {
 	if len(m.commandQueue) == 0 {
 		// Evaluate execution
-		maxAttempts := m.session.config.MaxAttempts
-		if maxAttempts <= 0 {
-			maxAttempts = 3
-		}
+		maxAttempts := m.session.config.maxAttempts()
 		
 		if m.currentAttempt >= maxAttempts {
 			fmt.Println(m.systemStyle.Render(fmt.Sprintf("❌ Max attempts (%d) reached", maxAttempts)))
@@ -284,8 +351,9 @@ func (m *InlineModel) executeNextCommand() (tea.Model, tea.Cmd) {
 			return m, nil
 		}
 		
-		return m, tea.Cmd(func() tea.Msg {
+		return m, safeCmd("evaluating the results", func() tea.Msg {
 			nextCommands, shouldContinue, err := m.session.evaluator.EvaluateAndGetNextCommands(
+				m.ctx,
 				m.executionLog.String(),
 				m.originalRequest,
 				m.commandQueue,
@@ -298,17 +366,28 @@ func (m *InlineModel) executeNextCommand() (tea.Model, tea.Cmd) {
 	command := m.commandQueue[0]
 	m.commandQueue = m.commandQueue[1:]
 	
-	if !m.session.config.AutoApprove {
+	if m.session.needsApproval(command) {
+		if m.session.config.AutoApprove {
+			fmt.Println(m.errorStyle.Render(fmt.Sprintf("Not auto-approving a dangerous command: %s", command)))
+		}
 		explanation := m.session.generateCommandExplanation(command)
 		m.pendingCommand = command
 		m.pendingExplanation = explanation
+		m.pendingRisk = m.session.safety().Classify(command).RiskNotice()
+		m.pendingAlways = m.session.alwaysOption(command)
 		m.state = "approval"
 		return m, nil
+	} else if rule := m.session.ruleApproving(command); rule != "" {
+		fmt.Println(m.systemStyle.Render(fmt.Sprintf("⚡ %s (auto-approved by rule: %s)", command, rule)))
+		return m, safeCmd("running a command", func() tea.Msg {
+			result, err := m.session.executeCommand(m.ctx, command)
+			return commandExecutedMsg{command: command, result: result, err: err}
+		})
 	} else {
 		fmt.Println(m.systemStyle.Render(fmt.Sprintf("⚡ Auto-approving command: %s", command)))
-		return m, tea.Cmd(func() tea.Msg {
-			output, err := m.session.executor.Execute(command)
-			return commandExecutedMsg{command: command, output: output, err: err}
+		return m, safeCmd("running a command", func() tea.Msg {
+			result, err := m.session.executeCommand(m.ctx, command)
+			return commandExecutedMsg{command: command, result: result, err: err}
 		})
 	}
 }
@@ -317,11 +396,13 @@ func (m *InlineModel) approveCommand() (tea.Model, tea.Cmd) {
 	command := m.pendingCommand
 	m.pendingCommand = ""
 	m.pendingExplanation = ""
+	m.pendingRisk = ""
+	m.pendingAlways = ""
 	m.state = "processing"
 	
-	return m, tea.Cmd(func() tea.Msg {
-		output, err := m.session.executor.Execute(command)
-		return commandExecutedMsg{command: command, output: output, err: err}
+	return m, safeCmd("running a command", func() tea.Msg {
+		result, err := m.session.executeCommand(m.ctx, command)
+		return commandExecutedMsg{command: command, result: result, err: err}
 	})
 }
 
@@ -330,6 +411,8 @@ func (m *InlineModel) denyCommand() (tea.Model, tea.Cmd) {
 	fmt.Print("\n")
 	m.pendingCommand = ""
 	m.pendingExplanation = ""
+	m.pendingRisk = ""
+	m.pendingAlways = ""
 	m.state = "input"
 	return m, nil
 }
@@ -340,7 +423,8 @@ RAG CLI Interactive Chat Help
 
 Available commands:
   help, ?     - Show this help message
-  clear       - Clear the screen
+  clear       - Clear the screen and forget earlier turns
+  /prompt last - Show the prompt last sent to the model
   exit, quit  - Exit the chat
 
 Keyboard shortcuts:
@@ -357,8 +441,15 @@ Features:
 	fmt.Println(m.systemStyle.Render(help))
 }
 
-func (m *InlineModel) Run() error {
-	p := tea.NewProgram(m)
+// Run starts the inline interface. Cancelling ctx stops in-flight work and
+// closes the program.
+func (m *InlineModel) Run(ctx context.Context) error {
+	m.ctx = ctx
+	p := tea.NewProgram(m, tea.WithContext(ctx))
 	_, err := p.Run()
+	fmt.Println(m.systemStyle.Render(m.session.finish()))
+	if m.fatal != nil {
+		return m.fatal
+	}
 	return err
 }
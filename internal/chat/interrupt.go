@@ -0,0 +1,55 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrCommandInterrupted is returned, wrapped in ErrCommandFailed, for a
+// command stopped with InterruptCommand. The task carries on, so the model
+// can try something else.
+var ErrCommandInterrupted = errors.New("command interrupted")
+
+var (
+	runningMu     sync.Mutex
+	runningCancel context.CancelFunc // Stops the command being run, nil while none is
+)
+
+// InterruptCommand stops the command a chat session is running and reports
+// whether there was one. Ctrl+C calls it first, so that it stops a command
+// that never exits, such as tail -f, rather than the whole session.
+func InterruptCommand() bool {
+	runningMu.Lock()
+	defer runningMu.Unlock()
+	if runningCancel == nil {
+		return false
+	}
+	runningCancel()
+	runningCancel = nil
+	return true
+}
+
+// runCommand runs cmdStr with executor under a context that
+// InterruptCommand cancels. A command stopped by ctx still returns ctx's
+// error.
+func runCommand(ctx context.Context, executor Commander, cmdStr string) (ExecutionResult, error) {
+	cmdCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	runningMu.Lock()
+	runningCancel = cancel
+	runningMu.Unlock()
+	defer func() {
+		runningMu.Lock()
+		runningCancel = nil
+		runningMu.Unlock()
+	}()
+
+	result, err := executor.ExecuteContext(cmdCtx, cmdStr)
+	if ctx.Err() == nil && cmdCtx.Err() != nil {
+		return result, fmt.Errorf("%w: %w", ErrCommandFailed, ErrCommandInterrupted)
+	}
+	return result, err
+}
@@ -0,0 +1,50 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestInterruptCommand(t *testing.T) {
+	if InterruptCommand() {
+		t.Fatal("Expected nothing to interrupt while no command runs")
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := runCommand(context.Background(), NewCommandExecutor(nil), "sleep 5")
+		done <- err
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !InterruptCommand() {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the running command to be interruptible")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrCommandInterrupted) || !errors.Is(err, ErrCommandFailed) {
+			t.Errorf("Expected an interrupted command failure, got: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the command to be killed")
+	}
+	if InterruptCommand() {
+		t.Error("Expected nothing to interrupt once the command stopped")
+	}
+}
+
+func TestRunCommand_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := runCommand(ctx, NewCommandExecutor(nil), "sleep 5")
+	if err == nil || errors.Is(err, ErrCommandInterrupted) {
+		t.Errorf("Expected the session's cancellation rather than an interrupt, got: %v", err)
+	}
+}
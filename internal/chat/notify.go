@@ -0,0 +1,60 @@
+package chat
+
+import (
+	"fmt"
+	"io"
+	"sync"
+)
+
+// notifier collects messages from background work, such as auto-indexing,
+// so the main loop can print them between its own output instead of in the
+// middle of a command's output or the user's input
+type notifier struct {
+	mu      sync.Mutex
+	pending []string
+	running sync.WaitGroup
+}
+
+// run calls work in a new goroutine and keeps the message it returns, if any
+func (n *notifier) run(work func() string) {
+	n.running.Add(1)
+	go func() {
+		defer n.running.Done()
+		n.post(work())
+	}()
+}
+
+// post keeps a message for the next flush; empty messages are dropped
+func (n *notifier) post(message string) {
+	if message == "" {
+		return
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.pending = append(n.pending, message)
+}
+
+// flush writes the kept messages to w, one per line, passing each through
+// render when it is not nil
+func (n *notifier) flush(w io.Writer, render func(string) string) {
+	for _, message := range n.take() {
+		if render != nil {
+			message = render(message)
+		}
+		fmt.Fprintln(w, message)
+	}
+}
+
+// take returns the kept messages and forgets them
+func (n *notifier) take() []string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	messages := n.pending
+	n.pending = nil
+	return messages
+}
+
+// wait blocks until all background work started with run has finished
+func (n *notifier) wait() {
+	n.running.Wait()
+}
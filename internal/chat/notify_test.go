@@ -0,0 +1,142 @@
+package chat
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"rag-cli/internal/indexing"
+	"rag-cli/internal/vector"
+	"rag-cli/pkg/config"
+)
+
+func TestNotifier_ConcurrentPosts(t *testing.T) {
+	var n notifier
+	var out bytes.Buffer
+
+	const workers = 50
+	for i := 0; i < workers; i++ {
+		i := i
+		n.run(func() string { return fmt.Sprintf("notice %d", i) })
+		n.flush(&out, nil)
+	}
+	n.wait()
+	n.flush(&out, strings.ToUpper)
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != workers {
+		t.Fatalf("Expected %d notices, got %d: %q", workers, len(lines), lines)
+	}
+	seen := make(map[string]bool)
+	for _, line := range lines {
+		seen[strings.ToLower(line)] = true
+	}
+	for i := 0; i < workers; i++ {
+		if !seen[fmt.Sprintf("notice %d", i)] {
+			t.Errorf("Expected notice %d to be printed once", i)
+		}
+	}
+}
+
+func TestNotifier_DropsEmptyMessages(t *testing.T) {
+	var n notifier
+	n.run(func() string { return "" })
+	n.wait()
+
+	var out bytes.Buffer
+	n.flush(&out, nil)
+	if out.Len() != 0 {
+		t.Errorf("Expected no output, got %q", out.String())
+	}
+}
+
+// autoIndexStore records the documents added to it; other VectorStore
+// methods are not used
+type autoIndexStore struct {
+	vector.VectorStore
+
+	mu    sync.Mutex
+	added []string
+}
+
+func (s *autoIndexStore) AddDocumentWithMetadata(collectionName, id, content string, embedding []float32, metadata map[string]interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.added = append(s.added, id)
+	return nil
+}
+
+func (s *autoIndexStore) AutoIndexCollection() string { return "auto_indexed" }
+
+func (s *autoIndexStore) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.added)
+}
+
+// newAutoIndexSession returns a session with a real auto-indexer over a
+// temporary directory holding two new files
+func newAutoIndexSession(t *testing.T) (*Session, *autoIndexStore) {
+	t.Helper()
+	root := t.TempDir()
+	for _, name := range []string{"a.md", "b.md"} {
+		if err := os.WriteFile(filepath.Join(root, name), []byte("content of "+name), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	store := &autoIndexStore{}
+	cfg := &config.AutoIndexConfig{Enabled: true, Extensions: []string{".md"}, MaxFileSize: 1024}
+	indexer := indexing.NewAutoIndexer(cfg, contextEmbedder{}, store, root)
+	return NewSessionWithDeps(&SessionConfig{}, nil, indexer, SessionDeps{}), store
+}
+
+func TestSession_StartAutoIndex(t *testing.T) {
+	session, store := newAutoIndexSession(t)
+
+	// Overlapping runs must not race on the indexer or the stats, and must
+	// not index the same change twice
+	for i := 0; i < 5; i++ {
+		session.startAutoIndex(context.Background())
+	}
+	session.notices.wait()
+
+	var out bytes.Buffer
+	session.notices.flush(&out, nil)
+
+	if got := store.count(); got != 2 {
+		t.Errorf("Expected 2 documents to be indexed, got %d", got)
+	}
+	if got := strings.Count(out.String(), "[Auto-indexed"); got != 1 {
+		t.Errorf("Expected a single auto-index notice, got %d: %q", got, out.String())
+	}
+	if !strings.Contains(out.String(), "[Auto-indexed 2 of 2 changed file(s): a.md, b.md]") {
+		t.Errorf("Expected notice to list the indexed files, got %q", out.String())
+	}
+	if files := session.Stats().FilesModified(); len(files) != 2 {
+		t.Errorf("Expected 2 modified files in stats, got %v", files)
+	}
+}
+
+func TestBubbleTeaSession_AutoIndexMsg(t *testing.T) {
+	session, _ := newAutoIndexSession(t)
+	m := NewBubbleTeaSession(&SessionConfig{}, nil, nil, nil, nil)
+	m.session = session
+
+	msg := session.autoIndexCmd(context.Background())()
+	m.Update(msg)
+
+	last := m.messages[len(m.messages)-1]
+	if last.Type != "system" || !strings.Contains(last.Content, "[Auto-indexed 2 of 2 changed file(s)") {
+		t.Errorf("Expected auto-index notice in the transcript, got %q", last.Content)
+	}
+
+	if cmd := NewSession(&SessionConfig{}, nil, nil, nil, nil).autoIndexCmd(context.Background()); cmd != nil {
+		t.Error("Expected no command without an auto-indexer")
+	}
+}
@@ -0,0 +1,9 @@
+//go:build windows
+
+package chat
+
+import "os/exec"
+
+// killProcessGroupOnCancel keeps the default behaviour of killing only the
+// started process when its context is cancelled
+func killProcessGroupOnCancel(cmd *exec.Cmd) {}
@@ -0,0 +1,39 @@
+package chat
+
+import (
+	"fmt"
+	"runtime/debug"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// panicMsg reports a tea.Cmd that panicked, so the model can show an error
+// and take the next prompt instead of the program dying in raw mode
+type panicMsg struct {
+	err error
+}
+
+// safeCmd runs cmd, turning a panic into a panicMsg. task describes what the
+// command was doing, for the error message.
+func safeCmd(task string, cmd tea.Cmd) tea.Cmd {
+	return func() (msg tea.Msg) {
+		defer func() {
+			if r := recover(); r != nil {
+				msg = panicMsg{err: recordPanic(task, r)}
+			}
+		}()
+		return cmd()
+	}
+}
+
+// recordPanic writes a recovered panic and its stack to the debug log, not
+// the terminal, which may still be drawing the chat, and returns an error to
+// show the user in its place
+func recordPanic(task string, r interface{}) error {
+	WriteDebugLog(fmt.Sprintf("PANIC while %s: %v\n%s", task, r, debug.Stack()))
+	hint := "run with --debug to log the stack trace"
+	if debugLogEnabled() {
+		hint = "the stack trace is in the debug log"
+	}
+	return fmt.Errorf("internal error while %s: %v (%s)", task, r, hint)
+}
@@ -0,0 +1,137 @@
+package chat
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// panickingCommander panics instead of running commands, like a bug in a
+// new feature would
+type panickingCommander struct {
+	fakeCommander
+}
+
+func (p *panickingCommander) ExecuteContext(ctx context.Context, cmdStr string) (ExecutionResult, error) {
+	var outputs map[string]string
+	outputs[cmdStr] = "never stored" // Assignment to a nil map
+	return ExecutionResult{}, nil
+}
+
+// panickingRetriever panics while looking up context
+type panickingRetriever struct{}
+
+func (panickingRetriever) GetCombinedContext(ctx context.Context, prompt string, includeHistory bool, maxDocuments, maxHistory int) ([]ContextItem, error) {
+	panic("retriever exploded")
+}
+
+func TestSafeCmd(t *testing.T) {
+	debugLog := filepath.Join(t.TempDir(), "debug.log")
+	if err := EnableDebugLog(debugLog); err != nil {
+		t.Fatalf("Failed to enable debug log: %v", err)
+	}
+	defer EnableDebugLog("")
+
+	msg := safeCmd("testing", func() tea.Msg { panic("boom") })()
+
+	panicked, ok := msg.(panicMsg)
+	if !ok {
+		t.Fatalf("Expected a panicMsg, got %T", msg)
+	}
+	if !strings.Contains(panicked.err.Error(), "internal error while testing: boom") {
+		t.Errorf("Expected the panic in the error, got: %v", panicked.err)
+	}
+	data, err := os.ReadFile(debugLog)
+	if err != nil {
+		t.Fatalf("Expected a debug log, got: %v", err)
+	}
+	if !strings.Contains(string(data), "PANIC while testing: boom") || !strings.Contains(string(data), "recover_test.go") {
+		t.Errorf("Expected the panic and its stack in the debug log, got:\n%s", data)
+	}
+
+	if msg := safeCmd("testing", func() tea.Msg { return finalAnswerMsg{answer: "ok"} })(); msg != (finalAnswerMsg{answer: "ok"}) {
+		t.Errorf("Expected the command's message to pass through, got %v", msg)
+	}
+}
+
+func TestBubbleTeaSession_PanickingCommand(t *testing.T) {
+	m := NewBubbleTeaSession(&SessionConfig{AutoApprove: true}, nil, nil, nil, nil)
+	m.session.executor = &panickingCommander{}
+
+	_, cmd := m.Update(aiResponseMsg{response: "ls -la"})
+	msg := cmd()
+	m.Update(msg)
+
+	last := m.messages[len(m.messages)-1]
+	if last.Type != "error" || !strings.Contains(last.Content, "internal error while running a command") {
+		t.Errorf("Expected the panic as an error message, got %+v", last)
+	}
+	if m.state != stateInput {
+		t.Errorf("Expected the chat to take the next prompt, got state %v", m.state)
+	}
+}
+
+func TestBubbleTeaSession_PanicInUpdate(t *testing.T) {
+	m := NewBubbleTeaSession(&SessionConfig{}, nil, nil, nil, nil)
+	m.session.validator = nil // Parsing the response dereferences it
+
+	var out bytes.Buffer
+	p := tea.NewProgram(m, tea.WithInput(nil), tea.WithOutput(&out), tea.WithoutSignalHandler())
+	go p.Send(aiResponseMsg{response: "ls -la"})
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := p.Run()
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Expected the program to quit cleanly, got: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		p.Kill()
+		t.Fatal("Expected the program to quit after the panic")
+	}
+	if m.fatal == nil || !strings.Contains(m.fatal.Error(), "internal error while updating the chat") {
+		t.Errorf("Expected the panic to be kept for Run, got: %v", m.fatal)
+	}
+}
+
+func TestInlineSession_PanickingCommand(t *testing.T) {
+	m := NewInlineSession(&SessionConfig{AutoApprove: true}, nil, nil, nil, nil)
+	m.session.executor = &panickingCommander{}
+
+	withMockedInput("", func() {
+		_, cmd := m.Update(aiResponseMsg{response: "ls -la"})
+		m.Update(cmd())
+	})
+
+	if m.state != "input" || m.fatal != nil {
+		t.Errorf("Expected the chat to take the next prompt, got state %q and fatal %v", m.state, m.fatal)
+	}
+}
+
+func TestSimpleSession_Panic(t *testing.T) {
+	session := NewSimpleSession(&SessionConfig{}, nil, nil, nil, nil)
+	session.session.contextManager = panickingRetriever{}
+
+	var runErr error
+	output := withMockedInput("list files\n", func() {
+		runErr = session.Run(context.Background())
+	})
+
+	if runErr == nil || !strings.Contains(runErr.Error(), "retriever exploded") {
+		t.Fatalf("Expected the panic as an error, got: %v", runErr)
+	}
+	if !strings.Contains(output, "Session summary:") {
+		t.Errorf("Expected session summary in output, got: %s", output)
+	}
+}
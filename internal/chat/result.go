@@ -0,0 +1,57 @@
+package chat
+
+// PromptResult is what a single prompt did, collected while it runs so that
+// scripts can read it as JSON instead of parsing the progress printed for
+// people
+type PromptResult struct {
+	Prompt   string          `json:"prompt"`
+	Answer   string          `json:"answer"`
+	Commands []CommandResult `json:"commands"`
+	Proposed []string        `json:"proposed_commands,omitempty"` // Commands shown but not run because execution is disabled or this is a dry run
+	Attempts int             `json:"attempts"`                    // Rounds of commands run, 0 when none were
+	Achieved bool            `json:"achieved"`                    // The task finished without a failed or declined command
+	Error    string          `json:"error,omitempty"`
+}
+
+// CommandResult is a command run, refused or declined while handling a
+// prompt, or proposed by a dry run
+type CommandResult struct {
+	Command  string `json:"command"`
+	ExitCode int    `json:"exit_code"` // -1 when it did not run to completion, or at all
+	Stdout   string `json:"stdout"`
+	Stderr   string `json:"stderr"`
+	Error    string `json:"error,omitempty"`
+
+	// Set for the commands of a dry run only
+	Status      string `json:"status,omitempty"` // "proposed"
+	Risk        string `json:"risk,omitempty"`   // How the safety rules grade it, such as "caution - deletes files"
+	Explanation string `json:"explanation,omitempty"`
+}
+
+// newCommandResult describes a command that ran, or was stopped before it
+// could, with err
+func newCommandResult(command string, execution ExecutionResult, err error) CommandResult {
+	result := CommandResult{Command: command, ExitCode: execution.ExitCode, Stdout: execution.Stdout, Stderr: execution.Stderr}
+	if err != nil {
+		result.Error = err.Error()
+		if result.ExitCode == 0 {
+			result.ExitCode = exitCode(err) // Stopped before it could run
+		}
+	}
+	return result
+}
+
+// recordCommand adds a command to the result of the prompt being handled, if
+// one is being collected
+func (s *Session) recordCommand(command string, execution ExecutionResult, err error) {
+	if s.result != nil {
+		s.result.Commands = append(s.result.Commands, newCommandResult(command, execution, err))
+	}
+}
+
+// recordAttempt notes that a round of commands started
+func (s *Session) recordAttempt(attempt int) {
+	if s.result != nil {
+		s.result.Attempts = attempt
+	}
+}
@@ -0,0 +1,368 @@
+package chat
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"rag-cli/pkg/config"
+)
+
+// BlockedCommandError reports a command refused by the safety checker
+type BlockedCommandError struct {
+	Command string
+	Reason  string
+}
+
+func (e *BlockedCommandError) Error() string {
+	return fmt.Sprintf("refusing to run %q: %s", e.Command, e.Reason)
+}
+
+// SafetyAction is what happens to a command under the safety policy
+type SafetyAction int
+
+const (
+	SafetyAllow   SafetyAction = iota
+	SafetyWarn                 // Run after showing the reason
+	SafetyBlock                // Refuse to run
+	SafetyConfirm              // Ask first, even with --auto-approve
+)
+
+// SafetyVerdict is the outcome of classifying a command
+type SafetyVerdict struct {
+	Action SafetyAction
+	Risk   Risk
+	Reason string
+}
+
+// safetyRule is a pattern for a command that is never run, with the reason
+type safetyRule struct {
+	pattern *regexp.Regexp
+	reason  string
+	warn    bool // Warn instead of blocking (configured rules only)
+}
+
+// SafetyChecker refuses commands that could destroy the system or its data
+// regardless of approval, such as recursive deletes of the root or home
+// directory, formatting disks, and fork bombs. A policy from the safety
+// config section adds blocked and allowed patterns and a read-only mode.
+type SafetyChecker struct {
+	rules             []safetyRule // Built-in, never overridden by the allowlist
+	blocklist         []safetyRule
+	allowlist         []*regexp.Regexp
+	risk              *CommandValidator // Grades commands the rules let through
+	readOnly          bool
+	typedConfirmation bool
+	workingDir        func() string // Where commands run after a cd, "" for rag-cli's own directory
+}
+
+// NewSafetyChecker creates a checker with the built-in rules
+func NewSafetyChecker() *SafetyChecker {
+	rules := []struct {
+		pattern string
+		reason  string
+	}{
+		{`\brm\s+(-[a-zA-Z]*\s+)*-[a-zA-Z]*[rR][a-zA-Z]*\s+(-[a-zA-Z]+\s+)*(--no-preserve-root\s+)?("?/\*?"?|~/?|\$HOME/?|/(bin|boot|dev|etc|lib|lib64|sbin|usr|var)/?)(\s|;|&|\||$)`, "recursively deletes the root, home, or a system directory"},
+		{`\bmkfs(\.\w+)?\b`, "formats a filesystem"},
+		{`\bdd\b.*\bof=/dev/(sd|hd|nvme|disk|mmcblk|xvd|vd)`, "writes directly to a disk device"},
+		{`>\s*/dev/(sd|hd|nvme|disk|mmcblk|xvd|vd)`, "overwrites a disk device"},
+		{`:\(\)\s*\{\s*:\s*\|\s*:\s*&\s*\}\s*;\s*:`, "is a fork bomb"},
+		{`\bchmod\s+(-[a-zA-Z]+\s+)*-[a-zA-Z]*R[a-zA-Z]*\s+[0-7]*777\s+/(\s|$)`, "makes the whole filesystem world-writable"},
+		{`\bchown\s+(-[a-zA-Z]+\s+)*-[a-zA-Z]*R[a-zA-Z]*\s+\S+\s+/(\s|$)`, "changes ownership of the whole filesystem"},
+		{`(^|[;&|(]|\bsudo)\s*(shutdown|reboot|halt|poweroff)\b`, "shuts down or restarts the machine"},
+		{`\b(curl|wget)\b[^|;&]*\|\s*(sudo\s+)?(sh|bash|zsh)\b`, "pipes a download straight into a shell"},
+	}
+
+	checker := &SafetyChecker{risk: NewCommandValidator()}
+	for _, rule := range rules {
+		checker.rules = append(checker.rules, safetyRule{
+			pattern: regexp.MustCompile(rule.pattern),
+			reason:  rule.reason,
+		})
+	}
+	return checker
+}
+
+// NewSafetyPolicy creates a checker with the built-in rules and the policy
+// from the safety config section
+func NewSafetyPolicy(cfg config.SafetyConfig) (*SafetyChecker, error) {
+	checker := NewSafetyChecker()
+	for i, rule := range cfg.Blocklist {
+		pattern, err := config.CompilePattern(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("safety.blocklist[%d]: %w", i, err)
+		}
+		reason := rule.Reason
+		if reason == "" {
+			reason = fmt.Sprintf("matches the blocklist pattern %q", rule.Pattern)
+		}
+		checker.blocklist = append(checker.blocklist, safetyRule{
+			pattern: pattern,
+			reason:  reason,
+			warn:    rule.Severity == config.SeverityWarn,
+		})
+	}
+	for i, rule := range cfg.Dangerous {
+		pattern, err := config.CompilePattern(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("safety.dangerous[%d]: %w", i, err)
+		}
+		reason := rule.Reason
+		if reason == "" {
+			reason = fmt.Sprintf("matches the dangerous pattern %q", rule.Pattern)
+		}
+		checker.risk.dangerous = append(checker.risk.dangerous, safetyRule{pattern: pattern, reason: reason})
+	}
+	for i, allowed := range cfg.Allowlist {
+		pattern, err := config.CompilePattern(allowed)
+		if err != nil {
+			return nil, fmt.Errorf("safety.allowlist[%d]: %w", i, err)
+		}
+		checker.allowlist = append(checker.allowlist, pattern)
+	}
+	checker.readOnly = cfg.ReadOnly
+	checker.typedConfirmation = cfg.RequireTypedConfirmation
+	return checker, nil
+}
+
+// UseCommandPolicy limits the programs commands may run to those the
+// chat.commands section permits; see CommandValidator.IsPermitted
+func (c *SafetyChecker) UseCommandPolicy(cfg config.CommandPolicyConfig) error {
+	for i, name := range cfg.Allowlist {
+		pattern, err := config.CompilePattern(name)
+		if err != nil {
+			return fmt.Errorf("chat.commands.allowlist[%d]: %w", i, err)
+		}
+		c.risk.allowlist = append(c.risk.allowlist, pattern)
+	}
+	for i, name := range cfg.Denylist {
+		pattern, err := config.CompilePattern(name)
+		if err != nil {
+			return fmt.Errorf("chat.commands.denylist[%d]: %w", i, err)
+		}
+		c.risk.denylist = append(c.risk.denylist, pattern)
+	}
+	return nil
+}
+
+// UseWorkingDir has commands graded as running in the directory dir
+// returns, so that relative paths deleted after a cd that carried over from
+// an earlier command are graded by where they really are. dir returns ""
+// while commands run in rag-cli's own directory.
+func (c *SafetyChecker) UseWorkingDir(dir func() string) {
+	c.workingDir = dir
+}
+
+// Classify decides whether a command may run. Built-in rules always block,
+// and so does running a program chat.commands does not permit. The rules
+// and the blocklist are also matched against heredocs a shell runs as its
+// script.
+// An allowlisted command skips the blocklist, the risk grading and
+// read-only mode; otherwise the first matching blocklist rule applies, then
+// read-only mode. Commands ClassifyRisk grades dangerous must be confirmed,
+// and those it grades caution are allowed with the reason attached.
+func (c *SafetyChecker) Classify(command string) SafetyVerdict {
+	scripts := append([]string{command}, shellHeredocs(command)...)
+	for _, rule := range c.rules {
+		if matchesAny(rule.pattern, scripts) {
+			return SafetyVerdict{Action: SafetyBlock, Risk: RiskDangerous, Reason: rule.reason}
+		}
+	}
+	if program := c.risk.blockedProgram(command); program != "" {
+		return SafetyVerdict{Action: SafetyBlock, Reason: fmt.Sprintf("command '%s' blocked by policy", program)}
+	}
+	for _, pattern := range c.allowlist {
+		if pattern.MatchString(command) {
+			return SafetyVerdict{Action: SafetyAllow}
+		}
+	}
+	for _, rule := range c.blocklist {
+		if matchesAny(rule.pattern, scripts) {
+			if rule.warn {
+				return SafetyVerdict{Action: SafetyWarn, Risk: RiskCaution, Reason: rule.reason}
+			}
+			return SafetyVerdict{Action: SafetyBlock, Risk: RiskDangerous, Reason: rule.reason}
+		}
+	}
+	if c.readOnly && !isReadOnlyCommand(command) {
+		return SafetyVerdict{Action: SafetyBlock, Reason: "may change files or the system, and safety.read_only is set"}
+	}
+	moved := ""
+	if c.workingDir != nil {
+		if dir := c.workingDir(); dir != "" {
+			moved = "in " + dir + ", outside the directory rag-cli started in"
+		}
+	}
+	switch risk := c.risk.classifyRisk(command, moved); risk.Level {
+	case RiskDangerous:
+		return SafetyVerdict{Action: SafetyConfirm, Risk: risk.Level, Reason: risk.Reason}
+	case RiskCaution:
+		return SafetyVerdict{Action: SafetyAllow, Risk: risk.Level, Reason: risk.Reason}
+	}
+	return SafetyVerdict{Action: SafetyAllow}
+}
+
+// matchesAny reports whether pattern matches any of scripts
+func matchesAny(pattern *regexp.Regexp, scripts []string) bool {
+	for _, script := range scripts {
+		if pattern.MatchString(script) {
+			return true
+		}
+	}
+	return false
+}
+
+// RiskNotice describes the risk of a command for an approval prompt, or
+// returns "" when the command is graded safe
+func (v SafetyVerdict) RiskNotice() string {
+	if v.Risk == RiskSafe || v.Reason == "" {
+		return ""
+	}
+	return fmt.Sprintf("Risk: %s - %s", v.Risk, v.Reason)
+}
+
+// Check returns a *BlockedCommandError if the command is blocked, and nil
+// otherwise
+func (c *SafetyChecker) Check(command string) error {
+	if verdict := c.Classify(command); verdict.Action == SafetyBlock {
+		return &BlockedCommandError{Command: command, Reason: verdict.Reason}
+	}
+	return nil
+}
+
+// ConfirmationPrompt returns the question asked before running a command
+func (c *SafetyChecker) ConfirmationPrompt() string {
+	if c.typedConfirmation {
+		return "Type 'yes' to run this command: "
+	}
+	return "Do you want to allow this? (Y/n): "
+}
+
+// Approves reports whether an answer to ConfirmationPrompt approves the
+// command. Pressing Enter approves unless typed confirmation is required.
+func (c *SafetyChecker) Approves(answer string) bool {
+	answer = strings.TrimSpace(strings.ToLower(answer))
+	if c.typedConfirmation {
+		return answer == "yes"
+	}
+	return answer == "" || answer == "y" || answer == "yes"
+}
+
+// AlwaysApproves reports whether an answer to an approval prompt approves the
+// command and asks to allow commands like it from now on: "a" or "always",
+// or only "always" when typed confirmation is required
+func (c *SafetyChecker) AlwaysApproves(answer string) bool {
+	answer = strings.TrimSpace(strings.ToLower(answer))
+	if c.typedConfirmation {
+		return answer == "always"
+	}
+	return answer == "a" || answer == "always"
+}
+
+// readOnlyCommands are programs that only read, used by read-only mode.
+// Those that can also write, such as sort -o, are checked further by
+// writesFile, and date and hostname, which can set the clock and the host
+// name, are only allowed to print them.
+var readOnlyCommands = map[string]bool{
+	"cat": true, "cd": true, "date": true, "df": true, "du": true, "echo": true,
+	"file": true, "find": true, "free": true, "grep": true, "head": true,
+	"hostname": true, "id": true, "less": true, "ls": true, "pwd": true, "ps": true,
+	"stat": true, "tail": true, "tree": true, "uname": true, "uptime": true, "wc": true,
+	"which": true, "whoami": true, "sort": true, "uniq": true, "cut": true, "awk": true,
+	"rg": true, "jq": true, "printenv": true, "type": true, "git": true,
+}
+
+// readOnlyGitCommands are the git subcommands allowed in read-only mode
+var readOnlyGitCommands = map[string]bool{
+	"status": true, "log": true, "diff": true, "show": true, "blame": true,
+	"rev-parse": true, "ls-files": true, "describe": true,
+}
+
+// writeRedirect matches output redirection to a file, including >&file;
+// 2>&1, >&- and >/dev/null are allowed
+var writeRedirect = regexp.MustCompile(`>>?\s*([^&\s/]|/[^d]|/d[^e]|/de[^v])|>&\s*([^0-9\s/-]|/[^d]|/d[^e]|/de[^v])`)
+
+// commandSeparator splits a command line into the commands it runs,
+// including those in command and process substitutions. Heredoc bodies
+// should be removed first with shellScript.
+var commandSeparator = regexp.MustCompile(`\|\|?|&&|;|\n|\$\(|[<>]\(|\x60`)
+
+// isReadOnlyCommand reports whether every command in a command line is known
+// to only read. Anything unrecognised counts as a write.
+func isReadOnlyCommand(command string) bool {
+	if writeRedirect.MatchString(command) {
+		return false
+	}
+	for _, part := range commandSeparator.Split(shellScript(command), -1) {
+		fields := shellWords(part)
+		if len(fields) == 0 {
+			continue
+		}
+		if !readOnlyCommands[fields[0]] || writesFile(fields[0], fields[1:]) {
+			return false
+		}
+		if fields[0] == "awk" && strings.Contains(part, "system(") {
+			return false
+		}
+	}
+	return true
+}
+
+// writesFile reports whether a program readOnlyCommands lists changes
+// something when run with args, as sort -o and uniq do given an output
+// file, or git when the subcommand is not one that only reads
+func writesFile(program string, args []string) bool {
+	switch program {
+	case "date":
+		// Only a +FORMAT to print the date with; -s and a bare date set it
+		return len(args) > 1 || len(args) == 1 && !strings.HasPrefix(args[0], "+")
+	case "hostname":
+		return len(args) > 0
+	case "git":
+		if len(args) == 0 || !readOnlyGitCommands[args[0]] {
+			return true
+		}
+		return hasOutputOption(args[1:], 0, "--output")
+	case "sort":
+		return hasOutputOption(args, 'o', "--output")
+	case "tree":
+		return hasOutputOption(args, 'o', "")
+	case "uniq":
+		// uniq [options] [input [output]]
+		operands := 0
+		for i := 0; i < len(args); i++ {
+			switch arg := args[i]; {
+			case arg == "-f" || arg == "-s" || arg == "-w":
+				i++ // Their value
+			case arg == "-" || !strings.HasPrefix(arg, "-"):
+				operands++
+			}
+		}
+		return operands > 1
+	case "find":
+		for _, arg := range args {
+			if arg == "-delete" || strings.HasPrefix(arg, "-exec") || strings.HasPrefix(arg, "-ok") ||
+				strings.HasPrefix(arg, "-fprint") || arg == "-fls" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hasOutputOption reports whether args include the short option, alone or
+// in a group such as -no, or the long one, alone or as --long=value. A short
+// of 0 or a long of "" is not looked for.
+func hasOutputOption(args []string, short rune, long string) bool {
+	for _, arg := range args {
+		switch {
+		case arg == "--":
+			return false
+		case long != "" && (arg == long || strings.HasPrefix(arg, long+"=")):
+			return true
+		case short != 0 && len(arg) > 1 && arg[0] == '-' && arg[1] != '-' && strings.ContainsRune(arg[1:], short):
+			return true
+		}
+	}
+	return false
+}
@@ -2,19 +2,39 @@ package chat
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"rag-cli/internal/embeddings"
+	"rag-cli/internal/httpclient"
 	"rag-cli/internal/indexing"
 	"rag-cli/internal/llm"
+	"rag-cli/internal/metrics"
+	"rag-cli/internal/transcript"
 	"rag-cli/internal/vector"
 
 	"github.com/fatih/color"
 )
 
+// ErrCommandDenied is returned when the user declines a proposed command
+var ErrCommandDenied = errors.New("command denied by user")
+
+// ErrGoalNotAchieved is returned with the execution log when a task ends with
+// its last command failing or with commands left after the last attempt
+var ErrGoalNotAchieved = errors.New("task not completed")
+
+// ErrGenerationFailed is returned when the model could not answer a prompt,
+// as when Ollama is down or the model is missing, so that a broken setup can
+// be told apart from a task that failed
+var ErrGenerationFailed = errors.New("error generating response")
+
 // SessionConfig holds configuration for a chat session
 type SessionConfig struct {
 	AutoApprove       bool
@@ -23,6 +43,51 @@ type SessionConfig struct {
 	MaxAttempts       int
 	MaxOutputLines    int
 	TruncateOutput    bool
+	MaxInputChars     int // 0 means unlimited
+	TopKDocuments     int // Document chunks retrieved per prompt (0 uses DefaultTopKDocuments)
+	TopKHistory       int // Past command sessions retrieved per prompt (0 uses DefaultTopKHistory)
+	NoExec            bool // Show proposed commands instead of running them
+	DryRun            bool // Show the plan for each prompt, with each command's risk, and run nothing
+	Safety            *SafetyChecker // Decides which commands may run (nil uses the built-in rules)
+	Approvals         *ApprovalPolicy // Always-allow rules (nil starts with none and keeps "always" answers for the session)
+	Usage             *metrics.Recorder // Records local usage events (nil records nothing)
+	Transcript        *transcript.Writer // Records the chat to a transcript file (nil records nothing)
+	RAGUnavailable    bool // ChromaDB could not be reached at startup; retrieval is retried later
+	ShowPrompt        bool // Show the prompt sent to the model after each response
+	MemoryChars       int  // Characters of earlier turns sent with each prompt (0 disables conversation memory)
+	SummarizeMemory   bool // Summarize turns that no longer fit instead of dropping them
+	MaxContextChars   int  // Characters of the whole prompt; retrieved context is trimmed to fit (0 means unlimited)
+	ContextItemChars  int  // Characters kept of each retrieved item (0 means unlimited)
+	CommandTimeout    time.Duration // Longest a command, or each step of a pipe, may run (0 for no limit)
+	NoSources         bool // Hide the documents an answer drew on
+}
+
+// Defaults for a session config that leaves these unset. The CLI validates
+// chat config, so they only apply to sessions built in code.
+const (
+	DefaultTopKDocuments = 5
+	DefaultTopKHistory   = 3
+	DefaultMaxAttempts   = 3
+)
+
+// maxAttempts returns how many rounds of commands a task may take
+func (c *SessionConfig) maxAttempts() int {
+	if c.MaxAttempts <= 0 {
+		return DefaultMaxAttempts
+	}
+	return c.MaxAttempts
+}
+
+// retrievalDepth returns the number of documents and history items to retrieve
+func (c *SessionConfig) retrievalDepth() (int, int) {
+	documents, history := c.TopKDocuments, c.TopKHistory
+	if documents <= 0 {
+		documents = DefaultTopKDocuments
+	}
+	if history <= 0 {
+		history = DefaultTopKHistory
+	}
+	return documents, history
 }
 
 // Session represents an interactive or single-prompt chat session
@@ -33,102 +98,531 @@ type Session struct {
 	vectorStore      *vector.ChromaClient
 	autoIndexer      *indexing.AutoIndexer
 	
-	executor        *CommandExecutor
-	validator       *CommandValidator
-	evaluator       *AIEvaluator
-	contextManager  *ContextManager
+	executor        Commander
+	validator       CommandParser
+	evaluator       Evaluator
+	contextManager  ContextRetriever
+	approve         func(command string) bool // nil uses requestPermission
+	approvals       *ApprovalPolicy
+	stats           *SessionStats
+	autoIndexMu     sync.Mutex // Serializes background auto-index runs
+	notices         notifier   // Messages from background work, printed by the main loop
+	rag             serviceStatus // Whether retrieval and session storage are working
+	stderr          io.Writer  // Where HandlePrompt shows the prompt (nil uses os.Stderr)
+	display         io.Writer  // Where HandlePrompt shows progress and the answer (nil uses os.Stdout)
+	result          *PromptResult // What the prompt being handled did, nil between prompts
+	conversation    *ConversationHistory // Earlier requests and responses sent with each prompt
+	commandDir      string // Where the last command ran, when an earlier one moved there with cd
 	
 	// UI colors
 	commandColor    *color.Color
 	outputColor     *color.Color
+	stderrColor     *color.Color
 	errorColor      *color.Color
 	infoColor       *color.Color
 }
 
 // NewSession creates a new chat session
 func NewSession(config *SessionConfig, llmClient *llm.Client, embeddingsClient *embeddings.Client, vectorStore *vector.ChromaClient, autoIndexer *indexing.AutoIndexer) *Session {
-	return &Session{
-		config:           config,
-		llmClient:        llmClient,
-		embeddingsClient: embeddingsClient,
-		vectorStore:      vectorStore,
-		autoIndexer:      autoIndexer,
+	executor := NewCommandExecutor(config.Safety)
+	executor.UseTimeout(config.CommandTimeout)
+	session := NewSessionWithDeps(config, llmClient, autoIndexer, SessionDeps{
+		Executor:  executor,
+		Validator: NewCommandValidator(),
+		Evaluator: NewAIEvaluator(llmClient, embeddingsClient, vectorStore),
+		Context:   NewContextManager(embeddingsClient, vectorStore),
+	})
+	session.embeddingsClient = embeddingsClient
+	session.vectorStore = vectorStore
+	return session
+}
+
+// NewSessionWithDeps creates a chat session that delegates to the given
+// collaborators
+func NewSessionWithDeps(config *SessionConfig, llmClient *llm.Client, autoIndexer *indexing.AutoIndexer, deps SessionDeps) *Session {
+	session := &Session{
+		config:      config,
+		llmClient:   llmClient,
+		autoIndexer: autoIndexer,
 		
-		executor:       NewCommandExecutor(),
-		validator:      NewCommandValidator(),
-		evaluator:      NewAIEvaluator(llmClient, embeddingsClient, vectorStore),
-		contextManager: NewContextManager(embeddingsClient, vectorStore),
+		executor:       deps.Executor,
+		validator:      deps.Validator,
+		evaluator:      deps.Evaluator,
+		contextManager: deps.Context,
+		approve:        deps.Approve,
+		approvals:      config.Approvals,
+		stats:          NewSessionStats(),
+		conversation:   NewConversationHistory(config.MemoryChars),
 		
 		// Initialize UI colors
 		commandColor: color.New(color.FgYellow, color.Bold),
 		outputColor:  color.New(color.FgWhite),
+		stderrColor:  color.New(color.Faint),
 		errorColor:   color.New(color.FgRed, color.Bold),
 		infoColor:    color.New(color.FgBlue),
 	}
+	if config.SummarizeMemory && llmClient != nil {
+		session.conversation.summarize = llmClient.SummarizeConversation
+	}
+	if autoIndexer != nil {
+		session.stats.TrackFiles()
+	}
+	if session.approvals == nil {
+		session.approvals = NewApprovalPolicy(nil, "", nil)
+	}
+	session.rag.name = "Context retrieval"
+	if config.RAGUnavailable {
+		session.rag.markDown()
+	}
+	if config.Usage != nil {
+		model := ""
+		if llmClient != nil {
+			model = llmClient.Model()
+		}
+		session.stats.RecordUsage(config.Usage, model)
+	}
+	return session
+}
+
+// safety returns the rules deciding which commands may run
+func (s *Session) safety() *SafetyChecker {
+	if s.executor == nil {
+		return NewSafetyChecker()
+	}
+	return s.executor.Safety()
+}
+
+// needsApproval reports whether command must be approved before it runs:
+// without auto-approve unless an always-allow rule covers it, and dangerous
+// commands even with it
+func (s *Session) needsApproval(command string) bool {
+	if s.safety().Classify(command).Action == SafetyConfirm {
+		return true
+	}
+	return !s.config.AutoApprove && s.ruleApproving(command) == ""
+}
+
+// approveCommand asks whether a command may run
+func (s *Session) approveCommand(command string) bool {
+	if s.approve != nil {
+		return s.approve(command)
+	}
+	return s.requestPermission(command)
+}
+
+// sourcesLine lists the documents among items that an answer drew on, or
+// returns "" when there are none or sources are hidden
+func (s *Session) sourcesLine(items []ContextItem) string {
+	if s.config.NoSources {
+		return ""
+	}
+	return sourcesLine(items)
+}
+
+// retrieveContext gathers document and historical context for a prompt using
+// the configured retrieval depth. When retrieval fails the prompt goes ahead
+// without context, so the only error returned is ctx's. While ChromaDB or the
+// embedding model is unreachable retrieval is only retried every
+// ragRetryInterval, and a notice is left when it works again.
+func (s *Session) retrieveContext(ctx context.Context, prompt string) ([]ContextItem, error) {
+	if !s.rag.ready() {
+		return []ContextItem{}, nil
+	}
+	documents, history := s.config.retrievalDepth()
+	contextDocs, err := s.contextManager.GetCombinedContext(ctx, prompt, !s.config.NoHistory, documents, history)
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if err != nil {
+		s.ragFailed("failed to retrieve context", err)
+		return []ContextItem{}, nil
+	}
+	s.ragSucceeded()
+	return s.fitContext(prompt, contextDocs), nil
+}
+
+// fitContext trims retrieved items to the session's ContextBudget, whatever
+// retriever they came from, leaving room for the conversation so far
+func (s *Session) fitContext(prompt string, items []ContextItem) []ContextItem {
+	return s.contextBudget().Fit(prompt, items)
+}
+
+// contextBudget bounds the context given with each prompt
+func (s *Session) contextBudget() ContextBudget {
+	return ContextBudget{
+		MaxChars:  s.config.MaxContextChars,
+		ItemChars: s.config.ContextItemChars,
+		PromptChars: func(prompt string) int {
+			return s.promptSize(prompt)
+		},
+	}
+}
+
+// promptSize returns the characters of the prompt the model would be sent
+// for prompt without context, following on from the conversation so far
+func (s *Session) promptSize(prompt string) int {
+	if s.llmClient != nil {
+		return s.llmClient.PromptSize(prompt, nil, s.conversation.Turns())
+	}
+	return len(prompt)
+}
+
+// ragFailed logs a failure to use the vector store, once per outage when the
+// service could not be reached
+func (s *Session) ragFailed(message string, err error) {
+	if httpclient.Unavailable(err) {
+		s.rag.failed(message, err)
+		return
+	}
+	slog.Warn(message, "component", "chat", "error", err)
+}
+
+// ragSucceeded leaves a notice when the vector store works again after an
+// outage
+func (s *Session) ragSucceeded() {
+	if message := s.rag.succeeded(); message != "" {
+		s.notices.post(message + "; documents and command history are back in use")
+	}
+}
+
+// HandlePrompt processes a single prompt (for non-interactive mode). When ctx
+// is cancelled, in-flight requests, commands, and auto-indexing are stopped,
+// the commands completed so far are printed, and ctx.Err() is returned.
+func (s *Session) HandlePrompt(ctx context.Context, prompt string) error {
+	result, err := s.RunPrompt(ctx, prompt)
+	if err == nil || errors.Is(err, ErrCommandDenied) || errors.Is(err, ErrGoalNotAchieved) {
+		fmt.Fprintln(s.out(), result.Answer)
+	}
+	s.printNotices()
+	return err
+}
+
+// RunPrompt is HandlePrompt without printing the answer: it returns what the
+// prompt did, including the commands run and their output, with the error
+// HandlePrompt would return. The result is never nil.
+func (s *Session) RunPrompt(ctx context.Context, prompt string) (*PromptResult, error) {
+	result := &PromptResult{Prompt: prompt, Commands: []CommandResult{}}
+	s.result = result
+	defer func() { s.result = nil }()
+
+	answer, err := s.runPrompt(ctx, prompt)
+	// Let auto-indexing finish before the process exits
+	s.notices.wait()
+
+	result.Answer = answer
+	result.Achieved = err == nil
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result, err
 }
 
-// HandlePrompt processes a single prompt (for non-interactive mode)
-func (s *Session) HandlePrompt(prompt string) error {
+// runPrompt answers prompt, running the commands in the response, and
+// returns the answer. Progress is shown as it goes, but not the answer.
+func (s *Session) runPrompt(ctx context.Context, prompt string) (string, error) {
+	s.stats.RecordTask()
+	defer s.stats.FinishTask()
+	
 	// Get combined context
-	context, err := s.contextManager.GetCombinedContext(prompt, !s.config.NoHistory, 5, 3)
+	contextDocs, err := s.retrieveContext(ctx, prompt)
 	if err != nil {
-		fmt.Printf("Warning: Failed to retrieve context: %v\n", err)
-		context = []string{}
+		s.reportInterrupted("")
+		return "", err
 	}
 
 	// Generate response using LLM
-	response, err := s.llmClient.GenerateResponse(prompt, context)
+	response, err := s.generateResponse(ctx, prompt, ContextTexts(contextDocs))
+	if ctx.Err() != nil {
+		s.reportInterrupted("")
+		return "", ctx.Err()
+	}
 	if err != nil {
-		return fmt.Errorf("error generating response: %w", err)
+		return "", fmt.Errorf("%w: %w", ErrGenerationFailed, err)
+	}
+	if s.config.ShowPrompt {
+		fmt.Fprintln(s.errOut(), s.lastPromptReport())
 	}
 
 	// Process response for commands and execute if needed
-	enhancedResponse, err := s.processResponseWithCommands(response, prompt)
+	enhancedResponse, err := s.processResponseWithCommands(ctx, response, prompt)
+	if ctx.Err() != nil {
+		s.reportInterrupted(enhancedResponse)
+		return "", ctx.Err()
+	}
+	if err != nil && !errors.Is(err, ErrCommandDenied) && !errors.Is(err, ErrGoalNotAchieved) {
+		return "", fmt.Errorf("error processing commands: %w", err)
+	}
+	return enhancedResponse, err
+}
+
+// UseDisplay shows the progress and answer of HandlePrompt on w instead of
+// stdout. Questions that need an answer, such as command approval, then go
+// to stderr, so that they are never hidden with the progress.
+func (s *Session) UseDisplay(w io.Writer) {
+	s.display = w
+}
+
+// generateResponse asks the model to answer prompt, following on from the
+// conversation so far, and records how long it took. The exchange is added
+// to the conversation.
+func (s *Session) generateResponse(ctx context.Context, prompt string, contextDocs []string) (string, error) {
+	s.config.Transcript.Record(transcript.User, prompt)
+	start := time.Now()
+	response, err := s.llmClient.GenerateResponseWithHistory(ctx, prompt, contextDocs, s.conversation.Turns())
+	s.recordResponse(ctx, prompt, response, time.Since(start), err)
+	return response, err
+}
+
+// generateResponseStream is generateResponse with the response passed to
+// onToken piece by piece as the model produces it
+func (s *Session) generateResponseStream(ctx context.Context, prompt string, contextDocs []string, onToken func(token string)) (string, error) {
+	s.config.Transcript.Record(transcript.User, prompt)
+	start := time.Now()
+	response, err := s.llmClient.GenerateResponseStreamWithHistory(ctx, prompt, contextDocs, s.conversation.Turns(), onToken)
+	s.recordResponse(ctx, prompt, response, time.Since(start), err)
+	return response, err
+}
+
+// recordResponse records a response to prompt that took duration in the
+// stats and the transcript, and adds it to the conversation
+func (s *Session) recordResponse(ctx context.Context, prompt, response string, duration time.Duration, err error) {
+	s.stats.RecordModelResponse(duration, err)
 	if err != nil {
-		return fmt.Errorf("error processing commands: %w", err)
+		s.config.Transcript.Record(transcript.Error, err.Error())
+		return
 	}
+	s.config.Transcript.Record(transcript.AI, response)
+	s.conversation.Record(ctx, prompt, response)
+}
 
-	fmt.Println(enhancedResponse)
-	return nil
+// generateFinalAnswer asks the model to sum up a finished task, recording
+// the answer in the transcript
+func (s *Session) generateFinalAnswer(ctx context.Context, executionLog, originalRequest string) (string, error) {
+	answer, err := s.evaluator.GenerateFinalAnswer(ctx, executionLog, originalRequest)
+	if err == nil && answer != "" {
+		s.config.Transcript.Record(transcript.AI, answer)
+	}
+	return answer, err
+}
+
+// executeCommand runs command for any of the chat front ends, recording it
+// and its output in the transcript
+func (s *Session) executeCommand(ctx context.Context, command string) (ExecutionResult, error) {
+	s.commandDir = ""
+	if executor, ok := s.executor.(WorkingDirCommander); ok {
+		s.commandDir = executor.WorkingDir()
+	}
+	s.config.Transcript.Record(transcript.Command, command)
+	result, err := runCommand(ctx, s.executor, command)
+	if output := LoggedOutput(result); output != "" {
+		s.config.Transcript.Record(transcript.Output, output)
+	}
+	if err != nil {
+		s.config.Transcript.Record(transcript.Error, err.Error())
+	}
+	return result, err
+}
+
+// LoggedOutput is how the output of a command appears in the execution log
+// the model is shown and in command history: stdout as the command wrote it,
+// then anything written to stderr under a "[stderr]" line, so the two are
+// not mistaken for each other. For a pipe that failed after its first step,
+// it also says which step failed and what the step before passed to it.
+func LoggedOutput(result ExecutionResult) string {
+	var b strings.Builder
+	section := func() {
+		if b.Len() > 0 && !strings.HasSuffix(b.String(), "\n") {
+			b.WriteString("\n")
+		}
+	}
+	b.WriteString(result.Stdout)
+	if result.Stderr != "" {
+		section()
+		b.WriteString("[stderr]\n")
+		b.WriteString(result.Stderr)
+	}
+	if failed := len(result.Steps); result.Err != nil && failed > 1 {
+		section()
+		fmt.Fprintf(&b, "Pipe step %d failed: %s", failed, result.Steps[failed-1].Command)
+		if previous := result.Steps[failed-2]; previous.Stdout != "" {
+			fmt.Fprintf(&b, "\nOutput of step %d, passed to it:\n%s", failed-1, previous.Stdout)
+		}
+	}
+	return b.String()
+}
+
+// logEntry is how a command that was run appears in the execution log the
+// model is shown: the command, its output as LoggedOutput gives it and, when
+// it failed, the error
+func (s *Session) logEntry(command string, result ExecutionResult, err error) string {
+	output := LoggedOutput(result)
+	switch {
+	case err == nil:
+		return fmt.Sprintf("%s\n%s\n\n", s.loggedCommand(command), output)
+	case output != "":
+		return fmt.Sprintf("%s\n%s\nError: %v\n\n", s.loggedCommand(command), output, err)
+	}
+	return fmt.Sprintf("%s\nError: %v\n\n", s.loggedCommand(command), err)
+}
+
+// shownOutput returns what a command wrote to stdout and to stderr, each cut
+// short for display as chat.truncate_output asks. Stdout ends with a newline
+// when stderr follows it, so the two start on lines of their own.
+func (s *Session) shownOutput(result ExecutionResult) (stdout, stderr string) {
+	stdout = s.truncateOutputForDisplay(result.Stdout)
+	if result.Stderr == "" {
+		return stdout, ""
+	}
+	if stdout != "" && !strings.HasSuffix(stdout, "\n") {
+		stdout += "\n"
+	}
+	return stdout, s.truncateOutputForDisplay(result.Stderr)
+}
+
+// loggedCommand is how the command just run appears in the execution log
+// the model is shown: "$ command", after the directory it ran in when an
+// earlier command moved there with cd
+func (s *Session) loggedCommand(command string) string {
+	if s.commandDir == "" {
+		return "$ " + command
+	}
+	return fmt.Sprintf("# in %s\n$ %s", s.commandDir, command)
+}
+
+// lastPromptReport describes the prompt most recently sent to the model, with
+// secrets redacted, for --show-prompt and /prompt last
+func (s *Session) lastPromptReport() string {
+	prompt := ""
+	if s.llmClient != nil {
+		prompt = s.llmClient.LastPrompt()
+	}
+	if prompt == "" {
+		return "No prompt has been sent to the model yet"
+	}
+	return "Prompt sent to the model (secrets redacted):\n" + llm.RedactSecrets(prompt)
+}
+
+// shownPrompt returns lastPromptReport when --show-prompt is set, or ""
+func (s *Session) shownPrompt() string {
+	if !s.config.ShowPrompt {
+		return ""
+	}
+	return s.lastPromptReport()
+}
+
+// out returns where progress and answers are shown
+func (s *Session) out() io.Writer {
+	if s.display == nil {
+		return os.Stdout
+	}
+	return s.display
+}
+
+// promptOut returns where questions for the user are shown
+func (s *Session) promptOut() io.Writer {
+	if s.display == nil {
+		return os.Stdout
+	}
+	return s.errOut()
+}
+
+// errOut returns where non-interactive diagnostics are written
+func (s *Session) errOut() io.Writer {
+	if s.stderr == nil {
+		return os.Stderr
+	}
+	return s.stderr
+}
+
+// printNotices prints the messages left by background work
+func (s *Session) printNotices() {
+	s.notices.flush(s.out(), func(message string) string { return s.infoColor.Sprint(message) })
 }
 
 // processResponseWithCommands checks for commands in AI response and executes them iteratively
-func (s *Session) processResponseWithCommands(response string, originalRequest string) (string, error) {
+func (s *Session) processResponseWithCommands(ctx context.Context, response string, originalRequest string) (string, error) {
 	// Parse commands from response
 	validCommands := s.validator.ParseCommands(response)
 	if len(validCommands) == 0 {
 		return response, nil
 	}
 
-	// Commands are always allowed in chat mode
+	if s.config.DryRun {
+		s.recordProposed(validCommands)
+		return s.dryRunPlan(validCommands), nil
+	}
+	if s.config.NoExec {
+		if s.result != nil {
+			s.result.Proposed = validCommands
+		}
+		return proposedCommands(validCommands), nil
+	}
 
 	// Execute commands iteratively with feedback (approval happens per command now)
-	return s.executeCommandsIteratively(validCommands, originalRequest)
+	return s.executeCommandsIteratively(ctx, validCommands, originalRequest)
+}
+
+// proposedCommands lists commands that were not run because execution is disabled
+func proposedCommands(commands []string) string {
+	var b strings.Builder
+	b.WriteString("Proposed command(s), not run because command execution is disabled:")
+	for _, command := range commands {
+		b.WriteString("\n$ ")
+		b.WriteString(command)
+	}
+	return b.String()
+}
+
+// reportInterrupted prints the commands that ran before a prompt was cancelled
+func (s *Session) reportInterrupted(executionLog string) {
+	if strings.TrimSpace(executionLog) == "" {
+		s.errorColor.Fprintf(s.out(), "\nStopped before any commands were run\n")
+		return
+	}
+	s.errorColor.Fprintf(s.out(), "\nStopped before the task was finished. Completed so far:\n")
+	fmt.Fprintln(s.out(), strings.TrimSpace(executionLog))
 }
 
 // requestPermission asks the user for permission to execute a single command
 func (s *Session) requestPermission(command string) bool {
 	// Generate a human-friendly explanation of what this command does
+	out := s.promptOut()
 	explanation := s.generateCommandExplanation(command)
 	if explanation != "" {
-		s.infoColor.Printf("\n%s\n", explanation)
+		s.infoColor.Fprintf(out, "\n%s\n", explanation)
 	} else {
-		s.infoColor.Printf("\nI need to run the following command:\n")
+		s.infoColor.Fprintf(out, "\nI need to run the following command:\n")
 	}
 	
 	lightRule := strings.Repeat("·", 40)
-	fmt.Println(lightRule)
-	s.commandColor.Printf("$ %s\n", command)
-	fmt.Println(lightRule)
-	fmt.Printf("Do you want to allow this? (Y/n): ")
+	fmt.Fprintln(out, lightRule)
+	s.commandColor.Fprintf(out, "$ %s\n", command)
+	fmt.Fprintln(out, lightRule)
+	if notice := s.safety().Classify(command).RiskNotice(); notice != "" {
+		s.errorColor.Fprintf(out, "%s\n", notice)
+	}
+	fmt.Fprint(out, s.confirmationPrompt(command))
 	
 	reader := bufio.NewReader(os.Stdin)
 	permission, _ := reader.ReadString('\n')
-	permission = strings.TrimSpace(strings.ToLower(permission))
-	
-	// Default to yes if user just presses Enter (empty string)
-	// Only deny if user explicitly types "n" or "no"
-	return permission == "" || permission == "y" || permission == "yes"
+	approved, message := s.answerApproval(command, permission)
+	if message != "" {
+		s.infoColor.Fprintf(out, "%s\n", message)
+	}
+	return approved
+}
+
+// confirmationPrompt returns the question asked before running command,
+// offering to always allow commands like it when that is possible
+func (s *Session) confirmationPrompt(command string) string {
+	option := s.alwaysOption(command)
+	switch {
+	case option == "":
+		return s.safety().ConfirmationPrompt()
+	case s.safety().typedConfirmation:
+		return fmt.Sprintf("Type 'yes' to run this command, or 'always' to also allow %s from now on: ", option)
+	}
+	return fmt.Sprintf("Do you want to allow this? (Y/n, a = always allow %s): ", option)
 }
 
 // generateCommandExplanation creates a human-friendly explanation of what a command does
@@ -240,91 +734,119 @@ func (s *Session) truncateOutputForDisplay(output string) string {
 	return result.String()
 }
 
-// executeCommandsIteratively executes commands one by one, allowing AI to refine approach based on results
-func (s *Session) executeCommandsIteratively(initialCommands []string, originalRequest string) (string, error) {
-	maxAttempts := s.config.MaxAttempts
-	if maxAttempts <= 0 {
-		maxAttempts = 3 // fallback default if not set or invalid
-	}
+// executeCommandsIteratively executes commands one by one, allowing AI to refine approach based on results.
+// If ctx is cancelled it stops and returns the log of the commands run so far with ctx.Err().
+// A declined command returns ErrCommandDenied, and a task that ends with a
+// failed command or runs out of attempts returns its log with ErrGoalNotAchieved.
+func (s *Session) executeCommandsIteratively(ctx context.Context, initialCommands []string, originalRequest string) (string, error) {
+	maxAttempts := s.config.maxAttempts()
 	var executionLog strings.Builder
 	var commandQueue []string
 
 	// Start with initial commands
 	commandQueue = append(commandQueue, initialCommands...)
 
+	// interrupted keeps the log of a cancelled run, so the commands that did
+	// run are still in the history
+	interrupted := func() (string, error) {
+		s.recordExecutionSession(executionLog.String(), originalRequest)
+		return executionLog.String(), ctx.Err()
+	}
+
 	var lastErr error
 	for attempt := 1; attempt <= maxAttempts && len(commandQueue) > 0; attempt++ {
+		s.recordAttempt(attempt)
 		// Only show attempt number when we're actually retrying due to failures
 		if attempt > 1 {
-			s.infoColor.Printf("\nRetry attempt %d/%d\n", attempt, maxAttempts)
+			s.infoColor.Fprintf(s.out(), "\nRetry attempt %d/%d\n", attempt, maxAttempts)
 		}
 
 		// Execute all commands in the queue
 		for len(commandQueue) > 0 {
+			if ctx.Err() != nil {
+				return interrupted()
+			}
+			s.printNotices()
 			cmdStr := commandQueue[0]
 			commandQueue = commandQueue[1:] // Remove executed command
 			
+			verdict := s.safety().Classify(cmdStr)
+			if verdict.Action == SafetyWarn {
+				s.errorColor.Fprintf(s.out(), "\nWarning: %q %s\n", cmdStr, verdict.Reason)
+			}
+
 			// Ask for permission for each command (unless auto-approved)
-			if !s.config.AutoApprove {
-				if !s.requestPermission(cmdStr) {
-					s.infoColor.Printf("Command execution cancelled by user\n")
-					return "Command execution cancelled by user.", nil // Return early when user denies
+			rule := s.ruleApproving(cmdStr)
+			switch {
+			case verdict.Action == SafetyBlock:
+				// No point asking: the executor refuses it and the refusal is logged
+			case rule != "":
+				s.infoColor.Fprintf(s.out(), "\n%s (auto-approved by rule: %s)\n", cmdStr, rule)
+			case !s.config.AutoApprove || verdict.Action == SafetyConfirm:
+				if s.config.AutoApprove {
+					s.errorColor.Fprintf(s.out(), "\nNot auto-approving a dangerous command: %s\n", cmdStr)
 				}
-			} else {
-				s.infoColor.Printf("\nAuto-approving command: %s\n", cmdStr)
+				if !s.approveCommand(cmdStr) {
+					s.infoColor.Fprintf(s.out(), "Command execution cancelled by user\n")
+					err := fmt.Errorf("%w: %s", ErrCommandDenied, cmdStr)
+					s.recordCommand(cmdStr, ExecutionResult{}, err)
+					return "Command execution cancelled by user.", err
+				}
+			default:
+				s.infoColor.Fprintf(s.out(), "\nAuto-approving command: %s\n", cmdStr)
 			}
 			
-			s.commandColor.Printf("\nExecuting: %s\n", cmdStr)
+			s.commandColor.Fprintf(s.out(), "\nExecuting: %s\n", cmdStr)
 			
-			output, err := s.executor.Execute(cmdStr)
+			result, err := s.executeCommand(ctx, cmdStr)
+			s.recordCommand(cmdStr, result, err)
+			if ctx.Err() != nil {
+				executionLog.WriteString(fmt.Sprintf("%s\n%s\nInterrupted: %v\n\n", s.loggedCommand(cmdStr), LoggedOutput(result), ctx.Err()))
+				return interrupted()
+			}
+			s.stats.RecordCommand(cmdStr, err != nil)
 			if err != nil {
-				s.errorColor.Printf("Error: %v\n", err)
+				s.errorColor.Fprintf(s.out(), "Error: %v\n", err)
 				// Show failure feedback immediately
-				s.errorColor.Printf("\n❌ Command failed\n")
+				s.errorColor.Fprintf(s.out(), "\n❌ Command failed\n")
 				// Include the actual command output (stderr) in the log for AI context
-				if output != "" {
-					executionLog.WriteString(fmt.Sprintf("$ %s\n%s\nError: %v\n\n", cmdStr, output, err))
-				} else {
-					executionLog.WriteString(fmt.Sprintf("$ %s\nError: %v\n\n", cmdStr, err))
-				}
+				executionLog.WriteString(s.logEntry(cmdStr, result, err))
 				lastErr = err
 				break // Exit the current execution loop if there's an error
 			} else {
-				// Truncate output for display but preserve full output for AI
-				displayOutput := s.truncateOutputForDisplay(output)
-				s.outputColor.Printf("%s", displayOutput)
+				// Truncate output for display but preserve full output for AI,
+				// with stderr dimmed below stdout
+				stdout, stderr := s.shownOutput(result)
+				s.outputColor.Fprintf(s.out(), "%s", stdout)
+				s.stderrColor.Fprintf(s.out(), "%s", stderr)
 				
 				// Show success feedback immediately after successful command
 				successColor := color.New(color.FgGreen, color.Bold)
-				successColor.Printf("\n✅ Command completed successfully\n")
+				successColor.Fprintf(s.out(), "\n✅ Command completed successfully\n")
 				
 				// Store full output in execution log for AI processing
-				executionLog.WriteString(fmt.Sprintf("$ %s\n%s\n\n", cmdStr, output))
+				executionLog.WriteString(s.logEntry(cmdStr, result, nil))
 				lastErr = nil
 				
 				// Auto-index file changes after successful command execution
-				if s.autoIndexer != nil {
-					go func() {
-						if changedFiles, err := s.autoIndexer.DetectChanges(); err == nil && len(changedFiles) > 0 {
-							if err := s.autoIndexer.IndexChangedFiles(changedFiles); err != nil {
-								fmt.Printf("[Auto-index error: %v]\n", err)
-							}
-						}
-					}()
-				}
+				s.startAutoIndex(ctx)
 			}
 		}
 
 		// Evaluate results and get new commands if needed
 		nextCommands, shouldContinue, evalErr := s.evaluator.EvaluateAndGetNextCommands(
+			ctx,
 			executionLog.String(),
 			originalRequest,
 			commandQueue,
 			lastErr != nil,
 		)
 
+		if ctx.Err() != nil {
+			return interrupted()
+		}
 		if evalErr != nil {
-			fmt.Printf("Error evaluating results: %v\n", evalErr)
+			fmt.Fprintf(s.out(), "Error evaluating results: %v\n", evalErr)
 			break
 		}
 
@@ -332,12 +854,12 @@ func (s *Session) executeCommandsIteratively(initialCommands []string, originalR
 			// Check if we have a successful result to present
 			if lastErr == nil && len(commandQueue) == 0 {
 				// Generate a final human-readable answer
-				finalAnswer, err := s.evaluator.GenerateFinalAnswer(executionLog.String(), originalRequest)
+				finalAnswer, err := s.generateFinalAnswer(ctx, executionLog.String(), originalRequest)
 				if err == nil && finalAnswer != "" {
 					// Return the final answer instead of the raw execution log
 					return finalAnswer, nil
 				}
-				s.infoColor.Printf("\nTask completed successfully!\n")
+				s.infoColor.Fprintf(s.out(), "\nTask completed successfully!\n")
 			}
 			break
 		}
@@ -345,11 +867,11 @@ func (s *Session) executeCommandsIteratively(initialCommands []string, originalR
 		// Provide feedback about what happened and what's next
 		if len(nextCommands) > 0 && attempt > 1 {
 			if lastErr != nil {
-				s.errorColor.Printf("\n❌ That didn't seem to work, let me try something else...\n")
+				s.errorColor.Fprintf(s.out(), "\n❌ That didn't seem to work, let me try something else...\n")
 			} else {
 				// Use green color for success messages
 				successColor := color.New(color.FgGreen, color.Bold)
-				successColor.Printf("\n✅ That seemed to work, moving on to the next planned command...\n")
+				successColor.Fprintf(s.out(), "\n✅ That seemed to work, moving on to the next planned command...\n")
 			}
 		}
 
@@ -358,43 +880,105 @@ func (s *Session) executeCommandsIteratively(initialCommands []string, originalR
 		
 		// Show AI's decision to modify commands
 		if len(nextCommands) > 0 && attempt > 1 {
-			s.infoColor.Printf("\nAI suggests next command(s): ")
+			s.infoColor.Fprintf(s.out(), "\nAI suggests next command(s): ")
 			for i, cmd := range nextCommands {
 				if i > 0 {
-					fmt.Printf(", ")
+					fmt.Fprintf(s.out(), ", ")
 				}
-				s.commandColor.Printf("%s", cmd)
+				s.commandColor.Fprintf(s.out(), "%s", cmd)
 			}
-			fmt.Println()
+			fmt.Fprintln(s.out())
 		}
 	}
 
-	if len(commandQueue) > 0 {
+	var result error
+	switch {
+	case len(commandQueue) > 0:
 		executionLog.WriteString(fmt.Sprintf("\nMax attempts (%d) reached. Remaining commands not executed.\n", maxAttempts))
+		result = fmt.Errorf("%w: max attempts (%d) reached", ErrGoalNotAchieved, maxAttempts)
+	case lastErr != nil:
+		result = fmt.Errorf("%w: %w", ErrGoalNotAchieved, lastErr)
 	}
 
-	// Store the execution session in ChromaDB for future learning
-	if err := s.evaluator.StoreExecutionSession(executionLog.String()); err != nil {
-		fmt.Printf("Warning: Failed to store execution session: %v\n", err)
-	}
+	s.recordExecutionSession(executionLog.String(), originalRequest)
+	return executionLog.String(), result
+}
+
+// recordExecutionSession stores a finished or interrupted run in ChromaDB for
+// future learning and writes it to the debug log
+func (s *Session) recordExecutionSession(executionLog, originalRequest string) {
+	s.storeExecutionSession(executionLog)
 	
-	// Debug log the evaluation process (always enabled for debugging)
-	if err := WriteDebugLog("evaluation_debug.log", fmt.Sprintf("EVALUATION SESSION:\nOriginal Request: %s\nExecution Log:\n%s\n=== END SESSION ===\n", originalRequest, executionLog.String())); err != nil {
-		// Don't fail on debug log errors, just continue
+	// Debug log the evaluation process
+	WriteDebugLog(fmt.Sprintf("EVALUATION SESSION:\nOriginal Request: %s\nExecution Log:\n%s\n=== END SESSION ===\n", originalRequest, executionLog))
+}
+
+// storeExecutionSession stores a run in ChromaDB for future learning. It is
+// skipped, like retrieval, while the vector store is unreachable.
+func (s *Session) storeExecutionSession(executionLog string) {
+	if !s.rag.ready() {
+		return
+	}
+	if err := s.evaluator.StoreExecutionSession(executionLog); err != nil {
+		s.ragFailed("failed to store execution session", err)
+		return
 	}
+	s.ragSucceeded()
+}
 
-	return executionLog.String(), nil
+// autoIndexResult is what a background auto-index run did
+type autoIndexResult struct {
+	files   []string // Changed files found
+	indexed int
+	err     error
 }
 
-// WriteDebugLog writes debug information to a log file
-func WriteDebugLog(filename, content string) error {
-	file, err := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
-		return err
+// message describes the run for the user, or is empty when nothing changed
+func (r autoIndexResult) message() string {
+	switch {
+	case errors.Is(r.err, context.Canceled), errors.Is(r.err, context.DeadlineExceeded):
+		return fmt.Sprintf("[Auto-index stopped after %d of %d changed file(s)]", r.indexed, len(r.files))
+	case r.err != nil:
+		return fmt.Sprintf("[Auto-index error: %v]", r.err)
+	case len(r.files) == 0:
+		return ""
+	default:
+		return fmt.Sprintf("[Auto-indexed %d of %d changed file(s): %s]", r.indexed, len(r.files), strings.Join(r.files, ", "))
 	}
-	defer file.Close()
+}
 
-	timestamp := time.Now().Format("2006-01-02 15:04:05")
-	_, err = file.WriteString(fmt.Sprintf("[%s] %s\n", timestamp, content))
-	return err
+// autoIndexChanges indexes files changed since the last snapshot and records
+// them in the session stats. Runs are serialized so a file changed by two
+// quick commands is not indexed twice, and stop early when ctx is cancelled.
+func (s *Session) autoIndexChanges(ctx context.Context) autoIndexResult {
+	s.autoIndexMu.Lock()
+	defer s.autoIndexMu.Unlock()
+
+	if ctx.Err() != nil {
+		return autoIndexResult{}
+	}
+	changedFiles, err := s.autoIndexer.DetectChanges()
+	if err != nil || len(changedFiles) == 0 {
+		return autoIndexResult{}
+	}
+	indexed, err := s.autoIndexer.IndexChangedFilesContext(ctx, changedFiles)
+	s.stats.RecordAutoIndex(changedFiles, indexed)
+	return autoIndexResult{files: changedFiles, indexed: indexed, err: err}
+}
+
+// startAutoIndex indexes changed files in the background when auto-indexing
+// is on, until ctx is cancelled. Its message is left with the session's
+// notifier for the main loop to print.
+func (s *Session) startAutoIndex(ctx context.Context) {
+	if s.autoIndexer == nil {
+		return
+	}
+	s.notices.run(func() string {
+		return s.autoIndexChanges(ctx).message()
+	})
+}
+
+// Stats returns the counters collected during this session
+func (s *Session) Stats() *SessionStats {
+	return s.stats
 }
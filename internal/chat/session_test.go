@@ -17,12 +17,24 @@ package chat
 // These tests verify the core permission handling logic works correctly.
 
 import (
+	"context"
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
+	"net/http"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
+
+	"rag-cli/internal/fakeserver"
+	"rag-cli/internal/llm"
+	"rag-cli/internal/system"
+	"rag-cli/internal/transcript"
+	"rag-cli/pkg/config"
 )
 
 import (
@@ -45,9 +57,11 @@ func createTestSessionForPermissionTesting(autoApprove bool) *Session {
 	// We only need to test the permission logic, not the full AI functionality
 	return &Session{
 		config: config,
+		stats:  NewSessionStats(),
 		// Initialize required color fields for testing - use simple colors
 		commandColor: color.New(color.FgYellow),
 		outputColor:  color.New(color.FgWhite),
+		stderrColor:  color.New(color.Faint),
 		errorColor:   color.New(color.FgRed),
 		infoColor:    color.New(color.FgBlue),
 	}
@@ -271,7 +285,765 @@ func TestOutputTruncationShortOutput(t *testing.T) {
 	}
 }
 
-// NOTE: More complex integration tests involving executeCommandsIteratively
-// would require mocking the executor, validator, and evaluator components.
-// The core permission logic is tested above, and the integration behavior
-// can be verified manually or with integration tests that use real components.
+// fakeCommander records the commands it runs and fails the ones listed
+type fakeCommander struct {
+	failing  map[string]bool
+	executed []string
+}
+
+func (f *fakeCommander) Execute(cmdStr string) (ExecutionResult, error) {
+	return f.ExecuteContext(context.Background(), cmdStr)
+}
+
+func (f *fakeCommander) ExecuteContext(ctx context.Context, cmdStr string) (ExecutionResult, error) {
+	f.executed = append(f.executed, cmdStr)
+	if f.failing[cmdStr] {
+		return ExecutionResult{Command: cmdStr, Stderr: "command not found", ExitCode: 127}, fmt.Errorf("%w: exit status 127", ErrCommandFailed)
+	}
+	return ExecutionResult{Command: cmdStr, Stdout: "output of " + cmdStr}, nil
+}
+
+func (f *fakeCommander) Safety() *SafetyChecker {
+	return NewSafetyChecker()
+}
+
+// evaluation is one scripted answer from fakeEvaluator
+type evaluation struct {
+	next    []string
+	proceed bool
+	err      error
+}
+
+// fakeEvaluator replays scripted evaluations and records what it was asked
+type fakeEvaluator struct {
+	evaluations []evaluation
+	finalAnswer string
+	hadErrors   []bool
+	stored      []string
+	storeErr    error
+}
+
+func (f *fakeEvaluator) EvaluateAndGetNextCommands(ctx context.Context, executionLog string, originalRequest string, remainingCommands []string, hadError bool) ([]string, bool, error) {
+	f.hadErrors = append(f.hadErrors, hadError)
+	if len(f.evaluations) == 0 {
+		return nil, false, nil
+	}
+	next := f.evaluations[0]
+	f.evaluations = f.evaluations[1:]
+	return next.next, next.proceed, next.err
+}
+
+func (f *fakeEvaluator) GenerateFinalAnswer(ctx context.Context, executionLog, originalRequest string) (string, error) {
+	return f.finalAnswer, nil
+}
+
+func (f *fakeEvaluator) StoreExecutionSession(executionLog string) error {
+	f.stored = append(f.stored, executionLog)
+	return f.storeErr
+}
+
+func TestExecuteCommandsIteratively(t *testing.T) {
+	tests := []struct {
+		name         string
+		commands     []string
+		failing      []string
+		evaluations  []evaluation
+		finalAnswer  string
+		approvals    map[string]bool // Commands the user denies map to false; nil auto-approves
+		maxAttempts  int
+		wantExecuted []string
+		wantResult   []string // Substrings of the returned log or answer
+		wantStored   int
+		wantErr      []error // Errors the returned error must wrap
+	}{
+		{
+			name:         "success on first attempt",
+			commands:     []string{"ls"},
+			evaluations:  []evaluation{{proceed: false}},
+			finalAnswer:  "There are three files.",
+			wantExecuted: []string{"ls"},
+			wantResult:   []string{"There are three files."},
+		},
+		{
+			name:         "failure then modified command succeeds",
+			commands:     []string{"lss"},
+			failing:      []string{"lss"},
+			evaluations:  []evaluation{{next: []string{"ls"}, proceed: true}, {proceed: false}},
+			finalAnswer:  "Listed the files.",
+			wantExecuted: []string{"lss", "ls"},
+			wantResult:   []string{"Listed the files."},
+		},
+		{
+			name:         "user denies a command mid-queue",
+			commands:     []string{"ls", "rm notes.txt", "ls"},
+			approvals:    map[string]bool{"ls": true, "rm notes.txt": false},
+			wantExecuted: []string{"ls"},
+			wantResult:   []string{"Command execution cancelled by user."},
+			wantErr:      []error{ErrCommandDenied},
+		},
+		{
+			name:         "max attempts exhausted",
+			commands:     []string{"make"},
+			failing:      []string{"make", "make all"},
+			evaluations:  []evaluation{{next: []string{"make all"}, proceed: true}, {next: []string{"make all"}, proceed: true}},
+			maxAttempts:  2,
+			wantExecuted: []string{"make", "make all"},
+			wantResult:   []string{"$ make\n", "$ make all\n", "Max attempts (2) reached"},
+			wantStored:   1,
+			wantErr:      []error{ErrGoalNotAchieved},
+		},
+		{
+			name:         "evaluator gives up after a failure",
+			commands:     []string{"lss"},
+			failing:      []string{"lss"},
+			evaluations:  []evaluation{{proceed: false}},
+			wantExecuted: []string{"lss"},
+			wantResult:   []string{"$ lss\n[stderr]\ncommand not found"},
+			wantStored:   1,
+			wantErr:      []error{ErrGoalNotAchieved, ErrCommandFailed},
+		},
+		{
+			name:         "evaluator error stops the loop",
+			commands:     []string{"ls"},
+			evaluations:  []evaluation{{err: fmt.Errorf("model unavailable")}},
+			wantExecuted: []string{"ls"},
+			wantResult:   []string{"$ ls\noutput of ls"},
+			wantStored:   1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			executor := &fakeCommander{failing: make(map[string]bool)}
+			for _, command := range tt.failing {
+				executor.failing[command] = true
+			}
+			evaluator := &fakeEvaluator{evaluations: tt.evaluations, finalAnswer: tt.finalAnswer}
+			deps := SessionDeps{Executor: executor, Validator: NewCommandValidator(), Evaluator: evaluator}
+			if tt.approvals != nil {
+				deps.Approve = func(command string) bool { return tt.approvals[command] }
+			}
+			config := &SessionConfig{AutoApprove: tt.approvals == nil, MaxAttempts: tt.maxAttempts, NoHistory: true}
+			session := NewSessionWithDeps(config, nil, nil, deps)
+
+			var result string
+			var err error
+			withMockedInput("", func() {
+				result, err = session.executeCommandsIteratively(context.Background(), tt.commands, "do the task")
+			})
+
+			if len(tt.wantErr) == 0 && err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			for _, want := range tt.wantErr {
+				if !errors.Is(err, want) {
+					t.Errorf("Expected error wrapping %q, got: %v", want, err)
+				}
+			}
+			if strings.Join(executor.executed, ",") != strings.Join(tt.wantExecuted, ",") {
+				t.Errorf("Expected commands %v to run, got %v", tt.wantExecuted, executor.executed)
+			}
+			for _, want := range tt.wantResult {
+				if !strings.Contains(result, want) {
+					t.Errorf("Expected result to contain %q, got: %q", want, result)
+				}
+			}
+			if len(evaluator.stored) != tt.wantStored {
+				t.Errorf("Expected %d stored session(s), got %d", tt.wantStored, len(evaluator.stored))
+			}
+		})
+	}
+}
+
+func TestLoggedOutput(t *testing.T) {
+	failed := errors.New("pipe step 2 failed")
+	tests := []struct {
+		name   string
+		result ExecutionResult
+		want   string
+	}{
+		{"stdout only", ExecutionResult{Stdout: "a\nb\n"}, "a\nb\n"},
+		{"stderr apart", ExecutionResult{Stdout: "{}", Stderr: "warning: slow\n"}, "{}\n[stderr]\nwarning: slow\n"},
+		{"stderr only", ExecutionResult{Stderr: "not found"}, "[stderr]\nnot found"},
+		{"failed pipe step", ExecutionResult{
+			Stderr: "grep: bad regex\n",
+			Steps:  []StepResult{{Command: "ls", Stdout: "a.go\n"}, {Command: "grep '['", Stderr: "grep: bad regex\n", ExitCode: 2}},
+			Err:    failed,
+		}, "[stderr]\ngrep: bad regex\nPipe step 2 failed: grep '['\nOutput of step 1, passed to it:\na.go\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := LoggedOutput(tt.result); got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestExecuteCommandsIteratively_LogsWorkingDir(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to resolve temp dir: %v", err)
+	}
+	session := NewSessionWithDeps(&SessionConfig{AutoApprove: true, NoHistory: true}, nil, nil, SessionDeps{
+		Executor:  NewCommandExecutor(nil),
+		Validator: NewCommandValidator(),
+		Evaluator: &fakeEvaluator{evaluations: []evaluation{{err: fmt.Errorf("model unavailable")}}},
+	})
+
+	var result string
+	withMockedInput("", func() {
+		result, err = session.executeCommandsIteratively(context.Background(), []string{"cd " + dir, "pwd"}, "where am I")
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if want := fmt.Sprintf("# in %s\n$ pwd\n%s\n", dir, dir); !strings.Contains(result, want) {
+		t.Errorf("Expected the log to show where pwd ran, %q, got: %q", want, result)
+	}
+	if !strings.HasPrefix(result, "$ cd ") {
+		t.Errorf("Expected no directory before the first command, got: %q", result)
+	}
+}
+
+func TestExecuteCommandsIteratively_ConfirmsDangerousCommands(t *testing.T) {
+	executor := &fakeCommander{}
+	var asked []string
+	session := NewSessionWithDeps(&SessionConfig{AutoApprove: true, NoHistory: true}, nil, nil, SessionDeps{
+		Executor:  executor,
+		Validator: NewCommandValidator(),
+		Evaluator: &fakeEvaluator{},
+		Approve: func(command string) bool {
+			asked = append(asked, command)
+			return false
+		},
+	})
+
+	var err error
+	withMockedInput("", func() {
+		_, err = session.executeCommandsIteratively(context.Background(), []string{"ls", `rm -rf "$HOME/.cache"`}, "clean up")
+	})
+
+	if !errors.Is(err, ErrCommandDenied) {
+		t.Errorf("Expected ErrCommandDenied, got: %v", err)
+	}
+	if len(asked) != 1 || asked[0] != `rm -rf "$HOME/.cache"` {
+		t.Errorf("Expected only the dangerous command to be asked about, got %v", asked)
+	}
+	if strings.Join(executor.executed, ",") != "ls" {
+		t.Errorf("Expected only ls to run, got %v", executor.executed)
+	}
+}
+
+func TestExecuteCommandsIteratively_ReportsErrors(t *testing.T) {
+	executor := &fakeCommander{failing: map[string]bool{"lss": true}}
+	evaluator := &fakeEvaluator{evaluations: []evaluation{{next: []string{"ls"}, proceed: true}, {proceed: false}}}
+	session := NewSessionWithDeps(&SessionConfig{AutoApprove: true}, nil, nil, SessionDeps{
+		Executor:  executor,
+		Validator: NewCommandValidator(),
+		Evaluator: evaluator,
+	})
+
+	withMockedInput("", func() {
+		session.executeCommandsIteratively(context.Background(), []string{"lss"}, "list files")
+	})
+
+	if len(evaluator.hadErrors) != 2 || !evaluator.hadErrors[0] || evaluator.hadErrors[1] {
+		t.Errorf("Expected the evaluator to see a failure and then a success, got %v", evaluator.hadErrors)
+	}
+	if stats := session.Stats(); stats.commandsRun != 2 || stats.commandsFailed != 1 {
+		t.Errorf("Expected 2 commands run with 1 failure, got %d run with %d failed", stats.commandsRun, stats.commandsFailed)
+	}
+}
+
+func TestExecuteCommandsIteratively_BlockedByPolicy(t *testing.T) {
+	safety := NewSafetyChecker()
+	if err := safety.UseCommandPolicy(config.CommandPolicyConfig{Allowlist: []string{"ls"}}); err != nil {
+		t.Fatalf("Expected the command policy to compile, got: %v", err)
+	}
+	evaluator := &fakeEvaluator{evaluations: []evaluation{{proceed: false}}}
+	session := NewSessionWithDeps(&SessionConfig{AutoApprove: true}, nil, nil, SessionDeps{
+		Executor:  NewCommandExecutor(safety),
+		Validator: NewCommandValidator(),
+		Evaluator: evaluator,
+	})
+
+	var err error
+	withMockedInput("", func() {
+		_, err = session.executeCommandsIteratively(context.Background(), []string{"touch blocked.txt"}, "create a file")
+	})
+
+	if !errors.Is(err, ErrGoalNotAchieved) {
+		t.Errorf("Expected ErrGoalNotAchieved, got: %v", err)
+	}
+	if len(evaluator.hadErrors) != 1 || !evaluator.hadErrors[0] {
+		t.Errorf("Expected the evaluator to hear about the refusal, got %v", evaluator.hadErrors)
+	}
+	if len(evaluator.stored) != 1 || !strings.Contains(evaluator.stored[0], "command 'touch' blocked by policy") {
+		t.Errorf("Expected the refusal in the execution log, got %q", evaluator.stored)
+	}
+}
+
+func TestExecuteCommandsIteratively_LogsStoreFailure(t *testing.T) {
+	var logs bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelWarn})))
+
+	evaluator := &fakeEvaluator{
+		evaluations: []evaluation{{err: fmt.Errorf("model unavailable")}},
+		storeErr:    fmt.Errorf("chroma is down"),
+	}
+	session := NewSessionWithDeps(&SessionConfig{AutoApprove: true, NoHistory: true}, nil, nil, SessionDeps{
+		Executor:  &fakeCommander{},
+		Validator: NewCommandValidator(),
+		Evaluator: evaluator,
+	})
+
+	output := withMockedInput("", func() {
+		session.executeCommandsIteratively(context.Background(), []string{"ls"}, "list files")
+	})
+
+	if strings.Contains(output, "chroma is down") {
+		t.Errorf("Expected the failure to stay out of stdout, got:\n%s", output)
+	}
+	if !strings.Contains(logs.String(), `level=WARN msg="failed to store execution session" component=chat error="chroma is down"`) {
+		t.Errorf("Expected a structured warning, got: %q", logs.String())
+	}
+}
+
+func TestProcessResponseWithCommands_NoExec(t *testing.T) {
+	response := "ls -la"
+
+	t.Run("no-exec returns the proposed commands", func(t *testing.T) {
+		session := createTestSessionForPermissionTesting(true)
+		session.validator = NewCommandValidator()
+		session.config.NoExec = true
+
+		// The session has no executor, so running anything would panic
+		result, err := session.processResponseWithCommands(context.Background(), response, "list files")
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if !strings.Contains(result, "not run") || !strings.Contains(result, "$ ls -la") {
+			t.Errorf("Expected the proposed command as text, got: %q", result)
+		}
+	})
+
+	t.Run("commands allowed goes through approval", func(t *testing.T) {
+		session := createTestSessionForPermissionTesting(false)
+		session.validator = NewCommandValidator()
+
+		var result string
+		output := withMockedInput("n\n", func() {
+			var err error
+			result, err = session.processResponseWithCommands(context.Background(), response, "list files")
+			if !errors.Is(err, ErrCommandDenied) {
+				t.Errorf("Expected ErrCommandDenied, got: %v", err)
+			}
+		})
+
+		if !strings.Contains(output, "Do you want to allow this? (Y/n):") {
+			t.Errorf("Expected a permission prompt, got: %s", output)
+		}
+		if result != "Command execution cancelled by user." {
+			t.Errorf("Expected cancellation after denial, got: %q", result)
+		}
+	})
+
+}
+
+// blockingCommander runs its first command until the context is cancelled,
+// like a command that hangs
+type blockingCommander struct {
+	fakeCommander
+}
+
+func (b *blockingCommander) ExecuteContext(ctx context.Context, cmdStr string) (ExecutionResult, error) {
+	b.executed = append(b.executed, cmdStr)
+	<-ctx.Done()
+	return ExecutionResult{Command: cmdStr, Stdout: "partial output", ExitCode: -1}, ctx.Err()
+}
+
+func TestExecuteCommandsIteratively_Cancel(t *testing.T) {
+	executor := &blockingCommander{}
+	evaluator := &fakeEvaluator{}
+	session := NewSessionWithDeps(&SessionConfig{AutoApprove: true}, nil, nil, SessionDeps{
+		Executor:  executor,
+		Validator: NewCommandValidator(),
+		Evaluator: evaluator,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	var err error
+	start := time.Now()
+	withMockedInput("", func() {
+		_, err = session.executeCommandsIteratively(ctx, []string{"sleep 60", "ls"}, "wait a minute")
+	})
+
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected a cancellation error, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected the run to stop when cancelled, took %s", elapsed)
+	}
+	if len(executor.executed) != 1 {
+		t.Errorf("Expected the queued command to be skipped, ran %v", executor.executed)
+	}
+	if len(evaluator.hadErrors) != 0 {
+		t.Errorf("Expected no evaluation after cancellation, got %d", len(evaluator.hadErrors))
+	}
+	if len(evaluator.stored) != 1 || !strings.Contains(evaluator.stored[0], "$ sleep 60\npartial output\nInterrupted: context canceled") {
+		t.Errorf("Expected the interrupted run to be stored, got %q", evaluator.stored)
+	}
+}
+
+func TestHandlePrompt_Cancel(t *testing.T) {
+	server := fakeserver.NewOllama(t)
+	server.Fail("/api/generate", fakeserver.Failure{Delay: 5 * time.Second})
+	llmClient, err := llm.NewClient(config.LLMConfig{BaseURL: server.URL, Model: "slow"}, config.TimeoutsConfig{})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	llmClient.UseSystemInfoCache(system.NewCache("", time.Hour))
+
+	session := NewSessionWithDeps(&SessionConfig{NoHistory: true}, llmClient, nil, SessionDeps{
+		Validator: NewCommandValidator(),
+		Context:   NewContextManager(contextEmbedder{}, &contextStore{requested: make(map[string]int)}),
+	})
+
+	// Cancel once the model has the request, as a user would while waiting
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		for {
+			if _, ok := server.LastRequest("/api/generate"); ok {
+				cancel()
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+
+	var start time.Time
+	withMockedInput("", func() {
+		start = time.Now()
+		err = session.HandlePrompt(ctx, "list files")
+	})
+
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected a cancellation error, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected the prompt to stop when cancelled, took %s", elapsed)
+	}
+}
+
+func TestHandlePrompt_TypedErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		failure  *fakeserver.Failure
+		response string
+		wantErr  []error
+	}{
+		{
+			name:    "missing model",
+			failure: &fakeserver.Failure{Status: http.StatusNotFound, Body: `{"error":"model \"missing\" not found"}`},
+			wantErr: []error{ErrGenerationFailed, llm.ErrModelNotFound},
+		},
+		{
+			name:    "server error",
+			failure: &fakeserver.Failure{Status: http.StatusInternalServerError, Body: `{"error":"out of memory"}`},
+			wantErr: []error{ErrGenerationFailed},
+		},
+		{
+			name:     "denied command",
+			response: "ls -la",
+			wantErr:  []error{ErrCommandDenied},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := fakeserver.NewOllama(t)
+			if tt.failure != nil {
+				server.Fail("/api/generate", *tt.failure)
+			}
+			server.SetResponse(tt.response)
+			llmClient, err := llm.NewClient(config.LLMConfig{BaseURL: server.URL, Model: "missing"}, config.TimeoutsConfig{})
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			llmClient.UseSystemInfoCache(system.NewCache("", time.Hour))
+
+			session := NewSessionWithDeps(&SessionConfig{NoHistory: true}, llmClient, nil, SessionDeps{
+				Validator: NewCommandValidator(),
+				Context:   NewContextManager(contextEmbedder{}, &contextStore{requested: make(map[string]int)}),
+				Approve:   func(command string) bool { return false },
+			})
+
+			withMockedInput("", func() {
+				err = session.HandlePrompt(context.Background(), "list files")
+			})
+
+			for _, want := range tt.wantErr {
+				if !errors.Is(err, want) {
+					t.Errorf("Expected error wrapping %q, got: %v", want, err)
+				}
+			}
+		})
+	}
+}
+
+func TestSession_Transcript(t *testing.T) {
+	writer := transcript.NewWriter(t.TempDir(), 0)
+	session := NewSessionWithDeps(&SessionConfig{AutoApprove: true, NoHistory: true, Transcript: writer}, newStreamingClient(t, "ls -la"), nil, SessionDeps{
+		Executor:  &fakeCommander{failing: map[string]bool{}},
+		Validator: NewCommandValidator(),
+		Evaluator: &fakeEvaluator{finalAnswer: "There are two files"},
+		Context:   NewContextManager(contextEmbedder{}, &contextStore{requested: make(map[string]int)}),
+	})
+
+	var err error
+	withMockedInput("", func() {
+		err = session.HandlePrompt(context.Background(), "list files")
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	events, err := transcript.Load(writer.Path())
+	if err != nil {
+		t.Fatalf("Expected a transcript, got: %v", err)
+	}
+	expected := []transcript.Event{
+		{Type: transcript.User, Content: "list files"},
+		{Type: transcript.AI, Content: "ls -la"},
+		{Type: transcript.Command, Content: "ls -la"},
+		{Type: transcript.Output, Content: "output of ls -la"},
+		{Type: transcript.AI, Content: "There are two files"},
+	}
+	if len(events) != len(expected) {
+		t.Fatalf("Expected %d events, got %+v", len(expected), events)
+	}
+	for i, want := range expected {
+		if events[i].Type != want.Type || events[i].Content != want.Content {
+			t.Errorf("Expected event %d to be %s %q, got %s %q", i, want.Type, want.Content, events[i].Type, events[i].Content)
+		}
+	}
+}
+
+func TestRunPrompt(t *testing.T) {
+	tests := []struct {
+		name     string
+		failing  map[string]bool
+		noExec   bool
+		answer   string
+		achieved bool
+		commands []CommandResult
+		proposed []string
+	}{
+		{
+			name:     "commands run",
+			answer:   "Here are the files",
+			achieved: true,
+			commands: []CommandResult{{Command: "ls -la", Stdout: "output of ls -la"}},
+		},
+		{
+			name:     "failed command",
+			failing:  map[string]bool{"ls -la": true},
+			answer:   "$ ls -la\n[stderr]\ncommand not found\nError: command failed: exit status 127\n\n",
+			commands: []CommandResult{{Command: "ls -la", ExitCode: 127, Stderr: "command not found", Error: "command failed: exit status 127"}},
+		},
+		{
+			name:     "execution disabled",
+			noExec:   true,
+			answer:   "Proposed command(s), not run because command execution is disabled:\n$ ls -la",
+			achieved: true,
+			commands: []CommandResult{},
+			proposed: []string{"ls -la"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := fakeserver.NewOllama(t)
+			server.SetResponse("ls -la")
+			llmClient, err := llm.NewClient(config.LLMConfig{BaseURL: server.URL, Model: "granite-code:3b"}, config.TimeoutsConfig{})
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			llmClient.UseSystemInfoCache(system.NewCache("", time.Hour))
+
+			session := NewSessionWithDeps(&SessionConfig{NoHistory: true, AutoApprove: true, NoExec: tt.noExec}, llmClient, nil, SessionDeps{
+				Executor:  &fakeCommander{failing: tt.failing},
+				Validator: NewCommandValidator(),
+				Evaluator: &fakeEvaluator{finalAnswer: "Here are the files"},
+				Context:   NewContextManager(contextEmbedder{}, &contextStore{requested: make(map[string]int)}),
+			})
+			var progress bytes.Buffer
+			session.UseDisplay(&progress)
+
+			var result *PromptResult
+			stdout := withMockedInput("", func() {
+				result, err = session.RunPrompt(context.Background(), "list files")
+			})
+
+			if stdout != "" {
+				t.Errorf("Expected nothing on stdout, got: %q", stdout)
+			}
+			if (err == nil) != tt.achieved || result.Achieved != tt.achieved {
+				t.Errorf("Expected achieved %v, got %v (%v)", tt.achieved, result.Achieved, err)
+			}
+			if result.Answer != tt.answer {
+				t.Errorf("Expected answer %q, got %q", tt.answer, result.Answer)
+			}
+			if fmt.Sprint(result.Commands) != fmt.Sprint(tt.commands) || fmt.Sprint(result.Proposed) != fmt.Sprint(tt.proposed) {
+				t.Errorf("Expected commands %+v and proposed %v, got %+v and %v", tt.commands, tt.proposed, result.Commands, result.Proposed)
+			}
+			if len(tt.commands) > 0 && (result.Attempts != 1 || progress.Len() == 0) {
+				t.Errorf("Expected one attempt with progress shown on the display, got %d attempts and %q", result.Attempts, progress.String())
+			}
+		})
+	}
+}
+
+func TestRunPrompt_DryRun(t *testing.T) {
+	server := fakeserver.NewOllama(t)
+	server.SetResponse("ls -la\nrm -rf build")
+	llmClient, err := llm.NewClient(config.LLMConfig{BaseURL: server.URL, Model: "granite-code:3b"}, config.TimeoutsConfig{})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	llmClient.UseSystemInfoCache(system.NewCache("", time.Hour))
+
+	executor := &fakeCommander{}
+	evaluator := &fakeEvaluator{finalAnswer: "Done"}
+	session := NewSessionWithDeps(&SessionConfig{NoHistory: true, DryRun: true}, llmClient, nil, SessionDeps{
+		Executor:  executor,
+		Validator: NewCommandValidator(),
+		Evaluator: evaluator,
+		Context:   NewContextManager(contextEmbedder{}, &contextStore{requested: make(map[string]int)}),
+	})
+	session.UseDisplay(io.Discard)
+
+	var result *PromptResult
+	output := withMockedInput("", func() {
+		result, err = session.RunPrompt(context.Background(), "clean up")
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if len(executor.executed) != 0 {
+		t.Errorf("Expected the executor never to be invoked, ran %v", executor.executed)
+	}
+	if len(evaluator.hadErrors) != 0 || len(evaluator.stored) != 0 {
+		t.Errorf("Expected no evaluation and nothing stored, got %d evaluations and %v", len(evaluator.hadErrors), evaluator.stored)
+	}
+	if strings.Contains(output, "Do you want to allow this?") {
+		t.Errorf("Expected no approval prompt, got:\n%s", output)
+	}
+	for _, want := range []string{"1. $ ls -la\n   I need to list the files and directories here.\n   Risk: safe\n", "2. $ rm -rf build\n   Risk: caution - recursively deletes files\n", "2 command(s) proposed, none executed"} {
+		if !strings.Contains(result.Answer, want) {
+			t.Errorf("Expected the plan to contain %q, got:\n%s", want, result.Answer)
+		}
+	}
+	if len(result.Commands) != 2 || result.Attempts != 0 {
+		t.Fatalf("Expected two proposed commands and no attempts, got %+v (%d attempts)", result.Commands, result.Attempts)
+	}
+	for _, command := range result.Commands {
+		if command.Status != "proposed" || command.ExitCode != -1 || command.Risk == "" {
+			t.Errorf("Expected the command marked proposed with its risk, got %+v", command)
+		}
+	}
+}
+
+// secretStore returns a document carrying a credential, as indexed notes can
+type secretStore struct {
+	contextStore
+}
+
+func (s *secretStore) SearchWithEmbedding(collectionName string, queryEmbedding []float32, numResults int) ([]string, error) {
+	return []string{"deploy with DEPLOY_TOKEN=abc123secret"}, nil
+}
+
+func TestSession_ShowPrompt(t *testing.T) {
+	server := fakeserver.NewOllama(t)
+	server.SetResponse("The files are listed above.")
+	llmClient, err := llm.NewClient(config.LLMConfig{BaseURL: server.URL, Model: "granite-code:3b"}, config.TimeoutsConfig{})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	llmClient.UseSystemInfoCache(system.NewCache("", time.Hour))
+
+	newSession := func() *Session {
+		return NewSessionWithDeps(&SessionConfig{NoHistory: true, NoExec: true, ShowPrompt: true}, llmClient, nil, SessionDeps{
+			Validator: NewCommandValidator(),
+			Context:   NewContextManager(contextEmbedder{}, &secretStore{}),
+		})
+	}
+	sentPrompt := func() string {
+		req, _ := server.LastRequest("/api/generate")
+		var body llm.GenerateRequest
+		if err := req.Decode(&body); err != nil {
+			t.Fatalf("Expected a JSON request body, got: %v", err)
+		}
+		return body.Prompt
+	}
+
+	t.Run("HandlePrompt", func(t *testing.T) {
+		session := newSession()
+		var stderr bytes.Buffer
+		session.stderr = &stderr
+
+		stdout := withMockedInput("", func() {
+			err = session.HandlePrompt(context.Background(), "list files")
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+
+		sent := sentPrompt()
+		if !strings.Contains(sent, "abc123secret") {
+			t.Fatalf("Expected the fake server to receive the document, got:\n%s", sent)
+		}
+		expected := "Prompt sent to the model (secrets redacted):\n" + llm.RedactSecrets(sent) + "\n"
+		if stderr.String() != expected {
+			t.Errorf("Expected stderr to show the prompt sent:\n%s\ngot:\n%s", expected, stderr.String())
+		}
+		if strings.Contains(stderr.String(), "abc123secret") {
+			t.Error("Expected the token to be redacted")
+		}
+		if strings.Contains(stdout, "User request:") {
+			t.Errorf("Expected the prompt to stay off stdout, got: %s", stdout)
+		}
+	})
+
+	t.Run("prompt last", func(t *testing.T) {
+		session := NewSimpleSession(&SessionConfig{NoHistory: true}, llmClient, nil, nil, nil)
+		session.session = newSession()
+		session.session.config.ShowPrompt = false
+
+		output := withMockedInput("list files\n/prompt last\n", func() {
+			session.Run(context.Background())
+		})
+
+		sent := sentPrompt()
+		for _, line := range strings.Split(llm.RedactSecrets(sent), "\n") {
+			if !strings.Contains(output, line) {
+				t.Errorf("Expected /prompt last to show %q, got:\n%s", line, output)
+			}
+		}
+		if strings.Contains(output, "abc123secret") {
+			t.Error("Expected the token to be redacted")
+		}
+	})
+
+	t.Run("nothing sent yet", func(t *testing.T) {
+		session := &Session{config: &SessionConfig{}}
+		if got := session.lastPromptReport(); got != "No prompt has been sent to the model yet" {
+			t.Errorf("Expected a note that no prompt was sent, got %q", got)
+		}
+	})
+}
@@ -0,0 +1,145 @@
+package chat
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+// shellState is what carries over from one command to the next, as it would
+// in a terminal: the working directory, changed with cd, and the
+// environment, changed with export and unset. The zero value is rag-cli's
+// own directory and environment.
+type shellState struct {
+	dir     string   // "" is rag-cli's working directory
+	environ []string // nil is rag-cli's environment
+}
+
+// variableName matches the names export and unset may change
+var variableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// WorkingDir returns the directory commands run in once a command has moved
+// elsewhere with cd, or "" while they run in rag-cli's own
+func (e *CommandExecutor) WorkingDir() string {
+	e.stateMu.Lock()
+	defer e.stateMu.Unlock()
+	return e.state.dir
+}
+
+// shellCommand prepares cmdStr to run with sh in the session's working
+// directory and environment, killed along with any children when ctx is
+// cancelled
+func (e *CommandExecutor) shellCommand(ctx context.Context, cmdStr string) *exec.Cmd {
+	e.stateMu.Lock()
+	state := e.state
+	e.stateMu.Unlock()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", cmdStr)
+	cmd.Dir = state.dir
+	cmd.Env = state.environ
+	killProcessGroupOnCancel(cmd)
+	return cmd
+}
+
+// captureState adds to cmdStr a trailer that writes the shell's working
+// directory and the variables cmdStr exports or unsets to a temporary file,
+// keeping cmdStr's exit status. It returns the script to run and a function
+// that reads the file into the executor's state once the script has
+// finished. A command that leaves a heredoc, quote or block open is run as
+// it is, since the trailer would become part of it.
+func (e *CommandExecutor) captureState(cmdStr string) (string, func()) {
+	var scan blockScanner
+	for _, line := range strings.Split(cmdStr, "\n") {
+		scan.scanLine(line)
+	}
+	if scan.open() {
+		return cmdStr, func() {}
+	}
+	file, err := os.CreateTemp("", "rag-cli-state-*")
+	if err != nil {
+		return cmdStr, func() {}
+	}
+	file.Close()
+	path := file.Name()
+
+	names := exportedNames(cmdStr)
+	var trailer strings.Builder
+	trailer.WriteString("\n__rag_cli_status=$?\n{ pwd; printf '\\0'")
+	for _, name := range names {
+		fmt.Fprintf(&trailer, "; printf '%%s\\0' \"${%s+=$%s}\"", name, name)
+	}
+	fmt.Fprintf(&trailer, "; } >| %s\nexit $__rag_cli_status", shellQuote(path))
+
+	return cmdStr + trailer.String(), func() {
+		defer os.Remove(path)
+		if data, err := os.ReadFile(path); err == nil {
+			e.updateState(string(data), names)
+		}
+	}
+}
+
+// updateState applies what a state trailer wrote: the working directory,
+// then for each of names "=value" when it is set or "" when it is not
+func (e *CommandExecutor) updateState(data string, names []string) {
+	fields := strings.Split(data, "\x00")
+	if len(fields) < len(names)+1 {
+		return // The command exited before the trailer ran
+	}
+
+	e.stateMu.Lock()
+	defer e.stateMu.Unlock()
+	if dir := strings.TrimSuffix(fields[0], "\n"); dir != "" {
+		if cwd, err := os.Getwd(); err == nil && dir == cwd {
+			dir = ""
+		}
+		e.state.dir = dir
+	}
+	for i, name := range names {
+		if e.state.environ == nil {
+			e.state.environ = os.Environ()
+		}
+		e.state.environ = withoutVariable(e.state.environ, name)
+		if value, set := strings.CutPrefix(fields[i+1], "="); set {
+			e.state.environ = append(e.state.environ, name+"="+value)
+		}
+	}
+}
+
+// withoutVariable returns environ without the entry for name
+func withoutVariable(environ []string, name string) []string {
+	kept := environ[:0:0]
+	for _, entry := range environ {
+		if !strings.HasPrefix(entry, name+"=") {
+			kept = append(kept, entry)
+		}
+	}
+	return kept
+}
+
+// exportedNames returns the variables cmd exports or unsets
+func exportedNames(cmd string) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, part := range commandSeparator.Split(shellScript(cmd), -1) {
+		words := shellWords(part)
+		if len(words) == 0 || (words[0] != "export" && words[0] != "unset") {
+			continue
+		}
+		for _, word := range words[1:] {
+			name, _, _ := strings.Cut(word, "=")
+			if variableName.MatchString(name) && !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// shellQuote quotes s as a single sh word
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
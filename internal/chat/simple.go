@@ -1,14 +1,18 @@
 package chat
 
 import (
+	"context"
 	"bufio"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
 	"strings"
 
 	"rag-cli/internal/embeddings"
 	"rag-cli/internal/indexing"
 	"rag-cli/internal/llm"
+	"rag-cli/internal/system"
 	"rag-cli/internal/vector"
 
 	"github.com/charmbracelet/lipgloss"
@@ -20,6 +24,8 @@ type SimpleSession struct {
 	commandQueue    []string
 	executionLog    strings.Builder
 	currentAttempt  int
+	quitting        bool
+	input           <-chan inputLine // Lines read from stdin, shared by the prompt and approvals
 	
 	// Styles
 	userStyle     lipgloss.Style
@@ -44,7 +50,57 @@ func NewSimpleSession(config *SessionConfig, llmClient *llm.Client, embeddingsCl
 	}
 }
 
-func (s *SimpleSession) Run() error {
+// inputLine is a line read from stdin, or the error that ended the input
+type inputLine struct {
+	text string
+	err  error
+}
+
+// readLines reads r line by line in the background, so waiting for input can
+// be abandoned when the session is cancelled. The channel is closed after the
+// first error.
+func readLines(r io.Reader) <-chan inputLine {
+	lines := make(chan inputLine)
+	go func() {
+		defer close(lines)
+		reader := bufio.NewReader(r)
+		for {
+			text, err := reader.ReadString('\n')
+			lines <- inputLine{text: text, err: err}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return lines
+}
+
+// readLine waits for the next line of input, or for ctx to be cancelled
+func (s *SimpleSession) readLine(ctx context.Context) (string, error) {
+	select {
+	case <-ctx.Done():
+		return "", ctx.Err()
+	case line, ok := <-s.input:
+		if !ok {
+			return "", io.EOF
+		}
+		return line.text, line.err
+	}
+}
+
+// Run reads prompts from stdin until the user quits, input ends, or ctx is
+// cancelled. Cancelling ctx stops the task in progress, waits for
+// auto-indexing to stop, prints the session summary, and returns ctx.Err().
+// A panic also ends the session with the summary, and is returned as an error.
+func (s *SimpleSession) Run(ctx context.Context) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = recordPanic("running the chat", r)
+			fmt.Println()
+			s.printSummary()
+		}
+	}()
+
 	// Print welcome message
 	fmt.Println(s.systemStyle.Render("🤖 RAG CLI Chat - Type 'help' for commands, Ctrl+C to quit"))
 	if s.session.config.AutoApprove {
@@ -55,15 +111,27 @@ func (s *SimpleSession) Run() error {
 	}
 	fmt.Println()
 	
-	reader := bufio.NewReader(os.Stdin)
+	if s.input == nil {
+		s.input = readLines(os.Stdin)
+	}
 	
 	for {
-		// Show prompt
+		// Show prompt, after anything auto-indexing reported meanwhile
+		s.printNotices()
 		fmt.Print(s.promptStyle.Render("> "))
 		
 		// Read input
-		input, err := reader.ReadString('\n')
+		input, err := s.readLine(ctx)
 		if err != nil {
+			if ctx.Err() != nil {
+				return s.stop(ctx)
+			}
+			if err == io.EOF {
+				fmt.Println()
+				s.session.notices.wait()
+				s.printSummary()
+				return nil
+			}
 			return err
 		}
 		
@@ -74,11 +142,19 @@ func (s *SimpleSession) Run() error {
 		
 		// Handle special commands
 		if s.handleSpecialCommands(input) {
+			if s.quitting {
+				s.session.notices.wait()
+				s.printSummary()
+				return nil
+			}
 			continue
 		}
 		
 		// Process with AI (don't reprint the input, user already sees it)
-		if err := s.handleUserInput(input); err != nil {
+		if err := s.handleUserInput(ctx, input); err != nil {
+			if ctx.Err() != nil {
+				return s.stop(ctx)
+			}
 			fmt.Println(s.errorStyle.Render(fmt.Sprintf("Error: %v", err)))
 		}
 		
@@ -93,59 +169,113 @@ func (s *SimpleSession) handleSpecialCommands(input string) bool {
 		return true
 	case "clear":
 		fmt.Print("\033[H\033[2J") // Clear screen
+		s.session.conversation.Reset() // Later requests start afresh
 		fmt.Println(s.systemStyle.Render("🤖 RAG CLI Chat - Chat cleared"))
 		fmt.Println()
 		return true
 	case "exit", "quit":
 		fmt.Println(s.systemStyle.Render("Goodbye!"))
-		os.Exit(0)
+		s.quitting = true
+		return true
+	case "/sysinfo":
+		s.showSystemInfo(s.session.llmClient.SystemInfo())
+		return true
+	case "/sysinfo refresh":
+		fmt.Println(s.systemStyle.Render("Detecting system information..."))
+		s.showSystemInfo(s.session.llmClient.RefreshSystemInfo())
+		return true
+	case "/prompt last":
+		fmt.Println(s.systemStyle.Render(s.session.lastPromptReport()))
+		return true
 	}
 	return false
 }
 
-func (s *SimpleSession) handleUserInput(input string) error {
+// stop ends a cancelled session: it lets auto-indexing wind down, prints the
+// summary, and returns ctx.Err()
+func (s *SimpleSession) stop(ctx context.Context) error {
+	fmt.Println()
+	fmt.Println(s.systemStyle.Render("Interrupted"))
+	s.session.notices.wait()
+	s.printSummary()
+	return ctx.Err()
+}
+
+// printSummary prints the end-of-session recap
+func (s *SimpleSession) printSummary() {
+	s.printNotices()
+	fmt.Println(s.systemStyle.Render(s.session.finish()))
+}
+
+// printNotices prints the messages left by background work
+func (s *SimpleSession) printNotices() {
+	s.session.notices.flush(os.Stdout, func(message string) string { return s.systemStyle.Render(message) })
+}
+
+func (s *SimpleSession) handleUserInput(ctx context.Context, input string) error {
 	s.originalRequest = input
+	s.session.stats.RecordTask()
 	
 	// Get context
-	context, err := s.session.contextManager.GetCombinedContext(input, !s.session.config.NoHistory, 5, 3)
+	contextDocs, err := s.session.retrieveContext(ctx, input)
 	if err != nil {
-		context = []string{}
+		return err
 	}
+	s.printNotices()
 	
-	// Generate response
-	response, err := s.session.llmClient.GenerateResponse(input, context)
+	// Generate response, printing it as it arrives
+	streamed := false
+	response, err := s.session.generateResponseStream(ctx, input, ContextTexts(contextDocs), func(token string) {
+		if !streamed {
+			fmt.Print(s.aiStyle.Render("AI:") + " ")
+			streamed = true
+		}
+		fmt.Print(token)
+	})
+	if streamed {
+		fmt.Println()
+	}
 	if err != nil {
 		return err
 	}
+	if sources := s.session.sourcesLine(contextDocs); sources != "" {
+		fmt.Println(s.systemStyle.Render(sources))
+	}
+	if prompt := s.session.shownPrompt(); prompt != "" {
+		fmt.Println(s.systemStyle.Render(prompt))
+	}
 	
-	// Check for commands first
+	// Run any commands in the response
 	validCommands := s.session.validator.ParseCommands(response)
 	if len(validCommands) > 0 {
-		// If response contains only commands, don't show the raw command text
-		if strings.TrimSpace(response) != validCommands[0] || len(validCommands) > 1 {
-			// Show AI response if it's more than just a bare command
-			fmt.Printf("%s %s\n", s.aiStyle.Render("AI:"), response)
+		if s.session.config.DryRun {
+			fmt.Printf("%s %s\n", s.aiStyle.Render("AI:"), s.session.dryRunPlan(validCommands))
+			return nil
 		}
-		return s.executeCommandsIteratively(validCommands)
+		if s.session.config.NoExec {
+			fmt.Printf("%s %s\n", s.aiStyle.Render("AI:"), proposedCommands(validCommands))
+			return nil
+		}
+		return s.executeCommandsIteratively(ctx, validCommands)
 	}
 	
-	// Show AI response for non-command responses
-	fmt.Printf("%s %s\n", s.aiStyle.Render("AI:"), response)
-	
 	return nil
 }
 
-func (s *SimpleSession) executeCommandsIteratively(initialCommands []string) error {
-	maxAttempts := s.session.config.MaxAttempts
-	if maxAttempts <= 0 {
-		maxAttempts = 3
-	}
+func (s *SimpleSession) executeCommandsIteratively(ctx context.Context, initialCommands []string) error {
+	maxAttempts := s.session.config.maxAttempts()
 	
 	s.commandQueue = initialCommands
 	s.currentAttempt = 1
 	s.executionLog.Reset()
 	
-	reader := bufio.NewReader(os.Stdin)
+	// interrupted keeps the log of a cancelled run, so the commands that did
+	// run are still in the history
+	interrupted := func() error {
+		s.session.recordExecutionSession(s.executionLog.String(), s.originalRequest)
+		return ctx.Err()
+	}
+	
 	var lastErr error
 	
 	for s.currentAttempt <= maxAttempts && len(s.commandQueue) > 0 {
@@ -164,67 +294,82 @@ func (s *SimpleSession) executeCommandsIteratively(initialCommands []string) err
 		for len(s.commandQueue) > 0 {
 			command := s.commandQueue[0]
 			s.commandQueue = s.commandQueue[1:]
+			s.printNotices()
 			
+			verdict := s.session.safety().Classify(command)
+			if verdict.Action == SafetyWarn {
+				fmt.Println(s.errorStyle.Render(fmt.Sprintf("⚠️  %q %s", command, verdict.Reason)))
+			}
+
 			// Ask for permission (unless auto-approved)
-			if !s.session.config.AutoApprove {
-				if !s.requestPermission(command, reader) {
+			rule := s.session.ruleApproving(command)
+			switch {
+			case verdict.Action == SafetyBlock:
+				// No point asking: the executor refuses it and the refusal is logged
+			case rule != "":
+				fmt.Println(s.systemStyle.Render(fmt.Sprintf("⚡ %s (auto-approved by rule: %s)", command, rule)))
+			case !s.session.config.AutoApprove || verdict.Action == SafetyConfirm:
+				if s.session.config.AutoApprove {
+					fmt.Println(s.errorStyle.Render(fmt.Sprintf("Not auto-approving a dangerous command: %s", command)))
+				}
+				if !s.requestPermission(ctx, command) {
+					if ctx.Err() != nil {
+						return interrupted()
+					}
 					fmt.Println(s.systemStyle.Render("❌ Command execution cancelled by user"))
 					return nil
 				}
-			} else {
+			default:
 				fmt.Println(s.systemStyle.Render(fmt.Sprintf("⚡ Auto-approving command: %s", command)))
 			}
 			
 			// Execute command
 			fmt.Println(s.commandStyle.Render(fmt.Sprintf("$ %s", command)))
-			output, err := s.session.executor.Execute(command)
+			result, err := s.session.executeCommand(ctx, command)
+			if ctx.Err() != nil {
+				s.executionLog.WriteString(fmt.Sprintf("%s\n%s\nInterrupted: %v\n\n", s.session.loggedCommand(command), LoggedOutput(result), ctx.Err()))
+				return interrupted()
+			}
+			s.session.stats.RecordCommand(command, err != nil)
 			
 			if err != nil {
 				fmt.Println(s.errorStyle.Render(fmt.Sprintf("❌ Command failed: %v", err)))
 				// Include the actual command output (stderr) in the log for AI context
-				if output != "" {
-					s.executionLog.WriteString(fmt.Sprintf("$ %s\n%s\nError: %v\n\n", command, output, err))
-				} else {
-					s.executionLog.WriteString(fmt.Sprintf("$ %s\nError: %v\n\n", command, err))
-				}
+				s.executionLog.WriteString(s.session.logEntry(command, result, err))
 				lastErr = err
 				break // Exit the current execution loop if there's an error
 			} else {
-				// Show output
-				if output != "" {
-					displayOutput := s.session.truncateOutputForDisplay(output)
-					fmt.Print(displayOutput)
-					if !strings.HasSuffix(displayOutput, "\n") {
-						fmt.Print("\n")
-					}
+				// Show output, with stderr dimmed below stdout
+				stdout, stderr := s.session.shownOutput(result)
+				fmt.Print(stdout)
+				if stderr != "" {
+					fmt.Println(s.systemStyle.Render(strings.TrimSuffix(stderr, "\n")))
+				} else if stdout != "" && !strings.HasSuffix(stdout, "\n") {
+					fmt.Print("\n")
 				}
 				fmt.Println(s.systemStyle.Render("✅ Command completed successfully"))
 				
 				// Store full output in execution log for AI processing
-				s.executionLog.WriteString(fmt.Sprintf("$ %s\n%s\n\n", command, output))
+				s.executionLog.WriteString(s.session.logEntry(command, result, nil))
 				lastErr = nil
 				
 				// Auto-index if enabled
-				if s.session.autoIndexer != nil {
-					go func() {
-						if changedFiles, err := s.session.autoIndexer.DetectChanges(); err == nil && len(changedFiles) > 0 {
-							if err := s.session.autoIndexer.IndexChangedFiles(changedFiles); err != nil {
-								fmt.Println(s.systemStyle.Render(fmt.Sprintf("[Auto-index error: %v]", err)))
-							}
-						}
-					}()
-				}
+				s.session.startAutoIndex(ctx)
 			}
 		}
 		
 		// Evaluate results and get new commands if needed
 		nextCommands, shouldContinue, evalErr := s.session.evaluator.EvaluateAndGetNextCommands(
+			ctx,
 			s.executionLog.String(),
 			s.originalRequest,
 			s.commandQueue,
 			lastErr != nil,
 		)
 		
+		if ctx.Err() != nil {
+			return interrupted()
+		}
 		if evalErr != nil {
 			fmt.Printf("Error evaluating results: %v\n", evalErr)
 			break
@@ -232,11 +377,11 @@ func (s *SimpleSession) executeCommandsIteratively(initialCommands []string) err
 		
 		if !shouldContinue {
 			// Generate a final human-readable answer when goal is achieved
-			finalAnswer, err := s.session.evaluator.GenerateFinalAnswer(s.executionLog.String(), s.originalRequest)
+			finalAnswer, err := s.session.generateFinalAnswer(ctx, s.executionLog.String(), s.originalRequest)
 			if err == nil && finalAnswer != "" {
 				fmt.Printf("%s %s\n", s.aiStyle.Render("AI:"), finalAnswer)
 			} else if err != nil {
-				fmt.Println(s.systemStyle.Render(fmt.Sprintf("Warning: Failed to generate final answer: %v", err)))
+				slog.Warn("failed to generate final answer", "component", "chat", "error", err)
 			}
 			fmt.Println(s.systemStyle.Render("✅ Task completed successfully!"))
 			break
@@ -273,14 +418,14 @@ func (s *SimpleSession) executeCommandsIteratively(initialCommands []string) err
 	}
 	
 	// Store the execution session in ChromaDB for future learning
-	if err := s.session.evaluator.StoreExecutionSession(s.executionLog.String()); err != nil {
-		fmt.Println(s.systemStyle.Render(fmt.Sprintf("Warning: Failed to store execution session: %v", err)))
-	}
+	s.session.storeExecutionSession(s.executionLog.String())
 	
 	return nil
 }
 
-func (s *SimpleSession) requestPermission(command string, reader *bufio.Reader) bool {
+// requestPermission asks whether command may run, reading the answer from
+// the session's input. Cancelling ctx denies the command.
+func (s *SimpleSession) requestPermission(ctx context.Context, command string) bool {
 	// Generate explanation
 	explanation := s.session.generateCommandExplanation(command)
 	if explanation != "" {
@@ -288,14 +433,34 @@ func (s *SimpleSession) requestPermission(command string, reader *bufio.Reader)
 	}
 	
 	fmt.Println(s.commandStyle.Render(fmt.Sprintf("$ %s", command)))
-	fmt.Print("Press Enter/Y to approve, N to deny: ")
-	
-	permission, _ := reader.ReadString('\n')
-	permission = strings.TrimSpace(strings.ToLower(permission))
+	safety := s.session.safety()
+	if notice := safety.Classify(command).RiskNotice(); notice != "" {
+		fmt.Println(s.errorStyle.Render(notice))
+	}
+	option := s.session.alwaysOption(command)
+	switch {
+	case safety.typedConfirmation:
+		fmt.Print(s.session.confirmationPrompt(command))
+	case option != "":
+		fmt.Printf("Press Enter/Y to approve, N to deny, A to always allow %s: ", option)
+	default:
+		fmt.Print("Press Enter/Y to approve, N to deny: ")
+	}
 	
-	// Default to yes if user just presses Enter (empty string)
-	// Only deny if user explicitly types "n" or "no"
-	return permission == "" || permission == "y" || permission == "yes"
+	permission, _ := s.readLine(ctx)
+	if ctx.Err() != nil {
+		return false
+	}
+	approved, message := s.session.answerApproval(command, permission)
+	if message != "" {
+		fmt.Println(s.systemStyle.Render(message))
+	}
+	return approved
+}
+
+// showSystemInfo prints the environment command prompts are built for
+func (s *SimpleSession) showSystemInfo(info *system.SystemInfo) {
+	fmt.Println(s.systemStyle.Render(strings.Join(info.Describe(), "\n")))
 }
 
 func (s *SimpleSession) showHelp() {
@@ -304,7 +469,10 @@ RAG CLI Interactive Chat Help
 
 Available commands:
   help, ?     - Show this help message
-  clear       - Clear the screen
+  clear       - Clear the screen and forget earlier turns
+  /sysinfo    - Show the detected OS, shell and tools
+  /sysinfo refresh - Detect them again, e.g. after installing tools
+  /prompt last - Show the prompt last sent to the model
   exit, quit  - Exit the chat
 
 Usage:
@@ -0,0 +1,191 @@
+package chat
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"rag-cli/internal/metrics"
+	"rag-cli/internal/transcript"
+)
+
+// SessionStats accumulates counters over the lifetime of a chat session so a
+// recap can be shown when the user exits
+type SessionStats struct {
+	mutex            sync.Mutex
+	startTime        time.Time
+	tasksAttempted   int
+	commandsRun      int
+	commandsFailed   int
+	filesModified    map[string]struct{}
+	documentsIndexed int
+	trackFiles       bool // Modified files are known, from auto-indexing
+
+	usage *metrics.Recorder // Local usage events (nil records nothing)
+	model string
+	task  *taskUsage // The task being recorded, nil between tasks
+}
+
+// taskUsage tracks the current task for its task_finished event
+type taskUsage struct {
+	start        time.Time
+	lastActivity time.Time // Last command or model response, so idle time is not counted
+	commands     int
+	failures     int
+	lastFailed   bool
+}
+
+// NewSessionStats creates an empty stats tracker starting now
+func NewSessionStats() *SessionStats {
+	return &SessionStats{
+		startTime:     time.Now(),
+		filesModified: make(map[string]struct{}),
+	}
+}
+
+// RecordUsage also writes usage events for tasks, commands, and model
+// requests to usage, tagged with model
+func (st *SessionStats) RecordUsage(usage *metrics.Recorder, model string) {
+	st.mutex.Lock()
+	defer st.mutex.Unlock()
+	st.usage = usage
+	st.model = model
+}
+
+// RecordTask counts a user request that was sent to the AI. The previous
+// task, if any, is finished first.
+func (st *SessionStats) RecordTask() {
+	st.mutex.Lock()
+	defer st.mutex.Unlock()
+	st.finishTask()
+	st.tasksAttempted++
+	if st.usage != nil {
+		now := time.Now()
+		st.task = &taskUsage{start: now, lastActivity: now}
+		st.usage.Record(metrics.Event{Type: metrics.TaskStarted, Model: st.model})
+	}
+}
+
+// RecordCommand counts an executed command and whether it failed
+func (st *SessionStats) RecordCommand(command string, failed bool) {
+	st.mutex.Lock()
+	defer st.mutex.Unlock()
+	st.commandsRun++
+	if failed {
+		st.commandsFailed++
+	}
+	if st.usage == nil {
+		return
+	}
+	st.usage.Record(metrics.Event{Type: metrics.CommandRun, Model: st.model, Command: metrics.CommandName(command), Success: !failed})
+	if st.task != nil {
+		st.task.lastActivity = time.Now()
+		st.task.commands++
+		st.task.lastFailed = failed
+		if failed {
+			st.task.failures++
+		}
+	}
+}
+
+// RecordModelResponse records how long the model took to answer a request
+func (st *SessionStats) RecordModelResponse(duration time.Duration, err error) {
+	st.mutex.Lock()
+	defer st.mutex.Unlock()
+	if st.usage == nil {
+		return
+	}
+	st.usage.Record(metrics.Event{Type: metrics.LLMRequest, Model: st.model, Success: err == nil, DurationMS: duration.Milliseconds()})
+	if st.task != nil {
+		st.task.lastActivity = time.Now()
+	}
+}
+
+// FinishTask records the outcome of the current task: it succeeded unless
+// its last command failed. Its duration runs to its last command or model
+// response, so time spent waiting for the next prompt is not counted.
+func (st *SessionStats) FinishTask() {
+	st.mutex.Lock()
+	defer st.mutex.Unlock()
+	st.finishTask()
+}
+
+func (st *SessionStats) finishTask() {
+	if st.task == nil {
+		return
+	}
+	st.usage.Record(metrics.Event{
+		Type:       metrics.TaskFinished,
+		Model:      st.model,
+		Attempts:   st.task.commands,
+		Failures:   st.task.failures,
+		Success:    !st.task.lastFailed,
+		DurationMS: st.task.lastActivity.Sub(st.task.start).Milliseconds(),
+	})
+	st.task = nil
+}
+
+// TrackFiles has the summary list the files modified and the documents
+// indexed for them, which are only known when auto-indexing looks for
+// changes. Without it the summary leaves them out rather than report none.
+func (st *SessionStats) TrackFiles() {
+	st.mutex.Lock()
+	defer st.mutex.Unlock()
+	st.trackFiles = true
+}
+
+// RecordAutoIndex counts files detected as changed and documents stored for them.
+// It is safe to call from background auto-index goroutines.
+func (st *SessionStats) RecordAutoIndex(changedFiles []string, documentsIndexed int) {
+	st.mutex.Lock()
+	defer st.mutex.Unlock()
+	for _, file := range changedFiles {
+		st.filesModified[file] = struct{}{}
+	}
+	st.documentsIndexed += documentsIndexed
+}
+
+// FilesModified returns the sorted list of files changed during the session
+func (st *SessionStats) FilesModified() []string {
+	st.mutex.Lock()
+	defer st.mutex.Unlock()
+	files := make([]string, 0, len(st.filesModified))
+	for file := range st.filesModified {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	return files
+}
+
+// Summary renders the end-of-session recap
+func (st *SessionStats) Summary() string {
+	files := st.FilesModified()
+
+	st.mutex.Lock()
+	defer st.mutex.Unlock()
+
+	var summary strings.Builder
+	summary.WriteString("Session summary:\n")
+	summary.WriteString(fmt.Sprintf("  Duration:          %s\n", time.Since(st.startTime).Round(time.Second)))
+	summary.WriteString(fmt.Sprintf("  Tasks attempted:   %d\n", st.tasksAttempted))
+	summary.WriteString(fmt.Sprintf("  Commands run:      %d (%d failed)", st.commandsRun, st.commandsFailed))
+	if st.trackFiles {
+		summary.WriteString(fmt.Sprintf("\n  Files modified:    %d", len(files)))
+		for _, file := range files {
+			summary.WriteString(fmt.Sprintf("\n    - %s", file))
+		}
+		summary.WriteString(fmt.Sprintf("\n  Documents indexed: %d", st.documentsIndexed))
+	}
+	return summary.String()
+}
+
+// finish ends the session's stats, finishing the current task, and records
+// the summary in the transcript, returning it to be shown
+func (s *Session) finish() string {
+	s.stats.FinishTask()
+	summary := s.stats.Summary()
+	s.config.Transcript.Record(transcript.Summary, summary)
+	return summary
+}
@@ -0,0 +1,194 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"rag-cli/internal/metrics"
+	"rag-cli/internal/transcript"
+)
+
+func TestSessionStats_Summary(t *testing.T) {
+	stats := NewSessionStats()
+	stats.TrackFiles()
+
+	// Scripted session: three tasks, five commands with two failures,
+	// and two rounds of auto-indexing touching the same file twice
+	for i := 0; i < 3; i++ {
+		stats.RecordTask()
+	}
+	for _, failed := range []bool{false, true, false, true, false} {
+		stats.RecordCommand("make", failed)
+	}
+	stats.RecordAutoIndex([]string{"notes.md", "main.go"}, 2)
+	stats.RecordAutoIndex([]string{"notes.md"}, 1)
+
+	summary := stats.Summary()
+
+	expected := []string{
+		"Session summary:",
+		"Tasks attempted:   3",
+		"Commands run:      5 (2 failed)",
+		"Files modified:    2",
+		"- main.go",
+		"- notes.md",
+		"Documents indexed: 3",
+		"Duration:",
+	}
+	for _, want := range expected {
+		if !strings.Contains(summary, want) {
+			t.Errorf("Expected summary to contain %q, got:\n%s", want, summary)
+		}
+	}
+}
+
+func TestSessionStats_EmptySession(t *testing.T) {
+	summary := NewSessionStats().Summary()
+
+	if !strings.Contains(summary, "Commands run:      0 (0 failed)") {
+		t.Errorf("Expected zero command counts, got:\n%s", summary)
+	}
+	if strings.Contains(summary, "Files modified") || strings.Contains(summary, "Documents indexed") {
+		t.Errorf("Expected no file counts without auto-indexing, got:\n%s", summary)
+	}
+
+	stats := NewSessionStats()
+	stats.TrackFiles()
+	if summary := stats.Summary(); !strings.Contains(summary, "Files modified:    0") {
+		t.Errorf("Expected zero modified files with auto-indexing, got:\n%s", summary)
+	}
+}
+
+func TestSimpleSession_SummaryOnExit(t *testing.T) {
+	testCases := []struct {
+		name  string
+		input string
+	}{
+		{"exit command", "help\nexit\n"},
+		{"quit command", "quit\n"},
+		{"end of input", "help\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			session := NewSimpleSession(&SessionConfig{}, nil, nil, nil, nil)
+
+			var runErr error
+			output := withMockedInput(tc.input, func() {
+				runErr = session.Run(context.Background())
+			})
+
+			if runErr != nil {
+				t.Fatalf("Expected clean exit, got: %v", runErr)
+			}
+			if !strings.Contains(output, "Session summary:") {
+				t.Errorf("Expected session summary in output, got: %s", output)
+			}
+		})
+	}
+}
+
+func TestSimpleSession_SummaryInTranscript(t *testing.T) {
+	writer := transcript.NewWriter(t.TempDir(), 0)
+	session := NewSimpleSession(&SessionConfig{Transcript: writer}, nil, nil, nil, nil)
+
+	withMockedInput("exit\n", func() {
+		session.Run(context.Background())
+	})
+
+	events, err := transcript.Load(writer.Path())
+	if err != nil {
+		t.Fatalf("Failed to load transcript: %v", err)
+	}
+	last := events[len(events)-1]
+	if last.Type != transcript.Summary || !strings.Contains(last.Content, "Session summary:") {
+		t.Errorf("Expected the transcript to end with the summary, got %+v", events)
+	}
+}
+
+func TestSimpleSession_Cancel(t *testing.T) {
+	session := NewSimpleSession(&SessionConfig{}, nil, nil, nil, nil)
+	session.input = make(chan inputLine) // Input that never arrives
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	var runErr error
+	start := time.Now()
+	output := withMockedInput("", func() {
+		runErr = session.Run(ctx)
+	})
+
+	if !errors.Is(runErr, context.Canceled) {
+		t.Fatalf("Expected a cancellation error, got: %v", runErr)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected the session to stop when cancelled, took %s", elapsed)
+	}
+	if !strings.Contains(output, "Session summary:") {
+		t.Errorf("Expected session summary in output, got: %s", output)
+	}
+}
+
+func TestSessionStats_Usage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usage.jsonl")
+	stats := NewSessionStats()
+	stats.RecordUsage(metrics.NewRecorder(path), "granite-code:3b")
+
+	// Two tasks: the first needs a retry, the second fails; the second is
+	// finished when the session ends
+	stats.RecordTask()
+	stats.RecordModelResponse(20*time.Millisecond, nil)
+	stats.RecordCommand("lss -la", true)
+	stats.RecordCommand("ls -la", false)
+	stats.RecordTask()
+	stats.RecordCommand("make build", true)
+	stats.FinishTask()
+	stats.FinishTask() // Nothing left to finish
+
+	events, err := metrics.Load(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	var types []string
+	for _, event := range events {
+		types = append(types, event.Type)
+		if event.Model != "granite-code:3b" {
+			t.Errorf("Expected every event to name the model, got %+v", event)
+		}
+	}
+	expected := "task_started,llm_request,command,command,task_finished,task_started,command,task_finished"
+	if strings.Join(types, ",") != expected {
+		t.Fatalf("Expected events %s, got %s", expected, strings.Join(types, ","))
+	}
+	if events[2].Command != "lss" || events[2].Success {
+		t.Errorf("Expected a failed lss command without arguments, got %+v", events[2])
+	}
+	if first := events[4]; first.Attempts != 2 || first.Failures != 1 || !first.Success {
+		t.Errorf("Expected the first task to succeed after a failure, got %+v", first)
+	}
+	if second := events[7]; second.Attempts != 1 || second.Success {
+		t.Errorf("Expected the second task to fail, got %+v", second)
+	}
+
+	summary := metrics.Summarize(events)
+	if summary.Tasks != 2 || summary.FirstAttempt != 0 || summary.LLMRequests != 1 {
+		t.Errorf("Expected 2 tasks, none on the first attempt, and 1 model response, got %+v", summary)
+	}
+}
+
+func TestSessionStats_NoUsage(t *testing.T) {
+	stats := NewSessionStats()
+	stats.RecordTask()
+	stats.RecordCommand("ls", false)
+	stats.RecordModelResponse(time.Second, nil)
+	stats.FinishTask()
+
+	if stats.task != nil {
+		t.Error("Expected no task to be tracked without a recorder")
+	}
+}
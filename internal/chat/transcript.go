@@ -0,0 +1,41 @@
+package chat
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"rag-cli/internal/transcript"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// PrintTranscript writes a saved chat to out in the colors the chat used: the
+// user's messages after a prompt, responses after "AI:", and commands with
+// their output and errors as they were shown when they ran
+func PrintTranscript(out io.Writer, events []transcript.Event) {
+	promptStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("86")).Bold(true)
+	aiStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("120"))
+	systemStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	commandStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("220")).Bold(true)
+	errorStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("196")).Bold(true)
+
+	for _, event := range events {
+		content := strings.TrimRight(event.Content, "\n")
+		switch event.Type {
+		case transcript.User:
+			fmt.Fprintln(out, systemStyle.Render(event.Time.Local().Format("2006-01-02 15:04:05")))
+			fmt.Fprintf(out, "%s%s\n", promptStyle.Render("> "), content)
+		case transcript.AI:
+			fmt.Fprintf(out, "%s %s\n", aiStyle.Render("AI:"), content)
+		case transcript.Command:
+			fmt.Fprintln(out, commandStyle.Render("$ "+content))
+		case transcript.Output:
+			fmt.Fprintln(out, content)
+		case transcript.Error:
+			fmt.Fprintln(out, errorStyle.Render("Error: "+content))
+		default:
+			fmt.Fprintln(out, systemStyle.Render(content))
+		}
+	}
+}
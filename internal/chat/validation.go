@@ -1,19 +1,29 @@
 package chat
 
 import (
+	"path/filepath"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 )
 
 // CommandValidator handles validation of command strings
-type CommandValidator struct{}
+type CommandValidator struct {
+	dangerous []safetyRule     // Added to the built-in dangerous patterns by safety.dangerous
+	allowlist []*regexp.Regexp // Programs chat.commands.allowlist permits; empty permits all
+	denylist  []*regexp.Regexp // Programs chat.commands.denylist refuses
+}
 
 // NewCommandValidator creates a new command validator
 func NewCommandValidator() *CommandValidator {
 	return &CommandValidator{}
 }
 
-// IsValid checks if a command string is valid for execution
+// IsValid checks if a command string is valid for execution. A command may
+// span several lines only when they form one block, such as a heredoc or a
+// for loop; see ParseCommands. The checks for output and error messages look
+// at its first line, so a heredoc may write any text.
 func (v *CommandValidator) IsValid(cmd string) bool {
 	// Remove common invalid patterns
 	if cmd == "" {
@@ -30,13 +40,14 @@ func (v *CommandValidator) IsValid(cmd string) bool {
 		return false
 	}
 	
-	// Skip pure numeric responses (likely command output)
-	if strings.Contains(cmd, "\n") {
+	// Skip several commands run together, likely output or a list of steps
+	if strings.Contains(cmd, "\n") && len(commandBlocks(cmd)) != 1 {
 		return false
 	}
+	firstLine, _, _ := strings.Cut(cmd, "\n")
 	
 	// Skip lines that look like directory listings
-	if strings.Contains(cmd, "drwxr-xr-x") || strings.Contains(cmd, "total ") {
+	if strings.Contains(firstLine, "drwxr-xr-x") || strings.Contains(firstLine, "total ") {
 		return false
 	}
 	
@@ -48,14 +59,17 @@ func (v *CommandValidator) IsValid(cmd string) bool {
 	}
 	
 	// Skip error messages
-	if strings.Contains(cmd, "Error:") || strings.Contains(cmd, "command not found") {
+	if strings.Contains(firstLine, "Error:") || strings.Contains(firstLine, "command not found") {
 		return false
 	}
 	
 	return true
 }
 
-// ParseCommands extracts valid commands from a response string
+// ParseCommands extracts valid commands from a response string. Each line is
+// a command, except that heredocs, lines continued with a backslash, and
+// quotes, brace groups and compound commands such as for loops that span
+// lines are kept together as one command.
 func (v *CommandValidator) ParseCommands(response string) []string {
 	response = strings.TrimSpace(response)
 	if response == "" {
@@ -63,10 +77,8 @@ func (v *CommandValidator) ParseCommands(response string) []string {
 	}
 
 	// Split into individual commands
-	commands := strings.Split(response, "\n")
 	var validCommands []string
-	for _, cmd := range commands {
-		cmd = strings.TrimSpace(cmd)
+	for _, cmd := range commandBlocks(response) {
 		if cmd != "" && v.IsValid(cmd) {
 			validCommands = append(validCommands, cmd)
 		}
@@ -77,3 +89,479 @@ func (v *CommandValidator) ParseCommands(response string) []string {
 	}
 	return validCommands
 }
+
+// Risk grades how much harm a command could do if it is not what the user
+// wanted
+type Risk int
+
+const (
+	RiskSafe      Risk = iota
+	RiskCaution        // Changes things in ways that are hard to undo
+	RiskDangerous      // Can destroy data or the system; never auto-approved
+)
+
+func (r Risk) String() string {
+	switch r {
+	case RiskCaution:
+		return "caution"
+	case RiskDangerous:
+		return "dangerous"
+	default:
+		return "safe"
+	}
+}
+
+// RiskAssessment is the risk of a command and the reason it was given
+type RiskAssessment struct {
+	Level  Risk
+	Reason string // Empty for safe commands
+}
+
+// riskPatterns are dangerous whole command lines, checked before the
+// commands in them are looked at one by one
+var riskPatterns = []safetyRule{
+	{pattern: regexp.MustCompile(`\bmkfs(\.\w+)?\b`), reason: "formats a filesystem"},
+	{pattern: regexp.MustCompile(`\bdd\b.*\bof=/dev/(sd|hd|nvme|disk|mmcblk|xvd|vd)`), reason: "writes to a block device"},
+	{pattern: regexp.MustCompile(`>\s*/dev/(sd|hd|nvme|disk|mmcblk|xvd|vd)`), reason: "writes to a block device"},
+	{pattern: regexp.MustCompile(`:\(\)\s*\{\s*:\s*\|\s*:\s*&\s*\}\s*;\s*:`), reason: "is a fork bomb"},
+	{pattern: regexp.MustCompile(`\b(curl|wget)\b[^;&]*\|\s*(sudo\s+)?(sh|bash|zsh|dash|ksh|python3?|perl)\b`), reason: "pipes remote content to a shell"},
+}
+
+// ClassifyRisk grades cmd as safe, caution or dangerous. Quotes and
+// backslashes are removed from arguments before they are checked, so
+// rm -rf "/" is caught like rm -rf /. Commands run through wrappers such as
+// env, nohup or xargs, and the scripts given to sh -c and eval, are graded
+// like the commands themselves, as are heredocs a shell reads its script
+// from, and relative paths deleted after a cd are taken to be outside the
+// working directory. The validator of a safety
+// policy also counts commands matching safety.dangerous as dangerous.
+func (v *CommandValidator) ClassifyRisk(cmd string) RiskAssessment {
+	return v.classifyRisk(cmd, "")
+}
+
+// classifyRisk is ClassifyRisk for a command run somewhere other than the
+// directory rag-cli started in when moved, which describes where, is not "".
+// Relative paths deleted or changed recursively there are dangerous.
+func (v *CommandValidator) classifyRisk(cmd, moved string) RiskAssessment {
+	var dangerous []safetyRule
+	if v != nil {
+		dangerous = v.dangerous
+	}
+	for _, rules := range [][]safetyRule{riskPatterns, dangerous} {
+		for _, rule := range rules {
+			if rule.pattern.MatchString(cmd) {
+				return RiskAssessment{Level: RiskDangerous, Reason: rule.reason}
+			}
+		}
+	}
+
+	assessment := RiskAssessment{Level: RiskSafe}
+	for _, part := range commandSeparator.Split(shellScript(cmd), -1) {
+		words := shellWords(part)
+		if risk := v.commandRisk(words, moved); risk.Level > assessment.Level {
+			assessment = risk
+		}
+		if changesDirectory(words) {
+			moved = "after changing directory"
+		}
+	}
+	for _, script := range shellHeredocs(cmd) {
+		if risk := v.classifyRisk(script, moved); risk.Level > assessment.Level {
+			assessment = risk
+		}
+	}
+	return assessment
+}
+
+// changesDirectory reports whether a command, given as its words, runs cd,
+// itself or in a script given to sh -c or eval
+func changesDirectory(words []string) bool {
+	program, args, _ := wrappedCommand(words)
+	if program == "cd" || program == "pushd" {
+		return true
+	}
+	script, ok := scriptArgument(program, args)
+	if !ok {
+		return false
+	}
+	for _, part := range commandSeparator.Split(shellScript(script), -1) {
+		if changesDirectory(shellWords(part)) {
+			return true
+		}
+	}
+	return false
+}
+
+// scriptArgument returns the script a shell run with -c, or eval, runs when
+// given args
+func scriptArgument(program string, args []string) (string, bool) {
+	switch program {
+	case "sh", "bash", "zsh", "dash", "ksh":
+		for i, arg := range args {
+			if arg == "-c" && i+1 < len(args) {
+				return args[i+1], true
+			}
+		}
+	case "eval":
+		return strings.Join(args, " "), true
+	}
+	return "", false
+}
+
+// wrappedCommand returns the program a command given as its words runs and
+// its arguments, past variable assignments and wrappers such as env, nohup
+// and xargs, and whether it reads its arguments from input through xargs.
+// sudo and doas are returned as they are, being risky themselves.
+func wrappedCommand(words []string) (program string, args []string, fromInput bool) {
+	for {
+		// Skip variable assignments such as LC_ALL=C before the program
+		for len(words) > 0 && strings.Contains(words[0], "=") && !strings.HasPrefix(words[0], "=") {
+			words = words[1:]
+		}
+		if len(words) == 0 {
+			return "", nil, fromInput
+		}
+		program = filepath.Base(words[0])
+		valueOptions, wrapper := commandWrappers[program]
+		if !wrapper || program == "sudo" || program == "doas" {
+			return program, words[1:], fromInput
+		}
+		fromInput = fromInput || program == "xargs"
+		words = wrappedArgs(program, words[1:], valueOptions)
+	}
+}
+
+// commandRisk grades a single command, given as its words, run somewhere
+// other than the working directory when moved is not ""
+func (v *CommandValidator) commandRisk(words []string, moved string) RiskAssessment {
+	program, args, fromInput := wrappedCommand(words)
+	if program == "" {
+		return RiskAssessment{Level: RiskSafe}
+	}
+
+	if script, ok := scriptArgument(program, args); ok {
+		return v.classifyRisk(script, moved)
+	}
+
+	flags, operands := splitFlags(args)
+	switch program {
+	case "find":
+		for i, arg := range args {
+			runs := (arg == "-exec" || arg == "-execdir" || arg == "-ok" || arg == "-okdir") && i+1 < len(args)
+			if arg == "-delete" || runs && filepath.Base(args[i+1]) == "rm" {
+				return RiskAssessment{Level: RiskDangerous, Reason: "deletes every file find matches"}
+			}
+		}
+	case "sudo", "doas", "su":
+		return RiskAssessment{Level: RiskDangerous, Reason: "runs as root"}
+	case "shutdown", "reboot", "halt", "poweroff":
+		return RiskAssessment{Level: RiskDangerous, Reason: "shuts down or restarts the machine"}
+	case "rm":
+		if !hasFlag(flags, 'r', "recursive") && !hasFlag(flags, 'R', "recursive") {
+			return RiskAssessment{Level: RiskCaution, Reason: "deletes files"}
+		}
+		if fromInput {
+			return RiskAssessment{Level: RiskDangerous, Reason: "recursively deletes paths read from input"}
+		}
+		for _, target := range operands {
+			if outsideWorkingDir(target) {
+				return RiskAssessment{Level: RiskDangerous, Reason: "recursively deletes " + target + ", outside the working directory"}
+			}
+			if moved != "" {
+				return RiskAssessment{Level: RiskDangerous, Reason: "recursively deletes " + target + " " + moved}
+			}
+		}
+		return RiskAssessment{Level: RiskCaution, Reason: "recursively deletes files"}
+	case "chmod":
+		if hasFlag(flags, 'R', "recursive") {
+			for _, mode := range operands {
+				if mode == "777" || mode == "0777" || mode == "a+rwx" || mode == "o+w" {
+					return RiskAssessment{Level: RiskDangerous, Reason: "makes files world-writable recursively"}
+				}
+			}
+			if moved != "" && len(operands) > 1 {
+				return RiskAssessment{Level: RiskDangerous, Reason: "changes permissions of " + strings.Join(operands[1:], " ") + " recursively " + moved}
+			}
+			return RiskAssessment{Level: RiskCaution, Reason: "changes permissions recursively"}
+		}
+	case "chown", "chgrp":
+		if hasFlag(flags, 'R', "recursive") {
+			if moved != "" && len(operands) > 1 {
+				return RiskAssessment{Level: RiskDangerous, Reason: "changes ownership of " + strings.Join(operands[1:], " ") + " recursively " + moved}
+			}
+			return RiskAssessment{Level: RiskCaution, Reason: "changes ownership recursively"}
+		}
+	case "kill", "pkill", "killall":
+		return RiskAssessment{Level: RiskCaution, Reason: "stops processes"}
+	case "git":
+		if len(operands) == 0 {
+			break
+		}
+		switch subcommand := operands[0]; {
+		case subcommand == "reset" && slices.Contains(flags, "--hard"),
+			subcommand == "clean" && hasFlag(flags, 'f', "force"),
+			subcommand == "push" && (hasFlag(flags, 'f', "force") || slices.Contains(flags, "--force-with-lease")):
+			return RiskAssessment{Level: RiskCaution, Reason: "discards changes or rewrites history"}
+		}
+	}
+	return RiskAssessment{Level: RiskSafe}
+}
+
+// splitFlags separates the flags in args from the operands. Everything after
+// "--" is an operand.
+func splitFlags(args []string) (flags, operands []string) {
+	for i, arg := range args {
+		if arg == "--" {
+			return flags, append(operands, args[i+1:]...)
+		}
+		if len(arg) > 1 && strings.HasPrefix(arg, "-") {
+			flags = append(flags, arg)
+		} else {
+			operands = append(operands, arg)
+		}
+	}
+	return flags, operands
+}
+
+// hasFlag reports whether flags include the short flag, alone or combined
+// as in -rf, or the long one
+func hasFlag(flags []string, short rune, long string) bool {
+	for _, flag := range flags {
+		if flag == "--"+long {
+			return true
+		}
+		if !strings.HasPrefix(flag, "--") && strings.ContainsRune(flag[1:], short) {
+			return true
+		}
+	}
+	return false
+}
+
+// outsideWorkingDir reports whether a path may point outside the working
+// directory: it is absolute, starts from the home directory, or climbs out
+// with ..
+func outsideWorkingDir(path string) bool {
+	switch {
+	case strings.HasPrefix(path, "/"), strings.HasPrefix(path, "~"),
+		strings.HasPrefix(path, "$HOME"), strings.HasPrefix(path, "${HOME}"):
+		return true
+	}
+	clean := filepath.Clean(path)
+	return clean == ".." || strings.HasPrefix(clean, "../")
+}
+
+// shellWords splits a command into words the way the shell would, removing
+// quotes and backslash escapes. It does not expand anything.
+func shellWords(command string) []string {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	var quote rune
+	escaped := false
+	for _, r := range command {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped, inWord = true, true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inWord = r, true
+		case r == ' ' || r == '\t' || r == '(' || r == ')':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words
+}
+
+// policySeparator splits a command line into the commands it runs for the
+// chat.commands policy. Unlike commandSeparator it also splits at & and at
+// process substitutions, and >& and <& are rewritten first so that 2>&1 is
+// not taken for a background command.
+var (
+	policySeparator     = regexp.MustCompile(`\|\|?|&&?|;|\n|\$\(|[<>]\(|\x60`)
+	policyRedirectFixer = strings.NewReplacer(">&", ">", "<&", "<")
+)
+
+// policyCommandSeparator splits like policySeparator but keeps command
+// substitutions in the command they belong to, so that one used as the
+// command word, as in $(echo rm) x, is seen there
+var policyCommandSeparator = regexp.MustCompile(`\|\|?|&&?|;|\n|[<>]\(`)
+
+// policyRedirect matches a word that is a redirection, such as 2>/dev/null
+var policyRedirect = regexp.MustCompile(`^[0-9]*(<<?|>>?)`)
+
+// shellBuiltins are commands the shell runs itself that cannot start other
+// programs, so they are permitted even when chat.commands.allowlist does not
+// name them. The denylist still applies.
+var shellBuiltins = map[string]bool{
+	"cd": true, "pwd": true, "echo": true, "printf": true, "true": true,
+	"false": true, "test": true, "[": true, "[[": true, ":": true, "export": true,
+	"unset": true, "set": true, "read": true, "shift": true, "exit": true,
+	"return": true, "local": true, "type": true, "alias": true, "umask": true,
+	"wait": true,
+}
+
+// shellKeywords are reserved words that may start a command without being one
+var shellKeywords = map[string]bool{
+	"if": true, "then": true, "else": true, "elif": true, "fi": true, "do": true,
+	"done": true, "while": true, "until": true, "esac": true, "!": true,
+	"{": true, "}": true, "time": true,
+}
+
+// commandWrappers are programs that run the command after their own options,
+// mapped to the options that take a value
+var commandWrappers = map[string][]string{
+	"sudo":    {"-u", "-g", "-C", "-D", "-h", "-p", "-r", "-t", "-U"},
+	"doas":    {"-u", "-C"},
+	"env":     {"-u", "-C", "-S"},
+	"nice":    {"-n"},
+	"nohup":   nil,
+	"command": nil,
+	"builtin": nil,
+	"exec":    {"-a"},
+	"xargs":   {"-a", "-d", "-E", "-I", "-L", "-n", "-P", "-s"},
+	"timeout": {"-k", "-s"},
+}
+
+// IsPermitted reports whether chat.commands allows every program cmd runs.
+// Each step of a pipe or list is checked, as are the commands run through
+// sudo, env and similar wrappers and the scripts given to sh -c, after any
+// variable assignments such as FOO=bar. With no allowlist any program not on
+// the denylist is permitted. A program named by a variable or a command
+// substitution, as in $CMD or $(echo rm), is never permitted, as what it
+// runs cannot be known in advance.
+func (v *CommandValidator) IsPermitted(cmd string) bool {
+	return v.blockedProgram(cmd) == ""
+}
+
+// blockedProgram returns the first program in cmd that chat.commands does
+// not permit, or "" when they all are
+func (v *CommandValidator) blockedProgram(cmd string) string {
+	if v == nil || (len(v.allowlist) == 0 && len(v.denylist) == 0) {
+		return ""
+	}
+	script := policyRedirectFixer.Replace(shellScript(cmd))
+	for _, separator := range []*regexp.Regexp{policyCommandSeparator, policySeparator} {
+		for _, part := range separator.Split(script, -1) {
+			if program := v.blockedInWords(shellWords(part)); program != "" {
+				return program
+			}
+		}
+	}
+	return ""
+}
+
+// blockedInWords checks the programs run by a single command, given as its
+// words
+func (v *CommandValidator) blockedInWords(words []string) string {
+	for len(words) > 0 {
+		word := words[0]
+		switch {
+		case shellKeywords[word]:
+			words = words[1:]
+			continue
+		case word == "for":
+			// The rest is a variable and the words it takes
+			return ""
+		case strings.Contains(word, "=") && !strings.HasPrefix(word, "="):
+			words = words[1:]
+			continue
+		case policyRedirect.MatchString(word):
+			if policyRedirect.FindString(word) == word && len(words) > 1 {
+				words = words[1:] // The target is the next word
+			}
+			words = words[1:]
+			continue
+		}
+
+		switch {
+		case word == "$":
+			return "$(...)" // shellWords ends the word at the parenthesis
+		case strings.HasPrefix(word, "`"):
+			return "`...`"
+		case strings.HasPrefix(word, "$"):
+			return word
+		}
+		program := filepath.Base(word)
+		if !v.permits(program) {
+			return program
+		}
+		args := words[1:]
+		switch program {
+		case "sh", "bash", "zsh", "dash", "ksh":
+			for i, arg := range args {
+				if arg == "-c" && i+1 < len(args) {
+					return v.blockedProgram(args[i+1])
+				}
+			}
+			return ""
+		}
+		valueOptions, wrapper := commandWrappers[program]
+		if !wrapper {
+			return ""
+		}
+		words = wrappedArgs(program, args, valueOptions)
+	}
+	return ""
+}
+
+// wrappedArgs drops the options of a wrapper such as sudo from args, and
+// the duration given to timeout, leaving the command it runs
+func wrappedArgs(program string, args, valueOptions []string) []string {
+	args = skipWrapperOptions(args, valueOptions)
+	if program == "timeout" && len(args) > 0 {
+		args = args[1:]
+	}
+	return args
+}
+
+// skipWrapperOptions drops the options of a wrapper such as sudo from args
+func skipWrapperOptions(args, valueOptions []string) []string {
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") {
+		option := args[0]
+		args = args[1:]
+		if option == "--" {
+			break
+		}
+		if slices.Contains(valueOptions, option) && len(args) > 0 {
+			args = args[1:]
+		}
+	}
+	return args
+}
+
+// permits reports whether chat.commands lets program run. The denylist wins
+// over the allowlist, and shell builtins need not be allowlisted.
+func (v *CommandValidator) permits(program string) bool {
+	for _, pattern := range v.denylist {
+		if pattern.MatchString(program) {
+			return false
+		}
+	}
+	if len(v.allowlist) == 0 || shellBuiltins[program] {
+		return true
+	}
+	for _, pattern := range v.allowlist {
+		if pattern.MatchString(program) {
+			return true
+		}
+	}
+	return false
+}
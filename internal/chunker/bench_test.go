@@ -0,0 +1,56 @@
+package chunker
+
+import (
+	"strings"
+	"testing"
+
+	"rag-cli/pkg/config"
+)
+
+// corpus returns size bytes of prose with some multibyte text, so rune
+// handling is exercised
+func corpus(size int) string {
+	paragraph := strings.Repeat("The quick brown fox jumps over the lazy dog. ", 20) + "Ünïcödé façade — naïve café.\n\n"
+	return strings.Repeat(paragraph, size/len(paragraph)+1)[:size]
+}
+
+var corpora = []struct {
+	name string
+	size int
+}{
+	{"1MB", 1 << 20},
+	{"10MB", 10 << 20},
+}
+
+func BenchmarkChunkText(b *testing.B) {
+	c := New(config.ChunkerConfig{ChunkSize: 1000, ChunkOverlap: 200})
+	for _, tc := range corpora {
+		text := corpus(tc.size)
+		b.Run(tc.name, func(b *testing.B) {
+			b.SetBytes(int64(len(text)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := c.ChunkText(text); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkChunkReader(b *testing.B) {
+	c := New(config.ChunkerConfig{ChunkSize: 1000, ChunkOverlap: 200})
+	emit := func(chunk string) error { return nil }
+	for _, tc := range corpora {
+		text := corpus(tc.size)
+		b.Run(tc.name, func(b *testing.B) {
+			b.SetBytes(int64(len(text)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if err := c.ChunkReader(strings.NewReader(text), emit); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package chunker
+
+import (
+	"bufio"
+	"io"
+	"unicode/utf8"
+)
+
+// Boundary strengths, strongest first. A chunk ends at the strongest
+// boundary found in the second half of its window.
+const (
+	boundaryParagraph = iota // After a blank line
+	boundaryLine             // After a newline
+	boundarySentence         // After ". ", "! " or "? "
+	boundaryWord             // After a space or tab
+	boundaryNone
+)
+
+// boundaryAt reports the strongest boundary that splitting s at byte offset p
+// would fall on. Only bytes before p are examined, and they are all ASCII, so
+// p is always a rune boundary when a boundary is found.
+func boundaryAt(s string, p int) int {
+	if p <= 0 || p > len(s) {
+		return boundaryNone
+	}
+	switch s[p-1] {
+	case '\n':
+		if p >= 2 && s[p-2] == '\n' {
+			return boundaryParagraph
+		}
+		return boundaryLine
+	case ' ', '\t':
+		if p >= 2 && (s[p-2] == '.' || s[p-2] == '!' || s[p-2] == '?') {
+			return boundarySentence
+		}
+		return boundaryWord
+	}
+	return boundaryNone
+}
+
+// boundaryChunk finds the next chunk of s, which starts at the beginning of
+// s: the chunk is s[:end] and the chunk after it starts at s[next:]. Only the
+// first chunkSize runes of s are examined, so a reader can call it with a
+// window of text as long as atEOF tells it whether more text follows.
+//
+// The chunk ends at the last paragraph break in the second half of the
+// window, or failing that the last line end, sentence end or space there, or
+// failing that the strongest boundary anywhere in the window. A window with
+// no boundary at all is cut after chunkSize runes. The next chunk starts at
+// the first sentence or stronger boundary within the last chunkOverlap runes
+// of this one, then the first space there, so the overlap begins with a whole
+// sentence or word. The overlap is at most half the chunk.
+func (c *Client) boundaryChunk(s string, atEOF bool) (end, next int) {
+	// Find the window and its midpoint
+	limit, half := 0, 0
+	for n := 0; n < c.chunkSize && limit < len(s); n++ {
+		if n == c.chunkSize/2 {
+			half = limit
+		}
+		_, width := utf8.DecodeRuneInString(s[limit:])
+		limit += width
+	}
+	if limit == len(s) && atEOF {
+		return len(s), len(s) // The rest fits in one chunk
+	}
+
+	// Boundaries before any text would make chunks of nothing but whitespace
+	var lastAny, lastLate [boundaryNone]int
+	text := false
+	for p := 1; p <= limit; p++ {
+		if kind := boundaryAt(s, p); kind != boundaryNone && text {
+			lastAny[kind] = p
+			if p > half {
+				lastLate[kind] = p
+			}
+		}
+		text = text || !isSpace(s[p-1])
+	}
+	end = limit
+	for _, candidates := range [][boundaryNone]int{lastLate, lastAny} {
+		if found := strongest(candidates); found > 0 {
+			end = found
+			break
+		}
+	}
+
+	return end, c.overlapStart(s, end)
+}
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r'
+}
+
+// strongest returns the position of the strongest boundary recorded, or 0
+func strongest(positions [boundaryNone]int) int {
+	for _, p := range positions {
+		if p > 0 {
+			return p
+		}
+	}
+	return 0
+}
+
+// overlapStart returns where the chunk after s[:end] starts, at a boundary
+// within the last chunkOverlap runes of it
+func (c *Client) overlapStart(s string, end int) int {
+	overlap := min(c.chunkOverlap, utf8.RuneCountInString(s[:end])/2)
+	if overlap <= 0 {
+		return end
+	}
+	from := end
+	for n := 0; n < overlap; n++ {
+		_, width := utf8.DecodeLastRuneInString(s[:from])
+		from -= width
+	}
+
+	word := 0
+	for p := from; p < end; p++ {
+		switch kind := boundaryAt(s, p); {
+		case kind <= boundarySentence:
+			return p
+		case kind == boundaryWord && word == 0:
+			word = p
+		}
+	}
+	if word > 0 {
+		return word
+	}
+	return from
+}
+
+// chunkTextAtBoundaries is ChunkText for the boundary strategy
+func (c *Client) chunkTextAtBoundaries(text string) []string {
+	var chunks []string
+	for start := 0; start < len(text); {
+		end, next := c.boundaryChunk(text[start:], true)
+		chunks = append(chunks, text[start:start+end])
+		if start+end == len(text) {
+			break
+		}
+		start += next
+	}
+	return chunks
+}
+
+// chunkReaderAtBoundaries is ChunkReader for the boundary strategy. It keeps
+// one rune more than a chunk buffered, which is enough for boundaryChunk to
+// tell whether the rest of the text fits in one chunk.
+func (c *Client) chunkReaderAtBoundaries(r io.Reader, emit func(chunk string) error) error {
+	reader := bufio.NewReader(r)
+	var window []byte
+	runes := 0
+	eof := false
+	for {
+		for !eof && runes <= c.chunkSize {
+			ch, _, err := reader.ReadRune()
+			if err == io.EOF {
+				eof = true
+				break
+			}
+			if err != nil {
+				return err
+			}
+			window = utf8.AppendRune(window, ch)
+			runes++
+		}
+		if len(window) == 0 {
+			return nil
+		}
+
+		text := string(window)
+		end, next := c.boundaryChunk(text, eof)
+		if err := emit(text[:end]); err != nil {
+			return err
+		}
+		if eof && end == len(text) {
+			return nil
+		}
+		runes -= utf8.RuneCountInString(text[:next])
+		window = window[:copy(window, window[next:])]
+	}
+}
@@ -1,32 +1,103 @@
 package chunker
 
-import "rag-cli/pkg/config"
+import (
+	"bufio"
+	"io"
+	"unicode/utf8"
+
+	"rag-cli/pkg/config"
+)
 
 type Client struct {
 	chunkSize    int
 	chunkOverlap int
+	fixed        bool // Cut every chunkSize runes rather than at boundaries
 }
 
 func New(cfg config.ChunkerConfig) *Client {
 	return &Client{
 		chunkSize:    cfg.ChunkSize,
 		chunkOverlap: cfg.ChunkOverlap,
+		fixed:        cfg.Strategy == config.ChunkStrategyFixed,
 	}
 }
 
+// ChunkText splits text into chunks of at most chunkSize runes that overlap
+// by up to chunkOverlap runes. With the boundary strategy, the default,
+// chunks end at paragraph, line, sentence or word boundaries; with the fixed
+// strategy each chunk starts chunkSize-chunkOverlap runes after the previous
+// one. Chunks are slices of text rather than copies, so chunking allocates
+// only the slice of chunks.
 func (c *Client) ChunkText(text string) ([]string, error) {
-	// Dummy implementation: split text into chunks of "chunkSize" bytes
+	if !c.fixed {
+		return c.chunkTextAtBoundaries(text), nil
+	}
+	step := c.chunkSize - c.chunkOverlap
 	var chunks []string
-	textRunes := []rune(text)
-	for i := 0; i < len(textRunes); i += c.chunkSize - c.chunkOverlap {
-		end := i + c.chunkSize
-		if end > len(textRunes) {
-			end = len(textRunes)
-		}
-		chunks = append(chunks, string(textRunes[i:end]))
-		if end == len(textRunes) {
+	for start := 0; start < len(text); {
+		// Find where this chunk ends and where the next one starts in one pass
+		end, next := start, start
+		for n := 0; n < c.chunkSize && end < len(text); n++ {
+			if n == step {
+				next = end
+			}
+			_, width := utf8.DecodeRuneInString(text[end:])
+			end += width
+		}
+		chunks = append(chunks, text[start:end])
+		if end == len(text) {
 			break
 		}
+		if step == c.chunkSize {
+			next = end
+		}
+		start = next
 	}
 	return chunks, nil
 }
+
+// ChunkReader splits the text read from r into the same chunks as ChunkText,
+// passing each to emit as soon as it is complete, so only one chunk is held
+// in memory however long the text is. It stops at the first error from r or
+// emit and returns it.
+func (c *Client) ChunkReader(r io.Reader, emit func(chunk string) error) error {
+	if !c.fixed {
+		return c.chunkReaderAtBoundaries(r, emit)
+	}
+	reader := bufio.NewReader(r)
+	step := c.chunkSize - c.chunkOverlap
+	window := make([]rune, 0, c.chunkSize)
+	for {
+		eof := false
+		for len(window) < c.chunkSize {
+			ch, _, err := reader.ReadRune()
+			if err == io.EOF {
+				eof = true
+				break
+			}
+			if err != nil {
+				return err
+			}
+			window = append(window, ch)
+		}
+		if len(window) == 0 {
+			return nil
+		}
+		if err := emit(string(window)); err != nil {
+			return err
+		}
+		if eof {
+			return nil
+		}
+
+		// A full window is the last chunk when nothing follows it
+		next, _, err := reader.ReadRune()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		window = append(window[:copy(window, window[step:])], next)
+	}
+}
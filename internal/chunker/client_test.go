@@ -0,0 +1,164 @@
+package chunker
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"rag-cli/pkg/config"
+)
+
+func TestChunkText(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		size     int
+		overlap  int
+		expected []string
+	}{
+		{"empty", "", 4, 1, nil},
+		{"overlapping", "abcdefghij", 4, 1, []string{"abcd", "defg", "ghij"}},
+		{"partial last chunk", "abcdefghijk", 4, 1, []string{"abcd", "defg", "ghij", "jk"}},
+		{"no overlap", "abcdefghij", 4, 0, []string{"abcd", "efgh", "ij"}},
+		{"counts runes, not bytes", "héllo wörld", 4, 2, []string{"héll", "llo ", "o wö", "wörl", "rld"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(config.ChunkerConfig{ChunkSize: tt.size, ChunkOverlap: tt.overlap, Strategy: config.ChunkStrategyFixed})
+			chunks, err := c.ChunkText(tt.text)
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if strings.Join(chunks, "|") != strings.Join(tt.expected, "|") || len(chunks) != len(tt.expected) {
+				t.Errorf("Expected chunks %q, got %q", tt.expected, chunks)
+			}
+		})
+	}
+}
+
+func TestChunkText_Boundaries(t *testing.T) {
+	markdown := "# Setup\n\nInstall the tool. Then run it.\n\n## Usage\n\nRun `rag-cli index` first.\n"
+	goSource := "package main\n\nfunc main() {\n\tfmt.Println(\"hi\")\n}\n\nfunc helper() int {\n\treturn 42\n}\n"
+
+	tests := []struct {
+		name     string
+		text     string
+		size     int
+		overlap  int
+		expected []string
+	}{
+		{"empty", "", 10, 2, nil},
+		{"fits in one chunk", "Short text.", 40, 10, []string{"Short text."}},
+		{
+			"markdown paragraphs", markdown, 45, 0,
+			[]string{"# Setup\n\nInstall the tool. Then run it.\n\n", "## Usage\n\nRun `rag-cli index` first.\n"},
+		},
+		{
+			"go source lines", goSource, 40, 0,
+			[]string{"package main\n\nfunc main() {\n", "\tfmt.Println(\"hi\")\n}\n\n", "func helper() int {\n\treturn 42\n}\n"},
+		},
+		{
+			"sentences", "One sentence here. Another one follows. And a third.", 30, 0,
+			[]string{"One sentence here. ", "Another one follows. ", "And a third."},
+		},
+		{
+			"overlap starts at a word", "alpha beta gamma delta epsilon zeta", 20, 8,
+			[]string{"alpha beta gamma ", "gamma delta epsilon ", "epsilon zeta"},
+		},
+		{"no boundaries", "abcdefghij", 4, 1, []string{"abcd", "defg", "ghij"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(config.ChunkerConfig{ChunkSize: tt.size, ChunkOverlap: tt.overlap})
+			chunks, err := c.ChunkText(tt.text)
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if strings.Join(chunks, "|") != strings.Join(tt.expected, "|") || len(chunks) != len(tt.expected) {
+				t.Errorf("Expected chunks %q, got %q", tt.expected, chunks)
+			}
+		})
+	}
+}
+
+func TestChunkText_LongLine(t *testing.T) {
+	line := strings.Repeat("x", 10000)
+	c := New(config.ChunkerConfig{ChunkSize: 1000, ChunkOverlap: 100})
+
+	chunks, err := c.ChunkText(line)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(chunks) != 11 {
+		t.Errorf("Expected the line to be cut into 11 chunks, got %d", len(chunks))
+	}
+	for i, chunk := range chunks {
+		if len(chunk) > 1000 {
+			t.Errorf("Expected chunk %d to be at most 1000 runes, got %d", i, len(chunk))
+		}
+	}
+	if !strings.HasPrefix(chunks[1], chunks[0][900:]) {
+		t.Error("Expected chunks of a line without boundaries to overlap")
+	}
+}
+
+func TestChunkReader_MatchesChunkText(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		size    int
+		overlap int
+	}{
+		{"empty", "", 10, 2},
+		{"shorter than a chunk", "hello", 10, 2},
+		{"exactly one chunk", "0123456789", 10, 2},
+		{"one rune over", "0123456789a", 10, 2},
+		{"several chunks", strings.Repeat("abcdefghij", 7) + "xyz", 10, 3},
+		{"no overlap", strings.Repeat("abc", 10), 5, 0},
+		{"multibyte", strings.Repeat("héllo wörld ", 20), 16, 4},
+		{"paragraphs", strings.Repeat("A sentence. Another one!\nNext line here.\n\n", 30), 50, 12},
+		{"long line", strings.Repeat("x", 10000), 1000, 100},
+	}
+
+	for _, strategy := range config.ChunkStrategies {
+		for _, tt := range tests {
+			t.Run(strategy+"/"+tt.name, func(t *testing.T) {
+				c := New(config.ChunkerConfig{ChunkSize: tt.size, ChunkOverlap: tt.overlap, Strategy: strategy})
+				expected, _ := c.ChunkText(tt.text)
+
+				var got []string
+				err := c.ChunkReader(strings.NewReader(tt.text), func(chunk string) error {
+					got = append(got, chunk)
+					return nil
+				})
+				if err != nil {
+					t.Fatalf("Expected no error, got: %v", err)
+				}
+				if len(got) != len(expected) {
+					t.Fatalf("Expected %d chunks %q, got %d %q", len(expected), expected, len(got), got)
+				}
+				for i := range expected {
+					if got[i] != expected[i] {
+						t.Errorf("Expected chunk %d to be %q, got %q", i, expected[i], got[i])
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestChunkReader_StopsOnEmitError(t *testing.T) {
+	c := New(config.ChunkerConfig{ChunkSize: 4, ChunkOverlap: 0})
+	stop := errors.New("stop")
+
+	calls := 0
+	err := c.ChunkReader(strings.NewReader(strings.Repeat("x", 100)), func(chunk string) error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Errorf("Expected to stop after the first chunk with its error, got %d calls and %v", calls, err)
+	}
+}
@@ -2,19 +2,56 @@ package embeddings
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"time"
 
+	"rag-cli/internal/httpclient"
 	"rag-cli/pkg/config"
 )
 
+// ErrModelNotFound is returned when the server does not have the embedding model
+var ErrModelNotFound = errors.New("embedding model not found")
+
+// ErrNoEmbeddings is returned when the server answers without an embedding
+var ErrNoEmbeddings = errors.New("no embeddings returned")
+
+// Embedder generates vector embeddings for text. Client is the production
+// implementation, backed by Ollama or an OpenAI-compatible server.
+type Embedder interface {
+	GenerateEmbedding(text string) ([]float32, error)
+}
+
+// ContextEmbedder is an Embedder whose requests can be cancelled
+type ContextEmbedder interface {
+	Embedder
+	GenerateEmbeddingContext(ctx context.Context, text string) ([]float32, error)
+}
+
+// Generate embeds text with e, stopping when ctx is cancelled. Embedders that
+// do not take a context are only checked before the request starts.
+func Generate(ctx context.Context, e Embedder, text string) ([]float32, error) {
+	if ce, ok := e.(ContextEmbedder); ok {
+		return ce.GenerateEmbeddingContext(ctx, text)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return e.GenerateEmbedding(text)
+}
+
 type Client struct {
-	baseURL string
-	client  *http.Client
-	model   string
+	baseURL  string
+	provider string // config.ProviderOllama or config.ProviderOpenAI
+	apiKey   string // Sent as a bearer token when set
+	client   *http.Client
+	retry    httpclient.Retry
+	model    string
 }
 
 type EmbeddingRequest struct {
@@ -26,17 +63,25 @@ type EmbeddingResponse struct {
 	Embeddings [][]float64 `json:"embeddings"`
 }
 
-func NewClient(cfg config.EmbeddingsConfig) (*Client, error) {
+func NewClient(cfg config.EmbeddingsConfig, timeouts config.TimeoutsConfig) (*Client, error) {
 	return &Client{
-		baseURL: cfg.BaseURL,
-		model:   cfg.Model,
-		client: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		baseURL:  cfg.URL(),
+		provider: cfg.Provider,
+		apiKey:   cfg.APIKey,
+		model:    cfg.Model,
+		client:   httpclient.New(timeouts.Embeddings, timeouts),
+		retry:    httpclient.Retry{MaxRetries: cfg.MaxRetries, Backoff: cfg.RetryBackoff},
 	}, nil
 }
 
 func (c *Client) GenerateEmbedding(text string) ([]float32, error) {
+	return c.GenerateEmbeddingContext(context.Background(), text)
+}
+
+// GenerateEmbeddingContext is GenerateEmbedding with a context; cancelling
+// ctx aborts the request. Requests that fail because the server is down or
+// failing are retried as configured.
+func (c *Client) GenerateEmbeddingContext(ctx context.Context, text string) ([]float32, error) {
 	req := EmbeddingRequest{
 		Model: c.model,
 		Input: text,
@@ -47,14 +92,43 @@ func (c *Client) GenerateEmbedding(text string) ([]float32, error) {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	resp, err := http.Post(c.baseURL+"/api/embed", "application/json", bytes.NewBuffer(reqBody))
+	var embedding []float32
+	err = c.retry.Do(ctx, func() error {
+		embedding, err = c.embed(ctx, reqBody, len(text))
+		return err
+	})
+	return embedding, err
+}
+
+// embed makes one attempt at an embedding request, to /api/embed or to
+// /v1/embeddings on an OpenAI-compatible server
+func (c *Client) embed(ctx context.Context, reqBody []byte, chars int) ([]float32, error) {
+	url := c.baseURL + "/api/embed"
+	if c.provider == config.ProviderOpenAI {
+		url = config.OpenAIURL(c.baseURL, "/embeddings")
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(reqBody))
 	if err != nil {
-		return nil, fmt.Errorf("failed to make request: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	start := time.Now()
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", httpclient.Classify(err))
 	}
 	defer resp.Body.Close()
+	slog.Debug("requested embedding", "component", "embeddings", "model", c.model, "status", resp.StatusCode, "chars", chars, "duration", time.Since(start))
 
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: %s (%w)", ErrModelNotFound, c.model, httpclient.NewStatusError(resp))
+	}
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return nil, httpclient.NewStatusError(resp)
 	}
 
 	body, err := io.ReadAll(resp.Body)
@@ -62,18 +136,25 @@ func (c *Client) GenerateEmbedding(text string) ([]float32, error) {
 		return nil, fmt.Errorf("failed to read response: %w", err)
 	}
 
-	var embResp EmbeddingResponse
-	if err := json.Unmarshal(body, &embResp); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
-	}
-
-	// Convert first embedding from float64 to float32
-	if len(embResp.Embeddings) == 0 {
-		return nil, fmt.Errorf("no embeddings returned")
+	var values []float64
+	if c.provider == config.ProviderOpenAI {
+		if values, err = parseOpenAIEmbedding(body); err != nil {
+			return nil, err
+		}
+	} else {
+		var embResp EmbeddingResponse
+		if err := json.Unmarshal(body, &embResp); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+		}
+		if len(embResp.Embeddings) == 0 {
+			return nil, ErrNoEmbeddings
+		}
+		values = embResp.Embeddings[0]
 	}
 
-	embedding := make([]float32, len(embResp.Embeddings[0]))
-	for i, v := range embResp.Embeddings[0] {
+	// Convert the embedding from float64 to float32
+	embedding := make([]float32, len(values))
+	for i, v := range values {
 		embedding[i] = float32(v)
 	}
 
@@ -0,0 +1,227 @@
+package embeddings
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"rag-cli/internal/fakeserver"
+	"rag-cli/internal/httpclient"
+	"rag-cli/pkg/config"
+)
+
+func TestNewClient_Timeouts(t *testing.T) {
+	client, err := NewClient(config.EmbeddingsConfig{Host: "localhost", Port: 11434}, config.TimeoutsConfig{Embeddings: 12 * time.Second, TLSHandshake: 7 * time.Second})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if client.client.Timeout != 12*time.Second {
+		t.Errorf("Expected request timeout 12s, got %s", client.client.Timeout)
+	}
+	if transport := client.client.Transport.(*http.Transport); transport.TLSHandshakeTimeout != 7*time.Second {
+		t.Errorf("Expected TLS handshake timeout 7s, got %s", transport.TLSHandshakeTimeout)
+	}
+}
+
+func TestGenerateEmbedding_Contract(t *testing.T) {
+	server := fakeserver.NewOllama(t)
+	server.SetEmbedding([]float64{0.5, -0.25, 1})
+	client, err := NewClient(config.EmbeddingsConfig{BaseURL: server.URL, Model: "nomic-embed-text"}, config.TimeoutsConfig{})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	embedding, err := client.GenerateEmbedding("hello world")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	expected := []float32{0.5, -0.25, 1}
+	if len(embedding) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, embedding)
+	}
+	for i := range expected {
+		if embedding[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected, embedding)
+			break
+		}
+	}
+
+	req, _ := server.LastRequest("/api/embed")
+	var body EmbeddingRequest
+	if err := req.Decode(&body); err != nil {
+		t.Fatalf("Expected a JSON request body, got: %v", err)
+	}
+	if req.Method != http.MethodPost || body.Model != "nomic-embed-text" || body.Input != "hello world" {
+		t.Errorf("Expected a POST embedding 'hello world' with nomic-embed-text, got %s %+v", req.Method, body)
+	}
+}
+
+func TestGenerateEmbedding_Errors(t *testing.T) {
+	tests := []struct {
+		name      string
+		failure   *fakeserver.Failure
+		embedding []float64
+		expected  string
+	}{
+		{name: "server error", failure: &fakeserver.Failure{Status: http.StatusInternalServerError}, expected: "unexpected status code: 500"},
+		{name: "model not found", failure: &fakeserver.Failure{Status: http.StatusNotFound, Body: `{"error":"model not found"}`}, expected: "unexpected status code: 404"},
+		{name: "malformed JSON", failure: &fakeserver.Failure{Body: `{"embeddings": [[0.1,`}, expected: "failed to unmarshal response"},
+		{name: "timeout", failure: &fakeserver.Failure{Delay: 5 * time.Second}, expected: "failed to make request"},
+		{name: "no embeddings", expected: "no embeddings returned"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := fakeserver.NewOllama(t)
+			server.SetEmbedding(tt.embedding)
+			if tt.failure != nil {
+				server.Fail("/api/embed", *tt.failure)
+			}
+			client, err := NewClient(config.EmbeddingsConfig{BaseURL: server.URL, Model: "nomic-embed-text"}, config.TimeoutsConfig{Embeddings: 100 * time.Millisecond})
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+
+			_, err = client.GenerateEmbedding("hello world")
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("Expected an error containing %q, got: %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestGenerateEmbedding_TypedErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		failure *fakeserver.Failure
+		is      error
+	}{
+		{name: "model not found", failure: &fakeserver.Failure{Status: http.StatusNotFound, Body: `{"error":"model not found"}`}, is: ErrModelNotFound},
+		{name: "timeout", failure: &fakeserver.Failure{Delay: 5 * time.Second}, is: httpclient.ErrTimeout},
+		{name: "no embeddings", is: ErrNoEmbeddings},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := fakeserver.NewOllama(t)
+			server.SetEmbedding(nil)
+			if tt.failure != nil {
+				server.Fail("/api/embed", *tt.failure)
+			}
+			client, err := NewClient(config.EmbeddingsConfig{BaseURL: server.URL, Model: "nomic-embed-text"}, config.TimeoutsConfig{Embeddings: 100 * time.Millisecond})
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+
+			_, err = client.GenerateEmbedding("hello world")
+			if !errors.Is(err, tt.is) {
+				t.Errorf("Expected an error matching %v, got: %v", tt.is, err)
+			}
+		})
+	}
+}
+
+func TestGenerateEmbedding_Retry(t *testing.T) {
+	server := fakeserver.NewOllama(t)
+	client, err := NewClient(config.EmbeddingsConfig{BaseURL: server.URL, Model: "nomic-embed-text", MaxRetries: 2, RetryBackoff: time.Millisecond}, config.TimeoutsConfig{})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	server.Fail("/api/embed", fakeserver.Failure{Status: http.StatusInternalServerError, Times: 1})
+	if _, err := client.GenerateEmbedding("hello world"); err != nil {
+		t.Fatalf("Expected the retry to succeed, got: %v", err)
+	}
+	if requests := len(server.Requests("/api/embed")); requests != 2 {
+		t.Errorf("Expected 2 requests, got %d", requests)
+	}
+
+	server.Fail("/api/embed", fakeserver.Failure{Status: http.StatusServiceUnavailable})
+	_, err = client.GenerateEmbedding("hello world")
+	if err == nil || !strings.Contains(err.Error(), "giving up after 3 attempts") {
+		t.Errorf("Expected the number of attempts in the error, got: %v", err)
+	}
+}
+
+func TestGenerateEmbedding_Concurrent(t *testing.T) {
+	server := fakeserver.NewOllama(t)
+	server.SetEmbedding([]float64{0.5, -0.25, 1})
+	client, err := NewClient(config.EmbeddingsConfig{BaseURL: server.URL, Model: "nomic-embed-text"}, config.TimeoutsConfig{})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	// Indexing workers share one client; run with -race to catch unsafe state
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			embedding, err := client.GenerateEmbedding("hello world")
+			if err == nil && len(embedding) != 3 {
+				err = fmt.Errorf("expected 3 dimensions, got %d", len(embedding))
+			}
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("Expected concurrent requests to succeed, got: %v", err)
+		}
+	}
+}
+
+func TestGenerateEmbeddingContext_Cancel(t *testing.T) {
+	server := fakeserver.NewOllama(t)
+	server.Fail("/api/embed", fakeserver.Failure{Delay: 5 * time.Second})
+	client, err := NewClient(config.EmbeddingsConfig{BaseURL: server.URL, Model: "nomic-embed-text"}, config.TimeoutsConfig{})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err = Generate(ctx, client, "hello world")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected a cancellation error, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected the request to stop when cancelled, took %s", elapsed)
+	}
+}
+
+// plainEmbedder does not take a context
+type plainEmbedder struct{ calls int }
+
+func (e *plainEmbedder) GenerateEmbedding(text string) ([]float32, error) {
+	e.calls++
+	return []float32{1}, nil
+}
+
+func TestGenerate_PlainEmbedder(t *testing.T) {
+	embedder := &plainEmbedder{}
+	if _, err := Generate(context.Background(), embedder, "hello"); err != nil || embedder.calls != 1 {
+		t.Fatalf("Expected one call without error, got %d calls and %v", embedder.calls, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Generate(ctx, embedder, "hello"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected a cancellation error, got: %v", err)
+	}
+	if embedder.calls != 1 {
+		t.Errorf("Expected no call after cancellation, got %d calls", embedder.calls)
+	}
+}
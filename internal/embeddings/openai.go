@@ -0,0 +1,27 @@
+package embeddings
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// OpenAIEmbeddingResponse is the body of an OpenAI-compatible /v1/embeddings
+// response. The request has the same shape as EmbeddingRequest.
+type OpenAIEmbeddingResponse struct {
+	Data []struct {
+		Embedding []float64 `json:"embedding"`
+	} `json:"data"`
+}
+
+// parseOpenAIEmbedding returns the first embedding of an OpenAI-compatible
+// response
+func parseOpenAIEmbedding(body []byte) ([]float64, error) {
+	var embResp OpenAIEmbeddingResponse
+	if err := json.Unmarshal(body, &embResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if len(embResp.Data) == 0 {
+		return nil, ErrNoEmbeddings
+	}
+	return embResp.Data[0].Embedding, nil
+}
@@ -0,0 +1,48 @@
+package embeddings
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"rag-cli/pkg/config"
+)
+
+func TestGenerateEmbedding_OpenAI(t *testing.T) {
+	var request EmbeddingRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/embeddings" || r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "unexpected request", http.StatusNotFound)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+		}
+		fmt.Fprint(w, `{"object":"list","data":[{"object":"embedding","index":0,"embedding":[0.5,-0.25]}],"model":"text-embedding-3-small"}`)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(config.EmbeddingsConfig{Provider: config.ProviderOpenAI, BaseURL: server.URL, Model: "text-embedding-3-small", APIKey: "secret"}, config.TimeoutsConfig{})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	embedding, err := client.GenerateEmbedding("hello")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(embedding) != 2 || embedding[0] != 0.5 || embedding[1] != -0.25 {
+		t.Errorf("Expected [0.5 -0.25], got %v", embedding)
+	}
+	if request.Model != "text-embedding-3-small" || request.Input != "hello" {
+		t.Errorf("Unexpected request: %+v", request)
+	}
+}
+
+func TestParseOpenAIEmbedding_Empty(t *testing.T) {
+	if _, err := parseOpenAIEmbedding([]byte(`{"data":[]}`)); err != ErrNoEmbeddings {
+		t.Errorf("Expected ErrNoEmbeddings, got: %v", err)
+	}
+}
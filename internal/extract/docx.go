@@ -0,0 +1,117 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Docx extracts paragraph text from a Word document. The body is read from
+// word/document.xml and the title, when set, from docProps/core.xml.
+func Docx(data []byte) (*Document, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("%w: not a zip archive: %v", ErrUnsupported, err)
+	}
+
+	body := findZipFile(archive, "word/document.xml")
+	if body == nil {
+		return nil, fmt.Errorf("%w: word/document.xml is missing", ErrUnsupported)
+	}
+
+	text, err := readDocxParagraphs(body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupported, err)
+	}
+
+	doc := &Document{Text: text}
+	if core := findZipFile(archive, "docProps/core.xml"); core != nil {
+		doc.Title = readDocxTitle(core)
+	}
+
+	if doc.Text == "" {
+		return nil, fmt.Errorf("%w: no paragraph text found", ErrUnsupported)
+	}
+	return doc, nil
+}
+
+func findZipFile(archive *zip.Reader, name string) *zip.File {
+	for _, file := range archive.File {
+		if file.Name == name {
+			return file
+		}
+	}
+	return nil
+}
+
+// readDocxParagraphs returns the text of each <w:p> paragraph on its own line
+func readDocxParagraphs(file *zip.File) (string, error) {
+	r, err := file.Open()
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+
+	var (
+		paragraphs []string
+		current    strings.Builder
+		inText     bool
+	)
+	decoder := xml.NewDecoder(r)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("invalid document XML: %w", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "t":
+				inText = true
+			case "tab":
+				current.WriteString("\t")
+			case "br", "cr":
+				current.WriteString("\n")
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				if paragraph := strings.TrimSpace(current.String()); paragraph != "" {
+					paragraphs = append(paragraphs, paragraph)
+				}
+				current.Reset()
+			}
+		case xml.CharData:
+			if inText {
+				current.Write(t)
+			}
+		}
+	}
+	return strings.Join(paragraphs, "\n"), nil
+}
+
+// readDocxTitle returns the dc:title core property, or "" if unavailable
+func readDocxTitle(file *zip.File) string {
+	r, err := file.Open()
+	if err != nil {
+		return ""
+	}
+	defer r.Close()
+
+	var props struct {
+		Title string `xml:"title"`
+	}
+	if err := xml.NewDecoder(r).Decode(&props); err != nil {
+		return ""
+	}
+	return strings.TrimSpace(props.Title)
+}
@@ -0,0 +1,69 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestDocx(t *testing.T) {
+	t.Run("fixture document", func(t *testing.T) {
+		data, err := os.ReadFile("testdata/report.docx")
+		if err != nil {
+			t.Fatalf("Failed to read fixture: %v", err)
+		}
+
+		doc, err := Docx(data)
+		if err != nil {
+			t.Fatalf("Failed to extract docx: %v", err)
+		}
+
+		if doc.Title != "Q3 Report" {
+			t.Errorf("Expected title %q, got %q", "Q3 Report", doc.Title)
+		}
+		expected := "Quarterly Report\nRevenue grew 12% this quarter.\nRegion\tNorth\nLine one\nLine two"
+		if doc.Text != expected {
+			t.Errorf("Unexpected text.\nExpected:\n%q\nGot:\n%q", expected, doc.Text)
+		}
+	})
+
+	t.Run("not a zip archive", func(t *testing.T) {
+		_, err := Docx([]byte("<html>not a docx</html>"))
+		if !errors.Is(err, ErrUnsupported) {
+			t.Errorf("Expected ErrUnsupported, got %v", err)
+		}
+	})
+
+	t.Run("zip without document body", func(t *testing.T) {
+		var buf bytes.Buffer
+		archive := zip.NewWriter(&buf)
+		w, _ := archive.Create("readme.txt")
+		w.Write([]byte("hello"))
+		archive.Close()
+
+		_, err := Docx(buf.Bytes())
+		if !errors.Is(err, ErrUnsupported) {
+			t.Errorf("Expected ErrUnsupported, got %v", err)
+		}
+	})
+}
+
+func TestForExtension(t *testing.T) {
+	for _, ext := range []string{".html", ".HTM", ".docx"} {
+		if _, ok := ForExtension(ext); !ok {
+			t.Errorf("Expected an extractor for %s", ext)
+		}
+	}
+	for _, ext := range []string{".md", ".go", ""} {
+		if _, ok := ForExtension(ext); ok {
+			t.Errorf("Expected no extractor for %q", ext)
+		}
+	}
+
+	extractor, _ := ForExtension(".html")
+	if _, err := extractor([]byte("<html><body><script>only()</script></body></html>")); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Expected HTML without text to be unsupported, got %v", err)
+	}
+}
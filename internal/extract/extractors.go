@@ -0,0 +1,39 @@
+package extract
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrUnsupported is returned when a file's structure cannot be turned into
+// readable text. Callers should skip such files rather than index raw markup.
+var ErrUnsupported = errors.New("unsupported document structure")
+
+// Extractor turns the raw contents of a file into readable text
+type Extractor func(data []byte) (*Document, error)
+
+// extractors maps lowercase file extensions, including the dot, to the
+// extractor for that format. Files without an extractor are indexed as-is.
+var extractors = map[string]Extractor{
+	".html":  htmlFile,
+	".htm":   htmlFile,
+	".xhtml": htmlFile,
+	".docx":  Docx,
+}
+
+// ForExtension returns the extractor registered for ext, such as ".docx"
+func ForExtension(ext string) (Extractor, bool) {
+	extractor, ok := extractors[strings.ToLower(ext)]
+	return extractor, ok
+}
+
+// htmlFile extracts a local HTML file with the same rules used for web pages
+func htmlFile(data []byte) (*Document, error) {
+	doc, err := HTML(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupported, err)
+	}
+	return doc, nil
+}
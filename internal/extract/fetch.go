@@ -0,0 +1,64 @@
+package extract
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"time"
+)
+
+const (
+	// DefaultFetchTimeout bounds how long fetching a single page may take
+	DefaultFetchTimeout = 30 * time.Second
+
+	// DefaultMaxPageBytes caps the size of a fetched page
+	DefaultMaxPageBytes = 5 << 20
+)
+
+// Fetcher downloads web pages and extracts their readable text
+type Fetcher struct {
+	client   *http.Client
+	maxBytes int64
+}
+
+func NewFetcher(timeout time.Duration, maxBytes int64) *Fetcher {
+	return &Fetcher{
+		client:   &http.Client{Timeout: timeout},
+		maxBytes: maxBytes,
+	}
+}
+
+// Fetch downloads url and extracts its title and main text. Only HTML
+// responses are accepted.
+func (f *Fetcher) Fetch(url string) (*Document, error) {
+	resp, err := f.client.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch %s: unexpected status code: %d", url, resp.StatusCode)
+	}
+
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || (mediaType != "text/html" && mediaType != "application/xhtml+xml") {
+		return nil, fmt.Errorf("refusing to index %s: content type %q is not HTML", url, resp.Header.Get("Content-Type"))
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, f.maxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", url, err)
+	}
+	if int64(len(body)) > f.maxBytes {
+		return nil, fmt.Errorf("refusing to index %s: page is larger than %d bytes", url, f.maxBytes)
+	}
+
+	doc, err := HTML(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract text from %s: %w", url, err)
+	}
+	return doc, nil
+}
@@ -0,0 +1,68 @@
+package extract
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newFetchServer() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte("<html><head><title>Docs</title></head><body><main><p>Hello from the docs.</p></main></body></html>"))
+	})
+	mux.HandleFunc("/data.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"a": 1}`))
+	})
+	mux.HandleFunc("/large", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<p>" + strings.Repeat("a", 2048) + "</p>"))
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<p>late</p>"))
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestFetcher(t *testing.T) {
+	server := newFetchServer()
+	defer server.Close()
+
+	t.Run("html page", func(t *testing.T) {
+		doc, err := NewFetcher(time.Second, DefaultMaxPageBytes).Fetch(server.URL + "/page")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if doc.Title != "Docs" || doc.Text != "Hello from the docs." {
+			t.Errorf("Unexpected document: %+v", doc)
+		}
+	})
+
+	errorCases := []struct {
+		name     string
+		path     string
+		timeout  time.Duration
+		maxBytes int64
+		expected string
+	}{
+		{"non-html content", "/data.json", time.Second, DefaultMaxPageBytes, "is not HTML"},
+		{"size cap", "/large", time.Second, 1024, "larger than 1024 bytes"},
+		{"timeout", "/slow", 50 * time.Millisecond, DefaultMaxPageBytes, "failed to fetch"},
+		{"not found", "/missing", time.Second, DefaultMaxPageBytes, "status code: 404"},
+	}
+
+	for _, tt := range errorCases {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewFetcher(tt.timeout, tt.maxBytes).Fetch(server.URL + tt.path)
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("Expected error containing %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
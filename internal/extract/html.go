@@ -0,0 +1,161 @@
+package extract
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Document is readable text extracted from a structured source
+type Document struct {
+	Title string
+	Text  string
+}
+
+// Content returns the text to index: the title followed by the body text
+func (d *Document) Content() string {
+	if d.Title == "" {
+		return d.Text
+	}
+	return d.Title + "\n\n" + d.Text
+}
+
+// skippedElements never contain readable page content
+var skippedElements = map[atom.Atom]bool{
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Noscript: true,
+	atom.Template: true,
+	atom.Svg:      true,
+	atom.Nav:      true,
+	atom.Header:   true,
+	atom.Footer:   true,
+	atom.Aside:    true,
+	atom.Form:     true,
+	atom.Button:   true,
+	atom.Iframe:   true,
+	atom.Head:     true,
+}
+
+// blockElements start a new line in the extracted text
+var blockElements = map[atom.Atom]bool{
+	atom.P: true, atom.Div: true, atom.Section: true, atom.Article: true, atom.Main: true,
+	atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+	atom.Li: true, atom.Ul: true, atom.Ol: true, atom.Dl: true, atom.Dt: true, atom.Dd: true,
+	atom.Pre: true, atom.Blockquote: true, atom.Table: true, atom.Tr: true, atom.Br: true,
+	atom.Hr: true, atom.Figcaption: true,
+}
+
+// HTML extracts the title and main readable text from an HTML document.
+// Content inside <main> or <article> is preferred over the whole <body>, and
+// navigation, scripts, styles, and similar chrome are dropped.
+func HTML(r io.Reader) (*Document, error) {
+	root, err := html.Parse(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse HTML: %w", err)
+	}
+
+	doc := &Document{}
+	if title := findElement(root, atom.Title); title != nil {
+		doc.Title = collapseSpaces(textContent(title))
+	}
+
+	content := findElement(root, atom.Main)
+	if content == nil {
+		content = findElement(root, atom.Article)
+	}
+	if content == nil {
+		content = findElement(root, atom.Body)
+	}
+	if content == nil {
+		content = root
+	}
+
+	var text strings.Builder
+	writeText(&text, content)
+	doc.Text = normalizeLines(text.String())
+
+	if doc.Text == "" && doc.Title == "" {
+		return nil, fmt.Errorf("no readable text found")
+	}
+	return doc, nil
+}
+
+// findElement returns the first element of the given type in document order
+func findElement(n *html.Node, a atom.Atom) *html.Node {
+	if n.Type == html.ElementNode && n.DataAtom == a {
+		return n
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if found := findElement(child, a); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// textContent concatenates every text node below n
+func textContent(n *html.Node) string {
+	var b strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+	}
+	walk(n)
+	return b.String()
+}
+
+// writeText appends the readable text below n, one line per block element
+func writeText(b *strings.Builder, n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		// Line breaks in the source are just whitespace outside <pre>
+		b.WriteString(strings.NewReplacer("\r", " ", "\n", " ").Replace(n.Data))
+		return
+	case html.ElementNode:
+		if skippedElements[n.DataAtom] {
+			return
+		}
+		if n.DataAtom == atom.Pre {
+			b.WriteString("\n" + textContent(n) + "\n")
+			return
+		}
+	}
+
+	block := n.Type == html.ElementNode && blockElements[n.DataAtom]
+	if block {
+		b.WriteString("\n")
+	}
+	if n.Type == html.ElementNode && n.DataAtom == atom.Li {
+		b.WriteString("- ")
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		writeText(b, child)
+	}
+	if block {
+		b.WriteString("\n")
+	}
+}
+
+// normalizeLines collapses whitespace within lines and drops blank lines
+func normalizeLines(text string) string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = collapseSpaces(line); line != "" && line != "-" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func collapseSpaces(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,58 @@
+package extract
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestHTML(t *testing.T) {
+	t.Run("fixture page", func(t *testing.T) {
+		f, err := os.Open("testdata/page.html")
+		if err != nil {
+			t.Fatalf("Failed to open fixture: %v", err)
+		}
+		defer f.Close()
+
+		doc, err := HTML(f)
+		if err != nil {
+			t.Fatalf("Failed to extract HTML: %v", err)
+		}
+
+		if doc.Title != "Configuring rag-cli" {
+			t.Errorf("Expected title %q, got %q", "Configuring rag-cli", doc.Title)
+		}
+
+		expected := "Configuration\n" +
+			"Settings live in ~/.rag-cli.yaml. Run rag-cli config show to inspect them.\n" +
+			"- llm.model\n" +
+			"- vector.host\n" +
+			"rag-cli config set llm.model llama3\n" +
+			"rag-cli config get llm.model"
+		if doc.Text != expected {
+			t.Errorf("Unexpected text.\nExpected:\n%s\nGot:\n%s", expected, doc.Text)
+		}
+
+		for _, unwanted := range []string{"tracking", "color", "Home", "Site banner", "Related posts", "Copyright"} {
+			if strings.Contains(doc.Content(), unwanted) {
+				t.Errorf("Expected %q to be stripped, got:\n%s", unwanted, doc.Content())
+			}
+		}
+	})
+
+	t.Run("falls back to body", func(t *testing.T) {
+		doc, err := HTML(strings.NewReader("<html><body><p>One</p><p>Two</p></body></html>"))
+		if err != nil {
+			t.Fatalf("Failed to extract HTML: %v", err)
+		}
+		if doc.Text != "One\nTwo" || doc.Content() != "One\nTwo" {
+			t.Errorf("Expected body text without title, got %q", doc.Content())
+		}
+	})
+
+	t.Run("no readable text", func(t *testing.T) {
+		if _, err := HTML(strings.NewReader("<html><body><script>x()</script></body></html>")); err == nil {
+			t.Error("Expected an error for a page without text")
+		}
+	})
+}
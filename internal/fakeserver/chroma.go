@@ -0,0 +1,383 @@
+package fakeserver
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// Chroma mimics the ChromaDB v1 REST API: collection listing, creation and
+// deletion, and adding, querying, getting, counting, and deleting documents.
+// NewChromaV2 mimics the v2 API instead.
+type Chroma struct {
+	*httptest.Server
+	recorder
+
+	version     string
+	v2          bool
+	collections []*collection
+	nextID      int
+}
+
+// v2Collections is where the v2 API serves the collections of the default
+// tenant and database, the only ones the fake has
+const v2Collections = "/api/v2/tenants/default_tenant/databases/default_database"
+
+// collection holds a fake collection's documents in insertion order
+type collection struct {
+	id        string
+	name      string
+	documents []document
+}
+
+type document struct {
+	id        string
+	content   string
+	metadata  map[string]interface{}
+	embedding []float32
+}
+
+// NewChroma starts an empty fake ChromaDB server that is closed when the
+// test ends
+func NewChroma(t testing.TB) *Chroma {
+	c := &Chroma{version: "0.5.23"}
+	c.Server = httptest.NewServer(http.HandlerFunc(c.handle))
+	t.Cleanup(c.Close)
+	return c
+}
+
+// NewChromaV2 starts an empty fake ChromaDB 1.0 server, which serves the
+// same requests under /api/v2 and the default tenant and database, and
+// answers v1 requests with 410 Gone
+func NewChromaV2(t testing.TB) *Chroma {
+	c := NewChroma(t)
+	c.version = "1.0.0"
+	c.v2 = true
+	return c
+}
+
+// AddCollection creates a collection directly, as if another client had,
+// and returns its ID
+func (c *Chroma) AddCollection(name string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.create(name).id
+}
+
+// Collections returns the names of the stored collections, in creation order
+func (c *Chroma) Collections() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	names := make([]string, len(c.collections))
+	for i, col := range c.collections {
+		names[i] = col.name
+	}
+	return names
+}
+
+// DocumentIDs returns the IDs stored in a collection, in insertion order
+func (c *Chroma) DocumentIDs(name string) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var ids []string
+	if col := c.byName(name); col != nil {
+		for _, doc := range col.documents {
+			ids = append(ids, doc.id)
+		}
+	}
+	return ids
+}
+
+func (c *Chroma) create(name string) *collection {
+	c.nextID++
+	col := &collection{id: fmt.Sprintf("col-%d", c.nextID), name: name}
+	c.collections = append(c.collections, col)
+	return col
+}
+
+func (c *Chroma) byName(name string) *collection {
+	for _, col := range c.collections {
+		if col.name == name {
+			return col
+		}
+	}
+	return nil
+}
+
+func (c *Chroma) byID(id string) *collection {
+	for _, col := range c.collections {
+		if col.id == id {
+			return col
+		}
+	}
+	return nil
+}
+
+func (c *Chroma) handle(w http.ResponseWriter, r *http.Request) {
+	req, failed := c.record(w, r)
+	if failed {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, "/api/v1")
+	if c.v2 {
+		if path != r.URL.Path {
+			http.Error(w, `{"error":"the v1 API is deprecated, use v2"}`, http.StatusGone)
+			return
+		}
+		path = strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, v2Collections), "/api/v2")
+	}
+	switch {
+	case path == "/heartbeat":
+		writeJSON(w, map[string]int64{"nanosecond heartbeat": 1})
+	case path == "/version":
+		writeJSON(w, c.version)
+	case path == "/collections" && r.Method == http.MethodGet:
+		c.listCollections(w)
+	case path == "/collections" && r.Method == http.MethodPost:
+		c.createCollection(w, req)
+	case strings.HasPrefix(path, "/collections/") && r.Method == http.MethodDelete:
+		c.deleteCollection(w, strings.TrimPrefix(path, "/collections/"))
+	case strings.HasPrefix(path, "/collections/"):
+		c.handleCollection(w, r.Method, strings.TrimPrefix(path, "/collections/"), req)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (c *Chroma) listCollections(w http.ResponseWriter) {
+	list := make([]map[string]interface{}, len(c.collections))
+	for i, col := range c.collections {
+		list[i] = map[string]interface{}{"id": col.id, "name": col.name}
+	}
+	writeJSON(w, list)
+}
+
+func (c *Chroma) createCollection(w http.ResponseWriter, req Request) {
+	var body struct {
+		Name        string `json:"name"`
+		GetOrCreate bool   `json:"get_or_create"`
+	}
+	if err := req.Decode(&body); err != nil || body.Name == "" {
+		http.Error(w, `{"error":"invalid collection"}`, http.StatusUnprocessableEntity)
+		return
+	}
+	col := c.byName(body.Name)
+	if col != nil && !body.GetOrCreate {
+		http.Error(w, fmt.Sprintf(`{"error":"collection %s already exists"}`, body.Name), http.StatusConflict)
+		return
+	}
+	if col == nil {
+		col = c.create(body.Name)
+	}
+	writeJSON(w, map[string]interface{}{"id": col.id, "name": col.name})
+}
+
+func (c *Chroma) deleteCollection(w http.ResponseWriter, name string) {
+	for i, col := range c.collections {
+		if col.name == name {
+			c.collections = append(c.collections[:i], c.collections[i+1:]...)
+			writeJSON(w, nil)
+			return
+		}
+	}
+	http.Error(w, fmt.Sprintf(`{"error":"collection %s does not exist"}`, name), http.StatusNotFound)
+}
+
+func (c *Chroma) handleCollection(w http.ResponseWriter, method, rest string, req Request) {
+	id, action, _ := strings.Cut(rest, "/")
+	col := c.byID(id)
+	if col == nil {
+		http.Error(w, fmt.Sprintf(`{"error":"collection %s does not exist"}`, id), http.StatusNotFound)
+		return
+	}
+
+	switch {
+	case action == "count" && method == http.MethodGet:
+		writeJSON(w, len(col.documents))
+	case action == "add" && method == http.MethodPost:
+		c.add(w, col, req)
+	case action == "query" && method == http.MethodPost:
+		c.query(w, col, req)
+	case action == "get" && method == http.MethodPost:
+		c.get(w, col, req)
+	case action == "delete" && method == http.MethodPost:
+		c.delete(w, col, req)
+	default:
+		http.Error(w, `{"error":"not found"}`, http.StatusNotFound)
+	}
+}
+
+func (c *Chroma) add(w http.ResponseWriter, col *collection, req Request) {
+	var body struct {
+		IDs        []string                 `json:"ids"`
+		Documents  []string                 `json:"documents"`
+		Embeddings [][]float32              `json:"embeddings"`
+		Metadatas  []map[string]interface{} `json:"metadatas"`
+	}
+	if err := req.Decode(&body); err != nil || len(body.IDs) != len(body.Documents) || len(body.IDs) != len(body.Embeddings) {
+		http.Error(w, `{"error":"ids, documents and embeddings must have the same length"}`, http.StatusUnprocessableEntity)
+		return
+	}
+	for i, id := range body.IDs {
+		doc := document{id: id, content: body.Documents[i], embedding: body.Embeddings[i]}
+		if i < len(body.Metadatas) {
+			doc.metadata = body.Metadatas[i]
+		}
+		col.documents = append(col.documents, doc)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	io.WriteString(w, "true")
+}
+
+// query ranks documents by squared L2 distance, as ChromaDB does by default
+func (c *Chroma) query(w http.ResponseWriter, col *collection, req Request) {
+	var body struct {
+		QueryEmbeddings [][]float32            `json:"query_embeddings"`
+		NResults        int                    `json:"n_results"`
+		Where           map[string]interface{} `json:"where"`
+	}
+	if err := req.Decode(&body); err != nil || len(body.QueryEmbeddings) == 0 {
+		http.Error(w, `{"error":"query_embeddings is required"}`, http.StatusUnprocessableEntity)
+		return
+	}
+
+	type ranked struct {
+		doc      document
+		distance float32
+	}
+	results := make([]ranked, 0, len(col.documents))
+	for _, doc := range col.documents {
+		if !matchesWhere(doc.metadata, body.Where) {
+			continue
+		}
+		results = append(results, ranked{doc, squaredDistance(body.QueryEmbeddings[0], doc.embedding)})
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].distance < results[j].distance })
+	if body.NResults > 0 && len(results) > body.NResults {
+		results = results[:body.NResults]
+	}
+
+	ids, documents, distances, metadatas := []string{}, []string{}, []float32{}, []map[string]interface{}{}
+	for _, result := range results {
+		ids = append(ids, result.doc.id)
+		documents = append(documents, result.doc.content)
+		distances = append(distances, result.distance)
+		metadatas = append(metadatas, result.doc.metadata)
+	}
+	writeJSON(w, map[string]interface{}{
+		"ids":       [][]string{ids},
+		"documents": [][]string{documents},
+		"distances": [][]float32{distances},
+		"metadatas": [][]map[string]interface{}{metadatas},
+	})
+}
+
+// matchesWhere reports whether metadata satisfies a where filter. Only
+// equality is supported, written as {"key": value} or {"key": {"$eq": value}}.
+func matchesWhere(metadata, where map[string]interface{}) bool {
+	for key, want := range where {
+		if operators, ok := want.(map[string]interface{}); ok {
+			want = operators["$eq"]
+		}
+		if got, ok := metadata[key]; !ok || fmt.Sprint(got) != fmt.Sprint(want) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *Chroma) get(w http.ResponseWriter, col *collection, req Request) {
+	var body struct {
+		IDs     []string `json:"ids"`
+		Limit   int      `json:"limit"`
+		Offset  int      `json:"offset"`
+		Include []string `json:"include"`
+	}
+	if err := req.Decode(&body); err != nil {
+		http.Error(w, `{"error":"invalid request"}`, http.StatusUnprocessableEntity)
+		return
+	}
+
+	var matched []document
+	for _, doc := range col.documents {
+		if len(body.IDs) == 0 || contains(body.IDs, doc.id) {
+			matched = append(matched, doc)
+		}
+	}
+	if body.Offset >= len(matched) {
+		matched = nil
+	} else {
+		matched = matched[body.Offset:]
+	}
+	if body.Limit > 0 && len(matched) > body.Limit {
+		matched = matched[:body.Limit]
+	}
+
+	response := map[string]interface{}{}
+	ids, documents, metadatas, embeddings := []string{}, []string{}, []map[string]interface{}{}, [][]float32{}
+	for _, doc := range matched {
+		ids = append(ids, doc.id)
+		documents = append(documents, doc.content)
+		metadatas = append(metadatas, doc.metadata)
+		embeddings = append(embeddings, doc.embedding)
+	}
+	response["ids"] = ids
+	if contains(body.Include, "documents") {
+		response["documents"] = documents
+	}
+	if contains(body.Include, "metadatas") {
+		response["metadatas"] = metadatas
+	}
+	if contains(body.Include, "embeddings") {
+		response["embeddings"] = embeddings
+	}
+	writeJSON(w, response)
+}
+
+func (c *Chroma) delete(w http.ResponseWriter, col *collection, req Request) {
+	var body struct {
+		IDs []string `json:"ids"`
+	}
+	if err := req.Decode(&body); err != nil {
+		http.Error(w, `{"error":"invalid request"}`, http.StatusUnprocessableEntity)
+		return
+	}
+	kept := col.documents[:0]
+	var deleted []string
+	for _, doc := range col.documents {
+		if contains(body.IDs, doc.id) {
+			deleted = append(deleted, doc.id)
+			continue
+		}
+		kept = append(kept, doc)
+	}
+	col.documents = kept
+	writeJSON(w, deleted)
+}
+
+func squaredDistance(a, b []float32) float32 {
+	var sum float64
+	for i := 0; i < len(a) && i < len(b); i++ {
+		d := float64(a[i] - b[i])
+		sum += d * d
+	}
+	return float32(math.Round(sum*1e6) / 1e6)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
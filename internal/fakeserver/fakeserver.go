@@ -0,0 +1,131 @@
+// Package fakeserver provides in-process stand-ins for the Ollama and
+// ChromaDB HTTP APIs. They record every request and can be told to fail, so
+// client tests can check request shapes and error handling offline.
+package fakeserver
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Request is a request received by a fake server
+type Request struct {
+	Method string
+	Path   string
+	Body   []byte
+}
+
+// Decode unmarshals the JSON request body into v
+func (r Request) Decode(v interface{}) error {
+	return json.Unmarshal(r.Body, v)
+}
+
+// Failure makes a fake server answer requests badly. A zero Status answers
+// 200 with Body, which is how malformed responses are produced.
+type Failure struct {
+	Status int
+	Body   string
+	Delay  time.Duration // Wait this long first, or until the client gives up
+	Times  int           // Fail only this many requests, then recover; 0 fails them all
+}
+
+// recorder keeps the requests a server received and the failures it was
+// told to produce
+type recorder struct {
+	mu       sync.Mutex
+	requests []Request
+	failures map[string]Failure
+}
+
+// Fail makes requests whose path ends with suffix fail. Later calls for the
+// same suffix replace the failure.
+func (r *recorder) Fail(suffix string, failure Failure) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.failures == nil {
+		r.failures = make(map[string]Failure)
+	}
+	r.failures[suffix] = failure
+}
+
+// Recover stops every programmed failure
+func (r *recorder) Recover() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.failures = nil
+}
+
+// Requests returns the requests whose path ends with suffix, oldest first;
+// "" returns every request
+func (r *recorder) Requests(suffix string) []Request {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var matched []Request
+	for _, req := range r.requests {
+		if strings.HasSuffix(req.Path, suffix) {
+			matched = append(matched, req)
+		}
+	}
+	return matched
+}
+
+// LastRequest returns the most recent request whose path ends with suffix
+func (r *recorder) LastRequest(suffix string) (Request, bool) {
+	requests := r.Requests(suffix)
+	if len(requests) == 0 {
+		return Request{}, false
+	}
+	return requests[len(requests)-1], true
+}
+
+// record stores req and answers it with a programmed failure if one
+// matches, reporting whether it did
+func (r *recorder) record(w http.ResponseWriter, req *http.Request) (Request, bool) {
+	body, _ := io.ReadAll(req.Body)
+	received := Request{Method: req.Method, Path: req.URL.Path, Body: body}
+
+	r.mu.Lock()
+	r.requests = append(r.requests, received)
+	failure, failing := Failure{}, false
+	for suffix, f := range r.failures {
+		if strings.HasSuffix(req.URL.Path, suffix) {
+			failure, failing = f, true
+			if f.Times == 1 {
+				delete(r.failures, suffix)
+			} else if f.Times > 1 {
+				f.Times--
+				r.failures[suffix] = f
+			}
+			break
+		}
+	}
+	r.mu.Unlock()
+
+	if !failing {
+		return received, false
+	}
+	if failure.Delay > 0 {
+		select {
+		case <-time.After(failure.Delay):
+		case <-req.Context().Done():
+			return received, true
+		}
+	}
+	status := failure.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	w.WriteHeader(status)
+	io.WriteString(w, failure.Body)
+	return received, true
+}
+
+// writeJSON answers with v encoded as JSON
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,134 @@
+package fakeserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Ollama mimics the Ollama endpoints rag-cli uses: /api/generate, /api/chat,
+// /api/tags, and /api/embed
+type Ollama struct {
+	*httptest.Server
+	recorder
+
+	models    []string
+	response  string
+	embedding []float64
+}
+
+// NewOllama starts a fake Ollama server that is closed when the test ends.
+// It offers one model, answers prompts with "ok", and embeds text as a
+// three-dimensional vector until told otherwise.
+func NewOllama(t testing.TB) *Ollama {
+	o := &Ollama{
+		models:    []string{"granite-code:3b"},
+		response:  "ok",
+		embedding: []float64{0.1, 0.2, 0.3},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/generate", o.handleGenerate)
+	mux.HandleFunc("/api/chat", o.handleChat)
+	mux.HandleFunc("/api/tags", o.handleTags)
+	mux.HandleFunc("/api/embed", o.handleEmbed)
+	o.Server = httptest.NewServer(mux)
+	t.Cleanup(o.Close)
+	return o
+}
+
+// SetModels sets the models listed by /api/tags
+func (o *Ollama) SetModels(models ...string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.models = models
+}
+
+// SetResponse sets the text /api/generate and /api/chat answer with
+func (o *Ollama) SetResponse(response string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.response = response
+}
+
+// SetEmbedding sets the vector /api/embed returns; nil returns none
+func (o *Ollama) SetEmbedding(embedding []float64) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.embedding = embedding
+}
+
+func (o *Ollama) handleGenerate(w http.ResponseWriter, r *http.Request) {
+	received, failed := o.record(w, r)
+	if failed {
+		return
+	}
+	var body struct {
+		Stream bool `json:"stream"`
+	}
+	received.Decode(&body)
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if body.Stream {
+		streamResponse(w, o.response)
+		return
+	}
+	writeJSON(w, map[string]interface{}{"response": o.response, "done": true})
+}
+
+// streamResponse answers like Ollama with streaming on: one JSON line per
+// word of response, then a "done" line without text
+func streamResponse(w http.ResponseWriter, response string) {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	encoder := json.NewEncoder(w)
+	flusher, _ := w.(http.Flusher)
+	for _, token := range strings.SplitAfter(response, " ") {
+		if token == "" {
+			continue
+		}
+		encoder.Encode(map[string]interface{}{"response": token, "done": false})
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	encoder.Encode(map[string]interface{}{"response": "", "done": true})
+}
+
+func (o *Ollama) handleChat(w http.ResponseWriter, r *http.Request) {
+	if _, failed := o.record(w, r); failed {
+		return
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	writeJSON(w, map[string]interface{}{
+		"message": map[string]string{"role": "assistant", "content": o.response},
+		"done":    true,
+	})
+}
+
+func (o *Ollama) handleTags(w http.ResponseWriter, r *http.Request) {
+	if _, failed := o.record(w, r); failed {
+		return
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	models := make([]map[string]string, len(o.models))
+	for i, name := range o.models {
+		models[i] = map[string]string{"name": name}
+	}
+	writeJSON(w, map[string]interface{}{"models": models})
+}
+
+func (o *Ollama) handleEmbed(w http.ResponseWriter, r *http.Request) {
+	if _, failed := o.record(w, r); failed {
+		return
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	embeddings := [][]float64{}
+	if o.embedding != nil {
+		embeddings = append(embeddings, o.embedding)
+	}
+	writeJSON(w, map[string]interface{}{"embeddings": embeddings})
+}
@@ -0,0 +1,59 @@
+// Package golden compares test output with checked-in golden files, so
+// changes to long generated text such as prompts show up in review.
+package golden
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// update rewrites golden files with the current output instead of comparing
+var update = flag.Bool("update", false, "rewrite golden files with the current output")
+
+// Assert fails the test when got differs from the golden file at path. Run
+// the test with -update to write got to the file instead.
+func Assert(t testing.TB, path, got string) {
+	t.Helper()
+
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create golden directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+			t.Fatalf("Failed to write golden file: %v", err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read golden file (run with -update to create it): %v", err)
+	}
+	if got == string(want) {
+		return
+	}
+	line, wantLine, gotLine := firstDifference(string(want), got)
+	t.Errorf("Output differs from %s at line %d (run with -update to accept it):\nwant: %q\ngot:  %q", path, line, wantLine, gotLine)
+}
+
+// firstDifference returns the first line, counting from 1, where want and
+// got differ, and that line from each
+func firstDifference(want, got string) (int, string, string) {
+	wantLines := strings.SplitAfter(want, "\n")
+	gotLines := strings.SplitAfter(got, "\n")
+	for i := 0; ; i++ {
+		var w, g string
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if w != g || i >= len(wantLines) && i >= len(gotLines) {
+			return i + 1, w, g
+		}
+	}
+}
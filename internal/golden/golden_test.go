@@ -0,0 +1,27 @@
+package golden
+
+import "testing"
+
+func TestFirstDifference(t *testing.T) {
+	tests := []struct {
+		name     string
+		want     string
+		got      string
+		line     int
+		wantLine string
+		gotLine  string
+	}{
+		{name: "changed line", want: "a\nb\nc\n", got: "a\nB\nc\n", line: 2, wantLine: "b\n", gotLine: "B\n"},
+		{name: "missing trailing newline", want: "a\nb\n", got: "a\nb", line: 2, wantLine: "b\n", gotLine: "b"},
+		{name: "extra line", want: "a\n", got: "a\nb\n", line: 2, wantLine: "", gotLine: "b\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line, wantLine, gotLine := firstDifference(tt.want, tt.got)
+			if line != tt.line || wantLine != tt.wantLine || gotLine != tt.gotLine {
+				t.Errorf("Expected line %d (%q vs %q), got line %d (%q vs %q)", tt.line, tt.wantLine, tt.gotLine, line, wantLine, gotLine)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package history
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"rag-cli/internal/vector"
+	"rag-cli/pkg/config"
+)
+
+// sessionIDPrefix is the prefix of command session IDs, followed by the unix
+// time the session was stored
+const sessionIDPrefix = "cmd_session_"
+
+// PruneOptions selects command sessions to remove. A session is removed when
+// it is older than OlderThan or falls outside the newest KeepLast sessions;
+// FailedOnly restricts both selectors to failed sessions.
+type PruneOptions struct {
+	OlderThan  time.Duration
+	KeepLast   int
+	FailedOnly bool
+}
+
+// IsZero reports whether no selector is set
+func (o PruneOptions) IsZero() bool {
+	return o.OlderThan == 0 && o.KeepLast == 0 && !o.FailedOnly
+}
+
+// RetentionPolicy returns the automatic pruning configured under history, or
+// zero options when retention is disabled
+func RetentionPolicy(cfg config.HistoryConfig) PruneOptions {
+	return PruneOptions{
+		OlderThan: time.Duration(cfg.RetentionDays) * 24 * time.Hour,
+		KeepLast:  cfg.MaxSessions,
+	}
+}
+
+// Timestamp returns when a document was stored, from its timestamp metadata
+// or, for command sessions, the unix time embedded in the cmd_session_ ID
+func Timestamp(doc vector.StoredDocument) (time.Time, bool) {
+	switch ts := doc.Metadata["timestamp"].(type) {
+	case string:
+		if t, err := time.Parse(time.RFC3339, ts); err == nil {
+			return t, true
+		}
+	case float64:
+		return time.Unix(int64(ts), 0), true
+	}
+
+	if strings.HasPrefix(doc.ID, sessionIDPrefix) {
+		if unix, err := strconv.ParseInt(strings.TrimPrefix(doc.ID, sessionIDPrefix), 10, 64); err == nil {
+			return time.Unix(unix, 0), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Succeeded reports whether a stored command session achieved its goal. The
+// "success" metadata flag is used when present; older sessions without
+// metadata are judged by whether their log records an error.
+func Succeeded(session vector.StoredDocument) bool {
+	if success, ok := session.Metadata["success"].(bool); ok {
+		return success
+	}
+	return !strings.Contains(session.Document, "Error:") && !strings.Contains(session.Document, "Max attempts")
+}
+
+// SortNewestFirst orders sessions by time, newest first. Sessions without a
+// known time are treated as the oldest.
+func SortNewestFirst(sessions []vector.StoredDocument) {
+	sort.SliceStable(sessions, func(i, j int) bool {
+		ti, iKnown := Timestamp(sessions[i])
+		tj, jKnown := Timestamp(sessions[j])
+		if !iKnown || !jKnown {
+			return iKnown
+		}
+		return ti.After(tj)
+	})
+}
+
+// Select returns the IDs of the sessions that opts selects for removal
+func Select(sessions []vector.StoredDocument, opts PruneOptions, now time.Time) []string {
+	ordered := make([]vector.StoredDocument, len(sessions))
+	copy(ordered, sessions)
+	SortNewestFirst(ordered)
+
+	var ids []string
+	for i, session := range ordered {
+		if opts.FailedOnly && Succeeded(session) {
+			continue
+		}
+
+		selected := opts.OlderThan == 0 && opts.KeepLast == 0
+		if opts.KeepLast > 0 && i >= opts.KeepLast {
+			selected = true
+		}
+		if opts.OlderThan > 0 {
+			if created, ok := Timestamp(session); ok && now.Sub(created) > opts.OlderThan {
+				selected = true
+			}
+		}
+		if selected {
+			ids = append(ids, session.ID)
+		}
+	}
+	return ids
+}
+
+// Prune removes the command sessions selected by opts and returns how many
+// were removed
+func Prune(store vector.VectorStore, opts PruneOptions, now time.Time) (int, error) {
+	if opts.IsZero() {
+		return 0, nil
+	}
+
+	sessions, err := store.GetDocuments(store.CommandsCollection(), 0)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read command sessions: %w", err)
+	}
+
+	ids := Select(sessions, opts, now)
+	if err := store.DeleteDocuments(store.CommandsCollection(), ids); err != nil {
+		return 0, fmt.Errorf("failed to delete command sessions: %w", err)
+	}
+	return len(ids), nil
+}
@@ -0,0 +1,62 @@
+package history
+
+import (
+	"testing"
+	"time"
+
+	"rag-cli/internal/vector"
+	"rag-cli/pkg/config"
+)
+
+func TestTimestamp(t *testing.T) {
+	tests := []struct {
+		name     string
+		doc      vector.StoredDocument
+		expected time.Time
+		ok       bool
+	}{
+		{"rfc3339 metadata", vector.StoredDocument{ID: "x", Metadata: map[string]interface{}{"timestamp": "2025-08-01T12:00:00Z"}}, time.Date(2025, 8, 1, 12, 0, 0, 0, time.UTC), true},
+		{"unix metadata", vector.StoredDocument{ID: "x", Metadata: map[string]interface{}{"timestamp": float64(1700000000)}}, time.Unix(1700000000, 0), true},
+		{"session id", vector.StoredDocument{ID: "cmd_session_1700000000"}, time.Unix(1700000000, 0), true},
+		{"unknown", vector.StoredDocument{ID: "doc-1"}, time.Time{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Timestamp(tt.doc)
+			if ok != tt.ok || !got.Equal(tt.expected) {
+				t.Errorf("Expected (%v, %t), got (%v, %t)", tt.expected, tt.ok, got, ok)
+			}
+		})
+	}
+}
+
+func TestSelect_UnknownTimesAreOldest(t *testing.T) {
+	sessions := []vector.StoredDocument{
+		{ID: "legacy"},
+		{ID: "cmd_session_1700000000"},
+		{ID: "cmd_session_1750000000"},
+	}
+	now := time.Unix(1760000000, 0)
+
+	ids := Select(sessions, PruneOptions{KeepLast: 2}, now)
+	if len(ids) != 1 || ids[0] != "legacy" {
+		t.Errorf("Expected only the session without a time to be pruned, got %v", ids)
+	}
+
+	ids = Select(sessions, PruneOptions{OlderThan: 24 * time.Hour}, now)
+	if len(ids) != 2 {
+		t.Errorf("Expected sessions without a time to survive --older-than, got %v", ids)
+	}
+}
+
+func TestRetentionPolicy(t *testing.T) {
+	if !RetentionPolicy(config.HistoryConfig{}).IsZero() {
+		t.Error("Expected retention to be disabled by default")
+	}
+
+	opts := RetentionPolicy(config.HistoryConfig{RetentionDays: 30, MaxSessions: 500})
+	if opts.OlderThan != 30*24*time.Hour || opts.KeepLast != 500 || opts.FailedOnly {
+		t.Errorf("Unexpected options: %+v", opts)
+	}
+}
@@ -0,0 +1,86 @@
+package httpclient
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ErrTimeout is matched by errors from requests that did not finish in time,
+// whether the client timeout or a context deadline stopped them
+var ErrTimeout = errors.New("request timed out")
+
+// ErrUnreachable is matched by errors from requests that could not connect
+// to the server at all, for example because nothing is listening on its port
+// or its host name does not resolve
+var ErrUnreachable = errors.New("server unreachable")
+
+// maxErrorBody bounds how much of an error response is kept for the message
+const maxErrorBody = 1024
+
+// StatusError reports a response with an unexpected HTTP status
+type StatusError struct {
+	StatusCode int
+	Body       string // Start of the response body, if any
+}
+
+// NewStatusError reads the start of resp's body into a StatusError
+func NewStatusError(resp *http.Response) *StatusError {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	return &StatusError{StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(body))}
+}
+
+func (e *StatusError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("unexpected status code: %d", e.StatusCode)
+	}
+	return fmt.Sprintf("unexpected status code: %d, body: %s", e.StatusCode, e.Body)
+}
+
+// Temporary reports whether the same request might succeed later: the server
+// failed, was overloaded, or asked the client to slow down
+func (e *StatusError) Temporary() bool {
+	return e.StatusCode >= 500 || e.StatusCode == http.StatusTooManyRequests || e.StatusCode == http.StatusRequestTimeout
+}
+
+// timeoutError keeps the message of a timed out request while matching
+// ErrTimeout
+type timeoutError struct {
+	err error
+}
+
+func (e *timeoutError) Error() string   { return e.err.Error() }
+func (e *timeoutError) Unwrap() []error { return []error{e.err, ErrTimeout} }
+
+// unreachableError keeps the message of a request that could not connect
+// while matching ErrUnreachable
+type unreachableError struct {
+	err error
+}
+
+func (e *unreachableError) Error() string   { return e.err.Error() }
+func (e *unreachableError) Unwrap() []error { return []error{e.err, ErrUnreachable} }
+
+// Classify returns the error of a failed request, marked to match ErrTimeout
+// when the request timed out or ErrUnreachable when it could not connect.
+// Other errors are returned unchanged.
+func Classify(err error) error {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return &timeoutError{err: err}
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return &unreachableError{err: err}
+	}
+	return err
+}
+
+// Unavailable reports whether err means the server could not be used at all,
+// because it could not be reached or did not answer in time
+func Unavailable(err error) bool {
+	return errors.Is(err, ErrUnreachable) || errors.Is(err, ErrTimeout)
+}
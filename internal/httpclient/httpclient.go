@@ -0,0 +1,30 @@
+// Package httpclient builds the HTTP clients rag-cli uses to reach Ollama
+// and ChromaDB, applying the timeouts section of the configuration.
+package httpclient
+
+import (
+	"net"
+	"net/http"
+	"time"
+
+	"rag-cli/pkg/config"
+)
+
+// keepAlive matches the interval used by http.DefaultTransport
+const keepAlive = 30 * time.Second
+
+// New returns a client whose requests time out after timeout, with dialing
+// and TLS handshakes bounded by timeouts. Zero disables a limit.
+func New(timeout time.Duration, timeouts config.TimeoutsConfig) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{
+		Timeout:   timeouts.Dial,
+		KeepAlive: keepAlive,
+	}).DialContext
+	transport.TLSHandshakeTimeout = timeouts.TLSHandshake
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+	}
+}
@@ -0,0 +1,127 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"rag-cli/pkg/config"
+)
+
+func TestNew(t *testing.T) {
+	client := New(45*time.Second, config.TimeoutsConfig{Dial: 3 * time.Second, TLSHandshake: 4 * time.Second})
+
+	if client.Timeout != 45*time.Second {
+		t.Errorf("Expected request timeout 45s, got %s", client.Timeout)
+	}
+	transport, ok := client.Transport.(*http.Transport)
+	if !ok {
+		t.Fatalf("Expected an *http.Transport, got %T", client.Transport)
+	}
+	if transport.TLSHandshakeTimeout != 4*time.Second {
+		t.Errorf("Expected TLS handshake timeout 4s, got %s", transport.TLSHandshakeTimeout)
+	}
+	if transport.DialContext == nil {
+		t.Error("Expected a dialer to be set")
+	}
+	if transport == http.DefaultTransport {
+		t.Error("Expected a copy of the default transport, not the shared one")
+	}
+}
+
+func TestClassify(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(5 * time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+
+	t.Run("client timeout", func(t *testing.T) {
+		client := New(50*time.Millisecond, config.TimeoutsConfig{})
+		_, err := client.Get(server.URL)
+		err = Classify(err)
+		if !errors.Is(err, ErrTimeout) {
+			t.Errorf("Expected ErrTimeout, got: %v", err)
+		}
+		if !strings.Contains(err.Error(), "Client.Timeout exceeded") {
+			t.Errorf("Expected the original message to be kept, got: %v", err)
+		}
+	})
+
+	t.Run("context deadline", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+		_, err := New(0, config.TimeoutsConfig{}).Do(req)
+		err = Classify(err)
+		if !errors.Is(err, ErrTimeout) || !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected ErrTimeout and context.DeadlineExceeded, got: %v", err)
+		}
+	})
+
+	t.Run("nothing listening", func(t *testing.T) {
+		closed := httptest.NewServer(http.NotFoundHandler())
+		closed.Close()
+		_, err := New(0, config.TimeoutsConfig{}).Get(closed.URL)
+		err = Classify(err)
+		if !errors.Is(err, ErrUnreachable) || errors.Is(err, ErrTimeout) || !Unavailable(err) {
+			t.Errorf("Expected only ErrUnreachable, got: %v", err)
+		}
+		if !strings.Contains(err.Error(), "connection refused") {
+			t.Errorf("Expected the original message to be kept, got: %v", err)
+		}
+	})
+
+	t.Run("cancellation is not a timeout", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(50*time.Millisecond, cancel)
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+		_, err := New(0, config.TimeoutsConfig{}).Do(req)
+		err = Classify(err)
+		if errors.Is(err, ErrTimeout) || !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected only context.Canceled, got: %v", err)
+		}
+	})
+
+	t.Run("other errors are unchanged", func(t *testing.T) {
+		want := errors.New("connection refused")
+		if got := Classify(want); got != want {
+			t.Errorf("Expected %v, got %v", want, got)
+		}
+	})
+}
+
+func TestStatusError(t *testing.T) {
+	tests := []struct {
+		status    int
+		body      string
+		message   string
+		temporary bool
+	}{
+		{status: http.StatusInternalServerError, body: "disk full\n", message: "unexpected status code: 500, body: disk full", temporary: true},
+		{status: http.StatusServiceUnavailable, message: "unexpected status code: 503", temporary: true},
+		{status: http.StatusTooManyRequests, message: "unexpected status code: 429", temporary: true},
+		{status: http.StatusNotFound, body: `{"error":"not found"}`, message: `unexpected status code: 404, body: {"error":"not found"}`},
+		{status: http.StatusBadRequest, message: "unexpected status code: 400"},
+	}
+
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			resp := &http.Response{StatusCode: tt.status, Body: io.NopCloser(strings.NewReader(tt.body))}
+			err := NewStatusError(resp)
+			if err.Error() != tt.message {
+				t.Errorf("Expected %q, got %q", tt.message, err.Error())
+			}
+			if err.Temporary() != tt.temporary {
+				t.Errorf("Expected temporary %v, got %v", tt.temporary, err.Temporary())
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+)
+
+// maxBackoff caps the wait between two attempts however many retries came
+// before
+const maxBackoff = 30 * time.Second
+
+// Retry sends a request again when it failed in a way a later attempt might
+// not: the server could not be reached, did not answer in time, or answered
+// with a temporary status. The zero value makes a single attempt.
+type Retry struct {
+	MaxRetries int           // Attempts after the first one
+	Backoff    time.Duration // Wait before the first retry, doubled for each later one
+
+	// sleep waits for d or until ctx is done; nil uses a timer. Tests
+	// replace it to run without waiting.
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// Retryable reports whether a request that failed with err might succeed if
+// sent again
+func Retryable(err error) bool {
+	var statusErr *StatusError
+	return Unavailable(err) || (errors.As(err, &statusErr) && statusErr.Temporary())
+}
+
+// Do calls attempt until it succeeds, fails with an error that is not
+// Retryable, or the retries run out. Waiting stops early when ctx is done.
+// When more than one attempt was made the final error says how many.
+func (r Retry) Do(ctx context.Context, attempt func() error) error {
+	for n := 1; ; n++ {
+		err := attempt()
+		if err == nil {
+			return nil
+		}
+		if n > r.MaxRetries || !Retryable(err) || ctx.Err() != nil {
+			if n > 1 {
+				return fmt.Errorf("giving up after %d attempts: %w", n, err)
+			}
+			return err
+		}
+
+		delay := r.delay(n)
+		slog.Debug("retrying request", "component", "httpclient", "attempt", n+1, "delay", delay, "error", err)
+		if waitErr := r.wait(ctx, delay); waitErr != nil {
+			return fmt.Errorf("%w after %d attempts: %w", waitErr, n, err)
+		}
+	}
+}
+
+// delay returns the wait before retry n: Backoff doubled for each earlier
+// retry and capped at maxBackoff, with jitter taking it down to as little as
+// half so clients that failed together do not all retry together
+func (r Retry) delay(n int) time.Duration {
+	if r.Backoff <= 0 {
+		return 0
+	}
+	d := r.Backoff
+	for i := 1; i < n && d < maxBackoff; i++ {
+		d *= 2
+	}
+	d = min(d, maxBackoff)
+	return d/2 + rand.N(d/2+1)
+}
+
+func (r Retry) wait(ctx context.Context, d time.Duration) error {
+	if r.sleep != nil {
+		return r.sleep(ctx, d)
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
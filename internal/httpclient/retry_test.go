@@ -0,0 +1,130 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{"unreachable", &unreachableError{err: errors.New("connection refused")}, true},
+		{"timeout", &timeoutError{err: errors.New("deadline exceeded")}, true},
+		{"server error", &StatusError{StatusCode: 503}, true},
+		{"rate limited", &StatusError{StatusCode: 429}, true},
+		{"bad request", &StatusError{StatusCode: 400}, false},
+		{"not found", &StatusError{StatusCode: 404}, false},
+		{"other", errors.New("failed to marshal request"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Retryable(tt.err); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestRetry_Do(t *testing.T) {
+	var waits []time.Duration
+	sleep := func(ctx context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return nil
+	}
+
+	t.Run("succeeds after temporary failures", func(t *testing.T) {
+		waits = nil
+		retry := Retry{MaxRetries: 3, Backoff: time.Second, sleep: sleep}
+		calls := 0
+		err := retry.Do(context.Background(), func() error {
+			calls++
+			if calls < 3 {
+				return &StatusError{StatusCode: 502}
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if calls != 3 {
+			t.Errorf("Expected 3 attempts, got %d", calls)
+		}
+		if len(waits) != 2 || waits[0] < 500*time.Millisecond || waits[0] > time.Second || waits[1] < time.Second || waits[1] > 2*time.Second {
+			t.Errorf("Expected jittered waits of up to 1s then 2s, got %v", waits)
+		}
+	})
+
+	t.Run("gives up after the last retry", func(t *testing.T) {
+		retry := Retry{MaxRetries: 2, sleep: sleep}
+		calls := 0
+		err := retry.Do(context.Background(), func() error {
+			calls++
+			return &unreachableError{err: errors.New("connection refused")}
+		})
+		if calls != 3 {
+			t.Errorf("Expected 3 attempts, got %d", calls)
+		}
+		if !errors.Is(err, ErrUnreachable) || !strings.Contains(err.Error(), "giving up after 3 attempts") {
+			t.Errorf("Expected the attempts and the last error, got: %v", err)
+		}
+	})
+
+	t.Run("does not retry permanent errors", func(t *testing.T) {
+		retry := Retry{MaxRetries: 2, sleep: sleep}
+		calls := 0
+		err := retry.Do(context.Background(), func() error {
+			calls++
+			return &StatusError{StatusCode: 400}
+		})
+		if calls != 1 {
+			t.Errorf("Expected 1 attempt, got %d", calls)
+		}
+		if err == nil || strings.Contains(err.Error(), "attempts") {
+			t.Errorf("Expected the error unchanged, got: %v", err)
+		}
+	})
+
+	t.Run("cancelled during a request", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		retry := Retry{MaxRetries: 5, Backoff: time.Minute}
+		calls := 0
+		err := retry.Do(ctx, func() error {
+			calls++
+			cancel()
+			return &StatusError{StatusCode: 503}
+		})
+		if calls != 1 {
+			t.Errorf("Expected 1 attempt, got %d", calls)
+		}
+		if err == nil {
+			t.Error("Expected an error")
+		}
+	})
+
+	t.Run("cancelled while waiting", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		retry := Retry{MaxRetries: 5, Backoff: time.Minute}
+		err := retry.Do(ctx, func() error { return &StatusError{StatusCode: 503} })
+		if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "after 1 attempts") {
+			t.Errorf("Expected the deadline and the attempts, got: %v", err)
+		}
+	})
+}
+
+func TestRetry_DelayIsCapped(t *testing.T) {
+	retry := Retry{Backoff: time.Second}
+	if d := retry.delay(20); d < maxBackoff/2 || d > maxBackoff {
+		t.Errorf("Expected a delay of at most %s, got %s", maxBackoff, d)
+	}
+	if d := (Retry{}).delay(3); d != 0 {
+		t.Errorf("Expected no delay without a backoff, got %s", d)
+	}
+}
@@ -1,49 +1,96 @@
 package indexing
 
 import (
+	"context"
 	"crypto/sha256"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	"golang.org/x/sync/semaphore"
+	"rag-cli/internal/chunker"
 	"rag-cli/internal/embeddings"
 	"rag-cli/internal/vector"
 	"rag-cli/pkg/config"
 )
 
+// maxInFlightBytes caps the file content held in memory at once across
+// everything the indexer is doing, whatever the size of the files
+const maxInFlightBytes = 4 << 20
+
+// ErrFileTooLarge is returned for a file larger than auto_index.max_file_size.
+// The file is not read.
+var ErrFileTooLarge = errors.New("file exceeds the auto-index size limit")
+
 // FileInfo represents metadata about a file for change detection
 type FileInfo struct {
 	Path    string
 	Size    int64
 	ModTime time.Time
 	Hash    string
+	DocIDs  []string // Documents stored for the file in the auto-index collection
 }
 
 // AutoIndexer handles automatic indexing of file changes
 type AutoIndexer struct {
 	config           *config.AutoIndexConfig
-	embeddingsClient *embeddings.Client
-	vectorStore      *vector.ChromaClient
+	embeddingsClient embeddings.Embedder
+	vectorStore      vector.VectorStore
+	chunker          *chunker.Client
+	inFlight         *semaphore.Weighted // Bytes of chunks being embedded or stored
+	inFlightLimit    int64
 	lastSnapshot     map[string]FileInfo
 	workingDir       string
+	manifest         *Manifest // Persists the documents stored for each file; nil keeps them in memory only
+	manifestPath     string
 	mutex            sync.RWMutex
 }
 
 // NewAutoIndexer creates a new auto-indexer instance
-func NewAutoIndexer(cfg *config.AutoIndexConfig, embeddingsClient *embeddings.Client, vectorStore *vector.ChromaClient, workingDir string) *AutoIndexer {
+func NewAutoIndexer(cfg *config.AutoIndexConfig, embeddingsClient embeddings.Embedder, vectorStore vector.VectorStore, workingDir string) *AutoIndexer {
 	return &AutoIndexer{
 		config:           cfg,
 		embeddingsClient: embeddingsClient,
 		vectorStore:      vectorStore,
+		chunker:          chunker.New(config.ChunkerConfig{ChunkSize: 1000, ChunkOverlap: 200}),
+		inFlight:         semaphore.NewWeighted(maxInFlightBytes),
+		inFlightLimit:    maxInFlightBytes,
 		lastSnapshot:     make(map[string]FileInfo),
 		workingDir:       workingDir,
 	}
 }
 
+// UseChunker sets how files are split into documents. The default is
+// 1000-character chunks overlapping by 200.
+func (ai *AutoIndexer) UseChunker(c *chunker.Client) {
+	ai.chunker = c
+}
+
+// UseManifest keeps the documents stored for each file in manifest, saved to
+// path whenever they change, so that a later chat or watch can remove or
+// replace the documents of files deleted or changed in the meantime. The
+// files under the working directory recorded there are taken up now, so
+// call it before the first TakeSnapshot.
+func (ai *AutoIndexer) UseManifest(manifest *Manifest, path string) {
+	ai.mutex.Lock()
+	defer ai.mutex.Unlock()
+	ai.manifest, ai.manifestPath = manifest, path
+	for fullPath, entry := range manifest.Files(ai.vectorStore.AutoIndexCollection()) {
+		relPath, err := filepath.Rel(ai.workingDir, fullPath)
+		if err != nil || relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator)) {
+			continue
+		}
+		ai.lastSnapshot[relPath] = FileInfo{Path: relPath, DocIDs: entry.DocIDs}
+	}
+}
+
 // TakeSnapshot captures the current state of files in the working directory
 func (ai *AutoIndexer) TakeSnapshot() error {
 	ai.mutex.Lock()
@@ -68,12 +115,12 @@ func (ai *AutoIndexer) TakeSnapshot() error {
 		}
 
 		// Check if file should be tracked
-		if !ai.shouldTrackFile(relPath) {
+		if !ai.shouldTrackFile(relPath, info.Size()) {
 			return nil
 		}
 
 		// Calculate file hash for content change detection
-		hash, err := ai.calculateFileHash(path)
+		hash, err := HashFile(path)
 		if err != nil {
 			return nil // Skip files that can't be hashed
 		}
@@ -83,19 +130,28 @@ func (ai *AutoIndexer) TakeSnapshot() error {
 			Size:    info.Size(),
 			ModTime: info.ModTime(),
 			Hash:    hash,
+			DocIDs:  ai.lastSnapshot[relPath].DocIDs,
 		}
 
 		return nil
 	})
 
 	if err == nil {
+		// Keep the documents of files that are gone until they are removed
+		for relPath, file := range ai.lastSnapshot {
+			if _, exists := snapshot[relPath]; !exists && len(file.DocIDs) > 0 {
+				snapshot[relPath] = FileInfo{Path: relPath, DocIDs: file.DocIDs}
+			}
+		}
 		ai.lastSnapshot = snapshot
 	}
 
 	return err
 }
 
-// DetectChanges returns a list of files that have changed since the last snapshot
+// DetectChanges returns the files that are new, modified, or deleted since
+// the last snapshot. A deleted file is only reported while it still has
+// documents in the auto-index collection.
 func (ai *AutoIndexer) DetectChanges() ([]string, error) {
 	ai.mutex.RLock()
 	defer ai.mutex.RUnlock()
@@ -118,11 +174,11 @@ func (ai *AutoIndexer) DetectChanges() ([]string, error) {
 			return nil
 		}
 
-		if !ai.shouldTrackFile(relPath) {
+		if !ai.shouldTrackFile(relPath, info.Size()) {
 			return nil
 		}
 
-		hash, err := ai.calculateFileHash(path)
+		hash, err := HashFile(path)
 		if err != nil {
 			return nil
 		}
@@ -148,66 +204,236 @@ func (ai *AutoIndexer) DetectChanges() ([]string, error) {
 		return nil
 	})
 
-	// TODO: Check for deleted files (in last snapshot but not current)
-	// This would be used to remove deleted files from the vector store
-	// For now, we don't handle deletions
+	if err != nil {
+		return changedFiles, err
+	}
+	var deletedFiles []string
+	for relPath, file := range ai.lastSnapshot {
+		if _, exists := currentSnapshot[relPath]; !exists && len(file.DocIDs) > 0 {
+			deletedFiles = append(deletedFiles, relPath)
+		}
+	}
+	sort.Strings(deletedFiles)
 
-	return changedFiles, err
+	return append(changedFiles, deletedFiles...), nil
 }
 
-// IndexChangedFiles indexes the provided list of changed files
-func (ai *AutoIndexer) IndexChangedFiles(changedFiles []string) error {
+// IndexChangedFiles indexes the provided list of changed files and returns
+// the number of documents stored. The documents of files that no longer exist
+// are removed.
+func (ai *AutoIndexer) IndexChangedFiles(changedFiles []string) (int, error) {
+	return ai.IndexChangedFilesContext(context.Background(), changedFiles)
+}
+
+// IndexChangedFilesContext is IndexChangedFiles with a context. When ctx is
+// cancelled it stops before the next file and returns ctx.Err() without
+// updating the snapshot, so the files it skipped are found again next time.
+func (ai *AutoIndexer) IndexChangedFilesContext(ctx context.Context, changedFiles []string) (int, error) {
 	if len(changedFiles) == 0 {
-		return nil
+		return 0, nil
 	}
 
-	fmt.Printf("[Auto-indexing %d file(s): %s]\n", len(changedFiles), strings.Join(changedFiles, ", "))
-
+	indexed := 0
 	for _, relPath := range changedFiles {
-		fullPath := filepath.Join(ai.workingDir, relPath)
-		
-		// Read file content
-		content, err := os.ReadFile(fullPath)
-		if err != nil {
-			fmt.Printf("[Auto-index warning: failed to read %s: %v]\n", relPath, err)
-			continue
+		if ctx.Err() != nil {
+			return indexed, ctx.Err()
 		}
-
-		// Generate embedding
-		embedding, err := ai.embeddingsClient.GenerateEmbedding(string(content))
-		if err != nil {
-			fmt.Printf("[Auto-index warning: failed to generate embedding for %s: %v]\n", relPath, err)
+		if _, err := os.Stat(filepath.Join(ai.workingDir, relPath)); os.IsNotExist(err) {
+			if _, err := ai.RemoveFile(relPath); err != nil {
+				slog.Warn("failed to remove documents of deleted file", "component", "auto_index", "path", relPath, "error", err)
+			}
 			continue
 		}
-
-		// Store in vector database
-		// Use relative path as document ID for consistency
-		docID := fmt.Sprintf("auto_%s_%d", strings.ReplaceAll(relPath, "/", "_"), time.Now().Unix())
-		err = ai.vectorStore.AddDocument(ai.vectorStore.AutoIndexCollection(), docID, string(content), embedding)
-		if err != nil {
-			fmt.Printf("[Auto-index warning: failed to store %s: %v]\n", relPath, err)
+		if err := ai.indexFile(ctx, relPath); err != nil {
+			if ctx.Err() != nil {
+				return indexed, ctx.Err()
+			}
+			slog.Warn("failed to auto-index file", "component", "auto_index", "path", relPath, "error", err)
 			continue
 		}
+		indexed++
 	}
 
 	// Update snapshot after successful indexing
-	return ai.TakeSnapshot()
+	return indexed, ai.TakeSnapshot()
+}
+
+// IndexFile embeds a single file, given relative to the working directory,
+// and stores it in the auto-index collection. The file is streamed through
+// the chunker, one document per chunk, so it is never held in memory whole.
+// A file larger than the size limit returns ErrFileTooLarge without being read.
+// Once the file is stored, the documents from when it was last indexed are
+// removed, so a modified file does not leave duplicates behind.
+func (ai *AutoIndexer) IndexFile(relPath string) error {
+	return ai.indexFile(context.Background(), relPath)
+}
+
+// indexFile is IndexFile with a context, checked between chunks
+func (ai *AutoIndexer) indexFile(ctx context.Context, relPath string) error {
+	fullPath := filepath.Join(ai.workingDir, relPath)
+
+	file, err := os.Open(fullPath)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", relPath, err)
+	}
+	defer file.Close()
+
+	// The file may have grown since it was found, so check the size again
+	// here rather than trusting the walk or the watcher
+	stat, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", relPath, err)
+	}
+	var content io.Reader = file
+	if limit := ai.config.MaxFileSize; limit > 0 {
+		if stat.Size() > limit {
+			return fmt.Errorf("%w: %s is %d bytes, limit %d", ErrFileTooLarge, relPath, stat.Size(), limit)
+		}
+		// Nor read past the limit if it grows while being read
+		content = io.LimitReader(file, limit)
+	}
+
+	// Use relative path as document ID for consistency, with a time that
+	// keeps it apart from the IDs of the previous version
+	prefix := fmt.Sprintf("auto_%s_%d", strings.ReplaceAll(relPath, "/", "_"), time.Now().UnixNano())
+	indexedAt := time.Now().UTC().Format(time.RFC3339)
+	var docIDs []string
+	index := 0
+	err = ai.chunker.ChunkReader(content, func(chunk string) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		docID := prefix
+		if index > 0 {
+			docID = fmt.Sprintf("%s_%d", prefix, index)
+		}
+		metadata := map[string]interface{}{
+			"source_path": fullPath,
+			"chunk_index": index,
+			"indexed_at":  indexedAt,
+		}
+		index++
+		if err := ai.indexChunk(ctx, relPath, docID, chunk, metadata); err != nil {
+			return err
+		}
+		docIDs = append(docIDs, docID)
+		return nil
+	})
+	if err != nil {
+		// Keep the previous version rather than half of this one
+		if deleteErr := ai.deleteDocuments(docIDs); deleteErr != nil {
+			slog.Warn("failed to remove partly indexed file", "component", "auto_index", "path", relPath, "error", deleteErr)
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("failed to index %s: %w", relPath, err)
+	}
+
+	previous := ai.recordDocuments(relPath, docIDs)
+	if err := ai.deleteDocuments(previous); err != nil {
+		slog.Warn("failed to remove previous version of file", "component", "auto_index", "path", relPath, "error", err)
+	}
+	return nil
+}
+
+// RemoveFile deletes the documents stored for a file, given relative to the
+// working directory, and forgets it. It is used for files that were deleted
+// and returns the number of documents removed.
+func (ai *AutoIndexer) RemoveFile(relPath string) (int, error) {
+	ai.mutex.Lock()
+	docIDs := ai.lastSnapshot[relPath].DocIDs
+	ai.mutex.Unlock()
+
+	if err := ai.deleteDocuments(docIDs); err != nil {
+		return 0, fmt.Errorf("failed to remove %s: %w", relPath, err)
+	}
+
+	ai.mutex.Lock()
+	defer ai.mutex.Unlock()
+	if _, err := os.Stat(filepath.Join(ai.workingDir, relPath)); os.IsNotExist(err) {
+		delete(ai.lastSnapshot, relPath)
+	} else if file, ok := ai.lastSnapshot[relPath]; ok {
+		file.DocIDs = nil
+		ai.lastSnapshot[relPath] = file
+	}
+	ai.persistDocuments(relPath, nil)
+	return len(docIDs), nil
+}
+
+// deleteDocuments removes documents from the auto-index collection
+func (ai *AutoIndexer) deleteDocuments(docIDs []string) error {
+	if len(docIDs) == 0 {
+		return nil
+	}
+	return ai.vectorStore.DeleteDocuments(ai.vectorStore.AutoIndexCollection(), docIDs)
+}
+
+// recordDocuments remembers the documents stored for a file and returns the
+// ones stored for it before
+func (ai *AutoIndexer) recordDocuments(relPath string, docIDs []string) []string {
+	ai.mutex.Lock()
+	defer ai.mutex.Unlock()
+	file, ok := ai.lastSnapshot[relPath]
+	if !ok {
+		file = FileInfo{Path: relPath}
+	}
+	previous := file.DocIDs
+	file.DocIDs = docIDs
+	ai.lastSnapshot[relPath] = file
+	ai.persistDocuments(relPath, docIDs)
+	return previous
+}
+
+// persistDocuments records the documents stored for a file in the manifest,
+// forgetting the file when there are none, and saves it. The caller holds
+// the mutex, which keeps saves from overlapping.
+func (ai *AutoIndexer) persistDocuments(relPath string, docIDs []string) {
+	if ai.manifest == nil {
+		return
+	}
+	fullPath := filepath.Join(ai.workingDir, relPath)
+	collection := ai.vectorStore.AutoIndexCollection()
+	if len(docIDs) == 0 {
+		ai.manifest.Forget(collection, fullPath)
+	} else {
+		ai.manifest.Record(collection, fullPath, ManifestEntry{DocIDs: docIDs, IndexedAt: time.Now().UTC()})
+	}
+	if err := ai.manifest.Save(ai.manifestPath); err != nil {
+		slog.Warn("failed to save auto-index manifest", "component", "auto_index", "path", ai.manifestPath, "error", err)
+	}
 }
 
-// shouldTrackFile determines if a file should be tracked for auto-indexing
-func (ai *AutoIndexer) shouldTrackFile(relPath string) bool {
+// indexChunk embeds and stores one chunk of a file with its metadata, waiting
+// first until the chunk fits in the in-flight byte budget
+func (ai *AutoIndexer) indexChunk(ctx context.Context, relPath, docID, chunk string, metadata map[string]interface{}) error {
+	size := min(int64(len(chunk)), ai.inFlightLimit)
+	if err := ai.inFlight.Acquire(ctx, size); err != nil {
+		return err
+	}
+	defer ai.inFlight.Release(size)
+
+	embedding, err := embeddings.Generate(ctx, ai.embeddingsClient, chunk)
+	if err != nil {
+		return fmt.Errorf("failed to generate embedding for %s: %w", relPath, err)
+	}
+	if err := ai.vectorStore.AddDocumentWithMetadata(ai.vectorStore.AutoIndexCollection(), docID, chunk, embedding, metadata); err != nil {
+		return fmt.Errorf("failed to store %s: %w", relPath, err)
+	}
+	return nil
+}
+
+// shouldTrackFile determines if a file of the given size should be tracked
+// for auto-indexing
+func (ai *AutoIndexer) shouldTrackFile(relPath string, size int64) bool {
 	// Skip if auto-indexing is disabled
 	if !ai.config.Enabled {
 		return false
 	}
 
 	// Check file size limit
-	if ai.config.MaxFileSize > 0 {
-		if stat, err := os.Stat(filepath.Join(ai.workingDir, relPath)); err == nil {
-			if stat.Size() > ai.config.MaxFileSize {
-				return false
-			}
-		}
+	if ai.config.MaxFileSize > 0 && size > ai.config.MaxFileSize {
+		return false
 	}
 
 	// Check exclude patterns
@@ -239,8 +465,8 @@ func (ai *AutoIndexer) shouldTrackFile(relPath string) bool {
 	return true
 }
 
-// calculateFileHash computes SHA256 hash of file content
-func (ai *AutoIndexer) calculateFileHash(filePath string) (string, error) {
+// HashFile computes the SHA256 hash of a file's content
+func HashFile(filePath string) (string, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
 		return "", err
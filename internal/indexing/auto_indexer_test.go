@@ -0,0 +1,375 @@
+package indexing
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/sync/semaphore"
+	"rag-cli/internal/chunker"
+	"rag-cli/pkg/config"
+)
+
+// cancellingEmbedder cancels its context after the first embedding, as if the
+// user pressed Ctrl+C while a batch of files was being indexed
+type cancellingEmbedder struct {
+	cancel context.CancelFunc
+}
+
+func (e cancellingEmbedder) GenerateEmbedding(text string) ([]float32, error) {
+	return e.GenerateEmbeddingContext(context.Background(), text)
+}
+
+func (e cancellingEmbedder) GenerateEmbeddingContext(ctx context.Context, text string) ([]float32, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	e.cancel()
+	return []float32{0.1, 0.2}, nil
+}
+
+func TestIndexChangedFilesContext_Cancel(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"a.md", "b.md", "c.md"} {
+		writeWatchFile(t, root, name, "content of "+name)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	store := &watchStore{}
+	cfg := &config.AutoIndexConfig{Enabled: true, Extensions: []string{".md"}, MaxFileSize: 1024}
+	indexer := NewAutoIndexer(cfg, cancellingEmbedder{cancel: cancel}, store, root)
+
+	changed, err := indexer.DetectChanges()
+	if err != nil || len(changed) != 3 {
+		t.Fatalf("Expected 3 changed files, got %v (%v)", changed, err)
+	}
+
+	indexed, err := indexer.IndexChangedFilesContext(ctx, changed)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected a cancellation error, got: %v", err)
+	}
+	if indexed != 1 || len(store.contents()) != 1 {
+		t.Errorf("Expected indexing to stop after 1 file, got %d indexed and %d stored", indexed, len(store.contents()))
+	}
+
+	// The snapshot is left alone so the skipped files are indexed next time
+	changed, err = indexer.DetectChanges()
+	if err != nil || len(changed) != 3 {
+		t.Errorf("Expected all 3 files to still be changed, got %v (%v)", changed, err)
+	}
+}
+
+func TestIndexChangedFiles_UpdatesSnapshot(t *testing.T) {
+	root := t.TempDir()
+	writeWatchFile(t, root, "a.md", "alpha")
+	cfg := &config.AutoIndexConfig{Enabled: true, Extensions: []string{".md"}, MaxFileSize: 1024}
+	indexer := NewAutoIndexer(cfg, watchEmbedder{}, &watchStore{}, root)
+
+	changed, _ := indexer.DetectChanges()
+	if indexed, err := indexer.IndexChangedFiles(changed); err != nil || indexed != 1 {
+		t.Fatalf("Expected 1 file indexed, got %d (%v)", indexed, err)
+	}
+	if changed, _ := indexer.DetectChanges(); len(changed) != 0 {
+		t.Errorf("Expected no changes after indexing, got %v", changed)
+	}
+}
+
+// writeLargeFile writes size bytes of text to name under root without
+// holding it all in memory
+func writeLargeFile(t *testing.T, root, name string, size int) {
+	t.Helper()
+	file, err := os.Create(filepath.Join(root, name))
+	if err != nil {
+		t.Fatalf("Failed to create %s: %v", name, err)
+	}
+	defer file.Close()
+	line := strings.Repeat("lorem ipsum ", 85) + "\n" // 1KB
+	for written := 0; written < size; written += len(line) {
+		if _, err := file.WriteString(line[:min(len(line), size-written)]); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+}
+
+// countingStore counts the documents added to it without keeping them, so it
+// adds nothing to the heap being measured
+type countingStore struct {
+	watchStore
+	count   atomic.Int64
+	longest atomic.Int64
+}
+
+func (s *countingStore) AddDocumentWithMetadata(collectionName, id, content string, embedding []float32, metadata map[string]interface{}) error {
+	s.count.Add(1)
+	if n := int64(len(content)); n > s.longest.Load() {
+		s.longest.Store(n)
+	}
+	return nil
+}
+
+// heapEmbedder records the most live heap seen while embedding
+type heapEmbedder struct {
+	peak uint64
+}
+
+func (e *heapEmbedder) GenerateEmbedding(text string) ([]float32, error) {
+	runtime.GC()
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	e.peak = max(e.peak, stats.HeapAlloc)
+	return []float32{0.1, 0.2}, nil
+}
+
+// budgetEmbedder tracks the bytes being embedded at once
+type budgetEmbedder struct {
+	current atomic.Int64
+	peak    atomic.Int64
+}
+
+func (e *budgetEmbedder) GenerateEmbedding(text string) ([]float32, error) {
+	n := e.current.Add(int64(len(text)))
+	for {
+		peak := e.peak.Load()
+		if n <= peak || e.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	time.Sleep(time.Millisecond)
+	e.current.Add(-int64(len(text)))
+	return []float32{0.1, 0.2}, nil
+}
+
+func TestIndexFile_SizeGate(t *testing.T) {
+	root := t.TempDir()
+	writeWatchFile(t, root, "small.md", "small")
+	writeLargeFile(t, root, "big.md", 1<<20)
+	store := &watchStore{}
+	cfg := &config.AutoIndexConfig{Enabled: true, Extensions: []string{".md"}, MaxFileSize: 1024}
+	indexer := NewAutoIndexer(cfg, watchEmbedder{}, store, root)
+
+	changed, err := indexer.DetectChanges()
+	if err != nil || len(changed) != 1 || changed[0] != "small.md" {
+		t.Fatalf("Expected only small.md to be tracked, got %v (%v)", changed, err)
+	}
+
+	if err := indexer.IndexFile("big.md"); !errors.Is(err, ErrFileTooLarge) {
+		t.Errorf("Expected ErrFileTooLarge, got: %v", err)
+	}
+
+	// A file that grows past the limit after it was found is not read either
+	writeLargeFile(t, root, "small.md", 2048)
+	indexed, err := indexer.IndexChangedFiles(changed)
+	if err != nil || indexed != 0 {
+		t.Errorf("Expected the grown file to be skipped, got %d indexed (%v)", indexed, err)
+	}
+	if contents := store.contents(); len(contents) != 0 {
+		t.Errorf("Expected nothing stored, got %d documents", len(contents))
+	}
+}
+
+func TestIndexFile_BoundedMemory(t *testing.T) {
+	const fileSize = 16 << 20
+	root := t.TempDir()
+	writeLargeFile(t, root, "large.md", fileSize)
+
+	embedder := &heapEmbedder{}
+	store := &countingStore{}
+	cfg := &config.AutoIndexConfig{Enabled: true, Extensions: []string{".md"}, MaxFileSize: 2 * fileSize}
+	indexer := NewAutoIndexer(cfg, embedder, store, root)
+	indexer.UseChunker(chunker.New(config.ChunkerConfig{ChunkSize: 64 << 10, ChunkOverlap: 0, Strategy: config.ChunkStrategyFixed}))
+
+	runtime.GC()
+	var before runtime.MemStats
+	runtime.ReadMemStats(&before)
+
+	if err := indexer.IndexFile("large.md"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if store.count.Load() != fileSize/(64<<10) || store.longest.Load() != 64<<10 {
+		t.Errorf("Expected %d chunks of 64KB, got %d with the longest %d bytes", fileSize/(64<<10), store.count.Load(), store.longest.Load())
+	}
+	// Holding the file whole would add at least its size to the heap
+	if grown := int64(embedder.peak) - int64(before.HeapAlloc); grown > 2<<20 {
+		t.Errorf("Expected the live heap to grow by well under the %d byte file, grew by %d", fileSize, grown)
+	}
+}
+
+func TestIndexFile_InFlightBudget(t *testing.T) {
+	root := t.TempDir()
+	names := []string{"a.md", "b.md", "c.md", "d.md"}
+	for _, name := range names {
+		writeLargeFile(t, root, name, 8<<10)
+	}
+
+	embedder := &budgetEmbedder{}
+	cfg := &config.AutoIndexConfig{Enabled: true, Extensions: []string{".md"}, MaxFileSize: 1 << 20}
+	indexer := NewAutoIndexer(cfg, embedder, &countingStore{}, root)
+	indexer.UseChunker(chunker.New(config.ChunkerConfig{ChunkSize: 1024, ChunkOverlap: 0}))
+	const limit = 2048
+	indexer.inFlight = semaphore.NewWeighted(limit)
+	indexer.inFlightLimit = limit
+
+	var wg sync.WaitGroup
+	for _, name := range names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := indexer.IndexFile(name); err != nil {
+				t.Errorf("Expected no error indexing %s, got: %v", name, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if peak := embedder.peak.Load(); peak == 0 || peak > limit {
+		t.Errorf("Expected at most %d bytes in flight, got %d", limit, peak)
+	}
+}
+
+func TestIndexFile_Metadata(t *testing.T) {
+	root := t.TempDir()
+	writeWatchFile(t, root, "notes.md", "first chunk second chunk")
+	store := &watchStore{}
+	cfg := &config.AutoIndexConfig{Enabled: true, Extensions: []string{".md"}}
+	indexer := NewAutoIndexer(cfg, watchEmbedder{}, store, root)
+	indexer.UseChunker(chunker.New(config.ChunkerConfig{ChunkSize: 12, ChunkOverlap: 0}))
+
+	if err := indexer.IndexFile("notes.md"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if len(store.metadata) < 2 {
+		t.Fatalf("Expected several chunks, got %d", len(store.metadata))
+	}
+	for i, metadata := range store.metadata {
+		if metadata["source_path"] != filepath.Join(root, "notes.md") || metadata["chunk_index"] != i {
+			t.Errorf("Expected chunk %d to record its file and index, got %v", i, metadata)
+		}
+		if _, err := time.Parse(time.RFC3339, metadata["indexed_at"].(string)); err != nil {
+			t.Errorf("Expected an RFC 3339 indexed_at, got %v", metadata["indexed_at"])
+		}
+	}
+}
+
+func TestIndexChangedFiles_RemovesStaleDocuments(t *testing.T) {
+	root := t.TempDir()
+	writeWatchFile(t, root, "notes.md", "first version")
+	writeWatchFile(t, root, "keep.md", "kept")
+	store := &watchStore{}
+	cfg := &config.AutoIndexConfig{Enabled: true, Extensions: []string{".md"}}
+	indexer := NewAutoIndexer(cfg, watchEmbedder{}, store, root)
+
+	index := func() {
+		t.Helper()
+		changed, err := indexer.DetectChanges()
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if _, err := indexer.IndexChangedFiles(changed); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+	}
+
+	index()
+	if got := store.stored(); strings.Join(got, ",") != "first version,kept" {
+		t.Fatalf("Expected both files indexed, got %v", got)
+	}
+
+	// A modified file replaces its documents rather than adding to them
+	writeWatchFile(t, root, "notes.md", "second version")
+	index()
+	if got := store.stored(); strings.Join(got, ",") != "kept,second version" {
+		t.Errorf("Expected only the new version of notes.md, got %v", got)
+	}
+
+	// A deleted file is reported as changed and its documents removed
+	if err := os.Remove(filepath.Join(root, "notes.md")); err != nil {
+		t.Fatalf("Failed to remove notes.md: %v", err)
+	}
+	changed, err := indexer.DetectChanges()
+	if err != nil || strings.Join(changed, ",") != "notes.md" {
+		t.Fatalf("Expected the deleted file to be reported, got %v (%v)", changed, err)
+	}
+	if _, err := indexer.IndexChangedFiles(changed); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if got := store.stored(); strings.Join(got, ",") != "kept" {
+		t.Errorf("Expected the deleted file's documents to be removed, got %v", got)
+	}
+	if changed, _ := indexer.DetectChanges(); len(changed) != 0 {
+		t.Errorf("Expected the deleted file to be forgotten, got %v", changed)
+	}
+}
+
+func TestAutoIndexer_ManifestAcrossRuns(t *testing.T) {
+	root := t.TempDir()
+	writeWatchFile(t, root, "notes.md", "first version")
+	writeWatchFile(t, root, "gone.md", "deleted between runs")
+	manifestPath := filepath.Join(t.TempDir(), "auto-index-manifest.json")
+	store := &watchStore{}
+	cfg := &config.AutoIndexConfig{Enabled: true, Extensions: []string{".md"}}
+
+	// start begins a run as a new chat or watch would, with a fresh indexer
+	start := func() *AutoIndexer {
+		t.Helper()
+		manifest, err := LoadManifest(manifestPath)
+		if err != nil {
+			t.Fatalf("Failed to load manifest: %v", err)
+		}
+		indexer := NewAutoIndexer(cfg, watchEmbedder{}, store, root)
+		indexer.UseManifest(manifest, manifestPath)
+		if err := indexer.TakeSnapshot(); err != nil {
+			t.Fatalf("Failed to take snapshot: %v", err)
+		}
+		return indexer
+	}
+
+	first := start()
+	if _, err := first.IndexChangedFiles([]string{"notes.md", "gone.md"}); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if got := store.stored(); strings.Join(got, ",") != "deleted between runs,first version" {
+		t.Fatalf("Expected both files indexed, got %v", got)
+	}
+
+	if err := os.Remove(filepath.Join(root, "gone.md")); err != nil {
+		t.Fatalf("Failed to remove gone.md: %v", err)
+	}
+	second := start()
+
+	// The file deleted while nothing was running is still found and removed
+	changed, err := second.DetectChanges()
+	if err != nil || strings.Join(changed, ",") != "gone.md" {
+		t.Fatalf("Expected the file deleted between runs to be reported, got %v (%v)", changed, err)
+	}
+	if _, err := second.IndexChangedFiles(changed); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	// A file indexed again replaces the documents of the earlier run
+	writeWatchFile(t, root, "notes.md", "second version")
+	if err := second.IndexFile("notes.md"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if got := store.stored(); strings.Join(got, ",") != "second version" {
+		t.Errorf("Expected only the second version of notes.md, got %v", got)
+	}
+
+	manifest, err := LoadManifest(manifestPath)
+	if err != nil {
+		t.Fatalf("Failed to load manifest: %v", err)
+	}
+	files := manifest.Files("auto_indexed")
+	if _, ok := files[filepath.Join(root, "gone.md")]; ok || len(files) != 1 {
+		t.Errorf("Expected the manifest to hold only notes.md, got %v", files)
+	}
+}
@@ -0,0 +1,133 @@
+package indexing
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ExcludeMatcher matches paths against gitignore-style patterns:
+//
+//   - "vendor/" matches directories named vendor at any depth
+//   - "*.log" matches files by name at any depth
+//   - "docs/old" or "/build" is anchored to the root being indexed
+//   - "**" matches across directories, e.g. "**/testdata/**"
+//   - "!keep.md" re-includes a path excluded by an earlier pattern
+//
+// As in gitignore, the last matching pattern decides.
+type ExcludeMatcher struct {
+	rules []excludeRule
+}
+
+type excludeRule struct {
+	pattern *regexp.Regexp
+	dirOnly bool
+	negate  bool
+}
+
+// NewExcludeMatcher compiles the given patterns. Empty patterns and comments
+// starting with # are ignored.
+func NewExcludeMatcher(patterns []string) (*ExcludeMatcher, error) {
+	matcher := &ExcludeMatcher{}
+	for _, raw := range patterns {
+		pattern := strings.TrimSpace(raw)
+		if pattern == "" || strings.HasPrefix(pattern, "#") {
+			continue
+		}
+
+		rule := excludeRule{}
+		if strings.HasPrefix(pattern, "!") {
+			rule.negate = true
+			pattern = pattern[1:]
+		}
+		if strings.HasSuffix(pattern, "/") {
+			rule.dirOnly = true
+			pattern = strings.TrimSuffix(pattern, "/")
+		}
+
+		// Patterns without a slash match a name at any depth
+		anchored := strings.Contains(pattern, "/")
+		pattern = strings.TrimPrefix(pattern, "/")
+		if !anchored && !strings.HasPrefix(pattern, "**") {
+			pattern = "**/" + pattern
+		}
+
+		re, err := regexp.Compile("^" + globToRegexp(pattern) + "$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid exclude pattern %q: %w", raw, err)
+		}
+		rule.pattern = re
+		matcher.rules = append(matcher.rules, rule)
+	}
+	return matcher, nil
+}
+
+// Excluded reports whether relPath, relative to the indexed root, is excluded.
+// Callers walking a tree should skip excluded directories entirely.
+func (m *ExcludeMatcher) Excluded(relPath string, isDir bool) bool {
+	if m == nil {
+		return false
+	}
+	relPath = filepath.ToSlash(filepath.Clean(relPath))
+
+	excluded := false
+	for _, rule := range m.rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		if rule.pattern.MatchString(relPath) {
+			excluded = !rule.negate
+		}
+	}
+	return excluded
+}
+
+// ReadIgnoreFile returns the patterns listed in a gitignore-style file, one
+// per line. A missing file has no patterns.
+func ReadIgnoreFile(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ignore file: %w", err)
+	}
+	return strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n"), nil
+}
+
+// globToRegexp translates a glob with ** support into a regular expression
+func globToRegexp(glob string) string {
+	var re strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			re.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			re.WriteString(".*")
+			i++
+		case c == '*':
+			re.WriteString("[^/]*")
+		case c == '?':
+			re.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i:], ']')
+			if end <= 1 {
+				re.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			re.WriteString("[" + class + "]")
+			i += end
+		default:
+			re.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return re.String()
+}
@@ -0,0 +1,42 @@
+package indexing
+
+import "testing"
+
+func TestExcludeMatcher(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		path     string
+		isDir    bool
+		expected bool
+	}{
+		{"dir pattern matches dir", []string{"vendor/"}, "vendor", true, true},
+		{"dir pattern matches nested dir", []string{"vendor/"}, "pkg/vendor", true, true},
+		{"dir pattern ignores files", []string{"vendor/"}, "vendor", false, false},
+		{"name glob at any depth", []string{"*.log"}, "a/b/debug.log", false, true},
+		{"name glob does not cross into names", []string{"*.log"}, "debug.log.txt", false, false},
+		{"anchored pattern", []string{"docs/old"}, "docs/old", true, true},
+		{"anchored pattern not nested", []string{"docs/old"}, "x/docs/old", true, false},
+		{"leading slash anchors", []string{"/build"}, "build", true, true},
+		{"leading slash not nested", []string{"/build"}, "src/build", true, false},
+		{"double star", []string{"**/testdata/**"}, "pkg/a/testdata/f.txt", false, true},
+		{"negation re-includes", []string{"*.md", "!README.md"}, "README.md", false, false},
+		{"last match wins", []string{"!README.md", "*.md"}, "README.md", false, true},
+		{"question mark", []string{"file?.txt"}, "file1.txt", false, true},
+		{"character class", []string{"file[0-9].txt"}, "fileA.txt", false, false},
+		{"comments ignored", []string{"# *.txt"}, "a.txt", false, false},
+		{"no patterns", nil, "a.txt", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matcher, err := NewExcludeMatcher(tt.patterns)
+			if err != nil {
+				t.Fatalf("Failed to compile patterns: %v", err)
+			}
+			if got := matcher.Excluded(tt.path, tt.isDir); got != tt.expected {
+				t.Errorf("Expected Excluded(%q) with %v to be %t, got %t", tt.path, tt.patterns, tt.expected, got)
+			}
+		})
+	}
+}
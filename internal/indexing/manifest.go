@@ -0,0 +1,121 @@
+package indexing
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"rag-cli/pkg/paths"
+)
+
+// ManifestEntry records what `rag-cli index` stored for one file
+type ManifestEntry struct {
+	Hash      string    `json:"hash"`
+	Size      int64     `json:"size"`
+	DocIDs    []string  `json:"doc_ids"`
+	IndexedAt time.Time `json:"indexed_at"`
+}
+
+// Manifest records, per collection, the files `rag-cli index` has stored and
+// the content they had, so later runs can skip files that have not changed and
+// replace the documents of files that have. It is safe for concurrent use.
+type Manifest struct {
+	Collections map[string]map[string]ManifestEntry `json:"collections"`
+
+	mutex sync.Mutex
+}
+
+// DefaultManifestPath returns the location of the persisted index manifest
+func DefaultManifestPath() (string, error) {
+	dirs, err := paths.Default()
+	if err != nil {
+		return "", err
+	}
+	return dirs.DataFile("index-manifest.json"), nil
+}
+
+// LoadManifest reads the index manifest. A missing file is not an error and
+// returns an empty manifest, meaning nothing has been indexed yet.
+func LoadManifest(path string) (*Manifest, error) {
+	manifest := &Manifest{Collections: make(map[string]map[string]ManifestEntry)}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return manifest, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read index manifest: %w", err)
+	}
+
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse index manifest %s: %w", path, err)
+	}
+	if manifest.Collections == nil {
+		manifest.Collections = make(map[string]map[string]ManifestEntry)
+	}
+	return manifest, nil
+}
+
+// Save persists the manifest, creating its directory if needed
+func (m *Manifest) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	m.mutex.Lock()
+	data, err := json.MarshalIndent(m, "", "  ")
+	m.mutex.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to marshal index manifest: %w", err)
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// Lookup returns the entry recorded for a file in a collection
+func (m *Manifest) Lookup(collection, path string) (ManifestEntry, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	entry, ok := m.Collections[collection][path]
+	return entry, ok
+}
+
+// Record remembers what was stored for a file in a collection, replacing any
+// earlier entry
+func (m *Manifest) Record(collection, path string, entry ManifestEntry) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	files, ok := m.Collections[collection]
+	if !ok {
+		files = make(map[string]ManifestEntry)
+		m.Collections[collection] = files
+	}
+	files[path] = entry
+}
+
+// Forget removes the entry recorded for a file in a collection
+func (m *Manifest) Forget(collection, path string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	delete(m.Collections[collection], path)
+}
+
+// Files returns a copy of the entries recorded for a collection, by path
+func (m *Manifest) Files(collection string) map[string]ManifestEntry {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return maps.Clone(m.Collections[collection])
+}
+
+// DefaultAutoIndexManifestPath returns the location of the manifest of files
+// auto-indexed during chats and by 'rag-cli watch'. It is kept apart from the
+// index manifest so that neither overwrites the other's entries.
+func DefaultAutoIndexManifestPath() (string, error) {
+	dirs, err := paths.Default()
+	if err != nil {
+		return "", err
+	}
+	return dirs.DataFile("auto-index-manifest.json"), nil
+}
@@ -0,0 +1,49 @@
+package indexing
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestManifest_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "index-manifest.json")
+
+	manifest, err := LoadManifest(path)
+	if err != nil {
+		t.Fatalf("Expected no error for a missing file, got: %v", err)
+	}
+	if _, ok := manifest.Lookup("documents", "/docs/a.md"); ok {
+		t.Error("Expected an empty manifest for a missing file")
+	}
+
+	entry := ManifestEntry{Hash: "abc", Size: 7, DocIDs: []string{"index_1_0", "index_1_1"}, IndexedAt: time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)}
+	manifest.Record("documents", "/docs/a.md", entry)
+	manifest.Record("project-x", "/docs/a.md", ManifestEntry{Hash: "def"})
+	if err := manifest.Save(path); err != nil {
+		t.Fatalf("Failed to save manifest: %v", err)
+	}
+
+	loaded, err := LoadManifest(path)
+	if err != nil {
+		t.Fatalf("Failed to load manifest: %v", err)
+	}
+	got, ok := loaded.Lookup("documents", "/docs/a.md")
+	if !ok || got.Hash != "abc" || got.Size != 7 || len(got.DocIDs) != 2 || !got.IndexedAt.Equal(entry.IndexedAt) {
+		t.Errorf("Expected %+v, got %+v", entry, got)
+	}
+	if got, _ := loaded.Lookup("project-x", "/docs/a.md"); got.Hash != "def" {
+		t.Errorf("Expected collections to be kept apart, got %+v", got)
+	}
+}
+
+func TestLoadManifest_Corrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index-manifest.json")
+	if err := os.WriteFile(path, []byte("not json"), 0644); err != nil {
+		t.Fatalf("Failed to write manifest: %v", err)
+	}
+	if _, err := LoadManifest(path); err == nil {
+		t.Error("Expected an error for a corrupt manifest")
+	}
+}
@@ -0,0 +1,60 @@
+package indexing
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"rag-cli/pkg/paths"
+)
+
+// IndexState records the outcome of the most recent `rag-cli index` run
+type IndexState struct {
+	LastRun    time.Time `json:"last_run"`
+	Path       string    `json:"path"`
+	Collection string    `json:"collection"`
+	Files      int       `json:"files"`
+	Chunks     int       `json:"chunks"`
+}
+
+// DefaultStatePath returns the location of the persisted index state
+func DefaultStatePath() (string, error) {
+	dirs, err := paths.Default()
+	if err != nil {
+		return "", err
+	}
+	return dirs.DataFile("index-state.json"), nil
+}
+
+// LoadIndexState reads the persisted index state. A missing file is not an
+// error and returns nil, meaning no index run has been recorded yet.
+func LoadIndexState(path string) (*IndexState, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read index state: %w", err)
+	}
+
+	var state IndexState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse index state %s: %w", path, err)
+	}
+	return &state, nil
+}
+
+// SaveIndexState persists the index state, creating its directory if needed
+func SaveIndexState(path string, state *IndexState) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal index state: %w", err)
+	}
+	return os.WriteFile(path, data, 0644)
+}
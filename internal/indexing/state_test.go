@@ -0,0 +1,35 @@
+package indexing
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestIndexState_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "index-state.json")
+
+	state, err := LoadIndexState(path)
+	if err != nil || state != nil {
+		t.Fatalf("Expected nil state and no error for missing file, got %+v, %v", state, err)
+	}
+
+	saved := &IndexState{
+		LastRun:    time.Date(2025, 7, 12, 9, 30, 0, 0, time.UTC),
+		Path:       "/tmp/docs",
+		Collection: "documents",
+		Files:      3,
+		Chunks:     9,
+	}
+	if err := SaveIndexState(path, saved); err != nil {
+		t.Fatalf("Failed to save state: %v", err)
+	}
+
+	loaded, err := LoadIndexState(path)
+	if err != nil {
+		t.Fatalf("Failed to load state: %v", err)
+	}
+	if !loaded.LastRun.Equal(saved.LastRun) || loaded.Path != saved.Path || loaded.Chunks != 9 {
+		t.Errorf("Expected %+v, got %+v", saved, loaded)
+	}
+}
@@ -0,0 +1,204 @@
+package indexing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Watcher keeps the auto-index collection in sync with a directory tree by
+// batching file system events and indexing changed files once they settle
+type Watcher struct {
+	indexer *AutoIndexer
+	ignore  *ExcludeMatcher
+	delay   time.Duration
+	dryRun  bool
+	out     io.Writer
+
+	// addDir starts watching a directory; set by WatchTree
+	addDir func(path string) error
+}
+
+// NewWatcher creates a watcher for the indexer's working directory. Changes
+// are indexed once no new event has arrived for delay. Paths matched by
+// ignore are skipped on top of the indexer's own extension, size, and
+// exclude rules. With dryRun, changes are reported but not indexed.
+func NewWatcher(indexer *AutoIndexer, ignore *ExcludeMatcher, delay time.Duration, dryRun bool, out io.Writer) *Watcher {
+	return &Watcher{
+		indexer: indexer,
+		ignore:  ignore,
+		delay:   delay,
+		dryRun:  dryRun,
+		out:     out,
+	}
+}
+
+// WatchTree registers every directory under the working directory that is
+// not ignored, using add. Directories created later are added as they appear.
+func (w *Watcher) WatchTree(add func(path string) error) error {
+	w.addDir = add
+	return w.watchDir(w.indexer.workingDir)
+}
+
+// watchDir adds dir and its subdirectories
+func (w *Watcher) watchDir(dir string) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil // Skip directories that can't be accessed
+		}
+		if !info.IsDir() {
+			return nil
+		}
+		if path != w.indexer.workingDir && w.ignored(path, true) {
+			return filepath.SkipDir
+		}
+		if err := w.addDir(path); err != nil {
+			return fmt.Errorf("failed to watch %s: %w", path, err)
+		}
+		return nil
+	})
+}
+
+// Run processes events until ctx is cancelled or events is closed, then
+// indexes any changes still waiting for the debounce delay
+func (w *Watcher) Run(ctx context.Context, events <-chan fsnotify.Event, errs <-chan error) error {
+	pending := make(map[string]bool)
+	timer := time.NewTimer(w.delay)
+	timer.Stop()
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			w.flush(pending)
+			return nil
+		case event, ok := <-events:
+			if !ok {
+				w.flush(pending)
+				return nil
+			}
+			if relPath, ok := w.handleEvent(event); ok {
+				pending[relPath] = true
+				timer.Reset(w.delay)
+			}
+		case err, ok := <-errs:
+			if !ok {
+				errs = nil
+				continue
+			}
+			fmt.Fprintf(w.out, "Watch error: %v\n", err)
+		case <-timer.C:
+			w.flush(pending)
+		}
+	}
+}
+
+// handleEvent returns the relative path of a file that needs indexing, or
+// whose documents need removing because it was deleted or renamed. Newly
+// created directories are watched instead.
+func (w *Watcher) handleEvent(event fsnotify.Event) (string, bool) {
+	if event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename) {
+		relPath, err := filepath.Rel(w.indexer.workingDir, event.Name)
+		if err != nil || w.ignored(event.Name, false) || !w.indexer.shouldTrackFile(relPath, 0) {
+			return "", false
+		}
+		return relPath, true
+	}
+	if !event.Has(fsnotify.Create) && !event.Has(fsnotify.Write) {
+		return "", false
+	}
+
+	info, err := os.Stat(event.Name)
+	if err != nil {
+		return "", false
+	}
+	if info.IsDir() {
+		if event.Has(fsnotify.Create) && w.addDir != nil && !w.ignored(event.Name, true) {
+			if err := w.watchDir(event.Name); err != nil {
+				fmt.Fprintf(w.out, "Watch error: %v\n", err)
+			}
+		}
+		return "", false
+	}
+
+	relPath, err := filepath.Rel(w.indexer.workingDir, event.Name)
+	if err != nil || w.ignored(event.Name, false) || !w.indexer.shouldTrackFile(relPath, info.Size()) {
+		return "", false
+	}
+	return relPath, true
+}
+
+// ignored reports whether path, or any directory above it, matches the
+// ignore patterns. Version control directories are always ignored.
+func (w *Watcher) ignored(path string, isDir bool) bool {
+	relPath, err := filepath.Rel(w.indexer.workingDir, path)
+	if err != nil {
+		return true
+	}
+	parts := strings.Split(filepath.ToSlash(relPath), "/")
+	for i := range parts {
+		dir := i < len(parts)-1 || isDir
+		if dir && parts[i] == ".git" {
+			return true
+		}
+		if w.ignore.Excluded(strings.Join(parts[:i+1], "/"), dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// flush indexes the pending files, or removes the documents of those that
+// are gone, printing a line for each, and clears them
+func (w *Watcher) flush(pending map[string]bool) {
+	if len(pending) == 0 {
+		return
+	}
+	files := make([]string, 0, len(pending))
+	for relPath := range pending {
+		files = append(files, relPath)
+		delete(pending, relPath)
+	}
+	sort.Strings(files)
+
+	timestamp := time.Now().Format("15:04:05")
+	for _, relPath := range files {
+		if _, err := os.Stat(filepath.Join(w.indexer.workingDir, relPath)); os.IsNotExist(err) {
+			w.remove(timestamp, relPath)
+			continue
+		}
+		if w.dryRun {
+			fmt.Fprintf(w.out, "[%s] would index %s\n", timestamp, relPath)
+			continue
+		}
+		if err := w.indexer.IndexFile(relPath); err != nil {
+			fmt.Fprintf(w.out, "[%s] failed %s: %v\n", timestamp, relPath, err)
+			continue
+		}
+		fmt.Fprintf(w.out, "[%s] indexed %s\n", timestamp, relPath)
+	}
+}
+
+// remove deletes the documents of a file that is gone, printing a line when
+// it had any
+func (w *Watcher) remove(timestamp, relPath string) {
+	if w.dryRun {
+		fmt.Fprintf(w.out, "[%s] would remove %s\n", timestamp, relPath)
+		return
+	}
+	removed, err := w.indexer.RemoveFile(relPath)
+	if err != nil {
+		fmt.Fprintf(w.out, "[%s] failed %s: %v\n", timestamp, relPath, err)
+		return
+	}
+	if removed > 0 {
+		fmt.Fprintf(w.out, "[%s] removed %s\n", timestamp, relPath)
+	}
+}
@@ -0,0 +1,279 @@
+package indexing
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"rag-cli/internal/vector"
+	"rag-cli/pkg/config"
+)
+
+type watchEmbedder struct{}
+
+func (watchEmbedder) GenerateEmbedding(text string) ([]float32, error) {
+	return []float32{0.1, 0.2}, nil
+}
+
+// watchStore records the documents added to and deleted from it; other
+// VectorStore methods are not used
+type watchStore struct {
+	vector.VectorStore
+
+	mu       sync.Mutex
+	added    []string
+	metadata []map[string]interface{}
+	docs     map[string]string // Documents not deleted, by ID
+}
+
+func (s *watchStore) AddDocumentWithMetadata(collectionName, id, content string, embedding []float32, metadata map[string]interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.added = append(s.added, content)
+	s.metadata = append(s.metadata, metadata)
+	if s.docs == nil {
+		s.docs = make(map[string]string)
+	}
+	s.docs[id] = content
+	return nil
+}
+
+func (s *watchStore) DeleteDocuments(collectionName string, ids []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range ids {
+		delete(s.docs, id)
+	}
+	return nil
+}
+
+// stored returns the contents of the documents not deleted, sorted
+func (s *watchStore) stored() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	contents := make([]string, 0, len(s.docs))
+	for _, content := range s.docs {
+		contents = append(contents, content)
+	}
+	sort.Strings(contents)
+	return contents
+}
+
+func (s *watchStore) AutoIndexCollection() string { return "auto_indexed" }
+
+func (s *watchStore) contents() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	contents := append([]string{}, s.added...)
+	sort.Strings(contents)
+	return contents
+}
+
+// newWatchFixture creates a working tree and a watcher over it. The tree has
+// a .gitignore that ignores build/ and *.tmp.
+func newWatchFixture(t *testing.T, delay time.Duration, dryRun bool) (*Watcher, *watchStore, *bytes.Buffer, string) {
+	t.Helper()
+	root := t.TempDir()
+	for _, dir := range []string{"docs", "build", ".git", "docs/nested"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatalf("Failed to create %s: %v", dir, err)
+		}
+	}
+	writeWatchFile(t, root, ".gitignore", "build/\n*.tmp\n")
+
+	patterns, err := ReadIgnoreFile(filepath.Join(root, ".gitignore"))
+	if err != nil {
+		t.Fatalf("Failed to read ignore file: %v", err)
+	}
+	ignore, err := NewExcludeMatcher(patterns)
+	if err != nil {
+		t.Fatalf("Failed to compile patterns: %v", err)
+	}
+
+	cfg := &config.AutoIndexConfig{
+		Enabled:     true,
+		Extensions:  []string{".md", ".txt", ".tmp"},
+		MaxFileSize: 64,
+	}
+	store := &watchStore{}
+	indexer := NewAutoIndexer(cfg, watchEmbedder{}, store, root)
+	var out bytes.Buffer
+	return NewWatcher(indexer, ignore, delay, dryRun, &out), store, &out, root
+}
+
+func writeWatchFile(t *testing.T, root, name, content string) string {
+	t.Helper()
+	path := filepath.Join(root, name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", name, err)
+	}
+	return path
+}
+
+func TestWatcher_WatchTree(t *testing.T) {
+	watcher, _, _, root := newWatchFixture(t, time.Millisecond, false)
+
+	var watched []string
+	err := watcher.WatchTree(func(path string) error {
+		relPath, _ := filepath.Rel(root, path)
+		watched = append(watched, filepath.ToSlash(relPath))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	expected := []string{".", "docs", "docs/nested"}
+	if strings.Join(watched, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected to watch %v, got %v", expected, watched)
+	}
+}
+
+func TestWatcher_Run(t *testing.T) {
+	t.Run("indexes tracked changes after the debounce delay", func(t *testing.T) {
+		watcher, store, out, root := newWatchFixture(t, 10*time.Millisecond, false)
+		notes := writeWatchFile(t, root, "docs/notes.md", "notes")
+		todo := writeWatchFile(t, root, "todo.txt", "todo")
+		ignored := []fsnotify.Event{
+			{Name: writeWatchFile(t, root, "build/out.md", "build output"), Op: fsnotify.Write},
+			{Name: writeWatchFile(t, root, "scratch.tmp", "scratch"), Op: fsnotify.Create},
+			{Name: writeWatchFile(t, root, "image.png", "png"), Op: fsnotify.Create},
+			{Name: writeWatchFile(t, root, "big.md", strings.Repeat("x", 100)), Op: fsnotify.Write},
+			{Name: writeWatchFile(t, root, ".git/HEAD.md", "ref"), Op: fsnotify.Write},
+			{Name: filepath.Join(root, "gone.md"), Op: fsnotify.Remove},
+			{Name: notes, Op: fsnotify.Chmod},
+		}
+
+		events := make(chan fsnotify.Event)
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error)
+		go func() { done <- watcher.Run(ctx, events, nil) }()
+
+		// Repeated writes to the same file are indexed once
+		for _, event := range append(ignored,
+			fsnotify.Event{Name: notes, Op: fsnotify.Create},
+			fsnotify.Event{Name: notes, Op: fsnotify.Write},
+			fsnotify.Event{Name: todo, Op: fsnotify.Write},
+		) {
+			events <- event
+		}
+
+		deadline := time.Now().Add(2 * time.Second)
+		for len(store.contents()) < 2 && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+		cancel()
+		if err := <-done; err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+
+		if got := store.contents(); strings.Join(got, ",") != "notes,todo" {
+			t.Errorf("Expected notes and todo to be indexed once each, got %v", got)
+		}
+		output := out.String()
+		for _, expected := range []string{"indexed docs/notes.md", "indexed todo.txt"} {
+			if !strings.Contains(output, expected) {
+				t.Errorf("Expected output to contain %q, got:\n%s", expected, output)
+			}
+		}
+		if strings.Count(output, "indexed") != 2 {
+			t.Errorf("Expected one line per indexed file, got:\n%s", output)
+		}
+	})
+
+	t.Run("flushes pending changes on shutdown", func(t *testing.T) {
+		watcher, store, out, root := newWatchFixture(t, time.Hour, false)
+		notes := writeWatchFile(t, root, "notes.md", "notes")
+
+		events := make(chan fsnotify.Event, 1)
+		events <- fsnotify.Event{Name: notes, Op: fsnotify.Write}
+		close(events)
+
+		if err := watcher.Run(context.Background(), events, nil); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if len(store.contents()) != 1 || !strings.Contains(out.String(), "indexed notes.md") {
+			t.Errorf("Expected the pending change to be indexed, got %v and output:\n%s", store.contents(), out.String())
+		}
+	})
+
+	t.Run("removes deleted files", func(t *testing.T) {
+		watcher, store, out, root := newWatchFixture(t, time.Hour, false)
+		notes := writeWatchFile(t, root, "notes.md", "notes")
+		if err := watcher.indexer.IndexFile("notes.md"); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if err := os.Remove(notes); err != nil {
+			t.Fatalf("Failed to remove notes.md: %v", err)
+		}
+
+		events := make(chan fsnotify.Event, 1)
+		events <- fsnotify.Event{Name: notes, Op: fsnotify.Remove}
+		close(events)
+
+		if err := watcher.Run(context.Background(), events, nil); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if len(store.stored()) != 0 || !strings.Contains(out.String(), "removed notes.md") {
+			t.Errorf("Expected the file's documents to be removed, got %v and output:\n%s", store.stored(), out.String())
+		}
+	})
+
+	t.Run("dry run reports without indexing", func(t *testing.T) {
+		watcher, store, out, root := newWatchFixture(t, time.Hour, true)
+		notes := writeWatchFile(t, root, "notes.md", "notes")
+
+		events := make(chan fsnotify.Event, 1)
+		events <- fsnotify.Event{Name: notes, Op: fsnotify.Write}
+		close(events)
+
+		if err := watcher.Run(context.Background(), events, nil); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if len(store.contents()) != 0 {
+			t.Errorf("Expected nothing to be indexed, got %v", store.contents())
+		}
+		if !strings.Contains(out.String(), "would index notes.md") {
+			t.Errorf("Expected dry run output, got:\n%s", out.String())
+		}
+	})
+
+	t.Run("watches new directories", func(t *testing.T) {
+		watcher, _, _, root := newWatchFixture(t, time.Hour, true)
+		var watched []string
+		if err := watcher.WatchTree(func(path string) error {
+			watched = append(watched, path)
+			return nil
+		}); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		watched = nil
+
+		created := filepath.Join(root, "docs", "new")
+		if err := os.MkdirAll(filepath.Join(created, "deeper"), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.MkdirAll(filepath.Join(root, "build", "cache"), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+
+		events := make(chan fsnotify.Event, 2)
+		events <- fsnotify.Event{Name: created, Op: fsnotify.Create}
+		events <- fsnotify.Event{Name: filepath.Join(root, "build", "cache"), Op: fsnotify.Create}
+		close(events)
+		if err := watcher.Run(context.Background(), events, nil); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+
+		if len(watched) != 2 || watched[0] != created || watched[1] != filepath.Join(created, "deeper") {
+			t.Errorf("Expected the new directory tree to be watched, got %v", watched)
+		}
+	})
+}
@@ -2,24 +2,39 @@ package llm
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"strings"
 	"sync"
+	"text/template"
 	"time"
 
+	"rag-cli/internal/httpclient"
 	"rag-cli/internal/system"
 	"rag-cli/pkg/config"
 )
 
+// ErrModelNotFound is returned when the server does not have the requested model
+var ErrModelNotFound = errors.New("model not found")
+
 type Client struct {
 	baseURL    string
+	provider   string // config.ProviderOllama or config.ProviderOpenAI
+	apiKey     string // Sent as a bearer token when set
 	client     *http.Client
+	retry      httpclient.Retry
 	model      string
 	systemInfo *system.SystemInfo
-	sysOnce    sync.Once
+	sysMu      sync.Mutex
+	sysCache   *system.Cache                 // Where system information is read from; nil detects it
+	prompts    map[string]*template.Template // Custom prompts by config prompt name
+	lastPrompt string                        // Most recent prompt built by GenerateResponse
+	promptMu   sync.Mutex
 }
 
 type GenerateRequest struct {
@@ -31,58 +46,287 @@ type GenerateRequest struct {
 type GenerateResponse struct {
 	Response string `json:"response"`
 	Done     bool   `json:"done"`
+	Error    string `json:"error,omitempty"` // Set when a stream fails part way
+}
+
+type TagsResponse struct {
+	Models []struct {
+		Name string `json:"name"`
+	} `json:"models"`
 }
 
-func NewClient(cfg config.LLMConfig) (*Client, error) {
+func NewClient(cfg config.LLMConfig, timeouts config.TimeoutsConfig) (*Client, error) {
 	return &Client{
-		baseURL: cfg.BaseURL,
-		model:   cfg.Model,
-		client: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		baseURL:  cfg.URL(),
+		provider: cfg.Provider,
+		apiKey:   cfg.APIKey,
+		model:    cfg.Model,
+		client:   httpclient.New(timeouts.LLM, timeouts),
+		retry:    httpclient.Retry{MaxRetries: cfg.MaxRetries, Backoff: cfg.RetryBackoff},
 	}, nil
 }
 
+// openAI reports whether the server speaks the OpenAI-compatible API rather
+// than Ollama's
+func (c *Client) openAI() bool {
+	return c.provider == config.ProviderOpenAI
+}
+
+// endpoint returns the URL of an API path, given as Ollama's path and as the
+// OpenAI-compatible one
+func (c *Client) endpoint(ollamaPath, openAIPath string) string {
+	if c.openAI() {
+		return config.OpenAIURL(c.baseURL, openAIPath)
+	}
+	return c.baseURL + ollamaPath
+}
+
+// newRequest creates a request to the server, with the API key when one is set
+func (c *Client) newRequest(ctx context.Context, method, url string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+	return req, nil
+}
+
 // getSystemInfo returns cached system information, detecting it once
 func (c *Client) getSystemInfo() *system.SystemInfo {
-	c.sysOnce.Do(func() {
-		c.systemInfo = system.DetectSystemInfo()
-	})
+	c.sysMu.Lock()
+	defer c.sysMu.Unlock()
+	if c.systemInfo == nil {
+		if c.sysCache != nil {
+			c.systemInfo = c.sysCache.Info()
+		} else {
+			c.systemInfo = system.DetectSystemInfo()
+		}
+	}
 	return c.systemInfo
 }
 
-func (c *Client) GenerateResponse(query string, context []string) (string, error) {
-	// Build prompt with context
-	prompt := c.buildPrompt(query, context)
-	
-	// Prepare request
-	req := GenerateRequest{
-		Model:  c.model,
-		Prompt: prompt,
-		Stream: false,
+// SystemInfo returns the system information used to build command prompts
+func (c *Client) SystemInfo() *system.SystemInfo {
+	return c.getSystemInfo()
+}
+
+// RefreshSystemInfo detects system information again, rewriting the cache,
+// for use after tools are installed or upgraded
+func (c *Client) RefreshSystemInfo() *system.SystemInfo {
+	var info *system.SystemInfo
+	if c.sysCache != nil {
+		info = c.sysCache.Refresh()
+	} else {
+		info = system.DetectSystemInfo()
 	}
+	c.sysMu.Lock()
+	c.systemInfo = info
+	c.sysMu.Unlock()
+	return info
+}
 
-	reqBody, err := json.Marshal(req)
+// GenerateResponse asks the model for shell command(s) that accomplish query
+func (c *Client) GenerateResponse(query string, contextDocs []string) (string, error) {
+	return c.GenerateResponseContext(context.Background(), query, contextDocs)
+}
+
+// GenerateResponseContext is GenerateResponse with a context that cancels the
+// request to the model
+func (c *Client) GenerateResponseContext(ctx context.Context, query string, contextDocs []string) (string, error) {
+	return c.GenerateResponseWithHistory(ctx, query, contextDocs, nil)
+}
+
+// GenerateResponseWithHistory is GenerateResponseContext for a request that
+// follows earlier turns of a conversation, which are included in the prompt
+// so the model can resolve references to them
+func (c *Client) GenerateResponseWithHistory(ctx context.Context, query string, contextDocs []string, history []Turn) (string, error) {
+	prompt, err := c.responsePrompt(query, contextDocs, history)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
+		return "", err
 	}
+	c.setLastPrompt(prompt)
+	return c.generate(ctx, prompt)
+}
+
+// GenerateResponseStream is GenerateResponseContext with the response
+// streamed: onToken is called with each piece of text as the model produces
+// it, and the whole response is returned at the end. When the stream breaks
+// off, as when ctx is cancelled, the text received so far is returned with
+// the error.
+func (c *Client) GenerateResponseStream(ctx context.Context, query string, contextDocs []string, onToken func(token string)) (string, error) {
+	return c.GenerateResponseStreamWithHistory(ctx, query, contextDocs, nil, onToken)
+}
 
-	// Make HTTP request
-	resp, err := http.Post(c.baseURL+"/api/generate", "application/json", bytes.NewBuffer(reqBody))
+// GenerateResponseStreamWithHistory is GenerateResponseStream for a request
+// that follows earlier turns of a conversation
+func (c *Client) GenerateResponseStreamWithHistory(ctx context.Context, query string, contextDocs []string, history []Turn, onToken func(token string)) (string, error) {
+	prompt, err := c.responsePrompt(query, contextDocs, history)
 	if err != nil {
-		return "", fmt.Errorf("failed to make request: %w", err)
+		return "", err
+	}
+	c.setLastPrompt(prompt)
+	return c.generateStream(ctx, prompt, onToken)
+}
+
+// SummarizeConversation asks the model for a short summary of turns, folding
+// in summary, the summary of turns before them, when there is one. Chat uses
+// it to keep the gist of turns that no longer fit in the prompt.
+func (c *Client) SummarizeConversation(ctx context.Context, summary string, turns []Turn) (string, error) {
+	var prompt strings.Builder
+	prompt.WriteString("Summarize this conversation between a user and a command-line assistant in at most three sentences. ")
+	prompt.WriteString("Keep the file names, directories, commands and goals mentioned, since later requests may refer to them. ")
+	prompt.WriteString("Output only the summary.\n\n")
+	if summary != "" {
+		turns = append([]Turn{{Role: RoleSummary, Content: summary}}, turns...)
+	}
+	prompt.WriteString(FormatTurns(turns))
+	prompt.WriteString("\nSummary: ")
+
+	response, err := c.generate(ctx, prompt.String())
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(response), nil
+}
+
+func (c *Client) setLastPrompt(prompt string) {
+	c.promptMu.Lock()
+	c.lastPrompt = prompt
+	c.promptMu.Unlock()
+}
+
+// responsePrompt renders the prompt GenerateResponse sends: the custom
+// command generation template when one is configured, or the built-in prompt
+func (c *Client) responsePrompt(query string, contextDocs []string, history []Turn) (string, error) {
+	tmpl := c.Prompt(config.PromptCommandGeneration)
+	if tmpl == nil {
+		return c.buildPrompt(query, contextDocs, history), nil
+	}
+	return config.RenderPrompt(tmpl, config.PromptData{
+		Request:      query,
+		Context:      contextDocs,
+		SystemHints:  c.getSystemInfo().GetCommandSyntaxHints(),
+		Conversation: FormatTurns(history),
+	})
+}
+
+// PromptSize returns the characters of the prompt GenerateResponse would send
+// for query with contextDocs and history, so callers can keep it within the
+// model's context window
+func (c *Client) PromptSize(query string, contextDocs []string, history []Turn) int {
+	prompt, err := c.responsePrompt(query, contextDocs, history)
+	if err != nil {
+		prompt = c.buildPrompt(query, contextDocs, history)
+	}
+	return len(prompt)
+}
+
+// LastPrompt returns the prompt most recently sent by GenerateResponse,
+// exactly as the model received it, or "" before the first request. It may
+// contain secrets from retrieved documents; see RedactSecrets.
+func (c *Client) LastPrompt() string {
+	c.promptMu.Lock()
+	defer c.promptMu.Unlock()
+	return c.lastPrompt
+}
+
+// Model returns the name of the model that answers requests
+func (c *Client) Model() string {
+	return c.model
+}
+
+// UseSystemInfoCache reads system information from cache instead of
+// detecting it on every run
+func (c *Client) UseSystemInfoCache(cache *system.Cache) {
+	c.sysCache = cache
+}
+
+// UsePrompts replaces built-in prompts with templates keyed by prompt name,
+// as returned by config.PromptsConfig.Templates
+func (c *Client) UsePrompts(templates map[string]*template.Template) {
+	c.prompts = templates
+}
+
+// Prompt returns the custom template for a prompt, or nil when the built-in
+// prompt is used
+func (c *Client) Prompt(name string) *template.Template {
+	return c.prompts[name]
+}
+
+// GenerateAnswer asks the model for a plain-language answer to query grounded in
+// the provided context. Unlike GenerateResponse the prompt does not instruct the
+// model to produce commands.
+func (c *Client) GenerateAnswer(query string, contextDocs []string) (string, error) {
+	return c.generate(context.Background(), buildAnswerPrompt(query, contextDocs))
+}
+
+// AnswerPromptSize returns the characters of the prompt GenerateAnswer would
+// send for query with contextDocs
+func AnswerPromptSize(query string, contextDocs []string) int {
+	return len(buildAnswerPrompt(query, contextDocs))
+}
+
+// ListModels returns the names of the models available on the server
+func (c *Client) ListModels() ([]string, error) {
+	req, err := c.newRequest(context.Background(), http.MethodGet, c.endpoint("/api/tags", "/models"), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", httpclient.Classify(err))
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return nil, httpclient.NewStatusError(resp)
+	}
+
+	if c.openAI() {
+		var list ModelsResponse
+		if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+		}
+		models := make([]string, len(list.Data))
+		for i, model := range list.Data {
+			models[i] = model.ID
+		}
+		return models, nil
+	}
+
+	var tags TagsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&tags); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	models := make([]string, len(tags.Models))
+	for i, model := range tags.Models {
+		models[i] = model.Name
+	}
+	return models, nil
+}
+
+// generate sends a fully built prompt to the model and returns its response
+func (c *Client) generate(ctx context.Context, prompt string) (string, error) {
+	resp, err := c.postGenerate(ctx, prompt, false)
+	if err != nil {
+		return "", err
 	}
+	defer resp.Body.Close()
 
 	// Parse response
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return "", fmt.Errorf("failed to read response: %w", err)
 	}
+	if c.openAI() {
+		return parseChatCompletion(body)
+	}
 
 	var genResp GenerateResponse
 	if err := json.Unmarshal(body, &genResp); err != nil {
@@ -92,7 +336,112 @@ func (c *Client) GenerateResponse(query string, context []string) (string, error
 	return genResp.Response, nil
 }
 
-func (c *Client) buildPrompt(query string, context []string) string {
+// generateStream sends a fully built prompt to the model with streaming on.
+// Ollama answers with one JSON object per line, each carrying the next piece
+// of the response, and a last one with "done": true that usually carries no
+// text.
+func (c *Client) generateStream(ctx context.Context, prompt string, onToken func(token string)) (string, error) {
+	resp, err := c.postGenerate(ctx, prompt, true)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if c.openAI() {
+		response, err := readChatCompletionStream(resp.Body, onToken)
+		if err != nil && ctx.Err() != nil {
+			return response, ctx.Err()
+		}
+		return response, err
+	}
+
+	var response strings.Builder
+	// The decoder buffers across reads, so a line split between two reads
+	// is decoded once it is complete
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var chunk GenerateResponse
+		if err := decoder.Decode(&chunk); err != nil {
+			if ctx.Err() != nil {
+				return response.String(), ctx.Err()
+			}
+			if err == io.EOF {
+				return response.String(), fmt.Errorf("failed to read response: stream ended before the model was done")
+			}
+			return response.String(), fmt.Errorf("failed to read response: %w", httpclient.Classify(err))
+		}
+		if chunk.Error != "" {
+			return response.String(), fmt.Errorf("model failed while responding: %s", chunk.Error)
+		}
+		if chunk.Response != "" {
+			response.WriteString(chunk.Response)
+			if onToken != nil {
+				onToken(chunk.Response)
+			}
+		}
+		if chunk.Done {
+			return response.String(), nil
+		}
+	}
+}
+
+// postGenerate sends prompt to /api/generate, or /v1/chat/completions on an
+// OpenAI-compatible server, and returns the response once its status is
+// known to be OK. The caller closes the body.
+func (c *Client) postGenerate(ctx context.Context, prompt string, stream bool) (*http.Response, error) {
+	// Prepare request
+	var req interface{} = GenerateRequest{
+		Model:  c.model,
+		Prompt: prompt,
+		Stream: stream,
+	}
+	if c.openAI() {
+		req = c.chatCompletionRequest(prompt, stream)
+	}
+
+	reqBody, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	// Make HTTP request, retrying when the server is down or failing
+	var resp *http.Response
+	err = c.retry.Do(ctx, func() error {
+		resp, err = c.sendGenerate(ctx, reqBody, len(prompt), stream)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// sendGenerate makes one attempt at a generate request
+func (c *Client) sendGenerate(ctx context.Context, reqBody []byte, promptChars int, stream bool) (*http.Response, error) {
+	httpReq, err := c.newRequest(ctx, http.MethodPost, c.endpoint("/api/generate", "/chat/completions"), bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %w", httpclient.Classify(err))
+	}
+	slog.Debug("generated response", "component", "llm", "model", c.model, "status", resp.StatusCode, "prompt_chars", promptChars, "stream", stream, "duration", time.Since(start))
+
+	if resp.StatusCode == http.StatusNotFound {
+		defer resp.Body.Close()
+		return nil, fmt.Errorf("%w: %s (%w)", ErrModelNotFound, c.model, httpclient.NewStatusError(resp))
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, httpclient.NewStatusError(resp)
+	}
+	return resp, nil
+}
+
+func (c *Client) buildPrompt(query string, context []string, history []Turn) string {
 	var prompt strings.Builder
 	
 	// Get system information
@@ -150,8 +499,41 @@ func (c *Client) buildPrompt(query string, context []string) string {
 		prompt.WriteString("Assistant: stat -c %s filename\n\n")
 	}
 	
+	// Earlier turns, so follow-ups like "do the same for ..." can be resolved
+	if len(history) > 0 {
+		prompt.WriteString("Conversation so far (the new request may refer to it):\n")
+		prompt.WriteString(FormatTurns(history))
+		prompt.WriteString("\n")
+	}
+	
 	prompt.WriteString("User request: ")
 	prompt.WriteString(query)
 	
 	return prompt.String()
 }
+
+// buildAnswerPrompt builds the question-answering prompt used by GenerateAnswer
+func buildAnswerPrompt(query string, context []string) string {
+	var prompt strings.Builder
+	
+	prompt.WriteString("You are a helpful assistant answering questions about the user's indexed documents. ")
+	prompt.WriteString("Answer in plain language using the context information below. ")
+	prompt.WriteString("Do not suggest shell commands for the user to run unless they explicitly ask how to do something on the command line. ")
+	prompt.WriteString("If the context does not contain the answer, say that you could not find it in the indexed documents.\n\n")
+	
+	if len(context) > 0 {
+		prompt.WriteString("Context information:\n")
+		for i, ctx := range context {
+			prompt.WriteString(fmt.Sprintf("%d. %s\n", i+1, ctx))
+		}
+		prompt.WriteString("\n")
+	} else {
+		prompt.WriteString("No context information was found for this question.\n\n")
+	}
+	
+	prompt.WriteString("Question: ")
+	prompt.WriteString(query)
+	prompt.WriteString("\nAnswer: ")
+	
+	return prompt.String()
+}
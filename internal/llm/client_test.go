@@ -0,0 +1,613 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+
+	"rag-cli/internal/fakeserver"
+	"rag-cli/internal/golden"
+	"rag-cli/internal/httpclient"
+	"rag-cli/internal/system"
+	"rag-cli/pkg/config"
+)
+
+func TestBuildAnswerPrompt(t *testing.T) {
+	t.Run("includes context and question", func(t *testing.T) {
+		prompt := buildAnswerPrompt("what port?", []string{"The API listens on 8080.", "Staging uses 9090."})
+
+		expected := []string{
+			"Context information:",
+			"1. The API listens on 8080.",
+			"2. Staging uses 9090.",
+			"Question: what port?",
+		}
+		for _, want := range expected {
+			if !strings.Contains(prompt, want) {
+				t.Errorf("Expected prompt to contain %q, got:\n%s", want, prompt)
+			}
+		}
+	})
+
+	t.Run("does not use command generation instructions", func(t *testing.T) {
+		prompt := buildAnswerPrompt("what port?", nil)
+
+		if strings.Contains(prompt, "respond with ONLY the shell command") {
+			t.Errorf("Expected answer prompt not to ask for shell commands, got:\n%s", prompt)
+		}
+		if !strings.Contains(prompt, "No context information was found") {
+			t.Errorf("Expected prompt to state that no context was found, got:\n%s", prompt)
+		}
+	})
+}
+
+func TestGenerateResponseContext_Deadline(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client, err := NewClient(config.LLMConfig{BaseURL: server.URL, Model: "slow"}, config.TimeoutsConfig{})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err = client.GenerateResponseContext(ctx, "list files", nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected a deadline exceeded error, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected the request to be cancelled at the deadline, took %s", elapsed)
+	}
+}
+
+func TestNewClient_Timeouts(t *testing.T) {
+	client, err := NewClient(config.LLMConfig{Host: "localhost", Port: 11434}, config.TimeoutsConfig{LLM: 90 * time.Second, TLSHandshake: 7 * time.Second})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if client.client.Timeout != 90*time.Second {
+		t.Errorf("Expected request timeout 90s, got %s", client.client.Timeout)
+	}
+	if transport := client.client.Transport.(*http.Transport); transport.TLSHandshakeTimeout != 7*time.Second {
+		t.Errorf("Expected TLS handshake timeout 7s, got %s", transport.TLSHandshakeTimeout)
+	}
+}
+
+func TestGenerateResponse_Prompts(t *testing.T) {
+	var received GenerateRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&received)
+		json.NewEncoder(w).Encode(GenerateResponse{Response: "ls", Done: true})
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		prompts map[string]*template.Template
+		check   func(t *testing.T, prompt string)
+	}{
+		{
+			name: "built-in",
+			check: func(t *testing.T, prompt string) {
+				if !strings.Contains(prompt, "You are a command-line assistant") || !strings.HasSuffix(prompt, "User request: list files") {
+					t.Errorf("Expected the built-in prompt, got:\n%s", prompt)
+				}
+			},
+		},
+		{
+			name: "custom template",
+			prompts: map[string]*template.Template{
+				config.PromptCommandGeneration: template.Must(template.New(config.PromptCommandGeneration).Parse("{{range .Context}}[{{.}}]{{end}} Do: {{.Request}}")),
+			},
+			check: func(t *testing.T, prompt string) {
+				if prompt != "[docs] Do: list files" {
+					t.Errorf("Expected the custom prompt, got:\n%s", prompt)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewClient(config.LLMConfig{BaseURL: server.URL, Model: "test"}, config.TimeoutsConfig{})
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			client.UsePrompts(tt.prompts)
+
+			if _, err := client.GenerateResponse("list files", []string{"docs"}); err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			tt.check(t, received.Prompt)
+		})
+	}
+}
+
+func TestGenerateResponseWithHistory(t *testing.T) {
+	server := fakeserver.NewOllama(t)
+	server.SetResponse("ls internal")
+	client := newFakeClient(t, server, time.Second)
+	history := []Turn{
+		{Role: RoleSummary, Content: "The user is exploring a Go repository."},
+		{Role: RoleUser, Content: "list the files in cmd"},
+		{Role: RoleAssistant, Content: "ls cmd"},
+	}
+
+	if _, err := client.GenerateResponseWithHistory(context.Background(), "now the same for internal", nil, history); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	expected := "Conversation so far (the new request may refer to it):\n" +
+		"Summary of earlier conversation: The user is exploring a Go repository.\n" +
+		"User: list the files in cmd\n" +
+		"Assistant: ls cmd\n\n" +
+		"User request: now the same for internal"
+	if prompt := client.LastPrompt(); !strings.HasSuffix(prompt, expected) {
+		t.Errorf("Expected the earlier turns before the request, got:\n%s", prompt)
+	}
+
+	client.UsePrompts(map[string]*template.Template{
+		config.PromptCommandGeneration: template.Must(template.New(config.PromptCommandGeneration).Parse("{{.Conversation}}Do: {{.Request}}")),
+	})
+	if _, err := client.GenerateResponseWithHistory(context.Background(), "now internal", nil, history[1:]); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if prompt := client.LastPrompt(); prompt != "User: list the files in cmd\nAssistant: ls cmd\nDo: now internal" {
+		t.Errorf("Expected the turns in the custom prompt, got:\n%s", prompt)
+	}
+}
+
+func TestSummarizeConversation(t *testing.T) {
+	server := fakeserver.NewOllama(t)
+	server.SetResponse("  The user listed the files in cmd.\n")
+	client := newFakeClient(t, server, time.Second)
+
+	summary, err := client.SummarizeConversation(context.Background(), "Working in a Go repository.", []Turn{
+		{Role: RoleUser, Content: "list the files in cmd"},
+		{Role: RoleAssistant, Content: "ls cmd"},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if summary != "The user listed the files in cmd." {
+		t.Errorf("Expected the trimmed summary, got %q", summary)
+	}
+
+	req, _ := server.LastRequest("/api/generate")
+	var body GenerateRequest
+	if err := req.Decode(&body); err != nil {
+		t.Fatalf("Expected a JSON request body, got: %v", err)
+	}
+	if !strings.Contains(body.Prompt, "Summary of earlier conversation: Working in a Go repository.\nUser: list the files in cmd\nAssistant: ls cmd\n") {
+		t.Errorf("Expected the earlier summary and the turns in the prompt, got:\n%s", body.Prompt)
+	}
+	if client.LastPrompt() != "" {
+		t.Errorf("Expected summarizing not to replace the last prompt, got:\n%s", client.LastPrompt())
+	}
+}
+
+func TestRefreshSystemInfo_BypassesCache(t *testing.T) {
+	version := "git version 2.43.0"
+	cache := system.NewCache(filepath.Join(t.TempDir(), "system-info.json"), 24*time.Hour)
+	cache.Detect = func() *system.SystemInfo {
+		return &system.SystemInfo{Capabilities: map[string]string{"git": version}}
+	}
+
+	client, err := NewClient(config.LLMConfig{Host: "localhost", Port: 11434}, config.TimeoutsConfig{})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	client.UseSystemInfoCache(cache)
+	client.SystemInfo()
+
+	version = "git version 2.45.1"
+	if got := client.SystemInfo().Capabilities["git"]; got != "git version 2.43.0" {
+		t.Errorf("Expected the cached version before a refresh, got %q", got)
+	}
+	if got := client.RefreshSystemInfo().Capabilities["git"]; got != version {
+		t.Errorf("Expected RefreshSystemInfo to detect %q, got %q", version, got)
+	}
+	if got := client.SystemInfo().Capabilities["git"]; got != version {
+		t.Errorf("Expected the refreshed information to be used, got %q", got)
+	}
+	if got := cache.Info().Capabilities["git"]; got != version {
+		t.Errorf("Expected the cache to be rewritten, got %q", got)
+	}
+}
+
+// newFakeClient returns a client for a fake Ollama server that does not
+// probe the machine it runs on
+func newFakeClient(t *testing.T, server *fakeserver.Ollama, timeout time.Duration) *Client {
+	t.Helper()
+	client, err := NewClient(config.LLMConfig{BaseURL: server.URL, Model: "granite-code:3b"}, config.TimeoutsConfig{LLM: timeout})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	cache := system.NewCache("", time.Hour)
+	cache.Detect = func() *system.SystemInfo {
+		return &system.SystemInfo{OS: "linux", Shell: "bash", Capabilities: map[string]string{}}
+	}
+	client.UseSystemInfoCache(cache)
+	return client
+}
+
+func TestClient_Contract(t *testing.T) {
+	server := fakeserver.NewOllama(t)
+	client := newFakeClient(t, server, 0)
+
+	t.Run("GenerateResponse", func(t *testing.T) {
+		server.SetResponse("ls -la")
+		response, err := client.GenerateResponse("list files", []string{"docs"})
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if response != "ls -la" {
+			t.Errorf("Expected response 'ls -la', got %q", response)
+		}
+
+		req, _ := server.LastRequest("/api/generate")
+		var body GenerateRequest
+		if err := req.Decode(&body); err != nil {
+			t.Fatalf("Expected a JSON request body, got: %v", err)
+		}
+		if req.Method != http.MethodPost || body.Model != "granite-code:3b" || body.Stream {
+			t.Errorf("Expected a non-streaming POST for granite-code:3b, got %s %+v", req.Method, body)
+		}
+		if !strings.Contains(body.Prompt, "docs") || !strings.HasSuffix(body.Prompt, "User request: list files") {
+			t.Errorf("Expected the prompt to carry the context and request, got:\n%s", body.Prompt)
+		}
+		if client.LastPrompt() != body.Prompt {
+			t.Errorf("Expected LastPrompt to be the prompt sent, got:\n%s", client.LastPrompt())
+		}
+	})
+
+	t.Run("GenerateAnswer", func(t *testing.T) {
+		server.SetResponse("Port 8080.")
+		answer, err := client.GenerateAnswer("what port?", []string{"The API listens on 8080."})
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if answer != "Port 8080." {
+			t.Errorf("Expected answer 'Port 8080.', got %q", answer)
+		}
+
+		req, _ := server.LastRequest("/api/generate")
+		var body GenerateRequest
+		req.Decode(&body)
+		if !strings.Contains(body.Prompt, "Question: what port?") {
+			t.Errorf("Expected the answer prompt, got:\n%s", body.Prompt)
+		}
+		if !strings.HasSuffix(client.LastPrompt(), "User request: list files") {
+			t.Errorf("Expected LastPrompt to keep the command prompt, got:\n%s", client.LastPrompt())
+		}
+	})
+
+	t.Run("ListModels", func(t *testing.T) {
+		server.SetModels("granite-code:3b", "llama3.2:latest")
+		models, err := client.ListModels()
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if strings.Join(models, ",") != "granite-code:3b,llama3.2:latest" {
+			t.Errorf("Expected both models, got %v", models)
+		}
+		if req, _ := server.LastRequest("/api/tags"); req.Method != http.MethodGet {
+			t.Errorf("Expected a GET request, got %s", req.Method)
+		}
+	})
+
+	t.Run("Prompt", func(t *testing.T) {
+		custom := template.Must(template.New(config.PromptFinalAnswer).Parse("{{.Request}}"))
+		client.UsePrompts(map[string]*template.Template{config.PromptFinalAnswer: custom})
+		defer client.UsePrompts(nil)
+
+		if client.Prompt(config.PromptFinalAnswer) != custom {
+			t.Error("Expected the custom final answer template")
+		}
+		if client.Prompt(config.PromptCommandGeneration) != nil {
+			t.Error("Expected the built-in command generation prompt")
+		}
+	})
+}
+
+func TestClient_Errors(t *testing.T) {
+	calls := []struct {
+		name string
+		path string
+		call func(c *Client) error
+	}{
+		{name: "GenerateResponse", path: "/api/generate", call: func(c *Client) error {
+			_, err := c.GenerateResponse("list files", nil)
+			return err
+		}},
+		{name: "GenerateAnswer", path: "/api/generate", call: func(c *Client) error {
+			_, err := c.GenerateAnswer("what port?", nil)
+			return err
+		}},
+		{name: "ListModels", path: "/api/tags", call: func(c *Client) error {
+			_, err := c.ListModels()
+			return err
+		}},
+	}
+	failures := []struct {
+		name     string
+		failure  fakeserver.Failure
+		expected string
+	}{
+		{name: "server error", failure: fakeserver.Failure{Status: http.StatusInternalServerError, Body: `{"error":"model crashed"}`}, expected: "unexpected status code: 500"},
+		{name: "model not found", failure: fakeserver.Failure{Status: http.StatusNotFound, Body: `{"error":"model not found"}`}, expected: "unexpected status code: 404"},
+		{name: "malformed JSON", failure: fakeserver.Failure{Body: `{"response": `}, expected: "failed to unmarshal response"},
+		{name: "timeout", failure: fakeserver.Failure{Delay: 5 * time.Second}, expected: "failed to make request"},
+	}
+
+	for _, call := range calls {
+		for _, tt := range failures {
+			t.Run(call.name+"/"+tt.name, func(t *testing.T) {
+				server := fakeserver.NewOllama(t)
+				server.Fail(call.path, tt.failure)
+				client := newFakeClient(t, server, 100*time.Millisecond)
+
+				err := call.call(client)
+				if err == nil || !strings.Contains(err.Error(), tt.expected) {
+					t.Errorf("Expected an error containing %q, got: %v", tt.expected, err)
+				}
+			})
+		}
+	}
+}
+
+func TestGenerateResponse_Retry(t *testing.T) {
+	server := fakeserver.NewOllama(t)
+	server.SetResponse("ls -la")
+	client := newFakeClient(t, server, time.Second)
+	client.retry = httpclient.Retry{MaxRetries: 2, Backoff: time.Millisecond}
+
+	server.Fail("/api/generate", fakeserver.Failure{Status: http.StatusServiceUnavailable, Times: 2})
+	response, err := client.GenerateResponse("list files", nil)
+	if err != nil {
+		t.Fatalf("Expected the third attempt to succeed, got: %v", err)
+	}
+	if response != "ls -la" || len(server.Requests("/api/generate")) != 3 {
+		t.Errorf("Expected 'ls -la' after 3 requests, got %q after %d", response, len(server.Requests("/api/generate")))
+	}
+
+	server.Fail("/api/generate", fakeserver.Failure{Status: http.StatusBadGateway})
+	_, err = client.GenerateResponse("list files", nil)
+	if err == nil || !strings.Contains(err.Error(), "giving up after 3 attempts") || !strings.Contains(err.Error(), "unexpected status code: 502") {
+		t.Errorf("Expected the attempts and the last status in the error, got: %v", err)
+	}
+
+	server.Fail("/api/generate", fakeserver.Failure{Status: http.StatusNotFound})
+	before := len(server.Requests("/api/generate"))
+	if _, err := client.GenerateResponse("list files", nil); !errors.Is(err, ErrModelNotFound) {
+		t.Errorf("Expected ErrModelNotFound, got: %v", err)
+	}
+	if sent := len(server.Requests("/api/generate")) - before; sent != 1 {
+		t.Errorf("Expected a missing model not to be retried, got %d requests", sent)
+	}
+}
+
+func TestGenerateResponse_TypedErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		failure   fakeserver.Failure
+		is        error
+		status    int // Expected *httpclient.StatusError code, or 0 for none
+		temporary bool
+	}{
+		{name: "model not found", failure: fakeserver.Failure{Status: http.StatusNotFound, Body: `{"error":"model 'slow' not found"}`}, is: ErrModelNotFound, status: http.StatusNotFound},
+		{name: "server error", failure: fakeserver.Failure{Status: http.StatusServiceUnavailable}, status: http.StatusServiceUnavailable, temporary: true},
+		{name: "timeout", failure: fakeserver.Failure{Delay: 5 * time.Second}, is: httpclient.ErrTimeout},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := fakeserver.NewOllama(t)
+			server.Fail("/api/generate", tt.failure)
+			client := newFakeClient(t, server, 100*time.Millisecond)
+
+			_, err := client.GenerateResponse("list files", nil)
+			if tt.is != nil && !errors.Is(err, tt.is) {
+				t.Errorf("Expected an error matching %v, got: %v", tt.is, err)
+			}
+			var statusErr *httpclient.StatusError
+			switch {
+			case tt.status == 0 && errors.As(err, &statusErr):
+				t.Errorf("Expected no status error, got: %v", statusErr)
+			case tt.status != 0 && !errors.As(err, &statusErr):
+				t.Errorf("Expected a status error, got: %v", err)
+			case tt.status != 0 && (statusErr.StatusCode != tt.status || statusErr.Temporary() != tt.temporary):
+				t.Errorf("Expected status %d (temporary %v), got %d (temporary %v)", tt.status, tt.temporary, statusErr.StatusCode, statusErr.Temporary())
+			}
+		})
+	}
+}
+
+func TestBuildPrompt_Golden(t *testing.T) {
+	tests := []struct {
+		name    string
+		info    *system.SystemInfo
+		context []string
+	}{
+		{
+			name: "bsd",
+			info: &system.SystemInfo{
+				OS: "darwin", Architecture: "arm64", Shell: "zsh", HasBSD: true,
+				Capabilities:    map[string]string{"stat": "BSD", "du": "BSD", "find": "BSD", "ls": "BSD", "git": "git version 2.39.3", "curl": "curl 8.4.0", "make": "available"},
+				PackageManagers: []string{"brew"},
+				Resources:       &system.Resources{CPUs: 10, MemoryBytes: 16 << 30, DiskFreeBytes: 200 << 30, DiskPath: "/Users/dev"},
+			},
+			context: []string{"The deploy script lives in scripts/deploy.sh.", "Releases are tagged vX.Y.Z."},
+		},
+		{
+			name: "gnu",
+			info: &system.SystemInfo{
+				OS: "linux", Architecture: "amd64", Shell: "bash", HasGNU: true,
+				Capabilities:    map[string]string{"stat": "GNU", "du": "GNU", "find": "GNU", "ls": "GNU", "git": "git version 2.43.0", "docker": "Docker version 24.0.7", "python3": "Python 3.12.3"},
+				PackageManagers: []string{"apt"},
+				InContainer:     true,
+				Container:       "docker",
+			},
+		},
+		{
+			name: "powershell",
+			info: &system.SystemInfo{
+				OS: "windows", Architecture: "amd64", Shell: system.ShellPowerShell, HasWSL: true,
+				Capabilities:    map[string]string{"git": "git version 2.44.0.windows.1"},
+				PackageManagers: []string{"winget"},
+				InCI:            true,
+				CI:              "GitHub Actions",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewClient(config.LLMConfig{Host: "localhost", Port: 11434}, config.TimeoutsConfig{})
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			cache := system.NewCache("", time.Hour)
+			cache.Detect = func() *system.SystemInfo { return tt.info }
+			client.UseSystemInfoCache(cache)
+
+			golden.Assert(t, filepath.Join("testdata", "command_generation_"+tt.name+".golden"), client.buildPrompt("find the largest files here", tt.context, nil))
+		})
+	}
+}
+
+func TestBuildAnswerPrompt_Golden(t *testing.T) {
+	tests := []struct {
+		name    string
+		context []string
+	}{
+		{name: "with_context", context: []string{"The API listens on 8080.", "Staging uses 9090."}},
+		{name: "without_context"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			golden.Assert(t, filepath.Join("testdata", "answer_"+tt.name+".golden"), buildAnswerPrompt("what port does the API use?", tt.context))
+		})
+	}
+}
+
+func TestGenerateResponseStream(t *testing.T) {
+	t.Run("tokens from the fake server", func(t *testing.T) {
+		server := fakeserver.NewOllama(t)
+		server.SetResponse("find . -name '*.go'")
+		client := newFakeClient(t, server, 0)
+
+		var tokens []string
+		response, err := client.GenerateResponseStream(context.Background(), "find go files", nil, func(token string) {
+			tokens = append(tokens, token)
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		if response != "find . -name '*.go'" {
+			t.Errorf("Expected the whole response, got %q", response)
+		}
+		if len(tokens) != 4 || strings.Join(tokens, "") != response {
+			t.Errorf("Expected the response in 4 tokens, got %q", tokens)
+		}
+
+		req, _ := server.LastRequest("/api/generate")
+		var body GenerateRequest
+		req.Decode(&body)
+		if !body.Stream {
+			t.Error("Expected a streaming request")
+		}
+		if client.LastPrompt() != body.Prompt {
+			t.Errorf("Expected LastPrompt to be the prompt sent, got:\n%s", client.LastPrompt())
+		}
+	})
+
+	t.Run("lines split across reads", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			stream := `{"response":"ls ","done":false}` + "\n" + `{"response":"-la","done":false}` + "\n" + `{"response":"","done":true}` + "\n"
+			for i := 0; i < len(stream); i += 7 {
+				io.WriteString(w, stream[i:min(i+7, len(stream))])
+				w.(http.Flusher).Flush()
+			}
+		}))
+		defer server.Close()
+		client, _ := NewClient(config.LLMConfig{BaseURL: server.URL, Model: "granite-code:3b"}, config.TimeoutsConfig{})
+		client.UseSystemInfoCache(system.NewCache("", time.Hour))
+
+		var tokens []string
+		response, err := client.GenerateResponseStream(context.Background(), "list files", nil, func(token string) {
+			tokens = append(tokens, token)
+		})
+		if err != nil || response != "ls -la" {
+			t.Fatalf("Expected \"ls -la\", got %q, %v", response, err)
+		}
+		if len(tokens) != 2 {
+			t.Errorf("Expected the empty done chunk not to be passed on, got %q", tokens)
+		}
+	})
+
+	t.Run("cancelled mid-stream", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, `{"response":"ls ","done":false}`+"\n")
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+		}))
+		defer server.Close()
+		client, _ := NewClient(config.LLMConfig{BaseURL: server.URL, Model: "granite-code:3b"}, config.TimeoutsConfig{})
+		client.UseSystemInfoCache(system.NewCache("", time.Hour))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		response, err := client.GenerateResponseStream(ctx, "list files", nil, func(token string) {
+			cancel() // Ctrl+C after the first token
+		})
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("Expected a cancellation error, got: %v", err)
+		}
+		if response != "ls " {
+			t.Errorf("Expected the text received before the cancel, got %q", response)
+		}
+	})
+
+	t.Run("error chunk", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, `{"response":"ls","done":false}`+"\n"+`{"error":"out of memory"}`+"\n")
+		}))
+		defer server.Close()
+		client, _ := NewClient(config.LLMConfig{BaseURL: server.URL, Model: "granite-code:3b"}, config.TimeoutsConfig{})
+		client.UseSystemInfoCache(system.NewCache("", time.Hour))
+
+		_, err := client.GenerateResponseStream(context.Background(), "list files", nil, nil)
+		if err == nil || !strings.Contains(err.Error(), "out of memory") {
+			t.Errorf("Expected the model's error, got: %v", err)
+		}
+	})
+
+	t.Run("missing model", func(t *testing.T) {
+		server := fakeserver.NewOllama(t)
+		server.Fail("/api/generate", fakeserver.Failure{Status: http.StatusNotFound, Body: `{"error":"model not found"}`})
+		client := newFakeClient(t, server, 0)
+
+		if _, err := client.GenerateResponseStream(context.Background(), "list files", nil, nil); !errors.Is(err, ErrModelNotFound) {
+			t.Errorf("Expected ErrModelNotFound, got: %v", err)
+		}
+	})
+}
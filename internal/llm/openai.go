@@ -0,0 +1,116 @@
+package llm
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"rag-cli/internal/httpclient"
+)
+
+// openAISystemMessage is sent ahead of each prompt to an OpenAI-compatible
+// server, which expects instructions in a system message. The prompt itself,
+// with its own instructions, is sent as the user message.
+const openAISystemMessage = "You are rag-cli, a command-line assistant. Follow the instructions in the user's message exactly."
+
+// ChatMessage is one message of an OpenAI-compatible chat completion
+type ChatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// ChatCompletionRequest is the body of an OpenAI-compatible
+// /v1/chat/completions request
+type ChatCompletionRequest struct {
+	Model    string        `json:"model"`
+	Messages []ChatMessage `json:"messages"`
+	Stream   bool          `json:"stream"`
+}
+
+// ChatCompletionResponse is a chat completion, or one chunk of a streamed
+// one, which carries its text in Delta rather than Message
+type ChatCompletionResponse struct {
+	Choices []struct {
+		Message      ChatMessage `json:"message"`
+		Delta        ChatMessage `json:"delta"`
+		FinishReason string      `json:"finish_reason"`
+	} `json:"choices"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"` // Set when a stream fails part way
+}
+
+// ModelsResponse lists the models of an OpenAI-compatible server
+type ModelsResponse struct {
+	Data []struct {
+		ID string `json:"id"`
+	} `json:"data"`
+}
+
+// chatCompletionRequest maps prompt to a system and user message pair
+func (c *Client) chatCompletionRequest(prompt string, stream bool) ChatCompletionRequest {
+	return ChatCompletionRequest{
+		Model: c.model,
+		Messages: []ChatMessage{
+			{Role: "system", Content: openAISystemMessage},
+			{Role: "user", Content: prompt},
+		},
+		Stream: stream,
+	}
+}
+
+// parseChatCompletion returns the text of a chat completion
+func parseChatCompletion(body []byte) (string, error) {
+	var completion ChatCompletionResponse
+	if err := json.Unmarshal(body, &completion); err != nil {
+		return "", fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if completion.Error != nil {
+		return "", fmt.Errorf("model failed while responding: %s", completion.Error.Message)
+	}
+	if len(completion.Choices) == 0 {
+		return "", fmt.Errorf("failed to read response: no choices returned")
+	}
+	return completion.Choices[0].Message.Content, nil
+}
+
+// readChatCompletionStream reads a streamed chat completion, which arrives
+// as server-sent events: "data: " lines carrying one chunk each, ending with
+// "data: [DONE]"
+func readChatCompletionStream(body io.Reader, onToken func(token string)) (string, error) {
+	var response strings.Builder
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(strings.TrimSpace(scanner.Text()), "data:")
+		if !ok {
+			continue // Blank lines between events, comments and other fields
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			return response.String(), nil
+		}
+
+		var chunk ChatCompletionResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return response.String(), fmt.Errorf("failed to unmarshal response: %w", err)
+		}
+		if chunk.Error != nil {
+			return response.String(), fmt.Errorf("model failed while responding: %s", chunk.Error.Message)
+		}
+		for _, choice := range chunk.Choices {
+			if choice.Delta.Content != "" {
+				response.WriteString(choice.Delta.Content)
+				if onToken != nil {
+					onToken(choice.Delta.Content)
+				}
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return response.String(), fmt.Errorf("failed to read response: %w", httpclient.Classify(err))
+	}
+	return response.String(), fmt.Errorf("failed to read response: stream ended before the model was done")
+}
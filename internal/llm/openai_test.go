@@ -0,0 +1,129 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"rag-cli/internal/httpclient"
+	"rag-cli/internal/system"
+	"rag-cli/pkg/config"
+)
+
+// newOpenAIServer serves the OpenAI-compatible chat completion and model
+// endpoints under prefix, recording the last chat completion request
+func newOpenAIServer(t *testing.T, prefix string, last *ChatCompletionRequest) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc(prefix+"/v1/chat/completions", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, `{"error":{"message":"invalid api key"}}`, http.StatusUnauthorized)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(last); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+		}
+		if !last.Stream {
+			fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"ls -la"},"finish_reason":"stop"}]}`)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, token := range []string{"ls", " -la"} {
+			fmt.Fprintf(w, "data: {\"choices\":[{\"delta\":{\"content\":%q}}]}\n\n", token)
+		}
+		fmt.Fprint(w, ": keep-alive\n\ndata: [DONE]\n\n")
+	})
+	mux.HandleFunc(prefix+"/v1/models", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"object":"list","data":[{"id":"qwen2.5-coder"},{"id":"llama-3.1-8b"}]}`)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newOpenAIClient(t *testing.T, baseURL, apiKey string) *Client {
+	client, err := NewClient(config.LLMConfig{Provider: config.ProviderOpenAI, BaseURL: baseURL, Model: "qwen2.5-coder", APIKey: apiKey}, config.TimeoutsConfig{})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	client.systemInfo = &system.SystemInfo{OS: "linux", Capabilities: map[string]string{}}
+	return client
+}
+
+func TestClient_OpenAI(t *testing.T) {
+	var last ChatCompletionRequest
+	server := newOpenAIServer(t, "", &last)
+	client := newOpenAIClient(t, server.URL, "secret")
+
+	t.Run("generate", func(t *testing.T) {
+		response, err := client.GenerateResponse("list files", nil)
+		if err != nil || response != "ls -la" {
+			t.Fatalf("Expected %q, got %q and %v", "ls -la", response, err)
+		}
+		if last.Model != "qwen2.5-coder" || last.Stream || len(last.Messages) != 2 {
+			t.Fatalf("Unexpected request: %+v", last)
+		}
+		if last.Messages[0].Role != "system" || last.Messages[1].Role != "user" {
+			t.Errorf("Expected a system and a user message, got %+v", last.Messages)
+		}
+		if last.Messages[1].Content != client.LastPrompt() || !strings.HasSuffix(last.Messages[1].Content, "User request: list files") {
+			t.Errorf("Expected the prompt as the user message, got %q", last.Messages[1].Content)
+		}
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		var tokens []string
+		response, err := client.GenerateResponseStream(context.Background(), "list files", nil, func(token string) {
+			tokens = append(tokens, token)
+		})
+		if err != nil || response != "ls -la" {
+			t.Fatalf("Expected %q, got %q and %v", "ls -la", response, err)
+		}
+		if !last.Stream || len(tokens) != 2 {
+			t.Errorf("Expected a streamed request and two tokens, got stream=%v and %q", last.Stream, tokens)
+		}
+	})
+
+	t.Run("list models", func(t *testing.T) {
+		models, err := client.ListModels()
+		if err != nil || strings.Join(models, ",") != "qwen2.5-coder,llama-3.1-8b" {
+			t.Errorf("Expected the server's models, got %v and %v", models, err)
+		}
+	})
+
+	t.Run("base URL ending in /v1", func(t *testing.T) {
+		server := newOpenAIServer(t, "/api", &last)
+		client := newOpenAIClient(t, server.URL+"/api/v1", "secret")
+		if response, err := client.GenerateAnswer("what is this?", nil); err != nil || response != "ls -la" {
+			t.Errorf("Expected %q, got %q and %v", "ls -la", response, err)
+		}
+	})
+
+	t.Run("errors include the status and body", func(t *testing.T) {
+		client := newOpenAIClient(t, server.URL, "wrong")
+		_, err := client.GenerateResponse("list files", nil)
+		var statusErr *httpclient.StatusError
+		if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("Expected a 401 status error, got: %v", err)
+		}
+		if !strings.Contains(err.Error(), "invalid api key") {
+			t.Errorf("Expected the body in the error, got: %v", err)
+		}
+	})
+}
+
+func TestReadChatCompletionStream_Errors(t *testing.T) {
+	response, err := readChatCompletionStream(strings.NewReader("data: {\"choices\":[{\"delta\":{\"content\":\"ls\"}}]}\n\n"), nil)
+	if err == nil || response != "ls" {
+		t.Errorf("Expected the partial response and an error for a stream without [DONE], got %q and %v", response, err)
+	}
+
+	_, err = readChatCompletionStream(strings.NewReader("data: {\"error\":{\"message\":\"overloaded\"}}\n\n"), nil)
+	if err == nil || !strings.Contains(err.Error(), "overloaded") {
+		t.Errorf("Expected the stream's error, got: %v", err)
+	}
+}
@@ -0,0 +1,36 @@
+package llm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Roles of the turns in a conversation
+const (
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+	RoleSummary   = "summary" // A summary of turns that were dropped
+)
+
+// Turn is one message of an earlier exchange with the model
+type Turn struct {
+	Role    string
+	Content string
+}
+
+// FormatTurns renders turns for a prompt, one "User:" or "Assistant:" entry
+// each, oldest first
+func FormatTurns(turns []Turn) string {
+	var text strings.Builder
+	for _, turn := range turns {
+		label := "User"
+		switch turn.Role {
+		case RoleAssistant:
+			label = "Assistant"
+		case RoleSummary:
+			label = "Summary of earlier conversation"
+		}
+		fmt.Fprintf(&text, "%s: %s\n", label, strings.TrimSpace(turn.Content))
+	}
+	return text.String()
+}
@@ -0,0 +1,77 @@
+// Package logging sets up the log/slog default logger from the log settings.
+// Diagnostics such as failed background writes are logged through slog, while
+// messages meant for the user stay with the command or chat UI.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"rag-cli/pkg/config"
+)
+
+var (
+	mu      sync.Mutex
+	logFile *os.File // open while log.to_file is set
+)
+
+// ParseLevel converts a log.level value to a slog level
+func ParseLevel(name string) (slog.Level, error) {
+	switch name {
+	case "error":
+		return slog.LevelError, nil
+	case "warn":
+		return slog.LevelWarn, nil
+	case "info":
+		return slog.LevelInfo, nil
+	case "debug":
+		return slog.LevelDebug, nil
+	default:
+		return 0, fmt.Errorf("unknown log level %q", name)
+	}
+}
+
+// New returns a logger that writes records at level or above to w as
+// key=value text
+func New(w io.Writer, level slog.Level) *slog.Logger {
+	return slog.New(slog.NewTextHandler(w, &slog.HandlerOptions{Level: level}))
+}
+
+// Configure makes the slog default logger write at cfg.Level to stderr, or to
+// the log file when cfg.ToFile is set. A log file opened by an earlier call is
+// closed.
+func Configure(cfg config.LogConfig, stderr io.Writer) error {
+	level, err := ParseLevel(cfg.Level)
+	if err != nil {
+		return err
+	}
+
+	out := stderr
+	var file *os.File
+	if cfg.ToFile {
+		path, err := cfg.LogPath()
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return fmt.Errorf("failed to create log directory: %w", err)
+		}
+		if file, err = os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644); err != nil {
+			return fmt.Errorf("failed to open log file: %w", err)
+		}
+		out = file
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if logFile != nil {
+		logFile.Close()
+	}
+	logFile = file
+	slog.SetDefault(New(out, level))
+	return nil
+}
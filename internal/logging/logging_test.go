@@ -0,0 +1,79 @@
+package logging
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"rag-cli/pkg/config"
+)
+
+func TestConfigure_Levels(t *testing.T) {
+	defer slog.SetDefault(slog.Default())
+
+	tests := []struct {
+		level    string
+		expected []string
+	}{
+		{level: "error", expected: []string{"level=ERROR"}},
+		{level: "warn", expected: []string{"level=ERROR", "level=WARN"}},
+		{level: "info", expected: []string{"level=ERROR", "level=WARN", "level=INFO"}},
+		{level: "debug", expected: []string{"level=ERROR", "level=WARN", "level=INFO", "level=DEBUG"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.level, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := Configure(config.LogConfig{Level: tt.level}, &out); err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			slog.Error("failed", "component", "test")
+			slog.Warn("degraded", "component", "test")
+			slog.Info("progress", "component", "test")
+			slog.Debug("detail", "component", "test")
+
+			lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+			if len(lines) != len(tt.expected) {
+				t.Fatalf("Expected %d records, got:\n%s", len(tt.expected), out.String())
+			}
+			for i, want := range tt.expected {
+				if !strings.Contains(lines[i], want) || !strings.Contains(lines[i], "component=test") {
+					t.Errorf("Expected record %d to contain %s and component=test, got %q", i, want, lines[i])
+				}
+			}
+		})
+	}
+}
+
+func TestConfigure_File(t *testing.T) {
+	defer slog.SetDefault(slog.Default())
+
+	path := filepath.Join(t.TempDir(), "logs", "rag-cli.log")
+	var stderr bytes.Buffer
+	if err := Configure(config.LogConfig{Level: "warn", ToFile: true, File: path}, &stderr); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	t.Cleanup(func() { Configure(config.LogConfig{Level: "warn"}, &bytes.Buffer{}) })
+
+	slog.Warn("failed to store execution session", "component", "chat")
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Expected the log file to be written, got: %v", err)
+	}
+	if !strings.Contains(string(content), `msg="failed to store execution session" component=chat`) {
+		t.Errorf("Expected the warning in the log file, got %q", content)
+	}
+	if stderr.Len() != 0 {
+		t.Errorf("Expected nothing on stderr, got %q", stderr.String())
+	}
+}
+
+func TestConfigure_UnknownLevel(t *testing.T) {
+	if err := Configure(config.LogConfig{Level: "verbose"}, &bytes.Buffer{}); err == nil {
+		t.Error("Expected an error for an unknown level")
+	}
+}
@@ -0,0 +1,190 @@
+// Package mcp implements a Model Context Protocol server over stdio, which
+// lets editors and agents call rag-cli as a set of tools.
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// ProtocolVersion is the MCP revision this server implements
+const ProtocolVersion = "2024-11-05"
+
+// maxMessageBytes bounds a single newline-delimited JSON-RPC message
+const maxMessageBytes = 10 << 20
+
+// JSON-RPC 2.0 error codes
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+)
+
+// Tool is a tool exposed to MCP clients. Handler receives the raw arguments
+// object of a tools/call request and returns the text shown to the model; a
+// returned error is reported to the model as a failed tool call rather than
+// as a protocol error.
+type Tool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	InputSchema map[string]interface{} `json:"inputSchema"`
+
+	Handler func(arguments json.RawMessage) (string, error) `json:"-"`
+}
+
+// Server answers MCP requests read from a stream
+type Server struct {
+	name    string
+	version string
+	tools   []Tool
+	byName  map[string]Tool
+}
+
+// NewServer creates a server that advertises the given tools
+func NewServer(name, version string, tools []Tool) *Server {
+	byName := make(map[string]Tool, len(tools))
+	for _, tool := range tools {
+		byName[tool.Name] = tool
+	}
+	return &Server{name: name, version: version, tools: tools, byName: byName}
+}
+
+// request is an incoming JSON-RPC request or notification. Notifications
+// have no ID and never get a response.
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// response is an outgoing JSON-RPC response
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+// rpcError is a JSON-RPC error object
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// textContent is a text item in a tool result
+type textContent struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// toolResult is the result of a tools/call request
+type toolResult struct {
+	Content []textContent `json:"content"`
+	IsError bool          `json:"isError,omitempty"`
+}
+
+// Serve reads newline-delimited JSON-RPC messages from in and writes
+// responses to out until in is exhausted
+func (s *Server) Serve(in io.Reader, out io.Writer) error {
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxMessageBytes)
+	encoder := json.NewEncoder(out)
+
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if resp := s.handleMessage(line); resp != nil {
+			if err := encoder.Encode(resp); err != nil {
+				return fmt.Errorf("failed to write response: %w", err)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read request: %w", err)
+	}
+	return nil
+}
+
+// handleMessage processes a single message, returning nil for notifications
+func (s *Server) handleMessage(line []byte) *response {
+	var req request
+	if err := json.Unmarshal(line, &req); err != nil {
+		return errorResponse(nil, CodeParseError, fmt.Sprintf("parse error: %v", err))
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		return errorResponse(req.ID, CodeInvalidRequest, "invalid request: expected a JSON-RPC 2.0 request with a method")
+	}
+	if req.ID == nil {
+		// Notifications such as notifications/initialized need no reply
+		return nil
+	}
+
+	result, rpcErr := s.dispatch(req)
+	if rpcErr != nil {
+		return &response{JSONRPC: "2.0", ID: req.ID, Error: rpcErr}
+	}
+	return &response{JSONRPC: "2.0", ID: req.ID, Result: result}
+}
+
+// dispatch routes a request to its method
+func (s *Server) dispatch(req request) (interface{}, *rpcError) {
+	switch req.Method {
+	case "initialize":
+		return map[string]interface{}{
+			"protocolVersion": ProtocolVersion,
+			"capabilities": map[string]interface{}{
+				"tools": map[string]interface{}{},
+			},
+			"serverInfo": map[string]string{
+				"name":    s.name,
+				"version": s.version,
+			},
+		}, nil
+	case "ping":
+		return map[string]interface{}{}, nil
+	case "tools/list":
+		return map[string]interface{}{"tools": s.tools}, nil
+	case "tools/call":
+		return s.callTool(req.Params)
+	default:
+		return nil, &rpcError{Code: CodeMethodNotFound, Message: fmt.Sprintf("method not found: %s", req.Method)}
+	}
+}
+
+// callTool runs the tool named in a tools/call request
+func (s *Server) callTool(params json.RawMessage) (interface{}, *rpcError) {
+	var call struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	}
+	if err := json.Unmarshal(params, &call); err != nil {
+		return nil, &rpcError{Code: CodeInvalidParams, Message: fmt.Sprintf("invalid params: %v", err)}
+	}
+	tool, ok := s.byName[call.Name]
+	if !ok {
+		return nil, &rpcError{Code: CodeInvalidParams, Message: fmt.Sprintf("unknown tool: %s", call.Name)}
+	}
+	if len(call.Arguments) == 0 {
+		call.Arguments = json.RawMessage("{}")
+	}
+
+	text, err := tool.Handler(call.Arguments)
+	if err != nil {
+		return toolResult{Content: []textContent{{Type: "text", Text: err.Error()}}, IsError: true}, nil
+	}
+	return toolResult{Content: []textContent{{Type: "text", Text: text}}}, nil
+}
+
+func errorResponse(id json.RawMessage, code int, message string) *response {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	return &response{JSONRPC: "2.0", ID: id, Error: &rpcError{Code: code, Message: message}}
+}
@@ -0,0 +1,187 @@
+package mcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// rpcResponse is a decoded response as seen by a client
+type rpcResponse struct {
+	ID     json.RawMessage `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *rpcError       `json:"error"`
+}
+
+func newTestServer() *Server {
+	return NewServer("rag-cli", "v1.2.3", []Tool{
+		{
+			Name:        "echo",
+			Description: "Echo the message argument",
+			InputSchema: map[string]interface{}{"type": "object"},
+			Handler: func(arguments json.RawMessage) (string, error) {
+				var args struct {
+					Message string `json:"message"`
+				}
+				if err := json.Unmarshal(arguments, &args); err != nil {
+					return "", err
+				}
+				if args.Message == "" {
+					return "", errors.New("message is required")
+				}
+				return args.Message, nil
+			},
+		},
+	})
+}
+
+// serveLines runs the server over the given messages and decodes every response
+func serveLines(t *testing.T, server *Server, lines ...string) []rpcResponse {
+	t.Helper()
+	var out bytes.Buffer
+	if err := server.Serve(strings.NewReader(strings.Join(lines, "\n")+"\n"), &out); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	var responses []rpcResponse
+	decoder := json.NewDecoder(&out)
+	for decoder.More() {
+		var resp rpcResponse
+		if err := decoder.Decode(&resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		responses = append(responses, resp)
+	}
+	return responses
+}
+
+func TestServe_Handshake(t *testing.T) {
+	responses := serveLines(t, newTestServer(),
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":{},"clientInfo":{"name":"test","version":"0"}}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`,
+		`{"jsonrpc":"2.0","id":"three","method":"ping"}`,
+	)
+
+	if len(responses) != 3 {
+		t.Fatalf("Expected 3 responses (none for the notification), got %d", len(responses))
+	}
+
+	var initResult struct {
+		ProtocolVersion string `json:"protocolVersion"`
+		ServerInfo      struct {
+			Name    string `json:"name"`
+			Version string `json:"version"`
+		} `json:"serverInfo"`
+		Capabilities map[string]interface{} `json:"capabilities"`
+	}
+	if err := json.Unmarshal(responses[0].Result, &initResult); err != nil {
+		t.Fatalf("Failed to decode initialize result: %v", err)
+	}
+	if initResult.ProtocolVersion != ProtocolVersion || initResult.ServerInfo.Version != "v1.2.3" {
+		t.Errorf("Unexpected initialize result: %+v", initResult)
+	}
+	if _, ok := initResult.Capabilities["tools"]; !ok {
+		t.Errorf("Expected tools capability, got: %v", initResult.Capabilities)
+	}
+
+	var listResult struct {
+		Tools []map[string]interface{} `json:"tools"`
+	}
+	if err := json.Unmarshal(responses[1].Result, &listResult); err != nil {
+		t.Fatalf("Failed to decode tools/list result: %v", err)
+	}
+	if len(listResult.Tools) != 1 || listResult.Tools[0]["name"] != "echo" || listResult.Tools[0]["inputSchema"] == nil {
+		t.Errorf("Expected the echo tool with its schema, got: %v", listResult.Tools)
+	}
+
+	if string(responses[2].ID) != `"three"` {
+		t.Errorf("Expected string IDs to be echoed back, got %s", responses[2].ID)
+	}
+}
+
+func TestServe_ToolCalls(t *testing.T) {
+	tests := []struct {
+		name      string
+		line      string
+		wantCode  int
+		wantText  string
+		wantError bool
+	}{
+		{
+			name:     "successful call",
+			line:     `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"echo","arguments":{"message":"hello"}}}`,
+			wantText: "hello",
+		},
+		{
+			name:      "tool failure is a result, not a protocol error",
+			line:      `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"echo","arguments":{}}}`,
+			wantText:  "message is required",
+			wantError: true,
+		},
+		{
+			name:      "missing arguments default to an empty object",
+			line:      `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"echo"}}`,
+			wantText:  "message is required",
+			wantError: true,
+		},
+		{
+			name:     "unknown tool",
+			line:     `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"nope"}}`,
+			wantCode: CodeInvalidParams,
+		},
+		{
+			name:     "missing params",
+			line:     `{"jsonrpc":"2.0","id":1,"method":"tools/call"}`,
+			wantCode: CodeInvalidParams,
+		},
+		{
+			name:     "unknown method",
+			line:     `{"jsonrpc":"2.0","id":1,"method":"resources/list"}`,
+			wantCode: CodeMethodNotFound,
+		},
+		{
+			name:     "malformed JSON",
+			line:     `{"jsonrpc":"2.0","id":1,`,
+			wantCode: CodeParseError,
+		},
+		{
+			name:     "not JSON-RPC 2.0",
+			line:     `{"id":1,"method":"ping"}`,
+			wantCode: CodeInvalidRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			responses := serveLines(t, newTestServer(), tt.line)
+			if len(responses) != 1 {
+				t.Fatalf("Expected 1 response, got %d", len(responses))
+			}
+			resp := responses[0]
+
+			if tt.wantCode != 0 {
+				if resp.Error == nil || resp.Error.Code != tt.wantCode {
+					t.Errorf("Expected error code %d, got: %+v", tt.wantCode, resp.Error)
+				}
+				return
+			}
+
+			if resp.Error != nil {
+				t.Fatalf("Expected a result, got error: %+v", resp.Error)
+			}
+			var result toolResult
+			if err := json.Unmarshal(resp.Result, &result); err != nil {
+				t.Fatalf("Failed to decode tool result: %v", err)
+			}
+			if result.IsError != tt.wantError {
+				t.Errorf("Expected isError %v, got %v", tt.wantError, result.IsError)
+			}
+			if len(result.Content) != 1 || result.Content[0].Type != "text" || result.Content[0].Text != tt.wantText {
+				t.Errorf("Expected text content %q, got: %+v", tt.wantText, result.Content)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+// Package metrics records opt-in usage events to a local JSONL file and
+// aggregates them for 'rag-cli stats --usage'. Nothing is sent anywhere.
+package metrics
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"rag-cli/pkg/paths"
+)
+
+// Event types
+const (
+	TaskStarted  = "task_started"
+	TaskFinished = "task_finished"
+	CommandRun   = "command"
+	LLMRequest   = "llm_request"
+)
+
+// Event is one usage record. Events are anonymized: prompts, arguments, and
+// output are never stored, and commands are recorded by program name only.
+type Event struct {
+	Time       time.Time `json:"time"`
+	Type       string    `json:"type"`
+	Model      string    `json:"model,omitempty"`
+	Command    string    `json:"command,omitempty"`  // Program name, for CommandRun
+	Attempts   int       `json:"attempts,omitempty"` // Commands run, for TaskFinished
+	Failures   int       `json:"failures,omitempty"` // Commands that failed, for TaskFinished
+	Success    bool      `json:"success"`
+	DurationMS int64     `json:"duration_ms,omitempty"`
+}
+
+// Duration returns the event's duration
+func (e Event) Duration() time.Duration {
+	return time.Duration(e.DurationMS) * time.Millisecond
+}
+
+// Recorder appends events to a JSONL file. A nil *Recorder records nothing,
+// so callers need not check whether metrics are enabled.
+type Recorder struct {
+	path  string
+	now   func() time.Time
+	mutex sync.Mutex
+}
+
+// NewRecorder creates a recorder that appends to path
+func NewRecorder(path string) *Recorder {
+	return &Recorder{path: path, now: time.Now}
+}
+
+// DefaultPath returns the location of the usage file in the state directory
+func DefaultPath() (string, error) {
+	dirs, err := paths.Default()
+	if err != nil {
+		return "", err
+	}
+	return dirs.StateFile("usage.jsonl"), nil
+}
+
+// Record appends event, stamping it with the current time when it has none.
+// Failures are logged rather than returned, so metrics never interrupt a task.
+func (r *Recorder) Record(event Event) {
+	if r == nil {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = r.now()
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if err := r.append(append(data, '\n')); err != nil {
+		slog.Warn("failed to record usage event", "component", "metrics", "path", r.path, "error", err)
+	}
+}
+
+func (r *Recorder) append(line []byte) error {
+	if err := os.MkdirAll(filepath.Dir(r.path), 0755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(r.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(line); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// CommandName returns the program a shell command runs, without its path or
+// arguments, so recorded commands do not reveal file names or data
+func CommandName(command string) string {
+	fields := strings.Fields(command)
+	for _, field := range fields {
+		if strings.Contains(field, "=") && !strings.HasPrefix(field, "=") {
+			continue // Leading VAR=value assignments
+		}
+		return filepath.Base(field)
+	}
+	return ""
+}
+
+// Load reads the events in path. A missing file has no events; lines that
+// cannot be parsed are skipped.
+func Load(path string) ([]Event, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open usage file: %w", err)
+	}
+	defer file.Close()
+
+	var events []Event
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var event Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil || event.Type == "" {
+			continue
+		}
+		events = append(events, event)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read usage file: %w", err)
+	}
+	return events, nil
+}
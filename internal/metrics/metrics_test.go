@@ -0,0 +1,137 @@
+package metrics
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRecorder_RecordAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "usage.jsonl")
+	recorder := NewRecorder(path)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			recorder.Record(Event{Type: CommandRun, Command: "ls", Success: true})
+		}()
+	}
+	wg.Wait()
+	recorder.Record(Event{Type: TaskFinished, Attempts: 2, Success: true, DurationMS: 1500})
+
+	events, err := Load(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(events) != 21 {
+		t.Fatalf("Expected 21 events, got %d", len(events))
+	}
+	last := events[20]
+	if last.Type != TaskFinished || last.Attempts != 2 || last.Duration() != 1500*time.Millisecond {
+		t.Errorf("Expected the task event to round-trip, got %+v", last)
+	}
+	if last.Time.IsZero() {
+		t.Error("Expected events to be stamped with the time")
+	}
+}
+
+func TestRecorder_Nil(t *testing.T) {
+	var recorder *Recorder
+	recorder.Record(Event{Type: TaskStarted}) // Must not panic
+}
+
+func TestLoad_MissingAndCorruptFiles(t *testing.T) {
+	dir := t.TempDir()
+
+	events, err := Load(filepath.Join(dir, "missing.jsonl"))
+	if err != nil || len(events) != 0 {
+		t.Errorf("Expected no events and no error for a missing file, got %v, %v", events, err)
+	}
+
+	path := filepath.Join(dir, "usage.jsonl")
+	data := `{"type":"command","command":"ls","success":true}` + "\n" + "not json\n" + `{"type":"command","command":"git","success":false}` + "\n"
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatalf("Failed to write usage file: %v", err)
+	}
+	events, err = Load(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(events) != 2 {
+		t.Errorf("Expected the unreadable line to be skipped, got %d events", len(events))
+	}
+}
+
+func TestCommandName(t *testing.T) {
+	tests := []struct {
+		command  string
+		expected string
+	}{
+		{"ls -la /home/me/secret", "ls"},
+		{"/usr/local/bin/rg TODO src", "rg"},
+		{"GOOS=linux go build ./...", "go"},
+		{"git log | head -5", "git"},
+		{"   ", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			if got := CommandName(tt.command); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	day := time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)
+	events := []Event{
+		{Time: day, Type: TaskStarted, Model: "granite-code:3b"},
+		{Time: day, Type: LLMRequest, Model: "granite-code:3b", Success: true, DurationMS: 2000},
+		{Time: day, Type: CommandRun, Command: "ls", Success: true},
+		{Time: day, Type: TaskFinished, Attempts: 1, Success: true, DurationMS: 3000},
+
+		{Time: day.Add(time.Hour), Type: LLMRequest, Model: "llama3.1:8b", Success: true, DurationMS: 4000},
+		{Time: day.Add(time.Hour), Type: CommandRun, Command: "grep", Success: false},
+		{Time: day.Add(time.Hour), Type: CommandRun, Command: "grep", Success: true},
+		{Time: day.Add(time.Hour), Type: TaskFinished, Attempts: 2, Failures: 1, Success: true, DurationMS: 5000},
+
+		{Time: day.Add(48 * time.Hour), Type: CommandRun, Command: "grep", Success: false},
+		{Time: day.Add(48 * time.Hour), Type: CommandRun, Command: "make", Success: false},
+		{Time: day.Add(48 * time.Hour), Type: TaskFinished, Attempts: 2, Failures: 2, Success: false, DurationMS: 1000},
+	}
+
+	summary := Summarize(events)
+
+	if summary.Tasks != 3 || summary.Succeeded != 2 || summary.FirstAttempt != 1 {
+		t.Errorf("Expected 3 tasks, 2 succeeded, 1 on the first attempt, got %d, %d, %d", summary.Tasks, summary.Succeeded, summary.FirstAttempt)
+	}
+	if summary.AverageTaskMS != 3000 {
+		t.Errorf("Expected an average task time of 3000ms, got %d", summary.AverageTaskMS)
+	}
+	if summary.Commands != 5 || summary.CommandsFailed != 3 {
+		t.Errorf("Expected 5 commands with 3 failures, got %d with %d", summary.Commands, summary.CommandsFailed)
+	}
+	if summary.LLMRequests != 2 || summary.AverageLLMMS != 3000 {
+		t.Errorf("Expected 2 model responses averaging 3000ms, got %d averaging %d", summary.LLMRequests, summary.AverageLLMMS)
+	}
+	if len(summary.Models) != 2 || summary.Models[0].Model != "granite-code:3b" || summary.Models[1].AverageMS != 4000 {
+		t.Errorf("Expected latency per model sorted by name, got %+v", summary.Models)
+	}
+	expected := []CommandFailures{{Command: "grep", Runs: 3, Failures: 2}, {Command: "make", Runs: 1, Failures: 1}}
+	if len(summary.FailingCommands) != len(expected) {
+		t.Fatalf("Expected failing commands %+v, got %+v", expected, summary.FailingCommands)
+	}
+	for i, want := range expected {
+		if summary.FailingCommands[i] != want {
+			t.Errorf("Expected failing command %d to be %+v, got %+v", i, want, summary.FailingCommands[i])
+		}
+	}
+	if !summary.Since.Equal(day) || !summary.Until.Equal(day.Add(48*time.Hour)) {
+		t.Errorf("Expected the range %s to %s, got %s to %s", day, day.Add(48*time.Hour), summary.Since, summary.Until)
+	}
+}
@@ -0,0 +1,130 @@
+package metrics
+
+import (
+	"sort"
+	"time"
+)
+
+// maxFailingCommands bounds the commands listed in a Summary
+const maxFailingCommands = 10
+
+// Summary aggregates usage events
+type Summary struct {
+	Since            time.Time         `json:"since"`
+	Until            time.Time         `json:"until"`
+	Tasks            int               `json:"tasks"`
+	Succeeded        int               `json:"succeeded"`
+	FirstAttempt     int               `json:"first_attempt"` // Succeeded without a failed command
+	SuccessRate      float64           `json:"success_rate"`
+	FirstAttemptRate float64           `json:"first_attempt_rate"`
+	AverageTaskMS    int64             `json:"average_task_ms"`
+	Commands         int               `json:"commands"`
+	CommandsFailed   int               `json:"commands_failed"`
+	LLMRequests      int               `json:"llm_requests"`
+	AverageLLMMS     int64             `json:"average_llm_ms"`
+	Models           []ModelLatency    `json:"models"`
+	FailingCommands  []CommandFailures `json:"failing_commands"`
+}
+
+// ModelLatency is the average response time of one model
+type ModelLatency struct {
+	Model     string `json:"model"`
+	Requests  int    `json:"requests"`
+	AverageMS int64  `json:"average_ms"`
+}
+
+// CommandFailures counts the runs and failures of one program
+type CommandFailures struct {
+	Command  string `json:"command"`
+	Runs     int    `json:"runs"`
+	Failures int    `json:"failures"`
+}
+
+// Summarize aggregates events. Failing commands are listed most failures
+// first; models are listed by name.
+func Summarize(events []Event) Summary {
+	summary := Summary{Models: []ModelLatency{}, FailingCommands: []CommandFailures{}}
+	var taskTime, llmTime time.Duration
+	models := make(map[string]*ModelLatency)
+	modelTime := make(map[string]time.Duration)
+	commands := make(map[string]*CommandFailures)
+
+	for _, event := range events {
+		if summary.Since.IsZero() || event.Time.Before(summary.Since) {
+			summary.Since = event.Time
+		}
+		if event.Time.After(summary.Until) {
+			summary.Until = event.Time
+		}
+
+		switch event.Type {
+		case TaskFinished:
+			summary.Tasks++
+			taskTime += event.Duration()
+			if event.Success {
+				summary.Succeeded++
+				if event.Failures == 0 {
+					summary.FirstAttempt++
+				}
+			}
+		case CommandRun:
+			summary.Commands++
+			name := event.Command
+			if name == "" {
+				name = "(unknown)"
+			}
+			command, ok := commands[name]
+			if !ok {
+				command = &CommandFailures{Command: name}
+				commands[name] = command
+			}
+			command.Runs++
+			if !event.Success {
+				summary.CommandsFailed++
+				command.Failures++
+			}
+		case LLMRequest:
+			summary.LLMRequests++
+			llmTime += event.Duration()
+			model, ok := models[event.Model]
+			if !ok {
+				model = &ModelLatency{Model: event.Model}
+				models[event.Model] = model
+			}
+			model.Requests++
+			modelTime[event.Model] += event.Duration()
+		}
+	}
+
+	if summary.Tasks > 0 {
+		summary.SuccessRate = float64(summary.Succeeded) / float64(summary.Tasks)
+		summary.FirstAttemptRate = float64(summary.FirstAttempt) / float64(summary.Tasks)
+		summary.AverageTaskMS = (taskTime / time.Duration(summary.Tasks)).Milliseconds()
+	}
+	if summary.LLMRequests > 0 {
+		summary.AverageLLMMS = (llmTime / time.Duration(summary.LLMRequests)).Milliseconds()
+	}
+
+	for name, model := range models {
+		model.AverageMS = (modelTime[name] / time.Duration(model.Requests)).Milliseconds()
+		summary.Models = append(summary.Models, *model)
+	}
+	sort.Slice(summary.Models, func(i, j int) bool { return summary.Models[i].Model < summary.Models[j].Model })
+
+	for _, command := range commands {
+		if command.Failures > 0 {
+			summary.FailingCommands = append(summary.FailingCommands, *command)
+		}
+	}
+	sort.Slice(summary.FailingCommands, func(i, j int) bool {
+		a, b := summary.FailingCommands[i], summary.FailingCommands[j]
+		if a.Failures != b.Failures {
+			return a.Failures > b.Failures
+		}
+		return a.Command < b.Command
+	})
+	if len(summary.FailingCommands) > maxFailingCommands {
+		summary.FailingCommands = summary.FailingCommands[:maxFailingCommands]
+	}
+	return summary
+}
@@ -0,0 +1,123 @@
+package system
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"rag-cli/pkg/paths"
+)
+
+// cacheVersion changes whenever SystemInfo gains fields, so entries written
+// without them are detected again
+const cacheVersion = 4
+
+// cacheEntry is the detected system information stored on disk, with the
+// machine it was detected on
+type cacheEntry struct {
+	Version    int         `json:"version"`
+	DetectedAt time.Time   `json:"detected_at"`
+	OS         string      `json:"os"`
+	Hostname   string      `json:"hostname"`
+	Info       *SystemInfo `json:"info"`
+}
+
+// Cache keeps DetectSystemInfo results on disk, since detection runs a dozen
+// external commands. An entry is used while it is younger than TTL and was
+// written on the same machine.
+type Cache struct {
+	// Path is where the detected information is stored; empty disables the cache
+	Path     string
+	TTL      time.Duration
+	Now      func() time.Time
+	Detect   func() *SystemInfo
+	Hostname func() (string, error)
+	// Resources also records CPU count, memory, and free disk space
+	Resources bool
+}
+
+// NewCache creates a cache at path whose entries expire after ttl
+func NewCache(path string, ttl time.Duration) *Cache {
+	return &Cache{
+		Path:     path,
+		TTL:      ttl,
+		Now:      time.Now,
+		Detect:   DetectSystemInfo,
+		Hostname: os.Hostname,
+	}
+}
+
+// DefaultCachePath returns the location of the system information cache
+func DefaultCachePath() (string, error) {
+	dirs, err := paths.Default()
+	if err != nil {
+		return "", err
+	}
+	return dirs.StateFile("system-info.json"), nil
+}
+
+// Info returns the cached system information when it is fresh, and detects
+// and stores it otherwise
+func (c *Cache) Info() *SystemInfo {
+	hostname, _ := c.Hostname()
+	if entry, ok := c.load(); ok && c.fresh(entry, hostname) {
+		return entry.Info
+	}
+	return c.Refresh()
+}
+
+// Refresh detects system information and stores it, whether or not the
+// cached information is fresh
+func (c *Cache) Refresh() *SystemInfo {
+	hostname, _ := c.Hostname()
+	info := c.Detect()
+	if c.Resources {
+		info.DetectResources()
+	}
+	c.save(cacheEntry{Version: cacheVersion, DetectedAt: c.Now(), OS: runtime.GOOS, Hostname: hostname, Info: info})
+	return info
+}
+
+// fresh reports whether entry can be used on this machine now
+func (c *Cache) fresh(entry cacheEntry, hostname string) bool {
+	age := c.Now().Sub(entry.DetectedAt)
+	return entry.Info != nil &&
+		entry.Version == cacheVersion &&
+		(entry.Info.Resources != nil) == c.Resources &&
+		entry.OS == runtime.GOOS &&
+		entry.Hostname == hostname &&
+		age >= 0 && age < c.TTL
+}
+
+func (c *Cache) load() (cacheEntry, bool) {
+	var entry cacheEntry
+	if c.Path == "" {
+		return entry, false
+	}
+	data, err := os.ReadFile(c.Path)
+	if err != nil {
+		return entry, false
+	}
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return entry, false
+	}
+	return entry, true
+}
+
+// save stores detected information. The cache only saves time, so failures
+// to write it are ignored.
+func (c *Cache) save(entry cacheEntry) {
+	if c.Path == "" {
+		return
+	}
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(c.Path), 0755); err != nil {
+		return
+	}
+	_ = os.WriteFile(c.Path, data, 0644)
+}
@@ -0,0 +1,114 @@
+package system
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCache_Info(t *testing.T) {
+	start := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		setup       func(t *testing.T, c *Cache)
+		elapsed     time.Duration
+		hostname    string
+		wantDetects int
+	}{
+		{
+			name:        "hit within the TTL",
+			setup:       func(t *testing.T, c *Cache) { c.Info() },
+			elapsed:     time.Hour,
+			hostname:    "laptop",
+			wantDetects: 1,
+		},
+		{
+			name:        "expired after the TTL",
+			setup:       func(t *testing.T, c *Cache) { c.Info() },
+			elapsed:     25 * time.Hour,
+			hostname:    "laptop",
+			wantDetects: 2,
+		},
+		{
+			name:        "written on another machine",
+			setup:       func(t *testing.T, c *Cache) { c.Info() },
+			elapsed:     time.Hour,
+			hostname:    "server",
+			wantDetects: 2,
+		},
+		{
+			name: "corrupt file falls back to detection",
+			setup: func(t *testing.T, c *Cache) {
+				if err := os.WriteFile(c.Path, []byte("{not json"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			},
+			hostname:    "laptop",
+			wantDetects: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			detects := 0
+			now := start
+			hostname := "laptop"
+			c := &Cache{
+				Path: filepath.Join(t.TempDir(), "system-info.json"),
+				TTL:  24 * time.Hour,
+				Now:  func() time.Time { return now },
+				Detect: func() *SystemInfo {
+					detects++
+					return &SystemInfo{OS: "linux", Capabilities: map[string]string{"git": "git version 2.43.0"}}
+				},
+				Hostname: func() (string, error) { return hostname, nil },
+			}
+
+			tt.setup(t, c)
+			now = start.Add(tt.elapsed)
+			hostname = tt.hostname
+
+			info := c.Info()
+			if detects != tt.wantDetects {
+				t.Errorf("Expected %d detections, got %d", tt.wantDetects, detects)
+			}
+			if info == nil || info.Capabilities["git"] != "git version 2.43.0" {
+				t.Errorf("Expected the detected information, got %+v", info)
+			}
+		})
+	}
+}
+
+func TestCache_Disabled(t *testing.T) {
+	detects := 0
+	c := NewCache("", 24*time.Hour)
+	c.Detect = func() *SystemInfo {
+		detects++
+		return &SystemInfo{}
+	}
+
+	c.Info()
+	c.Info()
+	if detects != 2 {
+		t.Errorf("Expected every call to detect without a cache path, got %d detections", detects)
+	}
+}
+
+func TestCache_Refresh(t *testing.T) {
+	version := "git version 2.43.0"
+	c := NewCache(filepath.Join(t.TempDir(), "system-info.json"), 24*time.Hour)
+	c.Detect = func() *SystemInfo {
+		return &SystemInfo{Capabilities: map[string]string{"git": version}}
+	}
+	c.Info()
+
+	version = "git version 2.45.1"
+	if got := c.Refresh().Capabilities["git"]; got != version {
+		t.Errorf("Expected Refresh to detect %q, got %q", version, got)
+	}
+	if got := c.Info().Capabilities["git"]; got != version {
+		t.Errorf("Expected the refreshed information to be cached, got %q", got)
+	}
+}
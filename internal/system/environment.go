@@ -0,0 +1,84 @@
+package system
+
+import (
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// ciProviders maps the environment variable each CI service sets to its
+// name, checked in order before the generic CI variable
+var ciProviders = []struct {
+	envVar string
+	name   string
+}{
+	{"GITHUB_ACTIONS", "GitHub Actions"},
+	{"GITLAB_CI", "GitLab CI"},
+	{"CIRCLECI", "CircleCI"},
+	{"BUILDKITE", "Buildkite"},
+	{"TF_BUILD", "Azure Pipelines"},
+	{"JENKINS_URL", "Jenkins"},
+	{"TEAMCITY_VERSION", "TeamCity"},
+	{"TRAVIS", "Travis CI"},
+}
+
+// cgroupRuntimes are the container runtimes recognized in /proc/1/cgroup
+var cgroupRuntimes = []struct {
+	marker string
+	name   string
+}{
+	{"kubepods", "kubernetes"},
+	{"docker", "docker"},
+	{"containerd", "containerd"},
+	{"libpod", "podman"},
+	{"lxc", "lxc"},
+}
+
+// detectEnvironment records whether rag-cli runs in a container or on a CI
+// runner
+func (si *SystemInfo) detectEnvironment() {
+	si.Container = detectContainer(os.DirFS("/"), os.Getenv)
+	si.InContainer = si.Container != ""
+	si.CI = detectCI(os.Getenv)
+	si.InCI = si.CI != ""
+}
+
+// detectContainer returns the container runtime rag-cli runs under, or ""
+// outside a container. root is the filesystem root.
+func detectContainer(root fs.FS, getenv func(string) string) string {
+	if getenv("KUBERNETES_SERVICE_HOST") != "" {
+		return "kubernetes"
+	}
+	if name := getenv("container"); name != "" {
+		return name // Set by podman and systemd-nspawn
+	}
+	if _, err := fs.Stat(root, ".dockerenv"); err == nil {
+		return "docker"
+	}
+	if _, err := fs.Stat(root, "run/.containerenv"); err == nil {
+		return "podman"
+	}
+	if cgroup, err := fs.ReadFile(root, "proc/1/cgroup"); err == nil {
+		for _, runtime := range cgroupRuntimes {
+			if strings.Contains(string(cgroup), runtime.marker) {
+				return runtime.name
+			}
+		}
+	}
+	return ""
+}
+
+// detectCI returns the CI service rag-cli runs on, "CI" for an unknown one,
+// or "" outside CI
+func detectCI(getenv func(string) string) string {
+	for _, provider := range ciProviders {
+		if value := getenv(provider.envVar); value != "" && value != "false" {
+			return provider.name
+		}
+	}
+	switch strings.ToLower(getenv("CI")) {
+	case "", "0", "false":
+		return ""
+	}
+	return "CI"
+}
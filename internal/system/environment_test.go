@@ -0,0 +1,81 @@
+package system
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// fakeEnv returns a getenv function that reads from vars
+func fakeEnv(vars map[string]string) func(string) string {
+	return func(key string) string { return vars[key] }
+}
+
+func TestDetectContainer(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    fstest.MapFS
+		env      map[string]string
+		expected string
+	}{
+		{name: "bare machine", files: fstest.MapFS{"proc/1/cgroup": {Data: []byte("0::/init.scope\n")}}, expected: ""},
+		{name: "docker marker file", files: fstest.MapFS{".dockerenv": {}}, expected: "docker"},
+		{name: "podman marker file", files: fstest.MapFS{"run/.containerenv": {}}, expected: "podman"},
+		{name: "docker cgroup", files: fstest.MapFS{"proc/1/cgroup": {Data: []byte("12:memory:/docker/3f2a9c\n")}}, expected: "docker"},
+		{name: "kubernetes cgroup", files: fstest.MapFS{"proc/1/cgroup": {Data: []byte("11:cpu:/kubepods/besteffort/pod1/abc\n")}}, expected: "kubernetes"},
+		{name: "kubernetes environment", files: fstest.MapFS{}, env: map[string]string{"KUBERNETES_SERVICE_HOST": "10.0.0.1"}, expected: "kubernetes"},
+		{name: "container environment variable", files: fstest.MapFS{}, env: map[string]string{"container": "podman"}, expected: "podman"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := detectContainer(tt.files, fakeEnv(tt.env)); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestDetectCI(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      map[string]string
+		expected string
+	}{
+		{name: "not CI", env: nil, expected: ""},
+		{name: "GitHub Actions", env: map[string]string{"GITHUB_ACTIONS": "true", "CI": "true"}, expected: "GitHub Actions"},
+		{name: "GitLab CI", env: map[string]string{"GITLAB_CI": "true"}, expected: "GitLab CI"},
+		{name: "Jenkins", env: map[string]string{"JENKINS_URL": "https://ci.example.com/"}, expected: "Jenkins"},
+		{name: "unknown CI", env: map[string]string{"CI": "1"}, expected: "CI"},
+		{name: "CI turned off", env: map[string]string{"CI": "false"}, expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := detectCI(fakeEnv(tt.env)); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestGetCommandSyntaxHints_Environment(t *testing.T) {
+	info := &SystemInfo{
+		OS:           "linux",
+		Capabilities: map[string]string{},
+		InContainer:  true,
+		Container:    "docker",
+		InCI:         true,
+		CI:           "GitHub Actions",
+	}
+	hints := info.GetCommandSyntaxHints()
+	for _, want := range []string{"Running inside a docker container: no systemd", "Running on a CI runner (GitHub Actions)"} {
+		if !strings.Contains(hints, want) {
+			t.Errorf("Expected hints to contain %q, got:\n%s", want, hints)
+		}
+	}
+
+	if hints := (&SystemInfo{OS: "linux", Capabilities: map[string]string{}}).GetCommandSyntaxHints(); strings.Contains(hints, "Running ") {
+		t.Errorf("Expected no environment lines outside containers and CI, got:\n%s", hints)
+	}
+}
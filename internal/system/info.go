@@ -1,11 +1,14 @@
 package system
 
 import (
+	"context"
 	"fmt"
-	"os"
 	"os/exec"
 	"runtime"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 )
 
 // SystemInfo holds information about the current system environment
@@ -15,7 +18,44 @@ type SystemInfo struct {
 	Shell        string
 	HasGNU       bool
 	HasBSD       bool
+	HasWSL       bool // Windows Subsystem for Linux is installed
 	Capabilities map[string]string
+	// PackageManagers lists the package managers found, preferred first
+	PackageManagers []string
+	InContainer     bool
+	Container       string // Container runtime, such as docker or kubernetes
+	InCI            bool
+	CI              string // CI service, such as GitHub Actions, or "CI" when unknown
+	Resources       *Resources // nil unless DetectResources was called
+}
+
+// Shells detected on Windows when no POSIX shell is in use
+const (
+	ShellPowerShell = "powershell"
+	ShellCmd        = "cmd"
+)
+
+// lookPath finds executables on PATH; tests replace it
+var lookPath = exec.LookPath
+
+// packageManagers lists the package managers detected on each OS, in order
+// of preference. Linux lists Homebrew last since it usually supplements the
+// distribution's own package manager there.
+var packageManagers = map[string][]string{
+	"darwin":  {"brew"},
+	"linux":   {"apt", "dnf", "pacman", "apk", "brew"},
+	"windows": {"winget", "choco"},
+}
+
+// installCommands is how each package manager installs a package
+var installCommands = map[string]string{
+	"brew":   "brew install <package>",
+	"apt":    "sudo apt install <package>",
+	"dnf":    "sudo dnf install <package>",
+	"pacman": "sudo pacman -S <package>",
+	"apk":    "apk add <package>",
+	"winget": "winget install <package>",
+	"choco":  "choco install <package>",
 }
 
 // DetectSystemInfo gathers information about the current system
@@ -26,77 +66,106 @@ func DetectSystemInfo() *SystemInfo {
 		Capabilities: make(map[string]string),
 	}
 
-	// Detect shell
-	if shell := os.Getenv("SHELL"); shell != "" {
-		info.Shell = shell
-	}
-
-	// Detect command variants
-	info.detectCommandVariants()
+	info.detectPlatform()
+	info.detectTools()
+	info.PackageManagers = detectPackageManagers(info.OS)
+	info.detectEnvironment()
 
 	return info
 }
 
-// detectCommandVariants checks which command variants are available
-func (si *SystemInfo) detectCommandVariants() {
-	// Check if GNU coreutils are available (common on Linux)
-	if output, err := exec.Command("ls", "--version").CombinedOutput(); err == nil {
-		outputStr := strings.ToLower(string(output))
-		if strings.Contains(outputStr, "gnu") {
-			si.HasGNU = true
-			si.Capabilities["ls"] = "GNU"
+// detectTools records the versions of common tools found on PATH
+func (si *SystemInfo) detectTools() {
+	// Check if common tools are available, probing their versions in parallel
+	commonTools := []string{"git", "curl", "wget", "docker", "kubectl", "npm", "python3", "go", "make"}
+	var (
+		mu sync.Mutex
+		wg sync.WaitGroup
+	)
+	for _, tool := range commonTools {
+		if _, err := lookPath(tool); err != nil {
+			continue
 		}
+		wg.Add(1)
+		go func(tool string) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+			defer cancel()
+			version := getToolVersion(ctx, tool)
+			if version == "" {
+				version = "available"
+			}
+			mu.Lock()
+			si.Capabilities[tool] = version
+			mu.Unlock()
+		}(tool)
 	}
+	wg.Wait()
+}
 
-	// Check if BSD commands are available (macOS, FreeBSD)
-	if output, err := exec.Command("stat", "-f", "%z", "/").CombinedOutput(); err == nil {
-		if len(output) > 0 {
-			si.HasBSD = true
-			si.Capabilities["stat"] = "BSD"
-		}
+// windowsShell works out the shell rag-cli was started from on Windows. A
+// POSIX shell such as Git Bash sets SHELL; PowerShell adds the user's module
+// directory to PSModulePath, which is otherwise only the system directories.
+func windowsShell(getenv func(string) string) string {
+	if shell := getenv("SHELL"); shell != "" {
+		return shell
+	}
+	modulePath := strings.ToLower(getenv("PSModulePath"))
+	if strings.Contains(modulePath, `documents\windowspowershell\modules`) || strings.Contains(modulePath, `documents\powershell\modules`) {
+		return ShellPowerShell
 	}
+	return ShellCmd
+}
+
+// windowsNativeShell reports whether commands are written for PowerShell or
+// cmd rather than a POSIX shell
+func (si *SystemInfo) windowsNativeShell() bool {
+	return si.OS == "windows" && (si.Shell == ShellPowerShell || si.Shell == ShellCmd)
+}
 
-	// Check stat command variant
-	if si.Capabilities["stat"] == "" {
-		if _, err := exec.Command("stat", "-c", "%s", "/").CombinedOutput(); err == nil {
-			si.Capabilities["stat"] = "GNU"
+// detectPackageManagers returns the package managers for goos found on PATH,
+// preferred first
+func detectPackageManagers(goos string) []string {
+	var found []string
+	for _, manager := range packageManagers[goos] {
+		if _, err := lookPath(manager); err == nil {
+			found = append(found, manager)
 		}
 	}
+	return found
+}
 
-	// Check du command variant
-	if _, err := exec.Command("du", "-b", "/dev/null").CombinedOutput(); err == nil {
-		si.Capabilities["du"] = "GNU"
-	} else if _, err := exec.Command("du", "-h", "/dev/null").CombinedOutput(); err == nil {
-		si.Capabilities["du"] = "BSD"
+// PreferredPackageManager returns the package manager install commands
+// should use, or "" when none was found
+func (si *SystemInfo) PreferredPackageManager() string {
+	if len(si.PackageManagers) == 0 {
+		return ""
 	}
+	return si.PackageManagers[0]
+}
 
-	// Check find command variant
-	if _, err := exec.Command("find", "/dev/null", "-printf", "%s").CombinedOutput(); err == nil {
-		si.Capabilities["find"] = "GNU"
-	} else {
-		si.Capabilities["find"] = "BSD"
-	}
+// probeTimeout bounds the version probes of each tool, so a CLI that hangs,
+// such as docker waiting on a dead daemon, cannot stall detection
+var probeTimeout = 2 * time.Second
 
-	// Check if common tools are available
-	commonTools := []string{"git", "curl", "wget", "docker", "kubectl", "npm", "python3", "go", "make"}
-	for _, tool := range commonTools {
-		if _, err := exec.LookPath(tool); err == nil {
-			if version := getToolVersion(tool); version != "" {
-				si.Capabilities[tool] = version
-			} else {
-				si.Capabilities[tool] = "available"
-			}
-		}
-	}
+// runProbe runs a version probe and returns its output; tests replace it
+var runProbe = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.WaitDelay = 100 * time.Millisecond // Don't wait on children that keep the output open
+	return cmd.CombinedOutput()
 }
 
-// getToolVersion attempts to get the version of a tool
-func getToolVersion(tool string) string {
+// getToolVersion attempts to get the version of a tool, giving up with ""
+// once ctx is done
+func getToolVersion(ctx context.Context, tool string) string {
 	// Try common version flags
 	versionFlags := []string{"--version", "-version", "-V", "version"}
 	
 	for _, flag := range versionFlags {
-		if output, err := exec.Command(tool, flag).CombinedOutput(); err == nil {
+		if ctx.Err() != nil {
+			return ""
+		}
+		if output, err := runProbe(ctx, tool, flag); err == nil {
 			lines := strings.Split(string(output), "\n")
 			if len(lines) > 0 && strings.TrimSpace(lines[0]) != "" {
 				// Return first line, truncated if too long
@@ -122,8 +191,49 @@ func (si *SystemInfo) GetCommandSyntaxHints() string {
 		hints.WriteString(fmt.Sprintf("Shell: %s\n", si.Shell))
 	}
 	
+	if si.InContainer {
+		hints.WriteString(fmt.Sprintf("Running inside a %s container: no systemd or systemctl, no desktop or GUI apps, and sudo may be missing; run commands directly as the current user\n", si.Container))
+	}
+	if si.Resources != nil {
+		hints.WriteString(fmt.Sprintf("Resources: %s\n", si.Resources))
+	}
+	if si.InCI {
+		hints.WriteString(fmt.Sprintf("Running on a CI runner (%s): nobody can answer interactive prompts, so pass non-interactive flags such as -y, and there is no desktop\n", si.CI))
+	}
+	
 	hints.WriteString("\nCOMMAND SYNTAX GUIDELINES:\n")
+	if si.windowsNativeShell() {
+		si.writeWindowsHints(&hints)
+	} else {
+		si.writeUnixHints(&hints)
+	}
 	
+	// Package manager
+	if manager := si.PreferredPackageManager(); manager != "" {
+		hints.WriteString(fmt.Sprintf("- Install missing tools with '%s' (%s is the package manager here)\n", installCommands[manager], manager))
+	} else {
+		hints.WriteString("- No known package manager was found; do not assume one is available to install missing tools\n")
+	}
+	
+	// Available tools, sorted so the prompt is the same from run to run
+	if len(si.Capabilities) > 0 {
+		hints.WriteString("\nAVAILABLE TOOLS:\n")
+		tools := si.Tools()
+		names := make([]string, 0, len(tools))
+		for tool := range tools {
+			names = append(names, tool)
+		}
+		sort.Strings(names)
+		for _, tool := range names {
+			hints.WriteString(fmt.Sprintf("- %s: %s\n", tool, tools[tool]))
+		}
+	}
+	
+	return hints.String()
+}
+
+// writeUnixHints describes the GNU or BSD syntax of the core commands
+func (si *SystemInfo) writeUnixHints(hints *strings.Builder) {
 	// Stat command
 	if si.Capabilities["stat"] == "BSD" {
 		hints.WriteString("- Use 'stat -f %z file' for file size (BSD syntax)\n")
@@ -151,26 +261,126 @@ func (si *SystemInfo) GetCommandSyntaxHints() string {
 	} else {
 		hints.WriteString("- Use 'ls -lS' for size sorting (BSD)\n")
 	}
-	
-	// Available tools
-	if len(si.Capabilities) > 0 {
-		hints.WriteString("\nAVAILABLE TOOLS:\n")
-		for tool, version := range si.Capabilities {
-			if tool != "stat" && tool != "du" && tool != "find" && tool != "ls" {
-				if version == "available" {
-					hints.WriteString(fmt.Sprintf("- %s: available\n", tool))
-				} else {
-					hints.WriteString(fmt.Sprintf("- %s: %s\n", tool, version))
-				}
-			}
+	if si.OS == "windows" {
+		hints.WriteString("- This is a POSIX shell on Windows: use forward slashes, and write C:\\Users as /c/Users\n")
+	}
+}
+
+// writeWindowsHints describes PowerShell or cmd syntax
+func (si *SystemInfo) writeWindowsHints(hints *strings.Builder) {
+	if si.Shell == ShellPowerShell {
+		hints.WriteString("- Use PowerShell cmdlets, not Unix commands: Get-ChildItem (not ls), Get-Content (not cat), Select-String (not grep)\n")
+		hints.WriteString("- Use 'Get-ChildItem -Recurse -File | Sort-Object Length -Descending' to sort files by size\n")
+		hints.WriteString("- Use '(Get-Item file).Length' for file size\n")
+		hints.WriteString("- Use $env:NAME for environment variables and ; to separate commands\n")
+	} else {
+		hints.WriteString("- Use cmd.exe syntax, not Unix commands: dir (not ls), type (not cat), findstr (not grep)\n")
+		hints.WriteString("- Use 'dir /O:-S' to sort files by size\n")
+		hints.WriteString("- Use %NAME% for environment variables and && to chain commands\n")
+	}
+	hints.WriteString("- Paths use backslashes, e.g. C:\\Users\\name\n")
+	if si.HasWSL {
+		hints.WriteString("- WSL is installed: Linux commands can run with 'wsl <command>'\n")
+	}
+}
+
+// variantCommands are the commands whose GNU or BSD flavor is detected
+var variantCommands = []string{"ls", "stat", "du", "find"}
+
+// Describe lists the detected environment, one fact per line
+func (si *SystemInfo) Describe() []string {
+	shell := si.Shell
+	if shell == "" {
+		shell = "unknown"
+	}
+	lines := []string{
+		fmt.Sprintf("OS: %s/%s", si.OS, si.Architecture),
+		fmt.Sprintf("Shell: %s", shell),
+	}
+	if si.Resources != nil {
+		lines = append(lines, "Resources: "+si.Resources.String())
+	}
+	if si.HasWSL {
+		lines = append(lines, "WSL: installed")
+	}
+	if si.InContainer {
+		lines = append(lines, "Container: "+si.Container)
+	}
+	if si.InCI {
+		lines = append(lines, "CI: "+si.CI)
+	}
+
+	syntax := si.CommandSyntax()
+	var variants []string
+	for _, command := range variantCommands {
+		if flavor := syntax[command]; flavor != "" {
+			variants = append(variants, command+" "+flavor)
 		}
 	}
-	
-	return hints.String()
+	if len(variants) > 0 {
+		lines = append(lines, "Command syntax: "+strings.Join(variants, ", "))
+	}
+	if len(si.PackageManagers) > 0 {
+		lines = append(lines, "Package managers: "+strings.Join(si.PackageManagers, ", "))
+	}
+
+	tools := si.Tools()
+	names := make([]string, 0, len(tools))
+	for tool := range tools {
+		names = append(names, tool)
+	}
+	sort.Strings(names)
+	for _, tool := range names {
+		lines = append(lines, fmt.Sprintf("%s: %s", tool, tools[tool]))
+	}
+	return lines
+}
+
+// CommandSyntax returns whether each core command (ls, stat, du, find) uses
+// GNU or BSD syntax, for the commands that were probed
+func (si *SystemInfo) CommandSyntax() map[string]string {
+	syntax := make(map[string]string)
+	for _, command := range variantCommands {
+		if flavor := si.Capabilities[command]; flavor != "" {
+			syntax[command] = flavor
+		}
+	}
+	return syntax
+}
+
+// Tools returns the detected tools with their versions, or "available" when
+// the version could not be read
+func (si *SystemInfo) Tools() map[string]string {
+	tools := make(map[string]string)
+	for tool, version := range si.Capabilities {
+		if !isVariantCommand(tool) {
+			tools[tool] = version
+		}
+	}
+	return tools
+}
+
+func isVariantCommand(name string) bool {
+	for _, command := range variantCommands {
+		if name == command {
+			return true
+		}
+	}
+	return false
 }
 
 // GetSystemDetectionCommands returns commands to detect system properties
 func (si *SystemInfo) GetSystemDetectionCommands() []string {
+	if si.windowsNativeShell() {
+		if si.Shell == ShellPowerShell {
+			return []string{
+				"$PSVersionTable",
+				"Get-CimInstance Win32_OperatingSystem | Select-Object Caption, Version, OSArchitecture",
+			}
+		}
+		return []string{"ver", "systeminfo | findstr /B /C:\"OS\""}
+	}
+	
 	commands := []string{
 		"uname -a",  // System information
 	}
@@ -0,0 +1,221 @@
+package system
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// stubLookPath makes only the named executables available for the test
+func stubLookPath(t *testing.T, available ...string) {
+	original := lookPath
+	t.Cleanup(func() { lookPath = original })
+	lookPath = func(file string) (string, error) {
+		for _, name := range available {
+			if name == file {
+				return "/usr/bin/" + file, nil
+			}
+		}
+		return "", errors.New("executable file not found in $PATH")
+	}
+}
+
+func TestDetectPackageManagers(t *testing.T) {
+	tests := []struct {
+		name      string
+		goos      string
+		available []string
+		expected  []string
+	}{
+		{name: "macOS with Homebrew", goos: "darwin", available: []string{"brew"}, expected: []string{"brew"}},
+		{name: "macOS ignores apt", goos: "darwin", available: []string{"apt"}, expected: nil},
+		{name: "Fedora", goos: "linux", available: []string{"dnf"}, expected: []string{"dnf"}},
+		{name: "distribution manager before Homebrew on Linux", goos: "linux", available: []string{"brew", "apt"}, expected: []string{"apt", "brew"}},
+		{name: "Alpine", goos: "linux", available: []string{"apk"}, expected: []string{"apk"}},
+		{name: "Windows", goos: "windows", available: []string{"choco", "winget"}, expected: []string{"winget", "choco"}},
+		{name: "none found", goos: "linux", available: nil, expected: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stubLookPath(t, tt.available...)
+			got := detectPackageManagers(tt.goos)
+			if strings.Join(got, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestGetCommandSyntaxHints_PackageManager(t *testing.T) {
+	tests := []struct {
+		name     string
+		managers []string
+		expected string
+	}{
+		{name: "Homebrew", managers: []string{"brew"}, expected: "Install missing tools with 'brew install <package>'"},
+		{name: "preferred first", managers: []string{"pacman", "brew"}, expected: "Install missing tools with 'sudo pacman -S <package>'"},
+		{name: "none", managers: nil, expected: "No known package manager was found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := &SystemInfo{OS: "linux", Capabilities: map[string]string{}, PackageManagers: tt.managers}
+			if hints := info.GetCommandSyntaxHints(); !strings.Contains(hints, tt.expected) {
+				t.Errorf("Expected hints to contain %q, got:\n%s", tt.expected, hints)
+			}
+		})
+	}
+}
+
+func TestWindowsShell(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      map[string]string
+		expected string
+	}{
+		{name: "Git Bash", env: map[string]string{"SHELL": "/usr/bin/bash"}, expected: "/usr/bin/bash"},
+		{name: "Windows PowerShell", env: map[string]string{"PSModulePath": `C:\Users\me\Documents\WindowsPowerShell\Modules;C:\Program Files\WindowsPowerShell\Modules`}, expected: ShellPowerShell},
+		{name: "PowerShell 7", env: map[string]string{"PSModulePath": `C:\Users\me\Documents\PowerShell\Modules;C:\Program Files\PowerShell\Modules`}, expected: ShellPowerShell},
+		{name: "cmd with only system modules", env: map[string]string{"PSModulePath": `C:\Program Files\WindowsPowerShell\Modules`}, expected: ShellCmd},
+		{name: "cmd", env: nil, expected: ShellCmd},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := windowsShell(fakeEnv(tt.env)); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestGetCommandSyntaxHints_Platforms(t *testing.T) {
+	tests := []struct {
+		name       string
+		info       SystemInfo
+		expected   []string
+		unexpected []string
+		detection  string
+	}{
+		{
+			name:       "Linux",
+			info:       SystemInfo{OS: "linux", Shell: "/bin/bash", HasGNU: true, Capabilities: map[string]string{"stat": "GNU"}},
+			expected:   []string{"stat -c %s file", "ls --sort=size"},
+			unexpected: []string{"Get-ChildItem", "POSIX shell on Windows"},
+			detection:  "uname -a",
+		},
+		{
+			name:       "macOS",
+			info:       SystemInfo{OS: "darwin", Shell: "/bin/zsh", HasBSD: true, Capabilities: map[string]string{"stat": "BSD"}},
+			expected:   []string{"stat -f %z file", "ls -lS"},
+			unexpected: []string{"Get-ChildItem"},
+			detection:  "sw_vers",
+		},
+		{
+			name:       "Windows PowerShell",
+			info:       SystemInfo{OS: "windows", Shell: ShellPowerShell, HasWSL: true, Capabilities: map[string]string{}},
+			expected:   []string{"Get-ChildItem", "Sort-Object Length", "wsl <command>"},
+			unexpected: []string{"stat -", "ls -lS", "find ..."},
+			detection:  "$PSVersionTable",
+		},
+		{
+			name:       "Windows cmd",
+			info:       SystemInfo{OS: "windows", Shell: ShellCmd, Capabilities: map[string]string{}},
+			expected:   []string{"dir /O:-S", "findstr"},
+			unexpected: []string{"Get-ChildItem", "wsl <command>", "ls -lS"},
+			detection:  "ver",
+		},
+		{
+			name:       "Git Bash on Windows",
+			info:       SystemInfo{OS: "windows", Shell: "/usr/bin/bash", Capabilities: map[string]string{}},
+			expected:   []string{"POSIX shell on Windows", "ls -lS"},
+			unexpected: []string{"Get-ChildItem"},
+			detection:  "uname -a",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hints := tt.info.GetCommandSyntaxHints()
+			for _, want := range tt.expected {
+				if !strings.Contains(hints, want) {
+					t.Errorf("Expected hints to contain %q, got:\n%s", want, hints)
+				}
+			}
+			for _, unwanted := range tt.unexpected {
+				if strings.Contains(hints, unwanted) {
+					t.Errorf("Expected hints not to contain %q, got:\n%s", unwanted, hints)
+				}
+			}
+			if commands := tt.info.GetSystemDetectionCommands(); !strings.Contains(strings.Join(commands, "\n"), tt.detection) {
+				t.Errorf("Expected detection commands to include %q, got %v", tt.detection, commands)
+			}
+		})
+	}
+}
+
+// stubProbe replaces the version probe for the test
+func stubProbe(t *testing.T, probe func(ctx context.Context, name string, args ...string) ([]byte, error)) {
+	original := runProbe
+	t.Cleanup(func() { runProbe = original })
+	runProbe = probe
+}
+
+func TestDetectTools_Timeout(t *testing.T) {
+	stubLookPath(t, "git", "docker")
+	stubProbe(t, func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		if name == "docker" {
+			<-ctx.Done() // A CLI waiting on a dead daemon
+			return nil, ctx.Err()
+		}
+		return []byte("git version 2.43.0\n"), nil
+	})
+	original := probeTimeout
+	t.Cleanup(func() { probeTimeout = original })
+	probeTimeout = 50 * time.Millisecond
+
+	info := &SystemInfo{Capabilities: map[string]string{}}
+	info.detectTools()
+
+	if got := info.Capabilities["git"]; got != "git version 2.43.0" {
+		t.Errorf("Expected the git version, got %q", got)
+	}
+	if got := info.Capabilities["docker"]; got != "available" {
+		t.Errorf("Expected a timed-out tool to be recorded as available, got %q", got)
+	}
+}
+
+func TestDetectTools_Concurrent(t *testing.T) {
+	tools := []string{"git", "curl", "docker", "go", "make"}
+	stubLookPath(t, tools...)
+
+	var running, maxRunning int32
+	stubProbe(t, func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			old := atomic.LoadInt32(&maxRunning)
+			if n <= old || atomic.CompareAndSwapInt32(&maxRunning, old, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		return []byte(name + " 1.0\n"), nil
+	})
+
+	info := &SystemInfo{Capabilities: map[string]string{}}
+	info.detectTools()
+
+	if maxRunning < 2 {
+		t.Errorf("Expected probes to run concurrently, at most %d ran at once", maxRunning)
+	}
+	for _, tool := range tools {
+		if got := info.Capabilities[tool]; got != tool+" 1.0" {
+			t.Errorf("Expected %s version %q, got %q", tool, tool+" 1.0", got)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+//go:build !windows
+
+package system
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// detectPlatform records the shell and probes the GNU or BSD flavor of the
+// core commands
+func (si *SystemInfo) detectPlatform() {
+	if shell := os.Getenv("SHELL"); shell != "" {
+		si.Shell = shell
+	}
+	si.detectCommandVariants()
+}
+
+// detectCommandVariants checks which command variants are available
+func (si *SystemInfo) detectCommandVariants() {
+	// Check if GNU coreutils are available (common on Linux)
+	if output, err := exec.Command("ls", "--version").CombinedOutput(); err == nil {
+		outputStr := strings.ToLower(string(output))
+		if strings.Contains(outputStr, "gnu") {
+			si.HasGNU = true
+			si.Capabilities["ls"] = "GNU"
+		}
+	}
+
+	// Check if BSD commands are available (macOS, FreeBSD)
+	if output, err := exec.Command("stat", "-f", "%z", "/").CombinedOutput(); err == nil {
+		if len(output) > 0 {
+			si.HasBSD = true
+			si.Capabilities["stat"] = "BSD"
+		}
+	}
+
+	// Check stat command variant
+	if si.Capabilities["stat"] == "" {
+		if _, err := exec.Command("stat", "-c", "%s", "/").CombinedOutput(); err == nil {
+			si.Capabilities["stat"] = "GNU"
+		}
+	}
+
+	// Check du command variant
+	if _, err := exec.Command("du", "-b", "/dev/null").CombinedOutput(); err == nil {
+		si.Capabilities["du"] = "GNU"
+	} else if _, err := exec.Command("du", "-h", "/dev/null").CombinedOutput(); err == nil {
+		si.Capabilities["du"] = "BSD"
+	}
+
+	// Check find command variant
+	if _, err := exec.Command("find", "/dev/null", "-printf", "%s").CombinedOutput(); err == nil {
+		si.Capabilities["find"] = "GNU"
+	} else {
+		si.Capabilities["find"] = "BSD"
+	}
+}
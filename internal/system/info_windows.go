@@ -0,0 +1,14 @@
+//go:build windows
+
+package system
+
+import "os"
+
+// detectPlatform records the shell and whether WSL is installed. The GNU
+// and BSD probes are skipped, since those commands do not exist on Windows.
+func (si *SystemInfo) detectPlatform() {
+	si.Shell = windowsShell(os.Getenv)
+	if _, err := lookPath("wsl"); err == nil {
+		si.HasWSL = true
+	}
+}
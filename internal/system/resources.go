@@ -0,0 +1,60 @@
+package system
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+)
+
+// Resources is the machine's capacity, for requests such as freeing disk
+// space or choosing how many parallel jobs to run. Zero means unknown.
+type Resources struct {
+	CPUs          int
+	MemoryBytes   uint64
+	DiskFreeBytes uint64
+	DiskPath      string // Directory whose volume DiskFreeBytes describes
+}
+
+// DetectResources records the CPU count, total memory, and free disk space
+// on the working directory's volume
+func (si *SystemInfo) DetectResources() {
+	resources := &Resources{CPUs: runtime.NumCPU()}
+	resources.MemoryBytes, _ = totalMemory()
+	if dir, err := os.Getwd(); err == nil {
+		if free, err := diskFree(dir); err == nil {
+			resources.DiskFreeBytes = free
+			resources.DiskPath = dir
+		}
+	}
+	si.Resources = resources
+}
+
+// String renders the known resources on one line
+func (r *Resources) String() string {
+	var parts []string
+	if r.CPUs > 0 {
+		parts = append(parts, fmt.Sprintf("%d CPUs", r.CPUs))
+	}
+	if r.MemoryBytes > 0 {
+		parts = append(parts, formatBytes(r.MemoryBytes)+" memory")
+	}
+	if r.DiskPath != "" {
+		parts = append(parts, fmt.Sprintf("%s free disk on %s", formatBytes(r.DiskFreeBytes), r.DiskPath))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// formatBytes renders a size in binary units with one decimal place
+func formatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for m := n / unit; m >= unit && exp < 4; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTP"[exp])
+}
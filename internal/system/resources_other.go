@@ -0,0 +1,30 @@
+//go:build !linux && !darwin
+
+package system
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// diskFree asks df for the space available on dir's volume
+func diskFree(dir string) (uint64, error) {
+	output, err := exec.Command("df", "-Pk", dir).Output()
+	if err != nil {
+		return 0, err
+	}
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	fields := strings.Fields(lines[len(lines)-1])
+	if len(lines) < 2 || len(fields) < 4 {
+		return 0, fmt.Errorf("unexpected df output: %q", output)
+	}
+	kib, err := strconv.ParseUint(fields[3], 10, 64)
+	return kib * 1024, err
+}
+
+// totalMemory is not detected on this platform
+func totalMemory() (uint64, error) {
+	return 0, fmt.Errorf("memory detection is not supported here")
+}
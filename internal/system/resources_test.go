@@ -0,0 +1,92 @@
+package system
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResources_String(t *testing.T) {
+	tests := []struct {
+		name      string
+		resources Resources
+		expected  string
+	}{
+		{
+			name:      "everything known",
+			resources: Resources{CPUs: 8, MemoryBytes: 16 << 30, DiskFreeBytes: 120*(1<<30) + 512<<20, DiskPath: "/home/me/project"},
+			expected:  "8 CPUs, 16.0 GiB memory, 120.5 GiB free disk on /home/me/project",
+		},
+		{
+			name:      "memory unknown",
+			resources: Resources{CPUs: 4, DiskFreeBytes: 900 << 20, DiskPath: `C:\work`},
+			expected:  `4 CPUs, 900.0 MiB free disk on C:\work`,
+		},
+		{
+			name:      "small sizes",
+			resources: Resources{MemoryBytes: 512, DiskFreeBytes: 2048, DiskPath: "/tmp"},
+			expected:  "512 B memory, 2.0 KiB free disk on /tmp",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.resources.String(); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestResourcesHints(t *testing.T) {
+	info := &SystemInfo{OS: "linux", Capabilities: map[string]string{}, Resources: &Resources{CPUs: 2}}
+	if hints := info.GetCommandSyntaxHints(); !strings.Contains(hints, "Resources: 2 CPUs\n") {
+		t.Errorf("Expected a resources line, got:\n%s", hints)
+	}
+
+	info.Resources = nil
+	if hints := info.GetCommandSyntaxHints(); strings.Contains(hints, "Resources:") {
+		t.Errorf("Expected no resources line when disabled, got:\n%s", hints)
+	}
+}
+
+func TestCache_Resources(t *testing.T) {
+	detect := func() *SystemInfo { return &SystemInfo{Capabilities: map[string]string{}} }
+
+	t.Run("disabled", func(t *testing.T) {
+		c := NewCache(filepath.Join(t.TempDir(), "system-info.json"), 24*time.Hour)
+		c.Detect = detect
+		if info := c.Info(); info.Resources != nil {
+			t.Errorf("Expected no resources when disabled, got %+v", info.Resources)
+		}
+	})
+
+	t.Run("enabled and cached", func(t *testing.T) {
+		c := NewCache(filepath.Join(t.TempDir(), "system-info.json"), 24*time.Hour)
+		c.Detect = detect
+		c.Resources = true
+		if info := c.Info(); info.Resources == nil || info.Resources.CPUs < 1 {
+			t.Fatalf("Expected detected resources, got %+v", info.Resources)
+		}
+		c.Detect = func() *SystemInfo {
+			t.Error("Expected the cached resources to be used")
+			return detect()
+		}
+		if info := c.Info(); info.Resources == nil {
+			t.Errorf("Expected resources from the cache, got none")
+		}
+	})
+
+	t.Run("turning resources on invalidates the cache", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "system-info.json")
+		c := NewCache(path, 24*time.Hour)
+		c.Detect = detect
+		c.Info()
+
+		c.Resources = true
+		if info := c.Info(); info.Resources == nil {
+			t.Errorf("Expected resources to be detected after enabling them")
+		}
+	})
+}
@@ -0,0 +1,185 @@
+// Package transcript records chat sessions as JSON Lines files, one file per
+// session, so they can be read back with 'rag-cli history show'.
+package transcript
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"rag-cli/pkg/paths"
+)
+
+// Event types
+const (
+	User    = "user"    // A message typed by the user
+	AI      = "ai"      // A response or final answer from the model
+	Command = "command" // A command about to run
+	Output  = "output"  // What a command printed
+	Error   = "error"   // A command or model request that failed
+	Summary = "summary" // The recap shown when the session ended
+)
+
+// fileExtension marks transcript files in the transcripts directory
+const fileExtension = ".jsonl"
+
+// Event is one line of a transcript
+type Event struct {
+	Time    time.Time `json:"timestamp"`
+	Type    string    `json:"type"`
+	Content string    `json:"content"`
+}
+
+// Writer appends the events of one session to its own file, created on the
+// first event. A nil *Writer records nothing, so callers need not check
+// whether transcripts are enabled.
+type Writer struct {
+	path      string
+	maxOutput int // Bytes of command output kept per event; 0 keeps it all
+	now       func() time.Time
+	mutex     sync.Mutex
+}
+
+// NewWriter creates a writer for a session starting now, named after its
+// start time, in dir. Command output longer than maxOutput bytes is cut
+// short; 0 keeps it all.
+func NewWriter(dir string, maxOutput int) *Writer {
+	return newWriter(dir, maxOutput, time.Now)
+}
+
+func newWriter(dir string, maxOutput int, now func() time.Time) *Writer {
+	name := now().Format("2006-01-02T15-04-05")
+	path := filepath.Join(dir, name+fileExtension)
+	for i := 2; fileExists(path); i++ {
+		path = filepath.Join(dir, fmt.Sprintf("%s-%d%s", name, i, fileExtension))
+	}
+	return &Writer{path: path, maxOutput: maxOutput, now: now}
+}
+
+// DefaultDir returns the directory transcripts are written to
+func DefaultDir() (string, error) {
+	dirs, err := paths.Default()
+	if err != nil {
+		return "", err
+	}
+	return dirs.DataFile("transcripts"), nil
+}
+
+// Path returns the file the writer appends to
+func (w *Writer) Path() string {
+	if w == nil {
+		return ""
+	}
+	return w.path
+}
+
+// Record appends an event of eventType. Failures are logged rather than
+// returned, so a transcript never interrupts a chat.
+func (w *Writer) Record(eventType, content string) {
+	if w == nil {
+		return
+	}
+	if eventType == Output {
+		content = truncate(content, w.maxOutput)
+	}
+	data, err := json.Marshal(Event{Time: w.now(), Type: eventType, Content: content})
+	if err != nil {
+		return
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if err := w.append(append(data, '\n')); err != nil {
+		slog.Warn("failed to write transcript", "component", "transcript", "path", w.path, "error", err)
+	}
+}
+
+func (w *Writer) append(line []byte) error {
+	if err := os.MkdirAll(filepath.Dir(w.path), 0700); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(w.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(line); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// truncate cuts text to at most max bytes, without splitting a character,
+// and says how much was left out
+func truncate(text string, max int) string {
+	if max <= 0 || len(text) <= max {
+		return text
+	}
+	cut := max
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	return fmt.Sprintf("%s\n... [%d bytes truncated]", text[:cut], len(text)-cut)
+}
+
+// Load reads the events in the transcript at path. Lines that cannot be
+// parsed are skipped.
+func Load(path string) ([]Event, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open transcript: %w", err)
+	}
+	defer file.Close()
+
+	var events []Event
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16<<20)
+	for scanner.Scan() {
+		var event Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil || event.Type == "" {
+			continue
+		}
+		events = append(events, event)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read transcript: %w", err)
+	}
+	return events, nil
+}
+
+// List returns the transcripts in dir, oldest first. A missing directory has
+// none.
+func List(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transcripts directory: %w", err)
+	}
+	var files []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), fileExtension) {
+			files = append(files, filepath.Join(dir, entry.Name()))
+		}
+	}
+	// Names are the session's start time, with -2 and so on added for later
+	// sessions started in the same second, so they sort by age
+	sort.Slice(files, func(i, j int) bool {
+		return strings.TrimSuffix(files[i], fileExtension) < strings.TrimSuffix(files[j], fileExtension)
+	})
+	return files, nil
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
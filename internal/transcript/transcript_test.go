@@ -0,0 +1,111 @@
+package transcript
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriter_RecordAndLoad(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "transcripts")
+	start := time.Date(2026, 10, 1, 9, 30, 0, 0, time.UTC)
+	writer := newWriter(dir, 10, func() time.Time { return start })
+
+	writer.Record(User, "list the Go files")
+	writer.Record(AI, "ls *.go")
+	writer.Record(Command, "ls *.go")
+	writer.Record(Output, "main.go\nserver.go\n")
+
+	if writer.Path() != filepath.Join(dir, "2026-10-01T09-30-00.jsonl") {
+		t.Errorf("Expected the file to be named after the start time, got %s", writer.Path())
+	}
+	info, err := os.Stat(writer.Path())
+	if err != nil {
+		t.Fatalf("Expected the transcript to be written, got: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected the transcript to be private, got %v", info.Mode().Perm())
+	}
+
+	events, err := Load(writer.Path())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(events) != 4 {
+		t.Fatalf("Expected 4 events, got %d", len(events))
+	}
+	if events[0].Type != User || events[0].Content != "list the Go files" || !events[0].Time.Equal(start) {
+		t.Errorf("Expected the user message to round-trip, got %+v", events[0])
+	}
+	if events[3].Content != "main.go\nse\n... [8 bytes truncated]" {
+		t.Errorf("Expected the output to be truncated, got %q", events[3].Content)
+	}
+	if events[2].Content != "ls *.go" {
+		t.Errorf("Expected commands not to be truncated, got %q", events[2].Content)
+	}
+}
+
+func TestNewWriter_OneFilePerSession(t *testing.T) {
+	dir := t.TempDir()
+	now := func() time.Time { return time.Date(2026, 10, 1, 9, 30, 0, 0, time.UTC) }
+
+	first := newWriter(dir, 0, now)
+	first.Record(User, "first")
+	second := newWriter(dir, 0, now)
+	second.Record(User, "second")
+
+	if first.Path() == second.Path() {
+		t.Fatalf("Expected sessions started in the same second to get their own files, got %s", first.Path())
+	}
+	files, err := List(dir)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(files) != 2 || files[0] != first.Path() {
+		t.Errorf("Expected both transcripts oldest first, got %v", files)
+	}
+}
+
+func TestWriter_Nil(t *testing.T) {
+	var writer *Writer
+	writer.Record(User, "hello") // Must not panic
+	if writer.Path() != "" {
+		t.Errorf("Expected no path, got %q", writer.Path())
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		text     string
+		max      int
+		expected string
+	}{
+		{"short", 10, "short"},
+		{"anything", 0, "anything"},
+		{"héllo", 2, "h\n... [5 bytes truncated]"},
+	}
+
+	for _, tt := range tests {
+		if got := truncate(tt.text, tt.max); got != tt.expected {
+			t.Errorf("truncate(%q, %d) = %q, expected %q", tt.text, tt.max, got, tt.expected)
+		}
+	}
+}
+
+func TestLoad_SkipsUnreadableLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.jsonl")
+	data := `{"timestamp":"2026-10-01T09:30:00Z","type":"user","content":"hi"}` + "\nnot json\n"
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatalf("Failed to write transcript: %v", err)
+	}
+
+	events, err := Load(path)
+	if err != nil || len(events) != 1 {
+		t.Errorf("Expected one event, got %v, %v", events, err)
+	}
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.jsonl")); err == nil || !strings.Contains(err.Error(), "failed to open transcript") {
+		t.Errorf("Expected an error for a missing transcript, got: %v", err)
+	}
+}
@@ -0,0 +1,71 @@
+package update
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// semver is a parsed vMAJOR.MINOR.PATCH[-PRERELEASE] version
+type semver struct {
+	major, minor, patch int
+	prerelease          string
+}
+
+// parseSemver parses a version such as v1.2.3, 1.2, or v1.2.3-rc.1. Build
+// metadata after + is ignored.
+func parseSemver(s string) (semver, error) {
+	version := strings.TrimPrefix(strings.TrimSpace(s), "v")
+	version, _, _ = strings.Cut(version, "+")
+	version, prerelease, _ := strings.Cut(version, "-")
+
+	parts := strings.Split(version, ".")
+	if len(parts) == 0 || len(parts) > 3 {
+		return semver{}, fmt.Errorf("invalid version %q", s)
+	}
+	var numbers [3]int
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return semver{}, fmt.Errorf("invalid version %q", s)
+		}
+		numbers[i] = n
+	}
+	return semver{major: numbers[0], minor: numbers[1], patch: numbers[2], prerelease: prerelease}, nil
+}
+
+// less reports whether v precedes other. A prerelease precedes the release
+// with the same number; prereleases are compared as strings.
+func (v semver) less(other semver) bool {
+	if v.major != other.major {
+		return v.major < other.major
+	}
+	if v.minor != other.minor {
+		return v.minor < other.minor
+	}
+	if v.patch != other.patch {
+		return v.patch < other.patch
+	}
+	switch {
+	case v.prerelease == other.prerelease:
+		return false
+	case v.prerelease == "":
+		return false
+	case other.prerelease == "":
+		return true
+	}
+	return v.prerelease < other.prerelease
+}
+
+// Newer reports whether latest is a newer version than current
+func Newer(latest, current string) (bool, error) {
+	latestVersion, err := parseSemver(latest)
+	if err != nil {
+		return false, err
+	}
+	currentVersion, err := parseSemver(current)
+	if err != nil {
+		return false, err
+	}
+	return currentVersion.less(latestVersion), nil
+}
@@ -0,0 +1,131 @@
+// Package update checks GitHub for newer releases of rag-cli
+package update
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"rag-cli/pkg/paths"
+)
+
+// DefaultReleasesURL is the GitHub API endpoint for the latest release
+const DefaultReleasesURL = "https://api.github.com/repos/wyattfry/rag-cli/releases/latest"
+
+// Release is the latest published release
+type Release struct {
+	Version string `json:"tag_name"`
+	URL     string `json:"html_url"`
+}
+
+// cacheEntry records the last check so passive checks run at most once per
+// interval. A failed check is recorded with no release.
+type cacheEntry struct {
+	CheckedAt time.Time `json:"checked_at"`
+	Release   *Release  `json:"release,omitempty"`
+}
+
+// Checker looks up the latest release
+type Checker struct {
+	ReleasesURL string
+	Client      *http.Client
+	// CachePath is where the last result is stored; empty disables the cache
+	CachePath string
+	Now       func() time.Time
+}
+
+// NewChecker creates a checker for the rag-cli releases with a short timeout,
+// so a slow network never holds up a command for long
+func NewChecker(cachePath string) *Checker {
+	return &Checker{
+		ReleasesURL: DefaultReleasesURL,
+		Client:      &http.Client{Timeout: 3 * time.Second},
+		CachePath:   cachePath,
+		Now:         time.Now,
+	}
+}
+
+// DefaultCachePath returns the location of the update check cache
+func DefaultCachePath() (string, error) {
+	dirs, err := paths.Default()
+	if err != nil {
+		return "", err
+	}
+	return dirs.DataFile("update-check.json"), nil
+}
+
+// Latest fetches the latest release and records the result in the cache
+func (c *Checker) Latest() (*Release, error) {
+	release, err := c.fetch()
+	c.saveCache(cacheEntry{CheckedAt: c.Now(), Release: release})
+	return release, err
+}
+
+// Due reports whether no check has been recorded within interval, so a
+// passive check should be made
+func (c *Checker) Due(interval time.Duration) bool {
+	entry, ok := c.loadCache()
+	return !ok || c.Now().Sub(entry.CheckedAt) >= interval
+}
+
+// fetch asks the releases API for the latest release
+func (c *Checker) fetch() (*Release, error) {
+	req, err := http.NewRequest(http.MethodGet, c.ReleasesURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach GitHub: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	var release Release
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return nil, fmt.Errorf("failed to parse release: %w", err)
+	}
+	if release.Version == "" {
+		return nil, fmt.Errorf("release has no tag")
+	}
+	return &release, nil
+}
+
+func (c *Checker) loadCache() (cacheEntry, bool) {
+	var entry cacheEntry
+	if c.CachePath == "" {
+		return entry, false
+	}
+	data, err := os.ReadFile(c.CachePath)
+	if err != nil {
+		return entry, false
+	}
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return entry, false
+	}
+	return entry, true
+}
+
+// saveCache stores the result of a check. The cache only saves requests, so
+// failures to write it are ignored.
+func (c *Checker) saveCache(entry cacheEntry) {
+	if c.CachePath == "" {
+		return
+	}
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(c.CachePath), 0755); err != nil {
+		return
+	}
+	_ = os.WriteFile(c.CachePath, data, 0644)
+}
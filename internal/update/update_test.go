@@ -0,0 +1,97 @@
+package update
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNewer(t *testing.T) {
+	tests := []struct {
+		latest, current string
+		want            bool
+	}{
+		{"v0.2.0", "v0.1.0", true},
+		{"v0.1.1", "v0.1.0", true},
+		{"v1.0.0", "v0.9.9", true},
+		{"v0.1.0", "v0.1.0", false},
+		{"v0.1.0", "v0.2.0", false},
+		{"v0.10.0", "v0.9.0", true},
+		{"v1.0.0", "v1.0.0-rc.1", true},
+		{"v1.0.0-rc.2", "v1.0.0-rc.1", true},
+		{"1.2", "v1.1.9", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.latest+" vs "+tt.current, func(t *testing.T) {
+			got, err := Newer(tt.latest, tt.current)
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	if _, err := Newer("v1.0.0", "abc1234"); err == nil {
+		t.Error("Expected an error for a version that is not semver")
+	}
+}
+
+func TestChecker(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`{"tag_name": "v0.3.0", "html_url": "https://github.com/wyattfry/rag-cli/releases/tag/v0.3.0"}`))
+	}))
+	defer server.Close()
+
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	checker := NewChecker(filepath.Join(t.TempDir(), "update-check.json"))
+	checker.ReleasesURL = server.URL
+	checker.Now = func() time.Time { return now }
+
+	if !checker.Due(24 * time.Hour) {
+		t.Fatal("Expected a check to be due before any has been made")
+	}
+
+	release, err := checker.Latest()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if release.Version != "v0.3.0" || release.URL == "" {
+		t.Errorf("Unexpected release: %+v", release)
+	}
+
+	now = now.Add(time.Hour)
+	if checker.Due(24 * time.Hour) {
+		t.Error("Expected no check to be due an hour after the last one")
+	}
+	now = now.Add(24 * time.Hour)
+	if !checker.Due(24 * time.Hour) {
+		t.Error("Expected a check to be due a day after the last one")
+	}
+	if requests != 1 {
+		t.Errorf("Expected 1 request, got %d", requests)
+	}
+}
+
+func TestChecker_Errors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "rate limited", http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	checker := NewChecker(filepath.Join(t.TempDir(), "update-check.json"))
+	checker.ReleasesURL = server.URL
+
+	if _, err := checker.Latest(); err == nil {
+		t.Fatal("Expected an error for a failed request")
+	}
+	if checker.Due(24 * time.Hour) {
+		t.Error("Expected a failed check to be recorded so it is not retried immediately")
+	}
+}
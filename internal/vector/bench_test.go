@@ -0,0 +1,58 @@
+package vector
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"rag-cli/internal/fakeserver"
+)
+
+// benchDocument returns a chunk-sized document and an embedding the size of
+// nomic-embed-text's
+func benchDocument() (string, []float32) {
+	embedding := make([]float32, 768)
+	for i := range embedding {
+		embedding[i] = float32(i) / 768
+	}
+	return strings.Repeat("The quick brown fox jumps over the lazy dog. ", 22), embedding
+}
+
+// BenchmarkAddRequestBody measures building the body of an add request for
+// one document and for batches
+func BenchmarkAddRequestBody(b *testing.B) {
+	content, embedding := benchDocument()
+	for _, size := range []int{1, 32, 256} {
+		doc := Document{}
+		for i := 0; i < size; i++ {
+			doc.IDs = append(doc.IDs, generateUUID())
+			doc.Documents = append(doc.Documents, content)
+			doc.Embeddings = append(doc.Embeddings, embedding)
+			doc.Metadatas = append(doc.Metadatas, map[string]interface{}{"source_path": "docs/guide.md", "chunk_index": i})
+		}
+		b.Run(fmt.Sprintf("batch=%d", size), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := json.Marshal(doc); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkAddDocument measures a whole add against a fake ChromaDB server
+func BenchmarkAddDocument(b *testing.B) {
+	server := fakeserver.NewChroma(b)
+	client := newFakeChromaClient(b, server, 0)
+	content, embedding := benchDocument()
+	metadata := map[string]interface{}{"source_path": "docs/guide.md", "chunk_index": 0}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := client.AddDocumentWithMetadata("documents", "", content, embedding, metadata); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
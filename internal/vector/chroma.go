@@ -2,48 +2,87 @@ package vector
 
 import (
 	"bytes"
+	"context"
 	"crypto/rand"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"sync"
 	"time"
 
+	"rag-cli/internal/httpclient"
 	"rag-cli/pkg/config"
 )
 
+// ChromaClient is a VectorStore backed by a ChromaDB server. It is safe for
+// concurrent use once NewChromaClient returns: the cached collection IDs are
+// guarded by mu, and the http.Client is shared by every request.
+//
+// The client speaks the v1 REST API or the v2 one, whose collections live
+// under a tenant and database. The version is detected, or the configured
+// one checked, with a heartbeat before the first request.
 type ChromaClient struct {
 	baseURL     string
 	client      *http.Client
 	collections map[string]string // collection name -> collection ID mapping
+	mu          sync.RWMutex      // guards collections once the client is in use
 	config      config.VectorConfig // store config for collection names
+	apiVersion  string              // v1 or v2 once known, "" until detected
+	apiMu       sync.Mutex          // guards apiVersion
 }
 
 type Collection struct {
-	ID   string `json:"id"`
-	Name string `json:"name"`
+	ID          string `json:"id,omitempty"`
+	Name        string `json:"name"`
+	GetOrCreate bool   `json:"get_or_create,omitempty"` // Return the collection if it already exists
 }
 
 type CollectionResponse struct {
-	ID   string `json:"id"`
-	Name string `json:"name"`
+	ID        string                 `json:"id"`
+	Name      string                 `json:"name"`
+	Dimension int                    `json:"dimension,omitempty"`
+	Metadata  map[string]interface{} `json:"metadata,omitempty"`
 }
 
 type Document struct {
 	IDs       []string    `json:"ids"`
 	Documents []string    `json:"documents"`
 	Embeddings [][]float32 `json:"embeddings"`
+	Metadatas  []map[string]interface{} `json:"metadatas,omitempty"`
 }
 
 type QueryRequest struct {
 	QueryEmbeddings [][]float32 `json:"query_embeddings"`
 	NResults        int         `json:"n_results"`
+	Where           map[string]interface{} `json:"where,omitempty"`
 }
 
 type QueryResponse struct {
-	IDs       [][]string    `json:"ids"`
-	Documents [][]string    `json:"documents"`
-	Distances [][]float32   `json:"distances"`
+	IDs       [][]string                   `json:"ids"`
+	Documents [][]string                   `json:"documents"`
+	Distances [][]float32                  `json:"distances"`
+	Metadatas [][]map[string]interface{}   `json:"metadatas"`
+}
+
+type GetRequest struct {
+	IDs     []string `json:"ids,omitempty"`
+	Limit   int      `json:"limit,omitempty"`
+	Offset  int      `json:"offset,omitempty"`
+	Include []string `json:"include"`
+}
+
+type GetResponse struct {
+	IDs        []string                 `json:"ids"`
+	Documents  []string                 `json:"documents"`
+	Metadatas  []map[string]interface{} `json:"metadatas"`
+	Embeddings [][]float32              `json:"embeddings"`
+}
+
+type DeleteRequest struct {
+	IDs []string `json:"ids"`
 }
 
 // generateUUID generates a simple UUID for ChromaDB
@@ -59,15 +98,8 @@ func generateUUID() string {
 	return fmt.Sprintf("%08x-%04x-%04x-%04x-%012x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
 }
 
-func NewChromaClient(cfg config.VectorConfig) (*ChromaClient, error) {
-	client := &ChromaClient{
-		baseURL:     fmt.Sprintf("http://%s:%d", cfg.Host, cfg.Port),
-		collections: make(map[string]string),
-		config:      cfg,
-		client: &http.Client{
-			Timeout: 30 * time.Second,
-		},
-	}
+func NewChromaClient(cfg config.VectorConfig, timeouts config.TimeoutsConfig) (*ChromaClient, error) {
+	client := NewLazyChromaClient(cfg, timeouts)
 
 	// Initialize all collections
 	collectionNames := []string{
@@ -76,6 +108,9 @@ func NewChromaClient(cfg config.VectorConfig) (*ChromaClient, error) {
 		cfg.AutoIndexCollection,
 	}
 
+	if _, err := client.collectionsURL(""); err != nil {
+		return nil, err
+	}
 	for _, name := range collectionNames {
 		if err := client.createCollection(name); err != nil {
 			return nil, fmt.Errorf("failed to create collection %s: %w", name, err)
@@ -85,6 +120,45 @@ func NewChromaClient(cfg config.VectorConfig) (*ChromaClient, error) {
 	return client, nil
 }
 
+// NewLazyChromaClient creates a client without contacting the server.
+// Collections are looked up, or created, on first use, so a client created
+// while ChromaDB is down starts working once the server is back.
+func NewLazyChromaClient(cfg config.VectorConfig, timeouts config.TimeoutsConfig) *ChromaClient {
+	return &ChromaClient{
+		baseURL:     cfg.URL(),
+		collections: make(map[string]string),
+		config:      cfg,
+		client:      httpclient.New(timeouts.Vector, timeouts),
+	}
+}
+
+// collectionsURL returns the URL of the collections endpoint followed by
+// path, such as "/<id>/add". With the v2 API, collections are under the
+// configured tenant and database.
+func (c *ChromaClient) collectionsURL(path string) (string, error) {
+	c.apiMu.Lock()
+	defer c.apiMu.Unlock()
+	if c.apiVersion == "" {
+		version, err := detectAPIVersion(c.client, c.baseURL, c.config.APIVersion)
+		if err != nil {
+			return "", err
+		}
+		c.apiVersion = version
+	}
+
+	if c.apiVersion == config.ChromaAPIV1 {
+		return c.baseURL + "/api/v1/collections" + path, nil
+	}
+	tenant, database := c.config.Tenant, c.config.Database
+	if tenant == "" {
+		tenant = config.DefaultChromaTenant
+	}
+	if database == "" {
+		database = config.DefaultChromaDatabase
+	}
+	return fmt.Sprintf("%s/api/v2/tenants/%s/databases/%s/collections%s", c.baseURL, url.PathEscape(tenant), url.PathEscape(database), path), nil
+}
+
 func (c *ChromaClient) createCollection(name string) error {
 	// First try to find existing collection
 	if id, err := c.findCollection(name); err == nil {
@@ -92,22 +166,25 @@ func (c *ChromaClient) createCollection(name string) error {
 		return nil // Collection found
 	}
 
-	// Create new collection
-	collection := Collection{Name: name}
+	// Create new collection, or take one created since it was looked up
+	collection := Collection{Name: name, GetOrCreate: true}
 	reqBody, err := json.Marshal(collection)
 	if err != nil {
 		return fmt.Errorf("failed to marshal collection: %w", err)
 	}
 
-	resp, err := http.Post(c.baseURL+"/api/v1/collections", "application/json", bytes.NewBuffer(reqBody))
+	collectionsURL, err := c.collectionsURL("")
+	if err != nil {
+		return err
+	}
+	resp, err := c.client.Post(collectionsURL, "application/json", bytes.NewBuffer(reqBody))
 	if err != nil {
-		return fmt.Errorf("failed to create collection: %w", err)
+		return fmt.Errorf("failed to create collection: %w", httpclient.Classify(err))
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("unexpected status code: %d, body: %s", resp.StatusCode, string(body))
+		return httpclient.NewStatusError(resp)
 	}
 
 	// Get the collection ID from response
@@ -126,44 +203,173 @@ func (c *ChromaClient) createCollection(name string) error {
 }
 
 func (c *ChromaClient) findCollection(name string) (string, error) {
-	resp, err := http.Get(c.baseURL + "/api/v1/collections")
+	collections, err := c.ListCollections()
 	if err != nil {
-		return "", fmt.Errorf("failed to get collections: %w", err)
+		return "", err
+	}
+
+	// Find collection by name
+	for _, col := range collections {
+		if col.Name == name {
+			return col.ID, nil
+		}
+	}
+
+	return "", fmt.Errorf("%w: %s", ErrCollectionNotFound, name)
+}
+
+// statusError describes an unexpected response to a request on a collection.
+// A 404 means the collection no longer exists, for example because it was
+// reset by another process, so its cached ID is dropped.
+func (c *ChromaClient) statusError(collectionName string, resp *http.Response) error {
+	err := httpclient.NewStatusError(resp)
+	if resp.StatusCode != http.StatusNotFound {
+		return err
+	}
+	c.mu.Lock()
+	delete(c.collections, collectionName)
+	c.mu.Unlock()
+	return fmt.Errorf("%w: %s (%w)", ErrCollectionNotFound, collectionName, err)
+}
+
+// ListCollections returns every collection known to the ChromaDB server,
+// including ones not referenced by the current configuration
+func (c *ChromaClient) ListCollections() ([]CollectionInfo, error) {
+	collectionsURL, err := c.collectionsURL("")
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Get(collectionsURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get collections: %w", httpclient.Classify(err))
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return nil, httpclient.NewStatusError(resp)
 	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("failed to read response: %w", err)
+		return nil, fmt.Errorf("failed to read response: %w", err)
 	}
 
 	var collections []CollectionResponse
 	if err := json.Unmarshal(body, &collections); err != nil {
-		return "", fmt.Errorf("failed to unmarshal response: %w", err)
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
 
-	// Find collection by name
+	infos := make([]CollectionInfo, 0, len(collections))
 	for _, col := range collections {
-		if col.Name == name {
-			return col.ID, nil
-		}
+		infos = append(infos, CollectionInfo{
+			Name:      col.Name,
+			ID:        col.ID,
+			Dimension: col.Dimension,
+			Metadata:  col.Metadata,
+		})
+	}
+	return infos, nil
+}
+
+// Count returns the number of documents stored in a collection
+func (c *ChromaClient) Count(collectionName string) (int, error) {
+	collectionID, err := c.collectionID(collectionName)
+	if err != nil {
+		return 0, err
+	}
+
+	countURL, err := c.collectionsURL("/" + collectionID + "/count")
+	if err != nil {
+		return 0, err
+	}
+	resp, err := c.client.Get(countURL)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count documents: %w", httpclient.Classify(err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, c.statusError(collectionName, resp)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var count int
+	if err := json.Unmarshal(body, &count); err != nil {
+		return 0, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return count, nil
+}
+
+// collectionID resolves a collection name to its ChromaDB ID, looking up
+// collections that were not created at startup on the server. The configured
+// collections, which NewChromaClient creates, are created if missing.
+func (c *ChromaClient) collectionID(name string) (string, error) {
+	c.mu.RLock()
+	id, exists := c.collections[name]
+	c.mu.RUnlock()
+	if exists {
+		return id, nil
+	}
+
+	id, err := c.findCollection(name)
+	if errors.Is(err, ErrCollectionNotFound) && c.configured(name) {
+		return c.ensureCollection(name)
+	}
+	if err != nil {
+		return "", err
+	}
+	c.mu.Lock()
+	c.collections[name] = id
+	c.mu.Unlock()
+	return id, nil
+}
+
+// configured reports whether name is one of the collections in the config
+func (c *ChromaClient) configured(name string) bool {
+	return name == c.config.Collection || name == c.config.CommandCollection || name == c.config.AutoIndexCollection
+}
+
+// ensureCollection resolves a collection name to its ID, creating the
+// collection on first use so documents can be added to any collection
+func (c *ChromaClient) ensureCollection(name string) (string, error) {
+	c.mu.RLock()
+	id, exists := c.collections[name]
+	c.mu.RUnlock()
+	if exists {
+		return id, nil
 	}
 
-	return "", fmt.Errorf("collection %s not found", name)
+	// Check again under the write lock, so concurrent adds to a new
+	// collection create it only once
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if id, exists := c.collections[name]; exists {
+		return id, nil
+	}
+	if err := c.createCollection(name); err != nil {
+		return "", fmt.Errorf("failed to create collection %s: %w", name, err)
+	}
+	return c.collections[name], nil
 }
 
 func (c *ChromaClient) AddDocument(collectionName, id, content string, embedding []float32) error {
+	return c.AddDocumentWithMetadata(collectionName, id, content, embedding, nil)
+}
+
+// AddDocumentWithMetadata stores a document together with metadata such as
+// its source. A nil metadata map stores the document without metadata.
+func (c *ChromaClient) AddDocumentWithMetadata(collectionName, id, content string, embedding []float32, metadata map[string]interface{}) error {
 	if id == "" {
 		id = generateUUID()
 	}
 	
-	collectionID, exists := c.collections[collectionName]
-	if !exists {
-		return fmt.Errorf("collection %s not found", collectionName)
+	collectionID, err := c.ensureCollection(collectionName)
+	if err != nil {
+		return err
 	}
 	
 	doc := Document{
@@ -171,22 +377,27 @@ func (c *ChromaClient) AddDocument(collectionName, id, content string, embedding
 		Documents:  []string{content},
 		Embeddings: [][]float32{embedding},
 	}
+	if metadata != nil {
+		doc.Metadatas = []map[string]interface{}{metadata}
+	}
 
 	reqBody, err := json.Marshal(doc)
 	if err != nil {
 		return fmt.Errorf("failed to marshal document: %w", err)
 	}
 
-	url := fmt.Sprintf("%s/api/v1/collections/%s/add", c.baseURL, collectionID)
-	resp, err := http.Post(url, "application/json", bytes.NewBuffer(reqBody))
+	addURL, err := c.collectionsURL("/" + collectionID + "/add")
 	if err != nil {
-		return fmt.Errorf("failed to add document: %w", err)
+		return err
+	}
+	resp, err := c.client.Post(addURL, "application/json", bytes.NewBuffer(reqBody))
+	if err != nil {
+		return fmt.Errorf("failed to add document: %w", httpclient.Classify(err))
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("unexpected status code: %d, body: %s", resp.StatusCode, string(body))
+		return c.statusError(collectionName, resp)
 	}
 
 	return nil
@@ -199,14 +410,55 @@ func (c *ChromaClient) Search(query string, numResults int) ([]string, error) {
 }
 
 func (c *ChromaClient) SearchWithEmbedding(collectionName string, queryEmbedding []float32, numResults int) ([]string, error) {
-	collectionID, exists := c.collections[collectionName]
-	if !exists {
-		return nil, fmt.Errorf("collection %s not found", collectionName)
+	return c.SearchWithEmbeddingContext(context.Background(), collectionName, queryEmbedding, numResults)
+}
+
+// SearchWithEmbeddingContext is SearchWithEmbedding with a context;
+// cancelling ctx aborts the query
+func (c *ChromaClient) SearchWithEmbeddingContext(ctx context.Context, collectionName string, queryEmbedding []float32, numResults int) ([]string, error) {
+	results, err := c.SearchWithScoresContext(ctx, collectionName, queryEmbedding, numResults)
+	if err != nil {
+		return nil, err
+	}
+
+	documents := make([]string, 0, len(results))
+	for _, result := range results {
+		documents = append(documents, result.Document)
+	}
+	return documents, nil
+}
+
+// SearchWithScores queries a collection and returns ranked results including
+// their IDs, distances, and metadata
+func (c *ChromaClient) SearchWithScores(collectionName string, queryEmbedding []float32, numResults int) ([]SearchResult, error) {
+	return c.SearchWithScoresContext(context.Background(), collectionName, queryEmbedding, numResults)
+}
+
+// SearchWithScoresContext is SearchWithScores with a context; cancelling ctx
+// aborts the query
+func (c *ChromaClient) SearchWithScoresContext(ctx context.Context, collectionName string, queryEmbedding []float32, numResults int) ([]SearchResult, error) {
+	return c.SearchWithFilterContext(ctx, collectionName, queryEmbedding, numResults, nil)
+}
+
+// SearchWithFilter is SearchWithScores limited to documents whose metadata
+// matches where, a ChromaDB where filter such as
+// {"source_path": "docs/setup.md"}. A nil filter searches every document.
+func (c *ChromaClient) SearchWithFilter(collectionName string, queryEmbedding []float32, numResults int, where map[string]interface{}) ([]SearchResult, error) {
+	return c.SearchWithFilterContext(context.Background(), collectionName, queryEmbedding, numResults, where)
+}
+
+// SearchWithFilterContext is SearchWithFilter with a context; cancelling ctx
+// aborts the query
+func (c *ChromaClient) SearchWithFilterContext(ctx context.Context, collectionName string, queryEmbedding []float32, numResults int, where map[string]interface{}) ([]SearchResult, error) {
+	collectionID, err := c.collectionID(collectionName)
+	if err != nil {
+		return nil, err
 	}
 	
 	queryReq := QueryRequest{
 		QueryEmbeddings: [][]float32{queryEmbedding},
 		NResults:        numResults,
+		Where:           where,
 	}
 
 	reqBody, err := json.Marshal(queryReq)
@@ -214,15 +466,23 @@ func (c *ChromaClient) SearchWithEmbedding(collectionName string, queryEmbedding
 		return nil, fmt.Errorf("failed to marshal query: %w", err)
 	}
 
-	url := fmt.Sprintf("%s/api/v1/collections/%s/query", c.baseURL, collectionID)
-	resp, err := http.Post(url, "application/json", bytes.NewBuffer(reqBody))
+	queryURL, err := c.collectionsURL("/" + collectionID + "/query")
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, queryURL, bytes.NewBuffer(reqBody))
 	if err != nil {
-		return nil, fmt.Errorf("failed to query: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	resp, err := c.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query: %w", httpclient.Classify(err))
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return nil, c.statusError(collectionName, resp)
 	}
 
 	body, err := io.ReadAll(resp.Body)
@@ -235,12 +495,211 @@ func (c *ChromaClient) SearchWithEmbedding(collectionName string, queryEmbedding
 		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
 
-	var results []string
-	if len(queryResp.Documents) > 0 {
-		results = queryResp.Documents[0]
+	return withinDistance(queryResp.results(), c.config.MaxDistance), nil
+}
+
+// withinDistance drops the results farther than maxDistance from the query,
+// keeping them all when maxDistance is 0. Results are ranked closest first,
+// so the rest are dropped from the first that is too far.
+func withinDistance(results []SearchResult, maxDistance float64) []SearchResult {
+	if maxDistance <= 0 {
+		return results
+	}
+	for i, result := range results {
+		if float64(result.Distance) > maxDistance {
+			return results[:i]
+		}
+	}
+	return results
+}
+
+// results flattens the first query's columns into ranked SearchResults
+func (r QueryResponse) results() []SearchResult {
+	if len(r.Documents) == 0 {
+		return nil
+	}
+
+	results := make([]SearchResult, 0, len(r.Documents[0]))
+	for i, doc := range r.Documents[0] {
+		result := SearchResult{Document: doc}
+		if len(r.IDs) > 0 && i < len(r.IDs[0]) {
+			result.ID = r.IDs[0][i]
+		}
+		if len(r.Distances) > 0 && i < len(r.Distances[0]) {
+			result.Distance = r.Distances[0][i]
+		}
+		if len(r.Metadatas) > 0 && i < len(r.Metadatas[0]) {
+			result.Metadata = r.Metadatas[0][i]
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// GetDocuments returns stored documents and their metadata from a collection.
+// A limit of 0 returns every document.
+func (c *ChromaClient) GetDocuments(collectionName string, limit int) ([]StoredDocument, error) {
+	return c.get(collectionName, GetRequest{Limit: limit})
+}
+
+// GetDocument returns a single stored document by ID, or nil if the
+// collection has no document with that ID
+func (c *ChromaClient) GetDocument(collectionName, id string) (*StoredDocument, error) {
+	documents, err := c.get(collectionName, GetRequest{IDs: []string{id}})
+	if err != nil {
+		return nil, err
+	}
+	if len(documents) == 0 {
+		return nil, nil
+	}
+	return &documents[0], nil
+}
+
+// ExportDocuments returns a page of documents with their metadata and
+// embeddings, starting at offset. A limit of 0 returns every remaining document.
+func (c *ChromaClient) ExportDocuments(collectionName string, offset, limit int) ([]StoredDocument, error) {
+	return c.get(collectionName, GetRequest{Offset: offset, Limit: limit, Include: []string{"documents", "metadatas", "embeddings"}})
+}
+
+// get fetches documents and metadata matching getReq from a collection
+func (c *ChromaClient) get(collectionName string, getReq GetRequest) ([]StoredDocument, error) {
+	collectionID, err := c.collectionID(collectionName)
+	if err != nil {
+		return nil, err
+	}
+
+	if getReq.Include == nil {
+		getReq.Include = []string{"documents", "metadatas"}
+	}
+
+	reqBody, err := json.Marshal(getReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	getURL, err := c.collectionsURL("/" + collectionID + "/get")
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Post(getURL, "application/json", bytes.NewBuffer(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get documents: %w", httpclient.Classify(err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, c.statusError(collectionName, resp)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
 	}
 
-	return results, nil
+	var getResp GetResponse
+	if err := json.Unmarshal(body, &getResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	documents := make([]StoredDocument, 0, len(getResp.IDs))
+	for i, id := range getResp.IDs {
+		doc := StoredDocument{ID: id}
+		if i < len(getResp.Documents) {
+			doc.Document = getResp.Documents[i]
+		}
+		if i < len(getResp.Metadatas) {
+			doc.Metadata = getResp.Metadatas[i]
+		}
+		if i < len(getResp.Embeddings) {
+			doc.Embedding = getResp.Embeddings[i]
+		}
+		documents = append(documents, doc)
+	}
+	return documents, nil
+}
+
+// DeleteDocuments removes documents by ID from a collection. An empty ID list
+// is a no-op rather than a request that could match everything.
+func (c *ChromaClient) DeleteDocuments(collectionName string, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	collectionID, err := c.collectionID(collectionName)
+	if err != nil {
+		return err
+	}
+
+	reqBody, err := json.Marshal(DeleteRequest{IDs: ids})
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	deleteURL, err := c.collectionsURL("/" + collectionID + "/delete")
+	if err != nil {
+		return err
+	}
+	resp, err := c.client.Post(deleteURL, "application/json", bytes.NewBuffer(reqBody))
+	if err != nil {
+		return fmt.Errorf("failed to delete documents: %w", httpclient.Classify(err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return c.statusError(collectionName, resp)
+	}
+
+	return nil
+}
+
+// ResetCollection deletes a collection and everything in it, then creates it
+// again empty under the same name
+func (c *ChromaClient) ResetCollection(name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.deleteCollection(name); err != nil {
+		return err
+	}
+	if err := c.createCollection(name); err != nil {
+		return fmt.Errorf("failed to recreate collection %s: %w", name, err)
+	}
+	return nil
+}
+
+// DeleteCollection removes a collection and everything in it. A configured
+// collection is created again, empty, the next time it is used.
+func (c *ChromaClient) DeleteCollection(name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.deleteCollection(name)
+}
+
+// deleteCollection removes a collection and forgets its ID. A collection that
+// does not exist counts as deleted. c.mu must be held.
+func (c *ChromaClient) deleteCollection(name string) error {
+	collectionURL, err := c.collectionsURL("/" + url.PathEscape(name))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodDelete, collectionURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to delete collection: %w", httpclient.Classify(err))
+	}
+	defer resp.Body.Close()
+
+	// A collection that does not exist is already gone
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return httpclient.NewStatusError(resp)
+	}
+
+	delete(c.collections, name)
+	return nil
 }
 
 // Helper methods to get collection names
@@ -0,0 +1,664 @@
+package vector
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"rag-cli/internal/fakeserver"
+	"rag-cli/internal/httpclient"
+	"rag-cli/pkg/config"
+)
+
+func TestNewChromaClient_Timeouts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"id":"1","name":"documents"},{"id":"2","name":"command_history"},{"id":"3","name":"auto_indexed"}]`))
+	}))
+	defer server.Close()
+
+	cfg := config.VectorConfig{
+		BaseURL:             server.URL,
+		Collection:          "documents",
+		CommandCollection:   "command_history",
+		AutoIndexCollection: "auto_indexed",
+	}
+	client, err := NewChromaClient(cfg, config.TimeoutsConfig{Vector: 20 * time.Second, TLSHandshake: 7 * time.Second})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if client.client.Timeout != 20*time.Second {
+		t.Errorf("Expected request timeout 20s, got %s", client.client.Timeout)
+	}
+	if transport := client.client.Transport.(*http.Transport); transport.TLSHandshakeTimeout != 7*time.Second {
+		t.Errorf("Expected TLS handshake timeout 7s, got %s", transport.TLSHandshakeTimeout)
+	}
+}
+
+func TestNewChromaServer_Timeouts(t *testing.T) {
+	tests := []struct {
+		name     string
+		vector   time.Duration
+		expected time.Duration
+	}{
+		{name: "capped for quick diagnosis", vector: 30 * time.Second, expected: probeTimeout},
+		{name: "shorter vector timeout", vector: 2 * time.Second, expected: 2 * time.Second},
+		{name: "no vector timeout", vector: 0, expected: probeTimeout},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := NewChromaServer(config.VectorConfig{Host: "localhost", Port: 8000}, config.TimeoutsConfig{Vector: tt.vector})
+			if server.client.Timeout != tt.expected {
+				t.Errorf("Expected timeout %s, got %s", tt.expected, server.client.Timeout)
+			}
+		})
+	}
+}
+
+// newFakeChromaClient connects a client to a fake ChromaDB server
+func newFakeChromaClient(t testing.TB, server *fakeserver.Chroma, timeout time.Duration) *ChromaClient {
+	t.Helper()
+	client, err := NewChromaClient(config.VectorConfig{
+		BaseURL:             server.URL,
+		Collection:          "documents",
+		CommandCollection:   "command_history",
+		AutoIndexCollection: "auto_indexed",
+	}, config.TimeoutsConfig{Vector: timeout})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	return client
+}
+
+func TestNewChromaClient_Collections(t *testing.T) {
+	server := fakeserver.NewChroma(t)
+	existing := server.AddCollection("documents")
+
+	client := newFakeChromaClient(t, server, 0)
+
+	if got := strings.Join(server.Collections(), ","); got != "documents,command_history,auto_indexed" {
+		t.Errorf("Expected the missing collections to be created, got %s", got)
+	}
+	if len(server.Requests("/api/v1/collections")) != 5 {
+		t.Errorf("Expected 3 lookups and 2 creations, got %d requests", len(server.Requests("/api/v1/collections")))
+	}
+	if client.collections["documents"] != existing {
+		t.Errorf("Expected the existing collection %s to be reused, got %s", existing, client.collections["documents"])
+	}
+	if client.DocumentsCollection() != "documents" || client.CommandsCollection() != "command_history" || client.AutoIndexCollection() != "auto_indexed" {
+		t.Errorf("Expected the configured collection names, got %s, %s, %s", client.DocumentsCollection(), client.CommandsCollection(), client.AutoIndexCollection())
+	}
+}
+
+func TestChromaClient_Contract(t *testing.T) {
+	servers := []struct {
+		name        string
+		start       func(testing.TB) *fakeserver.Chroma
+		collections string
+	}{
+		{"v1", fakeserver.NewChroma, "/api/v1/collections"},
+		{"v2", fakeserver.NewChromaV2, "/api/v2/tenants/default_tenant/databases/default_database/collections"},
+	}
+
+	for _, api := range servers {
+		t.Run(api.name, func(t *testing.T) {
+			server := api.start(t)
+			client := newFakeChromaClient(t, server, 0)
+
+			if err := client.AddDocument("documents", "a", "alpha", []float32{0, 0}); err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if err := client.AddDocumentWithMetadata("documents", "b", "beta", []float32{1, 0}, map[string]interface{}{"source": "b.md"}); err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if err := client.AddDocument("documents", "", "gamma", []float32{3, 0}); err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+
+			t.Run("AddDocument", func(t *testing.T) {
+				req, _ := server.LastRequest("/add")
+				var body Document
+				if err := req.Decode(&body); err != nil {
+					t.Fatalf("Expected a JSON request body, got: %v", err)
+				}
+				if len(body.IDs) != 1 || len(body.IDs[0]) != 36 || body.Documents[0] != "gamma" || body.Metadatas != nil {
+					t.Errorf("Expected a generated UUID and no metadata, got %+v", body)
+				}
+			})
+
+			t.Run("Count", func(t *testing.T) {
+				count, err := client.Count("documents")
+				if err != nil {
+					t.Fatalf("Expected no error, got: %v", err)
+				}
+				if count != 3 {
+					t.Errorf("Expected 3 documents, got %d", count)
+				}
+			})
+
+			t.Run("ListCollections", func(t *testing.T) {
+				collections, err := client.ListCollections()
+				if err != nil {
+					t.Fatalf("Expected no error, got: %v", err)
+				}
+				if len(collections) != 3 || collections[0].Name != "documents" || collections[0].ID == "" {
+					t.Errorf("Expected the three collections with IDs, got %+v", collections)
+				}
+			})
+
+			t.Run("SearchWithScores", func(t *testing.T) {
+				results, err := client.SearchWithScores("documents", []float32{0.9, 0}, 2)
+				if err != nil {
+					t.Fatalf("Expected no error, got: %v", err)
+				}
+				if len(results) != 2 || results[0].ID != "b" || results[1].ID != "a" {
+					t.Fatalf("Expected b then a, got %+v", results)
+				}
+				if results[0].Metadata["source"] != "b.md" || results[0].Distance >= results[1].Distance {
+					t.Errorf("Expected metadata and increasing distances, got %+v", results)
+				}
+
+				req, _ := server.LastRequest("/query")
+				var body QueryRequest
+				req.Decode(&body)
+				if body.NResults != 2 || len(body.QueryEmbeddings) != 1 {
+					t.Errorf("Expected one query embedding and n_results 2, got %+v", body)
+				}
+			})
+
+			t.Run("SearchWithFilter", func(t *testing.T) {
+				results, err := client.SearchWithFilter("documents", []float32{0, 0}, 2, map[string]interface{}{"source": "b.md"})
+				if err != nil {
+					t.Fatalf("Expected no error, got: %v", err)
+				}
+				if len(results) != 1 || results[0].ID != "b" || results[0].Metadata["source"] != "b.md" {
+					t.Errorf("Expected only b with its metadata, got %+v", results)
+				}
+
+				req, _ := server.LastRequest("/query")
+				var body QueryRequest
+				req.Decode(&body)
+				if body.Where["source"] != "b.md" {
+					t.Errorf("Expected the where filter to be sent, got %+v", body)
+				}
+			})
+
+			t.Run("SearchWithEmbedding", func(t *testing.T) {
+				documents, err := client.SearchWithEmbedding("documents", []float32{0, 0}, 1)
+				if err != nil {
+					t.Fatalf("Expected no error, got: %v", err)
+				}
+				if len(documents) != 1 || documents[0] != "alpha" {
+					t.Errorf("Expected [alpha], got %v", documents)
+				}
+			})
+
+			t.Run("Search", func(t *testing.T) {
+				documents, err := client.Search("alpha", 5)
+				if err != nil || len(documents) != 0 {
+					t.Errorf("Expected no results without an embedding, got %v, %v", documents, err)
+				}
+			})
+
+			t.Run("GetDocuments", func(t *testing.T) {
+				documents, err := client.GetDocuments("documents", 2)
+				if err != nil {
+					t.Fatalf("Expected no error, got: %v", err)
+				}
+				if len(documents) != 2 || documents[0].Document != "alpha" || documents[1].Metadata["source"] != "b.md" {
+					t.Errorf("Expected the first two documents with metadata, got %+v", documents)
+				}
+				if documents[0].Embedding != nil {
+					t.Errorf("Expected no embeddings, got %v", documents[0].Embedding)
+				}
+			})
+
+			t.Run("GetDocument", func(t *testing.T) {
+				doc, err := client.GetDocument("documents", "b")
+				if err != nil {
+					t.Fatalf("Expected no error, got: %v", err)
+				}
+				if doc == nil || doc.Document != "beta" {
+					t.Errorf("Expected document b, got %+v", doc)
+				}
+
+				missing, err := client.GetDocument("documents", "missing")
+				if err != nil || missing != nil {
+					t.Errorf("Expected nil for a missing document, got %+v, %v", missing, err)
+				}
+			})
+
+			t.Run("ExportDocuments", func(t *testing.T) {
+				documents, err := client.ExportDocuments("documents", 1, 1)
+				if err != nil {
+					t.Fatalf("Expected no error, got: %v", err)
+				}
+				if len(documents) != 1 || documents[0].ID != "b" || len(documents[0].Embedding) != 2 {
+					t.Errorf("Expected document b with its embedding, got %+v", documents)
+				}
+			})
+
+			t.Run("DeleteDocuments", func(t *testing.T) {
+				requests := len(server.Requests(""))
+				if err := client.DeleteDocuments("documents", nil); err != nil {
+					t.Fatalf("Expected no error, got: %v", err)
+				}
+				if len(server.Requests("")) != requests {
+					t.Error("Expected an empty ID list not to reach the server")
+				}
+
+				if err := client.DeleteDocuments("documents", []string{"a"}); err != nil {
+					t.Fatalf("Expected no error, got: %v", err)
+				}
+				if got := strings.Join(server.DocumentIDs("documents"), ","); !strings.HasPrefix(got, "b,") {
+					t.Errorf("Expected a to be deleted, got %s", got)
+				}
+			})
+
+			t.Run("unknown collections", func(t *testing.T) {
+				if _, err := client.Count("missing"); !errors.Is(err, ErrCollectionNotFound) || !strings.Contains(err.Error(), "missing") {
+					t.Errorf("Expected a not found error, got: %v", err)
+				}
+
+				server.AddCollection("shared")
+				if count, err := client.Count("shared"); err != nil || count != 0 {
+					t.Errorf("Expected a collection created elsewhere to be found, got %d, %v", count, err)
+				}
+
+				if err := client.AddDocument("notes", "n", "note", []float32{0, 0}); err != nil {
+					t.Fatalf("Expected no error, got: %v", err)
+				}
+				if got := server.DocumentIDs("notes"); len(got) != 1 {
+					t.Errorf("Expected the notes collection to be created on first use, got %v", got)
+				}
+			})
+
+			t.Run("ResetCollection", func(t *testing.T) {
+				if err := client.ResetCollection("documents"); err != nil {
+					t.Fatalf("Expected no error, got: %v", err)
+				}
+				if count, err := client.Count("documents"); err != nil || count != 0 {
+					t.Errorf("Expected an empty collection, got %d, %v", count, err)
+				}
+				if req, _ := server.LastRequest("/collections/documents"); req.Method != http.MethodDelete {
+					t.Errorf("Expected the collection to be deleted by name, got %s", req.Method)
+				}
+
+				if err := client.ResetCollection("never_created"); err != nil {
+					t.Errorf("Expected resetting a missing collection to create it, got: %v", err)
+				}
+			})
+
+			t.Run("DeleteCollection", func(t *testing.T) {
+				if err := client.DeleteCollection("never_created"); err != nil {
+					t.Fatalf("Expected no error, got: %v", err)
+				}
+				if req, _ := server.LastRequest("/collections/never_created"); req.Method != http.MethodDelete {
+					t.Errorf("Expected the collection to be deleted by name, got %s", req.Method)
+				}
+				collections, err := client.ListCollections()
+				if err != nil {
+					t.Fatalf("Expected no error, got: %v", err)
+				}
+				for _, info := range collections {
+					if info.Name == "never_created" {
+						t.Errorf("Expected the collection to be gone, got %+v", collections)
+					}
+				}
+				if err := client.DeleteCollection("never_created"); err != nil {
+					t.Errorf("Expected dropping a missing collection to succeed, got: %v", err)
+				}
+			})
+
+			t.Run("API version", func(t *testing.T) {
+				for _, req := range server.Requests("") {
+					if !strings.HasSuffix(req.Path, "/heartbeat") && !strings.HasPrefix(req.Path, api.collections) {
+						t.Errorf("Expected every request under %s, got %s %s", api.collections, req.Method, req.Path)
+					}
+				}
+			})
+		})
+	}
+}
+
+func TestChromaClient_APIVersion(t *testing.T) {
+	server := fakeserver.NewChromaV2(t)
+	cfg := config.VectorConfig{BaseURL: server.URL, Collection: "documents", CommandCollection: "command_history", AutoIndexCollection: "auto_indexed"}
+
+	lazy := NewLazyChromaClient(cfg, config.TimeoutsConfig{})
+	if requests := server.Requests(""); len(requests) != 0 {
+		t.Fatalf("Expected no requests until first use, got %d", len(requests))
+	}
+	if _, err := lazy.Count("documents"); err != nil {
+		t.Fatalf("Expected the v2 API to be detected on first use, got: %v", err)
+	}
+
+	cfg.APIVersion = config.ChromaAPIV1
+	if _, err := NewChromaClient(cfg, config.TimeoutsConfig{}); !errors.Is(err, ErrUnsupportedAPI) {
+		t.Errorf("Expected ErrUnsupportedAPI when forcing v1 against a v2 server, got: %v", err)
+	}
+
+	cfg.APIVersion = config.ChromaAPIV2
+	cfg.Tenant, cfg.Database = "acme", "notes"
+	if _, err := NewChromaClient(cfg, config.TimeoutsConfig{}); err == nil {
+		t.Error("Expected an error for a tenant the server does not have")
+	}
+	if req, _ := server.LastRequest("/collections"); req.Path != "/api/v2/tenants/acme/databases/notes/collections" {
+		t.Errorf("Expected the configured tenant and database in the path, got %s", req.Path)
+	}
+}
+
+func TestChromaClient_Errors(t *testing.T) {
+	calls := []struct {
+		name      string
+		path      string
+		readsBody bool // Calls that only check the status code ignore malformed bodies
+		call      func(c *ChromaClient) error
+	}{
+		{name: "ListCollections", readsBody: true, path: "/collections", call: func(c *ChromaClient) error {
+			_, err := c.ListCollections()
+			return err
+		}},
+		{name: "Count", readsBody: true, path: "/count", call: func(c *ChromaClient) error {
+			_, err := c.Count("documents")
+			return err
+		}},
+		{name: "AddDocument", path: "/add", call: func(c *ChromaClient) error {
+			return c.AddDocument("documents", "a", "alpha", []float32{0})
+		}},
+		{name: "SearchWithScores", readsBody: true, path: "/query", call: func(c *ChromaClient) error {
+			_, err := c.SearchWithScores("documents", []float32{0}, 1)
+			return err
+		}},
+		{name: "GetDocuments", readsBody: true, path: "/get", call: func(c *ChromaClient) error {
+			_, err := c.GetDocuments("documents", 0)
+			return err
+		}},
+		{name: "DeleteDocuments", path: "/delete", call: func(c *ChromaClient) error {
+			return c.DeleteDocuments("documents", []string{"a"})
+		}},
+		{name: "ResetCollection", path: "/collections/documents", call: func(c *ChromaClient) error {
+			return c.ResetCollection("documents")
+		}},
+	}
+	failures := []struct {
+		name     string
+		failure  fakeserver.Failure
+		expected string
+	}{
+		{name: "server error", failure: fakeserver.Failure{Status: http.StatusInternalServerError, Body: `{"error":"disk full"}`}, expected: "unexpected status code: 500"},
+		{name: "malformed JSON", failure: fakeserver.Failure{Body: `[{"id": `}, expected: "failed to unmarshal response"},
+		{name: "timeout", failure: fakeserver.Failure{Delay: 5 * time.Second}, expected: "Client.Timeout exceeded"},
+	}
+
+	for _, call := range calls {
+		for _, tt := range failures {
+			if tt.failure.Status == 0 && tt.failure.Delay == 0 && !call.readsBody {
+				continue
+			}
+			t.Run(call.name+"/"+tt.name, func(t *testing.T) {
+				server := fakeserver.NewChroma(t)
+				client := newFakeChromaClient(t, server, 100*time.Millisecond)
+				client.AddDocument("documents", "a", "alpha", []float32{0})
+				server.Fail(call.path, tt.failure)
+
+				err := call.call(client)
+				if err == nil || !strings.Contains(err.Error(), tt.expected) {
+					t.Errorf("Expected an error containing %q, got: %v", tt.expected, err)
+				}
+			})
+		}
+	}
+}
+
+func TestChromaClient_StaleCollection(t *testing.T) {
+	server := fakeserver.NewChroma(t)
+	client := newFakeChromaClient(t, server, 0)
+	client.AddDocument("notes", "a", "alpha", []float32{0})
+
+	// Another process resets the collection, so the cached ID is stale
+	other := newFakeChromaClient(t, server, 0)
+	if err := other.ResetCollection("notes"); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	_, err := client.SearchWithScores("notes", []float32{0}, 1)
+	if !errors.Is(err, ErrCollectionNotFound) {
+		t.Fatalf("Expected ErrCollectionNotFound, got: %v", err)
+	}
+	var statusErr *httpclient.StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected the 404 to be kept, got: %v", err)
+	}
+
+	// The stale ID was dropped, so the next call finds the new collection
+	if count, err := client.Count("notes"); err != nil || count != 0 {
+		t.Errorf("Expected the recreated collection to be found, got %d, %v", count, err)
+	}
+}
+
+func TestChromaClient_MaxDistance(t *testing.T) {
+	server := fakeserver.NewChroma(t)
+	client, err := NewChromaClient(config.VectorConfig{
+		BaseURL:             server.URL,
+		Collection:          "documents",
+		CommandCollection:   "command_history",
+		AutoIndexCollection: "auto_indexed",
+		MaxDistance:         1.5,
+	}, config.TimeoutsConfig{})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	// The fake server ranks by squared L2 distance: 0.01, 1 and 4 from {0.1, 0}
+	client.AddDocument("documents", "near", "alpha", []float32{0, 0})
+	client.AddDocument("documents", "edge", "beta", []float32{1.1, 0})
+	client.AddDocument("documents", "far", "gamma", []float32{2.1, 0})
+
+	results, err := client.SearchWithScores("documents", []float32{0.1, 0}, 3)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(results) != 2 || results[0].ID != "near" || results[1].ID != "edge" {
+		t.Errorf("Expected the results within 1.5, near then edge, got %+v", results)
+	}
+
+	documents, err := Search(context.Background(), client, "documents", []float32{10, 0}, 3)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(documents) != 0 {
+		t.Errorf("Expected nothing close enough to an unrelated query, got %v", documents)
+	}
+
+	unlimited := newFakeChromaClient(t, server, 0)
+	if results, _ := unlimited.SearchWithScores("documents", []float32{10, 0}, 3); len(results) != 3 {
+		t.Errorf("Expected every result without a max distance, got %+v", results)
+	}
+}
+
+func TestSearch_Cancel(t *testing.T) {
+	server := fakeserver.NewChroma(t)
+	client := newFakeChromaClient(t, server, 0)
+	client.AddDocument("documents", "a", "alpha", []float32{0})
+	server.Fail("/query", fakeserver.Failure{Delay: 5 * time.Second})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err := Search(ctx, client, "documents", []float32{0}, 1)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected a cancellation error, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected the query to stop when cancelled, took %s", elapsed)
+	}
+}
+
+func TestChromaServer_Contract(t *testing.T) {
+	server := fakeserver.NewChroma(t)
+	chroma := NewChromaServer(config.VectorConfig{BaseURL: server.URL}, config.TimeoutsConfig{})
+
+	if err := chroma.Heartbeat(); err != nil {
+		t.Errorf("Expected a heartbeat, got: %v", err)
+	}
+	version, err := chroma.Version()
+	if err != nil || version != "0.5.23" {
+		t.Errorf("Expected version 0.5.23, got %q, %v", version, err)
+	}
+
+	// Servers that answer with a bare version string are tolerated
+	server.Fail("/version", fakeserver.Failure{Body: "0.4.24\n"})
+	if version, err := chroma.Version(); err != nil || version != "0.4.24" {
+		t.Errorf("Expected version 0.4.24, got %q, %v", version, err)
+	}
+}
+
+func TestChromaServer_V2(t *testing.T) {
+	server := fakeserver.NewChromaV2(t)
+	chroma := NewChromaServer(config.VectorConfig{BaseURL: server.URL}, config.TimeoutsConfig{})
+
+	if err := chroma.Heartbeat(); err != nil {
+		t.Errorf("Expected a heartbeat from a v2 server, got: %v", err)
+	}
+	if version, err := chroma.Version(); err != nil || version != "1.0.0" {
+		t.Errorf("Expected version 1.0.0, got %q, %v", version, err)
+	}
+
+	forced := NewChromaServer(config.VectorConfig{BaseURL: server.URL, APIVersion: config.ChromaAPIV1}, config.TimeoutsConfig{})
+	if err := forced.Heartbeat(); !errors.Is(err, ErrUnsupportedAPI) {
+		t.Errorf("Expected ErrUnsupportedAPI when forcing v1, got: %v", err)
+	}
+}
+
+func TestChromaServer_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		failure  fakeserver.Failure
+		expected string
+	}{
+		{name: "no supported API", path: "/heartbeat", failure: fakeserver.Failure{Status: http.StatusGone}, expected: ErrUnsupportedAPI.Error()},
+		{name: "heartbeat server error", path: "/heartbeat", failure: fakeserver.Failure{Status: http.StatusServiceUnavailable}, expected: "unexpected status code: 503"},
+		{name: "heartbeat timeout", path: "/heartbeat", failure: fakeserver.Failure{Delay: 5 * time.Second}, expected: "failed to reach ChromaDB"},
+		{name: "version server error", path: "/version", failure: fakeserver.Failure{Status: http.StatusInternalServerError}, expected: "unexpected status code"},
+		{name: "version timeout", path: "/version", failure: fakeserver.Failure{Delay: 5 * time.Second}, expected: "failed to reach ChromaDB"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := fakeserver.NewChroma(t)
+			server.Fail(tt.path, tt.failure)
+			chroma := NewChromaServer(config.VectorConfig{BaseURL: server.URL}, config.TimeoutsConfig{Vector: 100 * time.Millisecond})
+
+			var err error
+			if tt.path == "/heartbeat" {
+				err = chroma.Heartbeat()
+			} else {
+				_, err = chroma.Version()
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("Expected an error containing %q, got: %v", tt.expected, err)
+			}
+			if tt.name == "no supported API" && !errors.Is(err, ErrUnsupportedAPI) {
+				t.Errorf("Expected ErrUnsupportedAPI, got: %v", err)
+			}
+		})
+	}
+}
+
+// TestChromaClient_Concurrent searches and adds from many goroutines at once,
+// as a chat session does while the auto-indexer runs, including adds to
+// collections that do not exist yet and a collection reset under the client.
+// Run with -race.
+func TestChromaClient_Concurrent(t *testing.T) {
+	server := fakeserver.NewChroma(t)
+	client := newFakeChromaClient(t, server, 0)
+	other := newFakeChromaClient(t, server, 0)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				if err := client.AddDocument("documents", "", "alpha", []float32{float32(j)}); err != nil {
+					t.Errorf("Expected no error adding, got: %v", err)
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				if _, err := client.SearchWithScores("documents", []float32{float32(j)}, 3); err != nil {
+					t.Errorf("Expected no error searching, got: %v", err)
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			// A new collection, reset by another process partway through;
+			// errors for the stale ID are expected and drop it from the cache
+			for j := 0; j < 20; j++ {
+				client.AddDocument("notes", "", "beta", []float32{0})
+				client.Count("notes")
+				if j == 10 {
+					other.ResetCollection("notes")
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if count, err := client.Count("documents"); err != nil || count != 160 {
+		t.Errorf("Expected 160 documents, got %d (%v)", count, err)
+	}
+	collections := server.Collections()
+	notes := 0
+	for _, name := range collections {
+		if name == "notes" {
+			notes++
+		}
+	}
+	if notes != 1 {
+		t.Errorf("Expected the notes collection to be created once, got collections %v", collections)
+	}
+}
+
+func TestNewLazyChromaClient(t *testing.T) {
+	closed := fakeserver.NewChroma(t)
+	closed.Close()
+	down := NewLazyChromaClient(config.VectorConfig{BaseURL: closed.URL, Collection: "documents"}, config.TimeoutsConfig{})
+	if _, err := down.SearchWithScores("documents", []float32{0}, 1); !httpclient.Unavailable(err) {
+		t.Errorf("Expected an unavailable error while the server is down, got: %v", err)
+	}
+
+	server := fakeserver.NewChroma(t)
+	client := NewLazyChromaClient(config.VectorConfig{
+		BaseURL:             server.URL,
+		Collection:          "documents",
+		CommandCollection:   "command_history",
+		AutoIndexCollection: "auto_indexed",
+	}, config.TimeoutsConfig{})
+	if collections := server.Collections(); len(collections) != 0 {
+		t.Fatalf("Expected no requests until first use, got collections %v", collections)
+	}
+
+	// The configured collections are created on first use, as NewChromaClient
+	// would have at startup
+	if results, err := client.SearchWithScores("documents", []float32{0}, 1); err != nil || len(results) != 0 {
+		t.Errorf("Expected an empty search, got %v (%v)", results, err)
+	}
+	if err := client.AddDocument("command_history", "a", "ls", []float32{0}); err != nil {
+		t.Errorf("Expected no error, got: %v", err)
+	}
+	if _, err := client.SearchWithScores("notes", []float32{0}, 1); !errors.Is(err, ErrCollectionNotFound) {
+		t.Errorf("Expected other collections to still be looked up only, got: %v", err)
+	}
+}
@@ -0,0 +1,122 @@
+package vector
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// exportPageSize is the number of documents fetched per request while exporting
+const exportPageSize = 500
+
+// maxImportLineBytes bounds a single JSONL record, which holds a chunk and its
+// embedding
+const maxImportLineBytes = 16 * 1024 * 1024
+
+// Export writes every document in a collection to w as JSON Lines, one
+// StoredDocument with its embedding per line, and returns the number written.
+// progress, if non-nil, is called with the running total after each page.
+func Export(store VectorStore, collectionName string, w io.Writer, progress func(written int)) (int, error) {
+	encoder := json.NewEncoder(w)
+	written := 0
+	for {
+		documents, err := store.ExportDocuments(collectionName, written, exportPageSize)
+		if err != nil {
+			return written, fmt.Errorf("failed to read documents from %s: %w", collectionName, err)
+		}
+
+		for _, doc := range documents {
+			if err := encoder.Encode(doc); err != nil {
+				return written, fmt.Errorf("failed to write document %s: %w", doc.ID, err)
+			}
+			written++
+		}
+		if progress != nil && len(documents) > 0 {
+			progress(written)
+		}
+
+		if len(documents) < exportPageSize {
+			return written, nil
+		}
+	}
+}
+
+// ImportOptions controls how Import treats the records it reads
+type ImportOptions struct {
+	// SkipExisting leaves documents whose ID is already in the collection untouched
+	SkipExisting bool
+	// Dimension is the embedding size records must have; 0 takes it from the first record
+	Dimension int
+	// Progress, if non-nil, is called with the number of records read so far
+	Progress func(read int)
+}
+
+// ImportResult counts the records handled by Import
+type ImportResult struct {
+	Imported int
+	Skipped  int
+}
+
+// Import reads JSON Lines written by Export and adds each document to a
+// collection with its stored embedding. Every embedding must have the same
+// dimension; a mismatch stops the import at that line.
+func Import(store VectorStore, collectionName string, r io.Reader, opts ImportOptions) (ImportResult, error) {
+	var result ImportResult
+	dimension := opts.Dimension
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxImportLineBytes)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var doc StoredDocument
+		if err := json.Unmarshal(scanner.Bytes(), &doc); err != nil {
+			return result, fmt.Errorf("line %d: invalid record: %w", line, err)
+		}
+		if doc.ID == "" {
+			return result, fmt.Errorf("line %d: record has no id", line)
+		}
+		if len(doc.Embedding) == 0 {
+			return result, fmt.Errorf("line %d: document %s has no embedding", line, doc.ID)
+		}
+		if dimension == 0 {
+			dimension = len(doc.Embedding)
+		} else if len(doc.Embedding) != dimension {
+			return result, fmt.Errorf("line %d: document %s has a %d-dimensional embedding, expected %d", line, doc.ID, len(doc.Embedding), dimension)
+		}
+
+		if opts.SkipExisting {
+			// A collection that does not exist yet, and is created by the
+			// first document added, holds nothing to skip
+			existing, err := store.GetDocument(collectionName, doc.ID)
+			if errors.Is(err, ErrCollectionNotFound) {
+				existing, err = nil, nil
+			}
+			if err != nil {
+				return result, fmt.Errorf("line %d: failed to look up document %s: %w", line, doc.ID, err)
+			}
+			if existing != nil {
+				result.Skipped++
+				continue
+			}
+		}
+
+		if err := store.AddDocumentWithMetadata(collectionName, doc.ID, doc.Document, doc.Embedding, doc.Metadata); err != nil {
+			return result, fmt.Errorf("line %d: failed to add document %s: %w", line, doc.ID, err)
+		}
+		result.Imported++
+		if opts.Progress != nil {
+			opts.Progress(result.Imported + result.Skipped)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return result, fmt.Errorf("failed to read import file: %w", err)
+	}
+	return result, nil
+}
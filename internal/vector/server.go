@@ -0,0 +1,108 @@
+package vector
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"rag-cli/internal/httpclient"
+	"rag-cli/pkg/config"
+)
+
+// ErrUnsupportedAPI is returned when the ChromaDB server serves neither the
+// v1 nor the v2 REST API, or not the one vector.api_version asks for
+var ErrUnsupportedAPI = errors.New("server does not support the ChromaDB v1 or v2 API")
+
+// ChromaServer talks to server-level ChromaDB endpoints. Unlike
+// NewChromaClient, creating one does not contact the server or create
+// collections, so it can be used to diagnose an unreachable server.
+type ChromaServer struct {
+	baseURL    string
+	apiVersion string // One of config.ChromaAPIVersions
+	client     *http.Client
+}
+
+// probeTimeout keeps diagnostics quick when ChromaDB is unreachable
+const probeTimeout = 5 * time.Second
+
+// NewChromaServer uses the shorter of probeTimeout and timeouts.Vector
+func NewChromaServer(cfg config.VectorConfig, timeouts config.TimeoutsConfig) *ChromaServer {
+	timeout := probeTimeout
+	if timeouts.Vector > 0 && timeouts.Vector < timeout {
+		timeout = timeouts.Vector
+	}
+	return &ChromaServer{
+		baseURL:    cfg.URL(),
+		apiVersion: cfg.APIVersion,
+		client:     httpclient.New(timeout, timeouts),
+	}
+}
+
+// Heartbeat checks that the server is reachable and serves an API version
+// ChromaClient can use
+func (s *ChromaServer) Heartbeat() error {
+	_, err := detectAPIVersion(s.client, s.baseURL, s.apiVersion)
+	return err
+}
+
+// detectAPIVersion returns the ChromaDB API version to use with the server at
+// baseURL: v2 if its heartbeat answers, otherwise v1. A configured v1 or v2
+// is only checked. Servers answer 404 or 410 Gone for an API they do not
+// serve; ChromaDB 1.0 dropped v1 that way.
+func detectAPIVersion(client *http.Client, baseURL, configured string) (string, error) {
+	versions := []string{config.ChromaAPIV2, config.ChromaAPIV1}
+	if configured == config.ChromaAPIV1 || configured == config.ChromaAPIV2 {
+		versions = []string{configured}
+	}
+
+	for _, version := range versions {
+		resp, err := client.Get(baseURL + "/api/" + version + "/heartbeat")
+		if err != nil {
+			return "", fmt.Errorf("failed to reach ChromaDB at %s: %w", baseURL, httpclient.Classify(err))
+		}
+		status := resp.StatusCode
+		if status != http.StatusOK && status != http.StatusNotFound && status != http.StatusGone {
+			err = httpclient.NewStatusError(resp)
+		}
+		resp.Body.Close()
+
+		switch {
+		case err != nil:
+			return "", err
+		case status == http.StatusOK:
+			return version, nil
+		}
+	}
+	return "", ErrUnsupportedAPI
+}
+
+// Version returns the server version, trying the v1 API and then v2
+func (s *ChromaServer) Version() (string, error) {
+	var lastErr error
+	for _, api := range []string{"v1", "v2"} {
+		resp, err := s.client.Get(s.baseURL + "/api/" + api + "/version")
+		if err != nil {
+			return "", fmt.Errorf("failed to reach ChromaDB at %s: %w", s.baseURL, err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return "", fmt.Errorf("failed to read response: %w", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			lastErr = fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+			continue
+		}
+
+		var version string
+		if err := json.Unmarshal(body, &version); err != nil {
+			version = strings.TrimSpace(string(body))
+		}
+		return version, nil
+	}
+	return "", lastErr
+}
@@ -0,0 +1,90 @@
+package vector
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrCollectionNotFound is returned when a collection does not exist on the
+// server
+var ErrCollectionNotFound = errors.New("collection not found")
+
+// SearchResult is a single ranked match returned by a similarity search
+type SearchResult struct {
+	ID       string                 `json:"id"`
+	Document string                 `json:"document"`
+	Distance float32                `json:"distance"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// StoredDocument is a document as stored in a collection. Embedding is only
+// filled in by ExportDocuments.
+type StoredDocument struct {
+	ID        string                 `json:"id"`
+	Document  string                 `json:"document"`
+	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+	Embedding []float32              `json:"embedding,omitempty"`
+}
+
+// CollectionInfo describes a collection stored in the vector database
+type CollectionInfo struct {
+	Name      string                 `json:"name"`
+	ID        string                 `json:"id"`
+	Dimension int                    `json:"dimension,omitempty"`
+	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// VectorStore is the set of vector database operations used by commands and
+// chat sessions. ChromaClient is the production implementation.
+//
+// Implementations must be safe for concurrent use: a chat session searches
+// for context while the auto-indexer adds documents in the background, and
+// 'rag-cli index' adds from several workers at once.
+type VectorStore interface {
+	AddDocument(collectionName, id, content string, embedding []float32) error
+	AddDocumentWithMetadata(collectionName, id, content string, embedding []float32, metadata map[string]interface{}) error
+	SearchWithEmbedding(collectionName string, queryEmbedding []float32, numResults int) ([]string, error)
+	SearchWithScores(collectionName string, queryEmbedding []float32, numResults int) ([]SearchResult, error)
+	ListCollections() ([]CollectionInfo, error)
+	Count(collectionName string) (int, error)
+	GetDocuments(collectionName string, limit int) ([]StoredDocument, error)
+	GetDocument(collectionName, id string) (*StoredDocument, error)
+	ExportDocuments(collectionName string, offset, limit int) ([]StoredDocument, error)
+	DeleteDocuments(collectionName string, ids []string) error
+	ResetCollection(name string) error
+	DeleteCollection(name string) error
+
+	DocumentsCollection() string
+	CommandsCollection() string
+	AutoIndexCollection() string
+}
+
+var _ VectorStore = (*ChromaClient)(nil)
+
+// ContextSearcher is implemented by stores whose searches can be cancelled
+type ContextSearcher interface {
+	SearchWithEmbeddingContext(ctx context.Context, collectionName string, queryEmbedding []float32, numResults int) ([]string, error)
+}
+
+var _ ContextSearcher = (*ChromaClient)(nil)
+
+// FilterSearcher is implemented by stores that can limit a search to
+// documents whose metadata matches a where filter, returning each match with
+// its metadata
+type FilterSearcher interface {
+	SearchWithFilterContext(ctx context.Context, collectionName string, queryEmbedding []float32, numResults int, where map[string]interface{}) ([]SearchResult, error)
+}
+
+var _ FilterSearcher = (*ChromaClient)(nil)
+
+// Search runs a similarity search on store, stopping when ctx is cancelled.
+// Stores that do not take a context are only checked before the search.
+func Search(ctx context.Context, store VectorStore, collectionName string, queryEmbedding []float32, numResults int) ([]string, error) {
+	if searcher, ok := store.(ContextSearcher); ok {
+		return searcher.SearchWithEmbeddingContext(ctx, collectionName, queryEmbedding, numResults)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return store.SearchWithEmbedding(collectionName, queryEmbedding, numResults)
+}
@@ -4,8 +4,11 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/spf13/viper"
+	"rag-cli/pkg/paths"
 )
 
 type Config struct {
@@ -15,101 +18,467 @@ type Config struct {
 	Chunker    ChunkerConfig    `mapstructure:"chunker"`
 	AutoIndex  AutoIndexConfig  `mapstructure:"auto_index"`
 	Chat       ChatConfig       `mapstructure:"chat"`
+	History    HistoryConfig    `mapstructure:"history"`
+	Index      IndexConfig      `mapstructure:"index"`
+	Updates    UpdatesConfig    `mapstructure:"updates"`
+	Debug      DebugConfig      `mapstructure:"debug"`
+	Log        LogConfig        `mapstructure:"log"`
+	Timeouts   TimeoutsConfig   `mapstructure:"timeouts"`
+	Prompts    PromptsConfig    `mapstructure:"prompts"`
+	Safety     SafetyConfig     `mapstructure:"safety"`
+	SystemInfo SystemInfoConfig `mapstructure:"system_info"`
+	Telemetry  TelemetryConfig  `mapstructure:"telemetry"`
+
+	// secretErrors holds the secret references that could not be resolved, by key
+	secretErrors map[string]error
 }
 
+// Providers accepted by llm.provider and embeddings.provider: Ollama's own
+// API, or an OpenAI-compatible one such as vLLM, LM Studio, llama.cpp's
+// server, or OpenRouter
+const (
+	ProviderOllama = "ollama"
+	ProviderOpenAI = "openai"
+)
+
+// Providers lists the accepted providers
+var Providers = []string{ProviderOllama, ProviderOpenAI}
+
 type LLMConfig struct {
+	Provider string `mapstructure:"provider"` // API spoken by the server, one of Providers
 	Model    string `mapstructure:"model"`
 	Host     string `mapstructure:"host"`
 	Port     int    `mapstructure:"port"`
-	APIKey   string `mapstructure:"api_key"`
-	BaseURL  string `mapstructure:"base_url"`
+	APIKey   string `mapstructure:"api_key"`  // A key, or a reference: env:NAME or keychain:service/account
+	BaseURL  string `mapstructure:"base_url"` // Overrides host and port when set
+
+	MaxRetries   int           `mapstructure:"max_retries"`   // Retries after a failed request, 0 disables them
+	RetryBackoff time.Duration `mapstructure:"retry_backoff"` // Wait before the first retry, doubled for each later one
 }
 
+// ChromaDB REST API versions accepted by vector.api_version. auto uses v2
+// when the server serves it and v1 otherwise.
+const (
+	ChromaAPIAuto = "auto"
+	ChromaAPIV1   = "v1"
+	ChromaAPIV2   = "v2"
+)
+
+// ChromaAPIVersions lists the accepted ChromaDB API versions
+var ChromaAPIVersions = []string{ChromaAPIAuto, ChromaAPIV1, ChromaAPIV2}
+
+// The tenant and database every ChromaDB server starts with
+const (
+	DefaultChromaTenant   = "default_tenant"
+	DefaultChromaDatabase = "default_database"
+)
+
 type VectorConfig struct {
-	Host                string `mapstructure:"host"`
-	Port                int    `mapstructure:"port"`
-	Collection          string `mapstructure:"collection"`           // Main documents collection
-	CommandCollection   string `mapstructure:"command_collection"`   // Command execution history
-	AutoIndexCollection string `mapstructure:"auto_index_collection"` // Auto-indexed files
+	Host                string  `mapstructure:"host"`
+	Port                int     `mapstructure:"port"`
+	BaseURL             string  `mapstructure:"base_url"`              // Overrides host and port when set
+	APIVersion          string  `mapstructure:"api_version"`           // One of ChromaAPIVersions (empty = auto)
+	Tenant              string  `mapstructure:"tenant"`                // Tenant holding the collections, v2 API only
+	Database            string  `mapstructure:"database"`              // Database holding the collections, v2 API only
+	Collection          string  `mapstructure:"collection"`            // Main documents collection
+	CommandCollection   string  `mapstructure:"command_collection"`    // Command execution history
+	AutoIndexCollection string  `mapstructure:"auto_index_collection"` // Auto-indexed files
+	MaxDistance         float64 `mapstructure:"max_distance"`          // Searches drop results farther than this from the query (0 = keep all)
+	DataDir             string  `mapstructure:"data_dir"`              // Where a local ChromaDB keeps its data, for stats (empty = unknown)
+}
+
+// DataPath returns the configured ChromaDB data directory, expanding a
+// leading ~/ to the home directory, or "" when it is not set
+func (c VectorConfig) DataPath() string {
+	if c.DataDir == "" {
+		return ""
+	}
+	return expandHome(c.DataDir)
 }
 
 type EmbeddingsConfig struct {
-	Model   string `mapstructure:"model"`
-	Host    string `mapstructure:"host"`
-	Port    int    `mapstructure:"port"`
-	BaseURL string `mapstructure:"base_url"`
+	Provider string `mapstructure:"provider"` // API spoken by the server, one of Providers
+	Model    string `mapstructure:"model"`
+	Host     string `mapstructure:"host"`
+	Port     int    `mapstructure:"port"`
+	APIKey   string `mapstructure:"api_key"`  // A key, or a reference: env:NAME or keychain:service/account
+	BaseURL  string `mapstructure:"base_url"` // Overrides host and port when set
+
+	MaxRetries   int           `mapstructure:"max_retries"`   // Retries after a failed request, 0 disables them
+	RetryBackoff time.Duration `mapstructure:"retry_backoff"` // Wait before the first retry, doubled for each later one
 }
 
+// Chunking strategies accepted by chunker.strategy: cut at paragraph, line,
+// sentence or word boundaries, or every chunk_size runes regardless
+const (
+	ChunkStrategyBoundary = "boundary"
+	ChunkStrategyFixed    = "fixed"
+)
+
+// ChunkStrategies lists the accepted chunking strategies
+var ChunkStrategies = []string{ChunkStrategyBoundary, ChunkStrategyFixed}
+
+// What answering "always" to a command approval remembers, set with
+// chat.always_allow_match: the programs the command runs, or the command
+// exactly as written
+const (
+	ApprovalMatchProgram = "program"
+	ApprovalMatchExact   = "exact"
+)
+
+// ApprovalMatches lists the accepted values of chat.always_allow_match
+var ApprovalMatches = []string{ApprovalMatchProgram, ApprovalMatchExact}
+
 type ChunkerConfig struct {
-	ChunkSize    int `mapstructure:"chunk_size"`
-	ChunkOverlap int `mapstructure:"chunk_overlap"`
+	ChunkSize    int    `mapstructure:"chunk_size"`
+	ChunkOverlap int    `mapstructure:"chunk_overlap"`
+	Strategy     string `mapstructure:"strategy"` // One of ChunkStrategies (empty = boundary)
 }
 
 type AutoIndexConfig struct {
-	Enabled         bool     `mapstructure:"enabled"`
-	Extensions      []string `mapstructure:"extensions"`
-	MaxFileSize     int64    `mapstructure:"max_file_size"`
-	ExcludePatterns []string `mapstructure:"exclude_patterns"`
-	BatchDelay      string   `mapstructure:"batch_delay"`
+	Enabled         bool          `mapstructure:"enabled"`
+	Extensions      []string      `mapstructure:"extensions"`
+	MaxFileSize     int64         `mapstructure:"max_file_size"`
+	ExcludePatterns []string      `mapstructure:"exclude_patterns"`
+	BatchDelay      time.Duration `mapstructure:"batch_delay"` // Quiet period before indexing a batch of changes
 }
 
 type ChatConfig struct {
 	MaxAttempts       int  `mapstructure:"max_attempts"`
 	MaxOutputLines    int  `mapstructure:"max_output_lines"`    // Max lines to show in interactive mode
 	TruncateOutput    bool `mapstructure:"truncate_output"`     // Enable/disable output truncation
+	MaxInputChars     int  `mapstructure:"max_input_chars"`     // Max characters accepted by the TUI input (0 = unlimited)
+	TopKDocuments     int  `mapstructure:"top_k_documents"`     // Document chunks retrieved as context per prompt
+	TopKHistory       int  `mapstructure:"top_k_history"`       // Past command sessions retrieved as context per prompt
+	AllowCommands     bool `mapstructure:"allow_commands"`      // Run proposed commands; only applied to --prompt when set explicitly
+	MemoryChars       int  `mapstructure:"memory_chars"`        // Characters of earlier turns sent with each prompt (0 = no conversation memory)
+	MaxContextChars   int  `mapstructure:"max_context_chars"`   // Characters of the whole prompt, retrieved context being trimmed to fit (0 = unlimited)
+	ContextItemChars  int  `mapstructure:"context_item_chars"`  // Characters kept of each retrieved document or session (0 = unlimited)
+	SummarizeMemory   bool `mapstructure:"summarize_memory"`    // Summarize turns that no longer fit instead of dropping them
+	CommandTimeout    time.Duration `mapstructure:"command_timeout"` // Longest a command, or each step of a pipe, may run (0 = no limit)
+	ShowSources       bool `mapstructure:"show_sources"`        // List the documents an answer drew on under it in a chat
+	SaveTranscripts   bool `mapstructure:"save_transcripts"`    // Write each interactive chat to a transcript file
+	TranscriptMaxOutput int `mapstructure:"transcript_max_output"` // Bytes of each command's output kept in transcripts (0 = all)
+	AlwaysAllow       []string `mapstructure:"always_allow"`     // Rules approving commands without asking: a program name, or an exact command
+	AlwaysAllowMatch  string `mapstructure:"always_allow_match"` // What answering "always" remembers, one of ApprovalMatches
+	SaveAlwaysAllow   bool `mapstructure:"save_always_allow"`    // Add rules from answering "always" to always_allow in the user config file
+	Commands          CommandPolicyConfig `mapstructure:"commands"`   // Programs commands may run
+}
+
+// CommandPolicyConfig limits the programs the commands chat runs may start,
+// checked for each step of a pipe and each command run through sudo, env or
+// sh -c. Entries are program names, or safety patterns matched against the
+// program name, such as "git*" or "re:^py".
+type CommandPolicyConfig struct {
+	Allowlist []string `mapstructure:"allowlist"` // Programs permitted; empty permits all, and shell builtins are always permitted
+	Denylist  []string `mapstructure:"denylist"`  // Programs refused, even when allowlisted
+}
+
+type HistoryConfig struct {
+	RetentionDays int `mapstructure:"retention_days"` // Prune command sessions older than this at session start (0 = keep forever)
+	MaxSessions   int `mapstructure:"max_sessions"`   // Keep at most this many command sessions (0 = unlimited)
+}
+
+type IndexConfig struct {
+	ExcludePatterns []string `mapstructure:"exclude_patterns"` // gitignore-style patterns skipped by 'rag-cli index'
+	Workers         int      `mapstructure:"workers"`          // Files indexed in parallel (0 = 4, or the CPU cores when fewer)
+}
+
+type UpdatesConfig struct {
+	Check  bool `mapstructure:"check"`  // Allow contacting GitHub for the latest release
+	Notify bool `mapstructure:"notify"` // Print a notice at most once a day when an update is available
+}
+
+type DebugConfig struct {
+	Enabled bool   `mapstructure:"enabled"`  // Write detailed evaluation logs (also --debug)
+	LogFile string `mapstructure:"log_file"` // Debug log location (empty = DefaultDebugLogPath)
+	MaxSize int64  `mapstructure:"max_size"` // Bytes before the log is rotated to <log_file>.1 (0 = never)
+}
+
+// Log levels accepted by log.level and --log-level, most severe first
+var LogLevels = []string{"error", "warn", "info", "debug"}
+
+type LogConfig struct {
+	Level  string `mapstructure:"level"`   // Least severe level logged, one of LogLevels (also --log-level)
+	ToFile bool   `mapstructure:"to_file"` // Write to File instead of stderr
+	File   string `mapstructure:"file"`    // Log location (empty = DefaultLogPath)
+}
+
+type TimeoutsConfig struct {
+	LLM          time.Duration `mapstructure:"llm"`           // Whole request to Ollama, including the generated response
+	Embeddings   time.Duration `mapstructure:"embeddings"`    // Each embedding request
+	Vector       time.Duration `mapstructure:"vector"`        // Each ChromaDB request
+	Dial         time.Duration `mapstructure:"dial"`          // Opening a connection
+	TLSHandshake time.Duration `mapstructure:"tls_handshake"` // Completing a TLS handshake with an https base_url
+}
+
+type SystemInfoConfig struct {
+	Cache     bool          `mapstructure:"cache"`     // Reuse detected OS and tool versions between runs
+	CacheTTL  time.Duration `mapstructure:"cache_ttl"` // Detect again once the cached information is this old
+	Resources bool          `mapstructure:"resources"` // Tell the model the CPU count, memory, and free disk space
+}
+
+// TelemetryConfig controls usage metrics, which never leave the machine
+type TelemetryConfig struct {
+	Local bool `mapstructure:"local"` // Record usage events in usage.jsonl for 'rag-cli stats --usage'
+}
+
+// PromptsConfig points prompts at template files; see PromptNames. Empty
+// paths use the built-in prompts.
+type PromptsConfig struct {
+	CommandGeneration string         `mapstructure:"command_generation"`
+	GoalCheck         string         `mapstructure:"goal_check"`
+	NextCommands      string         `mapstructure:"next_commands"`
+	QueueDecision     string         `mapstructure:"queue_decision"`
+	FinalAnswer       string         `mapstructure:"final_answer"`
+	Models            []ModelPrompts `mapstructure:"models"` // Overrides for specific llm.model values
+}
+
+// SafetyConfig adds to the built-in rules that refuse destructive commands.
+// Patterns are globs matched against the whole command, or regular
+// expressions when written as re:<expression>.
+type SafetyConfig struct {
+	Blocklist                []SafetyRule `mapstructure:"blocklist"`                  // Commands refused, or run with a warning
+	Dangerous                []SafetyRule `mapstructure:"dangerous"`                  // Commands always confirmed, even with --auto-approve
+	Allowlist                []string     `mapstructure:"allowlist"`                  // Commands exempt from the blocklist, dangerous and read_only
+	RequireTypedConfirmation bool         `mapstructure:"require_typed_confirmation"` // Approve commands by typing "yes" instead of pressing Enter
+	ReadOnly                 bool         `mapstructure:"read_only"`                  // Only run commands known not to change anything
+}
+
+// SafetyRule is a blocklist or dangerous entry
+type SafetyRule struct {
+	Pattern  string `mapstructure:"pattern"`
+	Severity string `mapstructure:"severity"` // SeverityBlock (the default) or SeverityWarn; blocklist only
+	Reason   string `mapstructure:"reason"`   // Shown when the rule matches
+}
+
+// DefaultDebugLogPath returns where debug logs are written unless
+// debug.log_file is set
+func DefaultDebugLogPath() (string, error) {
+	dirs, err := paths.Default()
+	if err != nil {
+		return "", err
+	}
+	return dirs.StateFile("debug.log"), nil
+}
+
+// DebugLogPath returns the configured debug log location, expanding a
+// leading ~/ to the home directory
+func (c DebugConfig) DebugLogPath() (string, error) {
+	if c.LogFile == "" {
+		return DefaultDebugLogPath()
+	}
+	if strings.HasPrefix(c.LogFile, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to get home directory: %w", err)
+		}
+		return filepath.Join(home, c.LogFile[2:]), nil
+	}
+	return c.LogFile, nil
+}
+
+// DefaultLogPath returns where log.to_file writes unless log.file is set
+func DefaultLogPath() (string, error) {
+	dirs, err := paths.Default()
+	if err != nil {
+		return "", err
+	}
+	return dirs.StateFile("rag-cli.log"), nil
+}
+
+// LogPath returns the configured log file location, expanding a leading ~/
+// to the home directory
+func (c LogConfig) LogPath() (string, error) {
+	if c.File == "" {
+		return DefaultLogPath()
+	}
+	return expandHome(c.File), nil
 }
 
 func Load() (*Config, error) {
-	// Set defaults
-	viper.SetDefault("llm.model", "granite-code:3b")
-	viper.SetDefault("llm.host", "localhost")
-	viper.SetDefault("llm.port", 11434)
-	viper.SetDefault("llm.base_url", "http://localhost:11434")
-	
-	viper.SetDefault("vector.host", "localhost")
-	viper.SetDefault("vector.port", 8000)
-	viper.SetDefault("vector.collection", "documents")
-	viper.SetDefault("vector.command_collection", "command_history")
-	viper.SetDefault("vector.auto_index_collection", "auto_indexed")
-	
-	viper.SetDefault("embeddings.model", "all-minilm")
-	viper.SetDefault("embeddings.host", "localhost")
-	viper.SetDefault("embeddings.port", 11434)
-	viper.SetDefault("embeddings.base_url", "http://localhost:11434")
-	
-	viper.SetDefault("chunker.chunk_size", 1000)
-	viper.SetDefault("chunker.chunk_overlap", 200)
-	
-	// Chat settings
-	viper.SetDefault("chat.max_attempts", 3)
-	viper.SetDefault("chat.max_output_lines", 50)  // Show first and last 25 lines
-	viper.SetDefault("chat.truncate_output", true)  // Enable truncation by default
-	
-	// Auto-index defaults
-	viper.SetDefault("auto_index.enabled", false)
-	viper.SetDefault("auto_index.extensions", []string{".txt", ".md", ".py", ".js", ".go", ".json", ".yaml", ".yml"})
-	viper.SetDefault("auto_index.max_file_size", 1048576) // 1MB in bytes
-	viper.SetDefault("auto_index.exclude_patterns", []string{".git/*", "node_modules/*", "*.log", "tmp/*", "temp/*", "*.tmp"})
-	viper.SetDefault("auto_index.batch_delay", "2s")
+	setDefaults(viper.GetViper())
+	ConfigureEnv(viper.GetViper())
+
+	loadedFiles.user, loadedFiles.project, loadedFiles.projectKeys, loadedFiles.ignoredKeys = "", "", nil, nil
 
 	// Try to read config file
-	home, err := os.UserHomeDir()
+	configPath, err := UserConfigPath()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get home directory: %w", err)
+		return nil, err
 	}
 
-	configPath := filepath.Join(home, ".rag-cli.yaml")
 	if _, err := os.Stat(configPath); err == nil {
 		viper.SetConfigFile(configPath)
 		if err := viper.ReadInConfig(); err != nil {
 			return nil, fmt.Errorf("failed to read config file: %w", err)
 		}
+		loadedFiles.user = configPath
+	} else if explicitConfigFile != "" {
+		return nil, fmt.Errorf("config file %s: %w", explicitConfigFile, err)
+	}
+
+	// A project config found above the working directory wins over the user
+	// config, unless a config file was given explicitly
+	projectPath := ""
+	if explicitConfigFile == "" {
+		if projectPath, err = findProjectConfigFromCwd(); err != nil {
+			return nil, err
+		}
+	}
+	if projectPath != "" {
+		if err := mergeProjectConfig(viper.GetViper(), projectPath); err != nil {
+			return nil, err
+		}
+	}
+
+	// Check durations before decoding, which would accept a bare number as nanoseconds
+	if err := checkDurations(viper.GetViper()); err != nil {
+		return nil, err
 	}
 
 	var config Config
 	if err := viper.Unmarshal(&config); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
+	if err := checkEndpoints(&config); err != nil {
+		return nil, err
+	}
+	config.resolveSecrets()
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
 
+// DefaultConfig returns the configuration used when no config file or
+// overrides are present
+func DefaultConfig() (*Config, error) {
+	v := viper.New()
+	setDefaults(v)
+
+	var config Config
+	if err := v.Unmarshal(&config); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal default config: %w", err)
+	}
 	return &config, nil
 }
+
+// setDefaults registers the default value of every setting
+func setDefaults(v *viper.Viper) {
+	v.SetDefault("llm.provider", ProviderOllama)
+	v.SetDefault("llm.model", "granite-code:3b")
+	v.SetDefault("llm.host", "localhost")
+	v.SetDefault("llm.port", 11434)
+	v.SetDefault("llm.base_url", "") // Empty builds the URL from host and port
+	v.SetDefault("llm.api_key", "")
+	v.SetDefault("llm.max_retries", 2)
+	v.SetDefault("llm.retry_backoff", "1s")
+	
+	v.SetDefault("vector.host", "localhost")
+	v.SetDefault("vector.port", 8000)
+	v.SetDefault("vector.base_url", "")
+	v.SetDefault("vector.api_version", ChromaAPIAuto)
+	v.SetDefault("vector.tenant", DefaultChromaTenant)
+	v.SetDefault("vector.database", DefaultChromaDatabase)
+	v.SetDefault("vector.collection", "documents")
+	v.SetDefault("vector.command_collection", "command_history")
+	v.SetDefault("vector.auto_index_collection", "auto_indexed")
+	v.SetDefault("vector.max_distance", 0.0)
+	v.SetDefault("vector.data_dir", "")
+	
+	v.SetDefault("embeddings.provider", ProviderOllama)
+	v.SetDefault("embeddings.model", "all-minilm")
+	v.SetDefault("embeddings.host", "localhost")
+	v.SetDefault("embeddings.port", 11434)
+	v.SetDefault("embeddings.base_url", "")
+	v.SetDefault("embeddings.api_key", "")
+	v.SetDefault("embeddings.max_retries", 2)
+	v.SetDefault("embeddings.retry_backoff", "1s")
+	
+	v.SetDefault("chunker.chunk_size", 1000)
+	v.SetDefault("chunker.chunk_overlap", 200)
+	v.SetDefault("chunker.strategy", ChunkStrategyBoundary)
+	
+	// Chat settings
+	v.SetDefault("chat.max_attempts", 3)
+	v.SetDefault("chat.max_output_lines", 50)  // Show first and last 25 lines
+	v.SetDefault("chat.truncate_output", true)  // Enable truncation by default
+	v.SetDefault("chat.max_input_chars", 0)     // No input limit by default
+	v.SetDefault("chat.top_k_documents", 5)
+	v.SetDefault("chat.top_k_history", 3)
+	v.SetDefault("chat.allow_commands", true) // --prompt runs without commands unless this is set
+	v.SetDefault("chat.memory_chars", 4000)
+	v.SetDefault("chat.max_context_chars", 12000) // About 3000 tokens, leaving room for the answer in a 4096-token window
+	v.SetDefault("chat.context_item_chars", 2000)
+	v.SetDefault("chat.summarize_memory", false)
+	v.SetDefault("chat.command_timeout", "60s")
+	v.SetDefault("chat.show_sources", true)
+	v.SetDefault("chat.save_transcripts", false)
+	v.SetDefault("chat.transcript_max_output", 10000)
+	v.SetDefault("chat.always_allow", []string{})
+	v.SetDefault("chat.always_allow_match", ApprovalMatchProgram)
+	v.SetDefault("chat.save_always_allow", false)
+	v.SetDefault("chat.commands.allowlist", []string{}) // Empty permits every program
+	v.SetDefault("chat.commands.denylist", []string{})
+	
+	// Command history retention (disabled by default)
+	v.SetDefault("history.retention_days", 0)
+	v.SetDefault("history.max_sessions", 0)
+
+	// Index command defaults
+	v.SetDefault("index.exclude_patterns", []string{})
+	v.SetDefault("index.workers", 0) // Half the CPU cores
+
+	// Update checks: 'rag-cli version --check' works, passive notices are opt-in
+	v.SetDefault("updates.check", true)
+	v.SetDefault("updates.notify", false)
+
+	// Debug logging is off unless --debug or debug.enabled is set
+	v.SetDefault("debug.enabled", false)
+	v.SetDefault("debug.log_file", "")
+	v.SetDefault("debug.max_size", 10485760) // 10MB in bytes
+
+	// Warnings and errors are logged to stderr
+	v.SetDefault("log.level", "warn")
+	v.SetDefault("log.to_file", false)
+	v.SetDefault("log.file", "")
+
+	// Network timeouts; 0 disables a limit
+	v.SetDefault("timeouts.llm", "5m") // Local models can take a while to answer
+	v.SetDefault("timeouts.embeddings", "30s")
+	v.SetDefault("timeouts.vector", "30s")
+	v.SetDefault("timeouts.dial", "10s")
+	v.SetDefault("timeouts.tls_handshake", "10s")
+
+	// Detected OS and tool versions are cached in the state directory
+	v.SetDefault("system_info.cache", true)
+	v.SetDefault("system_info.cache_ttl", "24h")
+	v.SetDefault("system_info.resources", true)
+
+	// Local usage metrics are opt-in
+	v.SetDefault("telemetry.local", false)
+
+	// Prompt template files; empty uses the built-in prompts
+	v.SetDefault("prompts.command_generation", "")
+	v.SetDefault("prompts.goal_check", "")
+	v.SetDefault("prompts.next_commands", "")
+	v.SetDefault("prompts.queue_decision", "")
+	v.SetDefault("prompts.final_answer", "")
+	v.SetDefault("prompts.models", []interface{}{})
+
+	// Command safety policy, on top of the built-in destructive-command rules
+	v.SetDefault("safety.blocklist", []interface{}{})
+	v.SetDefault("safety.dangerous", []interface{}{})
+	v.SetDefault("safety.allowlist", []string{})
+	v.SetDefault("safety.require_typed_confirmation", false)
+	v.SetDefault("safety.read_only", false)
+
+	// Auto-index defaults
+	v.SetDefault("auto_index.enabled", false)
+	v.SetDefault("auto_index.extensions", []string{".txt", ".md", ".py", ".js", ".go", ".json", ".yaml", ".yml"})
+	v.SetDefault("auto_index.max_file_size", 1048576) // 1MB in bytes
+	v.SetDefault("auto_index.exclude_patterns", []string{".git/*", "node_modules/*", "*.log", "tmp/*", "temp/*", "*.tmp"})
+	v.SetDefault("auto_index.batch_delay", "2s")
+}
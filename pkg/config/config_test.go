@@ -1,7 +1,11 @@
 package config
 
 import (
+	"os"
+	"path/filepath"
+	"reflect"
 	"testing"
+	"time"
 )
 
 func TestDefaultConfig(t *testing.T) {
@@ -32,3 +36,126 @@ func TestDefaultConfig(t *testing.T) {
 		t.Errorf("Expected default chunk overlap to be 200, got %d", cfg.Chunker.ChunkOverlap)
 	}
 }
+
+func TestDebugLogPath(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip("no home directory")
+	}
+	stateHome := t.TempDir()
+	t.Setenv("XDG_STATE_HOME", stateHome)
+
+	tests := []struct {
+		name     string
+		logFile  string
+		expected string
+	}{
+		{name: "default under the state directory", logFile: "", expected: filepath.Join(stateHome, "rag-cli", "debug.log")},
+		{name: "home relative", logFile: "~/logs/rag.log", expected: filepath.Join(home, "logs", "rag.log")},
+		{name: "absolute", logFile: "/var/log/rag-cli.log", expected: "/var/log/rag-cli.log"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, err := DebugConfig{LogFile: tt.logFile}.DebugLogPath()
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if path != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, path)
+			}
+		})
+	}
+}
+
+func TestChatConfigDefaults(t *testing.T) {
+	cfg, err := DefaultConfig()
+	if err != nil {
+		t.Fatalf("Failed to build default config: %v", err)
+	}
+
+	expected := ChatConfig{
+		MaxAttempts:         3,
+		MaxOutputLines:      50,
+		TruncateOutput:      true,
+		MaxInputChars:       0,
+		TopKDocuments:       5,
+		TopKHistory:         3,
+		AllowCommands:       true,
+		MemoryChars:         4000,
+		MaxContextChars:     12000,
+		ContextItemChars:    2000,
+		CommandTimeout:      time.Minute,
+		ShowSources:         true,
+		TranscriptMaxOutput: 10000,
+		AlwaysAllowMatch:    ApprovalMatchProgram,
+	}
+	got := cfg.Chat
+	if len(got.Commands.Allowlist) != 0 || len(got.Commands.Denylist) != 0 {
+		t.Errorf("Expected no command allowlist or denylist by default, got %+v", got.Commands)
+	}
+	if len(got.AlwaysAllow) != 0 {
+		t.Errorf("Expected no always-allow rules by default, got %v", got.AlwaysAllow)
+	}
+	got.Commands = CommandPolicyConfig{}
+	got.AlwaysAllow = nil
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected chat defaults %+v, got %+v", expected, cfg.Chat)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected defaults to be valid, got: %v", err)
+	}
+}
+
+func TestTimeoutsDefaults(t *testing.T) {
+	cfg, err := DefaultConfig()
+	if err != nil {
+		t.Fatalf("Failed to build default config: %v", err)
+	}
+
+	expected := TimeoutsConfig{
+		LLM:          5 * time.Minute,
+		Embeddings:   30 * time.Second,
+		Vector:       30 * time.Second,
+		Dial:         10 * time.Second,
+		TLSHandshake: 10 * time.Second,
+	}
+	if cfg.Timeouts != expected {
+		t.Errorf("Expected timeout defaults %+v, got %+v", expected, cfg.Timeouts)
+	}
+}
+
+func TestLogDefaults(t *testing.T) {
+	cfg, err := DefaultConfig()
+	if err != nil {
+		t.Fatalf("Failed to build default config: %v", err)
+	}
+
+	expected := LogConfig{Level: "warn"}
+	if cfg.Log != expected {
+		t.Errorf("Expected log defaults %+v, got %+v", expected, cfg.Log)
+	}
+}
+
+func TestSystemInfoDefaults(t *testing.T) {
+	cfg, err := DefaultConfig()
+	if err != nil {
+		t.Fatalf("Failed to build default config: %v", err)
+	}
+
+	expected := SystemInfoConfig{Cache: true, CacheTTL: 24 * time.Hour, Resources: true}
+	if cfg.SystemInfo != expected {
+		t.Errorf("Expected system_info defaults %+v, got %+v", expected, cfg.SystemInfo)
+	}
+}
+
+func TestTelemetryDefaults(t *testing.T) {
+	cfg, err := DefaultConfig()
+	if err != nil {
+		t.Fatalf("Failed to build default config: %v", err)
+	}
+
+	if cfg.Telemetry.Local {
+		t.Error("Expected local usage metrics to be off by default")
+	}
+}
@@ -0,0 +1,332 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+	"rag-cli/pkg/paths"
+)
+
+// ProjectConfigName is the file name of a per-project configuration file
+const ProjectConfigName = ".rag-cli.yaml"
+
+// explicitConfigFile is the config file chosen with --config, if any
+var explicitConfigFile string
+
+// SetConfigFile makes Load read path instead of ~/.rag-cli.yaml and any
+// project config file. An empty path restores the default discovery.
+func SetConfigFile(path string) {
+	explicitConfigFile = path
+}
+
+// UserConfigPath returns the location of the user configuration file: the
+// file set with SetConfigFile, else config.yaml in the rag-cli config
+// directory, unless only the legacy ~/.rag-cli.yaml exists
+func UserConfigPath() (string, error) {
+	if explicitConfigFile != "" {
+		return explicitConfigFile, nil
+	}
+	dirs, err := paths.Default()
+	if err != nil {
+		return "", err
+	}
+	return paths.PreferExisting(dirs.ConfigFile(), dirs.LegacyConfigFile()), nil
+}
+
+// ProjectConfigPath returns the project configuration file that applies to
+// the current directory, or where a new one would be created in it
+func ProjectConfigPath() (string, error) {
+	if path, err := findProjectConfigFromCwd(); err != nil || path != "" {
+		return path, err
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("failed to get working directory: %w", err)
+	}
+	return filepath.Join(cwd, ProjectConfigName), nil
+}
+
+// schema maps every configuration key to the Go type of its Config field
+func schema() map[string]reflect.Type {
+	keys := make(map[string]reflect.Type)
+	var walk func(prefix string, t reflect.Type)
+	walk = func(prefix string, t reflect.Type) {
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name := field.Tag.Get("mapstructure")
+			if name == "" {
+				continue
+			}
+			if prefix != "" {
+				name = prefix + "." + name
+			}
+			if field.Type.Kind() == reflect.Struct {
+				walk(name, field.Type)
+				continue
+			}
+			keys[name] = field.Type
+		}
+	}
+	walk("", reflect.TypeOf(Config{}))
+	return keys
+}
+
+// KnownKeys returns every configuration key, sorted
+func KnownKeys() []string {
+	types := schema()
+	keys := make([]string, 0, len(types))
+	for key := range types {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// UnknownKeys returns keys set in the loaded config file that do not match
+// any known setting, which usually indicates a typo
+func UnknownKeys() []string {
+	known := schema()
+	var unknown []string
+	for _, key := range viper.AllKeys() {
+		if _, ok := known[key]; !ok && viper.InConfig(key) {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+// ParseValue validates key against the configuration schema and converts
+// raw to the key's type. Lists are given as comma-separated values; lists of
+// entries with several fields, such as safety.blocklist, cannot be set this
+// way and must be edited in the config file.
+func ParseValue(key, raw string) (interface{}, error) {
+	t, ok := schema()[key]
+	if !ok {
+		return nil, fmt.Errorf("unknown config key %q (run 'rag-cli config show' to list keys)", key)
+	}
+
+	if t == durationType {
+		if _, err := time.ParseDuration(raw); err != nil {
+			return nil, fmt.Errorf("%s expects a duration such as 2s or 500ms, got %q", key, raw)
+		}
+		return raw, nil
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return raw, nil
+	case reflect.Bool:
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s expects true or false, got %q", key, raw)
+		}
+		return value, nil
+	case reflect.Int, reflect.Int64:
+		value, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s expects an integer, got %q", key, raw)
+		}
+		return value, nil
+	case reflect.Float64:
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s expects a number, got %q", key, raw)
+		}
+		return value, nil
+	case reflect.Slice:
+		if t.Elem().Kind() != reflect.String {
+			return nil, fmt.Errorf("%s is a list of entries with several fields and cannot be set from the command line; edit it in the config file instead", key)
+		}
+		raw = strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(raw), "["), "]")
+		values := []string{}
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				values = append(values, item)
+			}
+		}
+		return values, nil
+	default:
+		return nil, fmt.Errorf("%s has unsupported type %s", key, t)
+	}
+}
+
+// SetValue writes key to the YAML config file at path, creating the file if
+// needed. Other keys and comments in the file are preserved.
+func SetValue(path, key string, value interface{}) error {
+	doc, err := readConfigNode(path)
+	if err != nil {
+		return err
+	}
+
+	node := doc.Content[0]
+	parts := strings.Split(key, ".")
+	for _, part := range parts[:len(parts)-1] {
+		child := mappingValue(node, part)
+		if child == nil || child.Kind != yaml.MappingNode {
+			child = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			setMappingValue(node, part, child)
+		}
+		node = child
+	}
+
+	var valueNode yaml.Node
+	if err := valueNode.Encode(value); err != nil {
+		return fmt.Errorf("failed to encode value for %s: %w", key, err)
+	}
+	if existing := mappingValue(node, parts[len(parts)-1]); existing != nil {
+		valueNode.LineComment = existing.LineComment
+	}
+	setMappingValue(node, parts[len(parts)-1], &valueNode)
+
+	return writeConfigNode(path, doc)
+}
+
+// AppendValues adds values to the list at key in the YAML config file at
+// path, skipping any already there, and creates the file or key if needed
+func AppendValues(path, key string, values []string) error {
+	doc, err := readConfigNode(path)
+	if err != nil {
+		return err
+	}
+
+	var list []string
+	node := doc.Content[0]
+	for _, part := range strings.Split(key, ".") {
+		if node = mappingValue(node, part); node == nil {
+			break
+		}
+	}
+	if node != nil {
+		if err := node.Decode(&list); err != nil {
+			return fmt.Errorf("%s in %s is not a list: %w", key, path, err)
+		}
+	}
+	for _, value := range values {
+		if !containsString(list, value) {
+			list = append(list, value)
+		}
+	}
+	return SetValue(path, key, list)
+}
+
+// UnsetValue removes key from the YAML config file at path, along with any
+// sections left empty. It reports whether the key was present.
+func UnsetValue(path, key string) (bool, error) {
+	if _, ok := schema()[key]; !ok {
+		return false, fmt.Errorf("unknown config key %q (run 'rag-cli config show' to list keys)", key)
+	}
+
+	doc, err := readConfigNode(path)
+	if err != nil {
+		return false, err
+	}
+
+	if !removeKey(doc.Content[0], strings.Split(key, ".")) {
+		return false, nil
+	}
+	return true, writeConfigNode(path, doc)
+}
+
+// readConfigNode parses a config file into a YAML document node. A missing
+// or empty file yields an empty mapping.
+func readConfigNode(path string) (*yaml.Node, error) {
+	doc := &yaml.Node{Kind: yaml.DocumentNode}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	if len(data) > 0 {
+		if err := yaml.Unmarshal(data, doc); err != nil {
+			return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+	}
+
+	if len(doc.Content) == 0 {
+		doc.Kind = yaml.DocumentNode
+		doc.Content = []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}
+	}
+	if doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("config file %s is not a YAML mapping", path)
+	}
+	return doc, nil
+}
+
+func writeConfigNode(path string, doc *yaml.Node) error {
+	var buf strings.Builder
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(doc); err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(buf.String()), mode); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	return nil
+}
+
+// mappingValue returns the value node for key in a mapping node
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// setMappingValue replaces or appends the value for key in a mapping node
+func setMappingValue(node *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			node.Content[i+1] = value
+			return
+		}
+	}
+	node.Content = append(node.Content,
+		&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key},
+		value,
+	)
+}
+
+// removeKey deletes the nested key path from a mapping node, pruning
+// mappings that become empty
+func removeKey(node *yaml.Node, path []string) bool {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value != path[0] {
+			continue
+		}
+		if len(path) > 1 {
+			child := node.Content[i+1]
+			if child.Kind != yaml.MappingNode || !removeKey(child, path[1:]) {
+				return false
+			}
+			if len(child.Content) > 0 {
+				return true
+			}
+		}
+		node.Content = append(node.Content[:i], node.Content[i+2:]...)
+		return true
+	}
+	return false
+}
@@ -0,0 +1,180 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSetValueRoundTrip(t *testing.T) {
+	tests := []struct {
+		key  string
+		raw  string
+		read func(cfg *Config) interface{}
+		want interface{}
+	}{
+		{"llm.model", "llama3", func(cfg *Config) interface{} { return cfg.LLM.Model }, "llama3"},
+		{"vector.port", "9000", func(cfg *Config) interface{} { return cfg.Vector.Port }, 9000},
+		{"auto_index.enabled", "true", func(cfg *Config) interface{} { return cfg.AutoIndex.Enabled }, true},
+		{"auto_index.max_file_size", "2048", func(cfg *Config) interface{} { return cfg.AutoIndex.MaxFileSize }, int64(2048)},
+		{"vector.max_distance", "0.75", func(cfg *Config) interface{} { return cfg.Vector.MaxDistance }, 0.75},
+		{"auto_index.extensions", ".go, .md", func(cfg *Config) interface{} { return cfg.AutoIndex.Extensions }, []string{".go", ".md"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			loadWithConfigFile(t, "")
+			path, err := UserConfigPath()
+			if err != nil {
+				t.Fatalf("Failed to get config path: %v", err)
+			}
+
+			value, err := ParseValue(tt.key, tt.raw)
+			if err != nil {
+				t.Fatalf("Failed to parse value: %v", err)
+			}
+			if err := SetValue(path, tt.key, value); err != nil {
+				t.Fatalf("Failed to set value: %v", err)
+			}
+
+			loadWithConfigFile(t, readFile(t, path))
+			cfg, err := Load()
+			if err != nil {
+				t.Fatalf("Failed to load config: %v", err)
+			}
+			if got := tt.read(cfg); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSetValuePreservesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	original := "# LLM settings\nllm:\n  model: granite-code:3b # small and fast\n  port: 11434\nvector:\n  host: chroma\n"
+	if err := os.WriteFile(path, []byte(original), 0600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	if err := SetValue(path, "llm.model", "llama3"); err != nil {
+		t.Fatalf("Failed to set value: %v", err)
+	}
+
+	contents := readFile(t, path)
+	for _, want := range []string{"# LLM settings", "model: llama3 # small and fast", "port: 11434", "host: chroma"} {
+		if !strings.Contains(contents, want) {
+			t.Errorf("Expected config file to contain %q, got:\n%s", want, contents)
+		}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Failed to stat config file: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected file mode 0600 to be kept, got %v", info.Mode().Perm())
+	}
+}
+
+func TestUnsetValue(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("llm:\n  model: llama3\nchat:\n  max_attempts: 5\n"), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	t.Run("removes key and empty section", func(t *testing.T) {
+		removed, err := UnsetValue(path, "llm.model")
+		if err != nil {
+			t.Fatalf("Failed to unset value: %v", err)
+		}
+		if !removed {
+			t.Error("Expected key to be reported as removed")
+		}
+
+		contents := readFile(t, path)
+		if strings.Contains(contents, "llm") {
+			t.Errorf("Expected llm section to be removed, got:\n%s", contents)
+		}
+		if !strings.Contains(contents, "max_attempts: 5") {
+			t.Errorf("Expected other keys to be kept, got:\n%s", contents)
+		}
+	})
+
+	t.Run("missing key", func(t *testing.T) {
+		removed, err := UnsetValue(path, "llm.model")
+		if err != nil {
+			t.Fatalf("Failed to unset value: %v", err)
+		}
+		if removed {
+			t.Error("Expected missing key not to be reported as removed")
+		}
+	})
+}
+
+func TestAppendValues(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("chat:\n  max_attempts: 5 # a few tries\n  always_allow:\n    - git\n"), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	if err := AppendValues(path, "chat.always_allow", []string{"ls", "git"}); err != nil {
+		t.Fatalf("Failed to append values: %v", err)
+	}
+	loadWithConfigFile(t, readFile(t, path))
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if want := []string{"git", "ls"}; !reflect.DeepEqual(cfg.Chat.AlwaysAllow, want) {
+		t.Errorf("Expected %v, got %v", want, cfg.Chat.AlwaysAllow)
+	}
+	if contents := readFile(t, path); !strings.Contains(contents, "max_attempts: 5 # a few tries") {
+		t.Errorf("Expected other keys to be kept, got:\n%s", contents)
+	}
+
+	t.Run("creates the file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "new.yaml")
+		if err := AppendValues(path, "chat.always_allow", []string{"make test"}); err != nil {
+			t.Fatalf("Failed to append values: %v", err)
+		}
+		if contents := readFile(t, path); !strings.Contains(contents, "- make test") {
+			t.Errorf("Expected the value to be written, got:\n%s", contents)
+		}
+	})
+}
+
+func TestParseValueValidation(t *testing.T) {
+	tests := []struct {
+		key string
+		raw string
+	}{
+		{"llm.unknown", "x"},
+		{"llm", "x"},
+		{"vector.port", "eighty"},
+		{"auto_index.enabled", "maybe"},
+		{"auto_index.batch_delay", "2000"},
+		{"vector.max_distance", "close"},
+		{"safety.blocklist", "rm -rf"},
+		{"safety.dangerous", "dd *"},
+		{"prompts.models", "llama3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key+"="+tt.raw, func(t *testing.T) {
+			if _, err := ParseValue(tt.key, tt.raw); err == nil {
+				t.Errorf("Expected error for %s=%s", tt.key, tt.raw)
+			}
+		})
+	}
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", path, err)
+	}
+	return string(data)
+}
@@ -0,0 +1,86 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// URL returns the Ollama URL used for generation
+func (c LLMConfig) URL() string {
+	return endpointURL(c.BaseURL, c.Host, c.Port)
+}
+
+// URL returns the Ollama URL used for embeddings
+func (c EmbeddingsConfig) URL() string {
+	return endpointURL(c.BaseURL, c.Host, c.Port)
+}
+
+// URL returns the ChromaDB URL
+func (c VectorConfig) URL() string {
+	return endpointURL(c.BaseURL, c.Host, c.Port)
+}
+
+// OpenAIURL returns the URL of path, such as "/chat/completions", on an
+// OpenAI-compatible server at baseURL. The /v1 prefix is added unless
+// baseURL already ends with it, so both http://localhost:8080 and
+// https://openrouter.ai/api/v1 work.
+func OpenAIURL(baseURL, path string) string {
+	if strings.HasSuffix(baseURL, "/v1") {
+		return baseURL + path
+	}
+	return baseURL + "/v1" + path
+}
+
+// endpointURL returns baseURL when it is set, and http://host:port otherwise
+func endpointURL(baseURL, host string, port int) string {
+	if baseURL != "" {
+		return strings.TrimSuffix(baseURL, "/")
+	}
+	return "http://" + net.JoinHostPort(host, strconv.Itoa(port))
+}
+
+// checkEndpoints reports a base_url that disagrees with a host or port set
+// alongside it. base_url wins, so the other setting would be silently
+// ignored. Defaults never conflict.
+func checkEndpoints(c *Config) error {
+	var problems []Problem
+	for _, endpoint := range []struct {
+		section string
+		baseURL string
+		host    string
+		port    int
+	}{
+		{"llm", c.LLM.BaseURL, c.LLM.Host, c.LLM.Port},
+		{"embeddings", c.Embeddings.BaseURL, c.Embeddings.Host, c.Embeddings.Port},
+		{"vector", c.Vector.BaseURL, c.Vector.Host, c.Vector.Port},
+	} {
+		section, baseURL := endpoint.section, endpoint.baseURL
+		parsed, err := url.Parse(baseURL)
+		if baseURL == "" || err != nil || parsed.Host == "" {
+			continue // Validate reports malformed URLs
+		}
+
+		port := parsed.Port()
+		if port == "" {
+			port = map[string]string{"http": "80", "https": "443"}[parsed.Scheme]
+		}
+		for _, setting := range []struct{ key, value, urlValue string }{
+			{section + ".host", endpoint.host, parsed.Hostname()},
+			{section + ".port", strconv.Itoa(endpoint.port), port},
+		} {
+			if SourceOf(setting.key) != SourceDefault && setting.value != setting.urlValue {
+				problems = append(problems, Problem{
+					Key:     section + ".base_url",
+					Message: fmt.Sprintf("%s conflicts with %s (%s); base_url wins, so set only one of them", baseURL, setting.key, setting.value),
+				})
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
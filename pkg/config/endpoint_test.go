@@ -0,0 +1,84 @@
+package config
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		baseURL  string
+		host     string
+		port     int
+		expected string
+	}{
+		{name: "built from host and port", host: "localhost", port: 11434, expected: "http://localhost:11434"},
+		{name: "base_url wins", baseURL: "https://ollama.example.com", host: "localhost", port: 11434, expected: "https://ollama.example.com"},
+		{name: "base_url trailing slash", baseURL: "http://gpu-box:11434/", host: "localhost", port: 11434, expected: "http://gpu-box:11434"},
+		{name: "base_url path prefix", baseURL: "https://proxy.example.com/ollama", host: "localhost", port: 11434, expected: "https://proxy.example.com/ollama"},
+		{name: "IPv6 host", host: "::1", port: 8000, expected: "http://[::1]:8000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clients := map[string]string{
+				"llm":        LLMConfig{BaseURL: tt.baseURL, Host: tt.host, Port: tt.port}.URL(),
+				"embeddings": EmbeddingsConfig{BaseURL: tt.baseURL, Host: tt.host, Port: tt.port}.URL(),
+				"vector":     VectorConfig{BaseURL: tt.baseURL, Host: tt.host, Port: tt.port}.URL(),
+			}
+			for client, got := range clients {
+				if got != tt.expected {
+					t.Errorf("Expected %s URL %s, got %s", client, tt.expected, got)
+				}
+			}
+		})
+	}
+}
+
+func TestLoad_EndpointConflicts(t *testing.T) {
+	tests := []struct {
+		name        string
+		file        string
+		env         map[string]string
+		expectedKey string
+	}{
+		{name: "defaults", file: ""},
+		{name: "host and port only", file: "vector:\n  host: chroma\n  port: 9000\n"},
+		{name: "base_url only", file: "llm:\n  base_url: https://ollama.example.com\n"},
+		{name: "base_url agrees with host and port", file: "embeddings:\n  host: gpu-box\n  port: 11434\n  base_url: http://gpu-box:11434\n"},
+		{name: "base_url agrees with the scheme's default port", file: "llm:\n  port: 443\n  base_url: https://ollama.example.com\n"},
+		{name: "host conflicts", file: "llm:\n  host: gpu-box\n  base_url: http://localhost:11434\n", expectedKey: "llm.base_url"},
+		{name: "port conflicts", file: "vector:\n  port: 9000\n  base_url: http://localhost:8000\n", expectedKey: "vector.base_url"},
+		{name: "env host conflicts", file: "embeddings:\n  base_url: http://localhost:11434\n", env: map[string]string{"RAG_CLI_EMBEDDINGS_HOST": "gpu-box"}, expectedKey: "embeddings.base_url"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useConfigFile(t, tt.file)
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+
+			_, err := Load()
+			if tt.expectedKey == "" {
+				if err != nil {
+					t.Fatalf("Expected no error, got: %v", err)
+				}
+				return
+			}
+
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("Expected a ValidationError, got: %v", err)
+			}
+			if len(validationErr.Problems) != 1 || validationErr.Problems[0].Key != tt.expectedKey {
+				t.Fatalf("Expected one problem for %s, got: %v", tt.expectedKey, err)
+			}
+			if !strings.Contains(validationErr.Problems[0].Message, "conflicts") {
+				t.Errorf("Expected a conflict message, got: %s", validationErr.Problems[0].Message)
+			}
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// projectConfigDirName is the directory that can hold a project's config.yaml
+// instead of a .rag-cli.yaml file
+const projectConfigDirName = ".rag-cli"
+
+// loadedFiles records the config files read by the last Load so values can
+// be traced back to the file that set them
+var loadedFiles struct {
+	user    string
+	project string
+	// projectKeys holds the settings taken from the project file, to tell
+	// its keys apart from the user file's after they are merged
+	projectKeys *viper.Viper
+	// ignoredKeys are the keys in the project file that were not applied
+	// because ProjectScoped rejects them
+	ignoredKeys []string
+}
+
+// projectScopedKeys are the settings, or sections ending in ".", a project
+// config file may set: where the project's documents go and how they are
+// indexed. Anything else, such as the model server, always-allow rules or
+// the command policy, would let a cloned repository change what rag-cli
+// does on the user's machine.
+var projectScopedKeys = []string{
+	"vector.collection", "vector.command_collection", "vector.auto_index_collection",
+	"auto_index.", "chunker.", "index.exclude_patterns",
+}
+
+// projectTighteningKeys are the safety settings a project config file may
+// only make stricter: switches it can turn on but not off, and rules it can
+// add to the user's but not replace them with
+var projectTighteningKeys = map[string]bool{
+	"safety.read_only":                  true,
+	"safety.require_typed_confirmation": true,
+	"safety.blocklist":                  true,
+	"safety.dangerous":                  true,
+}
+
+// ProjectScoped reports whether a project config file may set key. Other
+// keys in a project file are ignored with a warning.
+func ProjectScoped(key string) bool {
+	if projectTighteningKeys[key] {
+		return true
+	}
+	for _, scoped := range projectScopedKeys {
+		if key == scoped || strings.HasSuffix(scoped, ".") && strings.HasPrefix(key, scoped) {
+			return true
+		}
+	}
+	return false
+}
+
+// FindProjectConfig looks for .rag-cli.yaml or .rag-cli/config.yaml in start
+// and each of its parents and returns the first one found, or "" if there is
+// none. The search never reads the home directory or any directory above it,
+// so the user config is not mistaken for a project config, and it never reads
+// the filesystem root.
+func FindProjectConfig(start, home string) (string, error) {
+	dir, err := filepath.Abs(start)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", start, err)
+	}
+	if home != "" {
+		if home, err = filepath.Abs(home); err != nil {
+			return "", fmt.Errorf("failed to resolve %s: %w", home, err)
+		}
+	}
+
+	for dir != filepath.Dir(dir) && !containsPath(dir, home) {
+		for _, candidate := range []string{
+			filepath.Join(dir, ProjectConfigName),
+			filepath.Join(dir, projectConfigDirName, "config.yaml"),
+		} {
+			info, err := os.Stat(candidate)
+			if err == nil && !info.IsDir() {
+				return candidate, nil
+			}
+		}
+		dir = filepath.Dir(dir)
+	}
+	return "", nil
+}
+
+// containsPath reports whether path is dir or inside it
+func containsPath(dir, path string) bool {
+	return path != "" && (path == dir || strings.HasPrefix(path, dir+string(filepath.Separator)))
+}
+
+// findProjectConfigFromCwd runs FindProjectConfig from the working directory
+func findProjectConfigFromCwd() (string, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("failed to get working directory: %w", err)
+	}
+	home, _ := os.UserHomeDir()
+	return FindProjectConfig(cwd, home)
+}
+
+// mergeProjectConfig reads a project config file and merges the settings
+// ProjectScoped allows over the values already loaded into v, so the project
+// file wins. Safety switches are only taken when they turn a check on, and
+// safety rules are added to those already loaded.
+func mergeProjectConfig(v *viper.Viper, path string) error {
+	project := viper.New()
+	project.SetConfigFile(path)
+	if err := project.ReadInConfig(); err != nil {
+		return fmt.Errorf("failed to read project config file %s: %w", path, err)
+	}
+
+	settings := make(map[string]interface{})
+	var ignored []string
+	for _, key := range project.AllKeys() {
+		value := project.Get(key)
+		switch {
+		case !ProjectScoped(key):
+			ignored = append(ignored, key)
+			continue
+		case key == "safety.read_only" || key == "safety.require_typed_confirmation":
+			if !project.GetBool(key) {
+				continue
+			}
+		case key == "safety.blocklist" || key == "safety.dangerous":
+			rules, ok := value.([]interface{})
+			if !ok {
+				return fmt.Errorf("project config file %s: %s must be a list", path, key)
+			}
+			value = append(slices.Clone(toList(v.Get(key))), rules...)
+		}
+		setNested(settings, key, value)
+	}
+
+	scoped := viper.New()
+	if err := scoped.MergeConfigMap(settings); err != nil {
+		return fmt.Errorf("failed to merge project config file %s: %w", path, err)
+	}
+	if err := v.MergeConfigMap(settings); err != nil {
+		return fmt.Errorf("failed to merge project config file %s: %w", path, err)
+	}
+	loadedFiles.project = path
+	loadedFiles.projectKeys = scoped
+	loadedFiles.ignoredKeys = ignored
+	return nil
+}
+
+// toList returns a list setting as a slice, or nil when it is not one
+func toList(value interface{}) []interface{} {
+	list, _ := value.([]interface{})
+	return list
+}
+
+// setNested stores value under a dotted key in a map of nested sections
+func setNested(settings map[string]interface{}, key string, value interface{}) {
+	section, name, nested := strings.Cut(key, ".")
+	if !nested {
+		settings[key] = value
+		return
+	}
+	inner, ok := settings[section].(map[string]interface{})
+	if !ok {
+		inner = make(map[string]interface{})
+		settings[section] = inner
+	}
+	setNested(inner, name, value)
+}
+
+// IgnoredProjectKeys returns the keys in the project config file read by
+// the last Load that were not applied because a project file may not set
+// them
+func IgnoredProjectKeys() []string {
+	return loadedFiles.ignoredKeys
+}
+
+// ConfigFiles returns the config files read by the last Load: the user
+// config and the project config, either of which may be ""
+func ConfigFiles() (user, project string) {
+	return loadedFiles.user, loadedFiles.project
+}
+
+// FileOf returns the config file that sets key, or "" when no file does
+func FileOf(key string) string {
+	if loadedFiles.projectKeys != nil && loadedFiles.projectKeys.InConfig(key) {
+		return loadedFiles.project
+	}
+	if viper.InConfig(key) {
+		return loadedFiles.user
+	}
+	return ""
+}
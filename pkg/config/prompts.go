@@ -0,0 +1,211 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// Prompt names, as used for keys in the prompts section
+const (
+	PromptCommandGeneration = "command_generation"
+	PromptGoalCheck         = "goal_check"
+	PromptNextCommands      = "next_commands"
+	PromptQueueDecision     = "queue_decision"
+	PromptFinalAnswer       = "final_answer"
+)
+
+// PromptData is what a custom prompt template is rendered with. Fields not
+// used by a prompt are left empty.
+type PromptData struct {
+	Request           string   // The user's request
+	Context           []string // Retrieved document chunks (command_generation)
+	SystemHints       string   // Command syntax hints for this system (command_generation)
+	Conversation      string   // Earlier turns of the chat as User:/Assistant: lines, or "" (command_generation)
+	ExecutionLog      string   // Commands run so far and their output
+	RemainingCommands []string // Commands still queued (queue_decision)
+	HadError          bool     // Whether the last command failed
+}
+
+// promptPlaceholders lists the PromptData fields each prompt template must
+// use, so a custom prompt cannot silently drop the user's request or the
+// command output the model needs to see
+var promptPlaceholders = map[string][]string{
+	PromptCommandGeneration: {"Request"},
+	PromptGoalCheck:         {"Request", "ExecutionLog"},
+	PromptNextCommands:      {"Request", "ExecutionLog"},
+	PromptQueueDecision:     {"Request", "ExecutionLog", "RemainingCommands"},
+	PromptFinalAnswer:       {"Request", "ExecutionLog"},
+}
+
+// PromptNames returns the prompts that can be customized, sorted
+func PromptNames() []string {
+	names := make([]string, 0, len(promptPlaceholders))
+	for name := range promptPlaceholders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// PromptFiles maps prompt names to template files. Empty paths use the
+// built-in prompt.
+type PromptFiles struct {
+	CommandGeneration string `mapstructure:"command_generation"`
+	GoalCheck         string `mapstructure:"goal_check"`
+	NextCommands      string `mapstructure:"next_commands"`
+	QueueDecision     string `mapstructure:"queue_decision"`
+	FinalAnswer       string `mapstructure:"final_answer"`
+}
+
+// byName returns the configured file for each prompt, keyed by prompt name
+func (f PromptFiles) byName() map[string]string {
+	return map[string]string{
+		PromptCommandGeneration: f.CommandGeneration,
+		PromptGoalCheck:         f.GoalCheck,
+		PromptNextCommands:      f.NextCommands,
+		PromptQueueDecision:     f.QueueDecision,
+		PromptFinalAnswer:       f.FinalAnswer,
+	}
+}
+
+// ModelPrompts overrides prompt files when llm.model is Model
+type ModelPrompts struct {
+	Model       string `mapstructure:"model"`
+	PromptFiles `mapstructure:",squash"`
+}
+
+// files returns the section's own prompt files, without model overrides
+func (c PromptsConfig) files() PromptFiles {
+	return PromptFiles{
+		CommandGeneration: c.CommandGeneration,
+		GoalCheck:         c.GoalCheck,
+		NextCommands:      c.NextCommands,
+		QueueDecision:     c.QueueDecision,
+		FinalAnswer:       c.FinalAnswer,
+	}
+}
+
+// PromptFile returns the template file for the named prompt when used with
+// model: a matching model override wins over the section's own file. ""
+// means the built-in prompt is used.
+func (c PromptsConfig) PromptFile(name, model string) string {
+	for _, override := range c.Models {
+		if override.Model == model {
+			if path := override.byName()[name]; path != "" {
+				return path
+			}
+		}
+	}
+	return c.files().byName()[name]
+}
+
+// Templates parses the custom prompt templates that apply to model, keyed by
+// prompt name. Prompts without a custom file are left out.
+func (c PromptsConfig) Templates(model string) (map[string]*template.Template, error) {
+	templates := make(map[string]*template.Template)
+	for _, name := range PromptNames() {
+		path := c.PromptFile(name, model)
+		if path == "" {
+			continue
+		}
+		tmpl, err := loadPromptTemplate(name, path)
+		if err != nil {
+			return nil, err
+		}
+		templates[name] = tmpl
+	}
+	return templates, nil
+}
+
+// RenderPrompt renders a custom prompt template
+func RenderPrompt(tmpl *template.Template, data PromptData) (string, error) {
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("failed to render prompt %s: %w", tmpl.Name(), err)
+	}
+	return b.String(), nil
+}
+
+// loadPromptTemplate reads and parses a prompt template, checking that it
+// uses the placeholders the prompt requires and no unknown ones
+func loadPromptTemplate(name, path string) (*template.Template, error) {
+	data, err := os.ReadFile(expandHome(path))
+	if err != nil {
+		return nil, fmt.Errorf("cannot read prompt file: %w", err)
+	}
+	tmpl, err := template.New(name).Parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid prompt template %s: %w", path, err)
+	}
+
+	used := make(map[string]bool)
+	collectFields(tmpl.Tree.Root, used)
+	var missing []string
+	for _, field := range promptPlaceholders[name] {
+		if !used[field] {
+			missing = append(missing, "{{."+field+"}}")
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("prompt template %s must use %s", path, strings.Join(missing, ", "))
+	}
+
+	// Rendering sample data catches placeholders PromptData does not have
+	if err := tmpl.Execute(io.Discard, PromptData{}); err != nil {
+		return nil, fmt.Errorf("invalid prompt template %s: %w", path, err)
+	}
+	return tmpl, nil
+}
+
+// collectFields records the top-level fields referenced by a template
+func collectFields(node parse.Node, used map[string]bool) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			collectFields(child, used)
+		}
+	case *parse.ActionNode:
+		collectFields(n.Pipe, used)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			for _, arg := range cmd.Args {
+				collectFields(arg, used)
+			}
+		}
+	case *parse.FieldNode:
+		used[n.Ident[0]] = true
+	case *parse.IfNode:
+		collectBranch(&n.BranchNode, used)
+	case *parse.RangeNode:
+		collectBranch(&n.BranchNode, used)
+	case *parse.WithNode:
+		collectBranch(&n.BranchNode, used)
+	}
+}
+
+func collectBranch(n *parse.BranchNode, used map[string]bool) {
+	collectFields(n.Pipe, used)
+	collectFields(n.List, used)
+	collectFields(n.ElseList, used)
+}
+
+// expandHome expands a leading ~/ to the home directory
+func expandHome(path string) string {
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return home + path[1:]
+		}
+	}
+	return path
+}
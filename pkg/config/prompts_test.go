@@ -0,0 +1,147 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writePrompt(t *testing.T, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "prompt.tmpl")
+	if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatalf("Failed to write prompt file: %v", err)
+	}
+	return path
+}
+
+func TestLoadPromptTemplate(t *testing.T) {
+	tests := []struct {
+		name      string
+		prompt    string
+		contents  string
+		wantError string
+	}{
+		{name: "all placeholders", prompt: PromptGoalCheck, contents: "Did {{.ExecutionLog}} achieve {{.Request}}? YES or NO: "},
+		{name: "placeholders with spaces", prompt: PromptFinalAnswer, contents: "{{ .Request }}\n{{ .ExecutionLog }}"},
+		{name: "placeholders inside blocks", prompt: PromptQueueDecision, contents: "{{.Request}}{{if .HadError}}failed{{end}}{{with .ExecutionLog}}{{.}}{{end}}{{range .RemainingCommands}}{{.}}\n{{end}}"},
+		{name: "optional placeholders", prompt: PromptCommandGeneration, contents: "{{.SystemHints}}{{range .Context}}- {{.}}\n{{end}}{{.Request}}"},
+		{name: "missing request", prompt: PromptGoalCheck, contents: "Log: {{.ExecutionLog}}", wantError: "must use {{.Request}}"},
+		{name: "missing remaining commands", prompt: PromptQueueDecision, contents: "{{.Request}} {{.ExecutionLog}}", wantError: "must use {{.RemainingCommands}}"},
+		{name: "unknown placeholder", prompt: PromptFinalAnswer, contents: "{{.Request}} {{.ExecutionLog}} {{.Question}}", wantError: "can't evaluate field Question"},
+		{name: "syntax error", prompt: PromptFinalAnswer, contents: "{{.Request", wantError: "invalid prompt template"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadPromptTemplate(tt.prompt, writePrompt(t, tt.contents))
+			if tt.wantError == "" {
+				if err != nil {
+					t.Fatalf("Expected no error, got: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantError) {
+				t.Errorf("Expected error containing %q, got: %v", tt.wantError, err)
+			}
+		})
+	}
+
+	t.Run("missing file", func(t *testing.T) {
+		_, err := loadPromptTemplate(PromptGoalCheck, filepath.Join(t.TempDir(), "missing.tmpl"))
+		if err == nil || !strings.Contains(err.Error(), "cannot read prompt file") {
+			t.Errorf("Expected a read error, got: %v", err)
+		}
+	})
+}
+
+func TestPromptsConfig_Templates(t *testing.T) {
+	general := writePrompt(t, "general {{.Request}} {{.ExecutionLog}}")
+	llama := writePrompt(t, "llama {{.Request}} {{.ExecutionLog}}")
+	prompts := PromptsConfig{
+		FinalAnswer: general,
+		Models: []ModelPrompts{
+			{Model: "llama3.1:8b", PromptFiles: PromptFiles{FinalAnswer: llama}},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		prompts  PromptsConfig
+		model    string
+		expected string // Rendered final_answer prompt, or "" for the built-in
+	}{
+		{name: "built-in when nothing is configured", prompts: PromptsConfig{}, model: "llama3.1:8b", expected: ""},
+		{name: "section file", prompts: prompts, model: "granite-code:3b", expected: "general req log"},
+		{name: "model override wins", prompts: prompts, model: "llama3.1:8b", expected: "llama req log"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			templates, err := tt.prompts.Templates(tt.model)
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if _, ok := templates[PromptGoalCheck]; ok {
+				t.Error("Expected unconfigured prompts to fall back to the built-ins")
+			}
+
+			tmpl, ok := templates[PromptFinalAnswer]
+			if tt.expected == "" {
+				if ok {
+					t.Errorf("Expected the built-in final_answer prompt, got template %s", tmpl.Name())
+				}
+				return
+			}
+			if !ok {
+				t.Fatal("Expected a custom final_answer template")
+			}
+			rendered, err := RenderPrompt(tmpl, PromptData{Request: "req", ExecutionLog: "log"})
+			if err != nil {
+				t.Fatalf("Expected no error, got: %v", err)
+			}
+			if rendered != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, rendered)
+			}
+		})
+	}
+}
+
+func TestLoad_Prompts(t *testing.T) {
+	t.Run("valid templates", func(t *testing.T) {
+		path := writePrompt(t, "{{.Request}} {{.ExecutionLog}}")
+		useConfigFile(t, "prompts:\n  goal_check: "+path+"\n  models:\n    - model: llama3\n      final_answer: "+path+"\n")
+
+		cfg, err := Load()
+		if err != nil {
+			t.Fatalf("Failed to load config: %v", err)
+		}
+		if cfg.Prompts.GoalCheck != path {
+			t.Errorf("Expected goal_check %s, got %s", path, cfg.Prompts.GoalCheck)
+		}
+		if len(cfg.Prompts.Models) != 1 || cfg.Prompts.Models[0].Model != "llama3" || cfg.Prompts.Models[0].FinalAnswer != path {
+			t.Errorf("Expected a llama3 final_answer override, got %+v", cfg.Prompts.Models)
+		}
+	})
+
+	t.Run("invalid templates", func(t *testing.T) {
+		path := writePrompt(t, "{{.ExecutionLog}}")
+		useConfigFile(t, "prompts:\n  goal_check: "+path+"\n  models:\n    - final_answer: "+path+"\n")
+
+		_, err := Load()
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) {
+			t.Fatalf("Expected a ValidationError, got: %v", err)
+		}
+		var keys []string
+		for _, problem := range validationErr.Problems {
+			keys = append(keys, problem.Key)
+		}
+		expected := []string{"prompts.goal_check", "prompts.models[0]", "prompts.models[0].final_answer"}
+		if strings.Join(keys, " ") != strings.Join(expected, " ") {
+			t.Errorf("Expected problems for %v, got %v", expected, keys)
+		}
+	})
+}
@@ -0,0 +1,94 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Severities of a safety.blocklist rule
+const (
+	SeverityBlock = "block" // Refuse the command
+	SeverityWarn  = "warn"  // Run it after showing a warning
+)
+
+// regexPatternPrefix marks a safety pattern as a regular expression
+const regexPatternPrefix = "re:"
+
+// CompilePattern compiles a safety pattern. A re: prefix marks a regular
+// expression, which may match anywhere in the command. Anything else is a
+// glob that must match the whole command, where * matches any text and ?
+// matches a single character.
+func CompilePattern(pattern string) (*regexp.Regexp, error) {
+	if expr, ok := strings.CutPrefix(pattern, regexPatternPrefix); ok {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression %q: %w", expr, err)
+		}
+		return re, nil
+	}
+	if strings.TrimSpace(pattern) == "" {
+		return nil, fmt.Errorf("pattern must not be empty")
+	}
+
+	var expr strings.Builder
+	expr.WriteString(`^\s*`)
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			expr.WriteString(".*")
+		case '?':
+			expr.WriteString(".")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	expr.WriteString(`\s*$`)
+	return regexp.Compile(expr.String())
+}
+
+// safetyProblems reports blocklist, dangerous and allowlist entries that
+// cannot be used
+func (c SafetyConfig) safetyProblems() []Problem {
+	var problems []Problem
+	for i, rule := range c.Blocklist {
+		key := fmt.Sprintf("safety.blocklist[%d]", i)
+		if _, err := CompilePattern(rule.Pattern); err != nil {
+			problems = append(problems, Problem{Key: key, Message: err.Error()})
+		}
+		if rule.Severity != "" && rule.Severity != SeverityBlock && rule.Severity != SeverityWarn {
+			problems = append(problems, Problem{Key: key, Message: fmt.Sprintf("severity must be %q or %q, got %q", SeverityBlock, SeverityWarn, rule.Severity)})
+		}
+	}
+	for i, rule := range c.Dangerous {
+		key := fmt.Sprintf("safety.dangerous[%d]", i)
+		if _, err := CompilePattern(rule.Pattern); err != nil {
+			problems = append(problems, Problem{Key: key, Message: err.Error()})
+		}
+		if rule.Severity != "" {
+			problems = append(problems, Problem{Key: key, Message: fmt.Sprintf("severity only applies to safety.blocklist, got %q", rule.Severity)})
+		}
+	}
+	for i, pattern := range c.Allowlist {
+		if _, err := CompilePattern(pattern); err != nil {
+			problems = append(problems, Problem{Key: fmt.Sprintf("safety.allowlist[%d]", i), Message: err.Error()})
+		}
+	}
+	return problems
+}
+
+// policyProblems reports chat.commands entries that cannot be used
+func (c CommandPolicyConfig) policyProblems() []Problem {
+	var problems []Problem
+	for i, name := range c.Allowlist {
+		if _, err := CompilePattern(name); err != nil {
+			problems = append(problems, Problem{Key: fmt.Sprintf("chat.commands.allowlist[%d]", i), Message: err.Error()})
+		}
+	}
+	for i, name := range c.Denylist {
+		if _, err := CompilePattern(name); err != nil {
+			problems = append(problems, Problem{Key: fmt.Sprintf("chat.commands.denylist[%d]", i), Message: err.Error()})
+		}
+	}
+	return problems
+}
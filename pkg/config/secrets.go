@@ -0,0 +1,159 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// secretResolvers look up the value behind a "scheme:reference" secret, so
+// credentials can stay out of config files
+var secretResolvers = map[string]func(reference string) (string, error){
+	"env":      resolveEnvSecret,
+	"keychain": resolveKeychainSecret,
+}
+
+// keychainLookup reads a password from the platform credential store; tests
+// replace it
+var keychainLookup = platformKeychainLookup
+
+// keychainEntry names a password in the platform credential store
+type keychainEntry struct {
+	service string
+	account string
+}
+
+// keychainResult is what looking up a keychainEntry found
+type keychainResult struct {
+	value string
+	err   error
+}
+
+// keychainCache keeps each keychain lookup for the rest of the process.
+// Config is loaded several times per invocation, and each lookup starts
+// security or secret-tool, which may prompt to unlock the keychain.
+var keychainCache = struct {
+	sync.Mutex
+	results map[keychainEntry]keychainResult
+}{results: make(map[keychainEntry]keychainResult)}
+
+// cachedKeychainLookup is keychainLookup, run once per entry
+func cachedKeychainLookup(service, account string) (string, error) {
+	keychainCache.Lock()
+	defer keychainCache.Unlock()
+	entry := keychainEntry{service: service, account: account}
+	result, ok := keychainCache.results[entry]
+	if !ok {
+		result.value, result.err = keychainLookup(service, account)
+		keychainCache.results[entry] = result
+	}
+	return result.value, result.err
+}
+
+// isSecretReference reports whether value names a secret to resolve rather
+// than holding the secret itself
+func isSecretReference(value string) bool {
+	scheme, _, ok := strings.Cut(value, ":")
+	_, known := secretResolvers[scheme]
+	return ok && known
+}
+
+// resolveSecrets replaces secret references in c with the values they point
+// to. Failures are kept for Validate to report with the other problems.
+func (c *Config) resolveSecrets() {
+	c.secretErrors = nil
+	for key, field := range secretFields(c) {
+		if !isSecretReference(*field) {
+			continue
+		}
+		scheme, reference, _ := strings.Cut(*field, ":")
+		value, err := secretResolvers[scheme](reference)
+		if err != nil {
+			if c.secretErrors == nil {
+				c.secretErrors = make(map[string]error)
+			}
+			c.secretErrors[key] = fmt.Errorf("cannot resolve %s: %w", *field, err)
+			continue
+		}
+		*field = value
+	}
+}
+
+// secretFields returns the string settings holding credentials, by key
+func secretFields(c *Config) map[string]*string {
+	fields := make(map[string]*string)
+	var walk func(prefix string, v reflect.Value)
+	walk = func(prefix string, v reflect.Value) {
+		for i := 0; i < v.NumField(); i++ {
+			name := v.Type().Field(i).Tag.Get("mapstructure")
+			if name == "" {
+				continue
+			}
+			if prefix != "" {
+				name = prefix + "." + name
+			}
+			field := v.Field(i)
+			switch {
+			case field.Kind() == reflect.Struct:
+				walk(name, field)
+			case field.Kind() == reflect.String && isSecretKey(name):
+				fields[name] = field.Addr().Interface().(*string)
+			}
+		}
+	}
+	walk("", reflect.ValueOf(c).Elem())
+	return fields
+}
+
+// resolveEnvSecret reads "env:NAME" from the environment
+func resolveEnvSecret(name string) (string, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+	return value, nil
+}
+
+// resolveKeychainSecret reads "keychain:service/account" from the platform
+// credential store
+func resolveKeychainSecret(reference string) (string, error) {
+	slash := strings.LastIndex(reference, "/")
+	if slash <= 0 || slash == len(reference)-1 {
+		return "", fmt.Errorf("expected keychain:<service>/<account>")
+	}
+	value, err := cachedKeychainLookup(reference[:slash], reference[slash+1:])
+	if err != nil {
+		return "", err
+	}
+	if value == "" {
+		return "", fmt.Errorf("the keychain entry is empty")
+	}
+	return value, nil
+}
+
+// platformKeychainLookup uses the macOS keychain or the freedesktop Secret
+// Service (through secret-tool) on Linux
+func platformKeychainLookup(service, account string) (string, error) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("security", "find-generic-password", "-s", service, "-a", account, "-w")
+	case "linux", "freebsd", "openbsd", "netbsd":
+		cmd = exec.Command("secret-tool", "lookup", "service", service, "account", account)
+	default:
+		return "", fmt.Errorf("keychain references are not supported on %s; use env: instead", runtime.GOOS)
+	}
+
+	output, err := cmd.Output()
+	if err != nil {
+		if _, missing := err.(*exec.Error); missing {
+			return "", fmt.Errorf("%s is not available: %w", cmd.Path, err)
+		}
+		return "", fmt.Errorf("no keychain entry for service %q and account %q", service, account)
+	}
+	return strings.TrimRight(string(output), "\r\n"), nil
+}
@@ -0,0 +1,133 @@
+package config
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestLoad_SecretReferences(t *testing.T) {
+	t.Run("env reference is resolved", func(t *testing.T) {
+		t.Setenv("TEST_OPENAI_API_KEY", "sk-from-env")
+		useConfigFile(t, "llm:\n  api_key: env:TEST_OPENAI_API_KEY\n")
+
+		cfg, err := Load()
+		if err != nil {
+			t.Fatalf("Failed to load config: %v", err)
+		}
+		if cfg.LLM.APIKey != "sk-from-env" {
+			t.Errorf("Expected the key from the environment, got %q", cfg.LLM.APIKey)
+		}
+
+		// config show prints the reference, never the resolved key
+		setting := findSetting(t, "llm.api_key")
+		if setting.Value != "env:TEST_OPENAI_API_KEY" {
+			t.Errorf("Expected the reference to be shown, got %v", setting.Value)
+		}
+	})
+
+	t.Run("unset env variable is reported", func(t *testing.T) {
+		useConfigFile(t, "llm:\n  api_key: env:TEST_MISSING_API_KEY\n")
+
+		_, err := Load()
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) {
+			t.Fatalf("Expected a validation error, got: %v", err)
+		}
+		expected := "llm.api_key: cannot resolve env:TEST_MISSING_API_KEY: environment variable TEST_MISSING_API_KEY is not set"
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected %q, got: %v", expected, err)
+		}
+	})
+
+	t.Run("keychain reference", func(t *testing.T) {
+		useKeychain(t, func(service, account string) (string, error) {
+			if service == "rag-cli" && account == "openai" {
+				return "sk-from-keychain", nil
+			}
+			return "", errors.New("no such entry")
+		})
+
+		useConfigFile(t, "llm:\n  api_key: keychain:rag-cli/openai\n")
+		cfg, err := Load()
+		if err != nil {
+			t.Fatalf("Failed to load config: %v", err)
+		}
+		if cfg.LLM.APIKey != "sk-from-keychain" {
+			t.Errorf("Expected the key from the keychain, got %q", cfg.LLM.APIKey)
+		}
+
+		useConfigFile(t, "llm:\n  api_key: keychain:openai\n")
+		if _, err := Load(); err == nil || !strings.Contains(err.Error(), "keychain:<service>/<account>") {
+			t.Errorf("Expected a format error, got: %v", err)
+		}
+	})
+
+	t.Run("keychain is read once per process", func(t *testing.T) {
+		lookups := 0
+		useKeychain(t, func(service, account string) (string, error) {
+			lookups++
+			return "sk-from-keychain", nil
+		})
+
+		useConfigFile(t, "llm:\n  api_key: keychain:rag-cli/openai\nembeddings:\n  api_key: keychain:rag-cli/openai\n")
+		for i := 0; i < 3; i++ {
+			cfg, err := Load()
+			if err != nil {
+				t.Fatalf("Failed to load config: %v", err)
+			}
+			if cfg.LLM.APIKey != "sk-from-keychain" || cfg.Embeddings.APIKey != "sk-from-keychain" {
+				t.Errorf("Expected the keys from the keychain, got %q and %q", cfg.LLM.APIKey, cfg.Embeddings.APIKey)
+			}
+		}
+		if lookups != 1 {
+			t.Errorf("Expected the keychain to be read once, got %d lookups", lookups)
+		}
+	})
+
+	t.Run("embeddings key from the environment", func(t *testing.T) {
+		useConfigFile(t, "")
+		t.Setenv("RAG_CLI_EMBEDDINGS_API_KEY", "sk-embeddings")
+
+		cfg, err := Load()
+		if err != nil {
+			t.Fatalf("Failed to load config: %v", err)
+		}
+		if cfg.Embeddings.APIKey != "sk-embeddings" {
+			t.Errorf("Expected the key from RAG_CLI_EMBEDDINGS_API_KEY, got %q", cfg.Embeddings.APIKey)
+		}
+	})
+
+	t.Run("literal keys are kept and redacted", func(t *testing.T) {
+		useConfigFile(t, "llm:\n  api_key: sk-literal\n")
+
+		cfg, err := Load()
+		if err != nil {
+			t.Fatalf("Failed to load config: %v", err)
+		}
+		if cfg.LLM.APIKey != "sk-literal" {
+			t.Errorf("Expected the literal key, got %q", cfg.LLM.APIKey)
+		}
+		if setting := findSetting(t, "llm.api_key"); setting.Value != redactedValue {
+			t.Errorf("Expected the key to be redacted, got %v", setting.Value)
+		}
+	})
+}
+
+// useKeychain replaces the platform credential store with lookup, forgetting
+// earlier lookups, for the rest of the test
+func useKeychain(t *testing.T, lookup func(service, account string) (string, error)) {
+	t.Helper()
+	original := keychainLookup
+	forget := func() {
+		keychainCache.Lock()
+		clear(keychainCache.results)
+		keychainCache.Unlock()
+	}
+	t.Cleanup(func() {
+		keychainLookup = original
+		forget()
+	})
+	keychainLookup = lookup
+	forget()
+}
@@ -0,0 +1,120 @@
+package config
+
+import (
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// Source identifies where an effective configuration value came from
+type Source string
+
+const (
+	SourceDefault Source = "default"
+	SourceFile    Source = "file"
+	SourceEnv     Source = "env"
+	SourceFlag    Source = "flag"
+)
+
+// redactedValue replaces secrets in printed configuration
+const redactedValue = "********"
+
+// Setting is a single effective configuration value and its origin
+type Setting struct {
+	Key    string      `json:"key"`
+	Value  interface{} `json:"value"`
+	Source Source      `json:"source"`
+	File   string      `json:"file,omitempty"` // Config file that sets the value, when Source is SourceFile
+}
+
+// EnvPrefix starts the name of every environment variable that overrides a
+// configuration key
+const EnvPrefix = "RAG_CLI"
+
+// ConfigureEnv lets every configuration key be overridden from the
+// environment: llm.base_url is read from RAG_CLI_LLM_BASE_URL. Environment
+// values win over the config file; empty values are ignored.
+func ConfigureEnv(v *viper.Viper) {
+	v.SetEnvPrefix(EnvPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.AutomaticEnv()
+}
+
+// flagBindings tracks command-line flags bound to configuration keys so
+// their source can be reported
+var flagBindings = make(map[string]*pflag.Flag)
+
+// BindFlag binds a command-line flag to a configuration key
+func BindFlag(key string, flag *pflag.Flag) error {
+	if err := viper.BindPFlag(key, flag); err != nil {
+		return err
+	}
+	flagBindings[key] = flag
+	return nil
+}
+
+// EffectiveSettings returns every known configuration key with its current
+// value and source, sorted by key. Secret values are redacted.
+func EffectiveSettings() []Setting {
+	keys := viper.AllKeys()
+	sort.Strings(keys)
+
+	settings := make([]Setting, 0, len(keys))
+	for _, key := range keys {
+		setting := Setting{
+			Key:    key,
+			Value:  displayValue(key, viper.Get(key)),
+			Source: SourceOf(key),
+		}
+		if setting.Source == SourceFile {
+			setting.File = FileOf(key)
+		}
+		settings = append(settings, setting)
+	}
+	return settings
+}
+
+// SourceOf determines which layer supplies the effective value for key,
+// following viper's precedence: flag, env, config file, default
+func SourceOf(key string) Source {
+	if flag, ok := flagBindings[key]; ok && flag.Changed {
+		return SourceFlag
+	}
+	if os.Getenv(EnvVarName(key)) != "" {
+		return SourceEnv
+	}
+	if viper.InConfig(key) {
+		return SourceFile
+	}
+	return SourceDefault
+}
+
+// EnvVarName returns the environment variable that overrides key
+func EnvVarName(key string) string {
+	return EnvPrefix + "_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// displayValue redacts secrets. References such as env:NAME are shown, since
+// they say where a secret is kept rather than holding it.
+func displayValue(key string, value interface{}) interface{} {
+	if !isSecretKey(key) || value == nil || value == "" {
+		return value
+	}
+	if reference, ok := value.(string); ok && isSecretReference(reference) {
+		return value
+	}
+	return redactedValue
+}
+
+// isSecretKey reports whether a key holds a credential that must not be printed
+func isSecretKey(key string) bool {
+	for _, marker := range []string{"api_key", "token", "password", "secret"} {
+		if strings.Contains(key, marker) {
+			return true
+		}
+	}
+	return false
+}
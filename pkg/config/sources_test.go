@@ -0,0 +1,193 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// loadWithConfigFile resets viper and loads configuration from a temporary
+// home directory containing the given config file contents
+func loadWithConfigFile(t *testing.T, contents string) {
+	t.Helper()
+	useConfigFile(t, contents)
+
+	if _, err := Load(); err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+}
+
+// useConfigFile resets viper and points HOME at a temporary directory
+// containing the given config file contents
+func useConfigFile(t *testing.T, contents string) {
+	t.Helper()
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	if contents != "" {
+		if err := os.WriteFile(filepath.Join(home, ".rag-cli.yaml"), []byte(contents), 0644); err != nil {
+			t.Fatalf("Failed to write config file: %v", err)
+		}
+	}
+}
+
+func findSetting(t *testing.T, key string) Setting {
+	t.Helper()
+	for _, setting := range EffectiveSettings() {
+		if setting.Key == key {
+			return setting
+		}
+	}
+	t.Fatalf("Expected setting %s to be reported", key)
+	return Setting{}
+}
+
+func TestEffectiveSettings(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		loadWithConfigFile(t, "")
+
+		setting := findSetting(t, "vector.port")
+		if setting.Source != SourceDefault {
+			t.Errorf("Expected source %s, got %s", SourceDefault, setting.Source)
+		}
+		if setting.Value != 8000 {
+			t.Errorf("Expected value 8000, got %v", setting.Value)
+		}
+	})
+
+	t.Run("file overrides default", func(t *testing.T) {
+		loadWithConfigFile(t, "llm:\n  model: llama3\n")
+
+		setting := findSetting(t, "llm.model")
+		if setting.Source != SourceFile {
+			t.Errorf("Expected source %s, got %s", SourceFile, setting.Source)
+		}
+		if setting.Value != "llama3" {
+			t.Errorf("Expected value llama3, got %v", setting.Value)
+		}
+	})
+
+	t.Run("env overrides file", func(t *testing.T) {
+		loadWithConfigFile(t, "llm:\n  model: llama3\n")
+		viper.AutomaticEnv()
+		t.Setenv(EnvVarName("llm.model"), "mistral")
+
+		setting := findSetting(t, "llm.model")
+		if setting.Source != SourceEnv {
+			t.Errorf("Expected source %s, got %s", SourceEnv, setting.Source)
+		}
+		if setting.Value != "mistral" {
+			t.Errorf("Expected value mistral, got %v", setting.Value)
+		}
+	})
+
+	t.Run("secrets are redacted", func(t *testing.T) {
+		loadWithConfigFile(t, "llm:\n  api_key: sk-very-secret\n")
+
+		setting := findSetting(t, "llm.api_key")
+		if setting.Value != redactedValue {
+			t.Errorf("Expected api key to be redacted, got %v", setting.Value)
+		}
+	})
+}
+
+func TestLoad_ModelPrecedence(t *testing.T) {
+	tests := []struct {
+		name       string
+		env        string
+		flag       string
+		wantModel  string
+		wantSource Source
+	}{
+		{name: "config file", wantModel: "llama3", wantSource: SourceFile},
+		{name: "env overrides config", env: "mistral", wantModel: "mistral", wantSource: SourceEnv},
+		{name: "flag overrides env", env: "mistral", flag: "qwen2.5", wantModel: "qwen2.5", wantSource: SourceFlag},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Reset()
+			t.Cleanup(viper.Reset)
+			home := t.TempDir()
+			t.Setenv("HOME", home)
+			if err := os.WriteFile(filepath.Join(home, ".rag-cli.yaml"), []byte("llm:\n  model: llama3\n"), 0644); err != nil {
+				t.Fatalf("Failed to write config file: %v", err)
+			}
+			if tt.env != "" {
+				t.Setenv("RAG_CLI_LLM_MODEL", tt.env)
+			}
+
+			flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+			flags.String("model", "", "")
+			if err := BindFlag("llm.model", flags.Lookup("model")); err != nil {
+				t.Fatalf("Failed to bind flag: %v", err)
+			}
+			t.Cleanup(func() { delete(flagBindings, "llm.model") })
+			if tt.flag != "" {
+				if err := flags.Set("model", tt.flag); err != nil {
+					t.Fatalf("Failed to set flag: %v", err)
+				}
+			}
+
+			cfg, err := Load()
+			if err != nil {
+				t.Fatalf("Failed to load config: %v", err)
+			}
+			if cfg.LLM.Model != tt.wantModel {
+				t.Errorf("Expected model %s, got %s", tt.wantModel, cfg.LLM.Model)
+			}
+			if source := SourceOf("llm.model"); source != tt.wantSource {
+				t.Errorf("Expected source %s, got %s", tt.wantSource, source)
+			}
+		})
+	}
+}
+
+func TestLoad_EnvOverrides(t *testing.T) {
+	t.Setenv("RAG_CLI_LLM_BASE_URL", "http://ollama:11434")
+	t.Setenv("RAG_CLI_CHAT_MAX_ATTEMPTS", "7")
+	t.Setenv("RAG_CLI_AUTO_INDEX_BATCH_DELAY", "5s")
+	loadWithConfigFile(t, "llm:\n  base_url: http://localhost:11434\nchat:\n  max_attempts: 2\n")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.LLM.BaseURL != "http://ollama:11434" {
+		t.Errorf("Expected base URL from the environment, got %s", cfg.LLM.BaseURL)
+	}
+	if cfg.Chat.MaxAttempts != 7 {
+		t.Errorf("Expected max attempts 7 from the environment, got %d", cfg.Chat.MaxAttempts)
+	}
+	if cfg.AutoIndex.BatchDelay != 5*time.Second {
+		t.Errorf("Expected batch delay 5s from the environment, got %s", cfg.AutoIndex.BatchDelay)
+	}
+
+	for _, key := range []string{"llm.base_url", "chat.max_attempts", "auto_index.batch_delay"} {
+		if source := SourceOf(key); source != SourceEnv {
+			t.Errorf("Expected %s to come from %s, got %s", key, SourceEnv, source)
+		}
+	}
+	if source := SourceOf("llm.host"); source != SourceDefault {
+		t.Errorf("Expected llm.host to keep its default, got %s", source)
+	}
+}
+
+func TestEnvVarName(t *testing.T) {
+	tests := map[string]string{
+		"llm.model":              "RAG_CLI_LLM_MODEL",
+		"llm.base_url":           "RAG_CLI_LLM_BASE_URL",
+		"auto_index.batch_delay": "RAG_CLI_AUTO_INDEX_BATCH_DELAY",
+	}
+	for key, expected := range tests {
+		if name := EnvVarName(key); name != expected {
+			t.Errorf("Expected %s for %s, got %s", expected, key, name)
+		}
+	}
+}
@@ -0,0 +1,356 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// configTemplate renders a commented config file from a Config
+var configTemplate = template.Must(template.New("config").Funcs(template.FuncMap{
+	"quote":        yamlQuote,
+	"list":         yamlList,
+	"modelPrompts": yamlModelPrompts,
+	"safetyRules":  yamlSafetyRules,
+}).Parse(`# RAG CLI Configuration
+# Generated by 'rag-cli config init' or 'rag-cli config defaults'. Edit
+# values as needed, or use 'rag-cli config set <key> <value>'. Run
+# 'rag-cli config show' to see which settings are in effect and where they
+# come from.
+#
+# Any key can be overridden from the environment with RAG_CLI_ and the key
+# path in upper case, dots replaced by underscores: llm.base_url is read
+# from RAG_CLI_LLM_BASE_URL.
+
+# LLM Configuration
+llm:
+  # API the server speaks: ollama, or openai for an OpenAI-compatible server
+  # such as vLLM, LM Studio, llama.cpp or OpenRouter. For openai, point
+  # base_url at the server, e.g. http://localhost:8080 or
+  # https://openrouter.ai/api/v1, and set api_key if it needs one
+  provider: {{quote .LLM.Provider}}
+  # Override per invocation with --model or RAG_CLI_LLM_MODEL
+  model: {{quote .LLM.Model}}
+  host: {{quote .LLM.Host}}
+  port: {{.LLM.Port}}
+  # Overrides host and port when set, e.g. https://ollama.example.com
+  base_url: {{quote .LLM.BaseURL}}
+  # API key for hosted endpoints. Keep it out of this file with a reference:
+  # env:NAME reads an environment variable, keychain:service/account reads
+  # the macOS keychain or the Secret Service on Linux
+  # api_key: "env:OPENAI_API_KEY"
+  # Retries after a refused connection, a timeout or a 5xx response, waiting
+  # retry_backoff before the first and twice as long before each later one
+  max_retries: {{.LLM.MaxRetries}}
+  retry_backoff: "{{.LLM.RetryBackoff}}"
+
+# Vector Database Configuration (ChromaDB)
+vector:
+  host: {{quote .Vector.Host}}
+  port: {{.Vector.Port}}
+  base_url: {{quote .Vector.BaseURL}}
+  # auto uses the v2 API when the server serves it (ChromaDB 0.6 and later)
+  # and v1 otherwise; v1 or v2 skips the check
+  api_version: {{quote .Vector.APIVersion}}
+  # Where collections live with the v2 API
+  tenant: {{quote .Vector.Tenant}}
+  database: {{quote .Vector.Database}}
+  collection: {{quote .Vector.Collection}}
+  command_collection: {{quote .Vector.CommandCollection}}
+  auto_index_collection: {{quote .Vector.AutoIndexCollection}}
+  # Leave out search results farther than this from the query, so a chat
+  # about something the indexed documents do not cover gets no context
+  # rather than the closest unrelated chunks. Distances depend on the
+  # embedding model; compare those 'rag-cli search' shows for related and
+  # unrelated queries. 0 = keep every result
+  max_distance: {{.Vector.MaxDistance}}
+  # The directory a ChromaDB on this machine keeps its data in (the
+  # 'chroma run --path' directory or the Docker volume), so 'rag-cli stats'
+  # can show how much disk it uses. ChromaDB does not report that itself
+  data_dir: {{quote .Vector.DataDir}}
+
+# Embeddings Configuration
+embeddings:
+  # ollama, or openai for a server with an OpenAI-compatible /v1/embeddings
+  provider: {{quote .Embeddings.Provider}}
+  model: {{quote .Embeddings.Model}}
+  host: {{quote .Embeddings.Host}}
+  port: {{.Embeddings.Port}}
+  base_url: {{quote .Embeddings.BaseURL}}
+  # api_key: "env:OPENAI_API_KEY"
+  max_retries: {{.Embeddings.MaxRetries}}
+  retry_backoff: "{{.Embeddings.RetryBackoff}}"
+
+# Text Chunking Configuration
+chunker:
+  chunk_size: {{.Chunker.ChunkSize}}
+  chunk_overlap: {{.Chunker.ChunkOverlap}}
+  # boundary ends chunks at paragraph breaks, then line ends, then sentence
+  # ends, cutting mid-word only when one of them is longer than chunk_size.
+  # fixed cuts every chunk_size characters, as earlier versions did
+  strategy: {{quote .Chunker.Strategy}}
+
+# Chat Behavior Configuration
+chat:
+  # Maximum number of retry attempts when commands fail
+  max_attempts: {{.Chat.MaxAttempts}}
+
+  # Maximum lines of command output to show (split between head and tail)
+  max_output_lines: {{.Chat.MaxOutputLines}}
+
+  # Enable output truncation to prevent overwhelming the terminal
+  truncate_output: {{.Chat.TruncateOutput}}
+
+  # Maximum number of characters accepted by the chat input box (0 = unlimited)
+  max_input_chars: {{.Chat.MaxInputChars}}
+
+  # Document chunks and past command sessions retrieved as context per prompt (1-50)
+  # Override the document count per invocation with --top-k
+  top_k_documents: {{.Chat.TopKDocuments}}
+  top_k_history: {{.Chat.TopKHistory}}
+
+  # Characters of earlier requests and responses sent with each prompt, so
+  # follow-ups such as "now do the same for the other directory" work.
+  # The oldest turns are dropped when over budget, or summarized by the model
+  # when summarize_memory is true. 'clear' forgets them. 0 disables memory
+  memory_chars: {{.Chat.MemoryChars}}
+  summarize_memory: {{.Chat.SummarizeMemory}}
+
+  # Characters of the whole prompt sent to the model. Servers such as Ollama
+  # silently cut prompts longer than the model's context window, so retrieved
+  # documents and sessions are trimmed to fit, lowest ranked first, after the
+  # instructions, conversation and request. Each is also cut to
+  # context_item_chars. 0 = unlimited
+  max_context_chars: {{.Chat.MaxContextChars}}
+  context_item_chars: {{.Chat.ContextItemChars}}
+
+  # Longest a command, or each step of a pipe, may run before it is killed and
+  # reported to the model as timed out, e.g. for 'tail -f' or 'ping'. Ctrl+C
+  # stops a running command sooner. 0 disables the limit
+  command_timeout: "{{.Chat.CommandTimeout}}"
+
+  # List the indexed documents an answer drew on under it in a chat, with
+  # their distance from the prompt (lower is closer). Hidden by --no-sources
+  show_sources: {{.Chat.ShowSources}}
+
+  # Write each interactive chat, with your messages, the model's responses and
+  # the commands run with their output, to a JSON Lines file in the
+  # transcripts directory, one file per session. Read one back with
+  # 'rag-cli history show', which shows the latest. Command output beyond
+  # transcript_max_output bytes is cut short (0 keeps it all)
+  save_transcripts: {{.Chat.SaveTranscripts}}
+  transcript_max_output: {{.Chat.TranscriptMaxOutput}}
+
+  # Run the commands the model proposes. When unset, interactive chat runs them
+  # (after approval) and --prompt only prints them. Overridden by --no-exec
+  # and --allow-commands
+  # allow_commands: true
+
+  # Commands that run without asking for approval. A program name such as
+  # "git" allows it with any arguments, unless the command redirects output to
+  # a file or runs other commands through sudo, sh -c or find -exec; anything
+  # else allows exactly that command. Answering "a" (always) at an approval
+  # prompt adds a rule for the rest of the session: the command's programs, or
+  # the whole command when always_allow_match is "exact". With
+  # save_always_allow those rules are also added here in the user config file.
+  # Dangerous commands are asked about every time
+  always_allow: {{list .Chat.AlwaysAllow}}
+  always_allow_match: {{quote .Chat.AlwaysAllowMatch}}
+  save_always_allow: {{.Chat.SaveAlwaysAllow}}
+
+  # Programs the commands may run, checked for each step of a pipe and for
+  # commands run through sudo, env or sh -c. Entries are program names or
+  # safety patterns such as "git*". An empty allowlist permits every program;
+  # shell builtins such as cd and echo need not be listed. The denylist wins.
+  # A refused command is reported to the model, which can try another way
+  commands:
+    allowlist: {{list .Chat.Commands.Allowlist}}
+    denylist: {{list .Chat.Commands.Denylist}}
+
+# Command History Retention
+# Applied when a chat session starts; 0 disables each limit
+history:
+  retention_days: {{.History.RetentionDays}}
+  max_sessions: {{.History.MaxSessions}}
+
+# Index Command Configuration
+index:
+  # gitignore-style patterns skipped by 'rag-cli index', e.g. ["vendor/", "*.min.js"]
+  exclude_patterns: {{list .Index.ExcludePatterns}}
+  # Files indexed in parallel, like index --workers; 0 uses 4, or the number
+  # of CPU cores when there are fewer
+  workers: {{.Index.Workers}}
+
+# Update Checks
+updates:
+  # Allow 'rag-cli version --check' and update notices to contact GitHub
+  check: {{.Updates.Check}}
+  # Print a notice at most once a day when a newer release is available
+  notify: {{.Updates.Notify}}
+
+# Debug Logging
+debug:
+  # Write detailed evaluation logs; --debug enables this for one invocation
+  enabled: {{.Debug.Enabled}}
+  # Log location; empty uses debug.log in the rag-cli state directory
+  # ($XDG_STATE_HOME/rag-cli, ~/.local/state/rag-cli on Linux)
+  log_file: {{quote .Debug.LogFile}}
+  # Size at which the log is moved to <log_file>.1 and restarted, in bytes; 0 never rotates
+  max_size: {{.Debug.MaxSize}}
+
+# Logging
+log:
+  # Least severe messages logged: error, warn, info, or debug; --log-level
+  # overrides this for one invocation
+  level: {{quote .Log.Level}}
+  # Log to a file instead of stderr
+  to_file: {{.Log.ToFile}}
+  # Log file; empty uses rag-cli.log in the rag-cli state directory
+  file: {{quote .Log.File}}
+
+# Network Timeouts
+# Durations such as 30s or 2m; 0 disables a limit
+timeouts:
+  # Whole requests to each service, including reading the response
+  llm: "{{.Timeouts.LLM}}"
+  embeddings: "{{.Timeouts.Embeddings}}"
+  vector: "{{.Timeouts.Vector}}"
+  # Opening a connection and completing a TLS handshake
+  dial: "{{.Timeouts.Dial}}"
+  tls_handshake: "{{.Timeouts.TLSHandshake}}"
+
+# System Detection
+# Command prompts describe your OS and tools, found by running commands such
+# as ls --version and git --version. The result is kept in system-info.json
+# in the rag-cli state directory and detected again on another machine.
+system_info:
+  # Reuse the detected information between runs
+  cache: {{.SystemInfo.Cache}}
+  # Detect again once the cached information is this old
+  cache_ttl: "{{.SystemInfo.CacheTTL}}"
+  # Include the CPU count, total memory, and free disk space on the working
+  # directory's volume, for requests such as freeing space or picking a job count
+  resources: {{.SystemInfo.Resources}}
+
+# Usage Metrics
+# Nothing is ever sent over the network
+telemetry:
+  # Record task outcomes, failing commands, and model response times in
+  # usage.jsonl in the rag-cli state directory, for 'rag-cli stats --usage'
+  local: {{.Telemetry.Local}}
+
+# Custom Prompt Templates
+# Files with Go text/template prompts that replace the built-in ones; empty
+# uses the built-in prompt. Each must use the placeholders its prompt needs:
+# {{"{{"}}.Request{{"}}"}} everywhere, {{"{{"}}.ExecutionLog{{"}}"}} in all but command_generation,
+# and {{"{{"}}.RemainingCommands{{"}}"}} in queue_decision
+prompts:
+  command_generation: {{quote .Prompts.CommandGeneration}}
+  goal_check: {{quote .Prompts.GoalCheck}}
+  next_commands: {{quote .Prompts.NextCommands}}
+  queue_decision: {{quote .Prompts.QueueDecision}}
+  final_answer: {{quote .Prompts.FinalAnswer}}
+  # Different prompts for specific models, e.g.
+  # models:
+  #   - model: "llama3.1:8b"
+  #     final_answer: "~/.config/rag-cli/prompts/llama-answer.tmpl"
+  models: {{modelPrompts .Prompts.Models}}
+
+# Command Safety
+# Destructive commands such as rm -rf / are always refused. These settings add
+# your own rules. Patterns are globs matched against the whole command, e.g.
+# "git push *", or regular expressions written as "re:<expression>".
+safety:
+  # Commands to refuse (severity "block", the default) or to run only after a
+  # warning (severity "warn"), e.g.
+  # blocklist:
+  #   - pattern: "git push --force*"
+  #     severity: "block"
+  #     reason: "force-pushes"
+  #   - pattern: "re:\\bkubectl\\b"
+  #     severity: "warn"
+  blocklist: {{safetyRules .Safety.Blocklist}}
+  # Commands to treat as dangerous: always asked about, even with
+  # --auto-approve, with the reason shown in red. Built in are sudo, recursive
+  # deletes outside the working directory, writes to block devices,
+  # chmod -R 777, shutdown and reboot, and piping downloads to a shell, e.g.
+  # dangerous:
+  #   - pattern: "terraform destroy*"
+  #     reason: "destroys infrastructure"
+  dangerous: {{safetyRules .Safety.Dangerous}}
+  # Commands exempt from the blocklist, dangerous and read_only, e.g. ["git status", "make *"]
+  allowlist: {{list .Safety.Allowlist}}
+  # Approve commands by typing "yes" instead of pressing Enter
+  require_typed_confirmation: {{.Safety.RequireTypedConfirmation}}
+  # Only run commands that cannot change anything, such as ls, cat and grep
+  read_only: {{.Safety.ReadOnly}}
+
+# Auto-indexing Configuration
+auto_index:
+  enabled: {{.AutoIndex.Enabled}}
+  extensions: {{list .AutoIndex.Extensions}}
+  max_file_size: {{.AutoIndex.MaxFileSize}}  # in bytes
+  exclude_patterns: {{list .AutoIndex.ExcludePatterns}}
+  batch_delay: "{{.AutoIndex.BatchDelay}}"
+`))
+
+// RenderConfig renders cfg as a commented YAML config file
+func RenderConfig(cfg *Config) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := configTemplate.Execute(&buf, cfg); err != nil {
+		return nil, fmt.Errorf("failed to render config: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// yamlModelPrompts renders prompt overrides as a YAML flow sequence of
+// mappings, leaving out unset prompts
+func yamlModelPrompts(overrides []ModelPrompts) string {
+	entries := make([]string, len(overrides))
+	for i, override := range overrides {
+		fields := []string{fmt.Sprintf("model: %q", override.Model)}
+		files := override.byName()
+		for _, name := range PromptNames() {
+			if files[name] != "" {
+				fields = append(fields, fmt.Sprintf("%s: %q", name, files[name]))
+			}
+		}
+		entries[i] = "{" + strings.Join(fields, ", ") + "}"
+	}
+	return "[" + strings.Join(entries, ", ") + "]"
+}
+
+// yamlSafetyRules renders blocklist rules as a YAML flow sequence of
+// mappings, leaving out unset fields
+func yamlSafetyRules(rules []SafetyRule) string {
+	entries := make([]string, len(rules))
+	for i, rule := range rules {
+		fields := []string{fmt.Sprintf("pattern: %q", rule.Pattern)}
+		if rule.Severity != "" {
+			fields = append(fields, fmt.Sprintf("severity: %q", rule.Severity))
+		}
+		if rule.Reason != "" {
+			fields = append(fields, fmt.Sprintf("reason: %q", rule.Reason))
+		}
+		entries[i] = "{" + strings.Join(fields, ", ") + "}"
+	}
+	return "[" + strings.Join(entries, ", ") + "]"
+}
+
+// yamlList renders a string slice as a YAML flow sequence
+func yamlList(items []string) string {
+	quoted := make([]string, len(items))
+	for i, item := range items {
+		quoted[i] = yamlQuote(item)
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
+
+// yamlQuote renders s as a YAML double-quoted scalar. Go's escapes for
+// quotes, backslashes and control characters are all valid in YAML, so
+// values typed at 'rag-cli config init' cannot break the file
+func yamlQuote(s string) string {
+	return strconv.Quote(s)
+}
@@ -0,0 +1,92 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestRenderConfig(t *testing.T) {
+	defaults, err := DefaultConfig()
+	if err != nil {
+		t.Fatalf("Failed to build default config: %v", err)
+	}
+
+	data, err := RenderConfig(defaults)
+	if err != nil {
+		t.Fatalf("Failed to render config: %v", err)
+	}
+
+	t.Run("parses back into the defaults", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatalf("Failed to write config file: %v", err)
+		}
+
+		v := viper.New()
+		v.SetConfigFile(path)
+		if err := v.ReadInConfig(); err != nil {
+			t.Fatalf("Generated config does not parse: %v", err)
+		}
+
+		var parsed Config
+		if err := v.Unmarshal(&parsed); err != nil {
+			t.Fatalf("Failed to unmarshal generated config: %v", err)
+		}
+		// chat.allow_commands is left unset so its default depends on the mode
+		parsed.Chat.AllowCommands = defaults.Chat.AllowCommands
+		if !reflect.DeepEqual(&parsed, defaults) {
+			t.Errorf("Expected generated config to match defaults\nexpected: %+v\ngot:      %+v", defaults, parsed)
+		}
+	})
+
+	t.Run("sets every key explicitly", func(t *testing.T) {
+		loadWithConfigFile(t, string(data))
+		for _, setting := range EffectiveSettings() {
+			if setting.Key == "llm.api_key" || setting.Key == "embeddings.api_key" || setting.Key == "chat.allow_commands" {
+				continue
+			}
+			if setting.Source != SourceFile {
+				t.Errorf("Expected %s to be set in the generated file, got source %s", setting.Key, setting.Source)
+			}
+		}
+	})
+}
+
+func TestRenderConfig_EscapesStrings(t *testing.T) {
+	cfg, err := DefaultConfig()
+	if err != nil {
+		t.Fatalf("Failed to build default config: %v", err)
+	}
+	cfg.LLM.Model = `my "tuned" model`
+	cfg.Vector.Host = `C:\chroma\host`
+	cfg.Log.File = "line\nbreak # not a comment"
+
+	data, err := RenderConfig(cfg)
+	if err != nil {
+		t.Fatalf("Failed to render config: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		t.Fatalf("Generated config does not parse: %v\n%s", err, data)
+	}
+
+	for key, want := range map[string]string{
+		"llm.model":   cfg.LLM.Model,
+		"vector.host": cfg.Vector.Host,
+		"log.file":    cfg.Log.File,
+	} {
+		if got := v.GetString(key); got != want {
+			t.Errorf("Expected %s to be %q, got %q", key, want, got)
+		}
+	}
+}
@@ -0,0 +1,258 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// MaxRetrievalDepth bounds the number of chunks retrieved per prompt, keeping
+// the context within what local models can use
+const MaxRetrievalDepth = 50
+
+// Problem is a single invalid setting, identified by its key path
+type Problem struct {
+	Key     string
+	Message string
+}
+
+func (p Problem) String() string {
+	return p.Key + ": " + p.Message
+}
+
+// ValidationError lists every invalid setting found by Config.Validate
+type ValidationError struct {
+	Problems []Problem
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Problems) == 1 {
+		return "invalid configuration: " + e.Problems[0].String()
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "invalid configuration (%d problems):", len(e.Problems))
+	for _, problem := range e.Problems {
+		b.WriteString("\n  - ")
+		b.WriteString(problem.String())
+	}
+	return b.String()
+}
+
+// Validate checks ranges, URLs, durations, and constraints between settings,
+// returning a *ValidationError that lists every problem, or nil
+func (c *Config) Validate() error {
+	var problems []Problem
+	add := func(key, format string, args ...interface{}) {
+		problems = append(problems, Problem{Key: key, Message: fmt.Sprintf(format, args...)})
+	}
+	required := func(key, value string) {
+		if strings.TrimSpace(value) == "" {
+			add(key, "must not be empty")
+		}
+	}
+	port := func(key string, value int) {
+		if value < 1 || value > 65535 {
+			add(key, "must be a port between 1 and 65535, got %d", value)
+		}
+	}
+	baseURL := func(key, value string) {
+		if value == "" {
+			return // Built from host and port
+		}
+		parsed, err := url.Parse(value)
+		switch {
+		case err != nil:
+			add(key, "is not a valid URL: %v", err)
+		case parsed.Scheme != "http" && parsed.Scheme != "https":
+			add(key, "must be an http:// or https:// URL, got %q", value)
+		case parsed.Host == "":
+			add(key, "must include a host, got %q", value)
+		}
+	}
+	provider := func(key, value string) {
+		if !containsString(Providers, value) {
+			add(key, "must be one of %s, got %q", strings.Join(Providers, ", "), value)
+		}
+	}
+	atLeast := func(key string, value, min int) {
+		if value < min {
+			add(key, "must be at least %d, got %d", min, value)
+		}
+	}
+
+	secretKeys := make([]string, 0, len(c.secretErrors))
+	for key := range c.secretErrors {
+		secretKeys = append(secretKeys, key)
+	}
+	sort.Strings(secretKeys)
+	for _, key := range secretKeys {
+		add(key, "%v", c.secretErrors[key])
+	}
+
+	provider("llm.provider", c.LLM.Provider)
+	required("llm.model", c.LLM.Model)
+	port("llm.port", c.LLM.Port)
+	baseURL("llm.base_url", c.LLM.BaseURL)
+	atLeast("llm.max_retries", c.LLM.MaxRetries, 0)
+
+	provider("embeddings.provider", c.Embeddings.Provider)
+	required("embeddings.model", c.Embeddings.Model)
+	port("embeddings.port", c.Embeddings.Port)
+	baseURL("embeddings.base_url", c.Embeddings.BaseURL)
+	atLeast("embeddings.max_retries", c.Embeddings.MaxRetries, 0)
+
+	required("vector.host", c.Vector.Host)
+	port("vector.port", c.Vector.Port)
+	baseURL("vector.base_url", c.Vector.BaseURL)
+	if !containsString(ChromaAPIVersions, c.Vector.APIVersion) {
+		add("vector.api_version", "must be one of %s, got %q", strings.Join(ChromaAPIVersions, ", "), c.Vector.APIVersion)
+	}
+	required("vector.tenant", c.Vector.Tenant)
+	required("vector.database", c.Vector.Database)
+	required("vector.collection", c.Vector.Collection)
+	required("vector.command_collection", c.Vector.CommandCollection)
+	required("vector.auto_index_collection", c.Vector.AutoIndexCollection)
+	if c.Vector.MaxDistance < 0 {
+		add("vector.max_distance", "must be at least 0, got %g", c.Vector.MaxDistance)
+	}
+
+	atLeast("chunker.chunk_size", c.Chunker.ChunkSize, 1)
+	atLeast("chunker.chunk_overlap", c.Chunker.ChunkOverlap, 0)
+	if !containsString(ChunkStrategies, c.Chunker.Strategy) {
+		add("chunker.strategy", "must be one of %s, got %q", strings.Join(ChunkStrategies, ", "), c.Chunker.Strategy)
+	}
+	if c.Chunker.ChunkSize > 0 && c.Chunker.ChunkOverlap >= c.Chunker.ChunkSize {
+		add("chunker.chunk_overlap", "must be smaller than chunker.chunk_size (%d), got %d", c.Chunker.ChunkSize, c.Chunker.ChunkOverlap)
+	}
+
+	if c.AutoIndex.MaxFileSize < 1 {
+		add("auto_index.max_file_size", "must be at least 1 byte, got %d", c.AutoIndex.MaxFileSize)
+	}
+	if c.AutoIndex.BatchDelay < 0 {
+		add("auto_index.batch_delay", "must not be negative, got %s", c.AutoIndex.BatchDelay)
+	}
+	if c.Debug.MaxSize < 0 {
+		add("debug.max_size", "must not be negative, got %d", c.Debug.MaxSize)
+	}
+
+	atLeast("chat.max_attempts", c.Chat.MaxAttempts, 1)
+	atLeast("chat.max_output_lines", c.Chat.MaxOutputLines, 0)
+	atLeast("chat.max_input_chars", c.Chat.MaxInputChars, 0)
+	atLeast("chat.memory_chars", c.Chat.MemoryChars, 0)
+	atLeast("chat.max_context_chars", c.Chat.MaxContextChars, 0)
+	atLeast("chat.context_item_chars", c.Chat.ContextItemChars, 0)
+	atLeast("chat.transcript_max_output", c.Chat.TranscriptMaxOutput, 0)
+	if !containsString(ApprovalMatches, c.Chat.AlwaysAllowMatch) {
+		add("chat.always_allow_match", "must be one of %s, got %q", strings.Join(ApprovalMatches, ", "), c.Chat.AlwaysAllowMatch)
+	}
+	for _, setting := range []struct {
+		key   string
+		value int
+	}{
+		{"chat.top_k_documents", c.Chat.TopKDocuments},
+		{"chat.top_k_history", c.Chat.TopKHistory},
+	} {
+		if setting.value < 1 || setting.value > MaxRetrievalDepth {
+			add(setting.key, "must be between 1 and %d, got %d", MaxRetrievalDepth, setting.value)
+		}
+	}
+
+	if !containsString(LogLevels, c.Log.Level) {
+		add("log.level", "must be one of %s, got %q", strings.Join(LogLevels, ", "), c.Log.Level)
+	}
+
+	atLeast("history.retention_days", c.History.RetentionDays, 0)
+	atLeast("history.max_sessions", c.History.MaxSessions, 0)
+	atLeast("index.workers", c.Index.Workers, 0)
+
+	for _, setting := range []struct {
+		key   string
+		value time.Duration
+	}{
+		{"llm.retry_backoff", c.LLM.RetryBackoff},
+		{"embeddings.retry_backoff", c.Embeddings.RetryBackoff},
+		{"timeouts.llm", c.Timeouts.LLM},
+		{"timeouts.embeddings", c.Timeouts.Embeddings},
+		{"timeouts.vector", c.Timeouts.Vector},
+		{"timeouts.dial", c.Timeouts.Dial},
+		{"timeouts.tls_handshake", c.Timeouts.TLSHandshake},
+		{"system_info.cache_ttl", c.SystemInfo.CacheTTL},
+		{"chat.command_timeout", c.Chat.CommandTimeout},
+	} {
+		if setting.value < 0 {
+			add(setting.key, "must not be negative, got %s", setting.value)
+		}
+	}
+
+	problems = append(problems, c.Chat.Commands.policyProblems()...)
+	problems = append(problems, c.Safety.safetyProblems()...)
+
+	for _, name := range PromptNames() {
+		if path := c.Prompts.files().byName()[name]; path != "" {
+			if _, err := loadPromptTemplate(name, path); err != nil {
+				add("prompts."+name, "%v", err)
+			}
+		}
+	}
+	for i, override := range c.Prompts.Models {
+		key := fmt.Sprintf("prompts.models[%d]", i)
+		if override.Model == "" {
+			add(key, "must name a model")
+		}
+		for _, name := range PromptNames() {
+			if path := override.byName()[name]; path != "" {
+				if _, err := loadPromptTemplate(name, path); err != nil {
+					add(key+"."+name, "%v", err)
+				}
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// durationType is the Go type of duration settings
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// checkDurations makes sure every duration setting in v is written with a
+// unit, such as 2s or 500ms. A bare number would otherwise be decoded as
+// nanoseconds, and a malformed string would fail without naming the key.
+func checkDurations(v *viper.Viper) error {
+	var problems []Problem
+	for key, t := range schema() {
+		if t != durationType {
+			continue
+		}
+		switch value := v.Get(key).(type) {
+		case nil, time.Duration:
+		case string:
+			if _, err := time.ParseDuration(value); err != nil {
+				problems = append(problems, Problem{Key: key, Message: fmt.Sprintf("must be a duration such as 2s or 500ms, got %q", value)})
+			}
+		default:
+			problems = append(problems, Problem{Key: key, Message: fmt.Sprintf("must be a duration with a unit, such as %vs or %vms, got %v", value, value, value)})
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	sort.Slice(problems, func(i, j int) bool { return problems[i].Key < problems[j].Key })
+	return &ValidationError{Problems: problems}
+}
@@ -0,0 +1,138 @@
+package config
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(c *Config)
+		wantKey string
+		wantMsg string
+	}{
+		{name: "empty model", modify: func(c *Config) { c.LLM.Model = " " }, wantKey: "llm.model", wantMsg: "must not be empty"},
+		{name: "empty embeddings model", modify: func(c *Config) { c.Embeddings.Model = "" }, wantKey: "embeddings.model", wantMsg: "must not be empty"},
+		{name: "port out of range", modify: func(c *Config) { c.Vector.Port = 70000 }, wantKey: "vector.port", wantMsg: "between 1 and 65535, got 70000"},
+		{name: "base URL without scheme", modify: func(c *Config) { c.LLM.BaseURL = "localhost:11434" }, wantKey: "llm.base_url", wantMsg: "http:// or https://"},
+		{name: "unparseable base URL", modify: func(c *Config) { c.Embeddings.BaseURL = "http://[::1" }, wantKey: "embeddings.base_url", wantMsg: "not a valid URL"},
+		{name: "base URL without host", modify: func(c *Config) { c.LLM.BaseURL = "http://" }, wantKey: "llm.base_url", wantMsg: "must include a host"},
+		{name: "empty collection", modify: func(c *Config) { c.Vector.CommandCollection = "" }, wantKey: "vector.command_collection", wantMsg: "must not be empty"},
+		{name: "zero chunk size", modify: func(c *Config) { c.Chunker.ChunkSize = 0 }, wantKey: "chunker.chunk_size", wantMsg: "at least 1, got 0"},
+		{name: "overlap not smaller than size", modify: func(c *Config) { c.Chunker.ChunkOverlap = 1000 }, wantKey: "chunker.chunk_overlap", wantMsg: "smaller than chunker.chunk_size (1000), got 1000"},
+		{name: "negative batch delay", modify: func(c *Config) { c.AutoIndex.BatchDelay = -time.Second }, wantKey: "auto_index.batch_delay", wantMsg: "must not be negative"},
+		{name: "negative timeout", modify: func(c *Config) { c.Timeouts.Dial = -time.Second }, wantKey: "timeouts.dial", wantMsg: "must not be negative"},
+		{name: "negative retries", modify: func(c *Config) { c.LLM.MaxRetries = -1 }, wantKey: "llm.max_retries", wantMsg: "must be at least 0"},
+		{name: "unknown chunk strategy", modify: func(c *Config) { c.Chunker.Strategy = "semantic" }, wantKey: "chunker.strategy", wantMsg: "must be one of boundary, fixed"},
+		{name: "unknown chroma api version", modify: func(c *Config) { c.Vector.APIVersion = "v3" }, wantKey: "vector.api_version", wantMsg: "must be one of auto, v1, v2"},
+		{name: "missing tenant", modify: func(c *Config) { c.Vector.Tenant = "" }, wantKey: "vector.tenant", wantMsg: "must not be empty"},
+		{name: "unknown provider", modify: func(c *Config) { c.LLM.Provider = "anthropic" }, wantKey: "llm.provider", wantMsg: "must be one of ollama, openai"},
+		{name: "negative command timeout", modify: func(c *Config) { c.Chat.CommandTimeout = -time.Second }, wantKey: "chat.command_timeout", wantMsg: "must not be negative"},
+		{name: "negative retry backoff", modify: func(c *Config) { c.Embeddings.RetryBackoff = -time.Second }, wantKey: "embeddings.retry_backoff", wantMsg: "must not be negative"},
+		{name: "invalid safety regex", modify: func(c *Config) { c.Safety.Blocklist = []SafetyRule{{Pattern: "re:(kubectl"}} }, wantKey: "safety.blocklist[0]", wantMsg: "invalid regular expression"},
+		{name: "unknown safety severity", modify: func(c *Config) { c.Safety.Blocklist = []SafetyRule{{Pattern: "kubectl *", Severity: "ask"}} }, wantKey: "safety.blocklist[0]", wantMsg: `severity must be "block" or "warn"`},
+		{name: "invalid command denylist pattern", modify: func(c *Config) { c.Chat.Commands.Denylist = []string{"re:(python"} }, wantKey: "chat.commands.denylist[0]", wantMsg: "invalid regular expression"},
+		{name: "empty allowlist pattern", modify: func(c *Config) { c.Safety.Allowlist = []string{" "} }, wantKey: "safety.allowlist[0]", wantMsg: "must not be empty"},
+		{name: "zero max file size", modify: func(c *Config) { c.AutoIndex.MaxFileSize = 0 }, wantKey: "auto_index.max_file_size", wantMsg: "at least 1 byte"},
+		{name: "negative debug log size", modify: func(c *Config) { c.Debug.MaxSize = -1 }, wantKey: "debug.max_size", wantMsg: "must not be negative"},
+		{name: "zero attempts", modify: func(c *Config) { c.Chat.MaxAttempts = 0 }, wantKey: "chat.max_attempts", wantMsg: "at least 1, got 0"},
+		{name: "negative output lines", modify: func(c *Config) { c.Chat.MaxOutputLines = -1 }, wantKey: "chat.max_output_lines", wantMsg: "at least 0, got -1"},
+		{name: "negative input chars", modify: func(c *Config) { c.Chat.MaxInputChars = -5 }, wantKey: "chat.max_input_chars", wantMsg: "at least 0, got -5"},
+		{name: "zero documents", modify: func(c *Config) { c.Chat.TopKDocuments = 0 }, wantKey: "chat.top_k_documents", wantMsg: "between 1 and 50, got 0"},
+		{name: "unknown always-allow match", modify: func(c *Config) { c.Chat.AlwaysAllowMatch = "prefix" }, wantKey: "chat.always_allow_match", wantMsg: `must be one of program, exact, got "prefix"`},
+		{name: "negative max distance", modify: func(c *Config) { c.Vector.MaxDistance = -0.5 }, wantKey: "vector.max_distance", wantMsg: "at least 0, got -0.5"},
+		{name: "too much history", modify: func(c *Config) { c.Chat.TopKHistory = 51 }, wantKey: "chat.top_k_history", wantMsg: "between 1 and 50, got 51"},
+		{name: "negative retention", modify: func(c *Config) { c.History.RetentionDays = -1 }, wantKey: "history.retention_days", wantMsg: "at least 0"},
+		{name: "negative workers", modify: func(c *Config) { c.Index.Workers = -2 }, wantKey: "index.workers", wantMsg: "at least 0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := DefaultConfig()
+			if err != nil {
+				t.Fatalf("Failed to build default config: %v", err)
+			}
+			tt.modify(cfg)
+
+			var validationErr *ValidationError
+			if err := cfg.Validate(); !errors.As(err, &validationErr) {
+				t.Fatalf("Expected a validation error, got: %v", err)
+			}
+			if len(validationErr.Problems) != 1 {
+				t.Fatalf("Expected exactly one problem, got: %v", validationErr)
+			}
+			problem := validationErr.Problems[0]
+			if problem.Key != tt.wantKey || !strings.Contains(problem.Message, tt.wantMsg) {
+				t.Errorf("Expected %s: ...%s..., got %s", tt.wantKey, tt.wantMsg, problem)
+			}
+		})
+	}
+
+	t.Run("lists every problem", func(t *testing.T) {
+		cfg, err := DefaultConfig()
+		if err != nil {
+			t.Fatalf("Failed to build default config: %v", err)
+		}
+		cfg.LLM.Model = ""
+		cfg.Vector.Port = 0
+
+		err = cfg.Validate()
+		if err == nil {
+			t.Fatal("Expected a validation error")
+		}
+		expected := "invalid configuration (2 problems):\n  - llm.model: must not be empty\n  - vector.port: must be a port between 1 and 65535, got 0"
+		if err.Error() != expected {
+			t.Errorf("Expected:\n%s\ngot:\n%s", expected, err)
+		}
+	})
+}
+
+func TestLoad_Validates(t *testing.T) {
+	useConfigFile(t, "chunker:\n  chunk_size: 100\n  chunk_overlap: 200\n")
+
+	_, err := Load()
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Expected a validation error, got: %v", err)
+	}
+	if !strings.Contains(err.Error(), "chunker.chunk_overlap") {
+		t.Errorf("Expected the offending key in the error, got: %v", err)
+	}
+}
+
+func TestLoad_Durations(t *testing.T) {
+	tests := []struct {
+		name      string
+		file      string
+		expected  time.Duration
+		wantError string
+	}{
+		{name: "valid", file: "auto_index:\n  batch_delay: 500ms\n", expected: 500 * time.Millisecond},
+		{name: "missing uses the default", file: "", expected: 2 * time.Second},
+		{name: "malformed", file: "auto_index:\n  batch_delay: soon\n", wantError: `auto_index.batch_delay: must be a duration such as 2s or 500ms, got "soon"`},
+		{name: "bare number", file: "auto_index:\n  batch_delay: 2000\n", wantError: "auto_index.batch_delay: must be a duration with a unit, such as 2000s or 2000ms, got 2000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useConfigFile(t, tt.file)
+
+			cfg, err := Load()
+			if tt.wantError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantError) {
+					t.Errorf("Expected error containing %q, got: %v", tt.wantError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Failed to load config: %v", err)
+			}
+			if cfg.AutoIndex.BatchDelay != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, cfg.AutoIndex.BatchDelay)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+// Package paths resolves where rag-cli keeps its files. It follows the XDG
+// base directory conventions, using the platform equivalents on macOS and
+// Windows when the XDG variables are not set.
+package paths
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// appName names the rag-cli directory inside each base directory
+const appName = "rag-cli"
+
+// Paths holds the directories rag-cli reads and writes
+type Paths struct {
+	Config string // Configuration files
+	Data   string // Data kept between runs, such as index snapshots and caches
+	State  string // Logs and other state that can be discarded
+	Legacy string // ~/.rag-cli, where earlier versions kept their files
+	Home   string
+}
+
+// Default returns the directories for the current platform and environment
+func Default() (Paths, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return Paths{}, fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return resolve(runtime.GOOS, os.Getenv, home), nil
+}
+
+// resolve works out the directories for goos. An XDG variable wins on every
+// platform; relative values are ignored, as the XDG specification requires.
+func resolve(goos string, getenv func(string) string, home string) Paths {
+	base := func(xdgVar string, fallback string) string {
+		if dir := getenv(xdgVar); dir != "" && filepath.IsAbs(dir) {
+			return filepath.Join(dir, appName)
+		}
+		return filepath.Join(fallback, appName)
+	}
+	windowsDir := func(envVar string, fallback ...string) string {
+		if dir := getenv(envVar); dir != "" {
+			return dir
+		}
+		return filepath.Join(append([]string{home}, fallback...)...)
+	}
+
+	var config, data, state string
+	switch goos {
+	case "windows":
+		config = windowsDir("APPDATA", "AppData", "Roaming")
+		data = windowsDir("LOCALAPPDATA", "AppData", "Local")
+		state = data
+	case "darwin":
+		config = filepath.Join(home, "Library", "Application Support")
+		data = config
+		state = filepath.Join(home, "Library", "Logs")
+	default:
+		config = filepath.Join(home, ".config")
+		data = filepath.Join(home, ".local", "share")
+		state = filepath.Join(home, ".local", "state")
+	}
+
+	return Paths{
+		Config: base("XDG_CONFIG_HOME", config),
+		Data:   base("XDG_DATA_HOME", data),
+		State:  base("XDG_STATE_HOME", state),
+		Legacy: filepath.Join(home, ".rag-cli"),
+		Home:   home,
+	}
+}
+
+// ConfigFile returns the user config file location
+func (p Paths) ConfigFile() string {
+	return filepath.Join(p.Config, "config.yaml")
+}
+
+// LegacyConfigFile returns ~/.rag-cli.yaml, the config file used by earlier
+// versions
+func (p Paths) LegacyConfigFile() string {
+	return filepath.Join(p.Home, ".rag-cli.yaml")
+}
+
+// DataFile returns the location of a data file. A file that exists only in
+// the legacy directory is returned from there, so state from earlier
+// versions keeps being used.
+func (p Paths) DataFile(name string) string {
+	return PreferExisting(filepath.Join(p.Data, name), filepath.Join(p.Legacy, name))
+}
+
+// StateFile returns the location of a state file
+func (p Paths) StateFile(name string) string {
+	return filepath.Join(p.State, name)
+}
+
+// PreferExisting returns legacy when it exists and path does not, and path
+// otherwise
+func PreferExisting(path, legacy string) string {
+	if _, err := os.Stat(path); err == nil {
+		return path
+	}
+	if _, err := os.Stat(legacy); err == nil {
+		return legacy
+	}
+	return path
+}
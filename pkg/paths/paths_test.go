@@ -0,0 +1,137 @@
+package paths
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	home := filepath.FromSlash("/home/ada")
+
+	tests := []struct {
+		name     string
+		goos     string
+		env      map[string]string
+		expected Paths
+	}{
+		{
+			name: "linux defaults",
+			goos: "linux",
+			expected: Paths{
+				Config: filepath.Join(home, ".config", "rag-cli"),
+				Data:   filepath.Join(home, ".local", "share", "rag-cli"),
+				State:  filepath.Join(home, ".local", "state", "rag-cli"),
+			},
+		},
+		{
+			name: "XDG variables",
+			goos: "linux",
+			env: map[string]string{
+				"XDG_CONFIG_HOME": filepath.FromSlash("/xdg/config"),
+				"XDG_DATA_HOME":   filepath.FromSlash("/xdg/data"),
+				"XDG_STATE_HOME":  filepath.FromSlash("/xdg/state"),
+			},
+			expected: Paths{
+				Config: filepath.FromSlash("/xdg/config/rag-cli"),
+				Data:   filepath.FromSlash("/xdg/data/rag-cli"),
+				State:  filepath.FromSlash("/xdg/state/rag-cli"),
+			},
+		},
+		{
+			name: "relative XDG values are ignored",
+			goos: "linux",
+			env:  map[string]string{"XDG_CONFIG_HOME": "config"},
+			expected: Paths{
+				Config: filepath.Join(home, ".config", "rag-cli"),
+				Data:   filepath.Join(home, ".local", "share", "rag-cli"),
+				State:  filepath.Join(home, ".local", "state", "rag-cli"),
+			},
+		},
+		{
+			name: "macOS defaults",
+			goos: "darwin",
+			expected: Paths{
+				Config: filepath.Join(home, "Library", "Application Support", "rag-cli"),
+				Data:   filepath.Join(home, "Library", "Application Support", "rag-cli"),
+				State:  filepath.Join(home, "Library", "Logs", "rag-cli"),
+			},
+		},
+		{
+			name: "macOS honors XDG",
+			goos: "darwin",
+			env:  map[string]string{"XDG_CONFIG_HOME": filepath.FromSlash("/xdg/config")},
+			expected: Paths{
+				Config: filepath.FromSlash("/xdg/config/rag-cli"),
+				Data:   filepath.Join(home, "Library", "Application Support", "rag-cli"),
+				State:  filepath.Join(home, "Library", "Logs", "rag-cli"),
+			},
+		},
+		{
+			name: "windows",
+			goos: "windows",
+			env: map[string]string{
+				"APPDATA":      filepath.FromSlash("/users/ada/roaming"),
+				"LOCALAPPDATA": filepath.FromSlash("/users/ada/local"),
+			},
+			expected: Paths{
+				Config: filepath.FromSlash("/users/ada/roaming/rag-cli"),
+				Data:   filepath.FromSlash("/users/ada/local/rag-cli"),
+				State:  filepath.FromSlash("/users/ada/local/rag-cli"),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			getenv := func(key string) string { return tt.env[key] }
+			tt.expected.Legacy = filepath.Join(home, ".rag-cli")
+			tt.expected.Home = home
+
+			if got := resolve(tt.goos, getenv, home); got != tt.expected {
+				t.Errorf("Expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestDefault_Env(t *testing.T) {
+	dataHome := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", dataHome)
+
+	dirs, err := Default()
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if dirs.Data != filepath.Join(dataHome, "rag-cli") {
+		t.Errorf("Expected data under %s, got %s", dataHome, dirs.Data)
+	}
+}
+
+func TestPreferExisting(t *testing.T) {
+	dir := t.TempDir()
+	dirs := Paths{Data: filepath.Join(dir, "data"), Legacy: filepath.Join(dir, "legacy"), Home: dir}
+	current := filepath.Join(dirs.Data, "index-state.json")
+	legacy := filepath.Join(dirs.Legacy, "index-state.json")
+
+	if got := dirs.DataFile("index-state.json"); got != current {
+		t.Errorf("Expected %s when neither exists, got %s", current, got)
+	}
+
+	for _, path := range []string{legacy, current} {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte("{}"), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+
+		if got := dirs.DataFile("index-state.json"); got != path {
+			t.Errorf("Expected %s, got %s", path, got)
+		}
+	}
+
+	if got := dirs.LegacyConfigFile(); got != filepath.Join(dir, ".rag-cli.yaml") {
+		t.Errorf("Expected the legacy config in the home directory, got %s", got)
+	}
+}